@@ -0,0 +1,275 @@
+package admin
+
+import (
+	"strconv"
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/service/admin/instance"
+	"oneclickvirt/service/task"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// parseAdminBackupID 解析路径中的ID参数
+func parseAdminBackupID(c *gin.Context, name, errMsg string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, errMsg))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondAdminBackupError 根据错误信息返回对应的错误码
+func respondAdminBackupError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "实例不存在" || msg == "备份不存在" || msg == "实例不存在或无权限" || msg == "定时备份计划不存在":
+		common.ResponseWithError(c, common.NewError(common.CodeNotFound, msg))
+	case strings.Contains(msg, "上限") || strings.Contains(msg, "正在进行"):
+		common.ResponseWithError(c, common.NewError(common.CodeConflict, msg))
+	case strings.Contains(msg, "不允许") || strings.Contains(msg, "不可用") || strings.Contains(msg, "不合法") ||
+		strings.Contains(msg, "不能超过") || strings.Contains(msg, "节点"):
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+	default:
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+	}
+}
+
+// GetAdminBackups 管理员获取备份列表
+// @Summary 管理员获取备份列表
+// @Description 管理员获取所有实例备份，可按用户或实例过滤
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param userId query int false "用户ID"
+// @Param instanceId query int false "实例ID"
+// @Success 200 {object} common.Response{data=[]provider.InstanceBackup} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /admin/backups [get]
+func GetAdminBackups(c *gin.Context) {
+	var userID, instanceID uint
+	if idStr := c.Query("userId"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			common.ResponseWithError(c, common.NewError(common.CodeValidationError, "无效的用户ID"))
+			return
+		}
+		userID = uint(id)
+	}
+	if idStr := c.Query("instanceId"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			common.ResponseWithError(c, common.NewError(common.CodeValidationError, "无效的实例ID"))
+			return
+		}
+		instanceID = uint(id)
+	}
+
+	instanceService := instance.NewService(task.GetTaskService())
+	backups, err := instanceService.ListBackups(userID, instanceID)
+	if err != nil {
+		respondAdminBackupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, backups)
+}
+
+// CreateAdminInstanceBackup 管理员创建实例备份
+// @Summary 管理员创建实例备份
+// @Description 管理员为任意实例创建备份，以实例所有者身份创建异步任务
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param request body user.CreateBackupRequest true "创建备份请求参数"
+// @Success 200 {object} common.Response{data=user.BackupTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "实例不存在"
+// @Failure 409 {object} common.Response "备份数量已达上限或已有备份任务"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /admin/instances/{id}/backups [post]
+func CreateAdminInstanceBackup(c *gin.Context) {
+	instanceID, ok := parseAdminBackupID(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	var req userModel.CreateBackupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	instanceService := instance.NewService(task.GetTaskService())
+	result, err := instanceService.CreateInstanceBackup(instanceID, req)
+	if err != nil {
+		global.APP_LOG.Error("管理员创建实例备份失败",
+			zap.Uint("instanceId", instanceID),
+			zap.Error(err))
+		respondAdminBackupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result, "备份创建任务已提交")
+}
+
+// RestoreAdminBackup 管理员将备份恢复为新实例
+// @Summary 管理员将备份恢复为新实例
+// @Description 管理员将任意备份恢复为新实例，新实例归属备份所有者
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param backupId path int true "备份ID"
+// @Success 200 {object} common.Response{data=user.BackupTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误或备份不可用"
+// @Failure 404 {object} common.Response "备份不存在"
+// @Failure 409 {object} common.Response "实例数量已达上限"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /admin/backups/{backupId}/restore [post]
+func RestoreAdminBackup(c *gin.Context) {
+	backupID, ok := parseAdminBackupID(c, "backupId", "无效的备份ID")
+	if !ok {
+		return
+	}
+
+	instanceService := instance.NewService(task.GetTaskService())
+	result, err := instanceService.RestoreBackupAsNewInstance(backupID)
+	if err != nil {
+		global.APP_LOG.Error("管理员恢复备份失败",
+			zap.Uint("backupId", backupID),
+			zap.Error(err))
+		respondAdminBackupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result, "备份恢复任务已提交")
+}
+
+// DeleteAdminBackup 管理员删除备份
+// @Summary 管理员删除备份
+// @Description 管理员删除任意备份及其备份文件
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param backupId path int true "备份ID"
+// @Success 200 {object} common.Response "删除成功"
+// @Failure 400 {object} common.Response "参数错误或备份正在使用"
+// @Failure 404 {object} common.Response "备份不存在"
+// @Failure 500 {object} common.Response "删除失败"
+// @Router /admin/backups/{backupId} [delete]
+func DeleteAdminBackup(c *gin.Context) {
+	backupID, ok := parseAdminBackupID(c, "backupId", "无效的备份ID")
+	if !ok {
+		return
+	}
+
+	instanceService := instance.NewService(task.GetTaskService())
+	if err := instanceService.DeleteBackup(backupID); err != nil {
+		respondAdminBackupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "备份已删除")
+}
+
+// GetAdminBackupSchedule 管理员获取实例定时备份计划
+// @Summary 管理员获取实例定时备份计划
+// @Description 管理员获取任意实例的定时备份计划，未设置时返回空
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Success 200 {object} common.Response{data=provider.BackupSchedule} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "实例不存在"
+// @Router /admin/instances/{id}/backup-schedule [get]
+func GetAdminBackupSchedule(c *gin.Context) {
+	instanceID, ok := parseAdminBackupID(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	instanceService := instance.NewService(task.GetTaskService())
+	schedule, err := instanceService.GetBackupSchedule(instanceID)
+	if err != nil {
+		respondAdminBackupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, schedule)
+}
+
+// SaveAdminBackupSchedule 管理员设置实例定时备份计划
+// @Summary 管理员设置实例定时备份计划
+// @Description 管理员为任意实例创建或更新定时备份计划
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param request body user.BackupScheduleRequest true "定时备份计划参数"
+// @Success 200 {object} common.Response{data=provider.BackupSchedule} "保存成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "实例不存在"
+// @Failure 500 {object} common.Response "保存失败"
+// @Router /admin/instances/{id}/backup-schedule [put]
+func SaveAdminBackupSchedule(c *gin.Context) {
+	instanceID, ok := parseAdminBackupID(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	var req userModel.BackupScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	instanceService := instance.NewService(task.GetTaskService())
+	schedule, err := instanceService.SaveBackupSchedule(instanceID, req)
+	if err != nil {
+		respondAdminBackupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, schedule, "定时备份计划已保存")
+}
+
+// DeleteAdminBackupSchedule 管理员删除实例定时备份计划
+// @Summary 管理员删除实例定时备份计划
+// @Description 管理员删除任意实例的定时备份计划，已生成的备份保留
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Success 200 {object} common.Response "删除成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "实例或定时备份计划不存在"
+// @Router /admin/instances/{id}/backup-schedule [delete]
+func DeleteAdminBackupSchedule(c *gin.Context) {
+	instanceID, ok := parseAdminBackupID(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	instanceService := instance.NewService(task.GetTaskService())
+	if err := instanceService.DeleteBackupSchedule(instanceID); err != nil {
+		respondAdminBackupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "定时备份计划已删除")
+}
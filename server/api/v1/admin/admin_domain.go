@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"strconv"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/admin"
+	"oneclickvirt/model/common"
+	"oneclickvirt/service/domain"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetAdminDomains 管理员获取域名绑定列表
+// @Summary 管理员获取域名绑定列表
+// @Description 分页获取所有用户的域名绑定，可按域名关键字、用户、节点和状态过滤
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Param keyword query string false "域名关键字"
+// @Param userId query int false "用户ID"
+// @Param providerId query int false "Provider ID"
+// @Param status query string false "状态：pending/active/failed"
+// @Success 200 {object} common.Response "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /admin/domains [get]
+func GetAdminDomains(c *gin.Context) {
+	var req admin.DomainListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "参数错误"))
+		return
+	}
+
+	domains, total, err := domain.NewService().ListDomains(req)
+	if err != nil {
+		global.APP_LOG.Error("获取域名绑定列表失败", zap.Error(err))
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, "获取域名绑定列表失败"))
+		return
+	}
+
+	common.ResponseSuccess(c, map[string]interface{}{
+		"list":  domains,
+		"total": total,
+	})
+}
+
+// DeleteAdminDomain 管理员删除域名绑定
+// @Summary 管理员删除域名绑定
+// @Description 删除任意用户的域名绑定，已生效的绑定会在后台从节点配置中移除
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "域名绑定ID"
+// @Success 200 {object} common.Response "删除成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "域名绑定不存在"
+// @Failure 500 {object} common.Response "删除失败"
+// @Router /admin/domains/{id} [delete]
+func DeleteAdminDomain(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "无效的域名绑定ID"))
+		return
+	}
+
+	if err := domain.NewService().AdminDeleteDomain(uint(id)); err != nil {
+		if err.Error() == "域名绑定不存在" {
+			common.ResponseWithError(c, common.NewError(common.CodeNotFound, err.Error()))
+			return
+		}
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "域名绑定已删除")
+}
+
+// SyncProviderDomains 重新同步节点的域名绑定配置
+// @Summary 同步节点域名绑定配置
+// @Description 按数据库中已生效的域名绑定重新生成节点上的Caddy配置并重载，用于节点重装Caddy或配置被手动修改后恢复
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Provider ID"
+// @Success 200 {object} common.Response "同步成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 500 {object} common.Response "同步失败"
+// @Router /admin/providers/{id}/domains/sync [post]
+func SyncProviderDomains(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "无效的Provider ID"))
+		return
+	}
+
+	if err := domain.SyncProvider(uint(id)); err != nil {
+		global.APP_LOG.Warn("同步节点域名绑定配置失败", zap.Uint64("providerID", id), zap.Error(err))
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "域名绑定配置已同步")
+}
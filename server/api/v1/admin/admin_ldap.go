@@ -0,0 +1,38 @@
+package admin
+
+import (
+	adminModel "oneclickvirt/model/admin"
+	"oneclickvirt/model/common"
+	"oneclickvirt/service/ldap"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestLDAPConnection 测试LDAP连接
+// @Summary 测试LDAP连接
+// @Description 使用当前LDAP配置连接服务器并以服务账户绑定。提供用户名时搜索该用户并返回DN、所属组和映射的用户等级，同时提供密码时校验密码
+// @Tags 系统配置
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body adminModel.LDAPTestRequest true "测试参数"
+// @Success 200 {object} common.Response{data=ldap.TestResult} "测试完成"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 404 {object} common.Response "测试用户不存在"
+// @Failure 500 {object} common.Response "连接失败"
+// @Router /admin/ldap/test [post]
+func TestLDAPConnection(c *gin.Context) {
+	var req adminModel.LDAPTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	ldapService := ldap.Service{}
+	result, err := ldapService.TestConnection(req.Username, req.Password)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, result, "LDAP连接测试完成")
+}
@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"strings"
+
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	"oneclickvirt/model/common"
+	"oneclickvirt/service/admin/instance"
+	"oneclickvirt/service/task"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// MigrateAdminInstance 管理员迁移实例到其他节点
+// @Summary 管理员迁移实例到其他节点
+// @Description 将实例迁移到同类型、同架构的其他节点，源节点可以是已冻结或已过期的节点。迁移通过导出/导入完成，会重新分配默认端口映射，手动添加的端口映射不会保留，源节点上的快照会被删除
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param request body admin.MigrateInstanceRequest true "迁移请求参数"
+// @Success 200 {object} common.Response{data=admin.MigrateTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误或目标节点不可用"
+// @Failure 404 {object} common.Response "实例或目标节点不存在"
+// @Failure 409 {object} common.Response "实例有任务正在进行"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /admin/instances/{id}/migrate [post]
+func MigrateAdminInstance(c *gin.Context) {
+	instanceID, ok := parseAdminBackupID(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	var req adminModel.MigrateInstanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	instanceService := instance.NewService(task.GetTaskService())
+	result, err := instanceService.MigrateInstance(instanceID, req)
+	if err != nil {
+		global.APP_LOG.Error("管理员迁移实例失败",
+			zap.Uint("instanceId", instanceID),
+			zap.Uint("targetProviderId", req.TargetProviderID),
+			zap.Error(err))
+		msg := err.Error()
+		switch {
+		case msg == "实例不存在" || msg == "目标节点不存在":
+			common.ResponseWithError(c, common.NewError(common.CodeNotFound, msg))
+		case strings.Contains(msg, "正在进行"):
+			common.ResponseWithError(c, common.NewError(common.CodeConflict, msg))
+		case strings.Contains(msg, "失败"):
+			common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+		default:
+			common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+		}
+		return
+	}
+
+	common.ResponseSuccess(c, result, "迁移任务已提交")
+}
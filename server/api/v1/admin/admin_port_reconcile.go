@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"oneclickvirt/global"
+	"oneclickvirt/model/admin"
+	"oneclickvirt/model/common"
+	"oneclickvirt/service/resources"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetPortDriftReports 获取端口映射漂移检测报告
+// @Summary 获取端口映射漂移检测报告
+// @Description 获取各Provider最近一次端口映射漂移检测（定时或手动）的报告，报告仅保存在内存中，服务重启后清空
+// @Tags 端口映射管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param providerId query int false "Provider ID，不传则返回全部"
+// @Success 200 {object} common.Response{data=[]admin.PortReconcileReport} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Router /admin/port-mappings/drift [get]
+func GetPortDriftReports(c *gin.Context) {
+	var req admin.PortReconcileRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "参数错误"))
+		return
+	}
+
+	reconcileService := resources.PortReconcileService{}
+	common.ResponseSuccess(c, reconcileService.GetLastReports(req.ProviderID))
+}
+
+// CheckPortDrift 检测端口映射漂移
+// @Summary 检测端口映射漂移
+// @Description 立即读取Provider主机上实际生效的端口映射并与数据库比对，只生成报告，不改动主机
+// @Tags 端口映射管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body admin.PortReconcileRequest true "检测参数，providerId为0时检测全部Provider"
+// @Success 200 {object} common.Response{data=[]admin.PortReconcileReport} "检测完成"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "Provider不存在"
+// @Router /admin/port-mappings/drift/check [post]
+func CheckPortDrift(c *gin.Context) {
+	runPortReconcile(c, false)
+}
+
+// FixPortDrift 修复端口映射漂移
+// @Summary 修复端口映射漂移
+// @Description 检测端口映射漂移并以数据库为准修复：删除多余和冲突的映射，补建缺失的映射
+// @Tags 端口映射管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body admin.PortReconcileRequest true "修复参数，providerId为0时修复全部Provider"
+// @Success 200 {object} common.Response{data=[]admin.PortReconcileReport} "修复完成，失败的条目见fixError"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "Provider不存在"
+// @Router /admin/port-mappings/drift/fix [post]
+func FixPortDrift(c *gin.Context) {
+	runPortReconcile(c, true)
+}
+
+// runPortReconcile 执行漂移检测，autoFix为true时同时修复
+func runPortReconcile(c *gin.Context, autoFix bool) {
+	var req admin.PortReconcileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "参数错误"))
+		return
+	}
+
+	reconcileService := resources.PortReconcileService{}
+	if req.ProviderID == 0 {
+		common.ResponseSuccess(c, reconcileService.ReconcileAll(c.Request.Context(), autoFix))
+		return
+	}
+
+	report, err := reconcileService.ReconcileProvider(c.Request.Context(), req.ProviderID, autoFix)
+	if err != nil {
+		global.APP_LOG.Warn("端口映射漂移检测失败", zap.Uint("providerID", req.ProviderID), zap.Error(err))
+		common.ResponseWithError(c, common.NewError(common.CodeNotFound, err.Error()))
+		return
+	}
+	common.ResponseSuccess(c, []admin.PortReconcileReport{*report})
+}
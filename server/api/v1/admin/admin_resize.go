@@ -0,0 +1,64 @@
+package admin
+
+import (
+	"strings"
+
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	"oneclickvirt/model/common"
+	"oneclickvirt/service/admin/instance"
+	"oneclickvirt/service/task"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ResizeAdminInstance 管理员调整实例规格
+// @Summary 管理员调整实例规格
+// @Description 管理员在线调整任意实例的CPU、内存、磁盘和带宽，以实例所有者身份创建异步任务，数值为0的项保持不变，磁盘只支持扩容
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param request body admin.ResizeInstanceRequest true "调整规格请求参数"
+// @Success 200 {object} common.Response{data=user.ResizeTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误或超出配额"
+// @Failure 404 {object} common.Response "实例不存在"
+// @Failure 409 {object} common.Response "实例有任务正在进行"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /admin/instances/{id}/resize [post]
+func ResizeAdminInstance(c *gin.Context) {
+	instanceID, ok := parseAdminBackupID(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	var req adminModel.ResizeInstanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	instanceService := instance.NewService(task.GetTaskService())
+	result, err := instanceService.ResizeInstance(instanceID, req)
+	if err != nil {
+		global.APP_LOG.Error("管理员调整实例规格失败",
+			zap.Uint("instanceId", instanceID),
+			zap.Error(err))
+		msg := err.Error()
+		switch {
+		case msg == "实例不存在" || msg == "实例不存在或无权限":
+			common.ResponseWithError(c, common.NewError(common.CodeNotFound, msg))
+		case strings.Contains(msg, "正在进行"):
+			common.ResponseWithError(c, common.NewError(common.CodeConflict, msg))
+		case strings.HasPrefix(msg, "创建调整规格任务失败") || strings.HasPrefix(msg, "序列化任务数据失败"):
+			common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+		default:
+			common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+		}
+		return
+	}
+
+	common.ResponseSuccess(c, result, "调整规格任务已提交")
+}
@@ -0,0 +1,231 @@
+package admin
+
+import (
+	"strconv"
+
+	adminModel "oneclickvirt/model/admin"
+	"oneclickvirt/model/auth"
+	"oneclickvirt/model/common"
+	auth2 "oneclickvirt/service/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPermissionList 获取可分配的操作权限
+// @Summary 获取可分配的操作权限
+// @Description 获取可分配给角色的操作权限列表。同一模块的manage权限包含对应的view权限
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=[]auth.PermissionInfo} "获取成功"
+// @Router /admin/permissions [get]
+func GetPermissionList(c *gin.Context) {
+	common.ResponseSuccess(c, auth.AllPermissions)
+}
+
+// GetRoleList 获取角色列表
+// @Summary 获取角色列表
+// @Description 分页获取角色列表，支持按名称或代码搜索
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(10)
+// @Param keyword query string false "名称或代码搜索"
+// @Success 200 {object} common.Response{data=object} "获取成功"
+// @Failure 500 {object} common.Response "获取失败"
+// @Router /admin/roles [get]
+func GetRoleList(c *gin.Context) {
+	var req common.PageInfo
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	roleService := auth2.RoleService{}
+	result, err := roleService.GetRoleList(req)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, result)
+}
+
+// CreateRole 创建角色
+// @Summary 创建角色
+// @Description 创建自定义角色并设置操作权限，只能授予自己拥有的权限
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body admin.RoleRequest true "角色信息"
+// @Success 200 {object} common.Response{data=auth.Role} "创建成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 403 {object} common.Response "不能授予自己没有的权限"
+// @Router /admin/roles [post]
+func CreateRole(c *gin.Context) {
+	var req adminModel.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, err.Error()))
+		return
+	}
+
+	operatorID, err := getUserIDFromContext(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	roleService := auth2.RoleService{}
+	role, err := roleService.CreateRole(req, operatorID)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, role, "角色创建成功")
+}
+
+// UpdateRole 更新角色
+// @Summary 更新角色
+// @Description 更新自定义角色的信息和操作权限，内置角色不可修改
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Param request body admin.RoleRequest true "角色信息"
+// @Success 200 {object} common.Response{data=auth.Role} "更新成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 403 {object} common.Response "内置角色不可修改"
+// @Failure 404 {object} common.Response "角色不存在"
+// @Router /admin/roles/{id} [put]
+func UpdateRole(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeInvalidParam, "无效的角色ID"))
+		return
+	}
+
+	var req adminModel.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, err.Error()))
+		return
+	}
+
+	operatorID, err := getUserIDFromContext(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	roleService := auth2.RoleService{}
+	role, err := roleService.UpdateRole(uint(roleID), req, operatorID)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, role, "角色更新成功")
+}
+
+// DeleteRole 删除角色
+// @Summary 删除角色
+// @Description 删除未分配给任何用户的自定义角色，内置角色不可删除
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} common.Response "删除成功"
+// @Failure 400 {object} common.Response "角色还有关联的用户"
+// @Failure 403 {object} common.Response "内置角色不可删除"
+// @Failure 404 {object} common.Response "角色不存在"
+// @Router /admin/roles/{id} [delete]
+func DeleteRole(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeInvalidParam, "无效的角色ID"))
+		return
+	}
+
+	roleService := auth2.RoleService{}
+	if err := roleService.DeleteRole(uint(roleID)); err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, nil, "角色删除成功")
+}
+
+// GetUserRoles 获取用户的角色
+// @Summary 获取用户的角色
+// @Description 获取指定用户已分配的角色
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} common.Response{data=[]auth.Role} "获取成功"
+// @Failure 404 {object} common.Response "用户不存在"
+// @Router /admin/users/{id}/roles [get]
+func GetUserRoles(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeInvalidParam, "无效的用户ID"))
+		return
+	}
+
+	roleService := auth2.RoleService{}
+	roles, err := roleService.GetUserRoles(uint(userID))
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, roles)
+}
+
+// SetUserRoles 设置用户的角色
+// @Summary 设置用户的角色
+// @Description 覆盖指定用户的角色列表。只能分配或移除自己拥有其全部权限的角色，不能修改自己的角色
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param request body admin.UserRolesRequest true "角色ID列表"
+// @Success 200 {object} common.Response{data=[]auth.Role} "设置成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "用户或角色不存在"
+// @Router /admin/users/{id}/roles [put]
+func SetUserRoles(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeInvalidParam, "无效的用户ID"))
+		return
+	}
+
+	if !requireManageableUsers(c, uint(userID)) {
+		return
+	}
+
+	var req adminModel.UserRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, err.Error()))
+		return
+	}
+
+	operatorID, err := getUserIDFromContext(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	roleService := auth2.RoleService{}
+	roles, err := roleService.SetUserRoles(uint(userID), req.RoleIDs, operatorID)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, roles, "用户角色设置成功")
+}
@@ -0,0 +1,186 @@
+package admin
+
+import (
+	"strconv"
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/service/admin/instance"
+	"oneclickvirt/service/task"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// parseAdminSnapshotParams 解析实例ID和快照ID路径参数
+func parseAdminSnapshotParams(c *gin.Context, withSnapshot bool) (uint, uint, bool) {
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "无效的实例ID"))
+		return 0, 0, false
+	}
+	if !withSnapshot {
+		return uint(instanceID), 0, true
+	}
+
+	snapshotID, err := strconv.ParseUint(c.Param("snapshotId"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "无效的快照ID"))
+		return 0, 0, false
+	}
+	return uint(instanceID), uint(snapshotID), true
+}
+
+// respondAdminSnapshotError 根据错误信息返回对应的错误码
+func respondAdminSnapshotError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "实例不存在" || msg == "快照不存在" || msg == "实例不存在或无权限":
+		common.ResponseWithError(c, common.NewError(common.CodeNotFound, msg))
+	case strings.Contains(msg, "上限") || strings.Contains(msg, "已存在") || strings.Contains(msg, "正在进行"):
+		common.ResponseWithError(c, common.NewError(common.CodeConflict, msg))
+	case strings.Contains(msg, "不允许") || strings.Contains(msg, "不可用") || strings.Contains(msg, "快照名称"):
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+	default:
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+	}
+}
+
+// GetAdminInstanceSnapshots 管理员获取实例快照列表
+// @Summary 管理员获取实例快照列表
+// @Description 管理员获取任意实例的快照列表
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Success 200 {object} common.Response{data=user.SnapshotListResponse} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "实例不存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /admin/instances/{id}/snapshots [get]
+func GetAdminInstanceSnapshots(c *gin.Context) {
+	instanceID, _, ok := parseAdminSnapshotParams(c, false)
+	if !ok {
+		return
+	}
+
+	instanceService := instance.NewService(task.GetTaskService())
+	result, err := instanceService.ListInstanceSnapshots(instanceID)
+	if err != nil {
+		respondAdminSnapshotError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result)
+}
+
+// CreateAdminInstanceSnapshot 管理员创建实例快照
+// @Summary 管理员创建实例快照
+// @Description 管理员为任意实例创建快照，以实例所有者身份创建异步任务
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param request body user.CreateSnapshotRequest true "创建快照请求参数"
+// @Success 200 {object} common.Response{data=user.SnapshotTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "实例不存在"
+// @Failure 409 {object} common.Response "快照数量已达上限或已有快照任务"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /admin/instances/{id}/snapshots [post]
+func CreateAdminInstanceSnapshot(c *gin.Context) {
+	instanceID, _, ok := parseAdminSnapshotParams(c, false)
+	if !ok {
+		return
+	}
+
+	var req userModel.CreateSnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	instanceService := instance.NewService(task.GetTaskService())
+	result, err := instanceService.CreateInstanceSnapshot(instanceID, req)
+	if err != nil {
+		global.APP_LOG.Error("管理员创建实例快照失败",
+			zap.Uint("instanceId", instanceID),
+			zap.Error(err))
+		respondAdminSnapshotError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result, "快照创建任务已提交")
+}
+
+// RestoreAdminInstanceSnapshot 管理员恢复实例快照
+// @Summary 管理员恢复实例快照
+// @Description 管理员将任意实例恢复到指定快照
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param snapshotId path int true "快照ID"
+// @Success 200 {object} common.Response{data=user.SnapshotTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "实例或快照不存在"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /admin/instances/{id}/snapshots/{snapshotId}/restore [post]
+func RestoreAdminInstanceSnapshot(c *gin.Context) {
+	instanceID, snapshotID, ok := parseAdminSnapshotParams(c, true)
+	if !ok {
+		return
+	}
+
+	instanceService := instance.NewService(task.GetTaskService())
+	result, err := instanceService.RestoreInstanceSnapshot(instanceID, snapshotID)
+	if err != nil {
+		global.APP_LOG.Error("管理员恢复实例快照失败",
+			zap.Uint("instanceId", instanceID),
+			zap.Uint("snapshotId", snapshotID),
+			zap.Error(err))
+		respondAdminSnapshotError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result, "快照恢复任务已提交")
+}
+
+// DeleteAdminInstanceSnapshot 管理员删除实例快照
+// @Summary 管理员删除实例快照
+// @Description 管理员删除任意实例的指定快照
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param snapshotId path int true "快照ID"
+// @Success 200 {object} common.Response{data=user.SnapshotTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "实例或快照不存在"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /admin/instances/{id}/snapshots/{snapshotId} [delete]
+func DeleteAdminInstanceSnapshot(c *gin.Context) {
+	instanceID, snapshotID, ok := parseAdminSnapshotParams(c, true)
+	if !ok {
+		return
+	}
+
+	instanceService := instance.NewService(task.GetTaskService())
+	result, err := instanceService.DeleteInstanceSnapshot(instanceID, snapshotID)
+	if err != nil {
+		global.APP_LOG.Error("管理员删除实例快照失败",
+			zap.Uint("instanceId", instanceID),
+			zap.Uint("snapshotId", snapshotID),
+			zap.Error(err))
+		respondAdminSnapshotError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result, "快照删除任务已提交")
+}
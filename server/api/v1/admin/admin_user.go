@@ -1,6 +1,7 @@
 package admin
 
 import (
+	"errors"
 	"fmt"
 	"oneclickvirt/service/provider"
 	"strconv"
@@ -10,11 +11,12 @@ import (
 	"oneclickvirt/model/admin"
 	"oneclickvirt/model/common"
 	"oneclickvirt/service/admin/user"
+	auth2 "oneclickvirt/service/auth"
 
 	"github.com/gin-gonic/gin"
 )
 
-// requireAdminOnly 检查是否为管理员用户
+// requireAdminOnly 检查是否为管理员或已通过路由操作权限校验的用户
 func requireAdminOnly(c *gin.Context) bool {
 	authCtx, exists := middleware.GetAuthContext(c)
 	if !exists {
@@ -22,14 +24,31 @@ func requireAdminOnly(c *gin.Context) bool {
 		return false
 	}
 
-	if authCtx.UserType != "admin" {
-		common.ResponseWithError(c, common.NewError(common.CodeForbidden, "此操作仅限管理员执行"))
+	// 路由已按操作权限校验，这里再确认一次，防止处理函数被挂到未校验权限的路由上
+	if _, granted := c.Get(middleware.GrantedPermissionKey); !granted && authCtx.UserType != "admin" {
+		common.ResponseWithError(c, common.NewError(common.CodeForbidden, "权限不足"))
 		return false
 	}
 
 	return true
 }
 
+// requireManageableUsers 检查当前用户能否操作目标用户，通过角色获得用户管理权限的用户不能操作管理员账户
+func requireManageableUsers(c *gin.Context, userIDs ...uint) bool {
+	operatorID, err := getUserIDFromContext(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return false
+	}
+
+	userService := user.NewService()
+	if err := userService.CheckManageable(operatorID, userIDs...); err != nil {
+		common.ResponseWithError(c, err)
+		return false
+	}
+	return true
+}
+
 // GetUserList 获取用户列表
 // @Summary 获取用户列表
 // @Description 获取系统中所有用户的列表（分页）
@@ -90,11 +109,20 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
+	operatorID, err := getUserIDFromContext(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
 	userService := user.NewService()
-	err := userService.CreateUser(req)
+	err = userService.CreateUser(req, operatorID)
 	if err != nil {
 		// 根据错误内容选择合适的错误码
-		if strings.Contains(err.Error(), "用户名已存在") {
+		var appErr *common.AppError
+		if errors.As(err, &appErr) {
+			common.ResponseWithError(c, err)
+		} else if strings.Contains(err.Error(), "用户名已存在") {
 			common.ResponseWithError(c, common.NewError(common.CodeUserExists, err.Error()))
 		} else {
 			common.ResponseWithError(c, common.NewError(common.CodeValidationError, err.Error()))
@@ -152,6 +180,10 @@ func DeleteUser(c *gin.Context) {
 		return
 	}
 
+	if !requireManageableUsers(c, uint(userID)) {
+		return
+	}
+
 	userService := user.NewService()
 	err = userService.DeleteUser(uint(userID))
 	if err != nil {
@@ -183,6 +215,10 @@ func UpdateUserStatus(c *gin.Context) {
 		return
 	}
 
+	if !requireManageableUsers(c, uint(userID)) {
+		return
+	}
+
 	var req admin.UpdateUserStatusRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "参数错误"))
@@ -228,6 +264,10 @@ func AdminBatchDeleteUsers(c *gin.Context) {
 		return
 	}
 
+	if !requireManageableUsers(c, req.UserIDs...) {
+		return
+	}
+
 	userService := user.NewService()
 	err := userService.BatchDeleteUsers(req.UserIDs)
 	if err != nil {
@@ -268,6 +308,10 @@ func AdminBatchUpdateUserStatus(c *gin.Context) {
 		return
 	}
 
+	if !requireManageableUsers(c, req.UserIDs...) {
+		return
+	}
+
 	userService := user.NewService()
 	err := userService.BatchUpdateUserStatus(req.UserIDs, req.Status)
 	if err != nil {
@@ -312,6 +356,10 @@ func AdminBatchUpdateUserLevel(c *gin.Context) {
 		return
 	}
 
+	if !requireManageableUsers(c, req.UserIDs...) {
+		return
+	}
+
 	userService := user.NewService()
 	err := userService.BatchUpdateUserLevel(req.UserIDs, req.Level)
 	if err != nil {
@@ -343,6 +391,10 @@ func UpdateUserLevel(c *gin.Context) {
 		return
 	}
 
+	if !requireManageableUsers(c, uint(userID)) {
+		return
+	}
+
 	var req admin.UpdateUserLevelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "参数错误"))
@@ -386,6 +438,10 @@ func ResetUserPassword(c *gin.Context) {
 		return
 	}
 
+	if !requireManageableUsers(c, uint(userID)) {
+		return
+	}
+
 	var req admin.ResetUserPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// 由于不再需要密码参数，这里可以忽略绑定错误
@@ -433,6 +489,10 @@ func ResetUserPasswordAndNotify(c *gin.Context) {
 		return
 	}
 
+	if !requireManageableUsers(c, uint(userID)) {
+		return
+	}
+
 	var req admin.ResetUserPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// 由于不再需要密码参数，这里可以忽略绑定错误
@@ -447,3 +507,89 @@ func ResetUserPasswordAndNotify(c *gin.Context) {
 
 	common.ResponseSuccess(c, nil, "重置用户密码成功，新密码已发送到用户绑定的通信渠道")
 }
+
+// ResetUserTwoFactor 管理员重置用户两步验证
+// @Summary 管理员重置用户两步验证
+// @Description 清除指定用户的TOTP密钥、恢复码和通行密钥，并使该用户已签发的所有登录令牌失效。用户丢失验证器和恢复码时使用
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} common.Response "重置成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "用户不存在"
+// @Failure 500 {object} common.Response "重置失败"
+// @Router /admin/users/{id}/reset-2fa [put]
+func ResetUserTwoFactor(c *gin.Context) {
+	if !requireAdminOnly(c) {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeInvalidParam, "无效的用户ID"))
+		return
+	}
+
+	if !requireManageableUsers(c, uint(userID)) {
+		return
+	}
+
+	adminUserID, err := getUserIDFromContext(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	twoFactorService := auth2.TwoFactorService{}
+	if err := twoFactorService.Reset(uint(userID), adminUserID); err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "重置用户两步验证成功，该用户需要重新登录")
+}
+
+// UnlockUser 管理员解锁用户账户
+// @Summary 管理员解锁用户账户
+// @Description 解除因密码错误次数过多导致的账户锁定，并清零锁定次数
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} common.Response "解锁成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "用户不存在"
+// @Failure 500 {object} common.Response "解锁失败"
+// @Router /admin/users/{id}/unlock [put]
+func UnlockUser(c *gin.Context) {
+	if !requireAdminOnly(c) {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeInvalidParam, "无效的用户ID"))
+		return
+	}
+
+	if !requireManageableUsers(c, uint(userID)) {
+		return
+	}
+
+	adminUserID, err := getUserIDFromContext(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	protectionService := auth2.LoginProtectionService{}
+	if err := protectionService.UnlockUser(uint(userID), adminUserID); err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "解锁用户账户成功")
+}
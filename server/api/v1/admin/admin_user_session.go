@@ -0,0 +1,134 @@
+package admin
+
+import (
+	"strconv"
+
+	"oneclickvirt/model/common"
+	auth2 "oneclickvirt/service/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserSessions 管理员查看用户登录会话
+// @Summary 管理员查看用户登录会话
+// @Description 获取指定用户未过期的登录会话（登录IP、设备、最近访问时间）
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} common.Response{data=[]auth.SessionInfo} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 500 {object} common.Response "获取失败"
+// @Router /admin/users/{id}/sessions [get]
+func GetUserSessions(c *gin.Context) {
+	if !requireAdminOnly(c) {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeInvalidParam, "无效的用户ID"))
+		return
+	}
+
+	sessionService := auth2.SessionService{}
+	list, err := sessionService.ListSessions(uint(userID), "")
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, list)
+}
+
+// RevokeUserSession 管理员撤销用户的指定会话
+// @Summary 管理员撤销用户的指定会话
+// @Description 撤销指定用户的某个登录会话，该会话的令牌立即失效
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param sessionId path int true "会话ID"
+// @Success 200 {object} common.Response "撤销成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "会话不存在"
+// @Router /admin/users/{id}/sessions/{sessionId} [delete]
+func RevokeUserSession(c *gin.Context) {
+	if !requireAdminOnly(c) {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeInvalidParam, "无效的用户ID"))
+		return
+	}
+
+	if !requireManageableUsers(c, uint(userID)) {
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("sessionId"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeInvalidParam, "无效的会话ID"))
+		return
+	}
+
+	adminUserID, err := getUserIDFromContext(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	sessionService := auth2.SessionService{}
+	if err := sessionService.RevokeSession(uint(userID), uint(sessionID), "admin", adminUserID); err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "会话已撤销")
+}
+
+// RevokeAllUserSessions 管理员撤销用户的所有会话
+// @Summary 管理员撤销用户的所有会话
+// @Description 撤销指定用户的所有登录会话，包括功能上线前签发、没有会话记录的令牌，用户需要重新登录
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} common.Response "撤销成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "用户不存在"
+// @Router /admin/users/{id}/sessions [delete]
+func RevokeAllUserSessions(c *gin.Context) {
+	if !requireAdminOnly(c) {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeInvalidParam, "无效的用户ID"))
+		return
+	}
+
+	if !requireManageableUsers(c, uint(userID)) {
+		return
+	}
+
+	adminUserID, err := getUserIDFromContext(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	sessionService := auth2.SessionService{}
+	if err := sessionService.RevokeAllSessions(uint(userID), "admin", adminUserID); err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "已撤销该用户的所有会话")
+}
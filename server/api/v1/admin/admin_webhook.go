@@ -0,0 +1,237 @@
+package admin
+
+import (
+	"strings"
+
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	"oneclickvirt/model/common"
+	"oneclickvirt/service/webhook"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// respondWebhookError 根据错误信息返回对应的错误码
+func respondWebhookError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "Webhook不存在" || msg == "投递记录不存在":
+		common.ResponseWithError(c, common.NewError(common.CodeNotFound, msg))
+	case strings.Contains(msg, "失败"):
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+	default:
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+	}
+}
+
+// GetWebhookEvents 获取可订阅的事件类型
+// @Summary 获取可订阅的Webhook事件类型
+// @Description 获取Webhook可订阅的事件类型列表，订阅"*"表示接收全部事件
+// @Tags Webhook管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=[]webhook.EventInfo} "获取成功"
+// @Router /admin/webhooks/events [get]
+func GetWebhookEvents(c *gin.Context) {
+	common.ResponseSuccess(c, webhook.SupportedEvents)
+}
+
+// GetWebhooks 获取Webhook列表
+// @Summary 获取Webhook列表
+// @Description 管理员获取所有出站Webhook配置
+// @Tags Webhook管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=[]admin.Webhook} "获取成功"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /admin/webhooks [get]
+func GetWebhooks(c *gin.Context) {
+	webhooks, err := webhook.NewService().ListWebhooks()
+	if err != nil {
+		global.APP_LOG.Error("获取Webhook列表失败", zap.Error(err))
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
+		return
+	}
+	common.ResponseSuccess(c, webhooks)
+}
+
+// CreateWebhook 创建Webhook
+// @Summary 创建Webhook
+// @Description 创建出站Webhook，事件发生时发送带HMAC-SHA256签名的JSON请求。签名密钥留空时自动生成，签名为 HMAC(secret, timestamp + "." + body)，通过X-OneClickVirt-Signature请求头发送
+// @Tags Webhook管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body admin.WebhookRequest true "Webhook配置"
+// @Success 200 {object} common.Response{data=admin.Webhook} "创建成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /admin/webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	var req adminModel.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	createdBy, _ := getUserIDFromContext(c)
+	result, err := webhook.NewService().CreateWebhook(req, createdBy)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, result, "Webhook创建成功")
+}
+
+// UpdateWebhook 更新Webhook
+// @Summary 更新Webhook
+// @Description 更新Webhook配置，签名密钥留空时保持不变
+// @Tags Webhook管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param request body admin.WebhookRequest true "Webhook配置"
+// @Success 200 {object} common.Response{data=admin.Webhook} "更新成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 404 {object} common.Response "Webhook不存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /admin/webhooks/{id} [put]
+func UpdateWebhook(c *gin.Context) {
+	id, ok := parseAdminBackupID(c, "id", "无效的Webhook ID")
+	if !ok {
+		return
+	}
+
+	var req adminModel.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	result, err := webhook.NewService().UpdateWebhook(id, req)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, result, "Webhook更新成功")
+}
+
+// DeleteWebhook 删除Webhook
+// @Summary 删除Webhook
+// @Description 删除Webhook，尚未完成的投递将被取消
+// @Tags Webhook管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} common.Response "删除成功"
+// @Failure 404 {object} common.Response "Webhook不存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /admin/webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	id, ok := parseAdminBackupID(c, "id", "无效的Webhook ID")
+	if !ok {
+		return
+	}
+
+	if err := webhook.NewService().DeleteWebhook(id); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, nil, "Webhook删除成功")
+}
+
+// TestWebhook 发送测试事件
+// @Summary 发送Webhook测试事件
+// @Description 向Webhook同步发送一次webhook.test事件并返回投递结果，测试事件失败不会重试
+// @Tags Webhook管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} common.Response{data=admin.WebhookDelivery} "投递结果"
+// @Failure 404 {object} common.Response "Webhook不存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /admin/webhooks/{id}/test [post]
+func TestWebhook(c *gin.Context) {
+	id, ok := parseAdminBackupID(c, "id", "无效的Webhook ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := webhook.NewService().SendTestEvent(id)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, delivery)
+}
+
+// GetWebhookDeliveries 获取Webhook投递记录
+// @Summary 获取Webhook投递记录
+// @Description 分页获取Webhook的投递记录，可按事件类型和投递状态筛选
+// @Tags Webhook管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(20)
+// @Param eventType query string false "事件类型"
+// @Param status query string false "投递状态：pending, delivering, success, failed"
+// @Success 200 {object} common.Response{data=object} "获取成功"
+// @Failure 404 {object} common.Response "Webhook不存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /admin/webhooks/{id}/deliveries [get]
+func GetWebhookDeliveries(c *gin.Context) {
+	id, ok := parseAdminBackupID(c, "id", "无效的Webhook ID")
+	if !ok {
+		return
+	}
+
+	var req adminModel.WebhookDeliveryListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	deliveries, total, err := webhook.NewService().ListDeliveries(id, req)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, map[string]interface{}{
+		"list":  deliveries,
+		"total": total,
+	})
+}
+
+// RedeliverWebhookDelivery 重新投递
+// @Summary 重新投递Webhook事件
+// @Description 使用原请求体重新投递一次事件，生成新的投递记录并同步返回结果
+// @Tags Webhook管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param deliveryId path int true "投递记录ID"
+// @Success 200 {object} common.Response{data=admin.WebhookDelivery} "投递结果"
+// @Failure 404 {object} common.Response "投递记录不存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /admin/webhook-deliveries/{deliveryId}/redeliver [post]
+func RedeliverWebhookDelivery(c *gin.Context) {
+	id, ok := parseAdminBackupID(c, "deliveryId", "无效的投递记录ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := webhook.NewService().RedeliverDelivery(id)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, delivery)
+}
@@ -2,12 +2,14 @@ package auth
 
 import (
 	auth2 "oneclickvirt/service/auth"
+	"oneclickvirt/service/ldap"
 	"strings"
 
 	"oneclickvirt/global"
 	"oneclickvirt/middleware"
 	"oneclickvirt/model/auth"
 	"oneclickvirt/model/common"
+	userModel "oneclickvirt/model/user"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -15,7 +17,7 @@ import (
 
 // Login 用户登录
 // @Summary 用户登录
-// @Description 用户登录接口，验证用户名密码并返回JWT token
+// @Description 用户登录接口，验证用户名密码并返回JWT token，loginType为ldap时使用LDAP账户登录。启用两步验证的用户返回twoFactorRequired和challengeToken，需调用/auth/login/2fa完成登录
 // @Tags 认证管理
 // @Accept json
 // @Produce json
@@ -32,8 +34,19 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	authService := auth2.AuthService{}
-	user, token, err := authService.Login(req)
+	var user *userModel.User
+	var token string
+	var err error
+	if req.LoginType == "ldap" {
+		ldapService := ldap.Service{}
+		user, token, err = ldapService.Login(req)
+	} else {
+		authService := auth2.AuthService{}
+		user, token, err = authService.Login(req)
+	}
+	if respondTwoFactorChallenge(c, err) {
+		return
+	}
 	if err != nil {
 		global.APP_LOG.Warn("用户登录失败",
 			zap.String("username", req.Username),
@@ -47,6 +60,8 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	recordLoginSession(c, user.ID, token)
+
 	global.APP_LOG.Info("用户登录成功",
 		zap.String("username", req.Username),
 		zap.Uint("user_id", user.ID),
@@ -77,6 +92,10 @@ func ForgotPassword(c *gin.Context) {
 	}
 	authService := auth2.AuthService{}
 	if err := authService.ForgotPassword(req); err != nil {
+		if appErr, ok := err.(*common.AppError); ok && appErr.Code == common.CodeTooManyRequests {
+			common.ResponseWithError(c, appErr)
+			return
+		}
 		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
 		return
 	}
@@ -134,6 +153,9 @@ func Register(c *gin.Context) {
 
 	authService := auth2.AuthService{}
 	user, token, err := authService.RegisterAndLogin(req, c.ClientIP(), c.GetHeader("User-Agent"))
+	if respondTwoFactorChallenge(c, err) {
+		return
+	}
 	if err != nil {
 		global.APP_LOG.Warn("用户注册失败",
 			zap.String("username", req.Username),
@@ -146,6 +168,8 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	recordLoginSession(c, user.ID, token)
+
 	global.APP_LOG.Info("用户注册成功",
 		zap.String("username", req.Username),
 		zap.Uint("user_id", user.ID),
@@ -269,6 +293,10 @@ func SendVerifyCode(c *gin.Context) {
 			zap.String("target", req.Target),
 			zap.String("error", err.Error()),
 			zap.String("ip", c.ClientIP()))
+		if appErr, ok := err.(*common.AppError); ok && appErr.Code == common.CodeTooManyRequests {
+			common.ResponseWithError(c, appErr)
+			return
+		}
 		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
 		return
 	}
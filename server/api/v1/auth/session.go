@@ -0,0 +1,17 @@
+package auth
+
+import (
+	"oneclickvirt/global"
+	auth2 "oneclickvirt/service/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// recordLoginSession 记录登录会话（IP、设备），失败不影响登录
+func recordLoginSession(c *gin.Context, userID uint, token string) {
+	sessionService := auth2.SessionService{}
+	if err := sessionService.RecordSession(token, userID, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		global.APP_LOG.Warn("记录登录会话失败", zap.Uint("userID", userID), zap.Error(err))
+	}
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"errors"
+
+	"oneclickvirt/global"
+	"oneclickvirt/middleware"
+	"oneclickvirt/model/auth"
+	"oneclickvirt/model/common"
+	auth2 "oneclickvirt/service/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// respondTwoFactorChallenge 登录需要两步验证时返回挑战令牌，已响应返回true
+func respondTwoFactorChallenge(c *gin.Context, err error) bool {
+	var challengeErr *auth2.TwoFactorChallengeError
+	if !errors.As(err, &challengeErr) {
+		return false
+	}
+	common.ResponseSuccess(c, auth.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challengeErr.ChallengeToken,
+		EnrollRequired:    challengeErr.EnrollRequired,
+		PasskeyAvailable:  challengeErr.PasskeyAvailable,
+	}, "请完成两步验证")
+	return true
+}
+
+// LoginTwoFactor 两步验证登录
+// @Summary 两步验证登录
+// @Description 使用登录返回的挑战令牌和TOTP验证码（或恢复码）完成登录。管理员强制绑定时，验证通过后同时启用两步验证并返回恢复码
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Param request body auth.TwoFactorLoginRequest true "两步验证登录请求参数"
+// @Success 200 {object} common.Response{data=object} "登录成功，返回用户信息和token"
+// @Failure 400 {object} common.Response "验证码错误"
+// @Failure 401 {object} common.Response "挑战令牌已过期"
+// @Router /auth/login/2fa [post]
+func LoginTwoFactor(c *gin.Context) {
+	var req auth.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, err.Error()))
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请输入验证码或恢复码"))
+		return
+	}
+
+	twoFactorService := auth2.TwoFactorService{}
+	user, token, recoveryCodes, err := twoFactorService.CompleteLogin(req)
+	if err != nil {
+		global.APP_LOG.Warn("两步验证登录失败",
+			zap.String("error", err.Error()),
+			zap.String("ip", c.ClientIP()))
+		common.ResponseWithError(c, err)
+		return
+	}
+
+	recordLoginSession(c, user.ID, token)
+
+	data := gin.H{
+		"user":  user,
+		"token": token,
+	}
+	if len(recoveryCodes) > 0 {
+		data["recoveryCodes"] = recoveryCodes
+	}
+	common.ResponseSuccess(c, data, "登录成功")
+}
+
+// LoginTwoFactorSetup 登录时绑定两步验证
+// @Summary 登录时绑定两步验证
+// @Description 管理员要求启用两步验证但用户尚未绑定时，使用挑战令牌获取TOTP密钥和二维码，绑定后调用/auth/login/2fa完成登录
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Param request body auth.TwoFactorChallengeRequest true "挑战令牌"
+// @Success 200 {object} common.Response{data=auth.TwoFactorSetupResponse} "获取成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "挑战令牌已过期"
+// @Router /auth/login/2fa/setup [post]
+func LoginTwoFactorSetup(c *gin.Context) {
+	var req auth.TwoFactorChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, err.Error()))
+		return
+	}
+
+	twoFactorService := auth2.TwoFactorService{}
+	resp, err := twoFactorService.SetupWithChallenge(req.ChallengeToken)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, resp)
+}
+
+// GetTwoFactorStatus 获取两步验证状态
+// @Summary 获取两步验证状态
+// @Description 获取当前用户是否已启用两步验证、管理员策略是否要求启用以及剩余恢复码数量
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=auth.TwoFactorStatusResponse} "获取成功"
+// @Failure 401 {object} common.Response "用户未认证"
+// @Router /auth/2fa [get]
+func GetTwoFactorStatus(c *gin.Context) {
+	authCtx, exists := middleware.GetAuthContext(c)
+	if !exists {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, "用户未认证"))
+		return
+	}
+
+	twoFactorService := auth2.TwoFactorService{}
+	resp, err := twoFactorService.GetStatus(authCtx.UserID)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, resp)
+}
+
+// SetupTwoFactor 获取两步验证绑定信息
+// @Summary 获取两步验证绑定信息
+// @Description 生成新的TOTP密钥和二维码，使用验证器扫码后调用启用接口确认
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=auth.TwoFactorSetupResponse} "获取成功"
+// @Failure 401 {object} common.Response "用户未认证"
+// @Failure 409 {object} common.Response "两步验证已启用"
+// @Router /auth/2fa/setup [post]
+func SetupTwoFactor(c *gin.Context) {
+	authCtx, exists := middleware.GetAuthContext(c)
+	if !exists {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, "用户未认证"))
+		return
+	}
+
+	twoFactorService := auth2.TwoFactorService{}
+	resp, err := twoFactorService.Setup(authCtx.UserID)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, resp)
+}
+
+// EnableTwoFactor 启用两步验证
+// @Summary 启用两步验证
+// @Description 使用验证器生成的验证码确认绑定并启用两步验证，返回一次性恢复码，请妥善保存
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body auth.TwoFactorCodeRequest true "TOTP验证码"
+// @Success 200 {object} common.Response{data=auth.TwoFactorRecoveryCodesResponse} "启用成功"
+// @Failure 400 {object} common.Response "验证码错误"
+// @Failure 401 {object} common.Response "用户未认证"
+// @Router /auth/2fa/enable [post]
+func EnableTwoFactor(c *gin.Context) {
+	authCtx, exists := middleware.GetAuthContext(c)
+	if !exists {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, "用户未认证"))
+		return
+	}
+
+	var req auth.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, err.Error()))
+		return
+	}
+
+	twoFactorService := auth2.TwoFactorService{}
+	codes, err := twoFactorService.Enable(authCtx.UserID, req.Code)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, auth.TwoFactorRecoveryCodesResponse{RecoveryCodes: codes}, "两步验证已启用")
+}
+
+// DisableTwoFactor 关闭两步验证
+// @Summary 关闭两步验证
+// @Description 使用TOTP验证码或恢复码关闭两步验证。管理员策略要求启用时不允许关闭
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body auth.TwoFactorCodeRequest true "TOTP验证码或恢复码"
+// @Success 200 {object} common.Response "关闭成功"
+// @Failure 400 {object} common.Response "验证码错误"
+// @Failure 401 {object} common.Response "用户未认证"
+// @Failure 403 {object} common.Response "管理员要求启用两步验证"
+// @Router /auth/2fa/disable [post]
+func DisableTwoFactor(c *gin.Context) {
+	authCtx, exists := middleware.GetAuthContext(c)
+	if !exists {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, "用户未认证"))
+		return
+	}
+
+	var req auth.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, err.Error()))
+		return
+	}
+
+	twoFactorService := auth2.TwoFactorService{}
+	if err := twoFactorService.Disable(authCtx.UserID, req.Code, req.RecoveryCode); err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, nil, "两步验证已关闭")
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+// @Summary 重新生成恢复码
+// @Description 使用TOTP验证码确认后重新生成恢复码，旧恢复码全部作废
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body auth.TwoFactorCodeRequest true "TOTP验证码"
+// @Success 200 {object} common.Response{data=auth.TwoFactorRecoveryCodesResponse} "生成成功"
+// @Failure 400 {object} common.Response "验证码错误"
+// @Failure 401 {object} common.Response "用户未认证"
+// @Router /auth/2fa/recovery-codes [post]
+func RegenerateRecoveryCodes(c *gin.Context) {
+	authCtx, exists := middleware.GetAuthContext(c)
+	if !exists {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, "用户未认证"))
+		return
+	}
+
+	var req auth.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, err.Error()))
+		return
+	}
+
+	twoFactorService := auth2.TwoFactorService{}
+	codes, err := twoFactorService.RegenerateRecoveryCodes(authCtx.UserID, req.Code)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, auth.TwoFactorRecoveryCodesResponse{RecoveryCodes: codes}, "恢复码已重新生成")
+}
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"strconv"
+
+	"oneclickvirt/global"
+	"oneclickvirt/middleware"
+	"oneclickvirt/model/auth"
+	"oneclickvirt/model/common"
+	auth2 "oneclickvirt/service/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// LoginPasskeyBegin 开始通行密钥登录
+// @Summary 开始通行密钥登录
+// @Description 获取通行密钥登录参数，前端将options传给navigator.credentials.get，由浏览器选择已保存的通行密钥
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} common.Response{data=auth.PasskeyBeginResponse} "获取成功"
+// @Failure 500 {object} common.Response "未配置通行密钥依赖方"
+// @Router /auth/login/passkey/begin [post]
+func LoginPasskeyBegin(c *gin.Context) {
+	passkeyService := auth2.PasskeyService{}
+	resp, err := passkeyService.BeginLogin()
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, resp)
+}
+
+// LoginPasskeyFinish 完成通行密钥登录
+// @Summary 完成通行密钥登录
+// @Description 提交浏览器返回的断言完成无密码登录。通行密钥要求用户验证，无需再进行两步验证
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Param request body auth.PasskeyFinishRequest true "通行密钥断言"
+// @Success 200 {object} common.Response{data=object} "登录成功，返回用户信息和token"
+// @Failure 400 {object} common.Response "通行密钥验证失败"
+// @Router /auth/login/passkey/finish [post]
+func LoginPasskeyFinish(c *gin.Context) {
+	var req auth.PasskeyFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, err.Error()))
+		return
+	}
+
+	passkeyService := auth2.PasskeyService{}
+	user, token, err := passkeyService.FinishLogin(req)
+	if err != nil {
+		global.APP_LOG.Warn("通行密钥登录失败",
+			zap.String("error", err.Error()),
+			zap.String("ip", c.ClientIP()))
+		common.ResponseWithError(c, err)
+		return
+	}
+	recordLoginSession(c, user.ID, token)
+	common.ResponseSuccess(c, gin.H{
+		"user":  user,
+		"token": token,
+	}, "登录成功")
+}
+
+// LoginTwoFactorPasskeyBegin 开始使用通行密钥完成两步验证
+// @Summary 开始使用通行密钥完成两步验证
+// @Description 登录返回passkeyAvailable时，可使用挑战令牌获取通行密钥断言参数代替TOTP验证码
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Param request body auth.PasskeyTwoFactorBeginRequest true "挑战令牌"
+// @Success 200 {object} common.Response{data=auth.PasskeyBeginResponse} "获取成功"
+// @Failure 401 {object} common.Response "挑战令牌已过期"
+// @Router /auth/login/2fa/passkey/begin [post]
+func LoginTwoFactorPasskeyBegin(c *gin.Context) {
+	var req auth.PasskeyTwoFactorBeginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, err.Error()))
+		return
+	}
+
+	passkeyService := auth2.PasskeyService{}
+	resp, err := passkeyService.BeginTwoFactor(req.ChallengeToken)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, resp)
+}
+
+// LoginTwoFactorPasskeyFinish 使用通行密钥完成两步验证登录
+// @Summary 使用通行密钥完成两步验证登录
+// @Description 提交浏览器返回的断言完成两步验证并获取令牌
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Param request body auth.PasskeyTwoFactorFinishRequest true "挑战令牌和通行密钥断言"
+// @Success 200 {object} common.Response{data=object} "登录成功，返回用户信息和token"
+// @Failure 400 {object} common.Response "通行密钥验证失败"
+// @Failure 401 {object} common.Response "挑战令牌已过期"
+// @Router /auth/login/2fa/passkey/finish [post]
+func LoginTwoFactorPasskeyFinish(c *gin.Context) {
+	var req auth.PasskeyTwoFactorFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, err.Error()))
+		return
+	}
+
+	passkeyService := auth2.PasskeyService{}
+	user, token, err := passkeyService.FinishTwoFactor(req)
+	if err != nil {
+		global.APP_LOG.Warn("通行密钥两步验证登录失败",
+			zap.String("error", err.Error()),
+			zap.String("ip", c.ClientIP()))
+		common.ResponseWithError(c, err)
+		return
+	}
+	recordLoginSession(c, user.ID, token)
+	common.ResponseSuccess(c, gin.H{
+		"user":  user,
+		"token": token,
+	}, "登录成功")
+}
+
+// GetPasskeys 获取通行密钥列表
+// @Summary 获取通行密钥列表
+// @Description 获取当前用户已绑定的通行密钥
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=[]user.UserWebAuthnCredential} "获取成功"
+// @Failure 401 {object} common.Response "用户未认证"
+// @Router /auth/passkeys [get]
+func GetPasskeys(c *gin.Context) {
+	authCtx, exists := middleware.GetAuthContext(c)
+	if !exists {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, "用户未认证"))
+		return
+	}
+
+	passkeyService := auth2.PasskeyService{}
+	list, err := passkeyService.ListPasskeys(authCtx.UserID)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, list)
+}
+
+// BeginPasskeyRegistration 开始注册通行密钥
+// @Summary 开始注册通行密钥
+// @Description 获取通行密钥注册参数，前端将options传给navigator.credentials.create
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body auth.PasskeyRegisterBeginRequest true "通行密钥名称"
+// @Success 200 {object} common.Response{data=auth.PasskeyBeginResponse} "获取成功"
+// @Failure 400 {object} common.Response "通行密钥数量已达上限"
+// @Failure 401 {object} common.Response "用户未认证"
+// @Router /auth/passkeys/register/begin [post]
+func BeginPasskeyRegistration(c *gin.Context) {
+	authCtx, exists := middleware.GetAuthContext(c)
+	if !exists {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, "用户未认证"))
+		return
+	}
+
+	var req auth.PasskeyRegisterBeginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, err.Error()))
+		return
+	}
+
+	passkeyService := auth2.PasskeyService{}
+	resp, err := passkeyService.BeginRegistration(authCtx.UserID, req.Name)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, resp)
+}
+
+// FinishPasskeyRegistration 完成注册通行密钥
+// @Summary 完成注册通行密钥
+// @Description 提交浏览器返回的凭据完成通行密钥注册
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body auth.PasskeyFinishRequest true "通行密钥凭据"
+// @Success 200 {object} common.Response{data=user.UserWebAuthnCredential} "注册成功"
+// @Failure 400 {object} common.Response "通行密钥验证失败"
+// @Failure 401 {object} common.Response "用户未认证"
+// @Router /auth/passkeys/register/finish [post]
+func FinishPasskeyRegistration(c *gin.Context) {
+	authCtx, exists := middleware.GetAuthContext(c)
+	if !exists {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, "用户未认证"))
+		return
+	}
+
+	var req auth.PasskeyFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, err.Error()))
+		return
+	}
+
+	passkeyService := auth2.PasskeyService{}
+	record, err := passkeyService.FinishRegistration(authCtx.UserID, req)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, record, "通行密钥注册成功")
+}
+
+// DeletePasskey 删除通行密钥
+// @Summary 删除通行密钥
+// @Description 删除当前用户的指定通行密钥
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "通行密钥ID"
+// @Success 200 {object} common.Response "删除成功"
+// @Failure 401 {object} common.Response "用户未认证"
+// @Failure 404 {object} common.Response "通行密钥不存在"
+// @Router /auth/passkeys/{id} [delete]
+func DeletePasskey(c *gin.Context) {
+	authCtx, exists := middleware.GetAuthContext(c)
+	if !exists {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, "用户未认证"))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeInvalidParam, "无效的通行密钥ID"))
+		return
+	}
+
+	passkeyService := auth2.PasskeyService{}
+	if err := passkeyService.DeletePasskey(authCtx.UserID, uint(id)); err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, nil, "通行密钥已删除")
+}
@@ -0,0 +1,484 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"oneclickvirt/global"
+	authModel "oneclickvirt/model/auth"
+	userModel "oneclickvirt/model/user"
+	auth2 "oneclickvirt/service/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	testRPID   = "panel.example.com"
+	testOrigin = "https://panel.example.com"
+)
+
+// softAuthenticator 软件实现的通行密钥验证器，使用ES256密钥完成注册和断言
+type softAuthenticator struct {
+	credentialID []byte
+	key          *ecdsa.PrivateKey
+	userHandle   []byte
+	signCount    uint32
+	origin       string
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatalf("生成凭据ID失败: %v", err)
+	}
+	return &softAuthenticator{credentialID: credentialID, key: key, origin: testOrigin}
+}
+
+// authenticatorData 生成验证器数据：RP ID哈希、标志位（UP|UV，注册时附加AT）、签名计数和可选的凭据数据
+func (a *softAuthenticator) authenticatorData(attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	flags := byte(0x01 | 0x04)
+	if attested != nil {
+		flags |= 0x40
+	}
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func (a *softAuthenticator) clientData(ceremony, challenge string) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    a.origin,
+	})
+	return data
+}
+
+// create 模拟navigator.credentials.create，返回浏览器序列化后的凭据
+func (a *softAuthenticator) create(t *testing.T, options json.RawMessage) json.RawMessage {
+	t.Helper()
+	var opts struct {
+		Challenge string `json:"challenge"`
+		User      struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	}
+	if err := json.Unmarshal(options, &opts); err != nil {
+		t.Fatalf("解析注册参数失败: %v", err)
+	}
+	userHandle, err := base64.RawURLEncoding.DecodeString(opts.User.ID)
+	if err != nil {
+		t.Fatalf("解析用户标识失败: %v", err)
+	}
+	a.userHandle = userHandle
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1, // P-256
+		XCoord: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("编码公钥失败: %v", err)
+	}
+	attested := make([]byte, 16) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authenticatorData(attested),
+	})
+	if err != nil {
+		t.Fatalf("编码证明对象失败: %v", err)
+	}
+
+	return mustJSON(t, map[string]interface{}{
+		"id":    b64(a.credentialID),
+		"rawId": b64(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64(a.clientData("webauthn.create", opts.Challenge)),
+			"attestationObject": b64(attestation),
+		},
+	})
+}
+
+// get 模拟navigator.credentials.get，签名计数递增后返回断言
+func (a *softAuthenticator) get(t *testing.T, options json.RawMessage) json.RawMessage {
+	t.Helper()
+	var opts struct {
+		Challenge string `json:"challenge"`
+	}
+	if err := json.Unmarshal(options, &opts); err != nil {
+		t.Fatalf("解析登录参数失败: %v", err)
+	}
+
+	a.signCount++
+	authData := a.authenticatorData(nil)
+	clientData := a.clientData("webauthn.get", opts.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("签名失败: %v", err)
+	}
+
+	return mustJSON(t, map[string]interface{}{
+		"id":    b64(a.credentialID),
+		"rawId": b64(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64(clientData),
+			"authenticatorData": b64(authData),
+			"signature":         b64(signature),
+			"userHandle":        b64(a.userHandle),
+		},
+	})
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func mustJSON(t *testing.T, v interface{}) json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("序列化失败: %v", err)
+	}
+	return data
+}
+
+// setupPasskeyTest 使用内存数据库和与正式路由一致的通行密钥接口，已认证接口固定以userID身份访问
+func setupPasskeyTest(t *testing.T) (*gin.Engine, *userModel.User) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&userModel.User{}, &userModel.UserWebAuthnCredential{}, &authModel.UserSession{}); err != nil {
+		t.Fatalf("初始化测试数据库失败: %v", err)
+	}
+
+	oldDB, oldLog, oldConfig := global.APP_DB, global.APP_LOG, global.APP_CONFIG
+	t.Cleanup(func() {
+		global.APP_DB, global.APP_LOG, global.APP_CONFIG = oldDB, oldLog, oldConfig
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	global.APP_DB = db
+	global.APP_LOG = zap.NewNop()
+	global.APP_CONFIG.System.FrontendURL = testOrigin
+	global.APP_CONFIG.Auth.WebAuthnRPID = ""
+	global.APP_CONFIG.Auth.WebAuthnOrigins = ""
+	global.APP_CONFIG.Auth.TwoFactorRequired = ""
+	global.APP_CONFIG.JWT.SigningKey = "passkey-test-signing-key"
+
+	user := &userModel.User{Username: "alice", Password: "x", Status: 1, UserType: "user"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("创建测试用户失败: %v", err)
+	}
+
+	r := gin.New()
+	authRouter := r.Group("/api/v1/auth")
+	authRouter.POST("login/passkey/begin", LoginPasskeyBegin)
+	authRouter.POST("login/passkey/finish", LoginPasskeyFinish)
+	authRouter.POST("login/2fa/passkey/begin", LoginTwoFactorPasskeyBegin)
+	authRouter.POST("login/2fa/passkey/finish", LoginTwoFactorPasskeyFinish)
+	passkeyRouter := authRouter.Group("passkeys", func(c *gin.Context) {
+		c.Set("auth_context", &authModel.AuthContext{UserID: user.ID, Username: user.Username, UserType: user.UserType})
+		c.Next()
+	})
+	passkeyRouter.GET("", GetPasskeys)
+	passkeyRouter.POST("register/begin", BeginPasskeyRegistration)
+	passkeyRouter.POST("register/finish", FinishPasskeyRegistration)
+	passkeyRouter.DELETE(":id", DeletePasskey)
+
+	return r, user
+}
+
+// testResponse 接口统一响应
+type testResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func doJSON(t *testing.T, r *gin.Engine, method, path string, body interface{}) (int, testResponse) {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		reader = bytes.NewReader(mustJSON(t, body))
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp testResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s 响应解析失败: %v, body: %s", method, path, err, w.Body.String())
+	}
+	return w.Code, resp
+}
+
+// beginResponse 开始注册或登录的响应
+type beginResponse struct {
+	SessionID string          `json:"sessionId"`
+	Options   json.RawMessage `json:"options"`
+}
+
+func begin(t *testing.T, r *gin.Engine, path string, body interface{}) beginResponse {
+	t.Helper()
+	status, resp := doJSON(t, r, http.MethodPost, path, body)
+	if status != http.StatusOK {
+		t.Fatalf("%s 失败: %d %s", path, status, resp.Message)
+	}
+	var result beginResponse
+	if err := json.Unmarshal(resp.Data, &result); err != nil {
+		t.Fatalf("解析 %s 响应失败: %v", path, err)
+	}
+	return result
+}
+
+// registerPasskey 通过注册接口为当前用户绑定验证器
+func registerPasskey(t *testing.T, r *gin.Engine, a *softAuthenticator, name string) {
+	t.Helper()
+	started := begin(t, r, "/api/v1/auth/passkeys/register/begin", map[string]string{"name": name})
+	status, resp := doJSON(t, r, http.MethodPost, "/api/v1/auth/passkeys/register/finish", map[string]interface{}{
+		"sessionId":  started.SessionID,
+		"credential": a.create(t, started.Options),
+	})
+	if status != http.StatusOK {
+		t.Fatalf("注册通行密钥失败: %d %s", status, resp.Message)
+	}
+}
+
+// loginWithPasskey 通过无密码登录接口登录，返回HTTP状态码和响应
+func loginWithPasskey(t *testing.T, r *gin.Engine, a *softAuthenticator) (int, testResponse) {
+	t.Helper()
+	started := begin(t, r, "/api/v1/auth/login/passkey/begin", nil)
+	return doJSON(t, r, http.MethodPost, "/api/v1/auth/login/passkey/finish", map[string]interface{}{
+		"sessionId":  started.SessionID,
+		"credential": a.get(t, started.Options),
+	})
+}
+
+func loginToken(t *testing.T, resp testResponse) string {
+	t.Helper()
+	var data struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil || data.Token == "" {
+		t.Fatalf("登录响应中没有token: %s", resp.Data)
+	}
+	return data.Token
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	r, user := setupPasskeyTest(t)
+
+	first := newSoftAuthenticator(t)
+	registerPasskey(t, r, first, "笔记本")
+
+	// 已绑定的凭据需要出现在第二次注册的排除列表中，避免同一验证器重复注册
+	started := begin(t, r, "/api/v1/auth/passkeys/register/begin", map[string]string{"name": "手机"})
+	var opts struct {
+		ExcludeCredentials []struct {
+			ID string `json:"id"`
+		} `json:"excludeCredentials"`
+	}
+	if err := json.Unmarshal(started.Options, &opts); err != nil {
+		t.Fatalf("解析注册参数失败: %v", err)
+	}
+	if len(opts.ExcludeCredentials) != 1 || opts.ExcludeCredentials[0].ID != b64(first.credentialID) {
+		t.Fatalf("排除列表应只包含已绑定的凭据，实际: %+v", opts.ExcludeCredentials)
+	}
+
+	second := newSoftAuthenticator(t)
+	status, resp := doJSON(t, r, http.MethodPost, "/api/v1/auth/passkeys/register/finish", map[string]interface{}{
+		"sessionId":  started.SessionID,
+		"credential": second.create(t, started.Options),
+	})
+	if status != http.StatusOK {
+		t.Fatalf("注册第二个通行密钥失败: %d %s", status, resp.Message)
+	}
+
+	status, resp = doJSON(t, r, http.MethodGet, "/api/v1/auth/passkeys", nil)
+	if status != http.StatusOK {
+		t.Fatalf("获取通行密钥列表失败: %d %s", status, resp.Message)
+	}
+	var list []userModel.UserWebAuthnCredential
+	if err := json.Unmarshal(resp.Data, &list); err != nil || len(list) != 2 {
+		t.Fatalf("应有两个通行密钥，实际: %s", resp.Data)
+	}
+
+	// 两个通行密钥都可以登录
+	for _, a := range []*softAuthenticator{first, second} {
+		status, resp := loginWithPasskey(t, r, a)
+		if status != http.StatusOK {
+			t.Fatalf("通行密钥登录失败: %d %s", status, resp.Message)
+		}
+		loginToken(t, resp)
+	}
+
+	var record userModel.UserWebAuthnCredential
+	global.APP_DB.Where("user_id = ? AND credential_id = ?", user.ID, b64(second.credentialID)).First(&record)
+	if record.LastUsedAt == nil {
+		t.Fatalf("登录后应记录通行密钥最近使用时间")
+	}
+
+	// 签名计数回退说明凭据可能被克隆，拒绝登录
+	first.signCount = 0
+	if status, _ := loginWithPasskey(t, r, first); status == http.StatusOK {
+		t.Fatalf("签名计数回退时登录应失败")
+	}
+
+	// 未注册的验证器不能登录
+	stranger := newSoftAuthenticator(t)
+	stranger.userHandle = second.userHandle
+	if status, _ := loginWithPasskey(t, r, stranger); status == http.StatusOK {
+		t.Fatalf("未注册的通行密钥登录应失败")
+	}
+}
+
+func TestPasskeyRegistrationRejectsInvalidCredential(t *testing.T) {
+	r, _ := setupPasskeyTest(t)
+
+	// 来源与依赖方不符
+	phishing := newSoftAuthenticator(t)
+	phishing.origin = "https://evil.example.net"
+	started := begin(t, r, "/api/v1/auth/passkeys/register/begin", map[string]string{})
+	status, _ := doJSON(t, r, http.MethodPost, "/api/v1/auth/passkeys/register/finish", map[string]interface{}{
+		"sessionId":  started.SessionID,
+		"credential": phishing.create(t, started.Options),
+	})
+	if status == http.StatusOK {
+		t.Fatalf("来源不符的注册应失败")
+	}
+
+	// 会话只能使用一次
+	a := newSoftAuthenticator(t)
+	started = begin(t, r, "/api/v1/auth/passkeys/register/begin", map[string]string{})
+	credential := a.create(t, started.Options)
+	body := map[string]interface{}{"sessionId": started.SessionID, "credential": credential}
+	if status, resp := doJSON(t, r, http.MethodPost, "/api/v1/auth/passkeys/register/finish", body); status != http.StatusOK {
+		t.Fatalf("注册通行密钥失败: %d %s", status, resp.Message)
+	}
+	if status, _ := doJSON(t, r, http.MethodPost, "/api/v1/auth/passkeys/register/finish", body); status == http.StatusOK {
+		t.Fatalf("重放注册会话应失败")
+	}
+}
+
+func TestPasskeySecondFactor(t *testing.T) {
+	r, user := setupPasskeyTest(t)
+	global.APP_CONFIG.Auth.TwoFactorRequired = "all"
+
+	first := newSoftAuthenticator(t)
+	second := newSoftAuthenticator(t)
+	registerPasskey(t, r, first, "笔记本")
+	registerPasskey(t, r, second, "手机")
+
+	// 密码校验通过后，已绑定通行密钥的用户可用通行密钥完成两步验证，无需绑定TOTP
+	challengeToken := func() string {
+		_, err := auth2.IssueLoginToken(user)
+		var challengeErr *auth2.TwoFactorChallengeError
+		if !errors.As(err, &challengeErr) {
+			t.Fatalf("策略要求两步验证时应返回挑战，实际: %v", err)
+		}
+		if !challengeErr.PasskeyAvailable || challengeErr.EnrollRequired {
+			t.Fatalf("应允许使用通行密钥验证且无需绑定TOTP: %+v", challengeErr)
+		}
+		return challengeErr.ChallengeToken
+	}
+
+	token := challengeToken()
+	started := begin(t, r, "/api/v1/auth/login/2fa/passkey/begin", map[string]string{"challengeToken": token})
+	var opts struct {
+		AllowCredentials []struct {
+			ID string `json:"id"`
+		} `json:"allowCredentials"`
+	}
+	if err := json.Unmarshal(started.Options, &opts); err != nil {
+		t.Fatalf("解析断言参数失败: %v", err)
+	}
+	if len(opts.AllowCredentials) != 2 {
+		t.Fatalf("断言参数应包含用户的全部通行密钥，实际: %+v", opts.AllowCredentials)
+	}
+
+	// 挑战令牌与会话不匹配时拒绝
+	status, _ := doJSON(t, r, http.MethodPost, "/api/v1/auth/login/2fa/passkey/finish", map[string]interface{}{
+		"challengeToken": challengeToken(),
+		"sessionId":      started.SessionID,
+		"credential":     second.get(t, started.Options),
+	})
+	if status == http.StatusOK {
+		t.Fatalf("挑战令牌不匹配时两步验证应失败")
+	}
+
+	// 使用任一已绑定的通行密钥完成两步验证
+	started = begin(t, r, "/api/v1/auth/login/2fa/passkey/begin", map[string]string{"challengeToken": token})
+	status, resp := doJSON(t, r, http.MethodPost, "/api/v1/auth/login/2fa/passkey/finish", map[string]interface{}{
+		"challengeToken": token,
+		"sessionId":      started.SessionID,
+		"credential":     second.get(t, started.Options),
+	})
+	if status != http.StatusOK {
+		t.Fatalf("通行密钥两步验证失败: %d %s", status, resp.Message)
+	}
+	loginToken(t, resp)
+
+	// 挑战完成后作废
+	if status, _ := doJSON(t, r, http.MethodPost, "/api/v1/auth/login/2fa/passkey/begin", map[string]string{"challengeToken": token}); status == http.StatusOK {
+		t.Fatalf("已完成的挑战不能再次使用")
+	}
+
+	// 未绑定的验证器不能作为第二因素
+	token = challengeToken()
+	started = begin(t, r, "/api/v1/auth/login/2fa/passkey/begin", map[string]string{"challengeToken": token})
+	intruder := newSoftAuthenticator(t)
+	intruder.userHandle = first.userHandle
+	status, _ = doJSON(t, r, http.MethodPost, "/api/v1/auth/login/2fa/passkey/finish", map[string]interface{}{
+		"challengeToken": token,
+		"sessionId":      started.SessionID,
+		"credential":     intruder.get(t, started.Options),
+	})
+	if status == http.StatusOK {
+		t.Fatalf("未绑定的通行密钥不能完成两步验证")
+	}
+}
@@ -224,6 +224,9 @@ func syncConfigToGlobalViaService(configData map[string]interface{}) error {
 			if v, ok := authMap["enableOAuth2"].(bool); ok {
 				global.APP_CONFIG.Auth.EnableOAuth2 = v
 			}
+			if v, ok := authMap["enableLDAP"].(bool); ok {
+				global.APP_CONFIG.Auth.EnableLDAP = v
+			}
 			if v, ok := authMap["enablePublicRegistration"].(bool); ok {
 				global.APP_CONFIG.Auth.EnablePublicRegistration = v
 			}
@@ -252,6 +255,9 @@ func syncConfigToGlobalViaService(configData map[string]interface{}) error {
 			if v, ok := authMap["qqAppKey"].(string); ok {
 				global.APP_CONFIG.Auth.QQAppKey = v
 			}
+			if v, ok := authMap["twoFactorRequired"].(string); ok {
+				global.APP_CONFIG.Auth.TwoFactorRequired = v
+			}
 			global.APP_LOG.Info("认证配置已同步到全局配置")
 		}
 	}
@@ -389,6 +395,42 @@ func syncConfigToGlobalViaService(configData map[string]interface{}) error {
 								}
 							}
 
+							// 解析 maxSnapshots
+							if maxSnapshots, exists := limitMap["maxSnapshots"]; exists {
+								if v, ok := maxSnapshots.(float64); ok {
+									levelLimit.MaxSnapshots = int(v)
+								} else if v, ok := maxSnapshots.(int); ok {
+									levelLimit.MaxSnapshots = v
+								}
+							}
+
+							// 解析 maxBackups
+							if maxBackups, exists := limitMap["maxBackups"]; exists {
+								if v, ok := maxBackups.(float64); ok {
+									levelLimit.MaxBackups = int(v)
+								} else if v, ok := maxBackups.(int); ok {
+									levelLimit.MaxBackups = v
+								}
+							}
+
+							// 解析 maxPorts
+							if maxPorts, exists := limitMap["maxPorts"]; exists {
+								if v, ok := maxPorts.(float64); ok {
+									levelLimit.MaxPorts = int(v)
+								} else if v, ok := maxPorts.(int); ok {
+									levelLimit.MaxPorts = v
+								}
+							}
+
+							// 解析 maxDomains
+							if maxDomains, exists := limitMap["maxDomains"]; exists {
+								if v, ok := maxDomains.(float64); ok {
+									levelLimit.MaxDomains = int(v)
+								} else if v, ok := maxDomains.(int); ok {
+									levelLimit.MaxDomains = v
+								}
+							}
+
 							global.APP_CONFIG.Quota.LevelLimits[level] = levelLimit
 						}
 					}
@@ -428,6 +470,10 @@ func convertLevelLimitsToMap(levelLimits map[int]config.LevelLimitInfo) map[stri
 		limitMap := map[string]interface{}{
 			"maxInstances": limitInfo.MaxInstances,
 			"maxTraffic":   limitInfo.MaxTraffic,
+			"maxSnapshots": limitInfo.MaxSnapshots,
+			"maxBackups":   limitInfo.MaxBackups,
+			"maxPorts":     limitInfo.MaxPorts,
+			"maxDomains":   limitInfo.MaxDomains,
 		}
 
 		if limitInfo.MaxResources != nil {
@@ -458,6 +504,10 @@ func getUserConfig(cm *config.ConfigManager, authCtx *authModel.AuthContext) map
 			"maxInstances": limitInfo.MaxInstances,
 			"maxResources": limitInfo.MaxResources,
 			"maxTraffic":   limitInfo.MaxTraffic,
+			"maxSnapshots": limitInfo.MaxSnapshots,
+			"maxBackups":   limitInfo.MaxBackups,
+			"maxPorts":     limitInfo.MaxPorts,
+			"maxDomains":   limitInfo.MaxDomains,
 		}
 	}
 
@@ -490,6 +540,7 @@ func getAdminConfig(cm *config.ConfigManager) map[string]interface{} {
 		"enableTelegram":           global.APP_CONFIG.Auth.EnableTelegram,
 		"enableQQ":                 global.APP_CONFIG.Auth.EnableQQ,
 		"enableOAuth2":             global.APP_CONFIG.Auth.EnableOAuth2,
+		"enableLDAP":               global.APP_CONFIG.Auth.EnableLDAP,
 		"enablePublicRegistration": global.APP_CONFIG.Auth.EnablePublicRegistration,
 		"emailSMTPHost":            global.APP_CONFIG.Auth.EmailSMTPHost,
 		"emailSMTPPort":            global.APP_CONFIG.Auth.EmailSMTPPort,
@@ -498,6 +549,7 @@ func getAdminConfig(cm *config.ConfigManager) map[string]interface{} {
 		"telegramBotToken":         global.APP_CONFIG.Auth.TelegramBotToken,
 		"qqAppID":                  global.APP_CONFIG.Auth.QQAppID,
 		"qqAppKey":                 global.APP_CONFIG.Auth.QQAppKey,
+		"twoFactorRequired":        global.APP_CONFIG.Auth.TwoFactorRequired,
 	}
 
 	// 邀请码配置
@@ -514,6 +566,10 @@ func getAdminConfig(cm *config.ConfigManager) map[string]interface{} {
 			"maxInstances": limitInfo.MaxInstances,
 			"maxResources": limitInfo.MaxResources,
 			"maxTraffic":   limitInfo.MaxTraffic,
+			"maxSnapshots": limitInfo.MaxSnapshots,
+			"maxBackups":   limitInfo.MaxBackups,
+			"maxPorts":     limitInfo.MaxPorts,
+			"maxDomains":   limitInfo.MaxDomains,
 		}
 	}
 
@@ -1,12 +1,14 @@
 package oauth2
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"oneclickvirt/global"
 	"oneclickvirt/model/common"
 	oauth2Model "oneclickvirt/model/oauth2"
+	auth2 "oneclickvirt/service/auth"
 	oauth2Svc "oneclickvirt/service/oauth2"
 	"strconv"
 
@@ -60,21 +62,21 @@ func OAuth2Login(c *gin.Context) {
 		return
 	}
 
-	// 生成state令牌
-	state, err := oauthService.GenerateStateToken(provider.ID)
+	// 生成state令牌和OAuth2授权URL，按配置附带PKCE挑战和OIDC nonce
+	authURL, err := oauthService.BuildAuthCodeURL(provider)
 	if err != nil {
-		global.APP_LOG.Error("生成state令牌失败", zap.Error(err))
-		common.ResponseWithError(c, common.NewError(common.CodeInternalError, "生成令牌失败"))
+		global.APP_LOG.Error("生成OAuth2授权地址失败", zap.Error(err))
+		if customErr, ok := err.(*common.AppError); ok {
+			common.ResponseWithError(c, customErr)
+		} else {
+			common.ResponseWithError(c, common.NewError(common.CodeInternalError, "生成令牌失败"))
+		}
 		return
 	}
 
-	// 生成OAuth2授权URL
-	oauth2Cfg := oauthService.GetOAuth2Config(provider)
-	authURL := oauth2Cfg.AuthCodeURL(state)
-
 	global.APP_LOG.Info("OAuth2登录跳转",
 		zap.String("provider", provider.Name),
-		zap.String("state", state))
+		zap.String("providerType", provider.ProviderType))
 
 	c.Redirect(302, authURL)
 }
@@ -105,16 +107,33 @@ func OAuth2Callback(c *gin.Context) {
 	}
 
 	// 验证state令牌
-	providerID, valid := oauthService.ValidateStateToken(state)
+	stateInfo, valid := oauthService.ValidateStateToken(state)
 	if !valid {
 		global.APP_LOG.Error("无效的state令牌", zap.String("state", state))
 		common.ResponseWithError(c, common.NewError(common.CodeOAuth2Failed, "无效的state令牌"))
 		return
 	}
 
+	// 绑定模式：将提供商账号绑定到发起绑定的已登录用户
+	if stateInfo.LinkUserID > 0 {
+		renderLinkResult(c, stateInfo, code)
+		return
+	}
+
 	// 处理回调
-	usr, token, err := oauthService.HandleCallback(providerID, code)
-	if err != nil {
+	providerID := stateInfo.ProviderID
+	usr, token, err := oauthService.HandleCallback(stateInfo, code)
+
+	// 需要两步验证时，携带挑战令牌跳转到前端完成验证
+	var redirectQuery, heading string
+	var challengeErr *auth2.TwoFactorChallengeError
+	if errors.As(err, &challengeErr) {
+		global.APP_LOG.Info("OAuth2登录需要两步验证", zap.Uint("provider_id", providerID))
+		redirectQuery = "oauth2_2fa_challenge=" + url.QueryEscape(challengeErr.ChallengeToken) +
+			"&enroll_required=" + strconv.FormatBool(challengeErr.EnrollRequired) +
+			"&passkey_available=" + strconv.FormatBool(challengeErr.PasskeyAvailable)
+		heading = "需要两步验证"
+	} else if err != nil {
 		global.APP_LOG.Error("OAuth2回调处理失败",
 			zap.Uint("provider_id", providerID),
 			zap.Error(err))
@@ -126,13 +145,46 @@ func OAuth2Callback(c *gin.Context) {
 			common.ResponseWithError(c, common.NewError(common.CodeOAuth2Failed, "认证失败"))
 		}
 		return
+	} else {
+		global.APP_LOG.Info("OAuth2登录成功",
+			zap.Uint("provider_id", providerID),
+			zap.String("username", usr.Username),
+			zap.Uint("user_id", usr.ID))
+		sessionService := auth2.SessionService{}
+		if err := sessionService.RecordSession(token, usr.ID, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+			global.APP_LOG.Warn("记录登录会话失败", zap.Uint("user_id", usr.ID), zap.Error(err))
+		}
+		redirectQuery = "oauth2_token=" + url.QueryEscape(token) + "&username=" + url.QueryEscape(usr.Username)
+		heading = "登录成功"
 	}
 
-	global.APP_LOG.Info("OAuth2登录成功",
-		zap.Uint("provider_id", providerID),
-		zap.String("username", usr.Username),
-		zap.Uint("user_id", usr.ID))
+	renderCallbackRedirect(c, heading, redirectQuery)
+}
+
+// renderLinkResult 处理绑定模式的回调，结果通过URL参数带回前端，不签发登录令牌
+func renderLinkResult(c *gin.Context, stateInfo *oauth2Svc.StateInfo, code string) {
+	provider, err := oauthService.LinkIdentity(stateInfo, code)
+	if err != nil {
+		global.APP_LOG.Warn("OAuth2账号绑定失败",
+			zap.Uint("provider_id", stateInfo.ProviderID),
+			zap.Uint("user_id", stateInfo.LinkUserID),
+			zap.Error(err))
+		msg := "绑定失败"
+		if customErr, ok := err.(*common.AppError); ok {
+			msg = customErr.Message
+			if customErr.Details != "" {
+				msg = customErr.Details
+			}
+		}
+		renderCallbackRedirect(c, "绑定失败", "oauth2_link_error="+url.QueryEscape(msg))
+		return
+	}
+
+	renderCallbackRedirect(c, "绑定成功", "oauth2_linked="+url.QueryEscape(provider.Name))
+}
 
+// renderCallbackRedirect 返回跳转到前端的中间页面，query为附加在前端地址后的URL参数
+func renderCallbackRedirect(c *gin.Context, heading, redirectQuery string) {
 	// 获取前端URL配置，如果没有配置，尝试智能检测
 	frontendURL := global.APP_CONFIG.System.FrontendURL
 
@@ -192,14 +244,13 @@ func OAuth2Callback(c *gin.Context) {
 <body>
     <div class="container">
         <div class="spinner"></div>
-        <h2>登录成功</h2>
+        <h2>%s</h2>
         <p>正在跳转到应用...</p>
     </div>
     <script>
         (function() {
             try {
-                var token = '%s';
-                var username = '%s';
+                var query = '%s';
                 var configuredFrontendURL = '%s';
                 
                 // 使用配置的前端URL
@@ -215,9 +266,8 @@ func OAuth2Callback(c *gin.Context) {
                     frontendURL += '/';
                 }
                 
-                // 将token作为URL参数传递（解决跨域localStorage隔离问题）
-                var redirectURL = frontendURL + '?oauth2_token=' + encodeURIComponent(token) + 
-                                  '&username=' + encodeURIComponent(username);
+                // 将token或两步验证挑战令牌作为URL参数传递（解决跨域localStorage隔离问题）
+                var redirectURL = frontendURL + '?' + query;
                 
                 console.log('OAuth2跳转到:', redirectURL);
                 
@@ -232,7 +282,7 @@ func OAuth2Callback(c *gin.Context) {
         })();
     </script>
 </body>
-</html>`, token, url.QueryEscape(usr.Username), frontendURL)
+</html>`, heading, redirectQuery, frontendURL)
 
 	c.Header("Content-Type", "text/html; charset=utf-8")
 	c.String(http.StatusOK, html)
@@ -3,6 +3,7 @@ package oauth2
 import (
 	"oneclickvirt/global"
 	"oneclickvirt/model/common"
+	oauth2Model "oneclickvirt/model/oauth2"
 	oauth2Service "oneclickvirt/service/oauth2"
 	"strconv"
 
@@ -98,9 +99,39 @@ func GetProvider(c *gin.Context) {
 	common.ResponseSuccess(c, provider)
 }
 
+// DiscoverProvider 获取OIDC发现文档
+// @Summary 获取OIDC发现文档
+// @Description 从签发者的.well-known/openid-configuration获取端点和支持的声明，用于填写OIDC提供商配置
+// @Tags OAuth2
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body oauth2Service.DiscoverRequest true "签发者地址"
+// @Success 200 {object} common.Response{data=oauth2Service.DiscoveryResult}
+// @Router /oauth2/providers/discover [post]
+func DiscoverProvider(c *gin.Context) {
+	var req oauth2Service.DiscoverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "参数错误"))
+		return
+	}
+
+	providerService := oauth2Service.ProviderService{}
+	result, err := providerService.Discover(req.IssuerURL)
+	if err != nil {
+		if appErr, ok := err.(*common.AppError); ok {
+			common.ResponseWithError(c, appErr)
+		} else {
+			common.ResponseWithError(c, common.NewError(common.CodeInternalError, "OIDC发现失败"))
+		}
+		return
+	}
+	common.ResponseSuccess(c, result)
+}
+
 // CreateProvider 创建OAuth2提供商
 // @Summary 创建OAuth2提供商
-// @Description 创建新的OAuth2提供商配置
+// @Description 创建新的OAuth2提供商配置，providerType为oidc时只需填写issuerUrl，端点通过发现获取
 // @Tags OAuth2
 // @Accept json
 // @Produce json
@@ -115,18 +146,20 @@ func CreateProvider(c *gin.Context) {
 		return
 	}
 
-	// 设置默认值
-	if req.UserIDField == "" {
-		req.UserIDField = "id"
-	}
-	if req.UsernameField == "" {
-		req.UsernameField = "username"
-	}
-	if req.EmailField == "" {
-		req.EmailField = "email"
-	}
-	if req.AvatarField == "" {
-		req.AvatarField = "avatar"
+	// 设置默认值，OIDC提供商使用标准声明作为默认字段映射
+	if req.ProviderType != oauth2Model.ProviderTypeOIDC {
+		if req.UserIDField == "" {
+			req.UserIDField = "id"
+		}
+		if req.UsernameField == "" {
+			req.UsernameField = "username"
+		}
+		if req.EmailField == "" {
+			req.EmailField = "email"
+		}
+		if req.AvatarField == "" {
+			req.AvatarField = "avatar"
+		}
 	}
 	if req.DefaultLevel == 0 {
 		req.DefaultLevel = 1
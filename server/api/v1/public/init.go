@@ -344,6 +344,7 @@ func GetRegisterConfig(c *gin.Context) {
 			"enabled": global.APP_CONFIG.InviteCode.Enabled,
 		},
 		"oauth2Enabled": global.APP_CONFIG.Auth.EnableOAuth2,
+		"ldapEnabled":   global.APP_CONFIG.Auth.EnableLDAP,
 	}
 	c.JSON(http.StatusOK, common.Success(config))
 }
@@ -611,7 +611,7 @@ func CancelUserTask(c *gin.Context) {
 
 // CreateUserInstance 创建实例
 // @Summary 创建实例
-// @Description 用户创建新的虚拟机或容器实例（异步处理）
+// @Description 用户创建新的虚拟机或容器实例（异步处理），可选择已保存的SSH公钥注入实例，并可禁用密码登录；userData可提供首次启动时执行的cloud-config或shell脚本，重置实例时会重新执行
 // @Tags 用户管理
 // @Accept json
 // @Produce json
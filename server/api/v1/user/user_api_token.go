@@ -0,0 +1,111 @@
+package user
+
+import (
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	"oneclickvirt/model/user"
+	auth2 "oneclickvirt/service/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetAPITokens 获取个人API令牌列表
+// @Summary 获取个人API令牌列表
+// @Description 获取当前用户创建的个人API令牌（不含明文）以及可选的权限范围
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=user.APITokenListResponse} "获取成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/api-tokens [get]
+func GetAPITokens(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	apiTokenService := auth2.APITokenService{}
+	resp, err := apiTokenService.ListTokens(userID)
+	if err != nil {
+		global.APP_LOG.Error("获取API令牌列表失败", zap.Uint("userID", userID), zap.Error(err))
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
+		return
+	}
+
+	common.ResponseSuccess(c, resp)
+}
+
+// CreateAPIToken 创建个人API令牌
+// @Summary 创建个人API令牌
+// @Description 创建用于脚本等自动化场景的长期API令牌，请求时使用 Authorization: Bearer <token>。令牌明文只在创建时返回一次
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body user.CreateAPITokenRequest true "API令牌参数"
+// @Success 200 {object} common.Response{data=user.CreateAPITokenResponse} "创建成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 409 {object} common.Response "令牌数量已达上限"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/api-tokens [post]
+func CreateAPIToken(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	var req user.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	apiTokenService := auth2.APITokenService{}
+	resp, err := apiTokenService.CreateToken(userID, req)
+	if err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, resp, "API令牌创建成功，请立即保存，令牌只显示一次")
+}
+
+// RevokeAPIToken 撤销个人API令牌
+// @Summary 撤销个人API令牌
+// @Description 撤销指定的个人API令牌，撤销后立即失效
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "令牌ID"
+// @Success 200 {object} common.Response "撤销成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 404 {object} common.Response "令牌不存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/api-tokens/{id} [delete]
+func RevokeAPIToken(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	tokenID, ok := parseUintParam(c, "id", "无效的令牌ID")
+	if !ok {
+		return
+	}
+
+	apiTokenService := auth2.APITokenService{}
+	if err := apiTokenService.RevokeToken(userID, tokenID); err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "API令牌已撤销")
+}
@@ -0,0 +1,311 @@
+package user
+
+import (
+	"strconv"
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	"oneclickvirt/model/user"
+	userService "oneclickvirt/service/user"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// parseUintParam 解析路径中的ID参数
+func parseUintParam(c *gin.Context, name, errMsg string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, errMsg))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondBackupError 根据错误信息返回对应的错误码
+func respondBackupError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "实例不存在或无权限":
+		common.ResponseWithError(c, common.NewError(common.CodeForbidden, msg))
+	case msg == "备份不存在" || msg == "定时备份计划不存在":
+		common.ResponseWithError(c, common.NewError(common.CodeNotFound, msg))
+	case strings.Contains(msg, "上限") || strings.Contains(msg, "正在进行"):
+		common.ResponseWithError(c, common.NewError(common.CodeConflict, msg))
+	case strings.Contains(msg, "不允许") || strings.Contains(msg, "不可用") || strings.Contains(msg, "不合法") ||
+		strings.Contains(msg, "不能超过") || strings.Contains(msg, "节点"):
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+	default:
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+	}
+}
+
+// GetUserBackups 获取用户备份列表
+// @Summary 获取用户备份列表
+// @Description 获取当前用户的实例备份列表及当前等级允许的备份数量，可按实例过滤
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param instanceId query int false "实例ID"
+// @Success 200 {object} common.Response{data=user.BackupListResponse} "获取成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/backups [get]
+func GetUserBackups(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	var instanceID uint
+	if idStr := c.Query("instanceId"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			common.ResponseWithError(c, common.NewError(common.CodeValidationError, "无效的实例ID"))
+			return
+		}
+		instanceID = uint(id)
+	}
+
+	result, err := userService.NewService().ListBackups(userID, instanceID)
+	if err != nil {
+		respondBackupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result)
+}
+
+// CreateInstanceBackup 创建实例备份
+// @Summary 创建实例备份
+// @Description 导出实例到本地备份存储，受用户等级备份数量限制，创建异步任务执行
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param request body user.CreateBackupRequest true "创建备份请求参数"
+// @Success 200 {object} common.Response{data=user.BackupTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "实例不存在或无权限"
+// @Failure 409 {object} common.Response "备份数量已达上限或已有备份任务"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /user/instances/{id}/backups [post]
+func CreateInstanceBackup(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, ok := parseUintParam(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	var req user.CreateBackupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	result, err := userService.NewService().CreateInstanceBackup(userID, instanceID, req)
+	if err != nil {
+		global.APP_LOG.Error("创建实例备份失败",
+			zap.Uint("userID", userID),
+			zap.Uint("instanceID", instanceID),
+			zap.Error(err))
+		respondBackupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result, "备份创建任务已提交")
+}
+
+// RestoreBackup 将备份恢复为新实例
+// @Summary 将备份恢复为新实例
+// @Description 在备份所在节点上使用备份创建一个新实例，占用用户的实例配额，创建异步任务执行
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param backupId path int true "备份ID"
+// @Success 200 {object} common.Response{data=user.BackupTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误或备份不可用"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 404 {object} common.Response "备份不存在"
+// @Failure 409 {object} common.Response "实例数量已达上限"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /user/backups/{backupId}/restore [post]
+func RestoreBackup(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	backupID, ok := parseUintParam(c, "backupId", "无效的备份ID")
+	if !ok {
+		return
+	}
+
+	result, err := userService.NewService().RestoreBackupAsNewInstance(userID, backupID)
+	if err != nil {
+		global.APP_LOG.Error("恢复备份失败",
+			zap.Uint("userID", userID),
+			zap.Uint("backupID", backupID),
+			zap.Error(err))
+		respondBackupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result, "备份恢复任务已提交")
+}
+
+// DeleteBackup 删除备份
+// @Summary 删除备份
+// @Description 删除当前用户的备份及其备份文件
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param backupId path int true "备份ID"
+// @Success 200 {object} common.Response "删除成功"
+// @Failure 400 {object} common.Response "参数错误或备份正在使用"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 404 {object} common.Response "备份不存在"
+// @Failure 500 {object} common.Response "删除失败"
+// @Router /user/backups/{backupId} [delete]
+func DeleteBackup(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	backupID, ok := parseUintParam(c, "backupId", "无效的备份ID")
+	if !ok {
+		return
+	}
+
+	if err := userService.NewService().DeleteBackup(userID, backupID); err != nil {
+		respondBackupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "备份已删除")
+}
+
+// GetBackupSchedule 获取实例定时备份计划
+// @Summary 获取实例定时备份计划
+// @Description 获取当前用户指定实例的定时备份计划，未设置时返回空
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Success 200 {object} common.Response{data=provider.BackupSchedule} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "实例不存在或无权限"
+// @Router /user/instances/{id}/backup-schedule [get]
+func GetBackupSchedule(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, ok := parseUintParam(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	schedule, err := userService.NewService().GetBackupSchedule(userID, instanceID)
+	if err != nil {
+		respondBackupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, schedule)
+}
+
+// SaveBackupSchedule 设置实例定时备份计划
+// @Summary 设置实例定时备份计划
+// @Description 创建或更新实例的定时备份计划，Cron表达式格式为"分 时 日 月 周"，超出保留数量的定时备份会被自动清理
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param request body user.BackupScheduleRequest true "定时备份计划参数"
+// @Success 200 {object} common.Response{data=provider.BackupSchedule} "保存成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "实例不存在或无权限"
+// @Failure 500 {object} common.Response "保存失败"
+// @Router /user/instances/{id}/backup-schedule [put]
+func SaveBackupSchedule(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, ok := parseUintParam(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	var req user.BackupScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	schedule, err := userService.NewService().SaveBackupSchedule(userID, instanceID, req)
+	if err != nil {
+		respondBackupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, schedule, "定时备份计划已保存")
+}
+
+// DeleteBackupSchedule 删除实例定时备份计划
+// @Summary 删除实例定时备份计划
+// @Description 删除实例的定时备份计划，已生成的备份保留
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Success 200 {object} common.Response "删除成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "实例不存在或无权限"
+// @Failure 404 {object} common.Response "定时备份计划不存在"
+// @Router /user/instances/{id}/backup-schedule [delete]
+func DeleteBackupSchedule(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, ok := parseUintParam(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	if err := userService.NewService().DeleteBackupSchedule(userID, instanceID); err != nil {
+		respondBackupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "定时备份计划已删除")
+}
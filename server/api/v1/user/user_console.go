@@ -0,0 +1,148 @@
+package user
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	"oneclickvirt/provider"
+	userService "oneclickvirt/service/user"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+)
+
+// CreateInstanceConsole 创建实例图形控制台会话
+// @Summary 创建实例图形控制台会话
+// @Description 为运行中的虚拟机建立VNC（Proxmox）或SPICE（LXD/Incus）控制台连接，返回一次性票据和控制台密码，票据需在有效期内通过控制台WebSocket接口使用
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Success 200 {object} common.Response{data=user.ConsoleResponse} "创建成功"
+// @Failure 400 {object} common.Response "参数错误或实例不支持控制台"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "实例不存在或无权限"
+// @Failure 500 {object} common.Response "打开控制台失败"
+// @Router /user/instances/{id}/console [post]
+func CreateInstanceConsole(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, ok := parseUintParam(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	result, err := userService.NewService().CreateInstanceConsole(userID, instanceID)
+	if err != nil {
+		msg := err.Error()
+		switch {
+		case msg == "实例不存在或无权限":
+			common.ResponseWithError(c, common.NewError(common.CodeForbidden, msg))
+		case strings.Contains(msg, "未运行") || strings.Contains(msg, "不支持"):
+			common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+		default:
+			global.APP_LOG.Error("创建实例控制台会话失败",
+				zap.Uint("userID", userID),
+				zap.Uint("instanceID", instanceID),
+				zap.Error(err))
+			common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+		}
+		return
+	}
+
+	common.ResponseSuccess(c, result)
+}
+
+// AttachInstanceConsole 连接实例图形控制台
+// @Summary 连接实例图形控制台
+// @Description 通过WebSocket接入已创建的控制台会话，以二进制帧透传VNC/SPICE协议数据，可直接供noVNC或spice-html5使用。浏览器无法设置请求头，可通过ws_ticket查询参数传递WebSocket连接票据（见签发WebSocket连接票据接口）
+// @Tags 用户管理
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param ticket query string true "控制台会话票据"
+// @Param ws_ticket query string false "WebSocket连接票据"
+// @Success 101 {string} string "切换到WebSocket协议"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 404 {object} common.Response "控制台会话不存在或已过期"
+// @Router /user/instances/{id}/console/ws [get]
+func AttachInstanceConsole(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, ok := parseUintParam(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	if !c.IsWebsocket() {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请使用WebSocket连接"))
+		return
+	}
+
+	stream, err := userService.NewService().AttachInstanceConsole(userID, instanceID, c.Query("ticket"))
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeNotFound, err.Error()))
+		return
+	}
+	defer stream.Conn.Close()
+
+	server := websocket.Server{
+		// noVNC和spice-html5使用binary子协议，票据已校验会话归属，这里不再校验Origin
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			protocols := config.Protocol
+			config.Protocol = nil
+			for _, protocol := range protocols {
+				if protocol == "binary" {
+					config.Protocol = []string{"binary"}
+				}
+			}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			serveConsole(ws, stream)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+
+	global.APP_LOG.Info("实例控制台会话结束",
+		zap.Uint("userID", userID),
+		zap.Uint("instanceID", instanceID))
+}
+
+// serveConsole 在浏览器WebSocket和控制台上游连接之间透传数据，任一方向结束即关闭会话
+func serveConsole(ws *websocket.Conn, stream *provider.ConsoleStream) {
+	ws.PayloadType = websocket.BinaryFrame
+	start := time.Now()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(stream.Conn, ws)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(ws, stream.Conn)
+		done <- struct{}{}
+	}()
+
+	<-done
+	ws.Close()
+	stream.Conn.Close()
+
+	global.APP_LOG.Debug("控制台数据转发结束",
+		zap.String("protocol", stream.Protocol),
+		zap.Duration("duration", time.Since(start)))
+}
@@ -0,0 +1,174 @@
+package user
+
+import (
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/service/domain"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// respondDomainError 将域名绑定操作错误转换为对应的响应码
+func respondDomainError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "实例不存在或无权限":
+		common.ResponseWithError(c, common.NewError(common.CodeForbidden, msg))
+	case msg == "域名绑定不存在":
+		common.ResponseWithError(c, common.NewError(common.CodeNotFound, msg))
+	case strings.Contains(msg, "上限") || strings.Contains(msg, "已被绑定") || strings.Contains(msg, "无需重复"):
+		common.ResponseWithError(c, common.NewError(common.CodeConflict, msg))
+	case strings.Contains(msg, "未启用") || strings.Contains(msg, "不合法") || strings.Contains(msg, "支持") ||
+		strings.Contains(msg, "记录") || strings.Contains(msg, "解析") || strings.Contains(msg, "节点不存在"):
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+	default:
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+	}
+}
+
+// GetUserDomains 获取用户的域名绑定
+// @Summary 获取域名绑定列表
+// @Description 获取当前用户的全部域名绑定及当前等级可绑定的数量，待验证的绑定附带需要添加的TXT记录
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=userModel.DomainListResponse} "获取成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/domains [get]
+func GetUserDomains(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	result, err := domain.NewService().ListUserDomains(userID)
+	if err != nil {
+		global.APP_LOG.Error("获取域名绑定失败", zap.Uint("userID", userID), zap.Error(err))
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, "获取域名绑定失败"))
+		return
+	}
+
+	common.ResponseSuccess(c, result)
+}
+
+// CreateUserDomain 添加域名绑定
+// @Summary 添加域名绑定
+// @Description 将域名绑定到当前用户NAT实例的HTTP端口，由节点上的Caddy反向代理并自动申请HTTPS证书。创建后需按返回的TXT记录和目标IP配置DNS，再调用验证接口生效，数量受用户等级限制
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body userModel.CreateDomainRequest true "域名绑定参数"
+// @Success 200 {object} common.Response{data=userModel.DomainBindingInfo} "创建成功"
+// @Failure 400 {object} common.Response "参数错误或实例不支持"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "无权限访问此实例"
+// @Failure 409 {object} common.Response "数量已达上限或域名已被绑定"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/domains [post]
+func CreateUserDomain(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	var req userModel.CreateDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	result, err := domain.NewService().CreateDomain(userID, req)
+	if err != nil {
+		global.APP_LOG.Warn("添加域名绑定失败",
+			zap.Uint("userID", userID),
+			zap.String("domain", req.Domain),
+			zap.Error(err))
+		respondDomainError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result, "域名绑定已创建，请配置DNS后进行验证")
+}
+
+// VerifyUserDomain 验证域名绑定
+// @Summary 验证域名绑定
+// @Description 检查TXT记录与A/AAAA记录，通过后在节点上生效；配置失败的绑定也可再次调用重试
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "域名绑定ID"
+// @Success 200 {object} common.Response{data=userModel.DomainBindingInfo} "验证通过"
+// @Failure 400 {object} common.Response "DNS记录未生效"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 404 {object} common.Response "域名绑定不存在"
+// @Failure 409 {object} common.Response "域名已被绑定或已生效"
+// @Failure 500 {object} common.Response "节点配置失败"
+// @Router /user/domains/{id}/verify [post]
+func VerifyUserDomain(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	id, ok := parseUintParam(c, "id", "无效的域名绑定ID")
+	if !ok {
+		return
+	}
+
+	result, err := domain.NewService().VerifyDomain(c.Request.Context(), userID, id)
+	if err != nil {
+		global.APP_LOG.Warn("验证域名绑定失败",
+			zap.Uint("userID", userID),
+			zap.Uint("domainID", id),
+			zap.Error(err))
+		respondDomainError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result, "域名绑定已生效")
+}
+
+// DeleteUserDomain 删除域名绑定
+// @Summary 删除域名绑定
+// @Description 删除当前用户的域名绑定，已生效的绑定会在后台从节点配置中移除
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "域名绑定ID"
+// @Success 200 {object} common.Response "删除成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 404 {object} common.Response "域名绑定不存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/domains/{id} [delete]
+func DeleteUserDomain(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	id, ok := parseUintParam(c, "id", "无效的域名绑定ID")
+	if !ok {
+		return
+	}
+
+	if err := domain.NewService().DeleteDomain(userID, id); err != nil {
+		respondDomainError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "域名绑定已删除")
+}
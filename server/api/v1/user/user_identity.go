@@ -0,0 +1,114 @@
+package user
+
+import (
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	oauth2Svc "oneclickvirt/service/oauth2"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetIdentities 获取已绑定的第三方登录身份
+// @Summary 获取已绑定的第三方登录身份
+// @Description 获取当前用户已绑定的OAuth2账号，以及已启用、可供绑定的提供商列表
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=oauth2.IdentityListResponse} "获取成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/identities [get]
+func GetIdentities(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	identityService := oauth2Svc.IdentityService{}
+	list, err := identityService.ListIdentities(userID)
+	if err != nil {
+		global.APP_LOG.Error("获取第三方登录身份失败", zap.Uint("userID", userID), zap.Error(err))
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
+		return
+	}
+
+	common.ResponseSuccess(c, list)
+}
+
+// LinkIdentity 绑定第三方登录身份
+// @Summary 绑定第三方登录身份
+// @Description 生成绑定指定提供商账号的授权地址，前端跳转到该地址完成授权后回调会将账号绑定到当前用户
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param providerId path int true "提供商ID"
+// @Success 200 {object} common.Response{data=map[string]string} "生成成功，data.authUrl为授权地址"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "OAuth2登录未启用"
+// @Failure 404 {object} common.Response "提供商不存在或未启用"
+// @Failure 409 {object} common.Response "已绑定该提供商的账号"
+// @Router /user/identities/{providerId}/link [post]
+func LinkIdentity(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	providerID, ok := parseUintParam(c, "providerId", "无效的提供商ID")
+	if !ok {
+		return
+	}
+
+	authURL, err := oauth2Svc.NewService().BuildLinkURL(userID, providerID)
+	if err != nil {
+		global.APP_LOG.Warn("生成绑定授权地址失败",
+			zap.Uint("userID", userID),
+			zap.Uint("providerID", providerID),
+			zap.Error(err))
+		common.ResponseWithError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, gin.H{"authUrl": authURL})
+}
+
+// UnlinkIdentity 解绑第三方登录身份
+// @Summary 解绑第三方登录身份
+// @Description 解绑当前用户的指定OAuth2账号，账户必须保留至少一种其他可用的登录方式（自行设置的密码、LDAP、通行密钥或其他已绑定账号）
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "身份ID"
+// @Success 200 {object} common.Response "解绑成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "这是账户唯一的登录方式"
+// @Failure 404 {object} common.Response "绑定的账号不存在"
+// @Router /user/identities/{id} [delete]
+func UnlinkIdentity(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	identityID, ok := parseUintParam(c, "id", "无效的身份ID")
+	if !ok {
+		return
+	}
+
+	identityService := oauth2Svc.IdentityService{}
+	if err := identityService.UnlinkIdentity(userID, identityID); err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "解绑成功")
+}
@@ -0,0 +1,246 @@
+package user
+
+import (
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	"oneclickvirt/model/user"
+	"oneclickvirt/service/notification"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// respondNotificationError 根据错误信息返回对应的错误码
+func respondNotificationError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "通知不存在" || msg == "用户不存在":
+		common.ResponseWithError(c, common.NewError(common.CodeNotFound, msg))
+	case strings.HasPrefix(msg, "不支持"):
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+	default:
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+	}
+}
+
+// GetNotifications 获取站内通知列表
+// @Summary 获取站内通知列表
+// @Description 分页获取当前用户的站内通知，包括实例到期提醒、流量使用提醒和任务执行结果，同时返回未读通知总数
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(20)
+// @Param category query string false "通知分类：instance_expiry, traffic, task"
+// @Param unreadOnly query bool false "只返回未读通知"
+// @Success 200 {object} common.Response{data=user.NotificationListResponse} "获取成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/notifications [get]
+func GetNotifications(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	var req user.NotificationListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	resp, err := notification.NewService().ListNotifications(userID, req)
+	if err != nil {
+		global.APP_LOG.Error("获取站内通知失败", zap.Uint("userID", userID), zap.Error(err))
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
+		return
+	}
+
+	common.ResponseSuccess(c, resp)
+}
+
+// GetUnreadNotificationCount 获取未读通知数量
+// @Summary 获取未读通知数量
+// @Description 获取当前用户的未读站内通知数量
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=object} "获取成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/notifications/unread-count [get]
+func GetUnreadNotificationCount(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	count, err := notification.NewService().UnreadCount(userID)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
+		return
+	}
+
+	common.ResponseSuccess(c, gin.H{"unread": count})
+}
+
+// MarkNotificationRead 标记通知已读
+// @Summary 标记通知已读
+// @Description 将单条站内通知标记为已读
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "通知ID"
+// @Success 200 {object} common.Response "标记成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 404 {object} common.Response "通知不存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/notifications/{id}/read [put]
+func MarkNotificationRead(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	notificationID, ok := parseUintParam(c, "id", "无效的通知ID")
+	if !ok {
+		return
+	}
+
+	if err := notification.NewService().MarkRead(userID, notificationID); err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "已标记为已读")
+}
+
+// MarkAllNotificationsRead 全部标记已读
+// @Summary 全部通知标记已读
+// @Description 将当前用户所有未读站内通知标记为已读，返回更新数量
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=object} "标记成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/notifications/read-all [put]
+func MarkAllNotificationsRead(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	count, err := notification.NewService().MarkAllRead(userID)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
+		return
+	}
+
+	common.ResponseSuccess(c, gin.H{"count": count}, "已全部标记为已读")
+}
+
+// DeleteNotification 删除通知
+// @Summary 删除站内通知
+// @Description 删除单条站内通知
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "通知ID"
+// @Success 200 {object} common.Response "删除成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 404 {object} common.Response "通知不存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/notifications/{id} [delete]
+func DeleteNotification(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	notificationID, ok := parseUintParam(c, "id", "无效的通知ID")
+	if !ok {
+		return
+	}
+
+	if err := notification.NewService().DeleteNotification(userID, notificationID); err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "通知删除成功")
+}
+
+// GetNotificationPreferences 获取通知偏好
+// @Summary 获取通知偏好
+// @Description 获取各通知分类除站内信外额外发送的外部渠道，以及各渠道的启用和绑定状态。未设置过的分类返回默认渠道
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=user.NotificationPreferencesResponse} "获取成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/notification-preferences [get]
+func GetNotificationPreferences(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	resp, err := notification.NewService().GetPreferences(userID)
+	if err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, resp)
+}
+
+// UpdateNotificationPreferences 更新通知偏好
+// @Summary 更新通知偏好
+// @Description 设置各通知分类额外发送的外部渠道（email, telegram, qq, sms），渠道为空表示只接收站内信。未提交的分类保持不变
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body user.UpdateNotificationPreferencesRequest true "通知偏好"
+// @Success 200 {object} common.Response{data=user.NotificationPreferencesResponse} "更新成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/notification-preferences [put]
+func UpdateNotificationPreferences(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	var req user.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	resp, err := notification.NewService().UpdatePreferences(userID, req)
+	if err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, resp, "通知偏好已更新")
+}
@@ -0,0 +1,40 @@
+package user
+
+import (
+	"oneclickvirt/global"
+	"oneclickvirt/middleware"
+	"oneclickvirt/model/common"
+	auth2 "oneclickvirt/service/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetMyPermissions 获取当前用户的操作权限
+// @Summary 获取当前用户的操作权限
+// @Description 获取当前用户拥有的管理操作权限，管理员返回["*"]，普通用户未分配角色时返回空列表。前端据此显示管理后台菜单
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=[]string} "获取成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/permissions [get]
+func GetMyPermissions(c *gin.Context) {
+	authCtx, exists := middleware.GetAuthContext(c)
+	if !exists {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, "用户未登录"))
+		return
+	}
+
+	permissionService := auth2.PermissionService{}
+	perms, err := permissionService.GetUserActionPermissions(authCtx.UserID)
+	if err != nil {
+		global.APP_LOG.Error("获取用户操作权限失败", zap.Uint("userID", authCtx.UserID), zap.Error(err))
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
+		return
+	}
+
+	common.ResponseSuccess(c, perms)
+}
@@ -10,7 +10,10 @@ import (
 	"oneclickvirt/middleware"
 	"oneclickvirt/model/common"
 	"oneclickvirt/model/provider"
+	userModel "oneclickvirt/model/user"
 	"oneclickvirt/service/admin/instance"
+	userService "oneclickvirt/service/user"
+	userInstance "oneclickvirt/service/user/instance"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -109,15 +112,21 @@ func GetInstancePorts(c *gin.Context) {
 			"status":      port.Status,
 			"description": port.Description,
 			"isSSH":       port.IsSSH,
+			"portType":    port.PortType, // manual为用户或管理员手动添加，可删除
 			"createdAt":   port.CreatedAt,
 		}
 	}
 
+	// 当前等级每个实例可自行添加的端口映射数量
+	var currentUser userModel.User
+	global.APP_DB.Select("level").First(&currentUser, userID)
+
 	// 实例和Provider信息
 	response := gin.H{
 		"list":     formattedPorts,
 		"total":    len(formattedPorts),
 		"publicIP": publicIP,
+		"maxPorts": userInstance.GetUserMaxPorts(currentUser.Level),
 		"instance": map[string]interface{}{
 			"id":       instance.ID,
 			"name":     instance.Name,
@@ -184,3 +193,115 @@ func GetUserPortMappings(c *gin.Context) {
 		"limit": req.Limit,
 	})
 }
+
+// respondPortMappingError 将端口映射操作错误转换为对应的响应码
+func respondPortMappingError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "实例不存在或无权限":
+		common.ResponseWithError(c, common.NewError(common.CodeForbidden, msg))
+	case msg == "端口映射不存在":
+		common.ResponseWithError(c, common.NewError(common.CodeNotFound, msg))
+	case strings.Contains(msg, "上限") || strings.Contains(msg, "已存在") || strings.Contains(msg, "处理中"):
+		common.ResponseWithError(c, common.NewError(common.CodeConflict, msg))
+	case strings.Contains(msg, "不允许") || strings.Contains(msg, "不支持") || strings.Contains(msg, "不需要") ||
+		strings.Contains(msg, "不能删除") || strings.Contains(msg, "没有可用端口"):
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+	default:
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+	}
+}
+
+// CreateInstancePort 为实例添加端口映射
+// @Summary 添加实例端口映射
+// @Description 为当前用户的实例添加端口映射，主机端口在节点端口范围内自动分配，每个实例可添加的数量受用户等级限制，创建异步任务执行（仅支持 LXD/Incus/Proxmox 的NAT实例）
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param request body userModel.CreatePortMappingRequest true "端口映射参数"
+// @Success 200 {object} common.Response{data=userModel.PortMappingTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误或节点不支持"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "无权限访问此实例"
+// @Failure 409 {object} common.Response "端口映射数量已达上限或端口已映射"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /user/instances/{id}/ports [post]
+func CreateInstancePort(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, ok := parseUintParam(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	var req userModel.CreatePortMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	result, err := userService.NewService().CreatePortMapping(userID, instanceID, req)
+	if err != nil {
+		global.APP_LOG.Warn("添加端口映射失败",
+			zap.Uint("userID", userID),
+			zap.Uint("instanceID", instanceID),
+			zap.Error(err))
+		respondPortMappingError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result, "端口映射任务已创建")
+}
+
+// DeleteInstancePort 删除实例端口映射
+// @Summary 删除实例端口映射
+// @Description 删除当前用户实例上自行添加的端口映射，随实例创建的区间映射端口不能删除，创建异步任务执行
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param portId path int true "端口映射ID"
+// @Success 200 {object} common.Response{data=userModel.PortMappingTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误或端口不能删除"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "无权限访问此实例"
+// @Failure 404 {object} common.Response "端口映射不存在"
+// @Failure 409 {object} common.Response "端口映射正在处理中"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /user/instances/{id}/ports/{portId} [delete]
+func DeleteInstancePort(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, ok := parseUintParam(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+	portID, ok := parseUintParam(c, "portId", "无效的端口映射ID")
+	if !ok {
+		return
+	}
+
+	result, err := userService.NewService().DeletePortMapping(userID, instanceID, portID)
+	if err != nil {
+		global.APP_LOG.Warn("删除端口映射失败",
+			zap.Uint("userID", userID),
+			zap.Uint("instanceID", instanceID),
+			zap.Uint("portID", portID),
+			zap.Error(err))
+		respondPortMappingError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result, "端口删除任务已创建")
+}
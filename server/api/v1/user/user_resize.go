@@ -0,0 +1,70 @@
+package user
+
+import (
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	"oneclickvirt/model/user"
+	userService "oneclickvirt/service/user"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ResizeInstance 调整实例规格
+// @Summary 调整实例规格
+// @Description 在线调整实例的CPU、内存、磁盘和带宽规格，留空的规格保持不变，磁盘只支持扩容。调整后的规格受用户配额和节点资源限制，创建异步任务执行，失败时自动回滚
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param request body user.ResizeInstanceRequest true "调整规格请求参数"
+// @Success 200 {object} common.Response{data=user.ResizeTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误或超出配额"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "实例不存在或无权限"
+// @Failure 409 {object} common.Response "实例有任务正在进行"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /user/instances/{id}/resize [post]
+func ResizeInstance(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, ok := parseUintParam(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	var req user.ResizeInstanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	result, err := userService.NewService().ResizeInstance(userID, instanceID, req)
+	if err != nil {
+		global.APP_LOG.Error("调整实例规格失败",
+			zap.Uint("userID", userID),
+			zap.Uint("instanceID", instanceID),
+			zap.Error(err))
+		msg := err.Error()
+		switch {
+		case msg == "实例不存在或无权限":
+			common.ResponseWithError(c, common.NewError(common.CodeForbidden, msg))
+		case strings.Contains(msg, "正在进行"):
+			common.ResponseWithError(c, common.NewError(common.CodeConflict, msg))
+		case strings.HasPrefix(msg, "创建调整规格任务失败") || strings.HasPrefix(msg, "序列化任务数据失败"):
+			common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+		default:
+			common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+		}
+		return
+	}
+
+	common.ResponseSuccess(c, result, "调整规格任务已提交")
+}
@@ -0,0 +1,287 @@
+package user
+
+import (
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	"oneclickvirt/model/user"
+	userService "oneclickvirt/service/user"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// respondSecurityGroupError 根据错误信息返回对应的错误码
+func respondSecurityGroupError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "实例不存在或无权限" || msg == "安全组不存在或无权限":
+		common.ResponseWithError(c, common.NewError(common.CodeForbidden, msg))
+	case msg == "安全组不存在" || msg == "实例不存在":
+		common.ResponseWithError(c, common.NewError(common.CodeNotFound, msg))
+	case strings.Contains(msg, "上限") || strings.Contains(msg, "正在被实例使用"):
+		common.ResponseWithError(c, common.NewError(common.CodeConflict, msg))
+	case strings.Contains(msg, "不合法") || strings.Contains(msg, "最多") || strings.Contains(msg, "不允许"):
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+	default:
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+	}
+}
+
+// GetSecurityGroups 获取安全组列表
+// @Summary 获取安全组列表
+// @Description 获取当前用户的安全组及其入站规则
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=[]provider.SecurityGroup} "获取成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/security-groups [get]
+func GetSecurityGroups(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	groups, err := userService.NewService().ListSecurityGroups(userID)
+	if err != nil {
+		global.APP_LOG.Error("获取安全组列表失败", zap.Uint("userID", userID), zap.Error(err))
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
+		return
+	}
+
+	common.ResponseSuccess(c, groups)
+}
+
+// CreateSecurityGroup 创建安全组
+// @Summary 创建安全组
+// @Description 创建包含入站规则的安全组。实例绑定安全组后未被允许的入站连接一律丢弃，拒绝规则优先于允许规则；端口为实例内部端口
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body user.SecurityGroupRequest true "安全组"
+// @Success 200 {object} common.Response{data=provider.SecurityGroup} "创建成功"
+// @Failure 400 {object} common.Response "规则不合法"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 409 {object} common.Response "安全组数量已达上限"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/security-groups [post]
+func CreateSecurityGroup(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	var req user.SecurityGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	group, err := userService.NewService().CreateSecurityGroup(userID, req)
+	if err != nil {
+		respondSecurityGroupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, group, "安全组创建成功")
+}
+
+// UpdateSecurityGroup 更新安全组
+// @Summary 更新安全组
+// @Description 修改安全组名称并整体替换规则，已绑定的实例在后台重新下发规则
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "安全组ID"
+// @Param request body user.SecurityGroupRequest true "安全组"
+// @Success 200 {object} common.Response{data=provider.SecurityGroup} "更新成功"
+// @Failure 400 {object} common.Response "规则不合法"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 404 {object} common.Response "安全组不存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/security-groups/{id} [put]
+func UpdateSecurityGroup(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	groupID, ok := parseUintParam(c, "id", "无效的安全组ID")
+	if !ok {
+		return
+	}
+
+	var req user.SecurityGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	group, err := userService.NewService().UpdateSecurityGroup(userID, groupID, req)
+	if err != nil {
+		respondSecurityGroupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, group, "安全组更新成功")
+}
+
+// DeleteSecurityGroup 删除安全组
+// @Summary 删除安全组
+// @Description 删除未绑定任何实例的安全组
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "安全组ID"
+// @Success 200 {object} common.Response "删除成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 404 {object} common.Response "安全组不存在"
+// @Failure 409 {object} common.Response "安全组正在被实例使用"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/security-groups/{id} [delete]
+func DeleteSecurityGroup(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	groupID, ok := parseUintParam(c, "id", "无效的安全组ID")
+	if !ok {
+		return
+	}
+
+	if err := userService.NewService().DeleteSecurityGroup(userID, groupID); err != nil {
+		respondSecurityGroupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "安全组删除成功")
+}
+
+// GetInstanceSecurityGroups 获取实例绑定的安全组
+// @Summary 获取实例安全组
+// @Description 获取当前用户实例绑定的安全组及其规则
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Success 200 {object} common.Response{data=[]provider.SecurityGroup} "获取成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "无权限访问此实例"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/instances/{id}/security-groups [get]
+func GetInstanceSecurityGroups(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, ok := parseUintParam(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	groups, err := userService.NewService().GetInstanceSecurityGroups(userID, instanceID)
+	if err != nil {
+		respondSecurityGroupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, groups)
+}
+
+// SetInstanceSecurityGroups 设置实例绑定的安全组
+// @Summary 设置实例安全组
+// @Description 整体替换实例绑定的安全组并立即下发到节点（LXD/Incus网络ACL、Proxmox防火墙、Docker iptables/nftables规则链），传空列表表示全部解绑。下发失败时绑定关系仍会保存，可调用同步接口重试
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param request body user.InstanceSecurityGroupsRequest true "安全组ID列表"
+// @Success 200 {object} common.Response{data=[]provider.SecurityGroup} "设置成功"
+// @Failure 400 {object} common.Response "参数错误或实例状态不允许"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "无权限访问此实例或安全组"
+// @Failure 500 {object} common.Response "下发到节点失败"
+// @Router /user/instances/{id}/security-groups [put]
+func SetInstanceSecurityGroups(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, ok := parseUintParam(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	var req user.InstanceSecurityGroupsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	groups, err := userService.NewService().SetInstanceSecurityGroups(userID, instanceID, req.SecurityGroupIDs)
+	if err != nil {
+		global.APP_LOG.Warn("设置实例安全组失败",
+			zap.Uint("userID", userID),
+			zap.Uint("instanceID", instanceID),
+			zap.Error(err))
+		respondSecurityGroupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, groups, "实例安全组已生效")
+}
+
+// SyncInstanceSecurityGroups 重新下发实例的安全组规则
+// @Summary 同步实例安全组
+// @Description 按当前绑定的安全组重新下发实例的防火墙规则，用于下发失败后重试
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Success 200 {object} common.Response "同步成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "无权限访问此实例"
+// @Failure 500 {object} common.Response "下发到节点失败"
+// @Router /user/instances/{id}/security-groups/sync [post]
+func SyncInstanceSecurityGroups(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, ok := parseUintParam(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	if err := userService.NewService().SyncInstanceSecurityGroups(userID, instanceID); err != nil {
+		global.APP_LOG.Warn("同步实例安全组失败",
+			zap.Uint("userID", userID),
+			zap.Uint("instanceID", instanceID),
+			zap.Error(err))
+		respondSecurityGroupError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "实例安全组已同步")
+}
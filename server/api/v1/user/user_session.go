@@ -0,0 +1,127 @@
+package user
+
+import (
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/middleware"
+	"oneclickvirt/model/common"
+	userModel "oneclickvirt/model/user"
+	auth2 "oneclickvirt/service/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetSessions 获取登录会话列表
+// @Summary 获取登录会话列表
+// @Description 获取当前用户未过期的登录会话（登录IP、设备、最近访问时间），current标记当前请求使用的会话
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=[]auth.SessionInfo} "获取成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/sessions [get]
+func GetSessions(c *gin.Context) {
+	authCtx, exists := middleware.GetAuthContext(c)
+	if !exists {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, "用户未登录"))
+		return
+	}
+
+	sessionService := auth2.SessionService{}
+	list, err := sessionService.ListSessions(authCtx.UserID, authCtx.TokenID)
+	if err != nil {
+		global.APP_LOG.Error("获取登录会话失败", zap.Uint("userID", authCtx.UserID), zap.Error(err))
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
+		return
+	}
+
+	common.ResponseSuccess(c, list)
+}
+
+// RevokeSession 撤销登录会话
+// @Summary 撤销登录会话
+// @Description 撤销当前用户的指定登录会话，该会话的令牌立即失效
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "会话ID"
+// @Success 200 {object} common.Response "撤销成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 404 {object} common.Response "会话不存在"
+// @Router /user/sessions/{id} [delete]
+func RevokeSession(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	sessionID, ok := parseUintParam(c, "id", "无效的会话ID")
+	if !ok {
+		return
+	}
+
+	sessionService := auth2.SessionService{}
+	if err := sessionService.RevokeSession(userID, sessionID, "revoke", userID); err != nil {
+		common.ResponseWithError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "会话已撤销")
+}
+
+// RevokeOtherSessions 撤销其他登录会话
+// @Summary 撤销其他登录会话
+// @Description 撤销除当前会话外的所有登录会话，常用于怀疑账号在其他设备登录时
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=object} "撤销成功，返回撤销数量"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/sessions/revoke-others [post]
+func RevokeOtherSessions(c *gin.Context) {
+	authCtx, exists := middleware.GetAuthContext(c)
+	if !exists {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, "用户未登录"))
+		return
+	}
+
+	sessionService := auth2.SessionService{}
+	count, err := sessionService.RevokeOtherSessions(authCtx.UserID, authCtx.TokenID)
+	if err != nil {
+		global.APP_LOG.Error("撤销其他会话失败", zap.Uint("userID", authCtx.UserID), zap.Error(err))
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
+		return
+	}
+
+	common.ResponseSuccess(c, gin.H{"count": count}, "其他会话已撤销")
+}
+
+// CreateWebSocketTicket 签发WebSocket连接票据
+// @Summary 签发WebSocket连接票据
+// @Description 浏览器建立WebSocket连接时无法设置请求头，先通过此接口换取一次性票据，再在Web终端、图形控制台等WebSocket地址上以ws_ticket查询参数传递。票据有效期很短且只能使用一次，避免完整令牌出现在URL和访问日志中
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=user.WebSocketTicketResponse} "签发成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Router /user/ws-ticket [post]
+func CreateWebSocketTicket(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, "未提供认证令牌"))
+		return
+	}
+
+	ticket, expiresIn := auth2.IssueWebSocketTicket(token)
+	common.ResponseSuccess(c, userModel.WebSocketTicketResponse{Ticket: ticket, ExpiresIn: expiresIn})
+}
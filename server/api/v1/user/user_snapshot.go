@@ -0,0 +1,216 @@
+package user
+
+import (
+	"strconv"
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	"oneclickvirt/model/user"
+	userService "oneclickvirt/service/user"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// parseSnapshotParams 解析实例ID和快照ID路径参数
+func parseSnapshotParams(c *gin.Context, withSnapshot bool) (uint, uint, bool) {
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "无效的实例ID"))
+		return 0, 0, false
+	}
+	if !withSnapshot {
+		return uint(instanceID), 0, true
+	}
+
+	snapshotID, err := strconv.ParseUint(c.Param("snapshotId"), 10, 32)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "无效的快照ID"))
+		return 0, 0, false
+	}
+	return uint(instanceID), uint(snapshotID), true
+}
+
+// respondSnapshotError 根据错误信息返回对应的错误码
+func respondSnapshotError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "实例不存在或无权限":
+		common.ResponseWithError(c, common.NewError(common.CodeForbidden, msg))
+	case msg == "快照不存在":
+		common.ResponseWithError(c, common.NewError(common.CodeNotFound, msg))
+	case strings.Contains(msg, "上限") || strings.Contains(msg, "已存在") || strings.Contains(msg, "正在进行"):
+		common.ResponseWithError(c, common.NewError(common.CodeConflict, msg))
+	case strings.Contains(msg, "不允许") || strings.Contains(msg, "不可用") || strings.Contains(msg, "快照名称"):
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+	default:
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+	}
+}
+
+// GetInstanceSnapshots 获取实例快照列表
+// @Summary 获取实例快照列表
+// @Description 获取当前用户指定实例的快照列表及当前等级允许的快照数量
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Success 200 {object} common.Response{data=user.SnapshotListResponse} "获取成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "实例不存在或无权限"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/instances/{id}/snapshots [get]
+func GetInstanceSnapshots(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, _, ok := parseSnapshotParams(c, false)
+	if !ok {
+		return
+	}
+
+	result, err := userService.NewService().ListInstanceSnapshots(userID, instanceID)
+	if err != nil {
+		respondSnapshotError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result)
+}
+
+// CreateInstanceSnapshot 创建实例快照
+// @Summary 创建实例快照
+// @Description 为当前用户的实例创建快照，受用户等级快照数量限制，创建异步任务执行
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param request body user.CreateSnapshotRequest true "创建快照请求参数"
+// @Success 200 {object} common.Response{data=user.SnapshotTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "实例不存在或无权限"
+// @Failure 409 {object} common.Response "快照数量已达上限或已有快照任务"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /user/instances/{id}/snapshots [post]
+func CreateInstanceSnapshot(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, _, ok := parseSnapshotParams(c, false)
+	if !ok {
+		return
+	}
+
+	var req user.CreateSnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	result, err := userService.NewService().CreateInstanceSnapshot(userID, instanceID, req)
+	if err != nil {
+		global.APP_LOG.Error("用户创建实例快照失败",
+			zap.Uint("userID", userID),
+			zap.Uint("instanceID", instanceID),
+			zap.Error(err))
+		respondSnapshotError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result, "快照创建任务已提交")
+}
+
+// RestoreInstanceSnapshot 恢复实例快照
+// @Summary 恢复实例快照
+// @Description 将当前用户的实例恢复到指定快照，快照之后的数据将丢失，创建异步任务执行
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param snapshotId path int true "快照ID"
+// @Success 200 {object} common.Response{data=user.SnapshotTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "实例不存在或无权限"
+// @Failure 404 {object} common.Response "快照不存在"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /user/instances/{id}/snapshots/{snapshotId}/restore [post]
+func RestoreInstanceSnapshot(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, snapshotID, ok := parseSnapshotParams(c, true)
+	if !ok {
+		return
+	}
+
+	result, err := userService.NewService().RestoreInstanceSnapshot(userID, instanceID, snapshotID)
+	if err != nil {
+		global.APP_LOG.Error("用户恢复实例快照失败",
+			zap.Uint("userID", userID),
+			zap.Uint("instanceID", instanceID),
+			zap.Uint("snapshotID", snapshotID),
+			zap.Error(err))
+		respondSnapshotError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result, "快照恢复任务已提交")
+}
+
+// DeleteInstanceSnapshot 删除实例快照
+// @Summary 删除实例快照
+// @Description 删除当前用户实例的指定快照，创建异步任务执行
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param snapshotId path int true "快照ID"
+// @Success 200 {object} common.Response{data=user.SnapshotTaskResponse} "任务创建成功"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "实例不存在或无权限"
+// @Failure 404 {object} common.Response "快照不存在"
+// @Failure 500 {object} common.Response "创建任务失败"
+// @Router /user/instances/{id}/snapshots/{snapshotId} [delete]
+func DeleteInstanceSnapshot(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	instanceID, snapshotID, ok := parseSnapshotParams(c, true)
+	if !ok {
+		return
+	}
+
+	result, err := userService.NewService().DeleteInstanceSnapshot(userID, instanceID, snapshotID)
+	if err != nil {
+		global.APP_LOG.Error("用户删除实例快照失败",
+			zap.Uint("userID", userID),
+			zap.Uint("instanceID", instanceID),
+			zap.Uint("snapshotID", snapshotID),
+			zap.Error(err))
+		respondSnapshotError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, result, "快照删除任务已提交")
+}
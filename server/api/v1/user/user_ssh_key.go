@@ -0,0 +1,168 @@
+package user
+
+import (
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	"oneclickvirt/model/user"
+	userService "oneclickvirt/service/user"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// respondSSHKeyError 根据错误信息返回对应的错误码
+func respondSSHKeyError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "SSH公钥不存在":
+		common.ResponseWithError(c, common.NewError(common.CodeNotFound, msg))
+	case msg == "该SSH公钥已存在" || strings.Contains(msg, "上限"):
+		common.ResponseWithError(c, common.NewError(common.CodeConflict, msg))
+	case strings.Contains(msg, "公钥不能") || strings.Contains(msg, "不合法") || strings.Contains(msg, "只能添加"):
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+	default:
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+	}
+}
+
+// GetSSHKeys 获取SSH公钥列表
+// @Summary 获取SSH公钥列表
+// @Description 获取当前用户保存的SSH公钥，创建实例时可选择公钥注入实例
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response{data=[]user.UserSSHKey} "获取成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/ssh-keys [get]
+func GetSSHKeys(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	keys, err := userService.NewService().ListSSHKeys(userID)
+	if err != nil {
+		global.APP_LOG.Error("获取SSH公钥列表失败", zap.Uint("userID", userID), zap.Error(err))
+		common.ResponseWithError(c, common.NewError(common.CodeInternalError, err.Error()))
+		return
+	}
+
+	common.ResponseSuccess(c, keys)
+}
+
+// CreateSSHKey 添加SSH公钥
+// @Summary 添加SSH公钥
+// @Description 添加一个authorized_keys格式的SSH公钥，同一公钥不能重复添加
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body user.SSHKeyRequest true "SSH公钥"
+// @Success 200 {object} common.Response{data=user.UserSSHKey} "添加成功"
+// @Failure 400 {object} common.Response "公钥格式不合法"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 409 {object} common.Response "公钥已存在或数量已达上限"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/ssh-keys [post]
+func CreateSSHKey(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	var req user.SSHKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	key, err := userService.NewService().CreateSSHKey(userID, req)
+	if err != nil {
+		respondSSHKeyError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, key, "SSH公钥添加成功")
+}
+
+// UpdateSSHKey 更新SSH公钥
+// @Summary 更新SSH公钥
+// @Description 修改SSH公钥的名称或内容，已创建实例中的公钥不会同步修改
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "公钥ID"
+// @Param request body user.SSHKeyRequest true "SSH公钥"
+// @Success 200 {object} common.Response{data=user.UserSSHKey} "更新成功"
+// @Failure 400 {object} common.Response "公钥格式不合法"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 404 {object} common.Response "公钥不存在"
+// @Failure 409 {object} common.Response "公钥已存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/ssh-keys/{id} [put]
+func UpdateSSHKey(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	keyID, ok := parseUintParam(c, "id", "无效的公钥ID")
+	if !ok {
+		return
+	}
+
+	var req user.SSHKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请求参数错误"))
+		return
+	}
+
+	key, err := userService.NewService().UpdateSSHKey(userID, keyID, req)
+	if err != nil {
+		respondSSHKeyError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, key, "SSH公钥更新成功")
+}
+
+// DeleteSSHKey 删除SSH公钥
+// @Summary 删除SSH公钥
+// @Description 删除SSH公钥，已注入实例中的公钥不会被移除
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "公钥ID"
+// @Success 200 {object} common.Response "删除成功"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 404 {object} common.Response "公钥不存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /user/ssh-keys/{id} [delete]
+func DeleteSSHKey(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	keyID, ok := parseUintParam(c, "id", "无效的公钥ID")
+	if !ok {
+		return
+	}
+
+	if err := userService.NewService().DeleteSSHKey(userID, keyID); err != nil {
+		respondSSHKeyError(c, err)
+		return
+	}
+
+	common.ResponseSuccess(c, nil, "SSH公钥删除成功")
+}
@@ -0,0 +1,181 @@
+package user
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/middleware"
+	"oneclickvirt/model/common"
+	"oneclickvirt/model/user"
+	userService "oneclickvirt/service/user"
+	"oneclickvirt/service/user/instance"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+)
+
+// terminalMaxMessageBytes 单条客户端消息的最大长度
+const terminalMaxMessageBytes = 64 * 1024
+
+// terminalCloseMessages 会话结束原因对应的提示
+var terminalCloseMessages = map[string]string{
+	instance.TerminalCloseClient:   "连接已关闭",
+	instance.TerminalCloseExited:   "终端已退出",
+	instance.TerminalCloseIdle:     "长时间无操作，终端已断开",
+	instance.TerminalCloseDuration: "已达到终端最长使用时间，终端已断开",
+	instance.TerminalCloseError:    "终端连接异常断开",
+}
+
+// OpenInstanceTerminal 打开实例Web终端
+// @Summary 打开实例Web终端
+// @Description 通过WebSocket连接实例的交互式终端。浏览器无法设置请求头，可通过ws_ticket查询参数传递WebSocket连接票据（见签发WebSocket连接票据接口）。客户端发送JSON消息：{"type":"input","data":"..."}、{"type":"resize","cols":80,"rows":24}；服务端以二进制帧返回终端输出，以JSON文本消息通知状态
+// @Tags 用户管理
+// @Security BearerAuth
+// @Param id path int true "实例ID"
+// @Param cols query int false "终端列数"
+// @Param rows query int false "终端行数"
+// @Param ws_ticket query string false "WebSocket连接票据"
+// @Success 101 {string} string "切换到WebSocket协议"
+// @Failure 400 {object} common.Response "参数错误或实例未运行"
+// @Failure 401 {object} common.Response "用户未登录"
+// @Failure 403 {object} common.Response "实例不存在或无权限"
+// @Failure 409 {object} common.Response "终端会话数量已达上限"
+// @Failure 500 {object} common.Response "打开终端失败"
+// @Router /user/instances/{id}/terminal [get]
+func OpenInstanceTerminal(c *gin.Context) {
+	authCtx, exists := middleware.GetAuthContext(c)
+	if !exists {
+		common.ResponseWithError(c, common.NewError(common.CodeUnauthorized, "用户未登录"))
+		return
+	}
+
+	instanceID, ok := parseUintParam(c, "id", "无效的实例ID")
+	if !ok {
+		return
+	}
+
+	if !c.IsWebsocket() {
+		common.ResponseWithError(c, common.NewError(common.CodeValidationError, "请使用WebSocket连接"))
+		return
+	}
+
+	cols, _ := strconv.Atoi(c.Query("cols"))
+	rows, _ := strconv.Atoi(c.Query("rows"))
+
+	session, err := userService.NewService().OpenInstanceTerminal(authCtx.UserID, instanceID, cols, rows, instance.TerminalClientInfo{
+		Username:  authCtx.Username,
+		Path:      c.Request.URL.Path,
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		msg := err.Error()
+		switch {
+		case msg == "实例不存在或无权限":
+			common.ResponseWithError(c, common.NewError(common.CodeForbidden, msg))
+		case strings.Contains(msg, "上限"):
+			common.ResponseWithError(c, common.NewError(common.CodeConflict, msg))
+		case strings.Contains(msg, "未运行"):
+			common.ResponseWithError(c, common.NewError(common.CodeValidationError, msg))
+		default:
+			global.APP_LOG.Error("打开实例终端失败",
+				zap.Uint("userID", authCtx.UserID),
+				zap.Uint("instanceID", instanceID),
+				zap.Error(err))
+			common.ResponseWithError(c, common.NewError(common.CodeInternalError, msg))
+		}
+		return
+	}
+
+	// 认证已由中间件完成，且令牌不通过Cookie传递，这里不再校验Origin
+	server := websocket.Server{
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			serveTerminal(ws, session)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+
+	// 握手失败时Handler不会执行，需要在这里关闭会话
+	session.Close(instance.TerminalCloseError)
+}
+
+// serveTerminal 在WebSocket和终端会话之间双向转发数据
+func serveTerminal(ws *websocket.Conn, session *instance.TerminalSession) {
+	ws.MaxPayloadBytes = terminalMaxMessageBytes
+	defer ws.Close()
+
+	sendTerminalMessage(ws, user.TerminalMessage{Type: "status", Message: "connected"})
+
+	// 终端输出 -> WebSocket
+	go func() {
+		buf := make([]byte, 8192)
+		for {
+			n, err := session.Read(buf)
+			if n > 0 {
+				if sendErr := websocket.Message.Send(ws, buf[:n]); sendErr != nil {
+					session.Close(instance.TerminalCloseClient)
+					return
+				}
+			}
+			if err != nil {
+				session.Close(instance.TerminalCloseExited)
+				return
+			}
+		}
+	}()
+
+	// 会话结束时通知客户端并断开WebSocket，使下面的读取循环退出
+	go func() {
+		<-session.Done()
+		reason := session.CloseReason()
+		if reason != instance.TerminalCloseClient {
+			sendTerminalMessage(ws, user.TerminalMessage{Type: "status", Message: terminalCloseMessages[reason]})
+		}
+		ws.Close()
+	}()
+
+	// WebSocket -> 终端输入
+	for {
+		var raw []byte
+		if err := websocket.Message.Receive(ws, &raw); err != nil {
+			session.Close(instance.TerminalCloseClient)
+			return
+		}
+
+		var msg user.TerminalMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			sendTerminalMessage(ws, user.TerminalMessage{Type: "error", Message: "无效的消息格式"})
+			continue
+		}
+
+		switch msg.Type {
+		case "input":
+			if _, err := session.Write([]byte(msg.Data)); err != nil {
+				session.Close(instance.TerminalCloseError)
+				return
+			}
+		case "resize":
+			if err := session.Resize(msg.Cols, msg.Rows); err != nil {
+				global.APP_LOG.Debug("调整终端大小失败", zap.Error(err))
+			}
+		case "ping":
+			sendTerminalMessage(ws, user.TerminalMessage{Type: "pong"})
+		default:
+			sendTerminalMessage(ws, user.TerminalMessage{Type: "error", Message: "不支持的消息类型"})
+		}
+	}
+}
+
+// sendTerminalMessage 以文本帧发送JSON状态消息
+func sendTerminalMessage(ws *websocket.Conn, msg user.TerminalMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	websocket.Message.Send(ws, string(data))
+}
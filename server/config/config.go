@@ -1,19 +1,23 @@
 package config
 
 type Server struct {
-	JWT        JWT        `mapstructure:"jwt" json:"jwt" yaml:"jwt"`
-	Zap        Zap        `mapstructure:"zap" json:"zap" yaml:"zap"`
-	System     System     `mapstructure:"system" json:"system" yaml:"system"`
-	Mysql      Mysql      `mapstructure:"mysql" json:"mysql" yaml:"mysql"`
-	Auth       Auth       `mapstructure:"auth" json:"auth" yaml:"auth"`
-	Quota      Quota      `mapstructure:"quota" json:"quota" yaml:"quota"`
-	InviteCode InviteCode `mapstructure:"invite-code" json:"invite-code" yaml:"invite-code"`
-	Captcha    Captcha    `mapstructure:"captcha" json:"captcha" yaml:"captcha"`
-	Cors       CORS       `mapstructure:"cors" json:"cors" yaml:"cors"`
-	Redis      Redis      `mapstructure:"redis" json:"redis" yaml:"redis"`
-	CDN        CDN        `mapstructure:"cdn" json:"cdn" yaml:"cdn"`
-	Task       Task       `mapstructure:"task" json:"task" yaml:"task"`
-	Upload     Upload     `mapstructure:"upload" json:"upload" yaml:"upload"`
+	JWT           JWT           `mapstructure:"jwt" json:"jwt" yaml:"jwt"`
+	Zap           Zap           `mapstructure:"zap" json:"zap" yaml:"zap"`
+	System        System        `mapstructure:"system" json:"system" yaml:"system"`
+	Mysql         Mysql         `mapstructure:"mysql" json:"mysql" yaml:"mysql"`
+	Auth          Auth          `mapstructure:"auth" json:"auth" yaml:"auth"`
+	Quota         Quota         `mapstructure:"quota" json:"quota" yaml:"quota"`
+	InviteCode    InviteCode    `mapstructure:"invite-code" json:"invite-code" yaml:"invite-code"`
+	Captcha       Captcha       `mapstructure:"captcha" json:"captcha" yaml:"captcha"`
+	Cors          CORS          `mapstructure:"cors" json:"cors" yaml:"cors"`
+	Redis         Redis         `mapstructure:"redis" json:"redis" yaml:"redis"`
+	CDN           CDN           `mapstructure:"cdn" json:"cdn" yaml:"cdn"`
+	Task          Task          `mapstructure:"task" json:"task" yaml:"task"`
+	Terminal      Terminal      `mapstructure:"terminal" json:"terminal" yaml:"terminal"`
+	Upload        Upload        `mapstructure:"upload" json:"upload" yaml:"upload"`
+	RateLimit     RateLimit     `mapstructure:"rate-limit" json:"rate-limit" yaml:"rate-limit"`
+	PortReconcile PortReconcile `mapstructure:"port-reconcile" json:"port-reconcile" yaml:"port-reconcile"`
+	DomainBinding DomainBinding `mapstructure:"domain-binding" json:"domain-binding" yaml:"domain-binding"`
 }
 
 type CORS struct {
@@ -34,6 +38,51 @@ type Auth struct {
 	TelegramBotToken         string `mapstructure:"telegram-bot-token" json:"telegram-bot-token" yaml:"telegram-bot-token"`
 	QQAppID                  string `mapstructure:"qq-app-id" json:"qq-app-id" yaml:"qq-app-id"`
 	QQAppKey                 string `mapstructure:"qq-app-key" json:"qq-app-key" yaml:"qq-app-key"`
+	QQBotAPI                 string `mapstructure:"qq-bot-api" json:"qq-bot-api" yaml:"qq-bot-api"`                            // OneBot v11 HTTP API地址，用于发送QQ私聊消息
+	QQBotToken               string `mapstructure:"qq-bot-token" json:"qq-bot-token" yaml:"qq-bot-token"`                      // OneBot access_token
+	SMSGatewayURL            string `mapstructure:"sms-gateway-url" json:"sms-gateway-url" yaml:"sms-gateway-url"`             // 短信网关地址，POST JSON {"phone","content"}
+	SMSGatewayToken          string `mapstructure:"sms-gateway-token" json:"sms-gateway-token" yaml:"sms-gateway-token"`       // 短信网关Bearer Token
+	TwoFactorRequired        string `mapstructure:"two-factor-required" json:"two-factor-required" yaml:"two-factor-required"` // 强制两步验证范围：空=不强制，admin=管理员，all=所有用户
+	WebAuthnRPID             string `mapstructure:"webauthn-rp-id" json:"webauthn-rp-id" yaml:"webauthn-rp-id"`                // 通行密钥依赖方ID（站点域名），为空时取system.frontend-url的域名
+	WebAuthnOrigins          string `mapstructure:"webauthn-origins" json:"webauthn-origins" yaml:"webauthn-origins"`          // 通行密钥允许的来源，逗号分隔，为空时取system.frontend-url
+
+	LoginProtection LoginProtection `mapstructure:"login-protection" json:"login-protection" yaml:"login-protection"` // 登录防暴力破解策略
+	EnableLDAP      bool            `mapstructure:"enable-ldap" json:"enable-ldap" yaml:"enable-ldap"`                // 是否启用LDAP登录
+	LDAP            LDAP            `mapstructure:"ldap" json:"ldap" yaml:"ldap"`                                     // LDAP/AD认证配置
+}
+
+// LDAP LDAP/AD认证配置
+type LDAP struct {
+	URL                string         `mapstructure:"url" json:"url" yaml:"url"`                                                    // 服务器地址，如 ldap://ldap.example.com:389 或 ldaps://dc.example.com:636
+	StartTLS           bool           `mapstructure:"start-tls" json:"start-tls" yaml:"start-tls"`                                  // 是否在ldap://连接上使用StartTLS
+	InsecureSkipVerify bool           `mapstructure:"insecure-skip-verify" json:"insecure-skip-verify" yaml:"insecure-skip-verify"` // 是否跳过TLS证书校验，仅用于测试环境
+	BindDN             string         `mapstructure:"bind-dn" json:"bind-dn" yaml:"bind-dn"`                                        // 用于搜索用户的服务账户DN，为空时匿名绑定
+	BindPassword       string         `mapstructure:"bind-password" json:"bind-password" yaml:"bind-password"`                      // 服务账户密码
+	BaseDN             string         `mapstructure:"base-dn" json:"base-dn" yaml:"base-dn"`                                        // 用户搜索的基础DN
+	UserFilter         string         `mapstructure:"user-filter" json:"user-filter" yaml:"user-filter"`                            // 用户搜索过滤器，%s替换为转义后的用户名，默认(uid=%s)，AD可用(sAMAccountName=%s)
+	UsernameAttribute  string         `mapstructure:"username-attribute" json:"username-attribute" yaml:"username-attribute"`       // 用户名属性，默认uid
+	EmailAttribute     string         `mapstructure:"email-attribute" json:"email-attribute" yaml:"email-attribute"`                // 邮箱属性，默认mail
+	NicknameAttribute  string         `mapstructure:"nickname-attribute" json:"nickname-attribute" yaml:"nickname-attribute"`       // 昵称属性，默认cn
+	GroupAttribute     string         `mapstructure:"group-attribute" json:"group-attribute" yaml:"group-attribute"`                // 用户条目上的组成员属性，默认memberOf
+	GroupBaseDN        string         `mapstructure:"group-base-dn" json:"group-base-dn" yaml:"group-base-dn"`                      // 组搜索的基础DN，服务器不支持memberOf时配置
+	GroupFilter        string         `mapstructure:"group-filter" json:"group-filter" yaml:"group-filter"`                         // 组搜索过滤器，%s替换为用户DN，默认(member=%s)
+	GroupLevelMapping  map[string]int `mapstructure:"group-level-mapping" json:"group-level-mapping" yaml:"group-level-mapping"`    // 组到用户等级的映射，键为组DN或CN（不区分大小写），匹配多个时取最高等级
+	DefaultLevel       int            `mapstructure:"default-level" json:"default-level" yaml:"default-level"`                      // 未匹配任何组时的用户等级，为0时使用quota.default-level
+	AutoProvision      bool           `mapstructure:"auto-provision" json:"auto-provision" yaml:"auto-provision"`                   // 首次登录时是否自动创建本地用户
+	TimeoutSeconds     int            `mapstructure:"timeout-seconds" json:"timeout-seconds" yaml:"timeout-seconds"`                // 连接和操作超时（秒），默认10
+}
+
+// LoginProtection 登录防暴力破解策略，数值为0时使用默认值
+type LoginProtection struct {
+	Enabled                   bool `mapstructure:"enabled" json:"enabled" yaml:"enabled"`                                                                // 是否启用
+	IPMaxAttempts             int  `mapstructure:"ip-max-attempts" json:"ip-max-attempts" yaml:"ip-max-attempts"`                                        // 单个IP在窗口内允许的登录、发送验证码、找回密码请求次数，默认20
+	IPWindowSeconds           int  `mapstructure:"ip-window-seconds" json:"ip-window-seconds" yaml:"ip-window-seconds"`                                  // IP限流滑动窗口（秒），默认300
+	MaxFailures               int  `mapstructure:"max-failures" json:"max-failures" yaml:"max-failures"`                                                 // 账户在窗口内允许的密码错误次数，达到后锁定，默认5
+	FailureWindowSeconds      int  `mapstructure:"failure-window-seconds" json:"failure-window-seconds" yaml:"failure-window-seconds"`                   // 密码错误计数滑动窗口（秒），默认900
+	LockoutSeconds            int  `mapstructure:"lockout-seconds" json:"lockout-seconds" yaml:"lockout-seconds"`                                        // 首次锁定时长（秒），之后每次锁定翻倍，默认300
+	MaxLockoutSeconds         int  `mapstructure:"max-lockout-seconds" json:"max-lockout-seconds" yaml:"max-lockout-seconds"`                            // 最长锁定时长（秒），默认86400
+	VerifyCodeIntervalSeconds int  `mapstructure:"verify-code-interval-seconds" json:"verify-code-interval-seconds" yaml:"verify-code-interval-seconds"` // 同一目标两次发送验证码的最小间隔（秒），默认60
+	VerifyCodeHourlyLimit     int  `mapstructure:"verify-code-hourly-limit" json:"verify-code-hourly-limit" yaml:"verify-code-hourly-limit"`             // 同一目标每小时最多发送验证码次数，默认10
 }
 
 type Quota struct {
@@ -54,7 +103,11 @@ type InstanceTypePermissions struct {
 type LevelLimitInfo struct {
 	MaxInstances int                    `mapstructure:"max-instances" json:"max-instances" yaml:"max-instances"`
 	MaxResources map[string]interface{} `mapstructure:"max-resources" json:"max-resources" yaml:"max-resources"`
-	MaxTraffic   int64                  `mapstructure:"max-traffic" json:"max-traffic" yaml:"max-traffic"` // 最大流量限制（MB）
+	MaxTraffic   int64                  `mapstructure:"max-traffic" json:"max-traffic" yaml:"max-traffic"`       // 最大流量限制（MB）
+	MaxSnapshots int                    `mapstructure:"max-snapshots" json:"max-snapshots" yaml:"max-snapshots"` // 每个实例最大快照数量，0表示使用默认值
+	MaxBackups   int                    `mapstructure:"max-backups" json:"max-backups" yaml:"max-backups"`       // 每个用户最大备份数量，0表示使用默认值
+	MaxPorts     int                    `mapstructure:"max-ports" json:"max-ports" yaml:"max-ports"`             // 每个实例用户自行添加的端口映射数量，0表示使用默认值
+	MaxDomains   int                    `mapstructure:"max-domains" json:"max-domains" yaml:"max-domains"`       // 每个用户可绑定的域名数量，0表示使用默认值
 }
 
 type System struct {
@@ -129,7 +182,51 @@ type Task struct {
 	DeleteRetryDelay int `mapstructure:"delete-retry-delay" json:"delete-retry-delay" yaml:"delete-retry-delay"` // 删除实例重试延迟（秒），默认2
 }
 
+// Terminal Web终端配置
+type Terminal struct {
+	MaxSessionsPerUser int `mapstructure:"max-sessions-per-user" json:"max-sessions-per-user" yaml:"max-sessions-per-user"` // 每个用户同时打开的终端会话上限，默认3
+	IdleTimeout        int `mapstructure:"idle-timeout" json:"idle-timeout" yaml:"idle-timeout"`                            // 无输入空闲超时（分钟），默认15
+	MaxDuration        int `mapstructure:"max-duration" json:"max-duration" yaml:"max-duration"`                            // 单个会话最长持续时间（分钟），默认240
+}
+
 // Upload 上传配置
 type Upload struct {
 	MaxAvatarSize int64 `mapstructure:"max-avatar-size" json:"max-avatar-size" yaml:"max-avatar-size"` // 头像最大大小（MB）
 }
+
+// RateLimit 接口限流配置（令牌桶），未配置的数值使用默认值
+type RateLimit struct {
+	Enabled     bool                   `mapstructure:"enabled" json:"enabled" yaml:"enabled"`                // 是否启用
+	Public      RateLimitRule          `mapstructure:"public" json:"public" yaml:"public"`                   // 公开接口，按IP限流，默认120次/分钟，突发60
+	User        RateLimitRule          `mapstructure:"user" json:"user" yaml:"user"`                         // 用户接口，按用户限流，默认300次/分钟，突发100
+	Admin       RateLimitRule          `mapstructure:"admin" json:"admin" yaml:"admin"`                      // 管理员接口，按用户限流，默认600次/分钟，突发200
+	Task        RateLimitRule          `mapstructure:"task" json:"task" yaml:"task"`                         // 创建任务的接口（创建实例、实例操作等），默认10次/分钟，突发5
+	LevelLimits map[int]LevelRateLimit `mapstructure:"level-limits" json:"level-limits" yaml:"level-limits"` // 按用户等级覆盖用户接口和任务接口的限额
+}
+
+// RateLimitRule 令牌桶规则
+type RateLimitRule struct {
+	RequestsPerMinute int `mapstructure:"requests-per-minute" json:"requests-per-minute" yaml:"requests-per-minute"` // 每分钟补充的令牌数
+	Burst             int `mapstructure:"burst" json:"burst" yaml:"burst"`                                           // 桶容量，即允许的突发请求数
+}
+
+// LevelRateLimit 用户等级限流规则，数值为0时使用对应分组的规则
+type LevelRateLimit struct {
+	User RateLimitRule `mapstructure:"user" json:"user" yaml:"user"`
+	Task RateLimitRule `mapstructure:"task" json:"task" yaml:"task"`
+}
+
+// PortReconcile 端口映射漂移检测配置
+type PortReconcile struct {
+	Enabled  bool `mapstructure:"enabled" json:"enabled" yaml:"enabled"`    // 是否启用定时检测
+	AutoFix  bool `mapstructure:"auto-fix" json:"auto-fix" yaml:"auto-fix"` // 定时检测时是否自动修复，关闭时只记录报告
+	Interval int  `mapstructure:"interval" json:"interval" yaml:"interval"` // 检测间隔（分钟），默认30
+}
+
+// DomainBinding 实例域名绑定配置，反向代理由各Provider节点上的Caddy提供，需预先安装
+type DomainBinding struct {
+	Enabled      bool   `mapstructure:"enabled" json:"enabled" yaml:"enabled"`                   // 是否允许用户绑定域名
+	ACMEEmail    string `mapstructure:"acme-email" json:"acme-email" yaml:"acme-email"`          // 申请证书时使用的邮箱，可为空
+	VerifyPrefix string `mapstructure:"verify-prefix" json:"verify-prefix" yaml:"verify-prefix"` // 所有权验证TXT记录的子域名前缀，默认_oneclickvirt-verify
+	CaddyFile    string `mapstructure:"caddy-file" json:"caddy-file" yaml:"caddy-file"`          // 节点上Caddy主配置文件路径，默认/etc/caddy/Caddyfile
+}
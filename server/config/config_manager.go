@@ -155,6 +155,10 @@ func (cm *ConfigManager) initValidationRules() {
 		Required: false,
 		Type:     "bool",
 	}
+	cm.validationRules["auth.enableLDAP"] = ConfigValidationRule{
+		Required: false,
+		Type:     "bool",
+	}
 	cm.validationRules["auth.emailSMTPPort"] = ConfigValidationRule{
 		Required: false,
 		Type:     "int",
@@ -420,6 +424,42 @@ func (cm *ConfigManager) validateLevelLimits(value interface{}) error {
 			return err
 		}
 
+		// 验证 maxSnapshots（可选）
+		if maxSnapshots, exists := limitMap["maxSnapshots"]; exists {
+			if v, ok := maxSnapshots.(float64); ok && v < 0 {
+				return fmt.Errorf("等级 %s 的 maxSnapshots 不能为负数", levelStr)
+			} else if v, ok := maxSnapshots.(int); ok && v < 0 {
+				return fmt.Errorf("等级 %s 的 maxSnapshots 不能为负数", levelStr)
+			}
+		}
+
+		// 验证 maxBackups（可选）
+		if maxBackups, exists := limitMap["maxBackups"]; exists {
+			if v, ok := maxBackups.(float64); ok && v < 0 {
+				return fmt.Errorf("等级 %s 的 maxBackups 不能为负数", levelStr)
+			} else if v, ok := maxBackups.(int); ok && v < 0 {
+				return fmt.Errorf("等级 %s 的 maxBackups 不能为负数", levelStr)
+			}
+		}
+
+		// 验证 maxPorts（可选）
+		if maxPorts, exists := limitMap["maxPorts"]; exists {
+			if v, ok := maxPorts.(float64); ok && v < 0 {
+				return fmt.Errorf("等级 %s 的 maxPorts 不能为负数", levelStr)
+			} else if v, ok := maxPorts.(int); ok && v < 0 {
+				return fmt.Errorf("等级 %s 的 maxPorts 不能为负数", levelStr)
+			}
+		}
+
+		// 验证 maxDomains（可选）
+		if maxDomains, exists := limitMap["maxDomains"]; exists {
+			if v, ok := maxDomains.(float64); ok && v < 0 {
+				return fmt.Errorf("等级 %s 的 maxDomains 不能为负数", levelStr)
+			} else if v, ok := maxDomains.(int); ok && v < 0 {
+				return fmt.Errorf("等级 %s 的 maxDomains 不能为负数", levelStr)
+			}
+		}
+
 		// 验证 maxResources
 		maxResources, exists := limitMap["maxResources"]
 		if !exists {
@@ -41,6 +41,7 @@ type PortMappingMethod string
 const (
 	PortMappingMethodDeviceProxy PortMappingMethod = "device_proxy" // LXD/Incus使用的设备代理方式
 	PortMappingMethodIptables    PortMappingMethod = "iptables"     // 使用iptables进行端口映射
+	PortMappingMethodNftables    PortMappingMethod = "nftables"     // 使用nftables进行端口映射（每个Provider独立的表）
 	PortMappingMethodNative      PortMappingMethod = "native"       // 原生实现（Docker, Proxmox独立IP）
 )
 
@@ -61,6 +61,20 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("system.iplimit-count", 15000)
 	v.SetDefault("system.iplimit-time", 3600)
 
+	v.SetDefault("auth.login-protection.enabled", true)
+	v.SetDefault("auth.ldap.user-filter", "(uid=%s)")
+	v.SetDefault("auth.ldap.username-attribute", "uid")
+	v.SetDefault("auth.ldap.email-attribute", "mail")
+	v.SetDefault("auth.ldap.nickname-attribute", "cn")
+	v.SetDefault("auth.ldap.group-attribute", "memberOf")
+	v.SetDefault("auth.ldap.auto-provision", true)
+	v.SetDefault("auth.ldap.timeout-seconds", 10)
+	v.SetDefault("rate-limit.enabled", true)
+	v.SetDefault("port-reconcile.enabled", true)
+	v.SetDefault("port-reconcile.interval", 30)
+	v.SetDefault("domain-binding.verify-prefix", "_oneclickvirt-verify")
+	v.SetDefault("domain-binding.caddy-file", "/etc/caddy/Caddyfile")
+
 	// 生成强制的安全JWT签名密钥
 	randomKey := generateSecureJWTKey()
 
@@ -5,6 +5,7 @@ import (
 	"oneclickvirt/config"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -41,6 +42,7 @@ type SystemInitializationCallback func()
 
 var (
 	APP_DB                        *gorm.DB
+	APP_REDIS                     *redis.Client // Redis客户端，未启用system.use-redis时为nil
 	APP_LOG                       *zap.Logger
 	APP_CONFIG                    config.Server
 	APP_VP                        *viper.Viper
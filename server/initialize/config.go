@@ -73,6 +73,19 @@ func getDefaultConfig() config.Server {
 			TelegramBotToken:         "",
 			QQAppID:                  "",
 			QQAppKey:                 "",
+			LoginProtection: config.LoginProtection{
+				Enabled: true,
+			},
+			EnableLDAP: false,
+			LDAP: config.LDAP{
+				UserFilter:        "(uid=%s)",
+				UsernameAttribute: "uid",
+				EmailAttribute:    "mail",
+				NicknameAttribute: "cn",
+				GroupAttribute:    "memberOf",
+				AutoProvision:     true,
+				TimeoutSeconds:    10,
+			},
 		},
 		Quota: config.Quota{
 			DefaultLevel: 1,
@@ -139,6 +152,9 @@ func getDefaultConfig() config.Server {
 			Password: "",
 			DB:       0,
 		},
+		RateLimit: config.RateLimit{
+			Enabled: true,
+		},
 	}
 }
 
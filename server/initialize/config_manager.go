@@ -200,6 +200,22 @@ func syncAuthConfig(authConfig map[string]interface{}) {
 		global.APP_LOG.Info("同步enable-oauth2", zap.Bool("value", enableOAuth2))
 	}
 
+	if enableLDAP, ok := authConfig["enableLDAP"].(bool); ok {
+		global.APP_CONFIG.Auth.EnableLDAP = enableLDAP
+		global.APP_LOG.Info("同步enableLDAP", zap.Bool("value", enableLDAP))
+	} else if enableLDAP, ok := authConfig["enable-ldap"].(bool); ok {
+		global.APP_CONFIG.Auth.EnableLDAP = enableLDAP
+		global.APP_LOG.Info("同步enable-ldap", zap.Bool("value", enableLDAP))
+	}
+
+	if twoFactorRequired, ok := authConfig["twoFactorRequired"].(string); ok {
+		global.APP_CONFIG.Auth.TwoFactorRequired = twoFactorRequired
+		global.APP_LOG.Info("同步twoFactorRequired", zap.String("value", twoFactorRequired))
+	} else if twoFactorRequired, ok := authConfig["two-factor-required"].(string); ok {
+		global.APP_CONFIG.Auth.TwoFactorRequired = twoFactorRequired
+		global.APP_LOG.Info("同步two-factor-required", zap.String("value", twoFactorRequired))
+	}
+
 	global.APP_LOG.Info("认证配置同步完成",
 		zap.Bool("EnableOAuth2", global.APP_CONFIG.Auth.EnableOAuth2),
 		zap.Bool("EnableEmail", global.APP_CONFIG.Auth.EnableEmail),
@@ -309,6 +325,66 @@ func syncQuotaConfig(quotaConfig map[string]interface{}) {
 						}
 					}
 
+					// 更新最大快照数量 - 支持驼峰和kebab-case
+					if maxSnapshots, exists := limitMap["maxSnapshots"]; exists {
+						if snapshots, ok := maxSnapshots.(float64); ok {
+							levelLimit.MaxSnapshots = int(snapshots)
+						} else if snapshots, ok := maxSnapshots.(int); ok {
+							levelLimit.MaxSnapshots = snapshots
+						}
+					} else if maxSnapshots, exists := limitMap["max-snapshots"]; exists {
+						if snapshots, ok := maxSnapshots.(float64); ok {
+							levelLimit.MaxSnapshots = int(snapshots)
+						} else if snapshots, ok := maxSnapshots.(int); ok {
+							levelLimit.MaxSnapshots = snapshots
+						}
+					}
+
+					// 更新最大备份数量 - 支持驼峰和kebab-case
+					if maxBackups, exists := limitMap["maxBackups"]; exists {
+						if backups, ok := maxBackups.(float64); ok {
+							levelLimit.MaxBackups = int(backups)
+						} else if backups, ok := maxBackups.(int); ok {
+							levelLimit.MaxBackups = backups
+						}
+					} else if maxBackups, exists := limitMap["max-backups"]; exists {
+						if backups, ok := maxBackups.(float64); ok {
+							levelLimit.MaxBackups = int(backups)
+						} else if backups, ok := maxBackups.(int); ok {
+							levelLimit.MaxBackups = backups
+						}
+					}
+
+					// 更新端口映射数量 - 支持驼峰和kebab-case
+					if maxPorts, exists := limitMap["maxPorts"]; exists {
+						if ports, ok := maxPorts.(float64); ok {
+							levelLimit.MaxPorts = int(ports)
+						} else if ports, ok := maxPorts.(int); ok {
+							levelLimit.MaxPorts = ports
+						}
+					} else if maxPorts, exists := limitMap["max-ports"]; exists {
+						if ports, ok := maxPorts.(float64); ok {
+							levelLimit.MaxPorts = int(ports)
+						} else if ports, ok := maxPorts.(int); ok {
+							levelLimit.MaxPorts = ports
+						}
+					}
+
+					// 更新域名绑定数量 - 支持驼峰和kebab-case
+					if maxDomains, exists := limitMap["maxDomains"]; exists {
+						if domains, ok := maxDomains.(float64); ok {
+							levelLimit.MaxDomains = int(domains)
+						} else if domains, ok := maxDomains.(int); ok {
+							levelLimit.MaxDomains = domains
+						}
+					} else if maxDomains, exists := limitMap["max-domains"]; exists {
+						if domains, ok := maxDomains.(float64); ok {
+							levelLimit.MaxDomains = int(domains)
+						} else if domains, ok := maxDomains.(int); ok {
+							levelLimit.MaxDomains = domains
+						}
+					}
+
 					global.APP_CONFIG.Quota.LevelLimits[level] = levelLimit
 				}
 			}
@@ -159,19 +159,35 @@ func monitorConnectionPool(db *gorm.DB) {
 func RegisterTables(db *gorm.DB) {
 	err := db.AutoMigrate(
 		// 用户相关表
-		&userModel.User{},          // 用户基础信息表
-		&userModel.TrafficRecord{}, // 用户流量记录表
-		&authModel.Role{},          // 角色管理表
-		&userModel.UserRole{},      // 用户角色关联表
+		&userModel.User{},                   // 用户基础信息表
+		&userModel.TrafficRecord{},          // 用户流量记录表
+		&authModel.Role{},                   // 角色管理表
+		&userModel.UserRole{},               // 用户角色关联表
+		&userModel.UserSSHKey{},             // 用户SSH公钥表
+		&userModel.UserRecoveryCode{},       // 两步验证恢复码表
+		&userModel.UserAPIToken{},           // 个人API令牌表
+		&userModel.UserWebAuthnCredential{}, // 通行密钥表
+		&userModel.UserIdentity{},           // 第三方登录身份表
+
+		// 通知相关表
+		&userModel.UserNotification{},           // 站内通知表
+		&userModel.UserNotificationPreference{}, // 用户通知渠道偏好表
 
 		// OAuth2相关表
 		&oauth2Model.OAuth2Provider{}, // OAuth2提供商配置表
 
 		// 实例相关表
-		&providerModel.Instance{}, // 虚拟机/容器实例表
-		&providerModel.Provider{}, // 服务提供商配置表
-		&providerModel.Port{},     // 端口映射表
-		&adminModel.Task{},        // 用户任务表
+		&providerModel.Instance{},              // 虚拟机/容器实例表
+		&providerModel.Provider{},              // 服务提供商配置表
+		&providerModel.Port{},                  // 端口映射表
+		&providerModel.InstanceSnapshot{},      // 实例快照表
+		&providerModel.InstanceBackup{},        // 实例备份表
+		&providerModel.BackupSchedule{},        // 定时备份计划表
+		&providerModel.InstanceDomain{},        // 实例域名绑定表
+		&providerModel.SecurityGroup{},         // 安全组表
+		&providerModel.SecurityGroupRule{},     // 安全组规则表
+		&providerModel.InstanceSecurityGroup{}, // 实例安全组绑定表
+		&adminModel.Task{},                     // 用户任务表
 
 		// 资源管理表
 		&resourceModel.ResourceReservation{}, // 资源预留表
@@ -180,6 +196,7 @@ func RegisterTables(db *gorm.DB) {
 		&userModel.VerifyCode{},    // 验证码表（邮箱/短信）
 		&userModel.PasswordReset{}, // 密码重置令牌表
 		&authModel.JWTBlacklist{},  // JWT黑名单表
+		&authModel.UserSession{},   // 用户登录会话表
 
 		// 系统配置表
 		&adminModel.SystemConfig{},  // 系统配置表
@@ -201,6 +218,10 @@ func RegisterTables(db *gorm.DB) {
 		// 管理员配置任务表
 		&adminModel.ConfigurationTask{}, // 管理员配置任务表
 
+		// Webhook相关表
+		&adminModel.Webhook{},         // 出站Webhook配置表
+		&adminModel.WebhookDelivery{}, // Webhook投递记录表
+
 		// 监控数据表
 		&monitoringModel.VnStatTrafficRecord{}, // vnStat流量记录表
 		&monitoringModel.VnStatInterface{},     // vnStat网络接口表
@@ -210,4 +231,6 @@ func RegisterTables(db *gorm.DB) {
 		return
 	}
 	global.APP_LOG.Info("数据库表注册成功")
+
+	migrateOAuth2Identities(db)
 }
@@ -0,0 +1,82 @@
+package initialize
+
+import (
+	"time"
+
+	"oneclickvirt/global"
+	userModel "oneclickvirt/model/user"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// legacyOAuth2User 旧版本users表中的OAuth2关联字段
+type legacyOAuth2User struct {
+	ID               uint
+	OAuth2ProviderID uint   `gorm:"column:o_auth2_provider_id"`
+	OAuth2UID        string `gorm:"column:o_auth2_uid"`
+	OAuth2Username   string `gorm:"column:o_auth2_username"`
+	OAuth2Email      string `gorm:"column:o_auth2_email"`
+	OAuth2Avatar     string `gorm:"column:o_auth2_avatar"`
+	OAuth2Extra      string `gorm:"column:o_auth2_extra"`
+}
+
+// migrateOAuth2Identities 将users表中旧的单一OAuth2关联迁移到user_identities表
+// 迁移后清空旧字段，重复执行不会产生重复数据
+func migrateOAuth2Identities(db *gorm.DB) {
+	if !db.Migrator().HasColumn(&userModel.User{}, "o_auth2_provider_id") ||
+		!db.Migrator().HasColumn(&userModel.User{}, "o_auth2_uid") {
+		return
+	}
+
+	var legacyUsers []legacyOAuth2User
+	if err := db.Model(&userModel.User{}).
+		Select("id, o_auth2_provider_id, o_auth2_uid, o_auth2_username, o_auth2_email, o_auth2_avatar, o_auth2_extra").
+		Where("o_auth2_provider_id > 0 AND o_auth2_uid <> ''").
+		Find(&legacyUsers).Error; err != nil {
+		global.APP_LOG.Error("读取旧OAuth2关联失败", zap.Error(err))
+		return
+	}
+	if len(legacyUsers) == 0 {
+		return
+	}
+
+	migrated := 0
+	for _, legacy := range legacyUsers {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			now := time.Now()
+			identity := userModel.UserIdentity{
+				UserID:      legacy.ID,
+				ProviderID:  legacy.OAuth2ProviderID,
+				UID:         legacy.OAuth2UID,
+				Username:    legacy.OAuth2Username,
+				Email:       legacy.OAuth2Email,
+				Avatar:      legacy.OAuth2Avatar,
+				Extra:       legacy.OAuth2Extra,
+				LastLoginAt: &now,
+			}
+			if err := tx.Where("provider_id = ? AND uid = ?", legacy.OAuth2ProviderID, legacy.OAuth2UID).
+				FirstOrCreate(&identity).Error; err != nil {
+				return err
+			}
+			if identity.UserID != legacy.ID {
+				global.APP_LOG.Warn("OAuth2身份已绑定到其他用户，跳过迁移",
+					zap.Uint("userID", legacy.ID),
+					zap.Uint("identityUserID", identity.UserID),
+					zap.Uint("providerID", legacy.OAuth2ProviderID))
+			}
+			// 旧版本无法区分密码是否为注册时随机生成（之后可能已被用户修改或管理员重置），password_generated保持默认值
+			return tx.Model(&userModel.User{}).Where("id = ?", legacy.ID).Updates(map[string]interface{}{
+				"o_auth2_provider_id": 0,
+				"o_auth2_uid":         "",
+			}).Error
+		})
+		if err != nil {
+			global.APP_LOG.Error("迁移OAuth2身份失败", zap.Uint("userID", legacy.ID), zap.Error(err))
+			continue
+		}
+		migrated++
+	}
+
+	global.APP_LOG.Info("OAuth2身份迁移完成", zap.Int("migrated", migrated), zap.Int("total", len(legacyUsers)))
+}
@@ -0,0 +1,41 @@
+package initialize
+
+import (
+	"context"
+	"time"
+
+	"oneclickvirt/global"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Redis 初始化Redis客户端，未启用或连接失败时返回nil
+func Redis() *redis.Client {
+	if !global.APP_CONFIG.System.UseRedis {
+		return nil
+	}
+
+	redisCfg := global.APP_CONFIG.Redis
+	if redisCfg.Addr == "" {
+		global.APP_LOG.Warn("已启用Redis但未配置redis.addr，使用内存存储")
+		return nil
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     redisCfg.Addr,
+		Password: redisCfg.Password,
+		DB:       redisCfg.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		global.APP_LOG.Error("Redis连接失败，使用内存存储", zap.String("addr", redisCfg.Addr), zap.Error(err))
+		client.Close()
+		return nil
+	}
+
+	global.APP_LOG.Info("Redis连接成功", zap.String("addr", redisCfg.Addr), zap.Int("db", redisCfg.DB))
+	return client
+}
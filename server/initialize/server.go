@@ -56,6 +56,11 @@ func InitServer(address string, router *gin.Engine) *http.Server {
 			}
 		}
 
+		// 关闭Redis连接
+		if global.APP_REDIS != nil {
+			global.APP_REDIS.Close()
+		}
+
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		if err := s.Shutdown(ctx); err != nil {
@@ -22,6 +22,7 @@ import (
 	_ "oneclickvirt/provider/portmapping/incus"
 	_ "oneclickvirt/provider/portmapping/iptables"
 	_ "oneclickvirt/provider/portmapping/lxd"
+	_ "oneclickvirt/provider/portmapping/nftables"
 
 	"go.uber.org/zap"
 )
@@ -46,6 +47,9 @@ func InitializeSystem() {
 
 	// 尝试连接数据库，但不强制要求成功
 	global.APP_DB = Gorm()
+
+	// 启用Redis时连接Redis，连接失败时限流等功能回退到内存存储
+	global.APP_REDIS = Redis()
 	isSystemInitialized := CheckSystemInitialized()
 
 	if isSystemInitialized {
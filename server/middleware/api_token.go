@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"encoding/json"
+	"time"
+
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	"oneclickvirt/model/auth"
+	"oneclickvirt/model/common"
+	userModel "oneclickvirt/model/user"
+	auth2 "oneclickvirt/service/auth"
+	"oneclickvirt/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	apiTokenContextKey      = "api_token"       // 使用API令牌认证时保存令牌信息
+	apiTokenScopeContextKey = "api_token_scope" // 本次请求使用的权限范围
+)
+
+// validateAPIToken 验证个人API令牌，检查权限范围并获取最新用户权限
+func validateAPIToken(c *gin.Context, raw string) (*auth.AuthContext, error) {
+	scope, ok := auth2.RequiredAPITokenScope(c.Request.Method, c.FullPath(), c.IsWebsocket())
+	if !ok {
+		return nil, common.NewError(common.CodeForbidden, "该接口不支持使用API令牌访问")
+	}
+
+	apiTokenService := auth2.APITokenService{}
+	token, err := apiTokenService.Authenticate(raw, c.ClientIP())
+	if err != nil {
+		return nil, err
+	}
+	if !auth2.HasAPITokenScope(token, scope) {
+		return nil, common.NewError(common.CodeForbidden, "API令牌缺少权限范围: "+scope)
+	}
+
+	authCtx, err := getUserAuthInfo(token.UserID)
+	if err != nil {
+		return nil, common.NewError(common.CodeUnauthorized, "获取用户权限失败")
+	}
+
+	c.Set(apiTokenContextKey, token)
+	c.Set(apiTokenScopeContextKey, scope)
+	return authCtx, nil
+}
+
+// recordAPITokenAudit 记录使用API令牌访问接口的审计日志
+func recordAPITokenAudit(c *gin.Context, authCtx *auth.AuthContext, startTime time.Time) {
+	value, exists := c.Get(apiTokenContextKey)
+	if !exists {
+		return
+	}
+	token, ok := value.(*userModel.UserAPIToken)
+	if !ok {
+		return
+	}
+
+	request, _ := json.Marshal(map[string]interface{}{
+		"authType":     "api_token",
+		"apiTokenId":   token.ID,
+		"apiTokenName": token.Name,
+		"scope":        c.GetString(apiTokenScopeContextKey),
+		"query":        c.Request.URL.RawQuery,
+	})
+
+	userID := authCtx.UserID
+	auditLog := adminModel.AuditLog{
+		UserID:     &userID,
+		Username:   authCtx.Username,
+		Method:     c.Request.Method,
+		Path:       utils.TruncateString(c.Request.URL.Path, 255),
+		StatusCode: c.Writer.Status(),
+		Latency:    time.Since(startTime).Milliseconds(),
+		ClientIP:   c.ClientIP(),
+		UserAgent:  utils.TruncateString(c.Request.UserAgent(), 255),
+		Request:    string(request),
+	}
+	if err := global.APP_DB.Create(&auditLog).Error; err != nil {
+		global.APP_LOG.Error("写入API令牌审计日志失败",
+			zap.Uint("userID", authCtx.UserID),
+			zap.Uint("tokenID", token.ID),
+			zap.Error(err))
+	}
+}
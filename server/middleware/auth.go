@@ -5,6 +5,7 @@ import (
 	"net/http"
 	auth2 "oneclickvirt/service/auth"
 	"strings"
+	"time"
 
 	"oneclickvirt/global"
 	"oneclickvirt/model/auth"
@@ -35,7 +36,9 @@ func RequireAuth(minLevel auth.AuthLevel) gin.HandlerFunc {
 			return
 		}
 
-		// 验证JWT Token并获取最新权限
+		startTime := time.Now()
+
+		// 验证JWT Token（或个人API令牌）并获取最新权限
 		authCtx, err := validateJWTToken(c)
 		if err != nil {
 			respondAuthError(c, err)
@@ -69,11 +72,17 @@ func RequireAuth(minLevel auth.AuthLevel) gin.HandlerFunc {
 		c.Set("user_type", authCtx.UserType)
 
 		c.Next()
+
+		recordAPITokenAudit(c, authCtx, startTime)
 	}
 }
 
-// RequireResourcePermission 基于资源的权限验证中间件
-func RequireResourcePermission(resource string) gin.HandlerFunc {
+// GrantedPermissionKey 通过RequireResourcePermission校验的权限在gin.Context中的键
+const GrantedPermissionKey = "granted_permission"
+
+// RequireResourcePermission 操作权限验证中间件，需挂在RequireAuth之后
+// 管理员拥有全部权限，其他用户按已分配角色的权限校验，见model/auth.AllPermissions
+func RequireResourcePermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 先确保用户已通过基础认证
 		authCtx, exists := GetAuthContext(c)
@@ -86,25 +95,44 @@ func RequireResourcePermission(resource string) gin.HandlerFunc {
 			return
 		}
 
-		// 使用权限服务进行精确的资源权限检查
 		permissionService := auth2.PermissionService{}
-		path := c.Request.URL.Path
-		method := c.Request.Method
+		if !permissionService.HasActionPermission(authCtx.UserID, permission) {
+			global.APP_LOG.Debug("用户权限不足", zap.Uint("userID", authCtx.UserID), zap.String("userType", authCtx.UserType), zap.String("permission", permission), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
+			c.JSON(http.StatusForbidden, common.Response{
+				Code: 403,
+				Msg:  "权限不足",
+			})
+			c.Abort()
+			return
+		}
 
-		// 检查用户是否有访问该资源的权限
-		hasPermission, err := permissionService.CanAccessResource(authCtx.UserID, path, method)
-		if err != nil {
-			global.APP_LOG.Warn("权限检查失败", zap.String("error", utils.FormatError(err)), zap.Uint("userID", authCtx.UserID), zap.String("resource", resource), zap.String("path", path), zap.String("method", method))
-			c.JSON(http.StatusInternalServerError, common.Response{
-				Code: 500,
-				Msg:  "权限检查失败",
+		c.Set(GrantedPermissionKey, permission)
+		c.Next()
+	}
+}
+
+// RequireAdminConsole 管理后台访问中间件，需挂在RequireAuth之后
+// 管理员或拥有任一操作权限的用户可以进入管理后台，具体接口再由RequireResourcePermission校验
+func RequireAdminConsole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authCtx, exists := GetAuthContext(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, common.Response{
+				Code: 401,
+				Msg:  "用户未认证",
 			})
 			c.Abort()
 			return
 		}
 
-		if !hasPermission {
-			global.APP_LOG.Debug("用户权限不足", zap.Uint("userID", authCtx.UserID), zap.String("userType", authCtx.UserType), zap.String("resource", resource), zap.String("path", path), zap.String("method", method))
+		permissionService := auth2.PermissionService{}
+		perms, err := permissionService.GetUserActionPermissions(authCtx.UserID)
+		if err != nil || len(perms) == 0 {
+			global.APP_LOG.Warn("无权访问管理后台",
+				zap.Uint("userID", authCtx.UserID),
+				zap.String("username", authCtx.Username),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("method", c.Request.Method))
 			c.JSON(http.StatusForbidden, common.Response{
 				Code: 403,
 				Msg:  "权限不足",
@@ -120,6 +148,13 @@ func RequireResourcePermission(resource string) gin.HandlerFunc {
 // validateJWTToken 验证JWT Token并获取最新用户权限
 func validateJWTToken(c *gin.Context) (*auth.AuthContext, error) {
 	token := c.GetHeader("Authorization")
+	// 浏览器发起WebSocket连接时无法设置请求头，通过一次性票据换取签发票据时使用的令牌，
+	// 避免完整令牌出现在URL和访问日志中
+	if token == "" && c.IsWebsocket() {
+		if ticket := c.Query("ws_ticket"); ticket != "" {
+			token, _ = auth2.RedeemWebSocketTicket(ticket)
+		}
+	}
 	if token == "" {
 		return nil, common.NewError(common.CodeUnauthorized, "未提供认证令牌")
 	}
@@ -128,6 +163,11 @@ func validateJWTToken(c *gin.Context) (*auth.AuthContext, error) {
 		token = after
 	}
 
+	// 个人API令牌
+	if strings.HasPrefix(token, auth2.APITokenPrefix) {
+		return validateAPIToken(c, token)
+	}
+
 	// 使用JWT验证逻辑
 	claims, err := utils.ValidateToken(token)
 	if err != nil {
@@ -156,11 +196,33 @@ func validateJWTToken(c *gin.Context) (*auth.AuthContext, error) {
 		return nil, common.NewError(common.CodeUnauthorized, "无效的用户信息")
 	}
 
+	// 检查用户的Token是否已被统一撤销（如管理员重置两步验证）
+	issuedAt, _ := (*claims)["iat"].(float64)
+	if blacklistService.IsUserTokenRevoked(uint(userID), int64(issuedAt)) {
+		global.APP_LOG.Warn("尝试使用已统一撤销的Token",
+			zap.Uint("userID", uint(userID)),
+			zap.String("jti", jti))
+		return nil, common.NewError(common.CodeUnauthorized, "认证令牌已失效")
+	}
+
+	// 检查登录会话是否已被单独撤销，并记录最近访问时间和IP
+	sessionService := auth2.SessionService{}
+	if err := sessionService.TouchSession(*claims, uint(userID), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		if appErr, ok := err.(*common.AppError); ok {
+			global.APP_LOG.Warn("尝试使用已撤销的会话",
+				zap.Uint("userID", uint(userID)),
+				zap.String("jti", jti))
+			return nil, appErr
+		}
+		global.APP_LOG.Warn("更新登录会话失败", zap.String("jti", jti), zap.Error(err))
+	}
+
 	// 从数据库获取用户当前状态和权限（不依赖JWT中的用户类型）
 	userAuth, err := getUserAuthInfo(uint(userID))
 	if err != nil {
 		return nil, common.NewError(common.CodeUnauthorized, "获取用户权限失败")
 	}
+	userAuth.TokenID = jti
 
 	return userAuth, nil
 }
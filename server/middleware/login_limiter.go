@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"oneclickvirt/model/common"
+	auth2 "oneclickvirt/service/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginRateLimit 认证接口IP限流中间件，scope区分不同接口的计数
+func LoginRateLimit(scope string) gin.HandlerFunc {
+	protectionService := auth2.LoginProtectionService{}
+	return func(c *gin.Context) {
+		if err := protectionService.CheckIP(scope, c.ClientIP()); err != nil {
+			common.ResponseWithError(c, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
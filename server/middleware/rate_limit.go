@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"oneclickvirt/config"
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	"oneclickvirt/service/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 限流分组
+const (
+	RateLimitGroupPublic = "public" // 公开接口，按IP计数
+	RateLimitGroupUser   = "user"   // 用户接口，按用户计数
+	RateLimitGroupAdmin  = "admin"  // 管理员接口，按用户计数
+	RateLimitGroupTask   = "task"   // 创建任务的接口，按用户计数，与用户接口分开计算
+)
+
+// 各分组的默认规则，配置中未设置时使用
+var defaultRateLimitRules = map[string]config.RateLimitRule{
+	RateLimitGroupPublic: {RequestsPerMinute: 120, Burst: 60},
+	RateLimitGroupUser:   {RequestsPerMinute: 300, Burst: 100},
+	RateLimitGroupAdmin:  {RequestsPerMinute: 600, Burst: 200},
+	RateLimitGroupTask:   {RequestsPerMinute: 10, Burst: 5},
+}
+
+// RateLimit 令牌桶限流中间件
+// 公开接口按客户端IP计数，需要认证的接口挂在RequireAuth之后，按用户计数并应用用户等级的限额
+func RateLimit(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rateLimitConfig := global.APP_CONFIG.RateLimit
+		if !rateLimitConfig.Enabled {
+			c.Next()
+			return
+		}
+
+		key := group + ":ip:" + c.ClientIP()
+		level := 0
+		if authCtx, exists := GetAuthContext(c); exists {
+			key = fmt.Sprintf("%s:user:%d", group, authCtx.UserID)
+			level = authCtx.Level
+		}
+		rule := resolveRateLimitRule(rateLimitConfig, group, level)
+
+		rate := float64(rule.RequestsPerMinute) / 60
+		result, err := ratelimit.GetStore().Take("api:"+key, rate, rule.Burst)
+		if err != nil {
+			// 计数存储不可用时放行，避免影响正常业务
+			global.APP_LOG.Warn("接口限流计数失败", zap.String("group", group), zap.Error(err))
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(rule.Burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			global.APP_LOG.Debug("接口请求被限流",
+				zap.String("group", group),
+				zap.String("key", key),
+				zap.String("path", c.Request.URL.Path))
+			common.ResponseWithError(c, common.NewError(common.CodeTooManyRequests, fmt.Sprintf("请在%d秒后重试", retryAfter)))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// resolveRateLimitRule 获取分组规则，用户和任务分组优先使用用户等级的限额
+func resolveRateLimitRule(cfg config.RateLimit, group string, level int) config.RateLimitRule {
+	var rule config.RateLimitRule
+	switch group {
+	case RateLimitGroupPublic:
+		rule = cfg.Public
+	case RateLimitGroupUser:
+		rule = cfg.User
+	case RateLimitGroupAdmin:
+		rule = cfg.Admin
+	case RateLimitGroupTask:
+		rule = cfg.Task
+	}
+
+	if levelRule, ok := cfg.LevelLimits[level]; ok {
+		switch group {
+		case RateLimitGroupUser:
+			rule = mergeRateLimitRule(levelRule.User, rule)
+		case RateLimitGroupTask:
+			rule = mergeRateLimitRule(levelRule.Task, rule)
+		}
+	}
+	return mergeRateLimitRule(rule, defaultRateLimitRules[group])
+}
+
+// mergeRateLimitRule 用fallback补全rule中未设置的数值
+func mergeRateLimitRule(rule, fallback config.RateLimitRule) config.RateLimitRule {
+	if rule.RequestsPerMinute <= 0 {
+		rule.RequestsPerMinute = fallback.RequestsPerMinute
+	}
+	if rule.Burst <= 0 {
+		rule.Burst = fallback.Burst
+	}
+	return rule
+}
+
+// ceilSeconds 将时长向上取整为秒
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
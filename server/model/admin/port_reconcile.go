@@ -0,0 +1,47 @@
+package admin
+
+import "time"
+
+// 端口映射漂移类型
+const (
+	PortDriftMissing     = "missing"     // 数据库中存在，主机上缺失
+	PortDriftOrphaned    = "orphaned"    // 主机上存在，数据库中没有对应记录
+	PortDriftConflicting = "conflicting" // 主机端口相同但转发目标与数据库不一致
+)
+
+// PortReconcileRequest 端口映射漂移检测请求
+type PortReconcileRequest struct {
+	ProviderID uint `json:"providerId" form:"providerId"` // Provider ID，为0时检测全部Provider
+}
+
+// PortDriftItem 单条端口映射漂移
+type PortDriftItem struct {
+	Type         string `json:"type"`         // 漂移类型：missing, orphaned, conflicting
+	PortID       uint   `json:"portId"`       // 数据库端口记录ID，orphaned时为0
+	InstanceID   uint   `json:"instanceId"`   // 实例ID
+	InstanceName string `json:"instanceName"` // 实例名称
+	Protocol     string `json:"protocol"`     // 协议：tcp, udp
+	HostPort     int    `json:"hostPort"`     // 宿主机端口
+	GuestPort    int    `json:"guestPort"`    // 数据库中的实例端口
+	LiveTarget   string `json:"liveTarget"`   // 主机上实际转发目标，如 10.0.0.2:22
+	Detail       string `json:"detail"`       // 说明
+	Fixed        bool   `json:"fixed"`        // 是否已修复
+	FixError     string `json:"fixError"`     // 修复失败原因
+}
+
+// PortReconcileReport 单个Provider的端口映射漂移检测报告
+type PortReconcileReport struct {
+	ProviderID       uint            `json:"providerId"`       // Provider ID
+	ProviderName     string          `json:"providerName"`     // Provider名称
+	Backend          string          `json:"backend"`          // 读取实际映射使用的后端：nftables, iptables, lxd, incus
+	Supported        bool            `json:"supported"`        // 该Provider是否支持漂移检测
+	AutoFix          bool            `json:"autoFix"`          // 是否执行了自动修复
+	CheckedAt        time.Time       `json:"checkedAt"`        // 检测时间
+	ExpectedCount    int             `json:"expectedCount"`    // 数据库中的映射数（按协议展开）
+	LiveCount        int             `json:"liveCount"`        // 主机上的映射数（按协议展开）
+	MissingCount     int             `json:"missingCount"`     // 缺失数
+	OrphanedCount    int             `json:"orphanedCount"`    // 多余数
+	ConflictingCount int             `json:"conflictingCount"` // 冲突数
+	Items            []PortDriftItem `json:"items"`            // 漂移明细
+	Error            string          `json:"error"`            // 检测失败原因
+}
@@ -378,6 +378,14 @@ type BatchDeletePortMappingRequest struct {
 	IDs []uint `json:"ids" binding:"required"`
 }
 
+// DomainListRequest 域名绑定列表请求，Keyword按域名搜索
+type DomainListRequest struct {
+	common.PageInfo
+	UserID     uint   `json:"userId" form:"userId"`
+	ProviderID uint   `json:"providerId" form:"providerId"`
+	Status     string `json:"status" form:"status"`
+}
+
 // ProviderPortConfigRequest Provider端口配置请求
 type ProviderPortConfigRequest struct {
 	DefaultPortCount int    `json:"defaultPortCount" binding:"min=1,max=50"`                                                         // 每个实例默认映射端口数量
@@ -396,6 +404,10 @@ type CreateInstanceTaskRequest struct {
 	BandwidthId string `json:"bandwidthId"`
 	Description string `json:"description"`
 	SessionId   string `json:"sessionId"` // 会话ID，用于新的资源预留机制
+
+	SSHKeyIds       []uint `json:"sshKeyIds,omitempty"`       // 创建完成后注入的用户SSH公钥ID
+	DisablePassword bool   `json:"disablePassword,omitempty"` // 注入公钥后禁用密码登录
+	UserData        string `json:"userData,omitempty"`        // 已校验的cloud-config或shell脚本
 }
 
 // InstanceOperationTaskRequest 实例操作任务数据结构（启动、停止、重启、重置）
@@ -434,3 +446,89 @@ type DeletePortMappingTaskRequest struct {
 	InstanceID uint `json:"instanceId"` // 实例ID
 	ProviderID uint `json:"providerId"` // Provider ID
 }
+
+// SnapshotTaskRequest 快照任务数据结构（创建、恢复、删除快照）
+type SnapshotTaskRequest struct {
+	SnapshotID uint `json:"snapshotId"` // 快照记录ID
+	InstanceID uint `json:"instanceId"` // 实例ID
+	ProviderID uint `json:"providerId"` // Provider ID
+}
+
+// BackupTaskRequest 备份任务数据结构（创建备份、恢复为新实例）
+type BackupTaskRequest struct {
+	BackupID   uint `json:"backupId"`   // 备份记录ID
+	InstanceID uint `json:"instanceId"` // 创建备份时为来源实例ID，恢复时为新实例ID
+	ProviderID uint `json:"providerId"` // Provider ID
+}
+
+// ResizeTaskRequest 调整实例规格任务数据结构，记录调整前后的规格以便失败时回滚
+type ResizeTaskRequest struct {
+	InstanceID   uint  `json:"instanceId"`   // 实例ID
+	ProviderID   uint  `json:"providerId"`   // Provider ID
+	CPU          int   `json:"cpu"`          // 调整后CPU核数
+	Memory       int64 `json:"memory"`       // 调整后内存（MB）
+	Disk         int64 `json:"disk"`         // 调整后磁盘（MB）
+	Bandwidth    int   `json:"bandwidth"`    // 调整后带宽（Mbps）
+	OldCPU       int   `json:"oldCpu"`       // 调整前CPU核数
+	OldMemory    int64 `json:"oldMemory"`    // 调整前内存（MB）
+	OldDisk      int64 `json:"oldDisk"`      // 调整前磁盘（MB）
+	OldBandwidth int   `json:"oldBandwidth"` // 调整前带宽（Mbps）
+}
+
+// ResizeInstanceRequest 管理员调整实例规格请求，数值为0的项保持不变
+type ResizeInstanceRequest struct {
+	CPU       int   `json:"cpu" binding:"omitempty,min=1"`       // CPU核数
+	Memory    int64 `json:"memory" binding:"omitempty,min=64"`   // 内存（MB）
+	Disk      int64 `json:"disk" binding:"omitempty,min=1"`      // 磁盘（MB），只支持扩容
+	Bandwidth int   `json:"bandwidth" binding:"omitempty,min=1"` // 带宽（Mbps）
+}
+
+// MigrateTaskRequest 迁移实例任务数据结构
+type MigrateTaskRequest struct {
+	InstanceID       uint `json:"instanceId"`       // 实例ID
+	SourceProviderID uint `json:"sourceProviderId"` // 源Provider ID
+	TargetProviderID uint `json:"targetProviderId"` // 目标Provider ID
+}
+
+// MigrateInstanceRequest 管理员迁移实例请求
+type MigrateInstanceRequest struct {
+	TargetProviderID uint `json:"targetProviderId" binding:"required"` // 目标Provider ID，必须与源Provider类型相同
+}
+
+// WebhookRequest 创建/更新Webhook请求
+type WebhookRequest struct {
+	Name        string   `json:"name" binding:"required,max=64"`            // Webhook名称
+	URL         string   `json:"url" binding:"required,url,max=512"`        // 接收事件的URL，只支持http和https
+	Secret      string   `json:"secret" binding:"omitempty,min=16,max=128"` // 签名密钥，创建时留空自动生成，更新时留空保持不变
+	Events      []string `json:"events" binding:"required,min=1"`           // 订阅的事件类型，"*"表示全部事件
+	Enabled     *bool    `json:"enabled"`                                   // 是否启用，默认启用
+	Description string   `json:"description" binding:"max=255"`             // 描述信息
+}
+
+// WebhookDeliveryListRequest Webhook投递记录列表请求
+type WebhookDeliveryListRequest struct {
+	common.PageInfo
+	EventType string `json:"eventType" form:"eventType"` // 事件类型
+	Status    string `json:"status" form:"status"`       // 投递状态：pending, delivering, success, failed
+}
+
+// RoleRequest 创建/更新角色请求
+type RoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=64"`       // 角色名称
+	Code        string   `json:"code" binding:"required,max=64"`       // 角色代码
+	Description string   `json:"description" binding:"max=255"`        // 角色描述
+	Remark      string   `json:"remark" binding:"max=255"`             // 备注信息
+	Permissions []string `json:"permissions"`                          // 操作权限列表，见GET /admin/permissions
+	Status      *int     `json:"status" binding:"omitempty,oneof=0 1"` // 角色状态：0=禁用，1=启用，默认启用
+}
+
+// UserRolesRequest 设置用户角色请求
+type UserRolesRequest struct {
+	RoleIDs []uint `json:"roleIds"` // 角色ID列表，为空表示清除所有角色
+}
+
+// LDAPTestRequest LDAP连接测试请求
+type LDAPTestRequest struct {
+	Username string `json:"username"` // 测试用户名，为空时只测试连接和服务账户绑定
+	Password string `json:"password"` // 测试用户密码，为空时只搜索用户和所属组
+}
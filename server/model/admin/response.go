@@ -173,3 +173,9 @@ type TestSSHConnectionResponse struct {
 	TestCount          int    `json:"testCount"`              // 测试次数
 	ErrorMessage       string `json:"errorMessage,omitempty"` // 错误信息（如果失败）
 }
+
+// MigrateTaskResponse 迁移实例任务响应
+type MigrateTaskResponse struct {
+	InstanceID uint `json:"instanceId"`
+	TaskID     uint `json:"taskId"`
+}
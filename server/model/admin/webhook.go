@@ -0,0 +1,56 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Webhook 出站Webhook订阅
+// 事件发生时向URL发送带HMAC签名的JSON请求，Events为逗号分隔的事件类型，"*"表示订阅全部事件
+type Webhook struct {
+	ID        uint           `json:"id" gorm:"primarykey"` // Webhook主键ID
+	CreatedAt time.Time      `json:"createdAt"`            // 创建时间
+	UpdatedAt time.Time      `json:"updatedAt"`            // 更新时间
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`       // 软删除时间
+
+	Name        string     `json:"name" gorm:"size:64;not null"`    // Webhook名称
+	URL         string     `json:"url" gorm:"size:512;not null"`    // 接收事件的URL
+	Secret      string     `json:"secret" gorm:"size:128;not null"` // HMAC-SHA256签名密钥
+	Events      string     `json:"events" gorm:"type:text"`         // 订阅的事件类型，逗号分隔
+	Enabled     bool       `json:"enabled" gorm:"default:true"`     // 是否启用
+	Description string     `json:"description" gorm:"size:255"`     // 描述信息
+	CreatedBy   uint       `json:"createdBy"`                       // 创建者ID
+	LastStatus  string     `json:"lastStatus" gorm:"size:16"`       // 最近一次投递结果：success, failed
+	LastError   string     `json:"lastError" gorm:"size:512"`       // 最近一次投递失败原因
+	LastSentAt  *time.Time `json:"lastSentAt"`                      // 最近一次投递时间
+}
+
+// WebhookDelivery Webhook投递记录
+// 每个订阅者对每个事件生成一条记录，失败后按退避间隔重试，超过最大次数后标记为failed
+type WebhookDelivery struct {
+	ID        uint      `json:"id" gorm:"primarykey"`                                                              // 投递记录主键ID
+	UUID      string    `json:"uuid" gorm:"uniqueIndex;not null;size:36"`                                          // 投递唯一标识，随请求头发送，用于接收方去重
+	CreatedAt time.Time `json:"createdAt" gorm:"index"`                                                            // 创建时间
+	UpdatedAt time.Time `json:"updatedAt"`                                                                         // 更新时间
+	WebhookID uint      `json:"webhookId" gorm:"not null;index"`                                                   // 所属Webhook ID
+	EventType string    `json:"eventType" gorm:"size:64;not null;index"`                                           // 事件类型
+	Payload   string    `json:"payload" gorm:"type:text"`                                                          // 请求体JSON，重试时原样发送
+	Status    string    `json:"status" gorm:"size:16;default:pending;index:idx_webhook_delivery_retry,priority:1"` // 投递状态：pending, delivering, success, failed
+
+	Attempts     int        `json:"attempts" gorm:"default:0"`                                      // 已尝试次数
+	NextRetryAt  *time.Time `json:"nextRetryAt" gorm:"index:idx_webhook_delivery_retry,priority:2"` // 下次重试时间
+	ResponseCode int        `json:"responseCode"`                                                   // 最近一次响应状态码
+	ResponseBody string     `json:"responseBody" gorm:"type:text"`                                  // 最近一次响应内容（截断）
+	Error        string     `json:"error" gorm:"size:512"`                                          // 最近一次失败原因
+	DeliveredAt  *time.Time `json:"deliveredAt"`                                                    // 投递成功时间
+	DurationMs   int64      `json:"durationMs"`                                                     // 最近一次请求耗时（毫秒）
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.UUID == "" {
+		d.UUID = uuid.New().String()
+	}
+	return nil
+}
@@ -18,4 +18,5 @@ type AuthContext struct {
 	BaseUserType string   `json:"base_user_type"` // 用户基础类型
 	AllUserTypes []string `json:"all_user_types"` // 用户拥有的所有权限类型
 	IsEffective  bool     `json:"is_effective"`   // 权限是否有效
+	TokenID      string   `json:"-"`              // 当前JWT的JTI，个人API令牌认证时为空
 }
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -42,4 +43,23 @@ type Role struct {
 	Code        string `json:"code" gorm:"size:64"`                      // 角色代码（用于业务逻辑识别）
 	Status      int    `json:"status" gorm:"default:1"`                  // 角色状态：0=禁用，1=启用
 	Remark      string `json:"remark" gorm:"size:255"`                   // 备注信息
+	Permissions string `json:"permissions" gorm:"type:text"`             // 逗号分隔的操作权限，"*"表示全部权限
+	IsSystem    bool   `json:"isSystem" gorm:"default:false"`            // 内置角色，不可修改和删除
+}
+
+// GetPermissions 获取角色的操作权限列表
+func (r *Role) GetPermissions() []string {
+	if r.Permissions == "" {
+		return []string{}
+	}
+	perms := strings.Split(r.Permissions, ",")
+	for i, p := range perms {
+		perms[i] = strings.TrimSpace(p)
+	}
+	return perms
+}
+
+// SetPermissions 设置角色的操作权限列表
+func (r *Role) SetPermissions(perms []string) {
+	r.Permissions = strings.Join(perms, ",")
 }
@@ -0,0 +1,72 @@
+package auth
+
+import "strings"
+
+// 操作权限，分配给角色后由middleware.RequireResourcePermission在管理接口上校验
+// 同一模块的manage权限包含对应的view权限
+const (
+	PermissionAll = "*" // 全部权限，仅内置管理员角色使用
+
+	PermissionDashboardView     = "dashboard.view"
+	PermissionUserView          = "user.view"
+	PermissionUserManage        = "user.manage"
+	PermissionInstanceViewAny   = "instance.view.any"
+	PermissionInstanceManageAny = "instance.manage.any"
+	PermissionProviderView      = "provider.view"
+	PermissionProviderManage    = "provider.manage"
+	PermissionTrafficView       = "traffic.view"
+	PermissionTrafficManage     = "traffic.manage"
+	PermissionTaskView          = "task.view"
+	PermissionTaskManage        = "task.manage"
+	PermissionContentView       = "content.view"
+	PermissionContentManage     = "content.manage"
+	PermissionAuditView         = "audit.view"
+	PermissionRoleManage        = "role.manage"
+	PermissionSystemManage      = "system.manage"
+)
+
+// PermissionInfo 操作权限说明
+type PermissionInfo struct {
+	Code        string `json:"code"`
+	Group       string `json:"group"`
+	Description string `json:"description"`
+}
+
+// AllPermissions 可分配给角色的操作权限
+var AllPermissions = []PermissionInfo{
+	{Code: PermissionDashboardView, Group: "仪表盘", Description: "查看管理仪表盘和系统监控"},
+	{Code: PermissionUserView, Group: "用户", Description: "查看用户列表、会话和配额"},
+	{Code: PermissionUserManage, Group: "用户", Description: "创建、修改、禁用、删除用户，重置密码和两步验证"},
+	{Code: PermissionInstanceViewAny, Group: "实例", Description: "查看所有用户的实例、快照、备份和端口映射"},
+	{Code: PermissionInstanceManageAny, Group: "实例", Description: "操作和删除任意用户的实例，管理快照、备份和端口映射"},
+	{Code: PermissionProviderView, Group: "节点", Description: "查看节点、节点状态和系统镜像"},
+	{Code: PermissionProviderManage, Group: "节点", Description: "添加、修改、冻结、删除节点，管理系统镜像和节点配置"},
+	{Code: PermissionTrafficView, Group: "流量", Description: "查看流量统计"},
+	{Code: PermissionTrafficManage, Group: "流量", Description: "同步流量数据，调整流量限制"},
+	{Code: PermissionTaskView, Group: "任务", Description: "查看任务列表和统计"},
+	{Code: PermissionTaskManage, Group: "任务", Description: "取消和强制停止任务"},
+	{Code: PermissionContentView, Group: "内容", Description: "查看公告和邀请码"},
+	{Code: PermissionContentManage, Group: "内容", Description: "管理公告，生成、导出和删除邀请码"},
+	{Code: PermissionAuditView, Group: "审计", Description: "查看操作日志"},
+	{Code: PermissionRoleManage, Group: "角色", Description: "管理角色并为用户分配角色，只能授予自己拥有的权限"},
+	{Code: PermissionSystemManage, Group: "系统", Description: "修改系统配置、实例类型权限、Webhook和OAuth2提供商"},
+}
+
+// IsValidPermission 检查是否为可分配的操作权限
+func IsValidPermission(code string) bool {
+	for _, p := range AllPermissions {
+		if p.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionGrants 检查已授予的权限是否满足要求的权限
+func PermissionGrants(granted, required string) bool {
+	if granted == PermissionAll || granted == required {
+		return true
+	}
+	// manage权限包含同模块的view权限，如instance.manage.any包含instance.view.any
+	return strings.Contains(required, ".view") && strings.Replace(required, ".view", ".manage", 1) == granted
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"time"
+)
+
+// UserSession 用户登录会话
+// 每签发一个JWT记录一条会话，用于展示登录设备以及单独撤销某个会话
+type UserSession struct {
+	ID        uint      `json:"id" gorm:"primarykey"` // 会话主键ID
+	CreatedAt time.Time `json:"createdAt"`            // 登录时间
+	UpdatedAt time.Time `json:"updatedAt"`            // 更新时间
+
+	UserID     uint       `json:"userId" gorm:"not null;index"`           // 用户ID
+	JTI        string     `json:"-" gorm:"uniqueIndex;not null;size:128"` // JWT Token ID
+	IP         string     `json:"ip" gorm:"size:64"`                      // 登录IP
+	UserAgent  string     `json:"userAgent" gorm:"size:512"`              // 登录设备User-Agent
+	LastSeenIP string     `json:"lastSeenIp" gorm:"size:64"`              // 最近访问IP
+	LastSeenAt time.Time  `json:"lastSeenAt"`                             // 最近访问时间
+	ExpiresAt  time.Time  `json:"expiresAt" gorm:"not null;index"`        // Token过期时间
+	RevokedAt  *time.Time `json:"revokedAt"`                              // 撤销时间
+	RevokedBy  uint       `json:"-" gorm:"default:0"`                     // 撤销操作者ID
+	Reason     string     `json:"-" gorm:"size:100"`                      // 撤销原因：logout, revoke, admin, security
+}
+
+// TableName 指定表名
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// SessionInfo 会话信息
+type SessionInfo struct {
+	UserSession
+	Current bool `json:"current"` // 是否为当前请求使用的会话
+}
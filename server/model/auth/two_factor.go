@@ -0,0 +1,46 @@
+package auth
+
+// TwoFactorChallengeResponse 登录需要两步验证时的响应
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"` // 固定为true，表示需要完成两步验证才能获得令牌
+	ChallengeToken    string `json:"challengeToken"`    // 两步验证挑战令牌，5分钟内有效
+	EnrollRequired    bool   `json:"enrollRequired"`    // 管理员要求启用两步验证但用户尚未绑定，需先绑定
+	PasskeyAvailable  bool   `json:"passkeyAvailable"`  // 用户已绑定通行密钥，可使用通行密钥完成验证
+}
+
+// TwoFactorLoginRequest 两步验证登录请求
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"` // 登录时返回的挑战令牌
+	Code           string `json:"code"`                              // 6位TOTP验证码
+	RecoveryCode   string `json:"recoveryCode"`                      // 恢复码，与验证码二选一
+}
+
+// TwoFactorChallengeRequest 登录时绑定两步验证请求
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"` // 登录时返回的挑战令牌
+}
+
+// TwoFactorCodeRequest 两步验证码请求
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code"`         // 6位TOTP验证码
+	RecoveryCode string `json:"recoveryCode"` // 恢复码，仅关闭两步验证时可用
+}
+
+// TwoFactorSetupResponse 两步验证绑定信息
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`     // Base32密钥，用于手动输入
+	OTPAuthURL string `json:"otpauthUrl"` // otpauth://协议地址
+	QRCode     string `json:"qrCode"`     // 二维码图片（data:image/png;base64）
+}
+
+// TwoFactorStatusResponse 两步验证状态
+type TwoFactorStatusResponse struct {
+	Enabled                bool  `json:"enabled"`                // 是否已启用
+	Required               bool  `json:"required"`               // 管理员策略是否要求启用
+	RecoveryCodesRemaining int64 `json:"recoveryCodesRemaining"` // 剩余可用恢复码数量
+}
+
+// TwoFactorRecoveryCodesResponse 恢复码响应，恢复码只在生成时返回一次
+type TwoFactorRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
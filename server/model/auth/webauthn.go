@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"encoding/json"
+)
+
+// PasskeyRegisterBeginRequest 开始注册通行密钥请求
+type PasskeyRegisterBeginRequest struct {
+	Name string `json:"name" binding:"max=64"` // 通行密钥名称，如"MacBook Touch ID"
+}
+
+// PasskeyFinishRequest 完成通行密钥注册或登录请求
+type PasskeyFinishRequest struct {
+	SessionID  string          `json:"sessionId" binding:"required"`  // 开始时返回的会话ID
+	Credential json.RawMessage `json:"credential" binding:"required"` // 浏览器navigator.credentials返回的凭据（JSON序列化）
+}
+
+// PasskeyTwoFactorBeginRequest 使用通行密钥完成两步验证的开始请求
+type PasskeyTwoFactorBeginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"` // 登录时返回的两步验证挑战令牌
+}
+
+// PasskeyTwoFactorFinishRequest 使用通行密钥完成两步验证请求
+type PasskeyTwoFactorFinishRequest struct {
+	ChallengeToken string          `json:"challengeToken" binding:"required"` // 登录时返回的两步验证挑战令牌
+	SessionID      string          `json:"sessionId" binding:"required"`      // 开始时返回的会话ID
+	Credential     json.RawMessage `json:"credential" binding:"required"`     // 浏览器navigator.credentials.get返回的凭据
+}
+
+// PasskeyBeginResponse 开始通行密钥注册或登录的响应
+type PasskeyBeginResponse struct {
+	SessionID string      `json:"sessionId"` // 完成时需要提交的会话ID
+	Options   interface{} `json:"options"`   // 传给navigator.credentials.create/get的参数（publicKey）
+}
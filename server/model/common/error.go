@@ -21,6 +21,7 @@ const (
 	CodeNotFound        = 1005
 	CodeConflict        = 1006
 	CodeValidationError = 1007
+	CodeTooManyRequests = 1008
 
 	// 用户相关错误 2000-2999
 	CodeUserNotFound       = 2001
@@ -29,6 +30,10 @@ const (
 	CodeInvalidCredentials = 2004
 	CodeUserDisabled       = 2005
 	CodeUserPermissionDeny = 2006
+	CodeTwoFactorInvalid   = 2007 // 两步验证码错误
+	CodeTwoFactorExpired   = 2008 // 两步验证挑战已过期
+	CodePasskeyInvalid     = 2009 // 通行密钥验证失败
+	CodeAccountLocked      = 2010 // 密码错误次数过多，账户被临时锁定
 
 	// 角色权限相关错误 3000-3999
 	CodeRoleNotFound       = 3001
@@ -47,6 +52,7 @@ const (
 	CodeTokenGenerateError      = 4006
 	CodeOAuth2Failed            = 4007 // OAuth2认证失败
 	CodeOAuth2RegistrationLimit = 4008 // OAuth2注册已达限制
+	CodeLDAPFailed              = 4009 // LDAP服务器连接或查询失败
 
 	// 系统相关错误 5000-5999
 	CodeConfigError      = 5001
@@ -67,12 +73,17 @@ var ErrorMessages = map[int]string{
 	CodeNotFound:                "资源不存在",
 	CodeConflict:                "资源冲突",
 	CodeValidationError:         "数据验证失败",
+	CodeTooManyRequests:         "请求过于频繁，请稍后再试",
 	CodeUserNotFound:            "用户不存在",
 	CodeUserExists:              "用户已存在",
 	CodeUsernameExists:          "用户名已存在",
 	CodeInvalidCredentials:      "用户名或密码错误",
 	CodeUserDisabled:            "用户已被禁用",
 	CodeUserPermissionDeny:      "用户权限不足",
+	CodeTwoFactorInvalid:        "两步验证码错误",
+	CodeTwoFactorExpired:        "两步验证已过期，请重新登录",
+	CodePasskeyInvalid:          "通行密钥验证失败",
+	CodeAccountLocked:           "密码错误次数过多，账户已被临时锁定",
 	CodeRoleNotFound:            "角色不存在",
 	CodeRoleExists:              "角色已存在",
 	CodePermissionDeny:          "权限不足",
@@ -87,6 +98,7 @@ var ErrorMessages = map[int]string{
 	CodeTokenGenerateError:      "令牌生成失败",
 	CodeOAuth2Failed:            "OAuth2认证失败",
 	CodeOAuth2RegistrationLimit: "OAuth2注册已达到限制",
+	CodeLDAPFailed:              "LDAP认证失败",
 	CodeConfigError:             "配置错误",
 	CodeDatabaseError:           "数据库错误",
 	CodeCacheError:              "缓存错误",
@@ -176,11 +188,11 @@ func ResponseSuccessWithPagination(c *gin.Context, data interface{}, total int64
 // 根据错误码获取HTTP状态码
 func getHTTPCode(code int) int {
 	switch code {
-	case CodeInvalidParam, CodeValidationError, CodeCaptchaInvalid, CodeCaptchaRequired, CodeInviteCodeInvalid, CodeInviteCodeExpired, CodeInviteCodeUsed:
+	case CodeInvalidParam, CodeValidationError, CodeCaptchaInvalid, CodeCaptchaRequired, CodeInviteCodeInvalid, CodeInviteCodeExpired, CodeInviteCodeUsed, CodeTwoFactorInvalid, CodePasskeyInvalid:
 		return http.StatusBadRequest
-	case CodeUnauthorized, CodeInvalidCredentials:
+	case CodeUnauthorized, CodeInvalidCredentials, CodeTwoFactorExpired:
 		return http.StatusUnauthorized
-	case CodeForbidden, CodePermissionDeny, CodeUserPermissionDeny, CodeUserDisabled:
+	case CodeForbidden, CodePermissionDeny, CodeUserPermissionDeny, CodeUserDisabled, CodeAccountLocked:
 		return http.StatusForbidden
 	case CodeNotFound, CodeUserNotFound, CodeRoleNotFound, CodePermissionNotFound:
 		return http.StatusNotFound
@@ -188,6 +200,8 @@ func getHTTPCode(code int) int {
 		return http.StatusConflict
 	case CodeRequestTooLarge:
 		return http.StatusRequestEntityTooLarge
+	case CodeTooManyRequests:
+		return http.StatusTooManyRequests
 	default:
 		return http.StatusInternalServerError
 	}
@@ -11,6 +11,7 @@ type AuthConfig struct {
 	EnableTelegram           bool   `json:"enableTelegram"`
 	EnableQQ                 bool   `json:"enableQQ"`
 	EnableOAuth2             bool   `json:"enableOAuth2"`             // 是否启用OAuth2登录
+	EnableLDAP               bool   `json:"enableLDAP"`               // 是否启用LDAP登录，连接参数在配置文件auth.ldap中设置
 	EnablePublicRegistration bool   `json:"enablePublicRegistration"` // 是否启用公开注册（无需邀请码）
 	EmailSMTPHost            string `json:"emailSMTPHost"`
 	EmailSMTPPort            int    `json:"emailSMTPPort"`
@@ -19,6 +20,7 @@ type AuthConfig struct {
 	TelegramBotToken         string `json:"telegramBotToken"`
 	QQAppID                  string `json:"qqAppID"`
 	QQAppKey                 string `json:"qqAppKey"`
+	TwoFactorRequired        string `json:"twoFactorRequired"` // 强制两步验证范围：空=不强制，admin=管理员，all=所有用户
 }
 
 type InviteCodeConfig struct {
@@ -34,7 +36,11 @@ type QuotaConfig struct {
 type LevelLimitInfo struct {
 	MaxInstances int                    `json:"maxInstances"`
 	MaxResources map[string]interface{} `json:"maxResources"`
-	MaxTraffic   int64                  `json:"maxTraffic"` // 最大流量限制(MB)
+	MaxTraffic   int64                  `json:"maxTraffic"`   // 最大流量限制(MB)
+	MaxSnapshots int                    `json:"maxSnapshots"` // 每个实例最大快照数量
+	MaxBackups   int                    `json:"maxBackups"`   // 每个用户最大备份数量
+	MaxPorts     int                    `json:"maxPorts"`     // 每个实例用户自行添加的端口映射数量
+	MaxDomains   int                    `json:"maxDomains"`   // 每个用户可绑定的域名数量
 }
 
 // DatabaseConfig 数据库初始化配置
@@ -22,9 +22,15 @@ type OAuth2Provider struct {
 	ClientID     string `json:"clientId" gorm:"not null;size:255"`     // OAuth2客户端ID
 	ClientSecret string `json:"clientSecret" gorm:"not null;size:255"` // OAuth2客户端密钥
 	RedirectURL  string `json:"redirectUrl" gorm:"not null;size:512"`  // OAuth2回调地址
-	AuthURL      string `json:"authUrl" gorm:"not null;size:512"`      // OAuth2授权地址
-	TokenURL     string `json:"tokenUrl" gorm:"not null;size:512"`     // OAuth2令牌地址
-	UserInfoURL  string `json:"userInfoUrl" gorm:"not null;size:512"`  // OAuth2用户信息地址
+	AuthURL      string `json:"authUrl" gorm:"not null;size:512"`      // OAuth2授权地址（OIDC类型为空时使用发现的地址）
+	TokenURL     string `json:"tokenUrl" gorm:"not null;size:512"`     // OAuth2令牌地址（OIDC类型为空时使用发现的地址）
+	UserInfoURL  string `json:"userInfoUrl" gorm:"not null;size:512"`  // OAuth2用户信息地址（OIDC类型为空时使用发现的地址）
+	Scopes       string `json:"scopes" gorm:"size:512"`                // 授权范围，空格分隔，OIDC类型默认 openid profile email
+	UsePKCE      bool   `json:"usePkce" gorm:"default:false"`          // 是否使用PKCE（S256）保护授权码
+
+	// OpenID Connect配置
+	ProviderType string `json:"providerType" gorm:"default:oauth2;size:16"` // 提供商类型：oauth2=通用OAuth2，oidc=OpenID Connect
+	IssuerURL    string `json:"issuerUrl" gorm:"size:512"`                  // OIDC签发者地址，用于发现端点并校验ID Token
 
 	// 字段映射（支持嵌套字段，如 user.profile.name）
 	UserIDField   string `json:"userIdField" gorm:"default:id;size:128"`         // 用户ID字段映射
@@ -53,3 +59,14 @@ type OAuth2Provider struct {
 	// 显示顺序
 	Sort int `json:"sort" gorm:"default:0"` // 显示顺序，数字越小越靠前
 }
+
+// 提供商类型
+const (
+	ProviderTypeOAuth2 = "oauth2" // 通用OAuth2，用户信息来自用户信息接口
+	ProviderTypeOIDC   = "oidc"   // OpenID Connect，通过签发者发现端点并校验ID Token
+)
+
+// IsOIDC 是否为OpenID Connect提供商
+func (p *OAuth2Provider) IsOIDC() bool {
+	return p.ProviderType == ProviderTypeOIDC
+}
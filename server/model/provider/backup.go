@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InstanceBackup 实例备份模型
+// 备份文件保存在本地备份存储中，实例删除后备份仍然保留，可用于恢复为新实例
+type InstanceBackup struct {
+	// 基础字段
+	ID        uint           `json:"id" gorm:"primarykey"`                     // 备份主键ID
+	UUID      string         `json:"uuid" gorm:"uniqueIndex;not null;size:36"` // 备份唯一标识符，同时作为存储目录名
+	CreatedAt time.Time      `json:"createdAt"`                                // 创建时间
+	UpdatedAt time.Time      `json:"updatedAt"`                                // 更新时间
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`                           // 软删除时间
+
+	// 关联信息
+	InstanceID uint `json:"instanceId" gorm:"not null;index"`  // 来源实例ID
+	ProviderID uint `json:"providerId" gorm:"not null;index"`  // 来源Provider ID，恢复时只能恢复到同一Provider
+	UserID     uint `json:"userId" gorm:"not null;index"`      // 所属用户ID
+	ScheduleID uint `json:"scheduleId" gorm:"default:0;index"` // 关联的定时备份计划ID，0表示手动备份
+
+	// 来源实例信息快照，用于恢复为新实例
+	InstanceName string `json:"instanceName" gorm:"size:128"`                  // 来源实例名称
+	InstanceType string `json:"instanceType" gorm:"size:16;default:container"` // 实例类型：container, vm
+	ProviderType string `json:"providerType" gorm:"size:32"`                   // Provider类型
+	Image        string `json:"image" gorm:"size:128"`                         // 来源实例镜像
+	OSType       string `json:"osType" gorm:"size:64"`                         // 操作系统类型
+	CPU          int    `json:"cpu"`                                           // CPU核心数
+	Memory       int64  `json:"memory"`                                        // 内存大小（MB）
+	Disk         int64  `json:"disk"`                                          // 磁盘大小（MB）
+	Bandwidth    int    `json:"bandwidth"`                                     // 网络带宽（Mbps）
+	Username     string `json:"-" gorm:"size:64"`                              // 备份时的登录用户名
+	Password     string `json:"-" gorm:"size:128"`                             // 备份时的登录密码
+
+	// 备份文件信息
+	FileName     string     `json:"fileName" gorm:"size:255"`                  // 备份文件名（位于备份目录下）
+	FileSize     int64      `json:"fileSize" gorm:"default:0"`                 // 备份文件大小（字节）
+	Status       string     `json:"status" gorm:"default:creating;size:16"`    // 备份状态：creating, available, restoring, failed
+	TriggerType  string     `json:"triggerType" gorm:"default:manual;size:16"` // 触发方式：manual, scheduled
+	Description  string     `json:"description" gorm:"size:255"`               // 备份描述
+	TaskID       uint       `json:"taskId" gorm:"default:0"`                   // 最近一次操作关联的任务ID
+	ErrorMessage string     `json:"errorMessage" gorm:"size:512"`              // 失败原因
+	CompletedAt  *time.Time `json:"completedAt"`                               // 备份完成时间
+}
+
+func (b *InstanceBackup) BeforeCreate(tx *gorm.DB) error {
+	b.UUID = uuid.New().String()
+	return nil
+}
+
+// BackupSchedule 实例定时备份计划
+// 每个实例最多一个计划，按Cron表达式触发，定时备份超出保留数量时自动清理最旧的备份
+type BackupSchedule struct {
+	ID        uint      `json:"id" gorm:"primarykey"` // 计划主键ID
+	CreatedAt time.Time `json:"createdAt"`            // 创建时间
+	UpdatedAt time.Time `json:"updatedAt"`            // 更新时间
+
+	InstanceID uint       `json:"instanceId" gorm:"uniqueIndex;not null"` // 关联的实例ID
+	UserID     uint       `json:"userId" gorm:"not null;index"`           // 所属用户ID
+	CronExpr   string     `json:"cronExpr" gorm:"not null;size:64"`       // Cron表达式（分 时 日 月 周）
+	Retention  int        `json:"retention" gorm:"default:3"`             // 保留的定时备份数量
+	Enabled    bool       `json:"enabled" gorm:"default:true"`            // 是否启用
+	LastRunAt  *time.Time `json:"lastRunAt"`                              // 上次触发时间
+	NextRunAt  *time.Time `json:"nextRunAt" gorm:"index"`                 // 下次触发时间
+	LastError  string     `json:"lastError" gorm:"size:255"`              // 上次触发失败原因
+}
@@ -0,0 +1,23 @@
+package provider
+
+import "time"
+
+// InstanceDomain 实例域名绑定
+// 由Provider节点上的Caddy按域名（SNI）反向代理到实例端口，并通过ACME自动申请HTTPS证书。
+// 绑定前需在DNS中添加TXT记录证明域名所有权，同一域名同一时间只能有一个生效的绑定
+type InstanceDomain struct {
+	ID        uint      `json:"id" gorm:"primarykey"` // 绑定主键ID
+	CreatedAt time.Time `json:"createdAt"`            // 创建时间
+	UpdatedAt time.Time `json:"updatedAt"`            // 更新时间
+
+	UserID     uint `json:"userId" gorm:"not null;index"`     // 所属用户ID
+	InstanceID uint `json:"instanceId" gorm:"not null;index"` // 目标实例ID
+	ProviderID uint `json:"providerId" gorm:"not null;index"` // 实例所在Provider ID，反向代理运行在该节点上
+
+	Domain      string     `json:"domain" gorm:"size:253;not null;index"` // 绑定的域名（小写）
+	GuestPort   int        `json:"guestPort" gorm:"not null"`             // 实例内部HTTP服务端口
+	Status      string     `json:"status" gorm:"size:16;default:pending"` // 状态：pending(待验证), active(已生效), failed(配置失败)
+	VerifyToken string     `json:"verifyToken" gorm:"size:64;not null"`   // DNS TXT记录验证值
+	VerifiedAt  *time.Time `json:"verifiedAt"`                            // 所有权验证通过时间
+	LastError   string     `json:"lastError" gorm:"size:512"`             // 最近一次验证或配置失败原因
+}
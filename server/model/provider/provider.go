@@ -40,7 +40,7 @@ type Provider struct {
 	AllowClaim            bool   `json:"allowClaim" gorm:"default:true"`        // 是否允许用户使用此Provider
 
 	// 端口映射配置
-	IPv4PortMappingMethod string `json:"ipv4PortMappingMethod" gorm:"size:16;default:device_proxy"` // IPv4端口映射方式：device_proxy, iptables, native
+	IPv4PortMappingMethod string `json:"ipv4PortMappingMethod" gorm:"size:16;default:device_proxy"` // IPv4端口映射方式：device_proxy, iptables, nftables, native
 	IPv6PortMappingMethod string `json:"ipv6PortMappingMethod" gorm:"size:16;default:device_proxy"` // IPv6端口映射方式：device_proxy, iptables, native
 
 	// 配额管理
@@ -208,6 +208,11 @@ type Instance struct {
 	Username string `json:"username" gorm:"size:64"`  // 登录用户名
 	Password string `json:"password" gorm:"size:128"` // 登录密码
 
+	PasswordLoginDisabled bool `json:"passwordLoginDisabled" gorm:"default:false"` // 是否已禁用SSH密码登录，只允许公钥登录
+
+	// 初始化配置
+	UserData string `json:"-" gorm:"type:text"` // 创建时提供的cloud-config或shell脚本，重置实例时重新下发
+
 	// 系统信息
 	OSType string `json:"osType" gorm:"size:64"` // 操作系统类型：ubuntu, centos, debian等
 	Region string `json:"region" gorm:"size:64"` // 所在地区
@@ -259,7 +264,7 @@ type Port struct {
 	// IPv6支持
 	IPv6Enabled   bool   `json:"ipv6Enabled" gorm:"default:false"`            // 是否启用IPv6映射
 	IPv6Address   string `json:"ipv6Address" gorm:"size:64"`                  // IPv6映射地址
-	MappingMethod string `json:"mappingMethod" gorm:"size:32;default:native"` // 映射方法：native, iptables, gost, firewall
+	MappingMethod string `json:"mappingMethod" gorm:"size:32;default:native"` // 映射方法：native, iptables, nftables, gost, firewall
 }
 
 // PendingDeletion 待删除资源模型
@@ -319,6 +324,7 @@ type ProviderInstanceConfig struct {
 	Env          map[string]string `json:"env"`
 	Metadata     map[string]string `json:"metadata"`
 	InstanceType string            `json:"instance_type"` // container 或 vm
+	UserData     string            `json:"user_data"`     // 首次启动时执行的cloud-config或shell脚本
 }
 
 // ProviderNodeConfig 节点配置
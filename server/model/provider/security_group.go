@@ -0,0 +1,51 @@
+package provider
+
+import "time"
+
+// SecurityGroup 安全组
+// 由用户创建并绑定到自己的实例上，一个实例可绑定多个安全组，规则取并集。
+// 实例绑定了安全组后，未被允许的入站连接一律丢弃，拒绝规则优先于允许规则
+type SecurityGroup struct {
+	ID        uint      `json:"id" gorm:"primarykey"` // 安全组主键ID
+	CreatedAt time.Time `json:"createdAt"`            // 创建时间
+	UpdatedAt time.Time `json:"updatedAt"`            // 更新时间
+
+	UserID      uint   `json:"userId" gorm:"not null;index"` // 所属用户ID
+	Name        string `json:"name" gorm:"size:64;not null"` // 安全组名称
+	Description string `json:"description" gorm:"size:255"`  // 描述
+
+	Rules []SecurityGroupRule `json:"rules" gorm:"foreignKey:SecurityGroupID"` // 入站规则
+}
+
+// SecurityGroupRule 安全组入站规则
+type SecurityGroupRule struct {
+	ID        uint      `json:"id" gorm:"primarykey"` // 规则主键ID
+	CreatedAt time.Time `json:"createdAt"`            // 创建时间
+
+	SecurityGroupID uint   `json:"securityGroupId" gorm:"not null;index"` // 所属安全组ID
+	Action          string `json:"action" gorm:"size:8;not null"`         // 动作：allow, deny
+	Protocol        string `json:"protocol" gorm:"size:8;not null"`       // 协议：tcp, udp, icmp, all
+	PortStart       int    `json:"portStart"`                             // 起始端口，icmp和all协议为0
+	PortEnd         int    `json:"portEnd"`                               // 结束端口，单个端口时与起始端口相同
+	CIDR            string `json:"cidr" gorm:"size:64"`                   // 来源地址段，为空表示任意来源
+	Description     string `json:"description" gorm:"size:255"`           // 描述
+}
+
+// InstanceSecurityGroup 实例与安全组的绑定关系
+type InstanceSecurityGroup struct {
+	ID        uint      `json:"id" gorm:"primarykey"` // 绑定主键ID
+	CreatedAt time.Time `json:"createdAt"`            // 绑定时间
+
+	InstanceID      uint `json:"instanceId" gorm:"not null;uniqueIndex:idx_instance_security_group"`            // 实例ID
+	SecurityGroupID uint `json:"securityGroupId" gorm:"not null;uniqueIndex:idx_instance_security_group;index"` // 安全组ID
+}
+
+// ProviderFirewallRule 下发到Provider的实例入站防火墙规则（业务层结构体）
+// 目标端口为实例内部端口，NAT端口映射在转发前已完成目标地址转换
+type ProviderFirewallRule struct {
+	Action    string `json:"action"`    // allow, deny
+	Protocol  string `json:"protocol"`  // tcp, udp, icmp, all
+	PortStart int    `json:"portStart"` // 起始端口，0表示不限端口
+	PortEnd   int    `json:"portEnd"`   // 结束端口
+	CIDR      string `json:"cidr"`      // 来源地址段，为空表示任意来源
+}
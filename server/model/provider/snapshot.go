@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InstanceSnapshot 实例快照模型
+type InstanceSnapshot struct {
+	// 基础字段
+	ID        uint           `json:"id" gorm:"primarykey"`                     // 快照主键ID
+	UUID      string         `json:"uuid" gorm:"uniqueIndex;not null;size:36"` // 快照唯一标识符
+	CreatedAt time.Time      `json:"createdAt"`                                // 创建时间
+	UpdatedAt time.Time      `json:"updatedAt"`                                // 更新时间
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`                           // 软删除时间
+
+	// 关联信息
+	InstanceID uint `json:"instanceId" gorm:"not null;index"` // 关联的实例ID
+	ProviderID uint `json:"providerId" gorm:"not null;index"` // 关联的Provider ID
+	UserID     uint `json:"userId" gorm:"not null;index"`     // 所属用户ID
+
+	// 快照信息
+	Name        string     `json:"name" gorm:"not null;size:64"`           // 快照名称（在Provider上的实际名称）
+	Description string     `json:"description" gorm:"size:255"`            // 快照描述
+	Status      string     `json:"status" gorm:"default:creating;size:16"` // 快照状态：creating, available, restoring, deleting, failed
+	TaskID      uint       `json:"taskId" gorm:"default:0"`                // 最近一次操作关联的任务ID
+	RestoredAt  *time.Time `json:"restoredAt"`                             // 最近一次恢复时间
+}
+
+func (s *InstanceSnapshot) BeforeCreate(tx *gorm.DB) error {
+	s.UUID = uuid.New().String()
+	return nil
+}
+
+// ProviderSnapshot Provider侧快照信息（业务层结构体）
+type ProviderSnapshot struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Created     time.Time         `json:"created"`
+	Stateful    bool              `json:"stateful"` // 是否包含运行时内存状态
+	Metadata    map[string]string `json:"metadata"`
+}
@@ -0,0 +1,24 @@
+package user
+
+import (
+	"time"
+)
+
+// UserAPIToken 个人API令牌
+// 用于脚本等自动化场景，只保存令牌的SHA-256哈希，明文只在创建时返回一次
+type UserAPIToken struct {
+	ID        uint      `json:"id" gorm:"primarykey"` // 令牌主键ID
+	CreatedAt time.Time `json:"createdAt"`            // 创建时间
+	UpdatedAt time.Time `json:"updatedAt"`            // 更新时间
+
+	UserID      uint       `json:"userId" gorm:"not null;index"`          // 所属用户ID
+	Name        string     `json:"name" gorm:"size:64;not null"`          // 令牌名称
+	TokenPrefix string     `json:"tokenPrefix" gorm:"size:16"`            // 令牌前缀，便于用户识别
+	TokenHash   string     `json:"-" gorm:"size:64;not null;uniqueIndex"` // 令牌哈希
+	Scopes      string     `json:"scopes" gorm:"size:255"`                // 权限范围，逗号分隔，如instances:read,traffic:read
+	AllowedIPs  string     `json:"allowedIps" gorm:"type:text"`           // IP白名单，逗号分隔的IP或CIDR，为空表示不限制
+	ExpiresAt   *time.Time `json:"expiresAt"`                             // 过期时间，为空表示永不过期
+	LastUsedAt  *time.Time `json:"lastUsedAt"`                            // 最近使用时间
+	LastUsedIP  string     `json:"lastUsedIp" gorm:"size:64"`             // 最近使用的客户端IP
+	RevokedAt   *time.Time `json:"revokedAt"`                             // 撤销时间，为空表示有效
+}
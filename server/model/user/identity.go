@@ -0,0 +1,22 @@
+package user
+
+import (
+	"time"
+)
+
+// UserIdentity 用户绑定的第三方登录身份
+// 每个用户可以绑定多个OAuth2提供商账号，同一提供商账号只能绑定到一个用户
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primarykey"` // 身份主键ID
+	CreatedAt time.Time `json:"createdAt"`            // 绑定时间
+	UpdatedAt time.Time `json:"updatedAt"`            // 更新时间
+
+	UserID      uint       `json:"userId" gorm:"not null;index"`                                       // 所属用户ID
+	ProviderID  uint       `json:"providerId" gorm:"not null;uniqueIndex:idx_identity_provider_uid"`   // OAuth2提供商ID（关联oauth2_providers表）
+	UID         string     `json:"uid" gorm:"size:255;not null;uniqueIndex:idx_identity_provider_uid"` // 提供商返回的用户唯一标识
+	Username    string     `json:"username" gorm:"size:255"`                                           // 提供商返回的用户名
+	Email       string     `json:"email" gorm:"size:255"`                                              // 提供商返回的邮箱
+	Avatar      string     `json:"avatar" gorm:"size:512"`                                             // 提供商返回的头像URL
+	Extra       string     `json:"-" gorm:"type:text"`                                                 // 提供商返回的原始用户信息（JSON格式）
+	LastLoginAt *time.Time `json:"lastLoginAt"`                                                        // 最近一次通过该身份登录的时间
+}
@@ -0,0 +1,33 @@
+package user
+
+import (
+	"time"
+)
+
+// UserNotification 站内通知
+// 实例到期提醒、流量提醒、任务结果等通知都会写入站内信，外部渠道（邮件、Telegram等）按用户偏好额外发送
+type UserNotification struct {
+	ID        uint      `json:"id" gorm:"primarykey"`   // 通知主键ID
+	CreatedAt time.Time `json:"createdAt" gorm:"index"` // 创建时间
+	UpdatedAt time.Time `json:"updatedAt"`              // 更新时间
+
+	UserID   uint       `json:"userId" gorm:"not null;index:idx_user_notification_unread,priority:1"`      // 所属用户ID
+	Category string     `json:"category" gorm:"size:32;not null;index"`                                    // 通知分类：instance_expiry, traffic, task
+	Title    string     `json:"title" gorm:"size:255;not null"`                                            // 通知标题
+	Content  string     `json:"content" gorm:"type:text"`                                                  // 通知内容
+	IsRead   bool       `json:"isRead" gorm:"default:false;index:idx_user_notification_unread,priority:2"` // 是否已读
+	ReadAt   *time.Time `json:"readAt"`                                                                    // 阅读时间
+	DedupKey string     `json:"-" gorm:"size:128;index"`                                                   // 去重键，同一用户相同去重键的通知只发送一次
+}
+
+// UserNotificationPreference 用户通知渠道偏好
+// 每个通知分类一条记录，Channels为逗号分隔的外部渠道名称，为空表示只接收站内信
+type UserNotificationPreference struct {
+	ID        uint      `json:"id" gorm:"primarykey"` // 偏好主键ID
+	CreatedAt time.Time `json:"createdAt"`            // 创建时间
+	UpdatedAt time.Time `json:"updatedAt"`            // 更新时间
+
+	UserID   uint   `json:"userId" gorm:"not null;uniqueIndex:idx_user_notification_pref"`           // 所属用户ID
+	Category string `json:"category" gorm:"size:32;not null;uniqueIndex:idx_user_notification_pref"` // 通知分类
+	Channels string `json:"channels" gorm:"size:128"`                                                // 外部渠道，逗号分隔：email, telegram, qq, sms
+}
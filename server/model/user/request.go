@@ -3,13 +3,15 @@ package user
 import "oneclickvirt/model/common"
 
 type ClaimResourceRequest struct {
-	ProviderID   uint   `json:"providerId" binding:"required"`
-	InstanceType string `json:"instanceType" binding:"required"`
-	Name         string `json:"name" binding:"required"`
-	Image        string `json:"image" binding:"required"`
-	CPU          int    `json:"cpu"`
-	Memory       int64  `json:"memory"`
-	Disk         int64  `json:"disk"`
+	ProviderID      uint   `json:"providerId" binding:"required"`
+	InstanceType    string `json:"instanceType" binding:"required"`
+	Name            string `json:"name" binding:"required"`
+	Image           string `json:"image" binding:"required"`
+	CPU             int    `json:"cpu"`
+	Memory          int64  `json:"memory"`
+	Disk            int64  `json:"disk"`
+	SSHKeyIds       []uint `json:"sshKeyIds"`       // 注入实例的SSH公钥ID
+	DisablePassword bool   `json:"disablePassword"` // 禁用密码登录，只允许公钥登录
 }
 
 type InstanceActionRequest struct {
@@ -54,6 +56,84 @@ type ResetInstancePasswordRequest struct {
 	// 不需要传递任何参数，由后端自动生成新密码
 }
 
+// CreateSnapshotRequest 创建实例快照请求
+type CreateSnapshotRequest struct {
+	Name        string `json:"name" binding:"omitempty,max=40"`         // 快照名称，留空时自动生成
+	Description string `json:"description" binding:"omitempty,max=255"` // 快照描述
+}
+
+// CreateBackupRequest 创建实例备份请求
+type CreateBackupRequest struct {
+	Description string `json:"description" binding:"omitempty,max=255"` // 备份描述
+}
+
+// BackupScheduleRequest 设置实例定时备份计划请求
+type BackupScheduleRequest struct {
+	CronExpr  string `json:"cronExpr" binding:"required,max=64"` // Cron表达式（分 时 日 月 周）
+	Retention int    `json:"retention" binding:"required,min=1"` // 保留的定时备份数量
+	Enabled   bool   `json:"enabled"`                            // 是否启用
+}
+
+// CreatePortMappingRequest 用户添加端口映射请求，主机端口由系统在Provider端口范围内分配
+type CreatePortMappingRequest struct {
+	GuestPort   int    `json:"guestPort" binding:"required,min=1,max=65535"`   // 实例内部端口
+	Protocol    string `json:"protocol" binding:"required,oneof=tcp udp both"` // 协议
+	Description string `json:"description" binding:"omitempty,max=128"`        // 描述
+}
+
+// CreateDomainRequest 绑定域名请求
+type CreateDomainRequest struct {
+	InstanceID uint   `json:"instanceId" binding:"required"`                // 目标实例ID
+	Domain     string `json:"domain" binding:"required,max=253"`            // 域名，如 www.example.com
+	GuestPort  int    `json:"guestPort" binding:"required,min=1,max=65535"` // 实例内部HTTP服务端口
+}
+
+// ResizeInstanceRequest 调整实例规格请求，留空的规格保持不变
+type ResizeInstanceRequest struct {
+	CPUId       string `json:"cpuId"`       // CPU规格ID
+	MemoryId    string `json:"memoryId"`    // 内存规格ID
+	DiskId      string `json:"diskId"`      // 磁盘规格ID，只支持扩容
+	BandwidthId string `json:"bandwidthId"` // 带宽规格ID
+}
+
+// SSHKeyRequest 添加或更新SSH公钥请求
+type SSHKeyRequest struct {
+	Name      string `json:"name" binding:"required,max=64"` // 公钥名称
+	PublicKey string `json:"publicKey" binding:"required"`   // authorized_keys格式的公钥
+}
+
+// SecurityGroupRequest 创建或更新安全组请求，更新时规则整体替换
+type SecurityGroupRequest struct {
+	Name        string                     `json:"name" binding:"required,max=64"` // 安全组名称
+	Description string                     `json:"description" binding:"max=255"`  // 描述
+	Rules       []SecurityGroupRuleRequest `json:"rules" binding:"dive"`           // 入站规则
+}
+
+// SecurityGroupRuleRequest 安全组入站规则
+type SecurityGroupRuleRequest struct {
+	Action      string `json:"action" binding:"required,oneof=allow deny"`         // 动作
+	Protocol    string `json:"protocol" binding:"required,oneof=tcp udp icmp all"` // 协议
+	PortStart   int    `json:"portStart" binding:"min=0,max=65535"`                // 起始端口，0表示全部端口
+	PortEnd     int    `json:"portEnd" binding:"min=0,max=65535"`                  // 结束端口，0表示与起始端口相同
+	CIDR        string `json:"cidr" binding:"max=64"`                              // 来源IP或地址段，为空表示任意来源
+	Description string `json:"description" binding:"max=255"`                      // 描述
+}
+
+// InstanceSecurityGroupsRequest 设置实例绑定的安全组，传空列表表示全部解绑
+type InstanceSecurityGroupsRequest struct {
+	SecurityGroupIDs []uint `json:"securityGroupIds"` // 安全组ID
+}
+
+// TerminalMessage Web终端WebSocket消息
+// 客户端发送 input（终端输入）、resize（调整窗口大小）、ping；服务端以二进制帧发送终端输出，以 status、error 文本消息通知状态
+type TerminalMessage struct {
+	Type    string `json:"type"`              // 消息类型
+	Data    string `json:"data,omitempty"`    // 终端输入内容
+	Cols    int    `json:"cols,omitempty"`    // 终端列数
+	Rows    int    `json:"rows,omitempty"`    // 终端行数
+	Message string `json:"message,omitempty"` // 状态或错误说明
+}
+
 // UserTasksRequest 用户任务列表请求
 type UserTasksRequest struct {
 	common.PageInfo
@@ -74,13 +154,16 @@ type SystemImagesRequest struct {
 // 安全设计：所有参数都是从后端预定义配置中选择的ID，不允许自定义输入
 // 实例名称由后端根据provider名称自动生成
 type CreateInstanceRequest struct {
-	ProviderId  uint   `json:"providerId" binding:"required"`  // 节点ID
-	ImageId     uint   `json:"imageId" binding:"required"`     // 镜像ID（从数据库获取）
-	CPUId       string `json:"cpuId" binding:"required"`       // CPU规格ID
-	MemoryId    string `json:"memoryId" binding:"required"`    // 内存规格ID
-	DiskId      string `json:"diskId" binding:"required"`      // 磁盘规格ID
-	BandwidthId string `json:"bandwidthId" binding:"required"` // 带宽规格ID
-	Description string `json:"description"`                    // 描述信息
+	ProviderId      uint   `json:"providerId" binding:"required"`  // 节点ID
+	ImageId         uint   `json:"imageId" binding:"required"`     // 镜像ID（从数据库获取）
+	CPUId           string `json:"cpuId" binding:"required"`       // CPU规格ID
+	MemoryId        string `json:"memoryId" binding:"required"`    // 内存规格ID
+	DiskId          string `json:"diskId" binding:"required"`      // 磁盘规格ID
+	BandwidthId     string `json:"bandwidthId" binding:"required"` // 带宽规格ID
+	Description     string `json:"description"`                    // 描述信息
+	SSHKeyIds       []uint `json:"sshKeyIds"`                      // 注入实例的SSH公钥ID（从用户公钥列表中选择）
+	DisablePassword bool   `json:"disablePassword"`                // 禁用密码登录，只允许公钥登录，需要至少选择一个公钥
+	UserData        string `json:"userData"`                       // 首次启动时执行的cloud-config（#cloud-config开头）或shell脚本（#!开头）
 }
 
 // QuotaCheckRequest 配额检查请求
@@ -92,3 +175,29 @@ type QuotaCheckRequest struct {
 	Disk         int    `json:"disk"`
 	Bandwidth    int    `json:"bandwidth"`
 }
+
+// NotificationListRequest 站内通知列表请求
+type NotificationListRequest struct {
+	common.PageInfo
+	Category   string `json:"category" form:"category"`     // 通知分类
+	UnreadOnly bool   `json:"unreadOnly" form:"unreadOnly"` // 只返回未读通知
+}
+
+// NotificationPreferenceItem 单个通知分类的渠道偏好
+type NotificationPreferenceItem struct {
+	Category string   `json:"category" binding:"required"` // 通知分类
+	Channels []string `json:"channels"`                    // 外部渠道，为空表示只接收站内信
+}
+
+// UpdateNotificationPreferencesRequest 更新通知偏好请求
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceItem `json:"preferences" binding:"required,dive"`
+}
+
+// CreateAPITokenRequest 创建个人API令牌请求
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=64"`         // 令牌名称
+	Scopes        []string `json:"scopes" binding:"required,min=1"`        // 权限范围，如instances:read
+	ExpiresInDays int      `json:"expiresInDays" binding:"min=0,max=3650"` // 有效天数，0表示永不过期
+	AllowedIPs    []string `json:"allowedIps"`                             // IP白名单，IP或CIDR，为空表示不限制
+}
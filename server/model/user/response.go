@@ -266,3 +266,128 @@ type GetInstancePasswordResponse struct {
 	NewPassword string `json:"newPassword"`
 	ResetTime   int64  `json:"resetTime"`
 }
+
+// SnapshotListResponse 实例快照列表响应
+type SnapshotListResponse struct {
+	Snapshots    []providerModel.InstanceSnapshot `json:"snapshots"`
+	MaxSnapshots int                              `json:"maxSnapshots"` // 当前等级允许的最大快照数量
+}
+
+// SnapshotTaskResponse 快照操作任务响应
+type SnapshotTaskResponse struct {
+	SnapshotID uint `json:"snapshotId"`
+	TaskID     uint `json:"taskId"`
+}
+
+// ResizeTaskResponse 调整实例规格任务响应
+type ResizeTaskResponse struct {
+	InstanceID uint `json:"instanceId"`
+	TaskID     uint `json:"taskId"`
+}
+
+// PortMappingTaskResponse 端口映射操作任务响应
+type PortMappingTaskResponse struct {
+	PortID   uint `json:"portId"`
+	HostPort int  `json:"hostPort,omitempty"` // 添加时分配的主机端口
+	TaskID   uint `json:"taskId"`
+}
+
+// DomainBindingInfo 域名绑定信息，附带完成验证所需的DNS记录
+type DomainBindingInfo struct {
+	providerModel.InstanceDomain
+	InstanceName string `json:"instanceName"` // 目标实例名称
+	TXTRecord    string `json:"txtRecord"`    // 需添加的TXT记录名称，记录值为verifyToken
+	TargetIP     string `json:"targetIp"`     // 域名A记录应指向的节点公网IP
+}
+
+// DomainListResponse 域名绑定列表响应
+type DomainListResponse struct {
+	Domains    []DomainBindingInfo `json:"domains"`
+	MaxDomains int                 `json:"maxDomains"` // 当前等级允许绑定的域名数量
+}
+
+// BackupListResponse 实例备份列表响应
+type BackupListResponse struct {
+	Backups    []providerModel.InstanceBackup `json:"backups"`
+	MaxBackups int                            `json:"maxBackups"` // 当前等级允许的最大备份数量
+}
+
+// BackupTaskResponse 备份操作任务响应
+type BackupTaskResponse struct {
+	BackupID   uint `json:"backupId"`
+	InstanceID uint `json:"instanceId,omitempty"` // 恢复为新实例时的新实例ID
+	TaskID     uint `json:"taskId"`
+}
+
+// ConsoleResponse 图形控制台会话响应
+type ConsoleResponse struct {
+	Protocol  string `json:"protocol"`           // 控制台协议：vnc（noVNC）或 spice（spice-html5）
+	Password  string `json:"password,omitempty"` // 客户端连接控制台时使用的密码
+	Ticket    string `json:"ticket"`             // 连接控制台WebSocket时使用的一次性票据
+	ExpiresIn int    `json:"expiresIn"`          // 票据有效期（秒）
+}
+
+// WebSocketTicketResponse WebSocket连接票据响应
+type WebSocketTicketResponse struct {
+	Ticket    string `json:"ticket"`    // 建立WebSocket连接时通过ws_ticket查询参数传递的一次性票据
+	ExpiresIn int    `json:"expiresIn"` // 票据有效期（秒）
+}
+
+// NotificationListResponse 站内通知列表响应
+type NotificationListResponse struct {
+	List   []UserNotification `json:"list"`
+	Total  int64              `json:"total"`
+	Unread int64              `json:"unread"` // 未读通知总数
+}
+
+// NotificationChannelStatus 外部通知渠道状态
+type NotificationChannelStatus struct {
+	Name    string `json:"name"`    // 渠道名称：email, telegram, qq, sms
+	Enabled bool   `json:"enabled"` // 系统是否已启用该渠道
+	Bound   bool   `json:"bound"`   // 用户是否已绑定该渠道的接收地址
+}
+
+// NotificationPreferenceInfo 通知分类的渠道偏好
+type NotificationPreferenceInfo struct {
+	Category    string   `json:"category"`    // 通知分类
+	Description string   `json:"description"` // 分类说明
+	Channels    []string `json:"channels"`    // 除站内信外额外发送的渠道
+}
+
+// NotificationPreferencesResponse 通知偏好响应
+type NotificationPreferencesResponse struct {
+	Preferences []NotificationPreferenceInfo `json:"preferences"`
+	Channels    []NotificationChannelStatus  `json:"channels"`
+}
+
+// APITokenInfo 个人API令牌信息
+type APITokenInfo struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"tokenPrefix"` // 令牌前缀，便于识别
+	Scopes      []string   `json:"scopes"`
+	AllowedIPs  []string   `json:"allowedIps"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	LastUsedAt  *time.Time `json:"lastUsedAt"`
+	LastUsedIP  string     `json:"lastUsedIp"`
+	RevokedAt   *time.Time `json:"revokedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// APITokenScopeInfo API令牌权限范围说明
+type APITokenScopeInfo struct {
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+}
+
+// APITokenListResponse 个人API令牌列表响应
+type APITokenListResponse struct {
+	List   []APITokenInfo      `json:"list"`
+	Scopes []APITokenScopeInfo `json:"scopes"` // 可选的权限范围
+}
+
+// CreateAPITokenResponse 创建个人API令牌响应，明文令牌只返回一次
+type CreateAPITokenResponse struct {
+	Token    string       `json:"token"`
+	APIToken APITokenInfo `json:"apiToken"`
+}
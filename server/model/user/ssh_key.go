@@ -0,0 +1,19 @@
+package user
+
+import (
+	"time"
+)
+
+// UserSSHKey 用户SSH公钥
+// 创建实例时可选择若干公钥注入到实例的root用户authorized_keys中
+type UserSSHKey struct {
+	ID        uint      `json:"id" gorm:"primarykey"` // 公钥主键ID
+	CreatedAt time.Time `json:"createdAt"`            // 创建时间
+	UpdatedAt time.Time `json:"updatedAt"`            // 更新时间
+
+	UserID      uint   `json:"userId" gorm:"not null;index"`        // 所属用户ID
+	Name        string `json:"name" gorm:"size:64;not null"`        // 公钥名称
+	PublicKey   string `json:"publicKey" gorm:"type:text;not null"` // authorized_keys格式的公钥内容
+	KeyType     string `json:"keyType" gorm:"size:32"`              // 公钥算法，如ssh-ed25519、ssh-rsa
+	Fingerprint string `json:"fingerprint" gorm:"size:64;index"`    // SHA256指纹，同一用户下唯一
+}
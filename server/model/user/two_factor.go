@@ -0,0 +1,16 @@
+package user
+
+import (
+	"time"
+)
+
+// UserRecoveryCode 两步验证恢复码
+// 只保存恢复码的SHA-256哈希，每个恢复码只能使用一次
+type UserRecoveryCode struct {
+	ID        uint      `json:"id" gorm:"primarykey"` // 恢复码主键ID
+	CreatedAt time.Time `json:"createdAt"`            // 创建时间
+
+	UserID   uint       `json:"userId" gorm:"not null;index"` // 所属用户ID
+	CodeHash string     `json:"-" gorm:"size:64;not null"`    // 恢复码哈希
+	UsedAt   *time.Time `json:"usedAt"`                       // 使用时间，为空表示未使用
+}
@@ -51,13 +51,22 @@ type User struct {
 	InviteCode  string     `json:"inviteCode" gorm:"size:32"` // 注册时使用的邀请码
 	LastLoginAt *time.Time `json:"lastLoginAt"`               // 最后登录时间
 
-	// OAuth2关联信息
-	OAuth2ProviderID uint   `json:"oauth2ProviderId" gorm:"index"`   // OAuth2提供商ID（关联oauth2_providers表）
-	OAuth2UID        string `json:"oauth2Uid" gorm:"size:255;index"` // OAuth2提供商返回的用户唯一标识
-	OAuth2Username   string `json:"oauth2Username" gorm:"size:255"`  // OAuth2提供商返回的用户名
-	OAuth2Email      string `json:"oauth2Email" gorm:"size:255"`     // OAuth2提供商返回的邮箱
-	OAuth2Avatar     string `json:"oauth2Avatar" gorm:"size:512"`    // OAuth2提供商返回的头像URL
-	OAuth2Extra      string `json:"oauth2Extra" gorm:"type:text"`    // OAuth2提供商返回的额外信息（JSON格式）
+	// 两步验证
+	TwoFactorEnabled  bool       `json:"twoFactorEnabled" gorm:"default:false"` // 是否已启用TOTP两步验证
+	TwoFactorSecret   string     `json:"-" gorm:"size:64"`                      // TOTP密钥（Base32），未启用时为待确认的密钥
+	TwoFactorLastStep int64      `json:"-" gorm:"default:0"`                    // 最近一次验证通过的TOTP时间步，防止验证码重放
+	TokenRevokedAt    *time.Time `json:"-"`                                     // 该时间之前签发的Token全部失效
+
+	// 登录保护
+	LockedUntil  *time.Time `json:"lockedUntil"`                   // 密码错误次数过多被锁定的截止时间
+	LockoutCount int        `json:"lockoutCount" gorm:"default:0"` // 连续锁定次数，用于递增锁定时长，登录成功后清零
+
+	// 第三方登录（OAuth2身份见UserIdentity）
+	PasswordGenerated bool `json:"passwordGenerated" gorm:"default:false"` // 密码由系统随机生成（第三方登录自动注册），用户设置密码前不计为可用的登录方式
+
+	// LDAP关联信息
+	LDAPUID string `json:"ldapUid" gorm:"column:ldap_uid;size:255;index"` // LDAP用户名属性的值，用于关联LDAP账户
+	LDAPDN  string `json:"ldapDn" gorm:"column:ldap_dn;size:512"`         // 最近一次登录时的用户DN
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
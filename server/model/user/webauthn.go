@@ -0,0 +1,19 @@
+package user
+
+import (
+	"time"
+)
+
+// UserWebAuthnCredential 用户通行密钥（WebAuthn凭据）
+// 每个用户可以绑定多个通行密钥，可用于无密码登录或作为两步验证的第二因素
+type UserWebAuthnCredential struct {
+	ID        uint      `json:"id" gorm:"primarykey"` // 凭据主键ID
+	CreatedAt time.Time `json:"createdAt"`            // 创建时间
+	UpdatedAt time.Time `json:"updatedAt"`            // 更新时间
+
+	UserID       uint       `json:"userId" gorm:"not null;index"`           // 所属用户ID
+	Name         string     `json:"name" gorm:"size:64"`                    // 通行密钥名称
+	CredentialID string     `json:"-" gorm:"size:255;not null;uniqueIndex"` // 凭据ID（base64url）
+	Credential   string     `json:"-" gorm:"type:text;not null"`            // 凭据数据（JSON），包含公钥、标志位和签名计数
+	LastUsedAt   *time.Time `json:"lastUsedAt"`                             // 最近使用时间
+}
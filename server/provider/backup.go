@@ -0,0 +1,18 @@
+package provider
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// BackupRemoteDir Provider节点上存放备份中转文件的目录
+	BackupRemoteDir = "/tmp/oneclickvirt_backup"
+	// BackupCommandTimeout 导出、导入等备份命令的执行超时时间
+	BackupCommandTimeout = 2 * time.Hour
+)
+
+// BackupRemoteWorkDir 获取单次备份操作在Provider节点上的工作目录
+func BackupRemoteWorkDir(instanceID string) string {
+	return fmt.Sprintf("%s/%s-%d", BackupRemoteDir, instanceID, time.Now().UnixNano())
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// 图形控制台协议，前端据此选择 noVNC 或 spice-html5 客户端
+const (
+	ConsoleProtocolVNC   = "vnc"
+	ConsoleProtocolSPICE = "spice"
+)
+
+// ConsoleStream 实例图形控制台的上游数据流
+type ConsoleStream struct {
+	Protocol string             // 控制台协议：vnc 或 spice
+	Password string             // 客户端认证使用的密码（Proxmox为VNC票据），无需认证时为空
+	Conn     io.ReadWriteCloser // 与控制台服务端之间的原始协议数据流
+}
+
+// DialConsoleWebSocket 连接Provider节点上的控制台WebSocket，返回以二进制帧收发的连接
+func DialConsoleWebSocket(ctx context.Context, url, origin string, tlsConfig *tls.Config, header http.Header, protocols ...string) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig(url, origin)
+	if err != nil {
+		return nil, fmt.Errorf("无效的控制台地址: %w", err)
+	}
+	config.TlsConfig = tlsConfig
+	config.Protocol = protocols
+	config.Dialer = &net.Dialer{Timeout: 30 * time.Second}
+	for key, values := range header {
+		for _, value := range values {
+			config.Header.Add(key, value)
+		}
+	}
+
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("连接控制台失败: %w", err)
+	}
+	conn.PayloadType = websocket.BinaryFrame
+	return conn, nil
+}
+
+// consoleConn 关闭数据连接时一并关闭附属连接（如LXD的控制通道）
+type consoleConn struct {
+	io.ReadWriteCloser
+	closers []io.Closer
+}
+
+// Close 关闭数据连接和所有附属连接
+func (c *consoleConn) Close() error {
+	err := c.ReadWriteCloser.Close()
+	for _, closer := range c.closers {
+		closer.Close()
+	}
+	return err
+}
+
+// NewConsoleConn 组合控制台数据连接与需要同时关闭的附属连接
+func NewConsoleConn(data io.ReadWriteCloser, closers ...io.Closer) io.ReadWriteCloser {
+	return &consoleConn{ReadWriteCloser: data, closers: closers}
+}
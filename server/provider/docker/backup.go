@@ -0,0 +1,177 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// Docker备份由 docker export 导出的容器文件系统和 docker inspect 的容器参数打包而成，
+// 恢复时先 docker import 为镜像，再按原容器参数和新分配的端口创建容器
+
+const (
+	backupRootfsFile = "rootfs.tar"
+	backupConfigFile = "config.json"
+)
+
+// backupRepository 获取备份恢复镜像的仓库名（Docker仓库名只允许小写）
+func backupRepository(instanceName string) string {
+	return "oneclickvirt_backup_" + strings.ToLower(instanceName)
+}
+
+// shellQuote 使用单引号包裹参数，防止shell解析其中的特殊字符
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "'\\''") + "'"
+}
+
+// ExportInstance 导出实例备份到本地目录，返回备份文件名
+func (d *DockerProvider) ExportInstance(ctx context.Context, instanceID, localDir string) (string, error) {
+	if !d.connected {
+		return "", fmt.Errorf("not connected")
+	}
+
+	// Docker provider只支持SSH，检查执行规则
+	if d.config.ExecutionRule == "api_only" {
+		return "", fmt.Errorf("Docker provider不支持API调用，无法使用api_only执行规则")
+	}
+
+	workDir := provider.BackupRemoteWorkDir(instanceID)
+	fileName := fmt.Sprintf("%s-%s.tar.gz", instanceID, time.Now().Format("20060102150405"))
+	remotePath := fmt.Sprintf("%s/%s", workDir, fileName)
+
+	if _, err := d.sshClient.Execute(fmt.Sprintf("mkdir -p %s/bundle", workDir)); err != nil {
+		return "", fmt.Errorf("创建远程备份目录失败: %w", err)
+	}
+	defer d.cleanupBackupWorkDir(workDir)
+
+	global.APP_LOG.Info("开始导出Docker实例",
+		zap.String("id", utils.TruncateString(instanceID, 32)),
+		zap.String("remotePath", remotePath))
+
+	inspectCmd := fmt.Sprintf("docker inspect %s --format '{{json .}}' > %s/bundle/%s", instanceID, workDir, backupConfigFile)
+	if output, err := d.sshClient.Execute(inspectCmd); err != nil {
+		return "", fmt.Errorf("获取容器配置失败: %w, output: %s", err, output)
+	}
+
+	exportCmd := fmt.Sprintf("docker export %s -o %s/bundle/%s && tar -czf %s -C %s/bundle %s %s",
+		instanceID, workDir, backupRootfsFile, remotePath, workDir, backupRootfsFile, backupConfigFile)
+	output, err := d.sshClient.ExecuteWithTimeout(exportCmd, provider.BackupCommandTimeout)
+	if err != nil {
+		global.APP_LOG.Error("Docker实例导出失败",
+			zap.String("id", utils.TruncateString(instanceID, 32)),
+			zap.String("output", utils.TruncateString(output, 500)),
+			zap.Error(err))
+		return "", fmt.Errorf("导出实例失败: %w", err)
+	}
+
+	size, err := d.sshClient.DownloadFile(remotePath, filepath.Join(localDir, fileName))
+	if err != nil {
+		return "", fmt.Errorf("下载备份文件失败: %w", err)
+	}
+
+	global.APP_LOG.Info("Docker实例导出成功",
+		zap.String("id", utils.TruncateString(instanceID, 32)),
+		zap.String("file", fileName),
+		zap.Int64("size", size))
+	return fileName, nil
+}
+
+// ImportInstance 将本地备份文件导入为新容器
+func (d *DockerProvider) ImportInstance(ctx context.Context, localPath string, config provider.InstanceConfig) error {
+	if !d.connected {
+		return fmt.Errorf("not connected")
+	}
+
+	// Docker provider只支持SSH，检查执行规则
+	if d.config.ExecutionRule == "api_only" {
+		return fmt.Errorf("Docker provider不支持API调用，无法使用api_only执行规则")
+	}
+
+	workDir := provider.BackupRemoteWorkDir(config.Name)
+	remotePath := fmt.Sprintf("%s/%s", workDir, filepath.Base(localPath))
+	defer d.cleanupBackupWorkDir(workDir)
+
+	global.APP_LOG.Info("开始导入Docker实例备份",
+		zap.String("name", utils.TruncateString(config.Name, 32)),
+		zap.String("remotePath", remotePath))
+
+	if err := d.sshClient.UploadFile(localPath, remotePath); err != nil {
+		return fmt.Errorf("上传备份文件失败: %w", err)
+	}
+
+	extractCmd := fmt.Sprintf("mkdir -p %s/bundle && tar -xzf %s -C %s/bundle", workDir, remotePath, workDir)
+	if output, err := d.sshClient.ExecuteWithTimeout(extractCmd, provider.BackupCommandTimeout); err != nil {
+		return fmt.Errorf("解压备份文件失败: %w, output: %s", err, utils.TruncateString(output, 500))
+	}
+
+	configOutput, err := d.sshClient.Execute(fmt.Sprintf("cat %s/bundle/%s", workDir, backupConfigFile))
+	if err != nil {
+		return fmt.Errorf("读取备份容器配置失败: %w", err)
+	}
+	var spec dockerContainerSpec
+	if err := json.Unmarshal([]byte(strings.TrimSpace(configOutput)), &spec); err != nil {
+		return fmt.Errorf("解析备份容器配置失败: %w", err)
+	}
+
+	// docker export 不包含镜像元数据，导入时需要补回启动命令和工作目录
+	image := fmt.Sprintf("%s:latest", backupRepository(config.Name))
+	importCmd := "docker import"
+	if len(spec.Config.Entrypoint) > 0 {
+		entrypoint, _ := json.Marshal(spec.Config.Entrypoint)
+		importCmd += " --change " + shellQuote("ENTRYPOINT "+string(entrypoint))
+	}
+	if len(spec.Config.Cmd) > 0 {
+		cmd, _ := json.Marshal(spec.Config.Cmd)
+		importCmd += " --change " + shellQuote("CMD "+string(cmd))
+	}
+	if spec.Config.WorkingDir != "" {
+		importCmd += " --change " + shellQuote("WORKDIR "+spec.Config.WorkingDir)
+	}
+	importCmd += fmt.Sprintf(" %s/bundle/%s %s", workDir, backupRootfsFile, image)
+
+	if output, err := d.sshClient.ExecuteWithTimeout(importCmd, provider.BackupCommandTimeout); err != nil {
+		global.APP_LOG.Error("Docker备份镜像导入失败",
+			zap.String("name", utils.TruncateString(config.Name, 32)),
+			zap.String("output", utils.TruncateString(output, 500)),
+			zap.Error(err))
+		return fmt.Errorf("导入备份镜像失败: %w", err)
+	}
+
+	// 新容器使用新名称和新分配的端口，原容器的端口映射不能复用
+	spec.Config.Hostname = config.Name
+	spec.HostConfig.PortBindings = nil
+	runCmd := spec.buildRunCommand(config.Name, image, config.Ports...)
+
+	if output, err := d.sshClient.Execute(runCmd); err != nil {
+		global.APP_LOG.Error("使用备份镜像创建容器失败",
+			zap.String("name", utils.TruncateString(config.Name, 32)),
+			zap.String("output", utils.TruncateString(output, 500)),
+			zap.Error(err))
+		return fmt.Errorf("创建容器失败: %w", err)
+	}
+
+	if err := d.initializeVnstatMonitoring(ctx, config); err != nil {
+		global.APP_LOG.Warn("初始化vnstat监控失败",
+			zap.String("name", utils.TruncateString(config.Name, 32)),
+			zap.Error(err))
+	}
+
+	global.APP_LOG.Info("Docker实例备份导入成功", zap.String("name", utils.TruncateString(config.Name, 32)))
+	return nil
+}
+
+// cleanupBackupWorkDir 清理节点上的备份中转目录
+func (d *DockerProvider) cleanupBackupWorkDir(workDir string) {
+	if _, err := d.sshClient.Execute(fmt.Sprintf("rm -rf %s", workDir)); err != nil {
+		global.APP_LOG.Warn("清理远程备份目录失败", zap.String("dir", workDir), zap.Error(err))
+	}
+}
@@ -0,0 +1,13 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/provider"
+)
+
+// OpenConsole Docker容器没有图形输出，不支持图形控制台
+func (d *DockerProvider) OpenConsole(ctx context.Context, instanceID string) (*provider.ConsoleStream, error) {
+	return nil, fmt.Errorf("Docker容器不支持图形控制台，请使用Web终端")
+}
@@ -0,0 +1,153 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// firewallNftTable 节点上nftables方式使用的表，每个容器一条挂在forward钩子上的链
+const firewallNftTable = "inet oneclickvirt_sg"
+
+// SetInstanceFirewall 覆盖容器的入站过滤规则，rules为空时移除过滤
+// 宿主机存在DOCKER-USER链时使用iptables，否则使用nftables；规则按容器当前IP匹配，
+// 容器重启后IP可能变化，需要重新下发
+func (d *DockerProvider) SetInstanceFirewall(ctx context.Context, instanceID string, rules []provider.FirewallRule) error {
+	if !d.connected {
+		return fmt.Errorf("provider not connected")
+	}
+
+	useIptables := true
+	if _, err := d.sshClient.Execute("iptables -nL DOCKER-USER >/dev/null 2>&1"); err != nil {
+		if _, err := d.sshClient.Execute("command -v nft"); err != nil {
+			return fmt.Errorf("节点既没有DOCKER-USER链也没有nft命令，无法配置容器防火墙")
+		}
+		useIptables = false
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(instanceID))
+	iptablesChain := fmt.Sprintf("OCV-SG-%08x", h.Sum32())
+	nftChain := fmt.Sprintf("ocv_sg_%08x", h.Sum32())
+
+	var containerIP string
+	if len(rules) > 0 {
+		output, err := d.sshClient.Execute(fmt.Sprintf("docker inspect -f '{{range .NetworkSettings.Networks}}{{.IPAddress}} {{end}}' %s 2>/dev/null", instanceID))
+		if err == nil {
+			if fields := strings.Fields(output); len(fields) > 0 {
+				containerIP = fields[0]
+			}
+		}
+	}
+
+	var cmd string
+	switch {
+	case len(rules) == 0 || containerIP == "":
+		// 容器未运行时没有IP，先移除旧规则，启动后重新下发
+		if useIptables {
+			cmd = fmt.Sprintf("iptables -S DOCKER-USER | grep -- '-j %[1]s$' | sed 's/^-A /-D /' | while read -r r; do iptables $r; done; "+
+				"iptables -F %[1]s 2>/dev/null; iptables -X %[1]s 2>/dev/null; true", iptablesChain)
+		} else {
+			cmd = fmt.Sprintf("nft delete chain %s %s 2>/dev/null; true", firewallNftTable, nftChain)
+		}
+	case useIptables:
+		cmd = fmt.Sprintf("echo %[1]s | base64 -d | iptables-restore -n && { "+
+			"iptables -S DOCKER-USER | grep -- '-j %[2]s$' | sed 's/^-A /-D /' | while read -r r; do iptables $r; done; "+
+			"iptables -I DOCKER-USER -d %[3]s/32 -j %[2]s; }",
+			provider.EncodeFirewallConfig(buildIptablesFirewall(iptablesChain, rules)), iptablesChain, containerIP)
+	default:
+		cmd = fmt.Sprintf("echo %s | base64 -d | nft -f -",
+			provider.EncodeFirewallConfig(buildNftFirewall(nftChain, containerIP, rules)))
+	}
+
+	if output, err := d.sshClient.Execute(cmd); err != nil {
+		return fmt.Errorf("配置容器防火墙失败: %w, output: %s", err, output)
+	}
+
+	if len(rules) > 0 && containerIP == "" {
+		global.APP_LOG.Info("容器未运行，防火墙规则将在启动后下发",
+			zap.String("instanceID", utils.TruncateString(instanceID, 32)))
+		return nil
+	}
+	global.APP_LOG.Info("Docker容器防火墙配置成功",
+		zap.String("instanceID", utils.TruncateString(instanceID, 32)),
+		zap.String("containerIP", containerIP),
+		zap.Bool("iptables", useIptables),
+		zap.Int("ruleCount", len(rules)))
+	return nil
+}
+
+// buildIptablesFirewall 生成 iptables-restore -n 的输入，声明链时会清空原有规则，整条链原子替换
+// 容器地址为IPv4，只限制IPv6来源的规则不会匹配，直接跳过
+func buildIptablesFirewall(chain string, rules []provider.FirewallRule) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*filter\n:%s - [0:0]\n", chain)
+	fmt.Fprintf(&b, "-A %s -m conntrack --ctstate ESTABLISHED,RELATED -j RETURN\n", chain)
+	for _, rule := range provider.OrderFirewallRules(rules) {
+		if provider.FirewallRuleFamily(rule) == "6" {
+			continue
+		}
+		fmt.Fprintf(&b, "-A %s", chain)
+		if rule.CIDR != "" {
+			fmt.Fprintf(&b, " -s %s", rule.CIDR)
+		}
+		if rule.Protocol != "all" {
+			fmt.Fprintf(&b, " -p %s", rule.Protocol)
+		}
+		if ports := provider.FirewallPortRange(rule, ":"); ports != "" {
+			fmt.Fprintf(&b, " --dport %s", ports)
+		}
+		if rule.Action == "deny" {
+			b.WriteString(" -j DROP\n")
+		} else {
+			b.WriteString(" -j RETURN\n")
+		}
+	}
+	fmt.Fprintf(&b, "-A %s -j DROP\nCOMMIT\n", chain)
+	return b.String()
+}
+
+// buildNftFirewall 生成 nft -f 脚本，每个容器一条优先级高于Docker的forward基础链，
+// 放行的流量以return交给后续链处理，整个脚本在一个事务中生效
+func buildNftFirewall(chain, containerIP string, rules []provider.FirewallRule) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "add table %s\n", firewallNftTable)
+	fmt.Fprintf(&b, "add chain %s %s { type filter hook forward priority -1; policy accept; }\n", firewallNftTable, chain)
+	fmt.Fprintf(&b, "flush chain %s %s\n", firewallNftTable, chain)
+	fmt.Fprintf(&b, "add rule %s %s meta nfproto != ipv4 return\n", firewallNftTable, chain)
+	fmt.Fprintf(&b, "add rule %s %s ip daddr != %s return\n", firewallNftTable, chain, containerIP)
+	fmt.Fprintf(&b, "add rule %s %s ct state established,related return\n", firewallNftTable, chain)
+	for _, rule := range provider.OrderFirewallRules(rules) {
+		if provider.FirewallRuleFamily(rule) == "6" {
+			continue
+		}
+		fmt.Fprintf(&b, "add rule %s %s", firewallNftTable, chain)
+		if rule.CIDR != "" {
+			fmt.Fprintf(&b, " ip saddr %s", rule.CIDR)
+		}
+		switch rule.Protocol {
+		case "tcp", "udp":
+			if ports := provider.FirewallPortRange(rule, "-"); ports != "" {
+				fmt.Fprintf(&b, " %s dport %s", rule.Protocol, ports)
+			} else {
+				fmt.Fprintf(&b, " meta l4proto %s", rule.Protocol)
+			}
+		case "icmp":
+			b.WriteString(" meta l4proto icmp")
+		}
+		if rule.Action == "deny" {
+			b.WriteString(" drop\n")
+		} else {
+			b.WriteString(" return\n")
+		}
+	}
+	fmt.Fprintf(&b, "add rule %s %s drop\n", firewallNftTable, chain)
+	return b.String()
+}
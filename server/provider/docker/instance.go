@@ -395,6 +395,14 @@ func (d *DockerProvider) sshCreateInstanceWithProgress(ctx context.Context, conf
 		global.APP_LOG.Warn("配置SSH密码失败", zap.Error(err))
 	}
 
+	// 执行用户数据脚本
+	if config.UserData != "" {
+		updateProgress(97, "执行用户数据脚本...")
+		if err := d.runInstanceUserData(config); err != nil {
+			global.APP_LOG.Warn("执行用户数据脚本失败", zap.Error(err))
+		}
+	}
+
 	// 初始化vnstat监控
 	updateProgress(98, "初始化vnstat监控...")
 	if err := d.initializeVnstatMonitoring(ctx, config); err != nil {
@@ -573,6 +581,18 @@ func (d *DockerProvider) sshDeleteInstance(ctx context.Context, id string) error
 			zap.String("output", utils.TruncateString(cleanupOutput, 200)))
 	}
 
+	// 清理该实例的快照镜像和备份恢复镜像，随实例一同删除
+	for _, repository := range []string{snapshotRepository(id), backupRepository(id)} {
+		imageCleanupCmd := fmt.Sprintf("docker images %s -q | sort -u | xargs -r docker rmi -f", repository)
+		if output, err := d.sshClient.Execute(imageCleanupCmd); err != nil {
+			global.APP_LOG.Debug("清理实例镜像失败（可忽略）",
+				zap.String("id", utils.TruncateString(id, 32)),
+				zap.String("repository", repository),
+				zap.String("output", utils.TruncateString(output, 200)),
+				zap.Error(err))
+		}
+	}
+
 	// 定义多种删除策略，按优先级顺序执行
 	deleteStrategies := []struct {
 		name        string
@@ -0,0 +1,53 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// ResizeInstance 通过 docker update 在线调整容器CPU和内存
+// 容器磁盘由创建时的storage-opt决定，无法在线调整；Docker也未配置网卡限速，带宽只记录在数据库中
+func (d *DockerProvider) ResizeInstance(ctx context.Context, instanceID string, spec provider.ResizeSpec) error {
+	if !d.connected {
+		return fmt.Errorf("not connected")
+	}
+
+	// Docker provider只支持SSH，检查执行规则
+	if d.config.ExecutionRule == "api_only" {
+		return fmt.Errorf("Docker provider不支持API调用，无法使用api_only执行规则")
+	}
+
+	if spec.Disk > 0 {
+		return fmt.Errorf("Docker容器不支持在线调整磁盘大小")
+	}
+
+	var options []string
+	if spec.CPU > 0 {
+		options = append(options, fmt.Sprintf("--cpus=%d", spec.CPU))
+	}
+	if spec.Memory > 0 {
+		// 创建时未设置swap，Docker默认swap上限为内存的2倍，调整时保持一致，避免新内存超过原swap上限导致失败
+		options = append(options, fmt.Sprintf("--memory=%dm --memory-swap=%dm", spec.Memory, spec.Memory*2))
+	}
+	if len(options) == 0 {
+		return nil
+	}
+
+	cmd := fmt.Sprintf("docker update %s %s", strings.Join(options, " "), instanceID)
+	if output, err := d.sshClient.Execute(cmd); err != nil {
+		return fmt.Errorf("调整容器规格失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("Docker容器规格调整成功",
+		zap.String("id", utils.TruncateString(instanceID, 32)),
+		zap.Int("cpu", spec.CPU),
+		zap.Int64("memory", spec.Memory))
+	return nil
+}
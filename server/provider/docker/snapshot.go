@@ -0,0 +1,308 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// Docker没有原生快照能力，这里通过 docker commit 将容器文件系统保存为镜像作为快照，
+// 恢复时使用快照镜像按原容器参数重建容器
+
+// CreateSnapshot 创建实例快照
+func (d *DockerProvider) CreateSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	if !d.connected {
+		return fmt.Errorf("not connected")
+	}
+
+	// Docker provider只支持SSH，检查执行规则
+	if d.config.ExecutionRule == "api_only" {
+		return fmt.Errorf("Docker provider不支持API调用，无法使用api_only执行规则")
+	}
+
+	if !provider.IsValidSnapshotName(snapshotName) {
+		return fmt.Errorf("无效的快照名称: %s", snapshotName)
+	}
+
+	return d.sshCreateSnapshot(ctx, instanceID, snapshotName)
+}
+
+// ListSnapshots 获取实例快照列表
+func (d *DockerProvider) ListSnapshots(ctx context.Context, instanceID string) ([]provider.Snapshot, error) {
+	if !d.connected {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	// Docker provider只支持SSH，检查执行规则
+	if d.config.ExecutionRule == "api_only" {
+		return nil, fmt.Errorf("Docker provider不支持API调用，无法使用api_only执行规则")
+	}
+
+	return d.sshListSnapshots(ctx, instanceID)
+}
+
+// RestoreSnapshot 将实例恢复到指定快照
+func (d *DockerProvider) RestoreSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	if !d.connected {
+		return fmt.Errorf("not connected")
+	}
+
+	// Docker provider只支持SSH，检查执行规则
+	if d.config.ExecutionRule == "api_only" {
+		return fmt.Errorf("Docker provider不支持API调用，无法使用api_only执行规则")
+	}
+
+	if !provider.IsValidSnapshotName(snapshotName) {
+		return fmt.Errorf("无效的快照名称: %s", snapshotName)
+	}
+
+	return d.sshRestoreSnapshot(ctx, instanceID, snapshotName)
+}
+
+// DeleteSnapshot 删除实例快照
+func (d *DockerProvider) DeleteSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	if !d.connected {
+		return fmt.Errorf("not connected")
+	}
+
+	// Docker provider只支持SSH，检查执行规则
+	if d.config.ExecutionRule == "api_only" {
+		return fmt.Errorf("Docker provider不支持API调用，无法使用api_only执行规则")
+	}
+
+	if !provider.IsValidSnapshotName(snapshotName) {
+		return fmt.Errorf("无效的快照名称: %s", snapshotName)
+	}
+
+	return d.sshDeleteSnapshot(ctx, instanceID, snapshotName)
+}
+
+// snapshotRepository 获取实例快照镜像的仓库名（Docker仓库名只允许小写）
+func snapshotRepository(instanceID string) string {
+	return "oneclickvirt_snapshot_" + strings.ToLower(instanceID)
+}
+
+// sshCreateSnapshot 通过 docker commit 创建快照
+func (d *DockerProvider) sshCreateSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	image := fmt.Sprintf("%s:%s", snapshotRepository(instanceID), snapshotName)
+	commitCmd := fmt.Sprintf("docker commit -m 'oneclickvirt snapshot' %s %s", instanceID, image)
+
+	global.APP_LOG.Info("开始创建Docker快照",
+		zap.String("id", utils.TruncateString(instanceID, 32)),
+		zap.String("image", image))
+
+	output, err := d.sshClient.Execute(commitCmd)
+	if err != nil {
+		global.APP_LOG.Error("Docker快照创建失败",
+			zap.String("id", utils.TruncateString(instanceID, 32)),
+			zap.String("output", utils.TruncateString(output, 500)),
+			zap.Error(err))
+		return fmt.Errorf("创建快照失败: %w", err)
+	}
+
+	global.APP_LOG.Info("Docker快照创建成功",
+		zap.String("id", utils.TruncateString(instanceID, 32)),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
+
+// sshListSnapshots 通过快照镜像列表获取快照
+func (d *DockerProvider) sshListSnapshots(ctx context.Context, instanceID string) ([]provider.Snapshot, error) {
+	listCmd := fmt.Sprintf("docker images %s --format '{{.Tag}}|{{.CreatedAt}}|{{.Size}}'", snapshotRepository(instanceID))
+	output, err := d.sshClient.Execute(listCmd)
+	if err != nil {
+		return nil, fmt.Errorf("获取快照列表失败: %w", err)
+	}
+
+	snapshots := make([]provider.Snapshot, 0)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		parts := strings.Split(strings.TrimSpace(line), "|")
+		if len(parts) < 3 || parts[0] == "" || parts[0] == "<none>" {
+			continue
+		}
+
+		// CreatedAt 格式示例: 2024-01-01 12:00:00 +0800 CST
+		created, _ := time.Parse("2006-01-02 15:04:05 -0700 MST", parts[1])
+		snapshots = append(snapshots, provider.Snapshot{
+			Name:     parts[0],
+			Created:  created,
+			Metadata: map[string]string{"size": parts[2]},
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.Before(snapshots[j].Created)
+	})
+
+	return snapshots, nil
+}
+
+// dockerContainerSpec 重建容器所需的容器参数
+type dockerContainerSpec struct {
+	Config struct {
+		Hostname   string   `json:"Hostname"`
+		Env        []string `json:"Env"`
+		Cmd        []string `json:"Cmd"`
+		Entrypoint []string `json:"Entrypoint"`
+		WorkingDir string   `json:"WorkingDir"`
+	} `json:"Config"`
+	HostConfig struct {
+		NanoCpus      int64             `json:"NanoCpus"`
+		Memory        int64             `json:"Memory"`
+		NetworkMode   string            `json:"NetworkMode"`
+		Binds         []string          `json:"Binds"`
+		CapAdd        []string          `json:"CapAdd"`
+		StorageOpt    map[string]string `json:"StorageOpt"`
+		RestartPolicy struct {
+			Name string `json:"Name"`
+		} `json:"RestartPolicy"`
+		PortBindings map[string][]struct {
+			HostIP   string `json:"HostIp"`
+			HostPort string `json:"HostPort"`
+		} `json:"PortBindings"`
+	} `json:"HostConfig"`
+}
+
+// buildRunCommand 根据原容器参数构建使用快照镜像的 docker run 命令
+// extraPorts 为额外追加的端口映射，格式与 docker run -p 参数一致
+func (spec *dockerContainerSpec) buildRunCommand(name, image string, extraPorts ...string) string {
+	cmd := fmt.Sprintf("docker run -d --name %s", name)
+
+	if spec.Config.Hostname != "" {
+		cmd += fmt.Sprintf(" --hostname %s", spec.Config.Hostname)
+	}
+	if spec.HostConfig.NetworkMode != "" && spec.HostConfig.NetworkMode != "default" {
+		cmd += fmt.Sprintf(" --network=%s", spec.HostConfig.NetworkMode)
+	}
+	if spec.HostConfig.RestartPolicy.Name != "" && spec.HostConfig.RestartPolicy.Name != "no" {
+		cmd += fmt.Sprintf(" --restart=%s", spec.HostConfig.RestartPolicy.Name)
+	}
+	if spec.HostConfig.NanoCpus > 0 {
+		cmd += fmt.Sprintf(" --cpus=%g", float64(spec.HostConfig.NanoCpus)/1e9)
+	}
+	if spec.HostConfig.Memory > 0 {
+		cmd += fmt.Sprintf(" --memory=%db", spec.HostConfig.Memory)
+	}
+	if size, ok := spec.HostConfig.StorageOpt["size"]; ok && size != "" {
+		cmd += fmt.Sprintf(" --storage-opt size=%s", size)
+	}
+
+	// 端口映射按原样保留
+	for containerPort, bindings := range spec.HostConfig.PortBindings {
+		guestPort := strings.Split(containerPort, "/")[0]
+		protocol := "tcp"
+		if parts := strings.Split(containerPort, "/"); len(parts) > 1 {
+			protocol = parts[1]
+		}
+		for _, binding := range bindings {
+			hostIP := binding.HostIP
+			if hostIP == "" {
+				hostIP = "0.0.0.0"
+			}
+			cmd += fmt.Sprintf(" -p %s:%s:%s/%s", hostIP, binding.HostPort, guestPort, protocol)
+		}
+	}
+
+	for _, port := range extraPorts {
+		cmd += fmt.Sprintf(" -p %s", port)
+	}
+
+	for _, bind := range spec.HostConfig.Binds {
+		cmd += fmt.Sprintf(" -v %s", bind)
+	}
+	for _, capability := range spec.HostConfig.CapAdd {
+		cmd += fmt.Sprintf(" --cap-add=%s", capability)
+	}
+	for _, env := range spec.Config.Env {
+		// 镜像自带的PATH等变量由快照镜像提供，无需重复注入
+		if strings.HasPrefix(env, "PATH=") {
+			continue
+		}
+		cmd += fmt.Sprintf(" -e '%s'", strings.ReplaceAll(env, "'", "'\\''"))
+	}
+
+	return cmd + " " + image
+}
+
+// sshRestoreSnapshot 使用快照镜像重建容器
+// 流程：读取原容器参数 -> 重命名原容器作为备份 -> 使用快照镜像创建新容器 -> 删除备份
+// 新容器创建失败时会回滚到原容器
+func (d *DockerProvider) sshRestoreSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	image := fmt.Sprintf("%s:%s", snapshotRepository(instanceID), snapshotName)
+	if !d.imageExists(image) {
+		return fmt.Errorf("快照不存在: %s", snapshotName)
+	}
+
+	inspectOutput, err := d.sshClient.Execute(fmt.Sprintf("docker inspect %s --format '{{json .}}'", instanceID))
+	if err != nil {
+		return fmt.Errorf("获取容器配置失败: %w", err)
+	}
+
+	var spec dockerContainerSpec
+	if err := json.Unmarshal([]byte(strings.TrimSpace(inspectOutput)), &spec); err != nil {
+		return fmt.Errorf("解析容器配置失败: %w", err)
+	}
+
+	backupName := fmt.Sprintf("%s_pre_restore", instanceID)
+	global.APP_LOG.Info("开始恢复Docker快照",
+		zap.String("id", utils.TruncateString(instanceID, 32)),
+		zap.String("image", image))
+
+	// 停止并重命名原容器，保留用于失败回滚
+	if err := d.sshStopInstance(ctx, instanceID); err != nil {
+		global.APP_LOG.Warn("停止原容器失败，继续恢复", zap.String("id", utils.TruncateString(instanceID, 32)), zap.Error(err))
+	}
+	_, _ = d.sshClient.Execute(fmt.Sprintf("docker rm -f %s 2>/dev/null || true", backupName))
+	if output, err := d.sshClient.Execute(fmt.Sprintf("docker rename %s %s", instanceID, backupName)); err != nil {
+		return fmt.Errorf("备份原容器失败: %w, output: %s", err, output)
+	}
+
+	runCmd := spec.buildRunCommand(instanceID, image)
+	output, err := d.sshClient.Execute(runCmd)
+	if err != nil {
+		global.APP_LOG.Error("使用快照镜像创建容器失败，回滚到原容器",
+			zap.String("id", utils.TruncateString(instanceID, 32)),
+			zap.String("output", utils.TruncateString(output, 500)),
+			zap.Error(err))
+		_, _ = d.sshClient.Execute(fmt.Sprintf("docker rm -f %s 2>/dev/null || true", instanceID))
+		_, _ = d.sshClient.Execute(fmt.Sprintf("docker rename %s %s && docker start %s", backupName, instanceID, instanceID))
+		return fmt.Errorf("恢复快照失败: %w", err)
+	}
+
+	if _, err := d.sshClient.Execute(fmt.Sprintf("docker rm -f %s", backupName)); err != nil {
+		global.APP_LOG.Warn("删除备份容器失败", zap.String("backup", backupName), zap.Error(err))
+	}
+
+	global.APP_LOG.Info("Docker快照恢复成功",
+		zap.String("id", utils.TruncateString(instanceID, 32)),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
+
+// sshDeleteSnapshot 删除快照镜像
+func (d *DockerProvider) sshDeleteSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	image := fmt.Sprintf("%s:%s", snapshotRepository(instanceID), snapshotName)
+	if !d.imageExists(image) {
+		global.APP_LOG.Info("Docker快照不存在，跳过删除", zap.String("image", image))
+		return nil
+	}
+
+	output, err := d.sshClient.Execute(fmt.Sprintf("docker rmi %s", image))
+	if err != nil {
+		return fmt.Errorf("删除快照失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("Docker快照删除成功",
+		zap.String("id", utils.TruncateString(instanceID, 32)),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
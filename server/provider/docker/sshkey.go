@@ -0,0 +1,30 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// SetInstanceSSHKeys 向容器root用户写入SSH公钥，可选禁用密码登录
+func (d *DockerProvider) SetInstanceSSHKeys(ctx context.Context, instanceID string, publicKeys []string, disablePassword bool) error {
+	if !d.connected {
+		return fmt.Errorf("provider not connected")
+	}
+
+	cmd := fmt.Sprintf("docker exec %s sh -c '%s'", instanceID, provider.BuildSSHKeyScript(publicKeys, disablePassword))
+	if output, err := d.sshClient.Execute(cmd); err != nil {
+		return fmt.Errorf("注入SSH公钥失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("Docker容器SSH公钥注入成功",
+		zap.String("instanceID", utils.TruncateString(instanceID, 12)),
+		zap.Int("keyCount", len(publicKeys)),
+		zap.Bool("disablePassword", disablePassword))
+	return nil
+}
@@ -0,0 +1,33 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// OpenTerminal 通过 docker exec 打开容器的交互式终端
+func (d *DockerProvider) OpenTerminal(ctx context.Context, instanceID string, cols, rows int) (*utils.SSHTerminal, error) {
+	if !d.connected {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	// Docker provider只支持SSH，检查执行规则
+	if d.config.ExecutionRule == "api_only" {
+		return nil, fmt.Errorf("Docker provider不支持API调用，无法使用api_only执行规则")
+	}
+
+	cmd := fmt.Sprintf("docker exec -it -e TERM=xterm-256color %s %s", instanceID, provider.TerminalShell)
+	terminal, err := d.sshClient.OpenTerminal(cmd, cols, rows)
+	if err != nil {
+		return nil, fmt.Errorf("打开终端失败: %w", err)
+	}
+
+	global.APP_LOG.Info("Docker容器终端已打开", zap.String("id", utils.TruncateString(instanceID, 32)))
+	return terminal, nil
+}
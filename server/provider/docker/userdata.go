@@ -0,0 +1,24 @@
+package docker
+
+import (
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// runInstanceUserData 在容器启动后执行用户提供的shell脚本
+// 镜像原有的入口进程（sshd）保持不变，脚本写入容器后作为首次启动脚本在后台执行一次
+func (d *DockerProvider) runInstanceUserData(config provider.InstanceConfig) error {
+	cmd := fmt.Sprintf("docker exec %s sh -c '%s'", config.Name, provider.BuildUserDataRunScript(config.UserData))
+	if output, err := d.sshClient.Execute(cmd); err != nil {
+		return fmt.Errorf("启动用户数据脚本失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("Docker容器用户数据脚本已启动",
+		zap.String("name", utils.TruncateString(config.Name, 32)))
+	return nil
+}
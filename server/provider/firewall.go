@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// firewallNamePattern 可以直接作为ACL名称的实例名
+var firewallNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9-]{0,47}$`)
+
+// OrderFirewallRules 拒绝规则排在允许规则之前，各后端按此顺序下发即可得到一致的语义
+func OrderFirewallRules(rules []FirewallRule) []FirewallRule {
+	ordered := make([]FirewallRule, len(rules))
+	copy(ordered, rules)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Action == "deny" && ordered[j].Action != "deny"
+	})
+	return ordered
+}
+
+// FirewallRuleFamily 根据来源地址段判断规则适用的IP协议族，返回4、6，来源不限时返回空
+func FirewallRuleFamily(rule FirewallRule) string {
+	if rule.CIDR == "" {
+		return ""
+	}
+	ip, _, err := net.ParseCIDR(rule.CIDR)
+	if err != nil {
+		ip = net.ParseIP(rule.CIDR)
+	}
+	if ip != nil && ip.To4() == nil {
+		return "6"
+	}
+	return "4"
+}
+
+// FirewallPortRange 返回规则的端口范围，sep为起止端口之间的分隔符，不限端口时返回空
+func FirewallPortRange(rule FirewallRule, sep string) string {
+	if rule.PortStart <= 0 || (rule.Protocol != "tcp" && rule.Protocol != "udp") {
+		return ""
+	}
+	if rule.PortEnd <= rule.PortStart {
+		return fmt.Sprintf("%d", rule.PortStart)
+	}
+	return fmt.Sprintf("%d%s%d", rule.PortStart, sep, rule.PortEnd)
+}
+
+// FirewallResourceName 实例防火墙资源（ACL、规则链）名称，实例名不满足命名要求时使用哈希
+func FirewallResourceName(instanceName string) string {
+	if firewallNamePattern.MatchString(instanceName) {
+		return "ocv-sg-" + instanceName
+	}
+	h := fnv.New32a()
+	h.Write([]byte(instanceName))
+	return fmt.Sprintf("ocv-sg-%08x", h.Sum32())
+}
+
+// BuildNetworkACLYAML 生成LXD/Incus网络ACL配置，供 network acl edit 读取
+// ACL按drop、allow的优先级匹配，与规则顺序无关；未匹配的入站流量由网卡上的默认动作丢弃
+func BuildNetworkACLYAML(rules []FirewallRule) string {
+	var b strings.Builder
+	b.WriteString("description: Managed by OneClickVirt\nconfig: {}\negress: []\n")
+	if len(rules) == 0 {
+		b.WriteString("ingress: []\n")
+		return b.String()
+	}
+
+	b.WriteString("ingress:\n")
+	for _, rule := range OrderFirewallRules(rules) {
+		action := "allow"
+		if rule.Action == "deny" {
+			action = "drop"
+		}
+
+		var protocols []string
+		switch rule.Protocol {
+		case "tcp", "udp":
+			protocols = []string{rule.Protocol}
+		case "icmp":
+			switch FirewallRuleFamily(rule) {
+			case "4":
+				protocols = []string{"icmp4"}
+			case "6":
+				protocols = []string{"icmp6"}
+			default:
+				protocols = []string{"icmp4", "icmp6"}
+			}
+		default:
+			protocols = []string{""}
+		}
+
+		for _, protocol := range protocols {
+			fmt.Fprintf(&b, "- action: %s\n  state: enabled\n", action)
+			if protocol != "" {
+				fmt.Fprintf(&b, "  protocol: %s\n", protocol)
+			}
+			if ports := FirewallPortRange(rule, "-"); ports != "" {
+				fmt.Fprintf(&b, "  destination_port: \"%s\"\n", ports)
+			}
+			if rule.CIDR != "" {
+				fmt.Fprintf(&b, "  source: %s\n", rule.CIDR)
+			}
+		}
+	}
+	return b.String()
+}
+
+// EncodeFirewallConfig 将配置内容编码为base64，便于安全地拼接到shell命令中
+func EncodeFirewallConfig(content string) string {
+	return base64.StdEncoding.EncodeToString([]byte(content))
+}
@@ -243,6 +243,10 @@ func (i *IncusProvider) apiCreateInstanceWithProgress(ctx context.Context, confi
 	if config.Memory != "" {
 		instanceConfig["config"].(map[string]interface{})["limits.memory"] = config.Memory
 	}
+	if config.UserData != "" {
+		// 首次启动时由cloud-init执行
+		instanceConfig["config"].(map[string]interface{})["cloud-init.user-data"] = config.UserData
+	}
 	if config.Disk != "" {
 		instanceConfig["devices"].(map[string]interface{})["root"] = map[string]interface{}{
 			"type": "disk",
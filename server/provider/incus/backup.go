@@ -0,0 +1,149 @@
+package incus
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// 备份文件需要通过SFTP在节点和本地备份存储之间传输，因此导出和导入只支持SSH方式
+
+// ExportInstance 导出实例备份到本地目录，返回备份文件名
+func (i *IncusProvider) ExportInstance(ctx context.Context, instanceID, localDir string) (string, error) {
+	if !i.connected {
+		return "", fmt.Errorf("not connected")
+	}
+
+	// 如果执行规则不允许使用SSH，则返回错误
+	if !i.shouldUseSSH() {
+		return "", fmt.Errorf("执行规则不允许使用SSH，无法导出备份")
+	}
+
+	workDir := provider.BackupRemoteWorkDir(instanceID)
+	fileName := fmt.Sprintf("%s-%s.tar.gz", instanceID, time.Now().Format("20060102150405"))
+	remotePath := fmt.Sprintf("%s/%s", workDir, fileName)
+
+	if _, err := i.sshClient.Execute(fmt.Sprintf("mkdir -p %s", workDir)); err != nil {
+		return "", fmt.Errorf("创建远程备份目录失败: %w", err)
+	}
+	defer i.cleanupBackupWorkDir(workDir)
+
+	global.APP_LOG.Info("开始导出Incus实例",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.String("remotePath", remotePath))
+
+	// 只导出实例本身，不包含快照
+	exportCmd := fmt.Sprintf("incus export %s %s --instance-only", instanceID, remotePath)
+	output, err := i.sshClient.ExecuteWithTimeout(exportCmd, provider.BackupCommandTimeout)
+	if err != nil {
+		global.APP_LOG.Error("Incus实例导出失败",
+			zap.String("id", utils.TruncateString(instanceID, 50)),
+			zap.String("output", utils.TruncateString(output, 500)),
+			zap.Error(err))
+		return "", fmt.Errorf("导出实例失败: %w", err)
+	}
+
+	size, err := i.sshClient.DownloadFile(remotePath, filepath.Join(localDir, fileName))
+	if err != nil {
+		return "", fmt.Errorf("下载备份文件失败: %w", err)
+	}
+
+	global.APP_LOG.Info("Incus实例导出成功",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.String("file", fileName),
+		zap.Int64("size", size))
+	return fileName, nil
+}
+
+// ImportInstance 将本地备份文件导入为新实例，并按新实例重新配置网络和端口映射
+func (i *IncusProvider) ImportInstance(ctx context.Context, localPath string, config provider.InstanceConfig) error {
+	if !i.connected {
+		return fmt.Errorf("not connected")
+	}
+
+	// 如果执行规则不允许使用SSH，则返回错误
+	if !i.shouldUseSSH() {
+		return fmt.Errorf("执行规则不允许使用SSH，无法导入备份")
+	}
+
+	workDir := provider.BackupRemoteWorkDir(config.Name)
+	remotePath := fmt.Sprintf("%s/%s", workDir, filepath.Base(localPath))
+	defer i.cleanupBackupWorkDir(workDir)
+
+	global.APP_LOG.Info("开始导入Incus实例备份",
+		zap.String("name", config.Name),
+		zap.String("remotePath", remotePath))
+
+	if err := i.sshClient.UploadFile(localPath, remotePath); err != nil {
+		return fmt.Errorf("上传备份文件失败: %w", err)
+	}
+
+	importCmd := fmt.Sprintf("incus import %s %s", remotePath, config.Name)
+	output, err := i.sshClient.ExecuteWithTimeout(importCmd, provider.BackupCommandTimeout)
+	if err != nil {
+		global.APP_LOG.Error("Incus实例导入失败",
+			zap.String("name", config.Name),
+			zap.String("output", utils.TruncateString(output, 500)),
+			zap.Error(err))
+		return fmt.Errorf("导入实例失败: %w", err)
+	}
+
+	// 备份中保留了原实例的网络绑定和端口代理，需要清理后按新实例重新配置
+	i.resetImportedInstanceNetwork(config.Name)
+
+	if _, err := i.sshClient.Execute(fmt.Sprintf("incus start %s", config.Name)); err != nil {
+		global.APP_LOG.Warn("启动导入的实例失败，继续配置网络",
+			zap.String("name", config.Name),
+			zap.Error(err))
+	}
+
+	networkConfig := i.parseNetworkConfigFromInstanceConfig(config)
+	if err := i.configureInstanceNetwork(ctx, config, networkConfig); err != nil {
+		return fmt.Errorf("配置实例网络失败: %w", err)
+	}
+
+	global.APP_LOG.Info("Incus实例备份导入成功", zap.String("name", config.Name))
+	return nil
+}
+
+// resetImportedInstanceNetwork 清理导入实例中继承自原实例的网络配置
+// 包括端口代理设备、IPv6路由网卡、静态IPv4绑定和MAC地址，避免与原实例冲突
+func (i *IncusProvider) resetImportedInstanceNetwork(instanceName string) {
+	output, err := i.sshClient.Execute(fmt.Sprintf("incus config device list %s", instanceName))
+	if err == nil {
+		for _, device := range strings.Split(strings.TrimSpace(output), "\n") {
+			device = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(device), ":"))
+			if device == "" {
+				continue
+			}
+
+			deviceType, _ := i.sshClient.Execute(fmt.Sprintf("incus config device get %s %s type", instanceName, device))
+			if strings.TrimSpace(deviceType) == "proxy" || device == "eth1" {
+				if _, err := i.sshClient.Execute(fmt.Sprintf("incus config device remove %s %s", instanceName, device)); err != nil {
+					global.APP_LOG.Warn("移除导入实例的设备失败",
+						zap.String("name", instanceName),
+						zap.String("device", device),
+						zap.Error(err))
+				}
+			}
+		}
+	}
+
+	_, _ = i.sshClient.Execute(fmt.Sprintf("incus config device unset %s eth0 ipv4.address 2>/dev/null || true", instanceName))
+	_, _ = i.sshClient.Execute(fmt.Sprintf("incus config unset %s volatile.eth0.hwaddr 2>/dev/null || true", instanceName))
+}
+
+// cleanupBackupWorkDir 清理节点上的备份中转目录
+func (i *IncusProvider) cleanupBackupWorkDir(workDir string) {
+	if _, err := i.sshClient.Execute(fmt.Sprintf("rm -rf %s", workDir)); err != nil {
+		global.APP_LOG.Warn("清理远程备份目录失败", zap.String("dir", workDir), zap.Error(err))
+	}
+}
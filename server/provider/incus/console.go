@@ -0,0 +1,93 @@
+package incus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// 虚拟机图形控制台通过 console API（type=vga）建立，数据流为SPICE协议，需要证书认证的API访问
+
+// OpenConsole 打开虚拟机实例的SPICE图形控制台
+func (i *IncusProvider) OpenConsole(ctx context.Context, instanceID string) (*provider.ConsoleStream, error) {
+	if !i.connected {
+		return nil, fmt.Errorf("not connected")
+	}
+	if !i.hasAPIAccess() || i.config.ExecutionRule == "ssh_only" {
+		return nil, fmt.Errorf("图形控制台需要配置Incus API证书并允许API调用")
+	}
+
+	tlsConfig, err := i.createTLSConfig(i.config.CertPath, i.config.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("创建TLS配置失败: %w", err)
+	}
+
+	apiURL := fmt.Sprintf("https://%s:8443/1.0/instances/%s/console", i.config.Host, instanceID)
+	payload, _ := json.Marshal(map[string]interface{}{
+		"type":   "vga",
+		"width":  1024,
+		"height": 768,
+	})
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, strings.NewReader(string(payload)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := i.apiClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return nil, fmt.Errorf("failed to open console: %d", resp.StatusCode)
+	}
+
+	var consoleResp struct {
+		Operation string `json:"operation"`
+		Metadata  struct {
+			Metadata struct {
+				Fds map[string]string `json:"fds"`
+			} `json:"metadata"`
+		} `json:"metadata"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&consoleResp); err != nil {
+		return nil, fmt.Errorf("解析控制台响应失败: %w", err)
+	}
+	fds := consoleResp.Metadata.Metadata.Fds
+	if consoleResp.Operation == "" || fds["0"] == "" || fds["control"] == "" {
+		return nil, fmt.Errorf("控制台响应缺少连接信息")
+	}
+
+	// 数据通道和控制通道都需要连接，控制台才会开始转发
+	origin := fmt.Sprintf("https://%s:8443", i.config.Host)
+	wsURL := func(secret string) string {
+		return fmt.Sprintf("wss://%s:8443%s/websocket?secret=%s", i.config.Host, consoleResp.Operation, url.QueryEscape(secret))
+	}
+	control, err := provider.DialConsoleWebSocket(ctx, wsURL(fds["control"]), origin, tlsConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := provider.DialConsoleWebSocket(ctx, wsURL(fds["0"]), origin, tlsConfig, nil)
+	if err != nil {
+		control.Close()
+		return nil, err
+	}
+
+	global.APP_LOG.Info("Incus实例控制台已连接", zap.String("id", utils.TruncateString(instanceID, 50)))
+
+	return &provider.ConsoleStream{
+		Protocol: provider.ConsoleProtocolSPICE,
+		Conn:     provider.NewConsoleConn(data, control),
+	}, nil
+}
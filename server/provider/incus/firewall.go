@@ -0,0 +1,52 @@
+package incus
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// SetInstanceFirewall 通过网络ACL覆盖实例的入站过滤规则，rules为空时解除ACL
+// ACL挂载在实例eth0网卡上，随实例配置保存，重启后仍然生效
+func (i *IncusProvider) SetInstanceFirewall(ctx context.Context, instanceID string, rules []provider.FirewallRule) error {
+	if !i.connected {
+		return fmt.Errorf("provider not connected")
+	}
+	if !i.shouldUseSSH() {
+		return fmt.Errorf("执行规则不允许使用SSH，无法配置实例防火墙")
+	}
+
+	aclName := provider.FirewallResourceName(instanceID)
+	if len(rules) == 0 {
+		// 实例可能已被删除，解除和删除失败都忽略
+		cmd := fmt.Sprintf("incus config device unset %[1]s eth0 security.acls 2>/dev/null; "+
+			"incus config device unset %[1]s eth0 security.acls.default.ingress.action 2>/dev/null; "+
+			"incus network acl delete %[2]s 2>/dev/null; true", instanceID, aclName)
+		if output, err := i.sshClient.Execute(cmd); err != nil {
+			return fmt.Errorf("移除实例防火墙失败: %w, output: %s", err, output)
+		}
+		global.APP_LOG.Info("Incus实例防火墙已移除",
+			zap.String("instanceID", utils.TruncateString(instanceID, 32)))
+		return nil
+	}
+
+	deviceKeys := fmt.Sprintf("security.acls=%s security.acls.default.ingress.action=drop security.acls.default.egress.action=allow", aclName)
+	cmd := fmt.Sprintf("(incus network acl show %[1]s >/dev/null 2>&1 || incus network acl create %[1]s) && "+
+		"echo %[2]s | base64 -d | incus network acl edit %[1]s && "+
+		"(incus config device set %[3]s eth0 %[4]s 2>/dev/null || incus config device override %[3]s eth0 %[4]s)",
+		aclName, provider.EncodeFirewallConfig(provider.BuildNetworkACLYAML(rules)), instanceID, deviceKeys)
+	if output, err := i.sshClient.Execute(cmd); err != nil {
+		return fmt.Errorf("配置实例防火墙失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("Incus实例防火墙配置成功",
+		zap.String("instanceID", utils.TruncateString(instanceID, 32)),
+		zap.String("acl", aclName),
+		zap.Int("ruleCount", len(rules)))
+	return nil
+}
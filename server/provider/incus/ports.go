@@ -29,6 +29,13 @@ func (i *IncusProvider) configurePortMappingsWithIP(ctx context.Context, instanc
 		return nil
 	}
 
+	// nftables端口映射由nftables后端按数据库整表下发，实例内网IP写入数据库后统一应用
+	if networkConfig.IPv4PortMappingMethod == "nftables" {
+		global.APP_LOG.Info("端口映射由nftables后端下发，跳过Provider层配置",
+			zap.String("instance", instanceName))
+		return nil
+	}
+
 	// 从数据库获取实例的端口映射配置
 	var instance providerModel.Instance
 	if err := global.APP_DB.Where("name = ?", instanceName).First(&instance).Error; err != nil {
@@ -241,6 +248,14 @@ func (i *IncusProvider) setupPortMappingWithIP(instanceName string, hostPort, gu
 			zap.Int("guestPort", guestPort),
 			zap.String("protocol", protocol))
 		return nil
+	case "nftables":
+		// 由nftables后端统一下发
+		global.APP_LOG.Info("端口映射由nftables后端下发，跳过",
+			zap.String("instance", instanceName),
+			zap.Int("hostPort", hostPort),
+			zap.Int("guestPort", guestPort),
+			zap.String("protocol", protocol))
+		return nil
 	default:
 		// 默认使用device proxy方式
 		return i.setupDeviceProxyMappingWithIP(instanceName, hostPort, guestPort, protocol)
@@ -524,6 +539,9 @@ func (i *IncusProvider) removePortMapping(instanceName string, hostPort int, pro
 		return i.removeDeviceProxyMapping(instanceName, hostPort, protocol)
 	case "iptables":
 		return i.removeIptablesMappingByPort(instanceName, hostPort, protocol)
+	case "nftables":
+		// 由nftables后端按数据库重新生成规则时删除
+		return nil
 	default:
 		// 默认使用device proxy方式
 		return i.removeDeviceProxyMapping(instanceName, hostPort, protocol)
@@ -0,0 +1,394 @@
+package incus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// CreateSnapshot 创建实例快照
+func (i *IncusProvider) CreateSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	if !i.connected {
+		return fmt.Errorf("not connected")
+	}
+	if !provider.IsValidSnapshotName(snapshotName) {
+		return fmt.Errorf("无效的快照名称: %s", snapshotName)
+	}
+
+	// 根据执行规则判断使用哪种方式
+	if i.shouldUseAPI() {
+		if err := i.apiCreateSnapshot(ctx, instanceID, snapshotName); err == nil {
+			global.APP_LOG.Info("Incus API调用成功 - 创建快照",
+				zap.String("id", utils.TruncateString(instanceID, 50)),
+				zap.String("snapshot", snapshotName))
+			return nil
+		} else {
+			global.APP_LOG.Warn("Incus API失败", zap.Error(err))
+
+			// 检查是否可以回退到SSH
+			if !i.shouldFallbackToSSH() {
+				return fmt.Errorf("API调用失败且不允许回退到SSH: %w", err)
+			}
+			global.APP_LOG.Info("回退到SSH执行 - 创建快照", zap.String("id", utils.TruncateString(instanceID, 50)))
+		}
+	}
+
+	// 如果执行规则不允许使用SSH，则返回错误
+	if !i.shouldUseSSH() {
+		return fmt.Errorf("执行规则不允许使用SSH")
+	}
+
+	// SSH 方式
+	return i.sshCreateSnapshot(instanceID, snapshotName)
+}
+
+// ListSnapshots 获取实例快照列表
+func (i *IncusProvider) ListSnapshots(ctx context.Context, instanceID string) ([]provider.Snapshot, error) {
+	if !i.connected {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	// 根据执行规则判断使用哪种方式
+	if i.shouldUseAPI() {
+		snapshots, err := i.apiListSnapshots(ctx, instanceID)
+		if err == nil {
+			global.APP_LOG.Debug("Incus API调用成功 - 获取快照列表", zap.String("id", utils.TruncateString(instanceID, 50)))
+			return snapshots, nil
+		}
+		global.APP_LOG.Warn("Incus API失败", zap.Error(err))
+
+		// 检查是否可以回退到SSH
+		if !i.shouldFallbackToSSH() {
+			return nil, fmt.Errorf("API调用失败且不允许回退到SSH: %w", err)
+		}
+		global.APP_LOG.Info("回退到SSH执行 - 获取快照列表", zap.String("id", utils.TruncateString(instanceID, 50)))
+	}
+
+	// 如果执行规则不允许使用SSH，则返回错误
+	if !i.shouldUseSSH() {
+		return nil, fmt.Errorf("执行规则不允许使用SSH")
+	}
+
+	// SSH 方式
+	return i.sshListSnapshots(instanceID)
+}
+
+// RestoreSnapshot 将实例恢复到指定快照
+func (i *IncusProvider) RestoreSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	if !i.connected {
+		return fmt.Errorf("not connected")
+	}
+	if !provider.IsValidSnapshotName(snapshotName) {
+		return fmt.Errorf("无效的快照名称: %s", snapshotName)
+	}
+
+	// 根据执行规则判断使用哪种方式
+	if i.shouldUseAPI() {
+		if err := i.apiRestoreSnapshot(ctx, instanceID, snapshotName); err == nil {
+			global.APP_LOG.Info("Incus API调用成功 - 恢复快照",
+				zap.String("id", utils.TruncateString(instanceID, 50)),
+				zap.String("snapshot", snapshotName))
+			return nil
+		} else {
+			global.APP_LOG.Warn("Incus API失败", zap.Error(err))
+
+			// 检查是否可以回退到SSH
+			if !i.shouldFallbackToSSH() {
+				return fmt.Errorf("API调用失败且不允许回退到SSH: %w", err)
+			}
+			global.APP_LOG.Info("回退到SSH执行 - 恢复快照", zap.String("id", utils.TruncateString(instanceID, 50)))
+		}
+	}
+
+	// 如果执行规则不允许使用SSH，则返回错误
+	if !i.shouldUseSSH() {
+		return fmt.Errorf("执行规则不允许使用SSH")
+	}
+
+	// SSH 方式
+	return i.sshRestoreSnapshot(instanceID, snapshotName)
+}
+
+// DeleteSnapshot 删除实例快照
+func (i *IncusProvider) DeleteSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	if !i.connected {
+		return fmt.Errorf("not connected")
+	}
+	if !provider.IsValidSnapshotName(snapshotName) {
+		return fmt.Errorf("无效的快照名称: %s", snapshotName)
+	}
+
+	// 根据执行规则判断使用哪种方式
+	if i.shouldUseAPI() {
+		if err := i.apiDeleteSnapshot(ctx, instanceID, snapshotName); err == nil {
+			global.APP_LOG.Info("Incus API调用成功 - 删除快照",
+				zap.String("id", utils.TruncateString(instanceID, 50)),
+				zap.String("snapshot", snapshotName))
+			return nil
+		} else {
+			global.APP_LOG.Warn("Incus API失败", zap.Error(err))
+
+			// 检查是否可以回退到SSH
+			if !i.shouldFallbackToSSH() {
+				return fmt.Errorf("API调用失败且不允许回退到SSH: %w", err)
+			}
+			global.APP_LOG.Info("回退到SSH执行 - 删除快照", zap.String("id", utils.TruncateString(instanceID, 50)))
+		}
+	}
+
+	// 如果执行规则不允许使用SSH，则返回错误
+	if !i.shouldUseSSH() {
+		return fmt.Errorf("执行规则不允许使用SSH")
+	}
+
+	// SSH 方式
+	return i.sshDeleteSnapshot(instanceID, snapshotName)
+}
+
+// apiCreateSnapshot 通过API创建快照
+func (i *IncusProvider) apiCreateSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	url := fmt.Sprintf("https://%s:8443/1.0/instances/%s/snapshots", i.config.Host, instanceID)
+	payload := map[string]interface{}{
+		"name":     snapshotName,
+		"stateful": false,
+	}
+
+	jsonData, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonData)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := i.apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to create snapshot: %d", resp.StatusCode)
+	}
+
+	return i.apiWaitOperation(ctx, resp)
+}
+
+// apiListSnapshots 通过API获取快照列表
+func (i *IncusProvider) apiListSnapshots(ctx context.Context, instanceID string) ([]provider.Snapshot, error) {
+	url := fmt.Sprintf("https://%s:8443/1.0/instances/%s/snapshots?recursion=1", i.config.Host, instanceID)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := i.apiClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list snapshots: %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Metadata []incusSnapshotInfo `json:"metadata"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	return convertSnapshotInfos(response.Metadata), nil
+}
+
+// apiRestoreSnapshot 通过API恢复快照
+func (i *IncusProvider) apiRestoreSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	url := fmt.Sprintf("https://%s:8443/1.0/instances/%s", i.config.Host, instanceID)
+	payload := map[string]interface{}{
+		"restore": snapshotName,
+	}
+
+	jsonData, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, strings.NewReader(string(jsonData)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := i.apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to restore snapshot: %d", resp.StatusCode)
+	}
+
+	return i.apiWaitOperation(ctx, resp)
+}
+
+// apiDeleteSnapshot 通过API删除快照
+func (i *IncusProvider) apiDeleteSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	url := fmt.Sprintf("https://%s:8443/1.0/instances/%s/snapshots/%s", i.config.Host, instanceID, snapshotName)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := i.apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to delete snapshot: %d", resp.StatusCode)
+	}
+
+	return i.apiWaitOperation(ctx, resp)
+}
+
+// apiWaitOperation 等待Incus异步操作完成
+// 快照相关操作均为异步操作，需要等待完成后才能确定结果
+func (i *IncusProvider) apiWaitOperation(ctx context.Context, resp *http.Response) error {
+	var response struct {
+		Type       string `json:"type"`
+		StatusCode int    `json:"status_code"`
+		Error      string `json:"error"`
+		Operation  string `json:"operation"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("解析操作响应失败: %w", err)
+	}
+	if response.Error != "" {
+		return fmt.Errorf("操作失败: %s", response.Error)
+	}
+	if response.Operation == "" {
+		// 只有明确的同步成功响应才视为完成，否则无法确认操作结果
+		if response.Type == "sync" && response.StatusCode == http.StatusOK {
+			return nil
+		}
+		return fmt.Errorf("响应中缺少操作ID，无法确认操作结果")
+	}
+
+	url := fmt.Sprintf("https://%s:8443%s/wait?timeout=600", i.config.Host, response.Operation)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	waitResp, err := i.apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer waitResp.Body.Close()
+
+	var waitResult struct {
+		Metadata struct {
+			Status string `json:"status"`
+			Err    string `json:"err"`
+		} `json:"metadata"`
+	}
+	if err := json.NewDecoder(waitResp.Body).Decode(&waitResult); err != nil {
+		return fmt.Errorf("解析操作结果失败: %w", err)
+	}
+	if waitResult.Metadata.Err != "" {
+		return fmt.Errorf("操作失败: %s", waitResult.Metadata.Err)
+	}
+	if waitResult.Metadata.Status != "Success" {
+		return fmt.Errorf("操作未成功完成，状态: %s", waitResult.Metadata.Status)
+	}
+
+	return nil
+}
+
+// sshCreateSnapshot 通过SSH创建快照
+func (i *IncusProvider) sshCreateSnapshot(instanceID, snapshotName string) error {
+	output, err := i.sshClient.Execute(fmt.Sprintf("incus snapshot create %s %s", instanceID, snapshotName))
+	if err != nil {
+		return fmt.Errorf("创建快照失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("Incus快照创建成功",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
+
+// sshListSnapshots 通过SSH获取快照列表
+func (i *IncusProvider) sshListSnapshots(instanceID string) ([]provider.Snapshot, error) {
+	output, err := i.sshClient.Execute(fmt.Sprintf("incus query /1.0/instances/%s/snapshots?recursion=1", instanceID))
+	if err != nil {
+		return nil, fmt.Errorf("获取快照列表失败: %w", err)
+	}
+
+	var infos []incusSnapshotInfo
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &infos); err != nil {
+		return nil, fmt.Errorf("解析快照列表失败: %w", err)
+	}
+
+	return convertSnapshotInfos(infos), nil
+}
+
+// sshRestoreSnapshot 通过SSH恢复快照
+func (i *IncusProvider) sshRestoreSnapshot(instanceID, snapshotName string) error {
+	output, err := i.sshClient.Execute(fmt.Sprintf("incus snapshot restore %s %s", instanceID, snapshotName))
+	if err != nil {
+		return fmt.Errorf("恢复快照失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("Incus快照恢复成功",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
+
+// sshDeleteSnapshot 通过SSH删除快照
+func (i *IncusProvider) sshDeleteSnapshot(instanceID, snapshotName string) error {
+	output, err := i.sshClient.Execute(fmt.Sprintf("incus snapshot delete %s %s", instanceID, snapshotName))
+	if err != nil {
+		// 快照已不存在时视为删除成功
+		if strings.Contains(output, "not found") || strings.Contains(err.Error(), "not found") {
+			global.APP_LOG.Info("Incus快照不存在，跳过删除", zap.String("snapshot", snapshotName))
+			return nil
+		}
+		return fmt.Errorf("删除快照失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("Incus快照删除成功",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
+
+// incusSnapshotInfo Incus快照API返回结构
+type incusSnapshotInfo struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	Stateful    bool      `json:"stateful"`
+}
+
+// convertSnapshotInfos 将Incus快照信息转换为统一结构
+func convertSnapshotInfos(infos []incusSnapshotInfo) []provider.Snapshot {
+	snapshots := make([]provider.Snapshot, 0, len(infos))
+	for _, info := range infos {
+		snapshots = append(snapshots, provider.Snapshot{
+			Name:        info.Name,
+			Description: info.Description,
+			Created:     info.CreatedAt,
+			Stateful:    info.Stateful,
+		})
+	}
+	return snapshots
+}
@@ -260,6 +260,11 @@ func (i *IncusProvider) sshCreateInstanceWithProgress(ctx context.Context, confi
 		global.APP_LOG.Warn("配置实例安全设置失败，但继续", zap.Error(err))
 	}
 
+	// 写入用户数据，需在首次启动前完成
+	if err := i.configureInstanceUserData(config); err != nil {
+		return fmt.Errorf("配置用户数据失败: %w", err)
+	}
+
 	updateProgress(50, "启动实例...")
 	// 启动实例
 	_, err = i.sshClient.Execute(fmt.Sprintf("incus start %s", config.Name))
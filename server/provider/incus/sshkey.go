@@ -0,0 +1,34 @@
+package incus
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// SetInstanceSSHKeys 向实例root用户写入SSH公钥，可选禁用密码登录
+// 需要进入实例内部执行命令，只支持SSH方式
+func (i *IncusProvider) SetInstanceSSHKeys(ctx context.Context, instanceID string, publicKeys []string, disablePassword bool) error {
+	if !i.connected {
+		return fmt.Errorf("provider not connected")
+	}
+	if !i.shouldUseSSH() {
+		return fmt.Errorf("执行规则不允许使用SSH，无法注入SSH公钥")
+	}
+
+	cmd := fmt.Sprintf("incus exec %s -- sh -c '%s'", instanceID, provider.BuildSSHKeyScript(publicKeys, disablePassword))
+	if output, err := i.sshClient.Execute(cmd); err != nil {
+		return fmt.Errorf("注入SSH公钥失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("Incus实例SSH公钥注入成功",
+		zap.String("instanceID", utils.TruncateString(instanceID, 12)),
+		zap.Int("keyCount", len(publicKeys)),
+		zap.Bool("disablePassword", disablePassword))
+	return nil
+}
@@ -0,0 +1,33 @@
+package incus
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// OpenTerminal 通过 incus exec 打开实例的交互式终端，终端需要PTY因此只支持SSH方式
+func (i *IncusProvider) OpenTerminal(ctx context.Context, instanceID string, cols, rows int) (*utils.SSHTerminal, error) {
+	if !i.connected {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	// 如果执行规则不允许使用SSH，则返回错误
+	if !i.shouldUseSSH() {
+		return nil, fmt.Errorf("执行规则不允许使用SSH，无法打开终端")
+	}
+
+	cmd := fmt.Sprintf("incus exec %s --env TERM=xterm-256color -- %s", instanceID, provider.TerminalShell)
+	terminal, err := i.sshClient.OpenTerminal(cmd, cols, rows)
+	if err != nil {
+		return nil, fmt.Errorf("打开终端失败: %w", err)
+	}
+
+	global.APP_LOG.Info("Incus实例终端已打开", zap.String("id", utils.TruncateString(instanceID, 50)))
+	return terminal, nil
+}
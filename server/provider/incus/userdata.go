@@ -0,0 +1,28 @@
+package incus
+
+import (
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+
+	"go.uber.org/zap"
+)
+
+// configureInstanceUserData 在实例首次启动前写入cloud-init用户数据，由镜像内的cloud-init在首次启动时执行
+func (i *IncusProvider) configureInstanceUserData(config provider.InstanceConfig) error {
+	if config.UserData == "" {
+		return nil
+	}
+
+	cmd := fmt.Sprintf(`incus config set %s cloud-init.user-data "$(echo %s | base64 -d)"`,
+		config.Name, provider.EncodeUserData(config.UserData))
+	if output, err := i.sshClient.Execute(cmd); err != nil {
+		return fmt.Errorf("设置用户数据失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("Incus实例用户数据配置成功",
+		zap.String("instance", config.Name),
+		zap.Bool("cloudConfig", provider.IsCloudConfig(config.UserData)))
+	return nil
+}
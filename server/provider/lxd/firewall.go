@@ -0,0 +1,52 @@
+package lxd
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// SetInstanceFirewall 通过网络ACL覆盖实例的入站过滤规则，rules为空时解除ACL
+// ACL挂载在实例eth0网卡上，随实例配置保存，重启后仍然生效
+func (l *LXDProvider) SetInstanceFirewall(ctx context.Context, instanceID string, rules []provider.FirewallRule) error {
+	if !l.connected {
+		return fmt.Errorf("provider not connected")
+	}
+	if !l.shouldUseSSH() {
+		return fmt.Errorf("执行规则不允许使用SSH，无法配置实例防火墙")
+	}
+
+	aclName := provider.FirewallResourceName(instanceID)
+	if len(rules) == 0 {
+		// 实例可能已被删除，解除和删除失败都忽略
+		cmd := fmt.Sprintf("lxc config device unset %[1]s eth0 security.acls 2>/dev/null; "+
+			"lxc config device unset %[1]s eth0 security.acls.default.ingress.action 2>/dev/null; "+
+			"lxc network acl delete %[2]s 2>/dev/null; true", instanceID, aclName)
+		if output, err := l.sshClient.Execute(cmd); err != nil {
+			return fmt.Errorf("移除实例防火墙失败: %w, output: %s", err, output)
+		}
+		global.APP_LOG.Info("LXD实例防火墙已移除",
+			zap.String("instanceID", utils.TruncateString(instanceID, 32)))
+		return nil
+	}
+
+	deviceKeys := fmt.Sprintf("security.acls=%s security.acls.default.ingress.action=drop security.acls.default.egress.action=allow", aclName)
+	cmd := fmt.Sprintf("(lxc network acl show %[1]s >/dev/null 2>&1 || lxc network acl create %[1]s) && "+
+		"echo %[2]s | base64 -d | lxc network acl edit %[1]s && "+
+		"(lxc config device set %[3]s eth0 %[4]s 2>/dev/null || lxc config device override %[3]s eth0 %[4]s)",
+		aclName, provider.EncodeFirewallConfig(provider.BuildNetworkACLYAML(rules)), instanceID, deviceKeys)
+	if output, err := l.sshClient.Execute(cmd); err != nil {
+		return fmt.Errorf("配置实例防火墙失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("LXD实例防火墙配置成功",
+		zap.String("instanceID", utils.TruncateString(instanceID, 32)),
+		zap.String("acl", aclName),
+		zap.Int("ruleCount", len(rules)))
+	return nil
+}
@@ -0,0 +1,65 @@
+package lxd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// ResizeInstance 在线调整实例规格
+// CPU和内存通过实例配置调整，磁盘和带宽属于设备配置，只支持SSH方式
+func (l *LXDProvider) ResizeInstance(ctx context.Context, instanceID string, spec provider.ResizeSpec) error {
+	if !l.connected {
+		return fmt.Errorf("not connected")
+	}
+
+	if spec.CPU > 0 {
+		if err := l.setInstanceConfig(ctx, instanceID, "limits.cpu", strconv.Itoa(spec.CPU)); err != nil {
+			return fmt.Errorf("调整CPU失败: %w", err)
+		}
+	}
+	if spec.Memory > 0 {
+		if err := l.setInstanceConfig(ctx, instanceID, "limits.memory", fmt.Sprintf("%dMiB", spec.Memory)); err != nil {
+			return fmt.Errorf("调整内存失败: %w", err)
+		}
+	}
+
+	if spec.Disk <= 0 && spec.Bandwidth <= 0 {
+		return nil
+	}
+	if !l.shouldUseSSH() {
+		return fmt.Errorf("执行规则不允许使用SSH，无法调整磁盘和带宽")
+	}
+
+	// 设备可能继承自profile，此时需要先override到实例上
+	if spec.Disk > 0 {
+		size := fmt.Sprintf("size=%dMiB", spec.Disk)
+		cmd := fmt.Sprintf("lxc config device set %s root %s 2>/dev/null || lxc config device override %s root %s",
+			instanceID, size, instanceID, size)
+		if output, err := l.sshClient.Execute(cmd); err != nil {
+			return fmt.Errorf("调整磁盘失败: %w, output: %s", err, output)
+		}
+	}
+	if spec.Bandwidth > 0 {
+		limits := fmt.Sprintf("limits.egress=%dMbit limits.ingress=%dMbit limits.max=%dMbit", spec.Bandwidth, spec.Bandwidth, spec.Bandwidth)
+		cmd := fmt.Sprintf("lxc config device set %s eth0 %s 2>/dev/null || lxc config device override %s eth0 %s",
+			instanceID, limits, instanceID, limits)
+		if output, err := l.sshClient.Execute(cmd); err != nil {
+			return fmt.Errorf("调整带宽失败: %w, output: %s", err, output)
+		}
+	}
+
+	global.APP_LOG.Info("LXD实例规格调整成功",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.Int("cpu", spec.CPU),
+		zap.Int64("memory", spec.Memory),
+		zap.Int64("disk", spec.Disk),
+		zap.Int("bandwidth", spec.Bandwidth))
+	return nil
+}
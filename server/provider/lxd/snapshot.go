@@ -0,0 +1,394 @@
+package lxd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// CreateSnapshot 创建实例快照
+func (l *LXDProvider) CreateSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	if !l.connected {
+		return fmt.Errorf("not connected")
+	}
+	if !provider.IsValidSnapshotName(snapshotName) {
+		return fmt.Errorf("无效的快照名称: %s", snapshotName)
+	}
+
+	// 根据执行规则判断使用哪种方式
+	if l.shouldUseAPI() {
+		if err := l.apiCreateSnapshot(ctx, instanceID, snapshotName); err == nil {
+			global.APP_LOG.Info("LXD API调用成功 - 创建快照",
+				zap.String("id", utils.TruncateString(instanceID, 50)),
+				zap.String("snapshot", snapshotName))
+			return nil
+		} else {
+			global.APP_LOG.Warn("LXD API失败", zap.Error(err))
+
+			// 检查是否可以回退到SSH
+			if !l.shouldFallbackToSSH() {
+				return fmt.Errorf("API调用失败且不允许回退到SSH: %w", err)
+			}
+			global.APP_LOG.Info("回退到SSH执行 - 创建快照", zap.String("id", utils.TruncateString(instanceID, 50)))
+		}
+	}
+
+	// 如果执行规则不允许使用SSH，则返回错误
+	if !l.shouldUseSSH() {
+		return fmt.Errorf("执行规则不允许使用SSH")
+	}
+
+	// SSH 方式
+	return l.sshCreateSnapshot(instanceID, snapshotName)
+}
+
+// ListSnapshots 获取实例快照列表
+func (l *LXDProvider) ListSnapshots(ctx context.Context, instanceID string) ([]provider.Snapshot, error) {
+	if !l.connected {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	// 根据执行规则判断使用哪种方式
+	if l.shouldUseAPI() {
+		snapshots, err := l.apiListSnapshots(ctx, instanceID)
+		if err == nil {
+			global.APP_LOG.Debug("LXD API调用成功 - 获取快照列表", zap.String("id", utils.TruncateString(instanceID, 50)))
+			return snapshots, nil
+		}
+		global.APP_LOG.Warn("LXD API失败", zap.Error(err))
+
+		// 检查是否可以回退到SSH
+		if !l.shouldFallbackToSSH() {
+			return nil, fmt.Errorf("API调用失败且不允许回退到SSH: %w", err)
+		}
+		global.APP_LOG.Info("回退到SSH执行 - 获取快照列表", zap.String("id", utils.TruncateString(instanceID, 50)))
+	}
+
+	// 如果执行规则不允许使用SSH，则返回错误
+	if !l.shouldUseSSH() {
+		return nil, fmt.Errorf("执行规则不允许使用SSH")
+	}
+
+	// SSH 方式
+	return l.sshListSnapshots(instanceID)
+}
+
+// RestoreSnapshot 将实例恢复到指定快照
+func (l *LXDProvider) RestoreSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	if !l.connected {
+		return fmt.Errorf("not connected")
+	}
+	if !provider.IsValidSnapshotName(snapshotName) {
+		return fmt.Errorf("无效的快照名称: %s", snapshotName)
+	}
+
+	// 根据执行规则判断使用哪种方式
+	if l.shouldUseAPI() {
+		if err := l.apiRestoreSnapshot(ctx, instanceID, snapshotName); err == nil {
+			global.APP_LOG.Info("LXD API调用成功 - 恢复快照",
+				zap.String("id", utils.TruncateString(instanceID, 50)),
+				zap.String("snapshot", snapshotName))
+			return nil
+		} else {
+			global.APP_LOG.Warn("LXD API失败", zap.Error(err))
+
+			// 检查是否可以回退到SSH
+			if !l.shouldFallbackToSSH() {
+				return fmt.Errorf("API调用失败且不允许回退到SSH: %w", err)
+			}
+			global.APP_LOG.Info("回退到SSH执行 - 恢复快照", zap.String("id", utils.TruncateString(instanceID, 50)))
+		}
+	}
+
+	// 如果执行规则不允许使用SSH，则返回错误
+	if !l.shouldUseSSH() {
+		return fmt.Errorf("执行规则不允许使用SSH")
+	}
+
+	// SSH 方式
+	return l.sshRestoreSnapshot(instanceID, snapshotName)
+}
+
+// DeleteSnapshot 删除实例快照
+func (l *LXDProvider) DeleteSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	if !l.connected {
+		return fmt.Errorf("not connected")
+	}
+	if !provider.IsValidSnapshotName(snapshotName) {
+		return fmt.Errorf("无效的快照名称: %s", snapshotName)
+	}
+
+	// 根据执行规则判断使用哪种方式
+	if l.shouldUseAPI() {
+		if err := l.apiDeleteSnapshot(ctx, instanceID, snapshotName); err == nil {
+			global.APP_LOG.Info("LXD API调用成功 - 删除快照",
+				zap.String("id", utils.TruncateString(instanceID, 50)),
+				zap.String("snapshot", snapshotName))
+			return nil
+		} else {
+			global.APP_LOG.Warn("LXD API失败", zap.Error(err))
+
+			// 检查是否可以回退到SSH
+			if !l.shouldFallbackToSSH() {
+				return fmt.Errorf("API调用失败且不允许回退到SSH: %w", err)
+			}
+			global.APP_LOG.Info("回退到SSH执行 - 删除快照", zap.String("id", utils.TruncateString(instanceID, 50)))
+		}
+	}
+
+	// 如果执行规则不允许使用SSH，则返回错误
+	if !l.shouldUseSSH() {
+		return fmt.Errorf("执行规则不允许使用SSH")
+	}
+
+	// SSH 方式
+	return l.sshDeleteSnapshot(instanceID, snapshotName)
+}
+
+// apiCreateSnapshot 通过API创建快照
+func (l *LXDProvider) apiCreateSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	url := fmt.Sprintf("https://%s:8443/1.0/instances/%s/snapshots", l.config.Host, instanceID)
+	payload := map[string]interface{}{
+		"name":     snapshotName,
+		"stateful": false,
+	}
+
+	jsonData, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonData)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to create snapshot: %d", resp.StatusCode)
+	}
+
+	return l.apiWaitOperation(ctx, resp)
+}
+
+// apiListSnapshots 通过API获取快照列表
+func (l *LXDProvider) apiListSnapshots(ctx context.Context, instanceID string) ([]provider.Snapshot, error) {
+	url := fmt.Sprintf("https://%s:8443/1.0/instances/%s/snapshots?recursion=1", l.config.Host, instanceID)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := l.apiClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list snapshots: %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Metadata []lxdSnapshotInfo `json:"metadata"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	return convertSnapshotInfos(response.Metadata), nil
+}
+
+// apiRestoreSnapshot 通过API恢复快照
+func (l *LXDProvider) apiRestoreSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	url := fmt.Sprintf("https://%s:8443/1.0/instances/%s", l.config.Host, instanceID)
+	payload := map[string]interface{}{
+		"restore": snapshotName,
+	}
+
+	jsonData, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, strings.NewReader(string(jsonData)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to restore snapshot: %d", resp.StatusCode)
+	}
+
+	return l.apiWaitOperation(ctx, resp)
+}
+
+// apiDeleteSnapshot 通过API删除快照
+func (l *LXDProvider) apiDeleteSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	url := fmt.Sprintf("https://%s:8443/1.0/instances/%s/snapshots/%s", l.config.Host, instanceID, snapshotName)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := l.apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to delete snapshot: %d", resp.StatusCode)
+	}
+
+	return l.apiWaitOperation(ctx, resp)
+}
+
+// apiWaitOperation 等待LXD异步操作完成
+// 快照相关操作均为异步操作，需要等待完成后才能确定结果
+func (l *LXDProvider) apiWaitOperation(ctx context.Context, resp *http.Response) error {
+	var response struct {
+		Type       string `json:"type"`
+		StatusCode int    `json:"status_code"`
+		Error      string `json:"error"`
+		Operation  string `json:"operation"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("解析操作响应失败: %w", err)
+	}
+	if response.Error != "" {
+		return fmt.Errorf("操作失败: %s", response.Error)
+	}
+	if response.Operation == "" {
+		// 只有明确的同步成功响应才视为完成，否则无法确认操作结果
+		if response.Type == "sync" && response.StatusCode == http.StatusOK {
+			return nil
+		}
+		return fmt.Errorf("响应中缺少操作ID，无法确认操作结果")
+	}
+
+	url := fmt.Sprintf("https://%s:8443%s/wait?timeout=600", l.config.Host, response.Operation)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	waitResp, err := l.apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer waitResp.Body.Close()
+
+	var waitResult struct {
+		Metadata struct {
+			Status string `json:"status"`
+			Err    string `json:"err"`
+		} `json:"metadata"`
+	}
+	if err := json.NewDecoder(waitResp.Body).Decode(&waitResult); err != nil {
+		return fmt.Errorf("解析操作结果失败: %w", err)
+	}
+	if waitResult.Metadata.Err != "" {
+		return fmt.Errorf("操作失败: %s", waitResult.Metadata.Err)
+	}
+	if waitResult.Metadata.Status != "Success" {
+		return fmt.Errorf("操作未成功完成，状态: %s", waitResult.Metadata.Status)
+	}
+
+	return nil
+}
+
+// sshCreateSnapshot 通过SSH创建快照
+func (l *LXDProvider) sshCreateSnapshot(instanceID, snapshotName string) error {
+	output, err := l.sshClient.Execute(fmt.Sprintf("lxc snapshot %s %s", instanceID, snapshotName))
+	if err != nil {
+		return fmt.Errorf("创建快照失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("LXD快照创建成功",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
+
+// sshListSnapshots 通过SSH获取快照列表
+func (l *LXDProvider) sshListSnapshots(instanceID string) ([]provider.Snapshot, error) {
+	output, err := l.sshClient.Execute(fmt.Sprintf("lxc query /1.0/instances/%s/snapshots?recursion=1", instanceID))
+	if err != nil {
+		return nil, fmt.Errorf("获取快照列表失败: %w", err)
+	}
+
+	var infos []lxdSnapshotInfo
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &infos); err != nil {
+		return nil, fmt.Errorf("解析快照列表失败: %w", err)
+	}
+
+	return convertSnapshotInfos(infos), nil
+}
+
+// sshRestoreSnapshot 通过SSH恢复快照
+func (l *LXDProvider) sshRestoreSnapshot(instanceID, snapshotName string) error {
+	output, err := l.sshClient.Execute(fmt.Sprintf("lxc restore %s %s", instanceID, snapshotName))
+	if err != nil {
+		return fmt.Errorf("恢复快照失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("LXD快照恢复成功",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
+
+// sshDeleteSnapshot 通过SSH删除快照
+func (l *LXDProvider) sshDeleteSnapshot(instanceID, snapshotName string) error {
+	output, err := l.sshClient.Execute(fmt.Sprintf("lxc delete %s/%s", instanceID, snapshotName))
+	if err != nil {
+		// 快照已不存在时视为删除成功
+		if strings.Contains(output, "not found") || strings.Contains(err.Error(), "not found") {
+			global.APP_LOG.Info("LXD快照不存在，跳过删除", zap.String("snapshot", snapshotName))
+			return nil
+		}
+		return fmt.Errorf("删除快照失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("LXD快照删除成功",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
+
+// lxdSnapshotInfo LXD快照API返回结构
+type lxdSnapshotInfo struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	Stateful    bool      `json:"stateful"`
+}
+
+// convertSnapshotInfos 将LXD快照信息转换为统一结构
+func convertSnapshotInfos(infos []lxdSnapshotInfo) []provider.Snapshot {
+	snapshots := make([]provider.Snapshot, 0, len(infos))
+	for _, info := range infos {
+		snapshots = append(snapshots, provider.Snapshot{
+			Name:        info.Name,
+			Description: info.Description,
+			Created:     info.CreatedAt,
+			Stateful:    info.Stateful,
+		})
+	}
+	return snapshots
+}
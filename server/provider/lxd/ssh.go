@@ -334,6 +334,11 @@ func (l *LXDProvider) sshCreateInstanceWithProgress(ctx context.Context, config
 		global.APP_LOG.Warn("配置实例安全设置失败，但继续", zap.Error(err))
 	}
 
+	// 写入用户数据，需在首次启动前完成
+	if err := l.configureInstanceUserData(config); err != nil {
+		return fmt.Errorf("配置用户数据失败: %w", err)
+	}
+
 	updateProgress(55, "启动实例...")
 	// 启动实例
 	_, err = l.sshClient.Execute(fmt.Sprintf("lxc start %s", config.Name))
@@ -0,0 +1,33 @@
+package lxd
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// OpenTerminal 通过 lxc exec 打开实例的交互式终端，终端需要PTY因此只支持SSH方式
+func (l *LXDProvider) OpenTerminal(ctx context.Context, instanceID string, cols, rows int) (*utils.SSHTerminal, error) {
+	if !l.connected {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	// 如果执行规则不允许使用SSH，则返回错误
+	if !l.shouldUseSSH() {
+		return nil, fmt.Errorf("执行规则不允许使用SSH，无法打开终端")
+	}
+
+	cmd := fmt.Sprintf("lxc exec %s --env TERM=xterm-256color -- %s", instanceID, provider.TerminalShell)
+	terminal, err := l.sshClient.OpenTerminal(cmd, cols, rows)
+	if err != nil {
+		return nil, fmt.Errorf("打开终端失败: %w", err)
+	}
+
+	global.APP_LOG.Info("LXD实例终端已打开", zap.String("id", utils.TruncateString(instanceID, 50)))
+	return terminal, nil
+}
@@ -0,0 +1,35 @@
+package lxd
+
+import (
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+
+	"go.uber.org/zap"
+)
+
+// configureInstanceUserData 在实例首次启动前写入cloud-init用户数据，由镜像内的cloud-init在首次启动时执行
+// 旧版本LXD不支持cloud-init.*配置项，失败时回退到user.user-data
+func (l *LXDProvider) configureInstanceUserData(config provider.InstanceConfig) error {
+	if config.UserData == "" {
+		return nil
+	}
+
+	value := fmt.Sprintf(`"$(echo %s | base64 -d)"`, provider.EncodeUserData(config.UserData))
+	output, err := l.sshClient.Execute(fmt.Sprintf("lxc config set %s cloud-init.user-data %s", config.Name, value))
+	if err != nil {
+		global.APP_LOG.Warn("设置cloud-init.user-data失败，尝试user.user-data",
+			zap.String("instance", config.Name),
+			zap.String("output", output),
+			zap.Error(err))
+		if output, err = l.sshClient.Execute(fmt.Sprintf("lxc config set %s user.user-data %s", config.Name, value)); err != nil {
+			return fmt.Errorf("设置用户数据失败: %w, output: %s", err, output)
+		}
+	}
+
+	global.APP_LOG.Info("LXD实例用户数据配置成功",
+		zap.String("instance", config.Name),
+		zap.Bool("cloudConfig", provider.IsCloudConfig(config.UserData)))
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"oneclickvirt/global"
 	"oneclickvirt/model/provider"
 	"oneclickvirt/provider/portmapping"
+	"oneclickvirt/utils"
 	"strconv"
 
 	"go.uber.org/zap"
@@ -194,6 +195,55 @@ func (i *IncusPortMapping) ListPortMappings(ctx context.Context, instanceID stri
 	return results, nil
 }
 
+// ListLiveMappings 读取Incus主机上实际生效的proxy设备映射
+func (i *IncusPortMapping) ListLiveMappings(ctx context.Context, providerID uint) ([]*portmapping.LiveMapping, error) {
+	sshClient, err := i.connect(providerID)
+	if err != nil {
+		return nil, err
+	}
+	defer sshClient.Close()
+	return portmapping.ListProxyDevices(sshClient, "incus")
+}
+
+// RestoreMappings 按数据库记录重新创建缺失或被改动的proxy设备
+func (i *IncusPortMapping) RestoreMappings(ctx context.Context, providerID uint, ports []provider.Port) error {
+	if len(ports) == 0 {
+		return nil
+	}
+	sshClient, err := i.connect(providerID)
+	if err != nil {
+		return err
+	}
+	defer sshClient.Close()
+	return portmapping.RestoreProxyDevices(sshClient, "incus", ports)
+}
+
+// RemoveLiveMappings 删除多余或与数据库冲突的proxy设备
+func (i *IncusPortMapping) RemoveLiveMappings(ctx context.Context, providerID uint, mappings []*portmapping.LiveMapping) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+	sshClient, err := i.connect(providerID)
+	if err != nil {
+		return err
+	}
+	defer sshClient.Close()
+	return portmapping.RemoveProxyDevices(sshClient, "incus", mappings)
+}
+
+// connect 连接到Provider主机
+func (i *IncusPortMapping) connect(providerID uint) (*utils.SSHClient, error) {
+	providerInfo, err := i.getProvider(providerID)
+	if err != nil {
+		return nil, err
+	}
+	sshClient, err := portmapping.NewProviderSSHClient(providerInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to provider: %v", err)
+	}
+	return sshClient, nil
+}
+
 // validateRequest 验证请求参数
 func (i *IncusPortMapping) validateRequest(req *portmapping.PortMappingRequest) error {
 	if req.InstanceID == "" {
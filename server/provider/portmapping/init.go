@@ -84,6 +84,13 @@ func GetProviderDescription(providerType string) map[string]interface{} {
 			"protocols":   []string{"tcp", "udp"},
 			"features":    []string{"universal", "flexible", "host-level", "high-performance"},
 		},
+		"nftables": {
+			"name":        "nftables",
+			"description": "nftables NAT端口映射，每个Provider使用独立的表并通过nft -f原子更新",
+			"methods":     []string{"dnat", "masquerade"},
+			"protocols":   []string{"tcp", "udp"},
+			"features":    []string{"universal", "atomic", "host-level", "reconcilable"},
+		},
 	}
 
 	if desc, exists := descriptions[providerType]; exists {
@@ -156,6 +163,15 @@ func GetProviderCapabilities(providerType string) map[string]bool {
 			"hot_reload":           true,
 			"persistent":           false,
 		},
+		"nftables": {
+			"auto_port_allocation": true,
+			"custom_port_range":    true,
+			"ipv6_support":         false,
+			"protocol_tcp":         true,
+			"protocol_udp":         true,
+			"hot_reload":           true,
+			"persistent":           true,
+		},
 	}
 
 	if caps, exists := capabilities[providerType]; exists {
@@ -7,6 +7,7 @@ import (
 	"oneclickvirt/model/provider"
 	"oneclickvirt/provider/portmapping"
 	"oneclickvirt/utils"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -451,6 +452,132 @@ func (i *IptablesPortMapping) cleanupIptablesRule(ctx context.Context, instance
 	}
 }
 
+// dnatRulePattern 匹配 iptables -S 输出中的DNAT规则，端口可能为区间
+var dnatRulePattern = regexp.MustCompile(`-p (tcp|udp) .*--dport (\d+)(?::(\d+))? .*-j DNAT --to-destination ([0-9.]+)(?::(\d+)(?:-(\d+))?)?`)
+
+// ListLiveMappings 读取主机nat表PREROUTING链中的DNAT规则
+func (i *IptablesPortMapping) ListLiveMappings(ctx context.Context, providerID uint) ([]*portmapping.LiveMapping, error) {
+	providerInfo, err := i.getProvider(providerID)
+	if err != nil {
+		return nil, err
+	}
+	sshClient, err := i.createSSHClient(providerInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH client: %v", err)
+	}
+	defer sshClient.Close()
+
+	output, err := sshClient.Execute("iptables -t nat -S PREROUTING")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list iptables rules: %v", err)
+	}
+	return parseDNATRules(output), nil
+}
+
+// parseDNATRules 解析DNAT规则，区间规则展开为逐个端口，Ref保存规则原文用于删除
+func parseDNATRules(output string) []*portmapping.LiveMapping {
+	var mappings []*portmapping.LiveMapping
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "-A PREROUTING ") {
+			continue
+		}
+		m := dnatRulePattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		hostStart, _ := strconv.Atoi(m[2])
+		hostEnd := hostStart
+		if m[3] != "" {
+			hostEnd, _ = strconv.Atoi(m[3])
+		}
+		// 未指定目标端口时保持原端口
+		guestStart := hostStart
+		if m[5] != "" {
+			guestStart, _ = strconv.Atoi(m[5])
+		}
+
+		for port := hostStart; port <= hostEnd; port++ {
+			mappings = append(mappings, &portmapping.LiveMapping{
+				TargetIP:  m[4],
+				HostPort:  port,
+				GuestPort: guestStart + (port - hostStart),
+				Protocol:  m[1],
+				Ref:       line,
+			})
+		}
+	}
+	return mappings
+}
+
+// RestoreMappings 按数据库记录重新创建缺失或被改动的iptables规则
+func (i *IptablesPortMapping) RestoreMappings(ctx context.Context, providerID uint, ports []provider.Port) error {
+	if len(ports) == 0 {
+		return nil
+	}
+	providerInfo, err := i.getProvider(providerID)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, port := range ports {
+		instance, err := i.getInstance(strconv.FormatUint(uint64(port.InstanceID), 10))
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%d(%v)", port.HostPort, err))
+			continue
+		}
+		instance.PrivateIP = portmapping.CleanIP(instance.PrivateIP)
+		if err := i.createIptablesRule(ctx, instance, port.HostPort, port.GuestPort, port.Protocol, providerInfo); err != nil {
+			failed = append(failed, fmt.Sprintf("%d(%v)", port.HostPort, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to restore iptables rules: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// RemoveLiveMappings 删除多余或与数据库冲突的DNAT规则
+// FORWARD和MASQUERADE规则可能被同一目标的其他映射共用，这里不做删除
+func (i *IptablesPortMapping) RemoveLiveMappings(ctx context.Context, providerID uint, mappings []*portmapping.LiveMapping) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+	providerInfo, err := i.getProvider(providerID)
+	if err != nil {
+		return err
+	}
+	sshClient, err := i.createSSHClient(providerInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create SSH client: %v", err)
+	}
+	defer sshClient.Close()
+
+	removed := make(map[string]bool)
+	var failed []string
+	for _, m := range mappings {
+		if m.Ref == "" || removed[m.Ref] {
+			continue
+		}
+		removed[m.Ref] = true
+		cmd := "iptables -t nat -D " + strings.TrimPrefix(m.Ref, "-A ")
+		if _, err := sshClient.Execute(cmd); err != nil {
+			failed = append(failed, fmt.Sprintf("%s(%v)", m.Ref, err))
+		}
+	}
+
+	if _, err := sshClient.Execute("iptables-save > /etc/iptables/rules.v4 2>/dev/null || true"); err != nil {
+		global.APP_LOG.Warn("Failed to save iptables rules", zap.Error(err))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to remove iptables rules: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
 // createSSHClient 创建SSH客户端连接到provider主机
 func (i *IptablesPortMapping) createSSHClient(providerInfo *provider.Provider) (*utils.SSHClient, error) {
 	// 解析endpoint获取host和port
@@ -6,6 +6,7 @@ import (
 	"oneclickvirt/global"
 	"oneclickvirt/model/provider"
 	"oneclickvirt/provider/portmapping"
+	"oneclickvirt/utils"
 	"strconv"
 
 	"go.uber.org/zap"
@@ -194,6 +195,55 @@ func (l *LXDPortMapping) ListPortMappings(ctx context.Context, instanceID string
 	return results, nil
 }
 
+// ListLiveMappings 读取LXD主机上实际生效的proxy设备映射
+func (l *LXDPortMapping) ListLiveMappings(ctx context.Context, providerID uint) ([]*portmapping.LiveMapping, error) {
+	sshClient, err := l.connect(providerID)
+	if err != nil {
+		return nil, err
+	}
+	defer sshClient.Close()
+	return portmapping.ListProxyDevices(sshClient, "lxc")
+}
+
+// RestoreMappings 按数据库记录重新创建缺失或被改动的proxy设备
+func (l *LXDPortMapping) RestoreMappings(ctx context.Context, providerID uint, ports []provider.Port) error {
+	if len(ports) == 0 {
+		return nil
+	}
+	sshClient, err := l.connect(providerID)
+	if err != nil {
+		return err
+	}
+	defer sshClient.Close()
+	return portmapping.RestoreProxyDevices(sshClient, "lxc", ports)
+}
+
+// RemoveLiveMappings 删除多余或与数据库冲突的proxy设备
+func (l *LXDPortMapping) RemoveLiveMappings(ctx context.Context, providerID uint, mappings []*portmapping.LiveMapping) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+	sshClient, err := l.connect(providerID)
+	if err != nil {
+		return err
+	}
+	defer sshClient.Close()
+	return portmapping.RemoveProxyDevices(sshClient, "lxc", mappings)
+}
+
+// connect 连接到Provider主机
+func (l *LXDPortMapping) connect(providerID uint) (*utils.SSHClient, error) {
+	providerInfo, err := l.getProvider(providerID)
+	if err != nil {
+		return nil, err
+	}
+	sshClient, err := portmapping.NewProviderSSHClient(providerInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to provider: %v", err)
+	}
+	return sshClient, nil
+}
+
 // validateRequest 验证请求参数
 func (l *LXDPortMapping) validateRequest(req *portmapping.PortMappingRequest) error {
 	if req.InstanceID == "" {
@@ -269,6 +319,9 @@ func (l *LXDPortMapping) determineMappingMethod(req *portmapping.PortMappingRequ
 		return "lxd-iptables"
 	case "device_proxy":
 		return "lxd-device-proxy"
+	case "nftables":
+		// 记录交由nftables后端管理，下次按数据库下发整表时生效
+		return "nftables"
 	default:
 		return "lxd-device-proxy"
 	}
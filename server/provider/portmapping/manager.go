@@ -169,6 +169,10 @@ func (m *Manager) GetProviderCapabilities(providerType string) map[string]interf
 		capabilities["description"] = "通用iptables NAT端口映射"
 		capabilities["methods"] = []string{"nat", "dnat", "snat"}
 		capabilities["limitations"] = []string{"需要root权限"}
+	case "nftables":
+		capabilities["description"] = "nftables NAT端口映射，规则原子更新并可与数据库对账"
+		capabilities["methods"] = []string{"dnat", "masquerade"}
+		capabilities["limitations"] = []string{"需要root权限", "需要nft命令", "仅支持IPv4"}
 	}
 
 	return capabilities
@@ -0,0 +1,720 @@
+package nftables
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"oneclickvirt/global"
+	"oneclickvirt/model/provider"
+	"oneclickvirt/provider/portmapping"
+	"oneclickvirt/utils"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// mappingMethod 写入端口记录的映射方法，同时用于筛选由本后端管理的端口
+	mappingMethod = "nftables"
+	// rulesetDir Provider主机上保存规则文件的目录
+	rulesetDir = "/etc/nftables.d"
+	// nftablesConf 系统nftables配置文件，开机时加载
+	nftablesConf = "/etc/nftables.conf"
+)
+
+// 端口映射对账状态
+const (
+	StatusActive   = "active"   // 数据库记录与主机规则一致
+	StatusMissing  = "missing"  // 数据库中存在，但主机上没有对应规则
+	StatusOrphaned = "orphaned" // 主机上存在规则，但数据库中没有对应记录
+)
+
+// dnatRulePattern 匹配 nft list table 输出中由本后端生成的DNAT规则
+var dnatRulePattern = regexp.MustCompile(`(tcp|udp) dport (\d+) dnat (?:ip )?to ([0-9.]+):(\d+) comment "instance:(\d+)"`)
+
+// providerLocks 每个Provider一把锁，读取数据库、替换主机上的表和写回数据库在锁内完成，避免并发变更时互相覆盖
+var providerLocks sync.Map
+
+func providerLock(providerID uint) *sync.Mutex {
+	lock, _ := providerLocks.LoadOrStore(providerID, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// NftablesPortMapping nftables端口映射实现
+// 每个Provider的所有映射保存在主机上独立的表中，每次变更都根据数据库重新生成整张表并通过 nft -f 原子替换
+type NftablesPortMapping struct {
+	*portmapping.BaseProvider
+}
+
+// NewNftablesPortMapping 创建nftables端口映射Provider
+func NewNftablesPortMapping(config *portmapping.ManagerConfig) portmapping.PortMappingProvider {
+	return &NftablesPortMapping{
+		BaseProvider: portmapping.NewBaseProvider("nftables", config),
+	}
+}
+
+// SupportsDynamicMapping nftables支持动态端口映射
+func (n *NftablesPortMapping) SupportsDynamicMapping() bool {
+	return true
+}
+
+// rule 单条端口映射规则，协议为both时生成TCP和UDP两条规则
+type rule struct {
+	PortID     uint
+	InstanceID uint
+	InstanceIP string
+	HostPort   int
+	GuestPort  int
+	Protocol   string
+}
+
+// key 用于去重和对账的规则标识
+func (r rule) key() string {
+	return fmt.Sprintf("%d/%s/%d", r.InstanceID, r.Protocol, r.HostPort)
+}
+
+// CreatePortMapping 创建nftables端口映射
+func (n *NftablesPortMapping) CreatePortMapping(ctx context.Context, req *portmapping.PortMappingRequest) (*portmapping.PortMappingResult, error) {
+	global.APP_LOG.Info("Creating nftables port mapping",
+		zap.String("instanceId", req.InstanceID),
+		zap.Int("hostPort", req.HostPort),
+		zap.Int("guestPort", req.GuestPort),
+		zap.String("protocol", req.Protocol))
+
+	// 验证请求参数
+	if err := n.validateRequest(req); err != nil {
+		return nil, fmt.Errorf("invalid request: %v", err)
+	}
+
+	// 获取实例信息
+	instance, err := n.getInstance(req.InstanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance: %v", err)
+	}
+	instanceIP := portmapping.CleanIP(instance.PrivateIP)
+	if instanceIP == "" {
+		return nil, fmt.Errorf("instance private IP address not found for %s", instance.Name)
+	}
+
+	// 获取Provider信息
+	providerInfo, err := n.getProvider(req.ProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider: %v", err)
+	}
+
+	lock := providerLock(providerInfo.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// 分配端口
+	hostPort := req.HostPort
+	if hostPort == 0 {
+		hostPort, err = n.BaseProvider.AllocatePort(ctx, req.ProviderID, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to allocate port: %v", err)
+		}
+	}
+
+	// 在现有规则基础上加入新映射并原子应用
+	rules, err := n.loadRules(providerInfo.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	rules = append(rules, rule{
+		InstanceID: instance.ID,
+		InstanceIP: instanceIP,
+		HostPort:   hostPort,
+		GuestPort:  req.GuestPort,
+		Protocol:   req.Protocol,
+	})
+	if err := n.applyRuleset(providerInfo, rules); err != nil {
+		return nil, fmt.Errorf("failed to apply nftables ruleset: %v", err)
+	}
+
+	// 判断是否为SSH端口：优先使用请求中的IsSSH字段，否则根据GuestPort判断
+	isSSH := req.GuestPort == 22
+	if req.IsSSH != nil {
+		isSSH = *req.IsSSH
+	}
+
+	// 保存到数据库
+	result := &portmapping.PortMappingResult{
+		InstanceID:    req.InstanceID,
+		ProviderID:    req.ProviderID,
+		Protocol:      req.Protocol,
+		HostPort:      hostPort,
+		GuestPort:     req.GuestPort,
+		HostIP:        providerInfo.Endpoint,
+		PublicIP:      n.getPublicIP(providerInfo),
+		IPv6Address:   req.IPv6Address,
+		Status:        "active",
+		Description:   req.Description,
+		MappingMethod: mappingMethod,
+		IsSSH:         isSSH,
+		IsAutomatic:   req.HostPort == 0,
+	}
+
+	portModel := n.BaseProvider.ToDBModel(result)
+	portModel.MappingMethod = mappingMethod
+	if err := global.APP_DB.Create(portModel).Error; err != nil {
+		global.APP_LOG.Error("Failed to save port mapping to database", zap.Error(err))
+		// 按数据库重新生成规则，撤销刚加入的映射
+		if syncErr := n.applyWithout(providerInfo.ID, 0); syncErr != nil {
+			global.APP_LOG.Error("Failed to rollback nftables ruleset", zap.Error(syncErr))
+		}
+		return nil, fmt.Errorf("failed to save port mapping: %v", err)
+	}
+
+	result.ID = portModel.ID
+	result.CreatedAt = portModel.CreatedAt.Format(time.RFC3339)
+	result.UpdatedAt = portModel.UpdatedAt.Format(time.RFC3339)
+
+	global.APP_LOG.Info("nftables port mapping created successfully",
+		zap.Uint("id", result.ID),
+		zap.Int("hostPort", hostPort),
+		zap.Int("guestPort", req.GuestPort))
+
+	return result, nil
+}
+
+// DeletePortMapping 删除nftables端口映射
+func (n *NftablesPortMapping) DeletePortMapping(ctx context.Context, req *portmapping.DeletePortMappingRequest) error {
+	global.APP_LOG.Info("Deleting nftables port mapping",
+		zap.Uint("id", req.ID),
+		zap.String("instanceId", req.InstanceID))
+
+	// 获取端口映射信息
+	var portModel provider.Port
+	if err := global.APP_DB.First(&portModel, req.ID).Error; err != nil {
+		return fmt.Errorf("port mapping not found: %v", err)
+	}
+
+	lock := providerLock(portModel.ProviderID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// 重新生成不含该映射的规则
+	if err := n.applyWithout(portModel.ProviderID, portModel.ID); err != nil {
+		if !req.ForceDelete {
+			return fmt.Errorf("failed to remove nftables rule: %v", err)
+		}
+		global.APP_LOG.Warn("Failed to remove nftables rule, but force delete is enabled", zap.Error(err))
+	}
+
+	// 从数据库删除
+	if err := global.APP_DB.Delete(&portModel).Error; err != nil {
+		return fmt.Errorf("failed to delete port mapping from database: %v", err)
+	}
+
+	global.APP_LOG.Info("nftables port mapping deleted successfully", zap.Uint("id", req.ID))
+	return nil
+}
+
+// UpdatePortMapping 更新nftables端口映射
+func (n *NftablesPortMapping) UpdatePortMapping(ctx context.Context, req *portmapping.UpdatePortMappingRequest) (*portmapping.PortMappingResult, error) {
+	global.APP_LOG.Info("Updating nftables port mapping", zap.Uint("id", req.ID))
+
+	// 获取现有端口映射
+	var portModel provider.Port
+	if err := global.APP_DB.First(&portModel, req.ID).Error; err != nil {
+		return nil, fmt.Errorf("port mapping not found: %v", err)
+	}
+
+	// 未指定状态时保持原状态，避免写入空状态后在下次整表重建时丢失该映射
+	if req.Status == "" {
+		req.Status = portModel.Status
+	}
+
+	// 获取实例信息
+	instance, err := n.getInstance(req.InstanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance: %v", err)
+	}
+
+	// 获取Provider信息
+	providerInfo, err := n.getProvider(portModel.ProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider: %v", err)
+	}
+
+	lock := providerLock(providerInfo.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// 端口或协议发生变化时，用新的映射替换旧规则
+	if req.HostPort != portModel.HostPort || req.GuestPort != portModel.GuestPort || req.Protocol != portModel.Protocol || req.Status != portModel.Status {
+		rules, err := n.loadRules(providerInfo.ID, portModel.ID)
+		if err != nil {
+			return nil, err
+		}
+		if req.Status == "active" {
+			rules = append(rules, rule{
+				PortID:     portModel.ID,
+				InstanceID: instance.ID,
+				InstanceIP: portmapping.CleanIP(instance.PrivateIP),
+				HostPort:   req.HostPort,
+				GuestPort:  req.GuestPort,
+				Protocol:   req.Protocol,
+			})
+		}
+		if err := n.applyRuleset(providerInfo, rules); err != nil {
+			return nil, fmt.Errorf("failed to apply nftables ruleset: %v", err)
+		}
+	}
+
+	// 更新数据库记录
+	updates := map[string]interface{}{
+		"host_port":   req.HostPort,
+		"guest_port":  req.GuestPort,
+		"protocol":    req.Protocol,
+		"description": req.Description,
+		"status":      req.Status,
+	}
+
+	if err := global.APP_DB.Model(&portModel).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update port mapping: %v", err)
+	}
+
+	// 重新获取更新后的记录
+	if err := global.APP_DB.First(&portModel, req.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get updated port mapping: %v", err)
+	}
+
+	result := n.BaseProvider.FromDBModel(&portModel)
+	result.HostIP = providerInfo.Endpoint
+	result.PublicIP = n.getPublicIP(providerInfo)
+	result.MappingMethod = mappingMethod
+
+	global.APP_LOG.Info("nftables port mapping updated successfully", zap.Uint("id", req.ID))
+	return result, nil
+}
+
+// ListPortMappings 列出实例的nftables端口映射，并与主机上的实际规则对账
+// 数据库记录的Status为active或missing；主机上存在但数据库中没有的规则以ID为0、Status为orphaned返回
+func (n *NftablesPortMapping) ListPortMappings(ctx context.Context, instanceID string) ([]*portmapping.PortMappingResult, error) {
+	instance, err := n.getInstance(instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance: %v", err)
+	}
+
+	var ports []provider.Port
+	if err := global.APP_DB.Where("instance_id = ? AND mapping_method = ?", instance.ID, mappingMethod).
+		Find(&ports).Error; err != nil {
+		return nil, fmt.Errorf("failed to list port mappings: %v", err)
+	}
+
+	providerInfo, err := n.getProvider(instance.ProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider: %v", err)
+	}
+
+	live, err := n.listLiveRules(providerInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nftables rules: %v", err)
+	}
+
+	statuses, orphans := reconcileRules(ports, instance.ID, portmapping.CleanIP(instance.PrivateIP), live)
+
+	var results []*portmapping.PortMappingResult
+	for i, port := range ports {
+		result := n.BaseProvider.FromDBModel(&port)
+		result.MappingMethod = mappingMethod
+		result.HostIP = providerInfo.Endpoint
+		result.PublicIP = n.getPublicIP(providerInfo)
+		result.Status = statuses[i]
+		results = append(results, result)
+	}
+
+	// 主机上存在但数据库中没有对应记录的规则
+	for _, r := range orphans {
+		results = append(results, &portmapping.PortMappingResult{
+			InstanceID:    instanceID,
+			ProviderID:    providerInfo.ID,
+			Protocol:      r.Protocol,
+			HostPort:      r.HostPort,
+			GuestPort:     r.GuestPort,
+			HostIP:        providerInfo.Endpoint,
+			PublicIP:      n.getPublicIP(providerInfo),
+			Status:        StatusOrphaned,
+			MappingMethod: mappingMethod,
+		})
+	}
+
+	return results, nil
+}
+
+// reconcileRules 对比实例在数据库中的端口记录与主机上的规则
+// 返回与ports一一对应的状态：生效的记录为active或missing，其他记录保持原状态；以及主机上多出的该实例规则，按key排序
+func reconcileRules(ports []provider.Port, instanceID uint, instanceIP string, live []rule) ([]string, []rule) {
+	liveByKey := make(map[string]rule, len(live))
+	for _, r := range live {
+		if r.InstanceID == instanceID {
+			liveByKey[r.key()] = r
+		}
+	}
+
+	statuses := make([]string, len(ports))
+	for i, port := range ports {
+		statuses[i] = port.Status
+		if port.Status != "active" {
+			continue
+		}
+		// 协议为both时TCP和UDP规则都存在且目标一致才算一致
+		statuses[i] = StatusActive
+		for _, proto := range portmapping.ExpandProtocol(port.Protocol) {
+			key := rule{InstanceID: instanceID, HostPort: port.HostPort, Protocol: proto}.key()
+			liveRule, exists := liveByKey[key]
+			if !exists || liveRule.GuestPort != port.GuestPort || liveRule.InstanceIP != instanceIP {
+				statuses[i] = StatusMissing
+			}
+			delete(liveByKey, key)
+		}
+	}
+
+	orphanKeys := make([]string, 0, len(liveByKey))
+	for key := range liveByKey {
+		orphanKeys = append(orphanKeys, key)
+	}
+	sort.Strings(orphanKeys)
+	orphans := make([]rule, 0, len(orphanKeys))
+	for _, key := range orphanKeys {
+		orphans = append(orphans, liveByKey[key])
+	}
+	return statuses, orphans
+}
+
+// SyncProvider 按数据库中生效的端口记录重新生成并应用Provider的整张nftables表，用于修复规则漂移
+func (n *NftablesPortMapping) SyncProvider(ctx context.Context, providerID uint) error {
+	lock := providerLock(providerID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return n.applyWithout(providerID, 0)
+}
+
+// ListLiveMappings 读取Provider主机上本后端表中的端口映射
+func (n *NftablesPortMapping) ListLiveMappings(ctx context.Context, providerID uint) ([]*portmapping.LiveMapping, error) {
+	providerInfo, err := n.getProvider(providerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider: %v", err)
+	}
+	live, err := n.listLiveRules(providerInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	mappings := make([]*portmapping.LiveMapping, 0, len(live))
+	for _, r := range live {
+		mappings = append(mappings, &portmapping.LiveMapping{
+			InstanceID: r.InstanceID,
+			TargetIP:   r.InstanceIP,
+			HostPort:   r.HostPort,
+			GuestPort:  r.GuestPort,
+			Protocol:   r.Protocol,
+		})
+	}
+	return mappings, nil
+}
+
+// RestoreMappings 整张表按数据库重新生成，缺失的映射随之恢复
+func (n *NftablesPortMapping) RestoreMappings(ctx context.Context, providerID uint, ports []provider.Port) error {
+	return n.SyncProvider(ctx, providerID)
+}
+
+// RemoveLiveMappings 整张表按数据库重新生成，多余的映射随之删除
+func (n *NftablesPortMapping) RemoveLiveMappings(ctx context.Context, providerID uint, mappings []*portmapping.LiveMapping) error {
+	return n.SyncProvider(ctx, providerID)
+}
+
+// applyWithout 按数据库重新生成规则并应用，excludePortID不为0时排除该端口记录，调用方需持有Provider锁
+func (n *NftablesPortMapping) applyWithout(providerID, excludePortID uint) error {
+	providerInfo, err := n.getProvider(providerID)
+	if err != nil {
+		return fmt.Errorf("failed to get provider: %v", err)
+	}
+	rules, err := n.loadRules(providerID, excludePortID)
+	if err != nil {
+		return err
+	}
+	return n.applyRuleset(providerInfo, rules)
+}
+
+// loadRules 从数据库加载Provider下由nftables管理的生效端口映射，excludePortID不为0时排除该端口记录
+func (n *NftablesPortMapping) loadRules(providerID, excludePortID uint) ([]rule, error) {
+	query := global.APP_DB.Where("provider_id = ? AND mapping_method = ? AND status = ?", providerID, mappingMethod, "active")
+	if excludePortID != 0 {
+		query = query.Where("id <> ?", excludePortID)
+	}
+	var ports []provider.Port
+	if err := query.Find(&ports).Error; err != nil {
+		return nil, fmt.Errorf("failed to load port mappings: %v", err)
+	}
+	if len(ports) == 0 {
+		return nil, nil
+	}
+
+	instanceIDs := make([]uint, 0, len(ports))
+	for _, port := range ports {
+		instanceIDs = append(instanceIDs, port.InstanceID)
+	}
+	var instances []provider.Instance
+	if err := global.APP_DB.Where("id IN ?", instanceIDs).Find(&instances).Error; err != nil {
+		return nil, fmt.Errorf("failed to load instances: %v", err)
+	}
+	instanceIPs := make(map[uint]string, len(instances))
+	for _, instance := range instances {
+		instanceIPs[instance.ID] = portmapping.CleanIP(instance.PrivateIP)
+	}
+
+	rules := make([]rule, 0, len(ports))
+	for _, port := range ports {
+		ip := instanceIPs[port.InstanceID]
+		if ip == "" {
+			global.APP_LOG.Warn("Skipping nftables rule for instance without private IP",
+				zap.Uint("portId", port.ID),
+				zap.Uint("instanceId", port.InstanceID))
+			continue
+		}
+		rules = append(rules, rule{
+			PortID:     port.ID,
+			InstanceID: port.InstanceID,
+			InstanceIP: ip,
+			HostPort:   port.HostPort,
+			GuestPort:  port.GuestPort,
+			Protocol:   port.Protocol,
+		})
+	}
+	return rules, nil
+}
+
+// tableName Provider专用的nftables表名
+func tableName(providerID uint) string {
+	return fmt.Sprintf("oneclickvirt_p%d", providerID)
+}
+
+// rulesetPath Provider主机上保存该表规则的文件路径
+func rulesetPath(providerID uint) string {
+	return fmt.Sprintf("%s/%s.nft", rulesetDir, tableName(providerID))
+}
+
+// buildRuleset 生成整张表的nft脚本
+// 脚本先声明再删除同名表，随后重新定义，nft -f 在同一事务中执行，保证规则替换是原子的
+func buildRuleset(providerID uint, rules []rule) string {
+	table := tableName(providerID)
+
+	// 展开both协议并去重，保证输出稳定
+	expanded := make(map[string]rule)
+	for _, r := range rules {
+		// 表的地址族为ip，IPv6目标写入会导致整个脚本被拒绝，IPv6端口映射由Provider的IPv6映射方式处理
+		if ip := net.ParseIP(r.InstanceIP); ip == nil || ip.To4() == nil {
+			continue
+		}
+		for _, proto := range portmapping.ExpandProtocol(r.Protocol) {
+			item := r
+			item.Protocol = proto
+			expanded[item.key()] = item
+		}
+	}
+	keys := make([]string, 0, len(expanded))
+	for key := range expanded {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// 响应流量由conntrack自动反向转换，不需要额外的SNAT规则
+	var prerouting, forward strings.Builder
+	for _, key := range keys {
+		r := expanded[key]
+		comment := fmt.Sprintf(`comment "instance:%d"`, r.InstanceID)
+		// 将外部端口转发到内部实例
+		fmt.Fprintf(&prerouting, "\t\t%s dport %d dnat to %s:%d %s\n", r.Protocol, r.HostPort, r.InstanceIP, r.GuestPort, comment)
+		// 允许转发到实例
+		fmt.Fprintf(&forward, "\t\tip daddr %s %s dport %d accept %s\n", r.InstanceIP, r.Protocol, r.GuestPort, comment)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# 由OneClickVirt生成，请勿手动修改\n")
+	fmt.Fprintf(&sb, "table ip %s\n", table)
+	fmt.Fprintf(&sb, "delete table ip %s\n", table)
+	fmt.Fprintf(&sb, "table ip %s {\n", table)
+	fmt.Fprintf(&sb, "\tchain prerouting {\n\t\ttype nat hook prerouting priority -100; policy accept;\n%s\t}\n", prerouting.String())
+	fmt.Fprintf(&sb, "\tchain forward {\n\t\ttype filter hook forward priority 0; policy accept;\n%s\t}\n", forward.String())
+	fmt.Fprintf(&sb, "}\n")
+	return sb.String()
+}
+
+// applyRuleset 上传规则文件并通过 nft -f 原子替换Provider的表，成功后写入开机加载的配置
+func (n *NftablesPortMapping) applyRuleset(providerInfo *provider.Provider, rules []rule) error {
+	script := buildRuleset(providerInfo.ID, rules)
+	path := rulesetPath(providerInfo.ID)
+	tmpPath := path + ".new"
+
+	global.APP_LOG.Info("Applying nftables ruleset",
+		zap.Uint("providerId", providerInfo.ID),
+		zap.String("table", tableName(providerInfo.ID)),
+		zap.Int("ruleCount", len(rules)))
+
+	sshClient, err := n.createSSHClient(providerInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create SSH client: %v", err)
+	}
+	defer sshClient.Close()
+
+	if err := sshClient.UploadContent(script, tmpPath, 0600); err != nil {
+		return fmt.Errorf("failed to upload nftables ruleset: %v", err)
+	}
+
+	// 应用失败时保留上一次成功的规则文件
+	applyCmd := fmt.Sprintf("nft -f %s && mv -f %s %s", tmpPath, tmpPath, path)
+	if output, err := sshClient.Execute(applyCmd); err != nil {
+		global.APP_LOG.Error("Failed to apply nftables ruleset",
+			zap.Uint("providerId", providerInfo.ID),
+			zap.String("output", output),
+			zap.Error(err))
+		sshClient.Execute(fmt.Sprintf("rm -f %s", tmpPath))
+		return fmt.Errorf("nft -f failed: %v", err)
+	}
+
+	// 开机时随系统nftables配置加载
+	include := fmt.Sprintf(`include "%s"`, path)
+	persistCmd := fmt.Sprintf("[ -f %s ] && (grep -qF '%s' %s || echo '%s' >> %s) || true", nftablesConf, include, nftablesConf, include, nftablesConf)
+	if _, err := sshClient.Execute(persistCmd); err != nil {
+		global.APP_LOG.Warn("Failed to persist nftables ruleset", zap.Error(err))
+	}
+
+	return nil
+}
+
+// listLiveRules 读取Provider主机上本后端表中的DNAT规则，表不存在时返回空列表
+func (n *NftablesPortMapping) listLiveRules(providerInfo *provider.Provider) ([]rule, error) {
+	sshClient, err := n.createSSHClient(providerInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH client: %v", err)
+	}
+	defer sshClient.Close()
+
+	table := tableName(providerInfo.ID)
+	output, err := sshClient.Execute(fmt.Sprintf("nft list table ip %s 2>/dev/null || true", table))
+	if err != nil {
+		return nil, err
+	}
+	return parseLiveRules(output), nil
+}
+
+// parseLiveRules 解析 nft list table 的输出
+func parseLiveRules(output string) []rule {
+	var rules []rule
+	for _, match := range dnatRulePattern.FindAllStringSubmatch(output, -1) {
+		hostPort, _ := strconv.Atoi(match[2])
+		guestPort, _ := strconv.Atoi(match[4])
+		instanceID, _ := strconv.ParseUint(match[5], 10, 32)
+		rules = append(rules, rule{
+			InstanceID: uint(instanceID),
+			InstanceIP: match[3],
+			HostPort:   hostPort,
+			GuestPort:  guestPort,
+			Protocol:   match[1],
+		})
+	}
+	return rules
+}
+
+// validateRequest 验证请求参数
+func (n *NftablesPortMapping) validateRequest(req *portmapping.PortMappingRequest) error {
+	if req.InstanceID == "" {
+		return fmt.Errorf("instance ID is required")
+	}
+	if req.GuestPort <= 0 || req.GuestPort > 65535 {
+		return fmt.Errorf("invalid guest port: %d", req.GuestPort)
+	}
+	if req.HostPort < 0 || req.HostPort > 65535 {
+		return fmt.Errorf("invalid host port: %d", req.HostPort)
+	}
+	if req.Protocol == "" {
+		req.Protocol = "both"
+	}
+	req.Protocol = strings.ToLower(req.Protocol)
+	return portmapping.ValidateProtocol(req.Protocol)
+}
+
+// getInstance 获取实例信息
+func (n *NftablesPortMapping) getInstance(instanceID string) (*provider.Instance, error) {
+	var instance provider.Instance
+	id, err := strconv.ParseUint(instanceID, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid instance ID: %s", instanceID)
+	}
+
+	if err := global.APP_DB.First(&instance, uint(id)).Error; err != nil {
+		return nil, fmt.Errorf("instance not found: %v", err)
+	}
+
+	return &instance, nil
+}
+
+// getProvider 获取Provider信息
+func (n *NftablesPortMapping) getProvider(providerID uint) (*provider.Provider, error) {
+	var providerInfo provider.Provider
+	if err := global.APP_DB.First(&providerInfo, providerID).Error; err != nil {
+		return nil, fmt.Errorf("provider not found: %v", err)
+	}
+	return &providerInfo, nil
+}
+
+// getPublicIP 获取公网IP
+func (n *NftablesPortMapping) getPublicIP(providerInfo *provider.Provider) string {
+	// 优先使用PortIP（端口映射专用IP），如果为空则使用Endpoint（SSH地址）
+	if providerInfo.PortIP != "" {
+		return providerInfo.PortIP
+	}
+	return providerInfo.Endpoint
+}
+
+// createSSHClient 创建SSH客户端连接到provider主机
+func (n *NftablesPortMapping) createSSHClient(providerInfo *provider.Provider) (*utils.SSHClient, error) {
+	host, port := n.parseEndpoint(providerInfo.Endpoint)
+
+	sshConfig := utils.SSHConfig{
+		Host:           host,
+		Port:           port,
+		Username:       providerInfo.Username,
+		Password:       providerInfo.Password,
+		PrivateKey:     providerInfo.SSHKey,
+		ConnectTimeout: 10 * time.Second,
+		ExecuteTimeout: 60 * time.Second,
+	}
+
+	return utils.NewSSHClient(sshConfig)
+}
+
+// parseEndpoint 解析endpoint获取host和port
+func (n *NftablesPortMapping) parseEndpoint(endpoint string) (host string, port int) {
+	// 默认SSH端口
+	port = 22
+	host = endpoint
+
+	// 如果endpoint包含端口，解析它
+	if strings.Contains(endpoint, ":") {
+		parts := strings.Split(endpoint, ":")
+		if len(parts) == 2 {
+			host = parts[0]
+			if p, err := strconv.Atoi(parts[1]); err == nil {
+				port = p
+			}
+		}
+	}
+
+	return host, port
+}
+
+// init 注册nftables端口映射Provider
+func init() {
+	portmapping.RegisterProvider("nftables", func(config *portmapping.ManagerConfig) portmapping.PortMappingProvider {
+		return NewNftablesPortMapping(config)
+	})
+}
@@ -0,0 +1,269 @@
+package nftables
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"oneclickvirt/model/provider"
+)
+
+func TestBuildRuleset(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     []rule
+		want      []string
+		notWant   []string
+		dnatCount int
+	}{
+		{
+			name:      "空表",
+			rules:     nil,
+			dnatCount: 0,
+		},
+		{
+			name:  "TCP",
+			rules: []rule{{InstanceID: 7, InstanceIP: "10.0.0.2", HostPort: 10022, GuestPort: 22, Protocol: "tcp"}},
+			want: []string{
+				`tcp dport 10022 dnat to 10.0.0.2:22 comment "instance:7"`,
+				`ip daddr 10.0.0.2 tcp dport 22 accept comment "instance:7"`,
+			},
+			notWant:   []string{"udp dport"},
+			dnatCount: 1,
+		},
+		{
+			name:  "UDP",
+			rules: []rule{{InstanceID: 7, InstanceIP: "10.0.0.2", HostPort: 10053, GuestPort: 53, Protocol: "udp"}},
+			want: []string{
+				`udp dport 10053 dnat to 10.0.0.2:53 comment "instance:7"`,
+				`ip daddr 10.0.0.2 udp dport 53 accept comment "instance:7"`,
+			},
+			notWant:   []string{"tcp dport"},
+			dnatCount: 1,
+		},
+		{
+			name:  "both展开为TCP和UDP",
+			rules: []rule{{InstanceID: 7, InstanceIP: "10.0.0.2", HostPort: 10022, GuestPort: 22, Protocol: "both"}},
+			want: []string{
+				`tcp dport 10022 dnat to 10.0.0.2:22 comment "instance:7"`,
+				`udp dport 10022 dnat to 10.0.0.2:22 comment "instance:7"`,
+			},
+			dnatCount: 2,
+		},
+		{
+			name: "连续端口区间逐条生成",
+			rules: []rule{
+				{InstanceID: 8, InstanceIP: "10.0.0.3", HostPort: 20001, GuestPort: 20001, Protocol: "tcp"},
+				{InstanceID: 8, InstanceIP: "10.0.0.3", HostPort: 20002, GuestPort: 20002, Protocol: "tcp"},
+				{InstanceID: 8, InstanceIP: "10.0.0.3", HostPort: 20003, GuestPort: 20003, Protocol: "tcp"},
+			},
+			want: []string{
+				`tcp dport 20001 dnat to 10.0.0.3:20001`,
+				`tcp dport 20002 dnat to 10.0.0.3:20002`,
+				`tcp dport 20003 dnat to 10.0.0.3:20003`,
+			},
+			dnatCount: 3,
+		},
+		{
+			name: "IPv6目标被跳过",
+			rules: []rule{
+				{InstanceID: 9, InstanceIP: "fd00::2", HostPort: 30022, GuestPort: 22, Protocol: "tcp"},
+				{InstanceID: 10, InstanceIP: "10.0.0.4", HostPort: 30023, GuestPort: 22, Protocol: "tcp"},
+			},
+			want:      []string{`tcp dport 30023 dnat to 10.0.0.4:22 comment "instance:10"`},
+			notWant:   []string{"fd00::2", `"instance:9"`},
+			dnatCount: 1,
+		},
+		{
+			name: "重复映射去重",
+			rules: []rule{
+				{InstanceID: 7, InstanceIP: "10.0.0.2", HostPort: 10022, GuestPort: 22, Protocol: "tcp"},
+				{InstanceID: 7, InstanceIP: "10.0.0.2", HostPort: 10022, GuestPort: 22, Protocol: "both"},
+			},
+			dnatCount: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := buildRuleset(3, tt.rules)
+
+			// 先声明再删除，表不存在时也能整表替换
+			header := "table ip oneclickvirt_p3\ndelete table ip oneclickvirt_p3\ntable ip oneclickvirt_p3 {\n"
+			if !strings.Contains(script, header) {
+				t.Fatalf("缺少整表替换的声明:\n%s", script)
+			}
+			for _, chain := range []string{"chain prerouting", "chain forward"} {
+				if !strings.Contains(script, chain) {
+					t.Errorf("缺少%s:\n%s", chain, script)
+				}
+			}
+			if strings.Contains(script, "masquerade") {
+				t.Errorf("不应生成SNAT规则:\n%s", script)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(script, want) {
+					t.Errorf("缺少规则 %q:\n%s", want, script)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(script, notWant) {
+					t.Errorf("不应包含 %q:\n%s", notWant, script)
+				}
+			}
+			if got := strings.Count(script, " dnat to "); got != tt.dnatCount {
+				t.Errorf("DNAT规则数量 = %d, want %d", got, tt.dnatCount)
+			}
+		})
+	}
+}
+
+func TestBuildRulesetStableOrder(t *testing.T) {
+	rules := []rule{
+		{InstanceID: 2, InstanceIP: "10.0.0.3", HostPort: 10080, GuestPort: 80, Protocol: "tcp"},
+		{InstanceID: 1, InstanceIP: "10.0.0.2", HostPort: 10022, GuestPort: 22, Protocol: "both"},
+	}
+	reversed := []rule{rules[1], rules[0]}
+	if buildRuleset(1, rules) != buildRuleset(1, reversed) {
+		t.Error("规则顺序不同时生成的脚本应一致")
+	}
+}
+
+func TestParseLiveRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []rule
+	}{
+		{
+			name:   "表不存在",
+			output: "",
+			want:   nil,
+		},
+		{
+			name: "nft list table输出",
+			output: `table ip oneclickvirt_p3 {
+	chain prerouting {
+		type nat hook prerouting priority dstnat; policy accept;
+		tcp dport 10022 dnat to 10.0.0.2:22 comment "instance:7"
+		udp dport 10022 dnat to 10.0.0.2:22 comment "instance:7"
+		tcp dport 10080 dnat ip to 10.0.0.3:80 comment "instance:8"
+		tcp dport 10443 dnat to 10.0.0.9:443
+	}
+	chain forward {
+		type filter hook forward priority filter; policy accept;
+		ip daddr 10.0.0.2 tcp dport 22 accept comment "instance:7"
+	}
+}`,
+			want: []rule{
+				{InstanceID: 7, InstanceIP: "10.0.0.2", HostPort: 10022, GuestPort: 22, Protocol: "tcp"},
+				{InstanceID: 7, InstanceIP: "10.0.0.2", HostPort: 10022, GuestPort: 22, Protocol: "udp"},
+				{InstanceID: 8, InstanceIP: "10.0.0.3", HostPort: 10080, GuestPort: 80, Protocol: "tcp"},
+			},
+		},
+		{
+			name:   "IPv6目标不属于本后端",
+			output: `tcp dport 30022 dnat to [fd00::2]:22 comment "instance:9"`,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseLiveRules(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLiveRules() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRulesetRoundTrip(t *testing.T) {
+	rules := []rule{
+		{PortID: 1, InstanceID: 7, InstanceIP: "10.0.0.2", HostPort: 10022, GuestPort: 22, Protocol: "both"},
+		{PortID: 2, InstanceID: 8, InstanceIP: "10.0.0.3", HostPort: 10080, GuestPort: 80, Protocol: "tcp"},
+	}
+	want := []rule{
+		{InstanceID: 7, InstanceIP: "10.0.0.2", HostPort: 10022, GuestPort: 22, Protocol: "tcp"},
+		{InstanceID: 7, InstanceIP: "10.0.0.2", HostPort: 10022, GuestPort: 22, Protocol: "udp"},
+		{InstanceID: 8, InstanceIP: "10.0.0.3", HostPort: 10080, GuestPort: 80, Protocol: "tcp"},
+	}
+	if got := parseLiveRules(buildRuleset(1, rules)); !reflect.DeepEqual(got, want) {
+		t.Errorf("解析生成的脚本 = %+v, want %+v", got, want)
+	}
+}
+
+func TestReconcileRules(t *testing.T) {
+	const instanceID, instanceIP = 7, "10.0.0.2"
+	tcp22 := rule{InstanceID: instanceID, InstanceIP: instanceIP, HostPort: 10022, GuestPort: 22, Protocol: "tcp"}
+	udp22 := rule{InstanceID: instanceID, InstanceIP: instanceIP, HostPort: 10022, GuestPort: 22, Protocol: "udp"}
+	sshPort := provider.Port{HostPort: 10022, GuestPort: 22, Protocol: "both", Status: "active"}
+
+	tests := []struct {
+		name         string
+		ports        []provider.Port
+		live         []rule
+		wantStatuses []string
+		wantOrphans  []rule
+	}{
+		{
+			name:         "规则一致",
+			ports:        []provider.Port{sshPort},
+			live:         []rule{tcp22, udp22},
+			wantStatuses: []string{StatusActive},
+		},
+		{
+			name:         "从空表重建前全部缺失",
+			ports:        []provider.Port{sshPort, {HostPort: 10080, GuestPort: 80, Protocol: "tcp", Status: "active"}},
+			live:         nil,
+			wantStatuses: []string{StatusMissing, StatusMissing},
+		},
+		{
+			name:         "both只存在TCP规则",
+			ports:        []provider.Port{sshPort},
+			live:         []rule{tcp22},
+			wantStatuses: []string{StatusMissing},
+		},
+		{
+			name:         "内部端口不一致",
+			ports:        []provider.Port{{HostPort: 10022, GuestPort: 2222, Protocol: "tcp", Status: "active"}},
+			live:         []rule{tcp22},
+			wantStatuses: []string{StatusMissing},
+		},
+		{
+			name:         "实例IP已变化",
+			ports:        []provider.Port{{HostPort: 10022, GuestPort: 22, Protocol: "tcp", Status: "active"}},
+			live:         []rule{{InstanceID: instanceID, InstanceIP: "10.0.0.99", HostPort: 10022, GuestPort: 22, Protocol: "tcp"}},
+			wantStatuses: []string{StatusMissing},
+		},
+		{
+			name:         "未生效的记录保持原状态",
+			ports:        []provider.Port{{HostPort: 10022, GuestPort: 22, Protocol: "tcp", Status: "inactive"}},
+			live:         []rule{tcp22},
+			wantStatuses: []string{"inactive"},
+			wantOrphans:  []rule{tcp22},
+		},
+		{
+			name:  "主机上多出的规则",
+			ports: []provider.Port{{HostPort: 10022, GuestPort: 22, Protocol: "tcp", Status: "active"}},
+			live: []rule{
+				tcp22,
+				udp22,
+				{InstanceID: 8, InstanceIP: "10.0.0.3", HostPort: 10080, GuestPort: 80, Protocol: "tcp"},
+			},
+			wantStatuses: []string{StatusActive},
+			wantOrphans:  []rule{udp22},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses, orphans := reconcileRules(tt.ports, instanceID, instanceIP, tt.live)
+			if !reflect.DeepEqual(statuses, tt.wantStatuses) {
+				t.Errorf("statuses = %v, want %v", statuses, tt.wantStatuses)
+			}
+			if len(orphans) != len(tt.wantOrphans) || (len(orphans) > 0 && !reflect.DeepEqual(orphans, tt.wantOrphans)) {
+				t.Errorf("orphans = %+v, want %+v", orphans, tt.wantOrphans)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package portmapping
+
+import (
+	"context"
+	"fmt"
+	"oneclickvirt/global"
+	"oneclickvirt/model/provider"
+	"oneclickvirt/utils"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LiveMapping 主机上实际生效的单协议IPv4端口映射
+type LiveMapping struct {
+	InstanceID   uint   `json:"instanceId"`   // 所属实例ID，后端无法确定时为0
+	InstanceName string `json:"instanceName"` // 所属实例名称，后端无法确定时为空
+	TargetIP     string `json:"targetIp"`     // 转发目标IP，0.0.0.0表示转发到所属实例自身
+	HostPort     int    `json:"hostPort"`     // 宿主机端口
+	GuestPort    int    `json:"guestPort"`    // 实例内部端口
+	Protocol     string `json:"protocol"`     // 协议：tcp, udp
+	Ref          string `json:"ref"`          // 后端内部标识（设备名、规则内容），删除时使用
+}
+
+// Reconcilable 支持漂移检测与修复的端口映射Provider
+type Reconcilable interface {
+	// ListLiveMappings 读取Provider主机上实际生效的IPv4端口映射
+	ListLiveMappings(ctx context.Context, providerID uint) ([]*LiveMapping, error)
+
+	// RestoreMappings 在主机上补建数据库中存在但缺失或被改动的端口映射
+	RestoreMappings(ctx context.Context, providerID uint, ports []provider.Port) error
+
+	// RemoveLiveMappings 删除主机上多余或与数据库冲突的端口映射
+	RemoveLiveMappings(ctx context.Context, providerID uint, mappings []*LiveMapping) error
+}
+
+// NewProviderSSHClient 使用Provider的连接信息创建SSH客户端
+func NewProviderSSHClient(providerInfo *provider.Provider) (*utils.SSHClient, error) {
+	host := providerInfo.Endpoint
+	port := 22
+	if parts := strings.Split(providerInfo.Endpoint, ":"); len(parts) == 2 {
+		host = parts[0]
+		if p, err := strconv.Atoi(parts[1]); err == nil {
+			port = p
+		}
+	}
+
+	return utils.NewSSHClient(utils.SSHConfig{
+		Host:           host,
+		Port:           port,
+		Username:       providerInfo.Username,
+		Password:       providerInfo.Password,
+		PrivateKey:     providerInfo.SSHKey,
+		ConnectTimeout: 10 * time.Second,
+		ExecuteTimeout: 60 * time.Second,
+	})
+}
+
+// ExpandProtocol 将both展开为tcp和udp
+func ExpandProtocol(protocol string) []string {
+	protocol = strings.ToLower(protocol)
+	if protocol == "both" || protocol == "" {
+		return []string{"tcp", "udp"}
+	}
+	return []string{protocol}
+}
+
+// CleanIP 去除实例内网IP中的前缀长度、接口名称等多余内容
+func CleanIP(ip string) string {
+	ip = strings.TrimSpace(ip)
+	if idx := strings.IndexAny(ip, "/ ("); idx != -1 {
+		ip = ip[:idx]
+	}
+	return ip
+}
+
+// proxyDeviceEndpointPattern 匹配proxy设备的listen/connect地址，如 tcp:0.0.0.0:10000-10010
+var proxyDeviceEndpointPattern = regexp.MustCompile(`^(tcp|udp):([0-9.]+):(\d+)(?:-(\d+))?$`)
+
+// proxyDeviceListCommand 输出所有实例proxy设备配置的命令，cli为lxc或incus
+func proxyDeviceListCommand(cli string) string {
+	return fmt.Sprintf(`for c in $(%s list -c n --format csv); do echo "=== $c"; %s config device show "$c"; done`, cli, cli)
+}
+
+// ListProxyDevices 读取LXD/Incus主机上所有实例的IPv4 proxy设备，区间映射展开为逐个端口
+func ListProxyDevices(sshClient *utils.SSHClient, cli string) ([]*LiveMapping, error) {
+	output, err := sshClient.Execute(proxyDeviceListCommand(cli))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list proxy devices: %v", err)
+	}
+	return ParseProxyDevices(output), nil
+}
+
+// ParseProxyDevices 解析 config device show 的输出，实例之间以 "=== 实例名" 分隔
+func ParseProxyDevices(output string) []*LiveMapping {
+	var mappings []*LiveMapping
+	var instanceName, deviceName string
+	device := map[string]string{}
+
+	flush := func() {
+		if deviceName != "" && device["type"] == "proxy" {
+			mappings = append(mappings, expandProxyDevice(instanceName, deviceName, device["listen"], device["connect"])...)
+		}
+		deviceName = ""
+		device = map[string]string{}
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "=== "):
+			flush()
+			instanceName = strings.TrimSpace(strings.TrimPrefix(line, "=== "))
+		case trimmed == "" || trimmed == "{}":
+			continue
+		case !strings.HasPrefix(line, " ") && strings.HasSuffix(trimmed, ":"):
+			// 顶层键为设备名
+			flush()
+			deviceName = strings.TrimSuffix(trimmed, ":")
+		case deviceName != "":
+			if key, value, ok := strings.Cut(trimmed, ":"); ok {
+				device[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+			}
+		}
+	}
+	flush()
+	return mappings
+}
+
+// expandProxyDevice 将单个proxy设备展开为逐端口映射，非IPv4或格式无法识别的设备会被忽略
+func expandProxyDevice(instanceName, deviceName, listen, connect string) []*LiveMapping {
+	l := proxyDeviceEndpointPattern.FindStringSubmatch(listen)
+	c := proxyDeviceEndpointPattern.FindStringSubmatch(connect)
+	if l == nil || c == nil || l[1] != c[1] {
+		return nil
+	}
+
+	listenStart, _ := strconv.Atoi(l[3])
+	listenEnd := listenStart
+	if l[4] != "" {
+		listenEnd, _ = strconv.Atoi(l[4])
+	}
+	connectStart, _ := strconv.Atoi(c[3])
+
+	var mappings []*LiveMapping
+	for port := listenStart; port <= listenEnd; port++ {
+		mappings = append(mappings, &LiveMapping{
+			InstanceName: instanceName,
+			TargetIP:     c[2],
+			HostPort:     port,
+			GuestPort:    connectStart + (port - listenStart),
+			Protocol:     l[1],
+			Ref:          deviceName,
+		})
+	}
+	return mappings
+}
+
+// RestoreProxyDevices 为缺失的端口映射创建proxy设备，设备命名与实例创建时一致
+func RestoreProxyDevices(sshClient *utils.SSHClient, cli string, ports []provider.Port) error {
+	hostIP, err := sshClient.Execute("ip addr show | awk '/inet .*global/ && !/inet6/ {print $2}' | sed -n '1p' | cut -d/ -f1")
+	if err != nil {
+		return fmt.Errorf("failed to get host IP: %v", err)
+	}
+	hostIP = strings.TrimSpace(hostIP)
+	if hostIP == "" {
+		return fmt.Errorf("host IP is empty")
+	}
+
+	instanceNames, instanceIPs, err := loadInstanceTargets(ports)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, port := range ports {
+		name, ip := instanceNames[port.InstanceID], instanceIPs[port.InstanceID]
+		if name == "" || ip == "" {
+			failed = append(failed, fmt.Sprintf("%d(实例信息缺失)", port.HostPort))
+			continue
+		}
+		for _, proto := range ExpandProtocol(port.Protocol) {
+			deviceName := fmt.Sprintf("proxy-%s-%d", proto, port.HostPort)
+			// 先移除同名的残留设备，再按数据库重新创建
+			cmd := fmt.Sprintf("%s config device remove %s %s >/dev/null 2>&1; %s config device add %s %s proxy listen=%s:%s:%d connect=%s:%s:%d nat=true",
+				cli, name, deviceName, cli, name, deviceName, proto, hostIP, port.HostPort, proto, ip, port.GuestPort)
+			if _, err := sshClient.Execute(cmd); err != nil {
+				failed = append(failed, fmt.Sprintf("%s/%d(%v)", proto, port.HostPort, err))
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to restore proxy devices: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// RemoveProxyDevices 删除proxy设备，同一设备展开的多个端口只删除一次
+func RemoveProxyDevices(sshClient *utils.SSHClient, cli string, mappings []*LiveMapping) error {
+	removed := make(map[string]bool)
+	var failed []string
+	for _, m := range mappings {
+		key := m.InstanceName + "/" + m.Ref
+		if m.InstanceName == "" || m.Ref == "" || removed[key] {
+			continue
+		}
+		removed[key] = true
+		if _, err := sshClient.Execute(fmt.Sprintf("%s config device remove %s %s", cli, m.InstanceName, m.Ref)); err != nil {
+			failed = append(failed, fmt.Sprintf("%s(%v)", key, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to remove proxy devices: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// loadInstanceTargets 加载端口记录所属实例的名称和内网IP
+func loadInstanceTargets(ports []provider.Port) (map[uint]string, map[uint]string, error) {
+	instanceIDs := make([]uint, 0, len(ports))
+	for _, port := range ports {
+		instanceIDs = append(instanceIDs, port.InstanceID)
+	}
+	var instances []provider.Instance
+	if err := global.APP_DB.Where("id IN ?", instanceIDs).Find(&instances).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load instances: %v", err)
+	}
+
+	names := make(map[uint]string, len(instances))
+	ips := make(map[uint]string, len(instances))
+	for _, instance := range instances {
+		names[instance.ID] = instance.Name
+		ips[instance.ID] = CleanIP(instance.PrivateIP)
+	}
+	return names, ips, nil
+}
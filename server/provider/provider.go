@@ -7,6 +7,7 @@ import (
 
 	"oneclickvirt/model/provider"
 	"oneclickvirt/provider/health"
+	"oneclickvirt/utils"
 )
 
 // 类型别名，使用model包中的结构体
@@ -14,6 +15,8 @@ type Instance = provider.ProviderInstance
 type Image = provider.ProviderImage
 type InstanceConfig = provider.ProviderInstanceConfig
 type NodeConfig = provider.ProviderNodeConfig
+type Snapshot = provider.ProviderSnapshot
+type FirewallRule = provider.ProviderFirewallRule
 
 // ProgressCallback 进度回调函数类型
 type ProgressCallback func(percentage int, message string)
@@ -34,6 +37,7 @@ type Provider interface {
 	RestartInstance(ctx context.Context, id string) error
 	DeleteInstance(ctx context.Context, id string) error
 	GetInstance(ctx context.Context, id string) (*Instance, error)
+	ResizeInstance(ctx context.Context, id string, spec ResizeSpec) error // 在线调整实例规格
 
 	// 镜像管理
 	ListImages(ctx context.Context) ([]Image, error)
@@ -52,6 +56,26 @@ type Provider interface {
 	// 密码管理
 	SetInstancePassword(ctx context.Context, instanceID, password string) error
 	ResetInstancePassword(ctx context.Context, instanceID string) (string, error)
+	SetInstanceSSHKeys(ctx context.Context, instanceID string, publicKeys []string, disablePassword bool) error // 写入root用户公钥，可选禁用密码登录
+
+	// 防火墙管理，按规则整体覆盖实例的入站过滤，rules为空时移除过滤
+	SetInstanceFirewall(ctx context.Context, instanceID string, rules []FirewallRule) error
+
+	// 快照管理
+	CreateSnapshot(ctx context.Context, instanceID, snapshotName string) error
+	ListSnapshots(ctx context.Context, instanceID string) ([]Snapshot, error)
+	RestoreSnapshot(ctx context.Context, instanceID, snapshotName string) error
+	DeleteSnapshot(ctx context.Context, instanceID, snapshotName string) error
+
+	// 备份管理，备份文件通过SSH/SFTP在Provider节点与本地备份存储之间传输
+	ExportInstance(ctx context.Context, instanceID, localDir string) (string, error)
+	ImportInstance(ctx context.Context, localPath string, config InstanceConfig) error
+
+	// Web终端，通过节点SSH连接进入实例的交互式shell
+	OpenTerminal(ctx context.Context, instanceID string, cols, rows int) (*utils.SSHTerminal, error)
+
+	// 图形控制台，返回VNC/SPICE上游数据流
+	OpenConsole(ctx context.Context, instanceID string) (*ConsoleStream, error)
 
 	// SSH命令执行
 	ExecuteSSHCommand(ctx context.Context, command string) (string, error)
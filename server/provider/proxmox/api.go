@@ -163,6 +163,10 @@ func (p *ProxmoxProvider) apiCreateInstanceWithProgress(ctx context.Context, con
 		if err := p.apiCreateVM(ctx, vmid, config, updateProgress); err != nil {
 			return fmt.Errorf("API创建虚拟机失败: %w", err)
 		}
+		// 下发用户数据，需在首次启动前完成（snippet文件只能通过SSH写入）
+		if err := p.configureVMUserData(ctx, vmid, config); err != nil {
+			return fmt.Errorf("配置用户数据失败: %w", err)
+		}
 	}
 
 	updateProgress(90, "配置网络和启动...")
@@ -189,6 +193,14 @@ func (p *ProxmoxProvider) apiCreateInstanceWithProgress(ctx context.Context, con
 		global.APP_LOG.Warn("配置SSH密码失败", zap.Error(err))
 	}
 
+	// 容器没有cloud-init，启动后直接执行用户数据脚本
+	if config.InstanceType == "container" && config.UserData != "" {
+		updateProgress(93, "执行用户数据脚本...")
+		if err := p.runContainerUserData(ctx, vmid, config); err != nil {
+			global.APP_LOG.Warn("执行用户数据脚本失败", zap.Int("vmid", vmid), zap.Error(err))
+		}
+	}
+
 	// 初始化vnstat流量监控
 	updateProgress(95, "初始化vnstat流量监控...")
 	if err := p.initializeVnStatMonitoring(ctx, vmid, config.Name); err != nil {
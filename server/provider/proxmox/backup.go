@@ -0,0 +1,169 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"oneclickvirt/global"
+	providerModel "oneclickvirt/model/provider"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// 备份使用 vzdump 生成，恢复时分配新的VMID并重新配置名称、网络和端口映射
+// 备份文件需要通过SFTP在节点和本地备份存储之间传输，因此只支持SSH方式
+
+// ExportInstance 导出实例备份到本地目录，返回备份文件名
+func (p *ProxmoxProvider) ExportInstance(ctx context.Context, instanceID, localDir string) (string, error) {
+	if !p.connected {
+		return "", fmt.Errorf("not connected")
+	}
+
+	vmid, instanceType, err := p.findVMIDByNameOrID(ctx, instanceID)
+	if err != nil {
+		return "", fmt.Errorf("无法找到实例 %s 对应的VMID: %w", instanceID, err)
+	}
+
+	workDir := provider.BackupRemoteWorkDir(vmid)
+	if _, err := p.sshClient.Execute(fmt.Sprintf("mkdir -p %s", workDir)); err != nil {
+		return "", fmt.Errorf("创建远程备份目录失败: %w", err)
+	}
+	defer p.cleanupBackupWorkDir(workDir)
+
+	global.APP_LOG.Info("开始导出Proxmox实例",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.String("vmid", vmid),
+		zap.String("type", instanceType))
+
+	// snapshot模式可在实例运行时备份，不影响业务
+	dumpCmd := fmt.Sprintf("vzdump %s --dumpdir %s --mode snapshot --compress zstd", vmid, workDir)
+	output, err := p.sshClient.ExecuteWithTimeout(dumpCmd, provider.BackupCommandTimeout)
+	if err != nil {
+		global.APP_LOG.Error("Proxmox实例导出失败",
+			zap.String("vmid", vmid),
+			zap.String("output", utils.TruncateString(output, 500)),
+			zap.Error(err))
+		return "", fmt.Errorf("导出实例失败: %w", err)
+	}
+
+	// vzdump 自动生成文件名，格式: vzdump-{qemu|lxc}-<vmid>-<时间>.{vma|tar}.zst
+	listCmd := fmt.Sprintf("ls -1 %s | grep -E '^vzdump-(qemu|lxc)-.*\\.(vma|tar)\\.zst$' | head -n 1", workDir)
+	fileOutput, err := p.sshClient.Execute(listCmd)
+	fileName := strings.TrimSpace(fileOutput)
+	if err != nil || fileName == "" {
+		return "", fmt.Errorf("未找到vzdump生成的备份文件")
+	}
+
+	size, err := p.sshClient.DownloadFile(fmt.Sprintf("%s/%s", workDir, fileName), filepath.Join(localDir, fileName))
+	if err != nil {
+		return "", fmt.Errorf("下载备份文件失败: %w", err)
+	}
+
+	global.APP_LOG.Info("Proxmox实例导出成功",
+		zap.String("vmid", vmid),
+		zap.String("file", fileName),
+		zap.Int64("size", size))
+	return fileName, nil
+}
+
+// ImportInstance 将本地备份文件恢复为新实例
+func (p *ProxmoxProvider) ImportInstance(ctx context.Context, localPath string, config provider.InstanceConfig) error {
+	if !p.connected {
+		return fmt.Errorf("not connected")
+	}
+
+	// 实例类型以备份文件为准
+	fileName := filepath.Base(localPath)
+	config.InstanceType = "container"
+	if strings.HasPrefix(fileName, "vzdump-qemu-") {
+		config.InstanceType = "vm"
+	}
+
+	vmid, err := p.getNextVMID(ctx, config.InstanceType)
+	if err != nil {
+		return fmt.Errorf("获取VMID失败: %w", err)
+	}
+
+	workDir := provider.BackupRemoteWorkDir(config.Name)
+	remotePath := fmt.Sprintf("%s/%s", workDir, fileName)
+	defer p.cleanupBackupWorkDir(workDir)
+
+	global.APP_LOG.Info("开始导入Proxmox实例备份",
+		zap.String("name", config.Name),
+		zap.Int("vmid", vmid),
+		zap.String("type", config.InstanceType))
+
+	if err := p.sshClient.UploadFile(localPath, remotePath); err != nil {
+		return fmt.Errorf("上传备份文件失败: %w", err)
+	}
+
+	// 使用与创建实例相同的存储盘
+	var providerRecord providerModel.Provider
+	if err := global.APP_DB.Where("name = ?", p.config.Name).First(&providerRecord).Error; err != nil {
+		global.APP_LOG.Warn("获取Provider记录失败，使用默认存储", zap.Error(err))
+	}
+	storage := providerRecord.StoragePool
+	if storage == "" {
+		storage = "local" // 默认存储
+	}
+
+	// --unique 重新生成MAC地址，避免与原实例冲突
+	var restoreCmd, renameCmd string
+	if config.InstanceType == "vm" {
+		restoreCmd = fmt.Sprintf("qmrestore %s %d --storage %s --unique 1", remotePath, vmid, storage)
+		renameCmd = fmt.Sprintf("qm set %d --name %s", vmid, config.Name)
+	} else {
+		restoreCmd = fmt.Sprintf("pct restore %d %s --storage %s --unique 1", vmid, remotePath, storage)
+		renameCmd = fmt.Sprintf("pct set %d --hostname %s", vmid, config.Name)
+	}
+
+	output, err := p.sshClient.ExecuteWithTimeout(restoreCmd, provider.BackupCommandTimeout)
+	if err != nil {
+		global.APP_LOG.Error("Proxmox实例恢复失败",
+			zap.String("name", config.Name),
+			zap.Int("vmid", vmid),
+			zap.String("output", utils.TruncateString(output, 500)),
+			zap.Error(err))
+		return fmt.Errorf("恢复实例失败: %w", err)
+	}
+
+	if _, err := p.sshClient.Execute(renameCmd); err != nil {
+		return fmt.Errorf("设置实例名称失败: %w", err)
+	}
+
+	// 内网IP由VMID决定，需要按新VMID重新配置网络
+	if err := p.configureInstanceNetwork(ctx, vmid, config); err != nil {
+		global.APP_LOG.Warn("网络配置失败", zap.Int("vmid", vmid), zap.Error(err))
+	}
+
+	if err := p.sshStartInstance(ctx, fmt.Sprintf("%d", vmid)); err != nil {
+		return fmt.Errorf("启动实例失败: %w", err)
+	}
+
+	if err := p.configureInstancePortMappings(ctx, config, vmid); err != nil {
+		global.APP_LOG.Warn("配置端口映射失败", zap.Error(err))
+	}
+
+	if err := p.initializeVnStatMonitoring(ctx, vmid, config.Name); err != nil {
+		global.APP_LOG.Warn("初始化vnstat监控失败",
+			zap.Int("vmid", vmid),
+			zap.String("name", config.Name),
+			zap.Error(err))
+	}
+
+	global.APP_LOG.Info("Proxmox实例备份导入成功",
+		zap.String("name", config.Name),
+		zap.Int("vmid", vmid))
+	return nil
+}
+
+// cleanupBackupWorkDir 清理节点上的备份中转目录
+func (p *ProxmoxProvider) cleanupBackupWorkDir(workDir string) {
+	if _, err := p.sshClient.Execute(fmt.Sprintf("rm -rf %s", workDir)); err != nil {
+		global.APP_LOG.Warn("清理远程备份目录失败", zap.String("dir", workDir), zap.Error(err))
+	}
+}
@@ -0,0 +1,238 @@
+package proxmox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// firewallRuleComment 由系统下发的防火墙规则备注，同步时只替换带此备注的规则，保留管理员手动添加的规则
+const firewallRuleComment = "oneclickvirt-sg"
+
+// firewallCaller 调用Proxmox API，返回响应中data字段的原始JSON
+type firewallCaller func(ctx context.Context, method, path string, params url.Values) ([]byte, error)
+
+// SetInstanceFirewall 通过Proxmox防火墙覆盖实例的入站规则，rules为空时移除系统下发的规则并恢复放行
+// 实例级防火墙只有在数据中心防火墙启用时才会生效，未启用时直接报错，避免误以为已生效
+func (p *ProxmoxProvider) SetInstanceFirewall(ctx context.Context, instanceID string, rules []provider.FirewallRule) error {
+	if !p.connected {
+		return fmt.Errorf("not connected")
+	}
+
+	// 尝试 API 调用
+	if p.hasAPIAccess() {
+		err := p.applyFirewall(ctx, p.apiFirewallCall, instanceID, rules)
+		if err == nil {
+			global.APP_LOG.Info("Proxmox API调用成功 - 配置实例防火墙", zap.String("id", utils.TruncateString(instanceID, 50)), zap.Int("ruleCount", len(rules)))
+			return nil
+		}
+		global.APP_LOG.Warn("Proxmox API失败，回退到SSH - 配置实例防火墙", zap.String("id", utils.TruncateString(instanceID, 50)), zap.Error(err))
+	}
+
+	// SSH 方式，pvesh 调用的是同一套API
+	if err := p.applyFirewall(ctx, p.sshFirewallCall, instanceID, rules); err != nil {
+		return err
+	}
+	global.APP_LOG.Info("Proxmox实例防火墙配置成功", zap.String("id", utils.TruncateString(instanceID, 50)), zap.Int("ruleCount", len(rules)))
+	return nil
+}
+
+// applyFirewall 替换实例上系统下发的规则，并设置入站默认策略和网卡防火墙开关
+func (p *ProxmoxProvider) applyFirewall(ctx context.Context, call firewallCaller, instanceID string, rules []provider.FirewallRule) error {
+	vmid, instanceType, err := p.findVMIDByNameOrID(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to find instance %s: %w", instanceID, err)
+	}
+	apiType := "qemu"
+	if instanceType == "container" {
+		apiType = "lxc"
+	}
+	base := fmt.Sprintf("/nodes/%s/%s/%s", p.node, apiType, vmid)
+
+	if len(rules) > 0 {
+		data, err := call(ctx, http.MethodGet, "/cluster/firewall/options", nil)
+		if err != nil {
+			return fmt.Errorf("获取数据中心防火墙状态失败: %w", err)
+		}
+		var options struct {
+			Enable int `json:"enable"`
+		}
+		if err := json.Unmarshal(data, &options); err != nil || options.Enable != 1 {
+			return fmt.Errorf("Proxmox数据中心防火墙未启用，实例防火墙规则不会生效")
+		}
+	}
+
+	// 删除旧规则，从后往前删除避免位置变化
+	data, err := call(ctx, http.MethodGet, base+"/firewall/rules", nil)
+	if err != nil {
+		return fmt.Errorf("获取实例防火墙规则失败: %w", err)
+	}
+	var existing []struct {
+		Pos     int    `json:"pos"`
+		Comment string `json:"comment"`
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &existing); err != nil {
+			return fmt.Errorf("解析实例防火墙规则失败: %w", err)
+		}
+	}
+	for i := len(existing) - 1; i >= 0; i-- {
+		if existing[i].Comment != firewallRuleComment {
+			continue
+		}
+		if _, err := call(ctx, http.MethodDelete, fmt.Sprintf("%s/firewall/rules/%d", base, existing[i].Pos), nil); err != nil {
+			return fmt.Errorf("删除实例防火墙规则失败: %w", err)
+		}
+	}
+
+	// 新规则默认插入到最前面，倒序创建后顺序与下发顺序一致
+	ordered := provider.OrderFirewallRules(rules)
+	for i := len(ordered) - 1; i >= 0; i-- {
+		rule := ordered[i]
+		params := url.Values{}
+		params.Set("type", "in")
+		params.Set("enable", "1")
+		params.Set("comment", firewallRuleComment)
+		if rule.Action == "deny" {
+			params.Set("action", "DROP")
+		} else {
+			params.Set("action", "ACCEPT")
+		}
+		switch rule.Protocol {
+		case "tcp", "udp":
+			params.Set("proto", rule.Protocol)
+		case "icmp":
+			if provider.FirewallRuleFamily(rule) == "6" {
+				params.Set("proto", "ipv6-icmp")
+			} else {
+				params.Set("proto", "icmp")
+			}
+		}
+		if ports := provider.FirewallPortRange(rule, ":"); ports != "" {
+			params.Set("dport", ports)
+		}
+		if rule.CIDR != "" {
+			params.Set("source", rule.CIDR)
+		}
+		if _, err := call(ctx, http.MethodPost, base+"/firewall/rules", params); err != nil {
+			return fmt.Errorf("创建实例防火墙规则失败: %w", err)
+		}
+	}
+
+	options := url.Values{}
+	if len(rules) > 0 {
+		options.Set("enable", "1")
+		options.Set("policy_in", "DROP")
+	} else {
+		options.Set("policy_in", "ACCEPT")
+	}
+	if _, err := call(ctx, http.MethodPut, base+"/firewall/options", options); err != nil {
+		return fmt.Errorf("设置实例防火墙策略失败: %w", err)
+	}
+
+	if len(rules) == 0 {
+		return nil
+	}
+
+	// 网卡未开启firewall时实例级规则不生效，创建VM时默认为firewall=0
+	data, err = call(ctx, http.MethodGet, base+"/config", nil)
+	if err != nil {
+		return fmt.Errorf("获取实例配置失败: %w", err)
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("解析实例配置失败: %w", err)
+	}
+	net0, _ := config["net0"].(string)
+	if net0 == "" || strings.Contains(net0, "firewall=1") {
+		return nil
+	}
+	if strings.Contains(net0, "firewall=0") {
+		net0 = strings.Replace(net0, "firewall=0", "firewall=1", 1)
+	} else {
+		net0 += ",firewall=1"
+	}
+	netParams := url.Values{}
+	netParams.Set("net0", net0)
+	if _, err := call(ctx, http.MethodPut, base+"/config", netParams); err != nil {
+		return fmt.Errorf("开启网卡防火墙失败: %w", err)
+	}
+	return nil
+}
+
+// apiFirewallCall 通过HTTP API调用
+func (p *ProxmoxProvider) apiFirewallCall(ctx context.Context, method, path string, params url.Values) ([]byte, error) {
+	apiURL := fmt.Sprintf("https://%s:8006/api2/json%s", p.config.Host, path)
+	var body io.Reader
+	if method == http.MethodPost || method == http.MethodPut {
+		body = strings.NewReader(params.Encode())
+	} else if len(params) > 0 {
+		apiURL += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	// 设置认证头
+	p.setAPIAuth(req)
+
+	resp, err := p.apiClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: %d %s", method, path, resp.StatusCode, utils.TruncateString(string(respBody), 200))
+	}
+
+	var response struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// sshFirewallCall 通过SSH执行pvesh调用
+// 参数值均来自校验过的规则或固定内容，不含单引号
+func (p *ProxmoxProvider) sshFirewallCall(ctx context.Context, method, path string, params url.Values) ([]byte, error) {
+	verbs := map[string]string{
+		http.MethodGet:    "get",
+		http.MethodPost:   "create",
+		http.MethodPut:    "set",
+		http.MethodDelete: "delete",
+	}
+
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "pvesh %s %s", verbs[method], path)
+	for key, values := range params {
+		for _, value := range values {
+			fmt.Fprintf(&cmd, " --%s '%s'", key, value)
+		}
+	}
+	cmd.WriteString(" --output-format json")
+
+	output, err := p.sshClient.Execute(cmd.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w, output: %s", err, utils.TruncateString(output, 200))
+	}
+	return []byte(strings.TrimSpace(output)), nil
+}
@@ -211,6 +211,13 @@ func (p *ProxmoxProvider) configurePortMappingsWithIP(ctx context.Context, insta
 		return nil
 	}
 
+	// nftables端口映射由nftables后端按数据库整表下发，实例内网IP写入数据库后统一应用
+	if networkConfig.IPv4PortMappingMethod == "nftables" {
+		global.APP_LOG.Info("端口映射由nftables后端下发，跳过Provider层配置",
+			zap.String("instance", instanceName))
+		return nil
+	}
+
 	// 从数据库获取实例的端口映射配置
 	var instance providerModel.Instance
 	if err := global.APP_DB.Where("name = ?", instanceName).First(&instance).Error; err != nil {
@@ -311,6 +318,14 @@ func (p *ProxmoxProvider) setupSinglePortMapping(ctx context.Context, instanceNa
 	case "native":
 		// Proxmox原生端口映射（暂时使用iptables实现）
 		return p.setupIptablesMappingWithIP(ctx, instanceName, hostPort, guestPort, protocol, instanceIP)
+	case "nftables":
+		// 由nftables后端统一下发
+		global.APP_LOG.Info("端口映射由nftables后端下发，跳过",
+			zap.String("instance", instanceName),
+			zap.Int("hostPort", hostPort),
+			zap.Int("guestPort", guestPort),
+			zap.String("protocol", protocol))
+		return nil
 	default:
 		// 默认使用iptables方式
 		return p.setupIptablesMappingWithIP(ctx, instanceName, hostPort, guestPort, protocol, instanceIP)
@@ -378,6 +393,9 @@ func (p *ProxmoxProvider) removePortMapping(ctx context.Context, instanceName st
 	case "native":
 		// Proxmox原生端口映射移除（暂时使用iptables实现）
 		return p.removeIptablesMapping(ctx, instanceName, hostPort, protocol)
+	case "nftables":
+		// 由nftables后端按数据库重新生成规则时删除
+		return nil
 	default:
 		// 默认使用iptables方式
 		return p.removeIptablesMapping(ctx, instanceName, hostPort, protocol)
@@ -0,0 +1,66 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// ResizeInstance 在线调整实例规格
+// 虚拟机使用 qm set/resize，容器使用 pct set/resize；磁盘大小为目标值，只支持扩容
+func (p *ProxmoxProvider) ResizeInstance(ctx context.Context, instanceID string, spec provider.ResizeSpec) error {
+	if !p.connected {
+		return fmt.Errorf("not connected")
+	}
+
+	vmid, instanceType, err := p.findVMIDByNameOrID(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("无法找到实例 %s 对应的VMID: %w", instanceID, err)
+	}
+
+	var tool, diskName string
+	switch instanceType {
+	case "vm":
+		tool, diskName = "qm", "scsi0"
+	case "container":
+		tool, diskName = "pct", "rootfs"
+	default:
+		return fmt.Errorf("unknown instance type: %s", instanceType)
+	}
+
+	var options []string
+	if spec.CPU > 0 {
+		options = append(options, fmt.Sprintf("--cores %d", spec.CPU))
+	}
+	if spec.Memory > 0 {
+		options = append(options, fmt.Sprintf("--memory %d", spec.Memory))
+	}
+	if len(options) > 0 {
+		cmd := fmt.Sprintf("%s set %s %s", tool, vmid, strings.Join(options, " "))
+		if output, err := p.sshClient.Execute(cmd); err != nil {
+			return fmt.Errorf("调整CPU和内存失败: %w, output: %s", err, output)
+		}
+	}
+
+	if spec.Disk > 0 {
+		cmd := fmt.Sprintf("%s resize %s %s %dM", tool, vmid, diskName, spec.Disk)
+		if output, err := p.sshClient.Execute(cmd); err != nil {
+			return fmt.Errorf("调整磁盘失败: %w, output: %s", err, output)
+		}
+	}
+
+	// Proxmox实例创建时未配置网卡限速，带宽只记录在数据库中
+	global.APP_LOG.Info("Proxmox实例规格调整成功",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.String("vmid", vmid),
+		zap.Int("cpu", spec.CPU),
+		zap.Int64("memory", spec.Memory),
+		zap.Int64("disk", spec.Disk))
+	return nil
+}
@@ -0,0 +1,442 @@
+package proxmox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// CreateSnapshot 创建实例快照
+func (p *ProxmoxProvider) CreateSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	if !p.connected {
+		return fmt.Errorf("not connected")
+	}
+	if !provider.IsValidSnapshotName(snapshotName) {
+		return fmt.Errorf("无效的快照名称: %s", snapshotName)
+	}
+
+	// 尝试 API 调用
+	if p.hasAPIAccess() {
+		err := p.apiCreateSnapshot(ctx, instanceID, snapshotName)
+		if err == nil {
+			global.APP_LOG.Info("Proxmox API调用成功 - 创建快照", zap.String("id", utils.TruncateString(instanceID, 50)), zap.String("snapshot", snapshotName))
+			return nil
+		}
+		global.APP_LOG.Warn("Proxmox API失败，回退到SSH - 创建快照", zap.String("id", utils.TruncateString(instanceID, 50)), zap.Error(err))
+	}
+
+	// SSH 方式
+	return p.sshCreateSnapshot(ctx, instanceID, snapshotName)
+}
+
+// ListSnapshots 获取实例快照列表
+func (p *ProxmoxProvider) ListSnapshots(ctx context.Context, instanceID string) ([]provider.Snapshot, error) {
+	if !p.connected {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	// 尝试 API 调用
+	if p.hasAPIAccess() {
+		snapshots, err := p.apiListSnapshots(ctx, instanceID)
+		if err == nil {
+			global.APP_LOG.Debug("Proxmox API调用成功 - 获取快照列表", zap.String("id", utils.TruncateString(instanceID, 50)))
+			return snapshots, nil
+		}
+		global.APP_LOG.Warn("Proxmox API失败，回退到SSH - 获取快照列表", zap.String("id", utils.TruncateString(instanceID, 50)), zap.Error(err))
+	}
+
+	// SSH 方式
+	return p.sshListSnapshots(ctx, instanceID)
+}
+
+// RestoreSnapshot 将实例回滚到指定快照
+func (p *ProxmoxProvider) RestoreSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	if !p.connected {
+		return fmt.Errorf("not connected")
+	}
+	if !provider.IsValidSnapshotName(snapshotName) {
+		return fmt.Errorf("无效的快照名称: %s", snapshotName)
+	}
+
+	// 尝试 API 调用
+	if p.hasAPIAccess() {
+		err := p.apiRestoreSnapshot(ctx, instanceID, snapshotName)
+		if err == nil {
+			global.APP_LOG.Info("Proxmox API调用成功 - 恢复快照", zap.String("id", utils.TruncateString(instanceID, 50)), zap.String("snapshot", snapshotName))
+			return nil
+		}
+		global.APP_LOG.Warn("Proxmox API失败，回退到SSH - 恢复快照", zap.String("id", utils.TruncateString(instanceID, 50)), zap.Error(err))
+	}
+
+	// SSH 方式
+	return p.sshRestoreSnapshot(ctx, instanceID, snapshotName)
+}
+
+// DeleteSnapshot 删除实例快照
+func (p *ProxmoxProvider) DeleteSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	if !p.connected {
+		return fmt.Errorf("not connected")
+	}
+	if !provider.IsValidSnapshotName(snapshotName) {
+		return fmt.Errorf("无效的快照名称: %s", snapshotName)
+	}
+
+	// 尝试 API 调用
+	if p.hasAPIAccess() {
+		err := p.apiDeleteSnapshot(ctx, instanceID, snapshotName)
+		if err == nil {
+			global.APP_LOG.Info("Proxmox API调用成功 - 删除快照", zap.String("id", utils.TruncateString(instanceID, 50)), zap.String("snapshot", snapshotName))
+			return nil
+		}
+		global.APP_LOG.Warn("Proxmox API失败，回退到SSH - 删除快照", zap.String("id", utils.TruncateString(instanceID, 50)), zap.Error(err))
+	}
+
+	// SSH 方式
+	return p.sshDeleteSnapshot(ctx, instanceID, snapshotName)
+}
+
+// snapshotAPIBase 根据实例类型构建快照API的基础URL
+func (p *ProxmoxProvider) snapshotAPIBase(vmid, instanceType string) (string, error) {
+	switch instanceType {
+	case "vm":
+		return fmt.Sprintf("https://%s:8006/api2/json/nodes/%s/qemu/%s/snapshot", p.config.Host, p.node, vmid), nil
+	case "container":
+		return fmt.Sprintf("https://%s:8006/api2/json/nodes/%s/lxc/%s/snapshot", p.config.Host, p.node, vmid), nil
+	default:
+		return "", fmt.Errorf("unknown instance type: %s", instanceType)
+	}
+}
+
+// apiCreateSnapshot 通过API创建快照
+func (p *ProxmoxProvider) apiCreateSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	vmid, instanceType, err := p.findVMIDByNameOrID(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to find instance %s: %w", instanceID, err)
+	}
+
+	baseURL, err := p.snapshotAPIBase(vmid, instanceType)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set("snapname", snapshotName)
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// 设置认证头
+	p.setAPIAuth(req)
+
+	return p.apiDoTaskRequest(ctx, req, "create snapshot")
+}
+
+// apiListSnapshots 通过API获取快照列表
+func (p *ProxmoxProvider) apiListSnapshots(ctx context.Context, instanceID string) ([]provider.Snapshot, error) {
+	vmid, instanceType, err := p.findVMIDByNameOrID(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find instance %s: %w", instanceID, err)
+	}
+
+	baseURL, err := p.snapshotAPIBase(vmid, instanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 设置认证头
+	p.setAPIAuth(req)
+
+	resp, err := p.apiClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list snapshots: %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Data []proxmoxSnapshotInfo `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	return convertSnapshotInfos(response.Data), nil
+}
+
+// apiRestoreSnapshot 通过API回滚快照
+func (p *ProxmoxProvider) apiRestoreSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	vmid, instanceType, err := p.findVMIDByNameOrID(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to find instance %s: %w", instanceID, err)
+	}
+
+	baseURL, err := p.snapshotAPIBase(vmid, instanceType)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/%s/rollback", baseURL, snapshotName), nil)
+	if err != nil {
+		return err
+	}
+
+	// 设置认证头
+	p.setAPIAuth(req)
+
+	return p.apiDoTaskRequest(ctx, req, "rollback snapshot")
+}
+
+// apiDeleteSnapshot 通过API删除快照
+func (p *ProxmoxProvider) apiDeleteSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	vmid, instanceType, err := p.findVMIDByNameOrID(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to find instance %s: %w", instanceID, err)
+	}
+
+	baseURL, err := p.snapshotAPIBase(vmid, instanceType)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("%s/%s", baseURL, snapshotName), nil)
+	if err != nil {
+		return err
+	}
+
+	// 设置认证头
+	p.setAPIAuth(req)
+
+	return p.apiDoTaskRequest(ctx, req, "delete snapshot")
+}
+
+// apiDoTaskRequest 执行返回UPID的异步API请求，并等待任务完成
+func (p *ProxmoxProvider) apiDoTaskRequest(ctx context.Context, req *http.Request, action string) error {
+	resp, err := p.apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to %s: %d", action, resp.StatusCode)
+	}
+
+	var response struct {
+		Data string `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil || response.Data == "" {
+		// 部分版本不返回UPID，直接认为成功
+		return nil
+	}
+
+	return p.apiWaitTask(ctx, response.Data)
+}
+
+// apiWaitTask 轮询等待Proxmox任务完成，最多等待10分钟
+func (p *ProxmoxProvider) apiWaitTask(ctx context.Context, upid string) error {
+	statusURL := fmt.Sprintf("https://%s:8006/api2/json/nodes/%s/tasks/%s/status", p.config.Host, p.node, url.PathEscape(upid))
+	deadline := time.Now().Add(10 * time.Minute)
+
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", statusURL, nil)
+		if err != nil {
+			return err
+		}
+		p.setAPIAuth(req)
+
+		resp, err := p.apiClient.Do(req)
+		if err != nil {
+			continue
+		}
+
+		var response struct {
+			Data struct {
+				Status     string `json:"status"`
+				ExitStatus string `json:"exitstatus"`
+			} `json:"data"`
+		}
+		decodeErr := json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if decodeErr != nil {
+			continue
+		}
+
+		if response.Data.Status == "stopped" {
+			if response.Data.ExitStatus != "OK" {
+				return fmt.Errorf("任务执行失败: %s", response.Data.ExitStatus)
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("等待任务完成超时: %s", upid)
+}
+
+// sshCreateSnapshot 通过SSH创建快照
+func (p *ProxmoxProvider) sshCreateSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	vmid, instanceType, err := p.findVMIDByNameOrID(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to find instance %s: %w", instanceID, err)
+	}
+
+	var cmd string
+	switch instanceType {
+	case "vm":
+		cmd = fmt.Sprintf("qm snapshot %s %s", vmid, snapshotName)
+	case "container":
+		cmd = fmt.Sprintf("pct snapshot %s %s", vmid, snapshotName)
+	default:
+		return fmt.Errorf("unknown instance type: %s", instanceType)
+	}
+
+	output, err := p.sshClient.Execute(cmd)
+	if err != nil {
+		return fmt.Errorf("创建快照失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("Proxmox快照创建成功",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.String("vmid", vmid),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
+
+// sshListSnapshots 通过SSH获取快照列表
+func (p *ProxmoxProvider) sshListSnapshots(ctx context.Context, instanceID string) ([]provider.Snapshot, error) {
+	vmid, instanceType, err := p.findVMIDByNameOrID(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find instance %s: %w", instanceID, err)
+	}
+
+	apiType := "qemu"
+	if instanceType == "container" {
+		apiType = "lxc"
+	}
+
+	output, err := p.sshClient.Execute(fmt.Sprintf("pvesh get /nodes/%s/%s/%s/snapshot --output-format json", p.node, apiType, vmid))
+	if err != nil {
+		return nil, fmt.Errorf("获取快照列表失败: %w", err)
+	}
+
+	var infos []proxmoxSnapshotInfo
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &infos); err != nil {
+		return nil, fmt.Errorf("解析快照列表失败: %w", err)
+	}
+
+	return convertSnapshotInfos(infos), nil
+}
+
+// sshRestoreSnapshot 通过SSH回滚快照
+func (p *ProxmoxProvider) sshRestoreSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	vmid, instanceType, err := p.findVMIDByNameOrID(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to find instance %s: %w", instanceID, err)
+	}
+
+	var cmd string
+	switch instanceType {
+	case "vm":
+		cmd = fmt.Sprintf("qm rollback %s %s", vmid, snapshotName)
+	case "container":
+		cmd = fmt.Sprintf("pct rollback %s %s", vmid, snapshotName)
+	default:
+		return fmt.Errorf("unknown instance type: %s", instanceType)
+	}
+
+	output, err := p.sshClient.Execute(cmd)
+	if err != nil {
+		return fmt.Errorf("恢复快照失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("Proxmox快照恢复成功",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.String("vmid", vmid),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
+
+// sshDeleteSnapshot 通过SSH删除快照
+func (p *ProxmoxProvider) sshDeleteSnapshot(ctx context.Context, instanceID, snapshotName string) error {
+	vmid, instanceType, err := p.findVMIDByNameOrID(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to find instance %s: %w", instanceID, err)
+	}
+
+	var cmd string
+	switch instanceType {
+	case "vm":
+		cmd = fmt.Sprintf("qm delsnapshot %s %s", vmid, snapshotName)
+	case "container":
+		cmd = fmt.Sprintf("pct delsnapshot %s %s", vmid, snapshotName)
+	default:
+		return fmt.Errorf("unknown instance type: %s", instanceType)
+	}
+
+	output, err := p.sshClient.Execute(cmd)
+	if err != nil {
+		// 快照已不存在时视为删除成功
+		if strings.Contains(output, "does not exist") || strings.Contains(err.Error(), "does not exist") {
+			global.APP_LOG.Info("Proxmox快照不存在，跳过删除", zap.String("snapshot", snapshotName))
+			return nil
+		}
+		return fmt.Errorf("删除快照失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("Proxmox快照删除成功",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.String("vmid", vmid),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
+
+// proxmoxSnapshotInfo Proxmox快照API返回结构
+type proxmoxSnapshotInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	SnapTime    int64  `json:"snaptime"`
+	VMState     int    `json:"vmstate"`
+}
+
+// convertSnapshotInfos 将Proxmox快照信息转换为统一结构
+// Proxmox会在列表中返回代表当前状态的"current"条目，需要过滤
+func convertSnapshotInfos(infos []proxmoxSnapshotInfo) []provider.Snapshot {
+	snapshots := make([]provider.Snapshot, 0, len(infos))
+	for _, info := range infos {
+		if info.Name == "current" {
+			continue
+		}
+		snapshots = append(snapshots, provider.Snapshot{
+			Name:        info.Name,
+			Description: info.Description,
+			Created:     time.Unix(info.SnapTime, 0),
+			Stateful:    info.VMState == 1,
+		})
+	}
+	return snapshots
+}
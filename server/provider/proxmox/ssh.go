@@ -191,6 +191,14 @@ func (p *ProxmoxProvider) sshCreateInstanceWithProgress(ctx context.Context, con
 		global.APP_LOG.Warn("配置SSH密码失败", zap.Error(err))
 	}
 
+	// 容器没有cloud-init，启动后直接执行用户数据脚本
+	if config.InstanceType == "container" && config.UserData != "" {
+		updateProgress(93, "执行用户数据脚本...")
+		if err := p.runContainerUserData(ctx, vmid, config); err != nil {
+			global.APP_LOG.Warn("执行用户数据脚本失败", zap.Int("vmid", vmid), zap.Error(err))
+		}
+	}
+
 	// 初始化vnstat流量监控
 	updateProgress(95, "初始化vnstat流量监控...")
 	if err := p.initializeVnStatMonitoring(ctx, vmid, config.Name); err != nil {
@@ -630,6 +638,9 @@ func (p *ProxmoxProvider) handleVMDeletion(ctx context.Context, vmid string, ipA
 	if err := p.cleanupVMFiles(ctx, vmid); err != nil {
 		global.APP_LOG.Warn("清理VM文件失败", zap.String("vmid", vmid), zap.Error(err))
 	}
+	if err := p.cleanupVMUserData(ctx, vmid); err != nil {
+		global.APP_LOG.Warn("清理用户数据失败", zap.String("vmid", vmid), zap.Error(err))
+	}
 
 	// 8. 更新iptables规则
 	if ipAddress != "" {
@@ -1419,6 +1430,11 @@ func (p *ProxmoxProvider) createVM(ctx context.Context, vmid int, config provide
 		global.APP_LOG.Info("虚拟机名称设置成功", zap.Int("vmid", vmid), zap.String("name", config.Name))
 	}
 
+	// 下发用户数据，需在首次启动前完成
+	if err := p.configureVMUserData(ctx, vmid, config); err != nil {
+		return fmt.Errorf("配置用户数据失败: %w", err)
+	}
+
 	updateProgress(95, "启动虚拟机...")
 
 	// 启动虚拟机（参考脚本）
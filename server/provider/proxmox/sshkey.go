@@ -0,0 +1,104 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// guestExecSuccessPattern qm guest exec 输出的JSON中命令退出码为0
+var guestExecSuccessPattern = regexp.MustCompile(`"exitcode"\s*:\s*0\b`)
+
+// SetInstanceSSHKeys 向实例root用户写入SSH公钥，可选禁用密码登录
+// 容器通过 pct exec 写入；虚拟机通过cloud-init的sshkeys下发，重启后生效
+func (p *ProxmoxProvider) SetInstanceSSHKeys(ctx context.Context, instanceID string, publicKeys []string, disablePassword bool) error {
+	if !p.connected {
+		return fmt.Errorf("provider not connected")
+	}
+
+	vmid, instanceType, err := p.findVMIDByNameOrID(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("无法找到实例 %s 对应的VMID: %w", instanceID, err)
+	}
+
+	switch instanceType {
+	case "container":
+		cmd := fmt.Sprintf("pct exec %s -- sh -c '%s'", vmid, provider.BuildSSHKeyScript(publicKeys, disablePassword))
+		if output, err := p.sshClient.Execute(cmd); err != nil {
+			return fmt.Errorf("注入SSH公钥失败: %w, output: %s", err, output)
+		}
+	case "vm":
+		if err := p.setVMSSHKeys(ctx, vmid, publicKeys, disablePassword); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown instance type: %s", instanceType)
+	}
+
+	global.APP_LOG.Info("Proxmox实例SSH公钥注入成功",
+		zap.String("instanceID", utils.TruncateString(instanceID, 12)),
+		zap.String("vmid", vmid),
+		zap.Int("keyCount", len(publicKeys)),
+		zap.Bool("disablePassword", disablePassword))
+	return nil
+}
+
+// setVMSSHKeys 通过cloud-init为虚拟机设置SSH公钥
+// cloud-init不会修改sshd的密码登录配置，禁用密码登录需要通过guest agent在虚拟机内执行，且须在重启前完成
+func (p *ProxmoxProvider) setVMSSHKeys(ctx context.Context, vmid string, publicKeys []string, disablePassword bool) error {
+	if disablePassword {
+		if err := p.waitForGuestAgent(ctx, vmid); err != nil {
+			return err
+		}
+		cmd := fmt.Sprintf("qm guest exec %s -- sh -c '%s'", vmid, provider.BuildSSHKeyScript(publicKeys, true))
+		output, err := p.sshClient.Execute(cmd)
+		if err != nil || !guestExecSuccessPattern.MatchString(output) {
+			return fmt.Errorf("通过guest agent禁用密码登录失败: %v, output: %s", err, utils.TruncateString(output, 200))
+		}
+	}
+
+	// qm set --sshkeys 只接受文件路径，先把公钥写到节点的临时文件中
+	keyFile := fmt.Sprintf("/tmp/oneclickvirt-sshkeys-%s.pub", vmid)
+	cmd := fmt.Sprintf("echo %s | base64 -d > %s && qm set %s --sshkeys %s; ret=$?; rm -f %s; exit $ret",
+		provider.EncodeAuthorizedKeys(publicKeys), keyFile, vmid, keyFile, keyFile)
+	if output, err := p.sshClient.Execute(cmd); err != nil {
+		return fmt.Errorf("通过cloud-init设置SSH公钥失败: %w, output: %s", err, output)
+	}
+
+	// 虚拟机运行中时重启以重新生成cloud-init配置
+	statusOutput, err := p.sshClient.Execute(fmt.Sprintf("qm status %s", vmid))
+	if err == nil && strings.Contains(statusOutput, "status: running") {
+		if _, err := p.sshClient.Execute(fmt.Sprintf("qm reboot %s", vmid)); err != nil {
+			global.APP_LOG.Warn("重启虚拟机应用SSH公钥失败，可能需要手动重启",
+				zap.String("vmid", vmid),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// waitForGuestAgent 等待虚拟机内的qemu-guest-agent可用，最多等待120秒
+func (p *ProxmoxProvider) waitForGuestAgent(ctx context.Context, vmid string) error {
+	deadline := time.Now().Add(120 * time.Second)
+	for {
+		if _, err := p.sshClient.Execute(fmt.Sprintf("qm guest cmd %s ping", vmid)); err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("虚拟机 %s 的qemu-guest-agent不可用，无法禁用密码登录", vmid)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// OpenTerminal 通过 pct enter 打开容器的交互式终端
+// 虚拟机没有可直接进入的shell，需要使用VNC控制台
+func (p *ProxmoxProvider) OpenTerminal(ctx context.Context, instanceID string, cols, rows int) (*utils.SSHTerminal, error) {
+	if !p.connected {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	vmid, instanceType, err := p.findVMIDByNameOrID(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("无法找到实例 %s 对应的VMID: %w", instanceID, err)
+	}
+	if instanceType != "container" {
+		return nil, fmt.Errorf("虚拟机不支持Web终端，请使用VNC控制台")
+	}
+
+	terminal, err := p.sshClient.OpenTerminal(fmt.Sprintf("pct enter %s", vmid), cols, rows)
+	if err != nil {
+		return nil, fmt.Errorf("打开终端失败: %w", err)
+	}
+
+	global.APP_LOG.Info("Proxmox容器终端已打开",
+		zap.String("id", utils.TruncateString(instanceID, 50)),
+		zap.String("vmid", vmid))
+	return terminal, nil
+}
@@ -0,0 +1,107 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// userDataSnippetName 虚拟机用户数据在snippets存储中的文件名
+func userDataSnippetName(vmid int) string {
+	return fmt.Sprintf("oneclickvirt-%d-vendor.yaml", vmid)
+}
+
+// getSnippetsStorage 获取第一个启用了snippets内容类型的存储
+func (p *ProxmoxProvider) getSnippetsStorage() (string, error) {
+	output, err := p.sshClient.Execute("pvesm status --content snippets 2>/dev/null | awk 'NR > 1 && $3 == \"active\" {print $1}'")
+	if err != nil {
+		return "", fmt.Errorf("获取snippets存储失败: %w", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if storage := strings.TrimSpace(line); storage != "" {
+			return storage, nil
+		}
+	}
+	return "", fmt.Errorf("节点没有启用snippets内容类型的存储，无法下发用户数据，请在存储配置中为local等目录存储启用snippets")
+}
+
+// configureVMUserData 通过cicustom为虚拟机下发用户数据，需在虚拟机首次启动前完成
+// 使用vendor-data而不是user-data，保留PVE根据ciuser、cipassword、sshkeys生成的user-data
+func (p *ProxmoxProvider) configureVMUserData(ctx context.Context, vmid int, config provider.InstanceConfig) error {
+	if config.UserData == "" {
+		return nil
+	}
+
+	storage, err := p.getSnippetsStorage()
+	if err != nil {
+		return err
+	}
+	volume := fmt.Sprintf("%s:snippets/%s", storage, userDataSnippetName(vmid))
+
+	snippetPath, err := p.sshClient.Execute(fmt.Sprintf("pvesm path %s", volume))
+	if err != nil {
+		return fmt.Errorf("获取snippets路径失败: %w", err)
+	}
+	snippetPath = strings.TrimSpace(snippetPath)
+
+	writeCmd := fmt.Sprintf("mkdir -p $(dirname %s) && echo %s | base64 -d > %s",
+		snippetPath, provider.EncodeUserData(config.UserData), snippetPath)
+	if output, err := p.sshClient.Execute(writeCmd); err != nil {
+		return fmt.Errorf("写入用户数据失败: %w, output: %s", err, output)
+	}
+
+	if output, err := p.sshClient.Execute(fmt.Sprintf("qm set %d --cicustom vendor=%s", vmid, volume)); err != nil {
+		return fmt.Errorf("设置cicustom失败: %w, output: %s", err, output)
+	}
+
+	global.APP_LOG.Info("Proxmox虚拟机用户数据配置成功",
+		zap.Int("vmid", vmid),
+		zap.String("volume", volume),
+		zap.Bool("cloudConfig", provider.IsCloudConfig(config.UserData)))
+	return nil
+}
+
+// runContainerUserData 在容器启动后执行用户提供的shell脚本，LXC容器没有cloud-init
+func (p *ProxmoxProvider) runContainerUserData(ctx context.Context, vmid int, config provider.InstanceConfig) error {
+	if config.UserData == "" {
+		return nil
+	}
+
+	cmd := fmt.Sprintf("pct exec %d -- sh -c '%s'", vmid, provider.BuildUserDataRunScript(config.UserData))
+	if output, err := p.sshClient.Execute(cmd); err != nil {
+		return fmt.Errorf("启动用户数据脚本失败: %w, output: %s", err, utils.TruncateString(output, 200))
+	}
+
+	global.APP_LOG.Info("Proxmox容器用户数据脚本已启动", zap.Int("vmid", vmid))
+	return nil
+}
+
+// cleanupVMUserData 删除虚拟机的用户数据snippet文件
+func (p *ProxmoxProvider) cleanupVMUserData(ctx context.Context, vmid string) error {
+	vmidInt, err := strconv.Atoi(vmid)
+	if err != nil {
+		return fmt.Errorf("无效的VMID: %s", vmid)
+	}
+
+	output, err := p.sshClient.Execute("pvesm status --content snippets 2>/dev/null | awk 'NR > 1 {print $1}'")
+	if err != nil {
+		return fmt.Errorf("获取snippets存储失败: %w", err)
+	}
+	for _, storage := range strings.Split(strings.TrimSpace(output), "\n") {
+		if storage = strings.TrimSpace(storage); storage == "" {
+			continue
+		}
+		volume := fmt.Sprintf("%s:snippets/%s", storage, userDataSnippetName(vmidInt))
+		if _, err := p.sshClient.Execute(fmt.Sprintf("rm -f \"$(pvesm path %s 2>/dev/null)\"", volume)); err != nil {
+			global.APP_LOG.Warn("删除用户数据snippet失败", zap.String("volume", volume), zap.Error(err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package provider
+
+// ResizeSpec 实例规格调整参数，数值为0的项保持不变
+type ResizeSpec struct {
+	CPU       int   // CPU核数
+	Memory    int64 // 内存（MB）
+	Disk      int64 // 磁盘（MB），只支持扩容
+	Bandwidth int   // 带宽（Mbps）
+}
@@ -0,0 +1,12 @@
+package provider
+
+import "regexp"
+
+// snapshotNamePattern 快照名称只允许字母、数字、下划线和短横线，且必须以字母开头
+var snapshotNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]{0,39}$`)
+
+// IsValidSnapshotName 检查快照名称是否合法
+// 快照名称会被拼接进远程命令和API路径，必须严格校验
+func IsValidSnapshotName(name string) bool {
+	return snapshotNamePattern.MatchString(name)
+}
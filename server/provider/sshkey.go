@@ -0,0 +1,39 @@
+package provider
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// EncodeAuthorizedKeys 将公钥列表编码为base64格式的authorized_keys内容
+// 公钥注释中可能包含引号等特殊字符，编码后可以安全地拼接到shell命令中
+func EncodeAuthorizedKeys(publicKeys []string) string {
+	var lines []string
+	for _, key := range publicKeys {
+		if key = strings.TrimSpace(key); key != "" {
+			lines = append(lines, key)
+		}
+	}
+	return base64.StdEncoding.EncodeToString([]byte(strings.Join(lines, "\n") + "\n"))
+}
+
+// BuildSSHKeyScript 生成在实例内执行的公钥注入脚本
+// 公钥追加到root用户的authorized_keys中（已存在的不重复写入）；disablePassword为true时同时禁用SSH密码登录并重启sshd。
+// 脚本中不包含单引号，可直接放入 sh -c '...' 中执行
+func BuildSSHKeyScript(publicKeys []string, disablePassword bool) string {
+	script := fmt.Sprintf(
+		`mkdir -p /root/.ssh && chmod 700 /root/.ssh && touch /root/.ssh/authorized_keys && `+
+			`echo %s | base64 -d | while IFS= read -r key; do `+
+			`[ -z "$key" ] || grep -qxF "$key" /root/.ssh/authorized_keys || echo "$key" >> /root/.ssh/authorized_keys; `+
+			`done && chmod 600 /root/.ssh/authorized_keys`,
+		EncodeAuthorizedKeys(publicKeys))
+
+	if disablePassword {
+		// sshd以首次出现的配置为准，写到第一行可以覆盖Include进来的配置
+		script += ` && sed -i "/^[[:space:]]*PasswordAuthentication[[:space:]]/d" /etc/ssh/sshd_config` +
+			` && sed -i "1i PasswordAuthentication no" /etc/ssh/sshd_config` +
+			` && { systemctl restart sshd || systemctl restart ssh || service sshd restart || service ssh restart || rc-service sshd restart || pkill -HUP sshd; } >/dev/null 2>&1`
+	}
+	return script
+}
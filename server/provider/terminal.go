@@ -0,0 +1,10 @@
+package provider
+
+const (
+	// TerminalShell 实例内启动登录shell的命令，优先使用bash，不存在时回退到sh
+	TerminalShell = "sh -c 'if command -v bash >/dev/null 2>&1; then exec bash -l; else exec sh -l; fi'"
+	// TerminalDefaultCols 终端默认列数
+	TerminalDefaultCols = 80
+	// TerminalDefaultRows 终端默认行数
+	TerminalDefaultRows = 24
+)
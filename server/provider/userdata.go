@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxUserDataSize 用户数据最大长度（字节）
+const MaxUserDataSize = 16 * 1024
+
+const (
+	cloudConfigHeader = "#cloud-config"
+	shellScriptHeader = "#!"
+
+	// userDataDir 实例内保存用户数据脚本的目录
+	userDataDir = "/var/lib/oneclickvirt"
+)
+
+// NormalizeUserData 统一换行符并去除首尾空白，空内容返回空字符串
+func NormalizeUserData(userData string) string {
+	userData = strings.ReplaceAll(userData, "\r\n", "\n")
+	userData = strings.TrimSpace(userData)
+	if userData == "" {
+		return ""
+	}
+	return userData + "\n"
+}
+
+// IsCloudConfig 判断用户数据是否为cloud-config格式
+func IsCloudConfig(userData string) bool {
+	return strings.HasPrefix(strings.TrimSpace(userData), cloudConfigHeader)
+}
+
+// SupportsCloudConfig 判断实例是否能够通过cloud-init处理cloud-config
+// LXD/Incus通过cloud-init.user-data下发，Proxmox虚拟机通过cicustom下发；Docker和Proxmox容器只支持shell脚本
+func SupportsCloudConfig(providerType, instanceType string) bool {
+	switch providerType {
+	case "lxd", "incus":
+		return true
+	case "proxmox":
+		return instanceType == "vm"
+	default:
+		return false
+	}
+}
+
+// ValidateUserData 校验用户提供的cloud-config或shell脚本
+// 内容必须以 #cloud-config 或 #! 开头，cloud-config需要是合法的YAML映射
+func ValidateUserData(userData, providerType, instanceType string) error {
+	userData = NormalizeUserData(userData)
+	if userData == "" {
+		return nil
+	}
+	if len(userData) > MaxUserDataSize {
+		return fmt.Errorf("用户数据不能超过 %d 字节", MaxUserDataSize)
+	}
+	if !utf8.ValidString(userData) || strings.ContainsRune(userData, 0) {
+		return errors.New("用户数据包含非法字符")
+	}
+
+	firstLine := strings.TrimSpace(strings.SplitN(userData, "\n", 2)[0])
+	switch {
+	case firstLine == cloudConfigHeader:
+		if !SupportsCloudConfig(providerType, instanceType) {
+			return errors.New("该节点的实例不支持cloud-config，请使用以 #! 开头的shell脚本")
+		}
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal([]byte(userData), &doc); err != nil {
+			return fmt.Errorf("cloud-config不是合法的YAML: %v", err)
+		}
+		if len(doc) == 0 {
+			return errors.New("cloud-config内容不能为空")
+		}
+	case strings.HasPrefix(firstLine, shellScriptHeader):
+		if strings.TrimSpace(strings.TrimPrefix(firstLine, shellScriptHeader)) == "" {
+			return errors.New("shell脚本需要指定解释器，如 #!/bin/sh")
+		}
+	default:
+		return errors.New("用户数据必须以 #cloud-config 或 #! 开头")
+	}
+	return nil
+}
+
+// EncodeUserData 将用户数据编码为base64，便于安全地拼接到shell命令中
+func EncodeUserData(userData string) string {
+	return base64.StdEncoding.EncodeToString([]byte(NormalizeUserData(userData)))
+}
+
+// BuildUserDataRunScript 生成在实例内后台执行用户shell脚本的命令，适用于没有cloud-init的容器
+// 脚本保存到 /var/lib/oneclickvirt/user-data，输出写入 /var/log/oneclickvirt-user-data.log；
+// 命令中不包含单引号，可直接放入 sh -c '...' 中执行
+func BuildUserDataRunScript(userData string) string {
+	script := userDataDir + "/user-data"
+	return fmt.Sprintf(
+		`mkdir -p %s && echo %s | base64 -d > %s && chmod 700 %s && (nohup %s > /var/log/oneclickvirt-user-data.log 2>&1 &)`,
+		userDataDir, EncodeUserData(userData), script, script, script)
+}
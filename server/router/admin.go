@@ -14,123 +14,185 @@ import (
 // InitAdminRouter 管理员路由
 func InitAdminRouter(Router *gin.RouterGroup) {
 	AdminGroup := Router.Group("/v1/admin")
-	AdminGroup.Use(middleware.RequireAuth(authModel.AuthLevelAdmin))
+	AdminGroup.Use(middleware.RequireAuth(authModel.AuthLevelUser), middleware.RequireAdminConsole(), middleware.RateLimit(middleware.RateLimitGroupAdmin))
+
+	// 每个接口按操作权限校验，管理员拥有全部权限，其他用户按已分配的角色校验
+	dashboardView := middleware.RequireResourcePermission(authModel.PermissionDashboardView)
+	userView := middleware.RequireResourcePermission(authModel.PermissionUserView)
+	userManage := middleware.RequireResourcePermission(authModel.PermissionUserManage)
+	instanceViewAny := middleware.RequireResourcePermission(authModel.PermissionInstanceViewAny)
+	instanceManageAny := middleware.RequireResourcePermission(authModel.PermissionInstanceManageAny)
+	providerView := middleware.RequireResourcePermission(authModel.PermissionProviderView)
+	providerManage := middleware.RequireResourcePermission(authModel.PermissionProviderManage)
+	trafficView := middleware.RequireResourcePermission(authModel.PermissionTrafficView)
+	trafficManage := middleware.RequireResourcePermission(authModel.PermissionTrafficManage)
+	taskView := middleware.RequireResourcePermission(authModel.PermissionTaskView)
+	taskManage := middleware.RequireResourcePermission(authModel.PermissionTaskManage)
+	contentView := middleware.RequireResourcePermission(authModel.PermissionContentView)
+	contentManage := middleware.RequireResourcePermission(authModel.PermissionContentManage)
+	auditView := middleware.RequireResourcePermission(authModel.PermissionAuditView)
+	roleManage := middleware.RequireResourcePermission(authModel.PermissionRoleManage)
+	systemManage := middleware.RequireResourcePermission(authModel.PermissionSystemManage)
 	{
 		// 仪表盘
-		AdminGroup.GET("/dashboard", admin.GetAdminDashboard)
+		AdminGroup.GET("/dashboard", dashboardView, admin.GetAdminDashboard)
 
 		// 系统配置（管理员专用）
-		AdminGroup.GET("/config", config.GetUnifiedConfig)
-		AdminGroup.PUT("/config", config.UpdateUnifiedConfig)
+		AdminGroup.GET("/config", systemManage, config.GetUnifiedConfig)
+		AdminGroup.PUT("/config", systemManage, config.UpdateUnifiedConfig)
+		AdminGroup.POST("/ldap/test", systemManage, admin.TestLDAPConnection)
 
 		// 用户管理
-		AdminGroup.GET("/users", admin.GetUserList)
-		AdminGroup.POST("/users", admin.CreateUser)
-		AdminGroup.PUT("/users/:id", admin.UpdateUser)
-		AdminGroup.DELETE("/users/:id", admin.DeleteUser)
-		AdminGroup.PUT("/users/:id/status", admin.UpdateUserStatus)
-		AdminGroup.PUT("/users/:id/level", admin.UpdateUserLevel)
-		AdminGroup.PUT("/users/:id/reset-password", admin.ResetUserPassword)
-		AdminGroup.PUT("/users/batch-level", admin.AdminBatchUpdateUserLevel)
-		AdminGroup.PUT("/users/batch-status", admin.AdminBatchUpdateUserStatus)
-		AdminGroup.POST("/users/batch-delete", admin.AdminBatchDeleteUsers)
+		AdminGroup.GET("/users", userView, admin.GetUserList)
+		AdminGroup.POST("/users", userManage, admin.CreateUser)
+		AdminGroup.PUT("/users/:id", userManage, admin.UpdateUser)
+		AdminGroup.DELETE("/users/:id", userManage, admin.DeleteUser)
+		AdminGroup.PUT("/users/:id/status", userManage, admin.UpdateUserStatus)
+		AdminGroup.PUT("/users/:id/level", userManage, admin.UpdateUserLevel)
+		AdminGroup.PUT("/users/:id/reset-password", userManage, admin.ResetUserPassword)
+		AdminGroup.PUT("/users/:id/reset-2fa", userManage, admin.ResetUserTwoFactor)
+		AdminGroup.PUT("/users/:id/unlock", userManage, admin.UnlockUser)
+		AdminGroup.GET("/users/:id/sessions", userView, admin.GetUserSessions)
+		AdminGroup.DELETE("/users/:id/sessions", userManage, admin.RevokeAllUserSessions)
+		AdminGroup.DELETE("/users/:id/sessions/:sessionId", userManage, admin.RevokeUserSession)
+		AdminGroup.PUT("/users/batch-level", userManage, admin.AdminBatchUpdateUserLevel)
+		AdminGroup.PUT("/users/batch-status", userManage, admin.AdminBatchUpdateUserStatus)
+		AdminGroup.POST("/users/batch-delete", userManage, admin.AdminBatchDeleteUsers)
+
+		// 角色与权限
+		AdminGroup.GET("/permissions", roleManage, admin.GetPermissionList)
+		AdminGroup.GET("/roles", roleManage, admin.GetRoleList)
+		AdminGroup.POST("/roles", roleManage, admin.CreateRole)
+		AdminGroup.PUT("/roles/:id", roleManage, admin.UpdateRole)
+		AdminGroup.DELETE("/roles/:id", roleManage, admin.DeleteRole)
+		AdminGroup.GET("/users/:id/roles", roleManage, admin.GetUserRoles)
+		AdminGroup.PUT("/users/:id/roles", roleManage, admin.SetUserRoles)
 
 		// 实例管理
-		AdminGroup.GET("/instances", admin.GetInstanceList)
-		AdminGroup.POST("/instances", admin.CreateInstance)
-		AdminGroup.PUT("/instances/:id", admin.UpdateInstance)
-		AdminGroup.DELETE("/instances/:id", admin.DeleteInstance)
-		AdminGroup.POST("/instances/:id/action", admin.AdminInstanceAction)
-		AdminGroup.PUT("/instances/:id/reset-password", admin.ResetInstancePassword)
-		AdminGroup.GET("/instances/:id/password/:taskId", admin.GetInstanceNewPassword)
-		AdminGroup.GET("/instance-type-permissions", admin.GetAdminInstanceTypePermissions)
-		AdminGroup.PUT("/instance-type-permissions", admin.UpdateAdminInstanceTypePermissions)
+		AdminGroup.GET("/instances", instanceViewAny, admin.GetInstanceList)
+		AdminGroup.POST("/instances", instanceManageAny, admin.CreateInstance)
+		AdminGroup.PUT("/instances/:id", instanceManageAny, admin.UpdateInstance)
+		AdminGroup.DELETE("/instances/:id", instanceManageAny, admin.DeleteInstance)
+		AdminGroup.POST("/instances/:id/action", instanceManageAny, admin.AdminInstanceAction)
+		AdminGroup.PUT("/instances/:id/reset-password", instanceManageAny, admin.ResetInstancePassword)
+		AdminGroup.GET("/instances/:id/password/:taskId", instanceManageAny, admin.GetInstanceNewPassword)
+		AdminGroup.GET("/instances/:id/snapshots", instanceViewAny, admin.GetAdminInstanceSnapshots)
+		AdminGroup.POST("/instances/:id/snapshots", instanceManageAny, admin.CreateAdminInstanceSnapshot)
+		AdminGroup.POST("/instances/:id/snapshots/:snapshotId/restore", instanceManageAny, admin.RestoreAdminInstanceSnapshot)
+		AdminGroup.DELETE("/instances/:id/snapshots/:snapshotId", instanceManageAny, admin.DeleteAdminInstanceSnapshot)
+		AdminGroup.POST("/instances/:id/resize", instanceManageAny, admin.ResizeAdminInstance)
+		AdminGroup.POST("/instances/:id/migrate", instanceManageAny, admin.MigrateAdminInstance)
+		AdminGroup.GET("/backups", instanceViewAny, admin.GetAdminBackups)
+		AdminGroup.POST("/instances/:id/backups", instanceManageAny, admin.CreateAdminInstanceBackup)
+		AdminGroup.POST("/backups/:backupId/restore", instanceManageAny, admin.RestoreAdminBackup)
+		AdminGroup.DELETE("/backups/:backupId", instanceManageAny, admin.DeleteAdminBackup)
+		AdminGroup.GET("/instances/:id/backup-schedule", instanceViewAny, admin.GetAdminBackupSchedule)
+		AdminGroup.PUT("/instances/:id/backup-schedule", instanceManageAny, admin.SaveAdminBackupSchedule)
+		AdminGroup.DELETE("/instances/:id/backup-schedule", instanceManageAny, admin.DeleteAdminBackupSchedule)
+		AdminGroup.GET("/instance-type-permissions", instanceViewAny, admin.GetAdminInstanceTypePermissions)
+		AdminGroup.PUT("/instance-type-permissions", systemManage, admin.UpdateAdminInstanceTypePermissions)
 
 		// 公告管理
-		AdminGroup.GET("/announcements", admin.GetAnnouncements)
-		AdminGroup.POST("/announcements", admin.CreateAnnouncement)
-		AdminGroup.PUT("/announcements/:id", admin.UpdateAnnouncementItem)
-		AdminGroup.DELETE("/announcements/:id", admin.DeleteAnnouncement)
-		AdminGroup.PUT("/announcements/batch-status", admin.BatchUpdateAnnouncementStatus)
-		AdminGroup.POST("/announcements/batch-delete", admin.BatchDeleteAnnouncements)
+		AdminGroup.GET("/announcements", contentView, admin.GetAnnouncements)
+		AdminGroup.POST("/announcements", contentManage, admin.CreateAnnouncement)
+		AdminGroup.PUT("/announcements/:id", contentManage, admin.UpdateAnnouncementItem)
+		AdminGroup.DELETE("/announcements/:id", contentManage, admin.DeleteAnnouncement)
+		AdminGroup.PUT("/announcements/batch-status", contentManage, admin.BatchUpdateAnnouncementStatus)
+		AdminGroup.POST("/announcements/batch-delete", contentManage, admin.BatchDeleteAnnouncements)
 
 		// 邀请码管理
-		AdminGroup.GET("/invite-codes", admin.GetInviteCodeList)
-		AdminGroup.POST("/invite-codes", admin.CreateInviteCode)
-		AdminGroup.POST("/invite-codes/generate", admin.GenerateInviteCode)
-		AdminGroup.GET("/invite-codes/export", admin.ExportInviteCodes)
-		AdminGroup.POST("/invite-codes/batch-delete", admin.BatchDeleteInviteCodes)
-		AdminGroup.DELETE("/invite-codes/:id", admin.DeleteInviteCode)
+		AdminGroup.GET("/invite-codes", contentView, admin.GetInviteCodeList)
+		AdminGroup.POST("/invite-codes", contentManage, admin.CreateInviteCode)
+		AdminGroup.POST("/invite-codes/generate", contentManage, admin.GenerateInviteCode)
+		AdminGroup.GET("/invite-codes/export", contentManage, admin.ExportInviteCodes)
+		AdminGroup.POST("/invite-codes/batch-delete", contentManage, admin.BatchDeleteInviteCodes)
+		AdminGroup.DELETE("/invite-codes/:id", contentManage, admin.DeleteInviteCode)
+
+		// Webhook管理
+		AdminGroup.GET("/webhooks/events", systemManage, admin.GetWebhookEvents)
+		AdminGroup.GET("/webhooks", systemManage, admin.GetWebhooks)
+		AdminGroup.POST("/webhooks", systemManage, admin.CreateWebhook)
+		AdminGroup.PUT("/webhooks/:id", systemManage, admin.UpdateWebhook)
+		AdminGroup.DELETE("/webhooks/:id", systemManage, admin.DeleteWebhook)
+		AdminGroup.POST("/webhooks/:id/test", systemManage, admin.TestWebhook)
+		AdminGroup.GET("/webhooks/:id/deliveries", systemManage, admin.GetWebhookDeliveries)
+		AdminGroup.POST("/webhook-deliveries/:deliveryId/redeliver", systemManage, admin.RedeliverWebhookDelivery)
 
 		// 系统监控
-		AdminGroup.GET("/monitoring/system", admin.GetAdminDashboard)
-		AdminGroup.GET("/monitoring/audit-logs", system.GetOperationLogs)
+		AdminGroup.GET("/monitoring/system", dashboardView, admin.GetAdminDashboard)
+		AdminGroup.GET("/monitoring/audit-logs", auditView, system.GetOperationLogs)
 
 		// 流量同步管理
-		AdminGroup.POST("/traffic/sync/instance/:instance_id", admin.SyncInstanceTraffic)
-		AdminGroup.POST("/traffic/sync/user/:user_id", admin.SyncUserTraffic)
-		AdminGroup.POST("/traffic/sync/provider/:provider_id", admin.SyncProviderTraffic)
-		AdminGroup.POST("/traffic/sync/all", admin.SyncAllTraffic)
+		AdminGroup.POST("/traffic/sync/instance/:instance_id", trafficManage, admin.SyncInstanceTraffic)
+		AdminGroup.POST("/traffic/sync/user/:user_id", trafficManage, admin.SyncUserTraffic)
+		AdminGroup.POST("/traffic/sync/provider/:provider_id", trafficManage, admin.SyncProviderTraffic)
+		AdminGroup.POST("/traffic/sync/all", trafficManage, admin.SyncAllTraffic)
 
 		// 配额管理
-		AdminGroup.GET("/quota/users/:userId", system.GetUserQuotaInfo)
+		AdminGroup.GET("/quota/users/:userId", userView, system.GetUserQuotaInfo)
 
 		// Provider管理
-		AdminGroup.GET("/providers", admin.GetProviderList)
-		AdminGroup.POST("/providers", admin.CreateProvider)
-		AdminGroup.PUT("/providers/:id", admin.UpdateProvider)
-		AdminGroup.DELETE("/providers/:id", admin.DeleteProvider)
-		AdminGroup.POST("/providers/freeze", admin.FreezeProvider)
-		AdminGroup.POST("/providers/unfreeze", admin.UnfreezeProvider)
-		AdminGroup.POST("/providers/test-ssh-connection", admin.TestSSHConnection)
+		AdminGroup.GET("/providers", providerView, admin.GetProviderList)
+		AdminGroup.POST("/providers", providerManage, admin.CreateProvider)
+		AdminGroup.PUT("/providers/:id", providerManage, admin.UpdateProvider)
+		AdminGroup.DELETE("/providers/:id", providerManage, admin.DeleteProvider)
+		AdminGroup.POST("/providers/freeze", providerManage, admin.FreezeProvider)
+		AdminGroup.POST("/providers/unfreeze", providerManage, admin.UnfreezeProvider)
+		AdminGroup.POST("/providers/test-ssh-connection", providerManage, admin.TestSSHConnection)
 
 		// 证书管理
-		AdminGroup.POST("/providers/:id/generate-cert", admin.GenerateProviderCert)
-		AdminGroup.POST("/providers/:id/auto-configure-stream", admin.AutoConfigureProviderStream)
-		AdminGroup.POST("/providers/:id/health-check", admin.CheckProviderHealth)
-		AdminGroup.GET("/providers/:id/status", admin.GetProviderStatus)
+		AdminGroup.POST("/providers/:id/generate-cert", providerManage, admin.GenerateProviderCert)
+		AdminGroup.POST("/providers/:id/auto-configure-stream", providerManage, admin.AutoConfigureProviderStream)
+		AdminGroup.POST("/providers/:id/health-check", providerManage, admin.CheckProviderHealth)
+		AdminGroup.GET("/providers/:id/status", providerView, admin.GetProviderStatus)
 
 		// 配置导出
-		AdminGroup.POST("/providers/export-configs", admin.ExportProviderConfigs)
+		AdminGroup.POST("/providers/export-configs", providerManage, admin.ExportProviderConfigs)
 
 		// 配置任务管理
-		AdminGroup.POST("/providers/auto-configure", config.AutoConfigureProvider)
-		AdminGroup.GET("/configuration-tasks", config.GetConfigurationTasks)
-		AdminGroup.GET("/configuration-tasks/:id", config.GetConfigurationTaskDetail)
-		AdminGroup.POST("/configuration-tasks/:id/cancel", config.CancelConfigurationTask)
+		AdminGroup.POST("/providers/auto-configure", providerManage, config.AutoConfigureProvider)
+		AdminGroup.GET("/configuration-tasks", providerView, config.GetConfigurationTasks)
+		AdminGroup.GET("/configuration-tasks/:id", providerView, config.GetConfigurationTaskDetail)
+		AdminGroup.POST("/configuration-tasks/:id/cancel", providerManage, config.CancelConfigurationTask)
 
 		// 用户任务管理
-		AdminGroup.GET("/tasks", admin.GetAdminTasks)
-		AdminGroup.POST("/tasks/force-stop", admin.ForceStopTask)
-		AdminGroup.GET("/tasks/stats", admin.GetTaskStats)
-		AdminGroup.GET("/tasks/overall-stats", admin.GetTaskOverallStats)
-		AdminGroup.POST("/tasks/:taskId/cancel", admin.CancelUserTaskByAdmin)
+		AdminGroup.GET("/tasks", taskView, admin.GetAdminTasks)
+		AdminGroup.POST("/tasks/force-stop", taskManage, admin.ForceStopTask)
+		AdminGroup.GET("/tasks/stats", taskView, admin.GetTaskStats)
+		AdminGroup.GET("/tasks/overall-stats", taskView, admin.GetTaskOverallStats)
+		AdminGroup.POST("/tasks/:taskId/cancel", taskManage, admin.CancelUserTaskByAdmin)
 
 		// 系统镜像管理
-		AdminGroup.GET("/system-images", system.GetSystemImageList)
-		AdminGroup.POST("/system-images", system.CreateSystemImage)
-		AdminGroup.PUT("/system-images/:id", system.UpdateSystemImage)
-		AdminGroup.DELETE("/system-images/:id", system.DeleteSystemImage)
-		AdminGroup.POST("/system-images/batch-delete", system.BatchDeleteSystemImages)
-		AdminGroup.PUT("/system-images/batch-status", system.BatchUpdateSystemImageStatus)
+		AdminGroup.GET("/system-images", providerView, system.GetSystemImageList)
+		AdminGroup.POST("/system-images", providerManage, system.CreateSystemImage)
+		AdminGroup.PUT("/system-images/:id", providerManage, system.UpdateSystemImage)
+		AdminGroup.DELETE("/system-images/:id", providerManage, system.DeleteSystemImage)
+		AdminGroup.POST("/system-images/batch-delete", providerManage, system.BatchDeleteSystemImages)
+		AdminGroup.PUT("/system-images/batch-status", providerManage, system.BatchUpdateSystemImageStatus)
 
 		// 端口映射管理
-		AdminGroup.GET("/port-mappings", admin.GetPortMappingList)
-		AdminGroup.POST("/port-mappings", admin.CreatePortMapping)                   // 仅支持手动添加单个端口（LXD/Incus/PVE）
-		AdminGroup.DELETE("/port-mappings/:id", admin.DeletePortMapping)             // 仅支持删除手动添加的端口
-		AdminGroup.POST("/port-mappings/batch-delete", admin.BatchDeletePortMapping) // 仅支持删除手动添加的端口
-		AdminGroup.PUT("/providers/:id/port-config", admin.UpdateProviderPortConfig)
-		AdminGroup.GET("/providers/:id/port-usage", admin.GetProviderPortUsage)
-		AdminGroup.GET("/instances/:id/port-mappings", admin.GetInstancePortMappings)
+		AdminGroup.GET("/port-mappings", instanceViewAny, admin.GetPortMappingList)
+		AdminGroup.POST("/port-mappings", instanceManageAny, admin.CreatePortMapping)                   // 仅支持手动添加单个端口（LXD/Incus/PVE）
+		AdminGroup.DELETE("/port-mappings/:id", instanceManageAny, admin.DeletePortMapping)             // 仅支持删除手动添加的端口
+		AdminGroup.POST("/port-mappings/batch-delete", instanceManageAny, admin.BatchDeletePortMapping) // 仅支持删除手动添加的端口
+		AdminGroup.PUT("/providers/:id/port-config", providerManage, admin.UpdateProviderPortConfig)
+		AdminGroup.GET("/providers/:id/port-usage", providerView, admin.GetProviderPortUsage)
+		AdminGroup.GET("/instances/:id/port-mappings", instanceViewAny, admin.GetInstancePortMappings)
+		AdminGroup.GET("/port-mappings/drift", providerView, admin.GetPortDriftReports)
+		AdminGroup.POST("/port-mappings/drift/check", providerView, admin.CheckPortDrift) // 只检测，不改动主机
+		AdminGroup.POST("/port-mappings/drift/fix", providerManage, admin.FixPortDrift)
+		AdminGroup.GET("/domains", instanceViewAny, admin.GetAdminDomains)
+		AdminGroup.DELETE("/domains/:id", instanceManageAny, admin.DeleteAdminDomain)
+		AdminGroup.POST("/providers/:id/domains/sync", providerManage, admin.SyncProviderDomains)
 
 		// 流量管理API
 		adminTrafficAPI := &traffic.AdminTrafficAPI{}
-		AdminGroup.GET("/traffic/overview", adminTrafficAPI.GetSystemTrafficOverview)
-		AdminGroup.GET("/traffic/provider/:providerId", adminTrafficAPI.GetProviderTrafficStats)
-		AdminGroup.GET("/traffic/user/:userId", adminTrafficAPI.GetUserTrafficStats)
-		AdminGroup.GET("/traffic/users/rank", adminTrafficAPI.GetAllUsersTrafficRank)
-		AdminGroup.POST("/traffic/manage", adminTrafficAPI.ManageTrafficLimits)
-		AdminGroup.POST("/traffic/batch-manage", adminTrafficAPI.BatchManageTrafficLimits)
-		AdminGroup.POST("/traffic/batch-sync", adminTrafficAPI.BatchSyncUserTraffic)
+		AdminGroup.GET("/traffic/overview", trafficView, adminTrafficAPI.GetSystemTrafficOverview)
+		AdminGroup.GET("/traffic/provider/:providerId", trafficView, adminTrafficAPI.GetProviderTrafficStats)
+		AdminGroup.GET("/traffic/user/:userId", trafficView, adminTrafficAPI.GetUserTrafficStats)
+		AdminGroup.GET("/traffic/users/rank", trafficView, adminTrafficAPI.GetAllUsersTrafficRank)
+		AdminGroup.POST("/traffic/manage", trafficManage, adminTrafficAPI.ManageTrafficLimits)
+		AdminGroup.POST("/traffic/batch-manage", trafficManage, adminTrafficAPI.BatchManageTrafficLimits)
+		AdminGroup.POST("/traffic/batch-sync", trafficManage, adminTrafficAPI.BatchSyncUserTraffic)
 	}
 }
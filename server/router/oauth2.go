@@ -13,10 +13,11 @@ func InitOAuth2Router(Router *gin.RouterGroup) {
 	OAuth2Router := Router.Group("v1/oauth2")
 	{
 		// 管理员路由（需要管理员权限）
-		OAuth2Router.Use(middleware.RequireAuth(authModel.AuthLevelAdmin)).
+		OAuth2Router.Use(middleware.RequireAuth(authModel.AuthLevelAdmin), middleware.RequireResourcePermission(authModel.PermissionSystemManage)).
 			GET("providers", oauth2Api.GetProviders).                           // 获取所有提供商
 			GET("providers/:id", oauth2Api.GetProvider).                        // 获取单个提供商
 			POST("providers", oauth2Api.CreateProvider).                        // 创建提供商
+			POST("providers/discover", oauth2Api.DiscoverProvider).             // OIDC发现
 			PUT("providers/:id", oauth2Api.UpdateProvider).                     // 更新提供商
 			DELETE("providers/:id", oauth2Api.DeleteProvider).                  // 删除提供商
 			POST("providers/:id/reset-count", oauth2Api.ResetRegistrationCount) // 重置注册计数
@@ -13,11 +13,11 @@ import (
 func InitResourceRouter(Router *gin.RouterGroup) {
 	// 基于资源的细粒度权限路由（虚拟化资源）
 	ResourceGroup := Router.Group("/v1/resources")
-	ResourceGroup.Use(middleware.RequireAuth(authModel.AuthLevelUser))
+	ResourceGroup.Use(middleware.RequireAuth(authModel.AuthLevelUser), middleware.RateLimit(middleware.RateLimitGroupUser))
 	{
 		// 虚拟化资源管理，使用基于资源的权限验证
 		VirtualizationGroup := ResourceGroup.Group("/virtualization")
-		VirtualizationGroup.Use(middleware.RequireResourcePermission("virtualization"))
+		VirtualizationGroup.Use(middleware.RequireResourcePermission(authModel.PermissionProviderView))
 		{
 			VirtualizationGroup.GET("/providers", system.GetProviders)
 		}
@@ -27,7 +27,7 @@ func InitResourceRouter(Router *gin.RouterGroup) {
 // InitProviderRouter Provider API路由
 func InitProviderRouter(Router *gin.RouterGroup) {
 	ProviderGroup := Router.Group("/v1/providers")
-	ProviderGroup.Use(middleware.RequireAuth(authModel.AuthLevelUser))
+	ProviderGroup.Use(middleware.RequireAuth(authModel.AuthLevelUser), middleware.RateLimit(middleware.RateLimitGroupUser))
 	{
 		providerApi := &provider.ProviderApi{}
 		ProviderGroup.GET("/", providerApi.GetProviders)
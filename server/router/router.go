@@ -12,12 +12,37 @@ import (
 func InitAuthRouter(Router *gin.RouterGroup) {
 	AuthRouter := Router.Group("v1/auth")
 	{
-		AuthRouter.POST("login", auth.Login)
+		AuthRouter.POST("login", middleware.LoginRateLimit("login"), auth.Login)
 		AuthRouter.POST("register", auth.Register)
 		AuthRouter.GET("captcha", auth.GetCaptcha)
-		AuthRouter.POST("send-verify-code", auth.SendVerifyCode) // 发送登录验证码
-		AuthRouter.POST("forgot-password", auth.ForgotPassword)
+		AuthRouter.POST("send-verify-code", middleware.LoginRateLimit("verify-code"), auth.SendVerifyCode) // 发送登录验证码
+		AuthRouter.POST("forgot-password", middleware.LoginRateLimit("forgot-password"), auth.ForgotPassword)
 		AuthRouter.POST("reset-password", auth.ResetPassword)
 		AuthRouter.POST("logout", middleware.RequireAuth(authModel.AuthLevelUser), auth.Logout)
+
+		// 两步验证
+		AuthRouter.POST("login/2fa", middleware.LoginRateLimit("login"), auth.LoginTwoFactor)
+		AuthRouter.POST("login/2fa/setup", auth.LoginTwoFactorSetup)
+		TwoFactorRouter := AuthRouter.Group("2fa", middleware.RequireAuth(authModel.AuthLevelUser))
+		{
+			TwoFactorRouter.GET("", auth.GetTwoFactorStatus)
+			TwoFactorRouter.POST("setup", auth.SetupTwoFactor)
+			TwoFactorRouter.POST("enable", auth.EnableTwoFactor)
+			TwoFactorRouter.POST("disable", auth.DisableTwoFactor)
+			TwoFactorRouter.POST("recovery-codes", auth.RegenerateRecoveryCodes)
+		}
+
+		// 通行密钥
+		AuthRouter.POST("login/passkey/begin", auth.LoginPasskeyBegin)
+		AuthRouter.POST("login/passkey/finish", middleware.LoginRateLimit("login"), auth.LoginPasskeyFinish)
+		AuthRouter.POST("login/2fa/passkey/begin", auth.LoginTwoFactorPasskeyBegin)
+		AuthRouter.POST("login/2fa/passkey/finish", middleware.LoginRateLimit("login"), auth.LoginTwoFactorPasskeyFinish)
+		PasskeyRouter := AuthRouter.Group("passkeys", middleware.RequireAuth(authModel.AuthLevelUser))
+		{
+			PasskeyRouter.GET("", auth.GetPasskeys)
+			PasskeyRouter.POST("register/begin", auth.BeginPasskeyRegistration)
+			PasskeyRouter.POST("register/finish", auth.FinishPasskeyRegistration)
+			PasskeyRouter.DELETE(":id", auth.DeletePasskey)
+		}
 	}
 }
@@ -30,7 +30,7 @@ func SetupRouter() *gin.Engine {
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"*"},
-		ExposeHeaders:    []string{"Content-Length", "Authorization"},
+		ExposeHeaders:    []string{"Content-Length", "Authorization", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
 		AllowCredentials: true,
 	}))
 
@@ -52,7 +52,7 @@ func SetupRouter() *gin.Engine {
 
 		// 公开访问路由
 		PublicGroup := ApiGroup.Group("")
-		PublicGroup.Use(middleware.RequireAuth(authModel.AuthLevelPublic))
+		PublicGroup.Use(middleware.RequireAuth(authModel.AuthLevelPublic), middleware.RateLimit(middleware.RateLimitGroupPublic))
 		{
 			PublicGroup.GET("/ping", func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "pong"})
@@ -15,7 +15,7 @@ import (
 // InitUserRouter 用户路由
 func InitUserRouter(Router *gin.RouterGroup) {
 	UserGroup := Router.Group("/v1")
-	UserGroup.Use(middleware.RequireAuth(authModel.AuthLevelUser))
+	UserGroup.Use(middleware.RequireAuth(authModel.AuthLevelUser), middleware.RateLimit(middleware.RateLimitGroupUser))
 	{
 		// 用户管理
 		UserGroup.GET("/user/profile", user.GetUserInfo)
@@ -24,24 +24,92 @@ func InitUserRouter(Router *gin.RouterGroup) {
 		UserGroup.GET("/user/info", user.GetUserInfo)
 		UserGroup.GET("/user/dashboard", user.GetUserDashboard)
 		UserGroup.GET("/user/limits", user.GetUserLimits)
+		UserGroup.GET("/user/permissions", user.GetMyPermissions)
 
 		// 实例管理
 		UserGroup.GET("/user/instances", user.GetUserInstances)
-		UserGroup.POST("/user/instances", user.CreateUserInstance)
+		UserGroup.POST("/user/instances", middleware.RateLimit(middleware.RateLimitGroupTask), user.CreateUserInstance)
 		UserGroup.GET("/user/instances/:id", user.GetUserInstanceDetail)
 		UserGroup.GET("/user/instances/:id/monitoring", user.GetInstanceMonitoring)
 		UserGroup.GET("/user/instances/:id/vnstat/summary", user.GetInstanceVnStatSummary)
 		UserGroup.GET("/user/instances/:id/vnstat/query", user.QueryInstanceVnStatData)
 		UserGroup.GET("/user/instances/:id/vnstat/interfaces", user.GetInstanceVnStatInterfaces)
 		UserGroup.GET("/user/instances/:id/vnstat/dashboard", user.GetInstanceVnStatDashboard)
-		UserGroup.PUT("/user/instances/:id/reset-password", user.ResetInstancePassword)
+		UserGroup.PUT("/user/instances/:id/reset-password", middleware.RateLimit(middleware.RateLimitGroupTask), user.ResetInstancePassword)
 		UserGroup.GET("/user/instances/:id/password/:taskId", user.GetInstanceNewPassword)
 		UserGroup.GET("/user/instances/:id/ports", user.GetInstancePorts)
-		UserGroup.POST("/user/instances/action", user.InstanceAction)
+		UserGroup.POST("/user/instances/:id/ports", middleware.RateLimit(middleware.RateLimitGroupTask), user.CreateInstancePort)
+		UserGroup.DELETE("/user/instances/:id/ports/:portId", user.DeleteInstancePort)
+		UserGroup.GET("/user/instances/:id/security-groups", user.GetInstanceSecurityGroups)
+		UserGroup.PUT("/user/instances/:id/security-groups", middleware.RateLimit(middleware.RateLimitGroupTask), user.SetInstanceSecurityGroups)
+		UserGroup.POST("/user/instances/:id/security-groups/sync", middleware.RateLimit(middleware.RateLimitGroupTask), user.SyncInstanceSecurityGroups)
+		UserGroup.POST("/user/instances/action", middleware.RateLimit(middleware.RateLimitGroupTask), user.InstanceAction)
+		UserGroup.GET("/user/instances/:id/terminal", user.OpenInstanceTerminal)
+		UserGroup.POST("/user/instances/:id/console", user.CreateInstanceConsole)
+		UserGroup.GET("/user/instances/:id/console/ws", user.AttachInstanceConsole)
+
+		// 实例快照
+		UserGroup.GET("/user/instances/:id/snapshots", user.GetInstanceSnapshots)
+		UserGroup.POST("/user/instances/:id/snapshots", middleware.RateLimit(middleware.RateLimitGroupTask), user.CreateInstanceSnapshot)
+		UserGroup.POST("/user/instances/:id/snapshots/:snapshotId/restore", middleware.RateLimit(middleware.RateLimitGroupTask), user.RestoreInstanceSnapshot)
+		UserGroup.DELETE("/user/instances/:id/snapshots/:snapshotId", user.DeleteInstanceSnapshot)
+
+		// 实例备份
+		UserGroup.POST("/user/instances/:id/resize", middleware.RateLimit(middleware.RateLimitGroupTask), user.ResizeInstance)
+		UserGroup.GET("/user/backups", user.GetUserBackups)
+		UserGroup.POST("/user/instances/:id/backups", middleware.RateLimit(middleware.RateLimitGroupTask), user.CreateInstanceBackup)
+		UserGroup.POST("/user/backups/:backupId/restore", middleware.RateLimit(middleware.RateLimitGroupTask), user.RestoreBackup)
+		UserGroup.DELETE("/user/backups/:backupId", user.DeleteBackup)
+		UserGroup.GET("/user/instances/:id/backup-schedule", user.GetBackupSchedule)
+		UserGroup.PUT("/user/instances/:id/backup-schedule", user.SaveBackupSchedule)
+		UserGroup.DELETE("/user/instances/:id/backup-schedule", user.DeleteBackupSchedule)
+
+		// SSH公钥
+		UserGroup.GET("/user/ssh-keys", user.GetSSHKeys)
+		UserGroup.POST("/user/ssh-keys", user.CreateSSHKey)
+		UserGroup.PUT("/user/ssh-keys/:id", user.UpdateSSHKey)
+		UserGroup.DELETE("/user/ssh-keys/:id", user.DeleteSSHKey)
+
+		// 安全组
+		UserGroup.GET("/user/security-groups", user.GetSecurityGroups)
+		UserGroup.POST("/user/security-groups", user.CreateSecurityGroup)
+		UserGroup.PUT("/user/security-groups/:id", user.UpdateSecurityGroup)
+		UserGroup.DELETE("/user/security-groups/:id", user.DeleteSecurityGroup)
+
+		// 个人API令牌
+		UserGroup.GET("/user/api-tokens", user.GetAPITokens)
+		UserGroup.POST("/user/api-tokens", user.CreateAPIToken)
+		UserGroup.DELETE("/user/api-tokens/:id", user.RevokeAPIToken)
+
+		// 登录会话
+		UserGroup.GET("/user/sessions", user.GetSessions)
+		UserGroup.POST("/user/sessions/revoke-others", user.RevokeOtherSessions)
+		UserGroup.DELETE("/user/sessions/:id", user.RevokeSession)
+		UserGroup.POST("/user/ws-ticket", user.CreateWebSocketTicket)
+
+		// 第三方登录身份
+		UserGroup.GET("/user/identities", user.GetIdentities)
+		UserGroup.POST("/user/identities/:providerId/link", user.LinkIdentity)
+		UserGroup.DELETE("/user/identities/:id", user.UnlinkIdentity)
 
 		// 端口映射
 		UserGroup.GET("/user/port-mappings", user.GetUserPortMappings)
 
+		// 域名绑定
+		UserGroup.GET("/user/domains", user.GetUserDomains)
+		UserGroup.POST("/user/domains", user.CreateUserDomain)
+		UserGroup.POST("/user/domains/:id/verify", middleware.RateLimit(middleware.RateLimitGroupTask), user.VerifyUserDomain)
+		UserGroup.DELETE("/user/domains/:id", user.DeleteUserDomain)
+
+		// 站内通知
+		UserGroup.GET("/user/notifications", user.GetNotifications)
+		UserGroup.GET("/user/notifications/unread-count", user.GetUnreadNotificationCount)
+		UserGroup.PUT("/user/notifications/read-all", user.MarkAllNotificationsRead)
+		UserGroup.PUT("/user/notifications/:id/read", user.MarkNotificationRead)
+		UserGroup.DELETE("/user/notifications/:id", user.DeleteNotification)
+		UserGroup.GET("/user/notification-preferences", user.GetNotificationPreferences)
+		UserGroup.PUT("/user/notification-preferences", user.UpdateNotificationPreferences)
+
 		// 资源管理
 		UserGroup.GET("/user/resources/available", user.GetAvailableResources)
 		UserGroup.POST("/user/resources/claim", user.ClaimResource)
@@ -76,7 +144,7 @@ func InitUserRouter(Router *gin.RouterGroup) {
 
 		// 资源管理（普通用户只能管理自己的资源）
 		UserGroup.GET("/instances", user.GetUserInstances)
-		UserGroup.POST("/instances", user.CreateUserInstance)
+		UserGroup.POST("/instances", middleware.RateLimit(middleware.RateLimitGroupTask), user.CreateUserInstance)
 		UserGroup.PUT("/instances/:id", admin.UpdateInstance)
 		UserGroup.DELETE("/instances/:id", admin.DeleteInstance)
 	}
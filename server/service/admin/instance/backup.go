@@ -0,0 +1,111 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+
+	"oneclickvirt/global"
+	providerModel "oneclickvirt/model/provider"
+	userModel "oneclickvirt/model/user"
+	userInstance "oneclickvirt/service/user/instance"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// getBackupOwner 获取备份所属用户ID，管理员的备份操作以备份所有者身份执行
+func getBackupOwner(backupID uint) (uint, error) {
+	var backup providerModel.InstanceBackup
+	if err := global.APP_DB.Select("id", "user_id").First(&backup, backupID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errors.New("备份不存在")
+		}
+		return 0, fmt.Errorf("获取备份信息失败: %v", err)
+	}
+	return backup.UserID, nil
+}
+
+// ListBackups 管理员获取备份列表，userID和instanceID为0时不过滤
+func (s *Service) ListBackups(userID, instanceID uint) ([]providerModel.InstanceBackup, error) {
+	query := global.APP_DB.Model(&providerModel.InstanceBackup{})
+	if userID > 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if instanceID > 0 {
+		query = query.Where("instance_id = ?", instanceID)
+	}
+
+	var backups []providerModel.InstanceBackup
+	if err := query.Order("created_at DESC").Find(&backups).Error; err != nil {
+		return nil, fmt.Errorf("获取备份列表失败: %v", err)
+	}
+	return backups, nil
+}
+
+// CreateInstanceBackup 管理员创建实例备份
+func (s *Service) CreateInstanceBackup(instanceID uint, req userModel.CreateBackupRequest) (*userModel.BackupTaskResponse, error) {
+	ownerID, err := getInstanceOwner(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	global.APP_LOG.Info("管理员创建实例备份",
+		zap.Uint("instanceId", instanceID),
+		zap.Uint("ownerId", ownerID))
+	return userInstance.NewService().CreateInstanceBackup(ownerID, instanceID, req)
+}
+
+// RestoreBackupAsNewInstance 管理员将备份恢复为新实例，新实例归属备份所有者
+func (s *Service) RestoreBackupAsNewInstance(backupID uint) (*userModel.BackupTaskResponse, error) {
+	ownerID, err := getBackupOwner(backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	global.APP_LOG.Info("管理员恢复备份为新实例",
+		zap.Uint("backupId", backupID),
+		zap.Uint("ownerId", ownerID))
+	return userInstance.NewService().RestoreBackupAsNewInstance(ownerID, backupID)
+}
+
+// DeleteBackup 管理员删除备份
+func (s *Service) DeleteBackup(backupID uint) error {
+	ownerID, err := getBackupOwner(backupID)
+	if err != nil {
+		return err
+	}
+
+	global.APP_LOG.Info("管理员删除备份", zap.Uint("backupId", backupID))
+	return userInstance.NewService().DeleteBackup(ownerID, backupID)
+}
+
+// GetBackupSchedule 管理员获取实例定时备份计划
+func (s *Service) GetBackupSchedule(instanceID uint) (*providerModel.BackupSchedule, error) {
+	ownerID, err := getInstanceOwner(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	return userInstance.NewService().GetBackupSchedule(ownerID, instanceID)
+}
+
+// SaveBackupSchedule 管理员设置实例定时备份计划
+func (s *Service) SaveBackupSchedule(instanceID uint, req userModel.BackupScheduleRequest) (*providerModel.BackupSchedule, error) {
+	ownerID, err := getInstanceOwner(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	global.APP_LOG.Info("管理员设置实例定时备份计划",
+		zap.Uint("instanceId", instanceID),
+		zap.String("cron", req.CronExpr))
+	return userInstance.NewService().SaveBackupSchedule(ownerID, instanceID, req)
+}
+
+// DeleteBackupSchedule 管理员删除实例定时备份计划
+func (s *Service) DeleteBackupSchedule(instanceID uint) error {
+	ownerID, err := getInstanceOwner(instanceID)
+	if err != nil {
+		return err
+	}
+	return userInstance.NewService().DeleteBackupSchedule(ownerID, instanceID)
+}
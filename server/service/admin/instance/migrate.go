@@ -0,0 +1,99 @@
+package instance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	providerModel "oneclickvirt/model/provider"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// MigrateInstance 管理员将实例迁移到同类型的其他Provider
+// 任务挂在目标Provider上调度，源Provider冻结或过期时仍可执行
+func (s *Service) MigrateInstance(instanceID uint, req adminModel.MigrateInstanceRequest) (*adminModel.MigrateTaskResponse, error) {
+	var instance providerModel.Instance
+	if err := global.APP_DB.First(&instance, instanceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("实例不存在")
+		}
+		return nil, fmt.Errorf("获取实例信息失败: %v", err)
+	}
+
+	if instance.Status != "running" && instance.Status != "stopped" {
+		return nil, errors.New("实例当前状态不允许迁移")
+	}
+	if instance.ProviderID == req.TargetProviderID {
+		return nil, errors.New("目标节点与实例当前节点相同")
+	}
+
+	var source, target providerModel.Provider
+	if err := global.APP_DB.First(&source, instance.ProviderID).Error; err != nil {
+		return nil, fmt.Errorf("获取源节点信息失败: %v", err)
+	}
+	if err := global.APP_DB.First(&target, req.TargetProviderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("目标节点不存在")
+		}
+		return nil, fmt.Errorf("获取目标节点信息失败: %v", err)
+	}
+
+	if target.Type != source.Type {
+		return nil, fmt.Errorf("只能迁移到同类型节点（源节点类型 %s，目标节点类型 %s）", source.Type, target.Type)
+	}
+	if target.Architecture != source.Architecture {
+		return nil, fmt.Errorf("目标节点架构 %s 与源节点架构 %s 不一致", target.Architecture, source.Architecture)
+	}
+	if target.Status == "inactive" || target.IsFrozen {
+		return nil, errors.New("目标节点不可用")
+	}
+	if target.ExpiresAt != nil && target.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("目标节点已过期")
+	}
+	if instance.InstanceType == "vm" && !target.VirtualMachineEnabled {
+		return nil, errors.New("目标节点不支持虚拟机实例")
+	}
+	if instance.InstanceType != "vm" && !target.ContainerEnabled {
+		return nil, errors.New("目标节点不支持容器实例")
+	}
+
+	// 迁移期间不允许同一实例的其他任务并行执行
+	var count int64
+	global.APP_DB.Model(&adminModel.Task{}).
+		Where("instance_id = ? AND status IN (?)", instance.ID, []string{"pending", "running"}).
+		Count(&count)
+	if count > 0 {
+		return nil, errors.New("实例有任务正在进行，请稍后再试")
+	}
+
+	taskData, err := json.Marshal(adminModel.MigrateTaskRequest{
+		InstanceID:       instance.ID,
+		SourceProviderID: source.ID,
+		TargetProviderID: target.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("序列化任务数据失败: %v", err)
+	}
+
+	task, err := s.taskService.CreateTask(instance.UserID, &target.ID, &instance.ID, "migrate", string(taskData), 0)
+	if err != nil {
+		return nil, fmt.Errorf("创建迁移任务失败: %v", err)
+	}
+
+	global.APP_LOG.Info("管理员创建实例迁移任务",
+		zap.Uint("instanceId", instance.ID),
+		zap.String("instanceName", instance.Name),
+		zap.String("sourceProvider", source.Name),
+		zap.String("targetProvider", target.Name),
+		zap.Uint("taskId", task.ID))
+
+	return &adminModel.MigrateTaskResponse{
+		InstanceID: instance.ID,
+		TaskID:     task.ID,
+	}, nil
+}
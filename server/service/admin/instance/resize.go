@@ -0,0 +1,29 @@
+package instance
+
+import (
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/provider"
+	userInstance "oneclickvirt/service/user/instance"
+
+	"go.uber.org/zap"
+)
+
+// ResizeInstance 管理员调整实例规格，以实例所有者身份创建任务，仍受所有者配额限制
+func (s *Service) ResizeInstance(instanceID uint, req adminModel.ResizeInstanceRequest) (*userModel.ResizeTaskResponse, error) {
+	ownerID, err := getInstanceOwner(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	global.APP_LOG.Info("管理员调整实例规格",
+		zap.Uint("instanceId", instanceID),
+		zap.Uint("ownerId", ownerID))
+	return userInstance.NewService().CreateResizeTask(ownerID, instanceID, provider.ResizeSpec{
+		CPU:       req.CPU,
+		Memory:    req.Memory,
+		Disk:      req.Disk,
+		Bandwidth: req.Bandwidth,
+	})
+}
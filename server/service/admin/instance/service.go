@@ -250,7 +250,7 @@ func (s *Service) CreateInstance(req admin.CreateInstanceRequest) error {
 	dbService := database.GetDatabaseService()
 
 	// 在单个事务中创建实例并更新配额
-	return dbService.ExecuteTransaction(context.Background(), func(tx *gorm.DB) error {
+	err = dbService.ExecuteTransaction(context.Background(), func(tx *gorm.DB) error {
 		// 创建实例
 		if err := tx.Create(&instance).Error; err != nil {
 			return fmt.Errorf("创建实例失败: %v", err)
@@ -278,6 +278,14 @@ func (s *Service) CreateInstance(req admin.CreateInstanceRequest) error {
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	// 端口记录提交后再下发nftables表，实例尚无内网IP时其规则会在IP写入后补齐
+	portMappingService := resources.PortMappingService{}
+	portMappingService.RefreshNftablesMappings(context.Background(), provider.ID)
+	return nil
 }
 
 // UpdateInstance 更新实例
@@ -0,0 +1,74 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+
+	"oneclickvirt/global"
+	providerModel "oneclickvirt/model/provider"
+	userModel "oneclickvirt/model/user"
+	userInstance "oneclickvirt/service/user/instance"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// getInstanceOwner 获取实例所属用户ID，管理员的快照操作以实例所有者身份创建任务
+func getInstanceOwner(instanceID uint) (uint, error) {
+	var instance providerModel.Instance
+	if err := global.APP_DB.Select("id", "user_id").First(&instance, instanceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errors.New("实例不存在")
+		}
+		return 0, fmt.Errorf("获取实例信息失败: %v", err)
+	}
+	return instance.UserID, nil
+}
+
+// ListInstanceSnapshots 管理员获取实例快照列表
+func (s *Service) ListInstanceSnapshots(instanceID uint) (*userModel.SnapshotListResponse, error) {
+	ownerID, err := getInstanceOwner(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	return userInstance.NewService().ListInstanceSnapshots(ownerID, instanceID)
+}
+
+// CreateInstanceSnapshot 管理员创建实例快照
+func (s *Service) CreateInstanceSnapshot(instanceID uint, req userModel.CreateSnapshotRequest) (*userModel.SnapshotTaskResponse, error) {
+	ownerID, err := getInstanceOwner(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	global.APP_LOG.Info("管理员创建实例快照",
+		zap.Uint("instanceId", instanceID),
+		zap.Uint("ownerId", ownerID))
+	return userInstance.NewService().CreateInstanceSnapshot(ownerID, instanceID, req)
+}
+
+// RestoreInstanceSnapshot 管理员恢复实例快照
+func (s *Service) RestoreInstanceSnapshot(instanceID, snapshotID uint) (*userModel.SnapshotTaskResponse, error) {
+	ownerID, err := getInstanceOwner(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	global.APP_LOG.Info("管理员恢复实例快照",
+		zap.Uint("instanceId", instanceID),
+		zap.Uint("snapshotId", snapshotID))
+	return userInstance.NewService().RestoreInstanceSnapshot(ownerID, instanceID, snapshotID)
+}
+
+// DeleteInstanceSnapshot 管理员删除实例快照
+func (s *Service) DeleteInstanceSnapshot(instanceID, snapshotID uint) (*userModel.SnapshotTaskResponse, error) {
+	ownerID, err := getInstanceOwner(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	global.APP_LOG.Info("管理员删除实例快照",
+		zap.Uint("instanceId", instanceID),
+		zap.Uint("snapshotId", snapshotID))
+	return userInstance.NewService().DeleteInstanceSnapshot(ownerID, instanceID, snapshotID)
+}
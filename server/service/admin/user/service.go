@@ -4,15 +4,14 @@ import (
 	"context"
 	"crypto/rand"
 	"errors"
-	"fmt"
 	"math/big"
 	auth2 "oneclickvirt/service/auth"
 	"oneclickvirt/service/database"
+	"oneclickvirt/service/notification"
 
 	"oneclickvirt/config"
 	"oneclickvirt/global"
 	"oneclickvirt/model/admin"
-	"oneclickvirt/model/auth"
 	"oneclickvirt/model/common"
 	providerModel "oneclickvirt/model/provider"
 	userModel "oneclickvirt/model/user"
@@ -75,9 +74,19 @@ func (s *Service) GetUserList(req admin.UserListRequest) ([]admin.UserManageResp
 }
 
 // CreateUser 创建用户
-func (s *Service) CreateUser(req admin.CreateUserRequest) error {
+func (s *Service) CreateUser(req admin.CreateUserRequest, operatorID uint) error {
 	global.APP_LOG.Debug("开始创建用户", zap.String("username", utils.TruncateString(req.Username, 32)))
 
+	if req.UserType != "user" && req.UserType != "admin" {
+		return common.NewError(common.CodeValidationError, "无效的用户类型")
+	}
+	if req.UserType == "admin" && !isAdminOperator(operatorID) {
+		global.APP_LOG.Warn("用户创建失败：非管理员不能创建管理员账户",
+			zap.Uint("operatorID", operatorID),
+			zap.String("username", utils.TruncateString(req.Username, 32)))
+		return common.NewError(common.CodeForbidden, "只有管理员可以创建管理员账户")
+	}
+
 	var existingUser userModel.User
 	if err := global.APP_DB.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
 		global.APP_LOG.Warn("用户创建失败：用户名已存在", zap.String("username", utils.TruncateString(req.Username, 32)))
@@ -160,8 +169,27 @@ func (s *Service) UpdateUser(req admin.UpdateUserRequest, currentUserID uint) er
 		return err
 	}
 
+	// 通过角色获得用户管理权限的操作者只能管理普通用户，不能提升用户类型或分配角色
+	operatorIsAdmin := isAdminOperator(currentUserID)
+	if user.UserType == "admin" && !operatorIsAdmin {
+		global.APP_LOG.Warn("用户更新失败：非管理员不能修改管理员账户",
+			zap.Uint("userID", req.ID),
+			zap.Uint("currentUserID", currentUserID))
+		return common.NewError(common.CodeForbidden, "只有管理员可以修改管理员账户")
+	}
+	typeChanged := req.UserType != "" && req.UserType != user.UserType
+	if (typeChanged || req.RoleID > 0) && !operatorIsAdmin {
+		global.APP_LOG.Warn("用户更新失败：非管理员不能修改用户类型或角色",
+			zap.Uint("userID", req.ID),
+			zap.Uint("currentUserID", currentUserID))
+		return common.NewError(common.CodeForbidden, "只有管理员可以修改用户类型和角色")
+	}
+	if typeChanged && req.UserType != "user" && req.UserType != "admin" {
+		return common.NewError(common.CodeValidationError, "无效的用户类型")
+	}
+
 	// 防止管理员修改自己的用户类型
-	if req.ID == currentUserID && req.UserType != "" && req.UserType != user.UserType {
+	if req.ID == currentUserID && typeChanged {
 		global.APP_LOG.Warn("用户更新失败：不能修改当前登录用户的用户类型",
 			zap.Uint("userID", req.ID),
 			zap.String("currentType", user.UserType),
@@ -218,27 +246,20 @@ func (s *Service) UpdateUser(req admin.UpdateUserRequest, currentUserID uint) er
 		user.Status = req.Status
 	}
 
-	// 处理角色相关的用户类型更新
+	if typeChanged {
+		user.UserType = req.UserType
+	}
+
+	// 角色只决定操作权限，不影响用户类型；由角色服务校验操作者拥有所分配角色的全部权限
 	if req.RoleID > 0 {
-		var role auth.Role
-		if err := global.APP_DB.First(&role, req.RoleID).Error; err != nil {
-			global.APP_LOG.Warn("用户更新失败：角色不存在",
+		roleService := auth2.RoleService{}
+		if _, err := roleService.SetUserRoles(req.ID, []uint{req.RoleID}, currentUserID); err != nil {
+			global.APP_LOG.Warn("用户更新失败：设置角色失败",
 				zap.Uint("userID", req.ID),
-				zap.Uint("roleID", req.RoleID))
-			return common.NewError(common.CodeRoleNotFound, "角色不存在")
-		}
-
-		// 只有在不是修改自己的情况下才允许修改用户类型
-		if req.ID != currentUserID {
-			user.UserType = role.Code
-
-			// 更新用户角色关联
-			global.APP_DB.Model(&user).Association("Roles").Clear()
-			global.APP_DB.Model(&user).Association("Roles").Append(&role)
+				zap.Uint("roleID", req.RoleID),
+				zap.Error(err))
+			return err
 		}
-	} else if req.UserType != "" && req.ID != currentUserID {
-		// 直接指定的用户类型（仅在不是修改自己时允许）
-		user.UserType = req.UserType
 	}
 
 	// 保存更新
@@ -261,6 +282,30 @@ func (s *Service) UpdateUser(req admin.UpdateUserRequest, currentUserID uint) er
 	return nil
 }
 
+// isAdminOperator 操作者是否为管理员，通过角色获得用户管理权限的操作者不是管理员
+func isAdminOperator(operatorID uint) bool {
+	permissionService := auth2.PermissionService{}
+	return permissionService.VerifyAdminPrivilege(operatorID)
+}
+
+// CheckManageable 检查操作者能否管理指定用户，只有管理员可以操作管理员账户
+func (s *Service) CheckManageable(operatorID uint, userIDs ...uint) error {
+	if len(userIDs) == 0 || isAdminOperator(operatorID) {
+		return nil
+	}
+	var adminCount int64
+	if err := global.APP_DB.Model(&userModel.User{}).Where("id IN ? AND user_type = ?", userIDs, "admin").Count(&adminCount).Error; err != nil {
+		return err
+	}
+	if adminCount > 0 {
+		global.APP_LOG.Warn("非管理员尝试操作管理员账户",
+			zap.Uint("operatorID", operatorID),
+			zap.Uints("userIDs", userIDs))
+		return common.NewError(common.CodeForbidden, "只有管理员可以操作管理员账户")
+	}
+	return nil
+}
+
 // DeleteUser 删除用户
 func (s *Service) DeleteUser(userID uint) error {
 	global.APP_LOG.Debug("开始删除用户", zap.Uint("userID", userID))
@@ -277,6 +322,10 @@ func (s *Service) DeleteUser(userID uint) error {
 	// 使用数据库抽象层删除
 	dbService := database.GetDatabaseService()
 	if err := dbService.ExecuteTransaction(context.Background(), func(tx *gorm.DB) error {
+		// 释放绑定的第三方登录身份，便于这些账号重新注册或绑定到其他用户
+		if err := tx.Where("user_id = ?", userID).Delete(&userModel.UserIdentity{}).Error; err != nil {
+			return err
+		}
 		return tx.Delete(&userModel.User{}, userID).Error
 	}); err != nil {
 		global.APP_LOG.Error("用户删除失败", zap.Uint("userID", userID), zap.Error(err))
@@ -616,7 +665,7 @@ func (s *Service) ResetUserPassword(userID uint) (string, error) {
 	}
 
 	// 更新密码
-	if err := global.APP_DB.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+	if err := global.APP_DB.Model(&user).Updates(map[string]interface{}{"password": string(hashedPassword), "password_generated": false}).Error; err != nil {
 		return "", err
 	}
 
@@ -655,12 +704,15 @@ func (s *Service) ResetUserPasswordAndNotify(userID uint) error {
 	}
 
 	// 更新密码
-	if err := global.APP_DB.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+	if err := global.APP_DB.Model(&user).Updates(map[string]interface{}{"password": string(hashedPassword), "password_generated": false}).Error; err != nil {
 		return err
 	}
 
 	// 发送新密码到用户绑定的通信渠道
-	if err := s.sendPasswordToUser(&user, newPassword); err != nil {
+	if err := notification.SendToUser(&user, notification.TemplatePasswordReset, map[string]interface{}{
+		"Username": user.Username,
+		"Password": newPassword,
+	}); err != nil {
 		// 记录日志但不阻止密码重置完成
 		global.APP_LOG.Error("发送新密码失败",
 			zap.Uint("user_id", userID),
@@ -678,94 +730,6 @@ func (s *Service) ResetUserPasswordAndNotify(userID uint) error {
 	return nil
 }
 
-// sendPasswordToUser 发送新密码到用户绑定的通信渠道
-func (s *Service) sendPasswordToUser(user *userModel.User, newPassword string) error {
-	// 优先级：邮箱 > Telegram > QQ > 手机号
-
-	if user.Email != "" {
-		return s.sendPasswordByEmail(user.Email, user.Username, newPassword)
-	}
-
-	if user.Telegram != "" {
-		return s.sendPasswordByTelegram(user.Telegram, user.Username, newPassword)
-	}
-
-	if user.QQ != "" {
-		return s.sendPasswordByQQ(user.QQ, user.Username, newPassword)
-	}
-
-	if user.Phone != "" {
-		return s.sendPasswordBySMS(user.Phone, user.Username, newPassword)
-	}
-
-	return errors.New("用户未绑定任何通信渠道")
-}
-
-// sendPasswordByEmail 通过邮箱发送新密码
-func (s *Service) sendPasswordByEmail(email, username, newPassword string) error {
-	// 检查邮箱配置是否可用
-	if !s.isEmailConfigured() {
-		global.APP_LOG.Warn("邮箱服务未配置，跳过发送",
-			zap.String("email", email),
-			zap.String("username", username))
-		return nil
-	}
-
-	// 构建邮件内容
-	subject := "密码重置通知"
-	body := fmt.Sprintf(`
-尊敬的用户 %s：
-
-您的密码已由管理员重置，新密码为：%s
-
-请使用新密码登录系统，并建议您尽快修改密码。
-
-系统自动发送，请勿回复。
-`, username, newPassword)
-
-	// 发送邮件
-	err := s.sendEmail(email, subject, body)
-	if err != nil {
-		global.APP_LOG.Error("发送密码重置邮件失败",
-			zap.String("email", email),
-			zap.String("username", username),
-			zap.Error(err))
-		return fmt.Errorf("邮件发送失败: %w", err)
-	}
-
-	global.APP_LOG.Info("管理员操作：成功发送新密码到邮箱",
-		zap.String("email", email),
-		zap.String("username", username))
-	return nil
-}
-
-// sendPasswordByTelegram 通过Telegram发送新密码
-func (s *Service) sendPasswordByTelegram(telegram, username, newPassword string) error {
-	// TODO: 实现Telegram发送功能
-	global.APP_LOG.Info("管理员操作：模拟发送新密码到Telegram",
-		zap.String("telegram", telegram),
-		zap.String("username", username))
-	return nil
-}
-
-// sendPasswordByQQ 通过QQ发送新密码
-func (s *Service) sendPasswordByQQ(qq, username, newPassword string) error {
-	// TODO: 实现QQ发送功能
-	global.APP_LOG.Info("管理员操作：模拟发送新密码到QQ",
-		zap.String("qq", qq),
-		zap.String("username", username))
-	return nil
-}
-
-// sendPasswordBySMS 通过短信发送新密码
-func (s *Service) sendPasswordBySMS(phone, username, newPassword string) error {
-	// TODO: 实现短信发送功能
-	global.APP_LOG.Info("管理员操作：模拟发送新密码到手机",
-		zap.String("phone", phone),
-		zap.String("username", username))
-	return nil
-}
-
 // generateRandomPassword 生成随机密码（仅包含数字和大小写英文字母，长度不低于8位）
 func (s *Service) generateRandomPassword(length int) string {
 	if length < 8 {
@@ -847,29 +811,6 @@ func (s *Service) syncSingleUserResourceLimits(level int, userID uint) error {
 	return nil
 }
 
-// isEmailConfigured 检查邮箱配置是否可用
-func (s *Service) isEmailConfigured() bool {
-	// 检查系统配置中是否配置了邮箱服务
-	var emailConfig admin.SystemConfig
-	if err := global.APP_DB.Where("key = ?", "email_enabled").First(&emailConfig).Error; err != nil {
-		return false
-	}
-	return emailConfig.Value == "true"
-}
-
-// sendEmail 发送邮件的基础函数
-func (s *Service) sendEmail(to, subject, body string) error {
-	// 这里应该集成真正的邮件服务，如SMTP
-	// 目前只做记录，实际项目需要根据配置的邮件服务商实现
-	global.APP_LOG.Info("邮件发送请求",
-		zap.String("to", to),
-		zap.String("subject", subject))
-
-	// 模拟邮件发送成功
-	// 在实际实现中，这里会调用邮件服务API
-	return nil
-}
-
 // getCurrentAdminID 获取当前管理员ID
 // 在实际实现中，这应该从HTTP请求上下文中获取
 func (s *Service) getCurrentAdminID() uint {
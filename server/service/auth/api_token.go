@@ -0,0 +1,327 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	userModel "oneclickvirt/model/user"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	APITokenPrefix        = "ocv_"      // 个人API令牌前缀，用于和JWT区分
+	maxAPITokensPerUser   = 20          // 每个用户最多保留的有效令牌数量
+	apiTokenTouchInterval = time.Minute // 最近使用时间的更新间隔，避免每次请求都写库
+)
+
+// APITokenScopes 可选的API令牌权限范围，write包含同一资源的read
+var APITokenScopes = []userModel.APITokenScopeInfo{
+	{Scope: "instances:read", Description: "查看实例、快照、备份、任务、端口映射、安全组、域名绑定和可用资源"},
+	{Scope: "instances:write", Description: "创建、操作和删除实例，管理快照、备份、安全组和域名绑定，使用终端和控制台"},
+	{Scope: "traffic:read", Description: "查看流量统计"},
+	{Scope: "account:read", Description: "查看个人资料、SSH公钥和站内通知"},
+	{Scope: "account:write", Description: "修改个人资料、SSH公钥和站内通知"},
+	{Scope: "admin:read", Description: "查看管理接口数据（需要管理后台权限，仍按角色权限校验）"},
+	{Scope: "admin:write", Description: "调用管理接口修改数据（需要管理后台权限，仍按角色权限校验）"},
+}
+
+// apiTokenRoutes 路由前缀与权限资源的对应关系
+// 未列出的路由（如修改密码、两步验证、令牌管理）不允许使用API令牌访问
+var apiTokenRoutes = []struct {
+	Prefix   string
+	Resource string
+}{
+	{"/api/v1/user/instances", "instances"},
+	{"/api/v1/instances", "instances"},
+	{"/api/v1/user/backups", "instances"},
+	{"/api/v1/user/tasks", "instances"},
+	{"/api/v1/user/port-mappings", "instances"},
+	{"/api/v1/user/security-groups", "instances"},
+	{"/api/v1/user/domains", "instances"},
+	{"/api/v1/user/resources", "instances"},
+	{"/api/v1/user/providers", "instances"},
+	{"/api/v1/user/images", "instances"},
+	{"/api/v1/user/instance-type-permissions", "instances"},
+	{"/api/v1/user/instance-config", "instances"},
+	{"/api/v1/user/traffic", "traffic"},
+	{"/api/v1/user/profile", "account"},
+	{"/api/v1/user/info", "account"},
+	{"/api/v1/user/dashboard", "account"},
+	{"/api/v1/user/limits", "account"},
+	{"/api/v1/user/ssh-keys", "account"},
+	{"/api/v1/user/notifications", "account"},
+	{"/api/v1/user/notification-preferences", "account"},
+	{"/api/v1/admin", "admin"},
+}
+
+// APITokenService 个人API令牌服务
+type APITokenService struct{}
+
+// RequiredAPITokenScope 获取访问路由所需的权限范围
+// route为注册的路由模板（gin.Context.FullPath），WebSocket连接（终端、控制台）视为写操作
+func RequiredAPITokenScope(method, route string, websocket bool) (string, bool) {
+	for _, r := range apiTokenRoutes {
+		if route != r.Prefix && !strings.HasPrefix(route, r.Prefix+"/") {
+			continue
+		}
+		if (method == http.MethodGet || method == http.MethodHead) && !websocket {
+			return r.Resource + ":read", true
+		}
+		return r.Resource + ":write", true
+	}
+	return "", false
+}
+
+// HasAPITokenScope 检查令牌是否拥有所需的权限范围
+func HasAPITokenScope(token *userModel.UserAPIToken, required string) bool {
+	writeScope := strings.TrimSuffix(required, ":read") + ":write"
+	for _, scope := range splitCommaList(token.Scopes) {
+		if scope == required || scope == writeScope {
+			return true
+		}
+	}
+	return false
+}
+
+// ListTokens 获取用户的API令牌列表
+func (s *APITokenService) ListTokens(userID uint) (*userModel.APITokenListResponse, error) {
+	var tokens []userModel.UserAPIToken
+	if err := global.APP_DB.Where("user_id = ?", userID).Order("id DESC").Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("获取API令牌列表失败: %v", err)
+	}
+
+	resp := &userModel.APITokenListResponse{
+		List:   make([]userModel.APITokenInfo, 0, len(tokens)),
+		Scopes: APITokenScopes,
+	}
+	for i := range tokens {
+		resp.List = append(resp.List, toAPITokenInfo(&tokens[i]))
+	}
+	return resp, nil
+}
+
+// CreateToken 创建API令牌，明文令牌只在此时返回
+func (s *APITokenService) CreateToken(userID uint, req userModel.CreateAPITokenRequest) (*userModel.CreateAPITokenResponse, error) {
+	scopes, err := s.normalizeScopes(userID, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	allowedIPs, err := normalizeAllowedIPs(req.AllowedIPs)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := global.APP_DB.Model(&userModel.UserAPIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("检查API令牌数量失败: %v", err)
+	}
+	if count >= maxAPITokensPerUser {
+		return nil, common.NewError(common.CodeConflict, fmt.Sprintf("有效API令牌数量已达上限（%d个）", maxAPITokensPerUser))
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("生成API令牌失败: %v", err)
+	}
+	raw := APITokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	token := userModel.UserAPIToken{
+		UserID:      userID,
+		Name:        strings.TrimSpace(req.Name),
+		TokenPrefix: raw[:12],
+		TokenHash:   hashAPIToken(raw),
+		Scopes:      strings.Join(scopes, ","),
+		AllowedIPs:  strings.Join(allowedIPs, ","),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := global.APP_DB.Create(&token).Error; err != nil {
+		return nil, fmt.Errorf("保存API令牌失败: %v", err)
+	}
+
+	global.APP_LOG.Info("创建个人API令牌",
+		zap.Uint("userID", userID),
+		zap.Uint("tokenID", token.ID),
+		zap.String("scopes", token.Scopes))
+
+	return &userModel.CreateAPITokenResponse{
+		Token:    raw,
+		APIToken: toAPITokenInfo(&token),
+	}, nil
+}
+
+// RevokeToken 撤销用户的API令牌
+func (s *APITokenService) RevokeToken(userID, tokenID uint) error {
+	var token userModel.UserAPIToken
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", tokenID, userID).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return common.NewError(common.CodeNotFound, "API令牌不存在")
+		}
+		return fmt.Errorf("获取API令牌失败: %v", err)
+	}
+	if token.RevokedAt != nil {
+		return nil
+	}
+
+	if err := global.APP_DB.Model(&token).Update("revoked_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("撤销API令牌失败: %v", err)
+	}
+	global.APP_LOG.Info("撤销个人API令牌", zap.Uint("userID", userID), zap.Uint("tokenID", tokenID))
+	return nil
+}
+
+// Authenticate 校验API令牌是否有效以及客户端IP是否在白名单内
+func (s *APITokenService) Authenticate(raw, clientIP string) (*userModel.UserAPIToken, error) {
+	var token userModel.UserAPIToken
+	if err := global.APP_DB.Where("token_hash = ?", hashAPIToken(raw)).First(&token).Error; err != nil {
+		return nil, common.NewError(common.CodeUnauthorized, "无效的API令牌")
+	}
+	if token.RevokedAt != nil {
+		return nil, common.NewError(common.CodeUnauthorized, "API令牌已撤销")
+	}
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, common.NewError(common.CodeUnauthorized, "API令牌已过期")
+	}
+	if !ipAllowed(token.AllowedIPs, clientIP) {
+		global.APP_LOG.Warn("API令牌来源IP不在白名单内",
+			zap.Uint("tokenID", token.ID),
+			zap.String("clientIP", clientIP))
+		return nil, common.NewError(common.CodeForbidden, "当前IP不允许使用该API令牌")
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > apiTokenTouchInterval || token.LastUsedIP != clientIP {
+		global.APP_DB.Model(&token).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": clientIP,
+		})
+	}
+	return &token, nil
+}
+
+// normalizeScopes 校验并去重权限范围，管理范围只允许管理员创建
+func (s *APITokenService) normalizeScopes(userID uint, scopes []string) ([]string, error) {
+	valid := make(map[string]bool, len(APITokenScopes))
+	for _, info := range APITokenScopes {
+		valid[info.Scope] = true
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || seen[scope] {
+			continue
+		}
+		if !valid[scope] {
+			return nil, common.NewError(common.CodeValidationError, fmt.Sprintf("不支持的权限范围: %s", scope))
+		}
+		if strings.HasPrefix(scope, "admin:") {
+			permissionService := PermissionService{}
+			perms, err := permissionService.GetUserActionPermissions(userID)
+			if err != nil || len(perms) == 0 {
+				return nil, common.NewError(common.CodeForbidden, "只有拥有管理后台权限的用户可以创建管理权限范围的API令牌")
+			}
+		}
+		seen[scope] = true
+		result = append(result, scope)
+	}
+	if len(result) == 0 {
+		return nil, common.NewError(common.CodeValidationError, "至少选择一个权限范围")
+	}
+	return result, nil
+}
+
+// normalizeAllowedIPs 校验IP白名单，支持单个IP和CIDR
+func normalizeAllowedIPs(entries []string) ([]string, error) {
+	var result []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, common.NewError(common.CodeValidationError, fmt.Sprintf("无效的CIDR: %s", entry))
+			}
+			entry = ipNet.String()
+		} else {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, common.NewError(common.CodeValidationError, fmt.Sprintf("无效的IP地址: %s", entry))
+			}
+			entry = ip.String()
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// ipAllowed 检查IP是否在白名单内，白名单为空时不限制
+func ipAllowed(allowedIPs, clientIP string) bool {
+	entries := splitCommaList(allowedIPs)
+	if len(entries) == 0 {
+		return true
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry, "/") {
+			if _, ipNet, err := net.ParseCIDR(entry); err == nil && ipNet.Contains(ip) {
+				return true
+			}
+		} else if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func toAPITokenInfo(token *userModel.UserAPIToken) userModel.APITokenInfo {
+	return userModel.APITokenInfo{
+		ID:          token.ID,
+		Name:        token.Name,
+		TokenPrefix: token.TokenPrefix,
+		Scopes:      splitCommaList(token.Scopes),
+		AllowedIPs:  splitCommaList(token.AllowedIPs),
+		ExpiresAt:   token.ExpiresAt,
+		LastUsedAt:  token.LastUsedAt,
+		LastUsedIP:  token.LastUsedIP,
+		RevokedAt:   token.RevokedAt,
+		CreatedAt:   token.CreatedAt,
+	}
+}
+
+func hashAPIToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func splitCommaList(value string) []string {
+	result := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
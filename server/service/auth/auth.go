@@ -7,13 +7,12 @@ import (
 	"fmt"
 	"math/big"
 	mathRand "math/rand"
-	"net/smtp"
 	"oneclickvirt/service/database"
+	"oneclickvirt/service/notification"
 	"time"
 
 	"oneclickvirt/config"
 	"oneclickvirt/global"
-	adminModel "oneclickvirt/model/admin"
 	"oneclickvirt/model/auth"
 	"oneclickvirt/model/common"
 	"oneclickvirt/model/system"
@@ -51,14 +50,8 @@ func (s *AuthService) Login(req auth.LoginRequest) (*userModel.User, string, err
 
 // loginWithPassword 用户名密码登录
 func (s *AuthService) loginWithPassword(req auth.LoginRequest) (*userModel.User, string, error) {
-	// 验证图形验证码（开发模式下可以跳过）
-	if req.CaptchaId != "" && req.Captcha != "" {
-		if err := s.verifyCaptcha(req.CaptchaId, req.Captcha); err != nil {
-			return nil, "", common.NewError(common.CodeCaptchaInvalid)
-		}
-	} else if global.APP_CONFIG.System.Env != "development" {
-		// 只在非开发环境下强制要求验证码
-		return nil, "", common.NewError(common.CodeCaptchaRequired)
+	if err := s.CheckLoginCaptcha(req.CaptchaId, req.Captcha); err != nil {
+		return nil, "", err
 	}
 
 	// 检查必要参数
@@ -66,10 +59,17 @@ func (s *AuthService) loginWithPassword(req auth.LoginRequest) (*userModel.User,
 		return nil, "", common.NewError(common.CodeInvalidParam, "用户名和密码不能为空")
 	}
 
+	// 同一用户名短时间内密码错误过多时拒绝继续尝试
+	protectionService := LoginProtectionService{}
+	if err := protectionService.CheckUsername(req.Username); err != nil {
+		return nil, "", err
+	}
+
 	// 先查询用户是否存在
 	var user userModel.User
 	if err := global.APP_DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
 		global.APP_LOG.Debug("用户登录失败", zap.String("username", utils.SanitizeUserInput(req.Username)), zap.String("error", "record not found"))
+		protectionService.RecordPasswordFailure(req.Username, nil)
 		return nil, "", common.NewError(common.CodeInvalidCredentials)
 	}
 
@@ -79,22 +79,27 @@ func (s *AuthService) loginWithPassword(req auth.LoginRequest) (*userModel.User,
 		return nil, "", common.NewError(common.CodeUserDisabled)
 	}
 
+	// 检查账户是否因密码错误次数过多被锁定
+	if err := protectionService.CheckAccountLock(&user); err != nil {
+		global.APP_LOG.Warn("锁定用户尝试登录", zap.String("username", utils.SanitizeUserInput(req.Username)), zap.Uint("userID", user.ID))
+		return nil, "", err
+	}
+
 	// 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
 		global.APP_LOG.Debug("用户密码验证失败", zap.String("username", utils.SanitizeUserInput(req.Username)), zap.String("userType", user.UserType))
+		protectionService.RecordPasswordFailure(req.Username, &user)
 		return nil, "", common.NewError(common.CodeInvalidCredentials)
 	}
+	protectionService.RecordLoginSuccess(&user)
 
 	global.APP_LOG.Info("用户登录成功", zap.String("username", user.Username), zap.String("userType", user.UserType), zap.Uint("userID", user.ID))
 
-	// 生成JWT令牌
-	token, err := utils.GenerateToken(user.ID, user.Username, user.UserType)
+	// 生成JWT令牌，启用两步验证的用户返回两步验证挑战
+	token, err := IssueLoginToken(&user)
 	if err != nil {
-		global.APP_LOG.Error("生成JWT令牌失败", zap.Error(err))
-		return nil, "", errors.New("登录失败，请稍后重试")
+		return nil, "", err
 	}
-	// 更新最后登录时间
-	global.APP_DB.Model(&user).Update("last_login_at", time.Now())
 	return &user, token, nil
 }
 
@@ -130,14 +135,11 @@ func (s *AuthService) loginWithEmailCode(req auth.LoginRequest) (*userModel.User
 
 	global.APP_LOG.Info("用户邮箱登录成功", zap.String("email", req.Target), zap.String("username", user.Username), zap.Uint("userID", user.ID))
 
-	// 生成JWT令牌
-	token, err := utils.GenerateToken(user.ID, user.Username, user.UserType)
+	// 生成JWT令牌，启用两步验证的用户返回两步验证挑战
+	token, err := IssueLoginToken(&user)
 	if err != nil {
-		global.APP_LOG.Error("生成JWT令牌失败", zap.Error(err))
-		return nil, "", errors.New("登录失败，请稍后重试")
+		return nil, "", err
 	}
-	// 更新最后登录时间
-	global.APP_DB.Model(&user).Update("last_login_at", time.Now())
 	return &user, token, nil
 }
 
@@ -173,14 +175,11 @@ func (s *AuthService) loginWithTelegramCode(req auth.LoginRequest) (*userModel.U
 
 	global.APP_LOG.Info("用户Telegram登录成功", zap.String("telegram", req.Target), zap.String("username", user.Username), zap.Uint("userID", user.ID))
 
-	// 生成JWT令牌
-	token, err := utils.GenerateToken(user.ID, user.Username, user.UserType)
+	// 生成JWT令牌，启用两步验证的用户返回两步验证挑战
+	token, err := IssueLoginToken(&user)
 	if err != nil {
-		global.APP_LOG.Error("生成JWT令牌失败", zap.Error(err))
-		return nil, "", errors.New("登录失败，请稍后重试")
+		return nil, "", err
 	}
-	// 更新最后登录时间
-	global.APP_DB.Model(&user).Update("last_login_at", time.Now())
 	return &user, token, nil
 }
 
@@ -216,14 +215,11 @@ func (s *AuthService) loginWithQQCode(req auth.LoginRequest) (*userModel.User, s
 
 	global.APP_LOG.Info("用户QQ登录成功", zap.String("qq", req.Target), zap.String("username", user.Username), zap.Uint("userID", user.ID))
 
-	// 生成JWT令牌
-	token, err := utils.GenerateToken(user.ID, user.Username, user.UserType)
+	// 生成JWT令牌，启用两步验证的用户返回两步验证挑战
+	token, err := IssueLoginToken(&user)
 	if err != nil {
-		global.APP_LOG.Error("生成JWT令牌失败", zap.Error(err))
-		return nil, "", errors.New("登录失败，请稍后重试")
+		return nil, "", err
 	}
-	// 更新最后登录时间
-	global.APP_DB.Model(&user).Update("last_login_at", time.Now())
 	return &user, token, nil
 }
 
@@ -394,6 +390,12 @@ func (s *AuthService) SendVerifyCode(codeType, target string) error {
 		return errors.New("不支持的验证码类型")
 	}
 
+	// 限制向同一目标发送验证码的频率
+	protectionService := LoginProtectionService{}
+	if err := protectionService.CheckVerifyCodeTarget(codeType, target); err != nil {
+		return err
+	}
+
 	// 生成6位数字验证码
 	code := generateRandomCode()
 	expiresAt := time.Now().Add(5 * time.Minute)
@@ -419,17 +421,11 @@ func (s *AuthService) SendVerifyCode(codeType, target string) error {
 		return err
 	}
 
-	// 根据类型发送验证码
-	switch codeType {
-	case "email":
-		return s.sendEmailCode(target, code)
-	case "telegram":
-		return s.sendTelegramCode(target, code)
-	case "qq":
-		return s.sendQQCode(target, code)
-	default:
-		return errors.New("不支持的验证码类型")
-	}
+	// 验证码类型即通知渠道名称
+	return notification.SendToTarget(codeType, target, notification.TemplateVerifyCode, map[string]interface{}{
+		"Code":    code,
+		"Minutes": 5,
+	})
 }
 
 // verifyCode 验证验证码
@@ -473,6 +469,12 @@ func (s *AuthService) ForgotPassword(req auth.ForgotPasswordRequest) error {
 		}
 	}
 
+	// 限制向同一邮箱发送重置邮件的频率
+	protectionService := LoginProtectionService{}
+	if err := protectionService.CheckVerifyCodeTarget("password-reset", req.Email); err != nil {
+		return err
+	}
+
 	// 查询用户
 	var user userModel.User
 	query := global.APP_DB.Where("email = ?", req.Email)
@@ -504,8 +506,10 @@ func (s *AuthService) ForgotPassword(req auth.ForgotPasswordRequest) error {
 		return nil
 	}
 	resetURL := fmt.Sprintf("http://localhost:3000/reset-password?token=%s", resetToken)
-	emailBody := fmt.Sprintf("请点击以下链接重置密码：<br><a href='%s'>重置密码</a><br>链接有效期为24小时。", resetURL)
-	return s.sendEmail(req.Email, "密码重置", emailBody)
+	return notification.SendToTarget(notification.ChannelEmail, req.Email, notification.TemplatePasswordResetLink, map[string]interface{}{
+		"URL":   resetURL,
+		"Hours": 24,
+	})
 }
 
 func (s *AuthService) ResetPassword(token, newPassword string) error {
@@ -594,7 +598,10 @@ func (s *AuthService) ResetPasswordWithToken(token string) error {
 	}
 
 	// 发送新密码到用户绑定的通信渠道
-	if err := s.sendPasswordToUser(&user, newPassword); err != nil {
+	if err := notification.SendToUser(&user, notification.TemplatePasswordReset, map[string]interface{}{
+		"Username": user.Username,
+		"Password": newPassword,
+	}); err != nil {
 		// 记录日志但不阻止密码重置完成
 		global.APP_LOG.Error("发送新密码失败",
 			zap.String("user_uuid", user.UUID),
@@ -616,326 +623,6 @@ func (s *AuthService) ResetPasswordWithToken(token string) error {
 	return nil
 }
 
-// sendPasswordToUser 发送新密码到用户绑定的通信渠道
-func (s *AuthService) sendPasswordToUser(user *userModel.User, newPassword string) error {
-	// 优先级：邮箱 > Telegram > QQ > 手机号
-
-	if user.Email != "" {
-		return s.sendPasswordByEmail(user.Email, user.Username, newPassword)
-	}
-
-	if user.Telegram != "" {
-		return s.sendPasswordByTelegram(user.Telegram, user.Username, newPassword)
-	}
-
-	if user.QQ != "" {
-		return s.sendPasswordByQQ(user.QQ, user.Username, newPassword)
-	}
-
-	if user.Phone != "" {
-		return s.sendPasswordBySMS(user.Phone, user.Username, newPassword)
-	}
-
-	return errors.New("用户未绑定任何通信渠道")
-}
-
-// sendPasswordByEmail 通过邮箱发送新密码
-func (s *AuthService) sendPasswordByEmail(email, username, newPassword string) error {
-	// 检查邮箱配置是否可用
-	if !s.isEmailConfigured() {
-		global.APP_LOG.Warn("邮箱服务未配置，跳过发送",
-			zap.String("email", email),
-			zap.String("username", username),
-			zap.String("operation", "password_reset_by_token"))
-		return nil
-	}
-
-	global.APP_LOG.Info("发送新密码到邮箱",
-		zap.String("email", email),
-		zap.String("username", username),
-		zap.String("operation", "password_reset_by_token"))
-
-	// 实际实现中应该调用邮件服务
-	subject := "密码重置成功"
-	body := fmt.Sprintf("您好 %s，<br><br>您的新密码是：<strong>%s</strong><br><br>请妥善保管并尽快登录修改密码。", username, newPassword)
-	return s.sendEmail(email, subject, body)
-}
-
-// sendPasswordByTelegram 通过Telegram发送新密码
-func (s *AuthService) sendPasswordByTelegram(telegram, username, newPassword string) error {
-	config := global.APP_CONFIG.Auth
-
-	// 检查Telegram是否启用
-	if !config.EnableTelegram {
-		return errors.New("Telegram通知服务未启用")
-	}
-
-	// 检查Bot Token是否配置
-	if config.TelegramBotToken == "" {
-		return errors.New("Telegram Bot Token未配置")
-	}
-
-	global.APP_LOG.Info("发送新密码到Telegram",
-		zap.String("telegram", telegram),
-		zap.String("username", username),
-		zap.String("operation", "password_reset_by_token"))
-
-	// 在开发环境下直接返回成功
-	if global.APP_CONFIG.System.Env == "development" {
-		global.APP_LOG.Info("开发环境模拟发送成功")
-		return nil
-	}
-
-	// 构造消息内容
-	message := fmt.Sprintf("用户 %s 的新密码：%s\n请及时登录并修改密码。", username, newPassword)
-
-	// 这里应该调用Telegram Bot API发送消息
-	// 可以使用 go-telegram-bot-api 包
-	// 示例实现：
-	// bot, err := tgbotapi.NewBotAPI(config.TelegramBotToken)
-	// if err != nil {
-	//     return fmt.Errorf("创建Telegram Bot失败: %v", err)
-	// }
-	//
-	// chatID, err := strconv.ParseInt(telegram, 10, 64)
-	// if err != nil {
-	//     return fmt.Errorf("无效的Telegram Chat ID: %v", err)
-	// }
-	//
-	// msg := tgbotapi.NewMessage(chatID, message)
-	// _, err = bot.Send(msg)
-	// return err
-
-	// 暂时返回未实现错误，但保留完整的配置检查逻辑
-	global.APP_LOG.Warn("Telegram Bot API集成待实现",
-		zap.String("message", message),
-		zap.String("chatId", telegram))
-	return errors.New("Telegram Bot API集成待实现，请安装并配置 go-telegram-bot-api 包")
-}
-
-// sendPasswordByQQ 通过QQ发送新密码
-func (s *AuthService) sendPasswordByQQ(qq, username, newPassword string) error {
-	config := global.APP_CONFIG.Auth
-
-	// 检查QQ是否启用
-	if !config.EnableQQ {
-		return errors.New("QQ通知服务未启用")
-	}
-
-	// 检查QQ配置是否完整
-	if config.QQAppID == "" || config.QQAppKey == "" {
-		return errors.New("QQ应用配置不完整")
-	}
-
-	global.APP_LOG.Info("发送新密码到QQ",
-		zap.String("qq", qq),
-		zap.String("username", username),
-		zap.String("operation", "password_reset_by_token"))
-
-	// 在开发环境下直接返回成功
-	if global.APP_CONFIG.System.Env == "development" {
-		global.APP_LOG.Info("开发环境模拟发送成功")
-		return nil
-	}
-
-	// 构造消息内容
-	message := fmt.Sprintf("用户 %s 的新密码：%s\n请及时登录并修改密码。", username, newPassword)
-
-	// 这里应该调用QQ机器人API发送消息
-	// 可以使用QQ官方的OpenAPI或第三方SDK
-	// 示例实现：
-	// qqBot := qqapi.NewBot(config.QQAppID, config.QQAppKey)
-	// err := qqBot.SendPrivateMessage(qq, message)
-	// return err
-
-	// 暂时返回未实现错误，但保留完整的配置检查逻辑
-	global.APP_LOG.Warn("QQ机器人API集成待实现",
-		zap.String("message", message),
-		zap.String("qqNumber", qq))
-	return errors.New("QQ机器人API集成待实现，请安装并配置相应的QQ SDK")
-}
-
-// sendPasswordBySMS 通过短信发送新密码
-func (s *AuthService) sendPasswordBySMS(phone, username, newPassword string) error {
-	global.APP_LOG.Info("发送新密码到手机",
-		zap.String("phone", phone),
-		zap.String("username", username),
-		zap.String("operation", "password_reset_by_token"))
-
-	// 在开发环境下直接返回成功
-	if global.APP_CONFIG.System.Env == "development" {
-		global.APP_LOG.Info("开发环境模拟发送成功")
-		return nil
-	}
-
-	// 构造短信内容
-	message := fmt.Sprintf("【您的应用】用户 %s 的新密码：%s，请及时登录并修改密码。", username, newPassword)
-
-	// 这里应该调用短信服务商API
-	// 可以集成阿里云、腾讯云、华为云等短信服务
-	// 示例实现：
-	// smsClient := sms.NewClient(config.SMSAccessKey, config.SMSSecretKey)
-	// err := smsClient.SendSMS(phone, message, config.SMSTemplateID)
-	// return err
-
-	// 暂时返回未实现错误，但保留完整的日志记录
-	global.APP_LOG.Warn("短信服务API集成待实现",
-		zap.String("message", message),
-		zap.String("phone", phone))
-	return errors.New("短信服务API集成待实现，请配置短信服务商（如阿里云、腾讯云等）")
-}
-
-func (s *AuthService) sendEmailCode(email, code string) error {
-	// 检查邮箱配置是否可用
-	if !s.isEmailConfigured() {
-		global.APP_LOG.Warn("邮箱服务未配置，无法发送验证码",
-			zap.String("email", email),
-			zap.String("operation", "send_email_verify_code"))
-		return errors.New("邮箱服务未配置，请联系管理员")
-	}
-
-	subject := "登录验证码"
-	body := fmt.Sprintf("您的登录验证码是：<strong>%s</strong><br><br>验证码5分钟内有效，请勿泄露给他人。", code)
-	return s.sendEmail(email, subject, body)
-}
-
-func (s *AuthService) sendTelegramCode(telegram, code string) error {
-	config := global.APP_CONFIG.Auth
-
-	// 检查Telegram是否启用
-	if !config.EnableTelegram {
-		return errors.New("Telegram登录未启用")
-	}
-
-	// 检查Bot Token是否配置
-	if config.TelegramBotToken == "" {
-		return errors.New("Telegram Bot Token未配置")
-	}
-
-	global.APP_LOG.Info("发送验证码到Telegram",
-		zap.String("telegram", telegram),
-		zap.String("operation", "send_telegram_verify_code"))
-
-	// 在开发环境下直接返回成功并记录验证码
-	if global.APP_CONFIG.System.Env == "development" {
-		global.APP_LOG.Info("开发环境模拟发送Telegram验证码",
-			zap.String("telegram", telegram),
-			zap.String("code", code))
-		return nil
-	}
-
-	// 构造消息内容
-	message := fmt.Sprintf("您的登录验证码是：%s\n验证码5分钟内有效，请勿泄露给他人。", code)
-
-	// 这里应该调用Telegram Bot API发送消息
-	// 可以使用 go-telegram-bot-api 包
-	// 示例实现：
-	// bot, err := tgbotapi.NewBotAPI(config.TelegramBotToken)
-	// if err != nil {
-	//     return fmt.Errorf("创建Telegram Bot失败: %v", err)
-	// }
-	//
-	// chatID, err := strconv.ParseInt(telegram, 10, 64)
-	// if err != nil {
-	//     return fmt.Errorf("无效的Telegram Chat ID: %v", err)
-	// }
-	//
-	// msg := tgbotapi.NewMessage(chatID, message)
-	// _, err = bot.Send(msg)
-	// return err
-
-	// 暂时返回未实现错误，但保留完整的配置检查逻辑
-	global.APP_LOG.Warn("Telegram Bot API集成待实现",
-		zap.String("message", message),
-		zap.String("chatId", telegram))
-	return errors.New("Telegram Bot API集成待实现，请安装并配置 go-telegram-bot-api 包")
-}
-
-func (s *AuthService) sendQQCode(qq, code string) error {
-	config := global.APP_CONFIG.Auth
-
-	// 检查QQ是否启用
-	if !config.EnableQQ {
-		return errors.New("QQ登录未启用")
-	}
-
-	// 检查QQ配置是否完整
-	if config.QQAppID == "" || config.QQAppKey == "" {
-		return errors.New("QQ应用配置不完整")
-	}
-
-	global.APP_LOG.Info("发送验证码到QQ",
-		zap.String("qq", qq),
-		zap.String("operation", "send_qq_verify_code"))
-
-	// 在开发环境下直接返回成功并记录验证码
-	if global.APP_CONFIG.System.Env == "development" {
-		global.APP_LOG.Info("开发环境模拟发送QQ验证码",
-			zap.String("qq", qq),
-			zap.String("code", code))
-		return nil
-	}
-
-	// 构造消息内容
-	message := fmt.Sprintf("您的登录验证码是：%s\n验证码5分钟内有效，请勿泄露给他人。", code)
-
-	// 这里应该调用QQ机器人API发送消息
-	// 可以使用QQ官方的OpenAPI或第三方SDK
-	// 示例实现：
-	// qqBot := qqapi.NewBot(config.QQAppID, config.QQAppKey)
-	// err := qqBot.SendPrivateMessage(qq, message)
-	// return err
-
-	// 暂时返回未实现错误，但保留完整的配置检查逻辑
-	global.APP_LOG.Warn("QQ机器人API集成待实现",
-		zap.String("message", message),
-		zap.String("qqNumber", qq))
-	return errors.New("QQ机器人API集成待实现，请安装并配置相应的QQ SDK")
-}
-
-func (s *AuthService) sendSMSCode(phone, code string) error {
-	global.APP_LOG.Info("发送验证码到手机",
-		zap.String("phone", phone),
-		zap.String("operation", "send_verification_code"))
-
-	// 在开发环境下直接返回成功
-	if global.APP_CONFIG.System.Env == "development" {
-		global.APP_LOG.Info("开发环境模拟验证码发送成功", zap.String("code", code))
-		return nil
-	}
-
-	// 构造短信内容
-	message := fmt.Sprintf("【您的应用】验证码：%s，5分钟内有效，请勿泄露。", code)
-
-	// 这里应该调用短信服务商API
-	// 可以集成阿里云、腾讯云、华为云等短信服务
-	// 示例实现：
-	// smsClient := sms.NewClient(config.SMSAccessKey, config.SMSSecretKey)
-	// err := smsClient.SendSMS(phone, message, config.SMSVerificationTemplateID)
-	// return err
-
-	global.APP_LOG.Warn("短信验证码服务API集成待实现",
-		zap.String("message", message),
-		zap.String("phone", phone))
-	return errors.New("短信验证码服务API集成待实现，请配置短信服务商")
-}
-
-func (s *AuthService) sendEmail(to, subject, body string) error {
-	config := global.APP_CONFIG.Auth
-	if config.EmailSMTPHost == "" {
-		return errors.New("邮件服务未配置")
-	}
-	auth := smtp.PlainAuth("", config.EmailUsername, config.EmailPassword, config.EmailSMTPHost)
-	msg := fmt.Sprintf("To: %s\r\nSubject: %s\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s", to, subject, body)
-	return smtp.SendMail(
-		fmt.Sprintf("%s:%d", config.EmailSMTPHost, config.EmailSMTPPort),
-		auth,
-		config.EmailUsername,
-		[]string{to},
-		[]byte(msg),
-	)
-}
-
 func generateRandomCode() string {
 	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
 	if err != nil {
@@ -973,6 +660,19 @@ func (s *AuthService) GenerateCaptcha(width, height int) (*auth.CaptchaResponse,
 	}, nil
 }
 
+// CheckLoginCaptcha 验证登录时的图形验证码（开发模式下可以跳过）
+func (s *AuthService) CheckLoginCaptcha(captchaId, captcha string) error {
+	if captchaId != "" && captcha != "" {
+		if err := s.verifyCaptcha(captchaId, captcha); err != nil {
+			return common.NewError(common.CodeCaptchaInvalid)
+		}
+	} else if global.APP_CONFIG.System.Env != "development" {
+		// 只在非开发环境下强制要求验证码
+		return common.NewError(common.CodeCaptchaRequired)
+	}
+	return nil
+}
+
 func (s *AuthService) verifyCaptcha(captchaId, code string) error {
 	if captchaId == "" || code == "" {
 		return errors.New("验证码参数不完整")
@@ -1061,7 +761,7 @@ func (s *AuthService) ChangePassword(userID uint, oldPassword, newPassword strin
 		return err
 	}
 	// 更新密码
-	return global.APP_DB.Model(&user).Update("password", string(hashedPassword)).Error
+	return global.APP_DB.Model(&user).Updates(map[string]interface{}{"password": string(hashedPassword), "password_generated": false}).Error
 }
 
 // 生成随机字符串
@@ -1240,13 +940,3 @@ func syncNewUserResourceLimits(level int, userID uint) error {
 
 	return nil
 }
-
-// isEmailConfigured 检查邮箱配置是否可用
-func (s *AuthService) isEmailConfigured() bool {
-	// 检查系统配置中是否配置了邮箱服务
-	var emailConfig adminModel.SystemConfig
-	if err := global.APP_DB.Where("key = ?", "email_enabled").First(&emailConfig).Error; err != nil {
-		return false
-	}
-	return emailConfig.Value == "true"
-}
@@ -8,6 +8,7 @@ import (
 
 	"oneclickvirt/global"
 	"oneclickvirt/model/auth"
+	userModel "oneclickvirt/model/user"
 	"oneclickvirt/utils"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -53,7 +54,17 @@ func (s *JWTBlacklistService) AddToBlacklist(tokenString string, userID uint, re
 
 	dbService := database.GetDatabaseService()
 	if err := dbService.ExecuteTransaction(context.Background(), func(tx *gorm.DB) error {
-		return tx.Create(&blacklist).Error
+		if err := tx.Create(&blacklist).Error; err != nil {
+			return err
+		}
+		// 同步标记对应的登录会话
+		return tx.Model(&auth.UserSession{}).
+			Where("jti = ? AND revoked_at IS NULL", jti).
+			Updates(map[string]interface{}{
+				"revoked_at": time.Now(),
+				"revoked_by": revokedBy,
+				"reason":     reason,
+			}).Error
 	}); err != nil {
 		return err
 	}
@@ -81,14 +92,38 @@ func (s *JWTBlacklistService) IsBlacklisted(jti string) bool {
 
 // RevokeUserTokens 撤销指定用户的所有Token
 func (s *JWTBlacklistService) RevokeUserTokens(userID uint, reason string, revokedBy uint) error {
-	// 由于我们无法获取所有已签发的Token，这里只能标记用户状态改变的时间点
-	// 实际的Token验证会在中间件中通过检查用户状态来实现
+	// 无法枚举所有已签发的Token，这里记录撤销时间点
+	// 中间件通过IsUserTokenRevoked拒绝在此时间点之前签发的Token
+	now := time.Now()
+	if err := global.APP_DB.Model(&userModel.User{}).Where("id = ?", userID).
+		Update("token_revoked_at", now).Error; err != nil {
+		return fmt.Errorf("撤销用户Token失败: %w", err)
+	}
+	if err := global.APP_DB.Model(&auth.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{
+			"revoked_at": now,
+			"revoked_by": revokedBy,
+			"reason":     reason,
+		}).Error; err != nil {
+		return fmt.Errorf("撤销用户会话失败: %w", err)
+	}
 
 	global.APP_LOG.Debug("用户所有Token被标记为撤销", zap.Uint("userID", userID), zap.String("reason", reason), zap.Uint("revokedBy", revokedBy))
 
 	return nil
 }
 
+// IsUserTokenRevoked 检查Token是否签发于用户Token被统一撤销之前
+func (s *JWTBlacklistService) IsUserTokenRevoked(userID uint, issuedAt int64) bool {
+	var user userModel.User
+	if err := global.APP_DB.Select("id, token_revoked_at").First(&user, userID).Error; err != nil {
+		// 用户不存在时由后续的用户状态检查处理
+		return false
+	}
+	return user.TokenRevokedAt != nil && issuedAt < user.TokenRevokedAt.Unix()
+}
+
 // RevokeTokenByJTI 通过JTI撤销特定Token
 func (s *JWTBlacklistService) RevokeTokenByJTI(jti string, reason string, revokedBy uint) error {
 	// 更新现有记录或创建新记录
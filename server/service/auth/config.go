@@ -30,6 +30,7 @@ func (s *ConfigService) UpdateConfig(req configModel.UpdateConfigRequest) error
 		"enableTelegram":           req.Auth.EnableTelegram,
 		"enableQQ":                 req.Auth.EnableQQ,
 		"enableOAuth2":             req.Auth.EnableOAuth2,
+		"enableLDAP":               req.Auth.EnableLDAP,
 		"enablePublicRegistration": req.Auth.EnablePublicRegistration,
 		"emailSMTPHost":            req.Auth.EmailSMTPHost,
 		"emailSMTPPort":            req.Auth.EmailSMTPPort,
@@ -38,6 +39,7 @@ func (s *ConfigService) UpdateConfig(req configModel.UpdateConfigRequest) error
 		"telegramBotToken":         req.Auth.TelegramBotToken,
 		"qqAppID":                  req.Auth.QQAppID,
 		"qqAppKey":                 req.Auth.QQAppKey,
+		"twoFactorRequired":        req.Auth.TwoFactorRequired,
 	}
 	configUpdates["auth"] = authConfig
 
@@ -103,6 +105,10 @@ func (s *ConfigService) UpdateConfig(req configModel.UpdateConfigRequest) error
 				"maxInstances": modelLimit.MaxInstances,
 				"maxResources": modelLimit.MaxResources,
 				"maxTraffic":   modelLimit.MaxTraffic,
+				"maxSnapshots": modelLimit.MaxSnapshots,
+				"maxBackups":   modelLimit.MaxBackups,
+				"maxPorts":     modelLimit.MaxPorts,
+				"maxDomains":   modelLimit.MaxDomains,
 			}
 		}
 		quotaConfig["levelLimits"] = levelLimits
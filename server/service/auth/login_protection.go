@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"oneclickvirt/config"
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/service/ratelimit"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// LoginProtectionService 登录防暴力破解服务
+// IP请求频率和密码错误次数使用滑动窗口计数，账户锁定状态持久化到用户表，管理员可手动解锁
+type LoginProtectionService struct{}
+
+// loginProtectionPolicy 获取登录保护策略，未配置的数值使用默认值
+func loginProtectionPolicy() config.LoginProtection {
+	policy := global.APP_CONFIG.Auth.LoginProtection
+	if policy.IPMaxAttempts <= 0 {
+		policy.IPMaxAttempts = 20
+	}
+	if policy.IPWindowSeconds <= 0 {
+		policy.IPWindowSeconds = 300
+	}
+	if policy.MaxFailures <= 0 {
+		policy.MaxFailures = 5
+	}
+	if policy.FailureWindowSeconds <= 0 {
+		policy.FailureWindowSeconds = 900
+	}
+	if policy.LockoutSeconds <= 0 {
+		policy.LockoutSeconds = 300
+	}
+	if policy.MaxLockoutSeconds <= 0 {
+		policy.MaxLockoutSeconds = 86400
+	}
+	if policy.VerifyCodeIntervalSeconds <= 0 {
+		policy.VerifyCodeIntervalSeconds = 60
+	}
+	if policy.VerifyCodeHourlyLimit <= 0 {
+		policy.VerifyCodeHourlyLimit = 10
+	}
+	return policy
+}
+
+// CheckIP 按IP限制认证类请求频率，scope区分登录、发送验证码、找回密码等场景
+func (s *LoginProtectionService) CheckIP(scope, ip string) error {
+	policy := loginProtectionPolicy()
+	if !policy.Enabled {
+		return nil
+	}
+
+	window := time.Duration(policy.IPWindowSeconds) * time.Second
+	count, err := ratelimit.GetStore().Hit("auth:"+scope+":ip:"+ip, window)
+	if err != nil {
+		// 计数存储不可用时放行，避免影响正常登录
+		global.APP_LOG.Warn("登录限流计数失败", zap.String("scope", scope), zap.Error(err))
+		return nil
+	}
+	if count > policy.IPMaxAttempts {
+		global.APP_LOG.Warn("认证请求过于频繁",
+			zap.String("scope", scope),
+			zap.String("ip", ip),
+			zap.Int("count", count))
+		return common.NewError(common.CodeTooManyRequests, fmt.Sprintf("请在%d分钟后重试", (policy.IPWindowSeconds+59)/60))
+	}
+	return nil
+}
+
+// CheckAccountLock 检查账户是否处于锁定状态
+func (s *LoginProtectionService) CheckAccountLock(user *userModel.User) error {
+	if !loginProtectionPolicy().Enabled || user.LockedUntil == nil {
+		return nil
+	}
+	remaining := time.Until(*user.LockedUntil)
+	if remaining <= 0 {
+		return nil
+	}
+	return common.NewError(common.CodeAccountLocked, fmt.Sprintf("请在%d分钟后重试或联系管理员解锁", int(remaining.Minutes())+1))
+}
+
+// CheckUsername 按用户名限制密码错误次数，对不存在的用户名同样生效
+func (s *LoginProtectionService) CheckUsername(username string) error {
+	policy := loginProtectionPolicy()
+	if !policy.Enabled {
+		return nil
+	}
+
+	count, err := ratelimit.GetStore().Count(loginFailureKey(username), time.Duration(policy.FailureWindowSeconds)*time.Second)
+	if err != nil {
+		global.APP_LOG.Warn("登录限流计数失败", zap.Error(err))
+		return nil
+	}
+	if count >= policy.MaxFailures {
+		return common.NewError(common.CodeTooManyRequests, fmt.Sprintf("请在%d分钟后重试", (policy.FailureWindowSeconds+59)/60))
+	}
+	return nil
+}
+
+// RecordPasswordFailure 记录一次密码错误，user为nil表示用户名不存在
+// 窗口内错误次数达到阈值时锁定账户，每次锁定时长翻倍，直到达到最长锁定时长
+func (s *LoginProtectionService) RecordPasswordFailure(username string, user *userModel.User) {
+	policy := loginProtectionPolicy()
+	if !policy.Enabled {
+		return
+	}
+
+	key := loginFailureKey(username)
+	store := ratelimit.GetStore()
+	count, err := store.Hit(key, time.Duration(policy.FailureWindowSeconds)*time.Second)
+	if err != nil {
+		global.APP_LOG.Warn("记录密码错误次数失败", zap.Error(err))
+		return
+	}
+	if user == nil || count < policy.MaxFailures {
+		return
+	}
+
+	lockoutCount := user.LockoutCount + 1
+	duration := time.Duration(policy.LockoutSeconds) * time.Second
+	maxDuration := time.Duration(policy.MaxLockoutSeconds) * time.Second
+	for i := 1; i < lockoutCount && duration < maxDuration; i++ {
+		duration *= 2
+	}
+	if duration > maxDuration {
+		duration = maxDuration
+	}
+	lockedUntil := time.Now().Add(duration)
+
+	if err := global.APP_DB.Model(user).Updates(map[string]interface{}{
+		"locked_until":  lockedUntil,
+		"lockout_count": lockoutCount,
+	}).Error; err != nil {
+		global.APP_LOG.Error("锁定账户失败", zap.Uint("userID", user.ID), zap.Error(err))
+		return
+	}
+	store.Reset(key)
+
+	global.APP_LOG.Warn("密码错误次数过多，账户已被临时锁定",
+		zap.Uint("userID", user.ID),
+		zap.String("username", user.Username),
+		zap.Int("lockoutCount", lockoutCount),
+		zap.Duration("duration", duration))
+}
+
+// RecordLoginSuccess 密码验证通过后清除错误计数和锁定状态
+func (s *LoginProtectionService) RecordLoginSuccess(user *userModel.User) {
+	ratelimit.GetStore().Reset(loginFailureKey(user.Username))
+	if user.LockedUntil == nil && user.LockoutCount == 0 {
+		return
+	}
+	global.APP_DB.Model(user).Updates(map[string]interface{}{
+		"locked_until":  nil,
+		"lockout_count": 0,
+	})
+}
+
+// CheckVerifyCodeTarget 限制向同一目标发送验证码或重置邮件的频率
+func (s *LoginProtectionService) CheckVerifyCodeTarget(channel, target string) error {
+	policy := loginProtectionPolicy()
+	if !policy.Enabled {
+		return nil
+	}
+
+	store := ratelimit.GetStore()
+	target = strings.ToLower(strings.TrimSpace(target))
+	intervalKey := "auth:verify:interval:" + channel + ":" + target
+	hourlyKey := "auth:verify:hourly:" + channel + ":" + target
+	interval := time.Duration(policy.VerifyCodeIntervalSeconds) * time.Second
+
+	recent, err := store.Count(intervalKey, interval)
+	if err != nil {
+		global.APP_LOG.Warn("验证码发送限流计数失败", zap.String("channel", channel), zap.Error(err))
+		return nil
+	}
+	if recent > 0 {
+		return common.NewError(common.CodeTooManyRequests, fmt.Sprintf("发送过于频繁，请%d秒后再试", policy.VerifyCodeIntervalSeconds))
+	}
+	hourly, err := store.Count(hourlyKey, time.Hour)
+	if err != nil {
+		global.APP_LOG.Warn("验证码发送限流计数失败", zap.String("channel", channel), zap.Error(err))
+		return nil
+	}
+	if hourly >= policy.VerifyCodeHourlyLimit {
+		return common.NewError(common.CodeTooManyRequests, "发送次数已达上限，请1小时后再试")
+	}
+
+	store.Hit(intervalKey, interval)
+	store.Hit(hourlyKey, time.Hour)
+	return nil
+}
+
+// UnlockUser 管理员解锁被锁定的账户
+func (s *LoginProtectionService) UnlockUser(userID, adminUserID uint) error {
+	var user userModel.User
+	if err := global.APP_DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return common.NewError(common.CodeUserNotFound)
+		}
+		return err
+	}
+
+	if err := global.APP_DB.Model(&user).Updates(map[string]interface{}{
+		"locked_until":  nil,
+		"lockout_count": 0,
+	}).Error; err != nil {
+		return err
+	}
+	ratelimit.GetStore().Reset(loginFailureKey(user.Username))
+
+	global.APP_LOG.Info("管理员解锁用户账户",
+		zap.Uint("userID", userID),
+		zap.Uint("adminUserID", adminUserID))
+	return nil
+}
+
+// loginFailureKey 密码错误计数键，用户名不区分大小写
+func loginFailureKey(username string) string {
+	return "auth:login:fail:" + strings.ToLower(strings.TrimSpace(username))
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"oneclickvirt/global"
+	"oneclickvirt/model/auth"
 	"oneclickvirt/model/permission"
 	"oneclickvirt/model/user"
 
@@ -128,6 +129,58 @@ func (s *PermissionService) calculateEffectivePermission(user *user.User, permis
 	return effective
 }
 
+// GetUserActionPermissions 获取用户的操作权限
+// 管理员拥有全部权限，其他用户为已启用角色的权限并集
+func (s *PermissionService) GetUserActionPermissions(userID uint) ([]string, error) {
+	effective, err := s.GetUserEffectivePermission(userID)
+	if err != nil {
+		return nil, err
+	}
+	if effective.EffectiveType == "admin" && s.VerifyAdminPrivilege(userID) {
+		return []string{auth.PermissionAll}, nil
+	}
+
+	var roles []auth.Role
+	roleIDs := global.APP_DB.Model(&user.UserRole{}).Select("role_id").Where("user_id = ?", userID)
+	if err := global.APP_DB.Where("id IN (?) AND status = ?", roleIDs, 1).Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("查询用户角色失败: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	perms := make([]string, 0)
+	for _, role := range roles {
+		for _, perm := range role.GetPermissions() {
+			// 全部权限只通过管理员身份获得，角色中的"*"不生效
+			if perm == "" || perm == auth.PermissionAll || seen[perm] {
+				continue
+			}
+			seen[perm] = true
+			perms = append(perms, perm)
+		}
+	}
+	return perms, nil
+}
+
+// HasActionPermission 检查用户是否拥有指定的操作权限
+func (s *PermissionService) HasActionPermission(userID uint, required string) bool {
+	perms, err := s.GetUserActionPermissions(userID)
+	if err != nil {
+		global.APP_LOG.Error("获取用户操作权限失败", zap.Uint("userID", userID), zap.Error(err))
+		return false
+	}
+	return permissionsGrant(perms, required)
+}
+
+// permissionsGrant 检查权限列表是否包含要求的权限
+func permissionsGrant(perms []string, required string) bool {
+	for _, perm := range perms {
+		if auth.PermissionGrants(perm, required) {
+			return true
+		}
+	}
+	return false
+}
+
 // HasPermission 检查用户是否有指定权限
 func (s *PermissionService) HasPermission(userID uint, requiredType string) bool {
 	effective, err := s.GetUserEffectivePermission(userID)
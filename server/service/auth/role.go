@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"oneclickvirt/service/database"
+	"strings"
 
 	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
 	"oneclickvirt/model/auth"
 	"oneclickvirt/model/common"
 	"oneclickvirt/model/user"
 
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -17,6 +20,12 @@ type RoleService struct{}
 
 // GetRoleList 获取角色列表
 func (s *RoleService) GetRoleList(req common.PageInfo) (interface{}, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
 	limit := req.PageSize
 	offset := req.PageSize * (req.Page - 1)
 
@@ -24,6 +33,9 @@ func (s *RoleService) GetRoleList(req common.PageInfo) (interface{}, error) {
 	var total int64
 
 	db := global.APP_DB.Model(&auth.Role{})
+	if req.Keyword != "" {
+		db = db.Where("name LIKE ? OR code LIKE ?", "%"+req.Keyword+"%", "%"+req.Keyword+"%")
+	}
 
 	// 计算总数
 	if err := db.Count(&total).Error; err != nil {
@@ -31,7 +43,7 @@ func (s *RoleService) GetRoleList(req common.PageInfo) (interface{}, error) {
 	}
 
 	// 获取分页数据
-	if err := db.Limit(limit).Offset(offset).Find(&roles).Error; err != nil {
+	if err := db.Order("id ASC").Limit(limit).Offset(offset).Find(&roles).Error; err != nil {
 		return nil, err
 	}
 
@@ -43,85 +55,132 @@ func (s *RoleService) GetRoleList(req common.PageInfo) (interface{}, error) {
 	}, nil
 }
 
-// CreateRole 创建角色
-func (s *RoleService) CreateRole(name, code, remark string) error {
+// CreateRole 创建角色，只能授予操作者自己拥有的权限
+func (s *RoleService) CreateRole(req adminModel.RoleRequest, operatorID uint) (*auth.Role, error) {
+	perms, err := s.validatePermissions(req.Permissions, operatorID)
+	if err != nil {
+		return nil, err
+	}
+
 	// 检查角色名称是否已存在
 	var count int64
-	global.APP_DB.Model(&auth.Role{}).Where("name = ?", name).Count(&count)
+	global.APP_DB.Model(&auth.Role{}).Where("name = ?", req.Name).Count(&count)
 	if count > 0 {
-		return errors.New("角色名称已存在")
+		return nil, common.NewError(common.CodeValidationError, "角色名称已存在")
 	}
 
 	// 检查角色代码是否已存在
-	global.APP_DB.Model(&auth.Role{}).Where("code = ?", code).Count(&count)
+	global.APP_DB.Model(&auth.Role{}).Where("code = ?", req.Code).Count(&count)
 	if count > 0 {
-		return errors.New("角色代码已存在")
+		return nil, common.NewError(common.CodeValidationError, "角色代码已存在")
 	}
 
 	role := auth.Role{
-		Name:   name,
-		Code:   code,
-		Remark: remark,
-		Status: 1,
+		Name:        req.Name,
+		Code:        req.Code,
+		Description: req.Description,
+		Remark:      req.Remark,
+		Status:      1,
 	}
+	if req.Status != nil {
+		role.Status = *req.Status
+	}
+	role.SetPermissions(perms)
 
 	dbService := database.GetDatabaseService()
-	return dbService.ExecuteTransaction(context.Background(), func(tx *gorm.DB) error {
+	if err := dbService.ExecuteTransaction(context.Background(), func(tx *gorm.DB) error {
 		return tx.Create(&role).Error
-	})
+	}); err != nil {
+		return nil, err
+	}
+
+	global.APP_LOG.Info("创建角色",
+		zap.Uint("roleID", role.ID),
+		zap.String("code", role.Code),
+		zap.Strings("permissions", perms),
+		zap.Uint("operatorID", operatorID))
+	return &role, nil
 }
 
-// UpdateRole 更新角色
-func (s *RoleService) UpdateRole(roleID uint, name, code, remark string) error {
-	var role auth.Role
-	if err := global.APP_DB.First(&role, roleID).Error; err != nil {
-		return errors.New("角色不存在")
+// UpdateRole 更新角色，内置角色不可修改
+func (s *RoleService) UpdateRole(roleID uint, req adminModel.RoleRequest, operatorID uint) (*auth.Role, error) {
+	role, err := s.GetRoleByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role.IsSystem {
+		return nil, common.NewError(common.CodeForbidden, "内置角色不可修改")
+	}
+
+	// 操作者必须同时拥有角色原有的权限和新的权限，避免修改自己无法控制的角色
+	if err := s.checkRolesGrantable([]auth.Role{*role}, operatorID); err != nil {
+		return nil, err
+	}
+	perms, err := s.validatePermissions(req.Permissions, operatorID)
+	if err != nil {
+		return nil, err
 	}
 
 	// 检查角色名称是否被其他角色使用
-	if name != role.Name {
+	if req.Name != role.Name {
 		var count int64
-		global.APP_DB.Model(&auth.Role{}).Where("name = ? AND id != ?", name, roleID).Count(&count)
+		global.APP_DB.Model(&auth.Role{}).Where("name = ? AND id != ?", req.Name, roleID).Count(&count)
 		if count > 0 {
-			return errors.New("角色名称已存在")
+			return nil, common.NewError(common.CodeValidationError, "角色名称已存在")
 		}
 	}
 
 	// 检查角色代码是否被其他角色使用
-	if code != role.Code {
+	if req.Code != role.Code {
 		var count int64
-		global.APP_DB.Model(&auth.Role{}).Where("code = ? AND id != ?", code, roleID).Count(&count)
+		global.APP_DB.Model(&auth.Role{}).Where("code = ? AND id != ?", req.Code, roleID).Count(&count)
 		if count > 0 {
-			return errors.New("角色代码已存在")
+			return nil, common.NewError(common.CodeValidationError, "角色代码已存在")
 		}
 	}
 
 	updates := map[string]interface{}{
-		"name":   name,
-		"code":   code,
-		"remark": remark,
+		"name":        req.Name,
+		"code":        req.Code,
+		"description": req.Description,
+		"remark":      req.Remark,
+		"permissions": strings.Join(perms, ","),
+	}
+	if req.Status != nil {
+		updates["status"] = *req.Status
+	}
+
+	if err := global.APP_DB.Model(role).Updates(updates).Error; err != nil {
+		return nil, err
 	}
 
-	return global.APP_DB.Model(&role).Updates(updates).Error
+	global.APP_LOG.Info("更新角色",
+		zap.Uint("roleID", roleID),
+		zap.Strings("permissions", perms),
+		zap.Uint("operatorID", operatorID))
+	return s.GetRoleByID(roleID)
 }
 
 // DeleteRole 删除角色
 func (s *RoleService) DeleteRole(roleID uint) error {
-	var role auth.Role
-	if err := global.APP_DB.First(&role, roleID).Error; err != nil {
-		return errors.New("角色不存在")
+	role, err := s.GetRoleByID(roleID)
+	if err != nil {
+		return err
+	}
+	if role.IsSystem {
+		return common.NewError(common.CodeForbidden, "内置角色不可删除")
 	}
 
 	// 检查角色是否有关联的用户
 	var userCount int64
-	global.APP_DB.Model(&user.User{}).Where("role_id = ?", roleID).Count(&userCount)
+	global.APP_DB.Model(&user.UserRole{}).Where("role_id = ?", roleID).Count(&userCount)
 	if userCount > 0 {
-		return errors.New("角色还有关联的用户，无法删除")
+		return common.NewError(common.CodeValidationError, "角色还有关联的用户，无法删除")
 	}
 
 	dbService := database.GetDatabaseService()
 	return dbService.ExecuteTransaction(context.Background(), func(tx *gorm.DB) error {
-		return tx.Delete(&role).Error
+		return tx.Delete(role).Error
 	})
 }
 
@@ -129,7 +188,10 @@ func (s *RoleService) DeleteRole(roleID uint) error {
 func (s *RoleService) GetRoleByID(roleID uint) (*auth.Role, error) {
 	var role auth.Role
 	if err := global.APP_DB.First(&role, roleID).Error; err != nil {
-		return nil, errors.New("角色不存在")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, common.NewError(common.CodeNotFound, "角色不存在")
+		}
+		return nil, err
 	}
 	return &role, nil
 }
@@ -142,3 +204,131 @@ func (s *RoleService) GetAllRoles() ([]auth.Role, error) {
 	}
 	return roles, nil
 }
+
+// GetUserRoles 获取用户已分配的角色
+func (s *RoleService) GetUserRoles(userID uint) ([]auth.Role, error) {
+	var count int64
+	if err := global.APP_DB.Model(&user.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, common.NewError(common.CodeUserNotFound)
+	}
+
+	var roles []auth.Role
+	roleIDs := global.APP_DB.Model(&user.UserRole{}).Select("role_id").Where("user_id = ?", userID)
+	if err := global.APP_DB.Where("id IN (?)", roleIDs).Order("id ASC").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// SetUserRoles 设置用户的角色，操作者必须拥有所分配角色的全部权限
+func (s *RoleService) SetUserRoles(userID uint, roleIDs []uint, operatorID uint) ([]auth.Role, error) {
+	if userID == operatorID {
+		return nil, common.NewError(common.CodeForbidden, "不能修改自己的角色")
+	}
+
+	current, err := s.GetUserRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []auth.Role
+	if len(roleIDs) > 0 {
+		if err := global.APP_DB.Where("id IN ?", roleIDs).Find(&roles).Error; err != nil {
+			return nil, err
+		}
+		if len(roles) != len(uniqueRoleIDs(roleIDs)) {
+			return nil, common.NewError(common.CodeNotFound, "角色不存在")
+		}
+	}
+
+	for _, role := range roles {
+		if strings.Contains(role.Permissions, auth.PermissionAll) {
+			return nil, common.NewError(common.CodeValidationError, "管理员权限通过用户类型授予，不能分配角色"+role.Name)
+		}
+	}
+	// 新增和移除的角色都需要操作者拥有其权限
+	if err := s.checkRolesGrantable(append(append([]auth.Role{}, roles...), current...), operatorID); err != nil {
+		return nil, err
+	}
+
+	dbService := database.GetDatabaseService()
+	if err := dbService.ExecuteTransaction(context.Background(), func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&user.UserRole{}).Error; err != nil {
+			return err
+		}
+		for _, role := range roles {
+			if err := tx.Create(&user.UserRole{UserID: userID, RoleID: role.ID}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	global.APP_LOG.Info("设置用户角色",
+		zap.Uint("userID", userID),
+		zap.Uints("roleIDs", roleIDs),
+		zap.Uint("operatorID", operatorID))
+	return s.GetUserRoles(userID)
+}
+
+// validatePermissions 校验权限代码并去重，操作者只能授予自己拥有的权限
+func (s *RoleService) validatePermissions(perms []string, operatorID uint) ([]string, error) {
+	permissionService := PermissionService{}
+	operatorPerms, err := permissionService.GetUserActionPermissions(operatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(perms))
+	for _, perm := range perms {
+		perm = strings.TrimSpace(perm)
+		if perm == "" || seen[perm] {
+			continue
+		}
+		if !auth.IsValidPermission(perm) {
+			return nil, common.NewError(common.CodeValidationError, "无效的权限: "+perm)
+		}
+		if !permissionsGrant(operatorPerms, perm) {
+			return nil, common.NewError(common.CodeForbidden, "不能授予自己没有的权限: "+perm)
+		}
+		seen[perm] = true
+		result = append(result, perm)
+	}
+	return result, nil
+}
+
+// checkRolesGrantable 检查操作者是否拥有角色的全部权限
+func (s *RoleService) checkRolesGrantable(roles []auth.Role, operatorID uint) error {
+	permissionService := PermissionService{}
+	operatorPerms, err := permissionService.GetUserActionPermissions(operatorID)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		for _, perm := range role.GetPermissions() {
+			if perm != "" && !permissionsGrant(operatorPerms, perm) {
+				return common.NewError(common.CodeForbidden, "不能操作拥有自己没有的权限的角色: "+role.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// uniqueRoleIDs 角色ID去重
+func uniqueRoleIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
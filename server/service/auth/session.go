@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/auth"
+	"oneclickvirt/model/common"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	sessionTouchInterval   = time.Minute // 最近访问时间的更新间隔，避免每次请求都写库
+	sessionUserAgentMaxLen = 512
+)
+
+// SessionService 登录会话服务
+type SessionService struct{}
+
+// RecordSession 登录成功签发JWT后记录会话
+func (s *SessionService) RecordSession(token string, userID uint, ip, userAgent string) error {
+	jti, issuedAt, expiresAt, err := parseSessionClaims(token)
+	if err != nil {
+		return err
+	}
+	if len(userAgent) > sessionUserAgentMaxLen {
+		userAgent = userAgent[:sessionUserAgentMaxLen]
+	}
+
+	session := auth.UserSession{
+		CreatedAt:  issuedAt,
+		UserID:     userID,
+		JTI:        jti,
+		IP:         ip,
+		UserAgent:  userAgent,
+		LastSeenIP: ip,
+		LastSeenAt: issuedAt,
+		ExpiresAt:  expiresAt,
+	}
+	return global.APP_DB.Create(&session).Error
+}
+
+// TouchSession 校验会话是否已撤销并更新最近访问信息
+// 功能上线前签发的Token没有会话记录，首次访问时补录
+func (s *SessionService) TouchSession(claims jwt.MapClaims, userID uint, ip, userAgent string) error {
+	jti, _ := claims["jti"].(string)
+	issuedAt, expiresAt := sessionClaimTimes(claims)
+
+	var session auth.UserSession
+	err := global.APP_DB.Where("jti = ?", jti).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if len(userAgent) > sessionUserAgentMaxLen {
+			userAgent = userAgent[:sessionUserAgentMaxLen]
+		}
+		return global.APP_DB.Create(&auth.UserSession{
+			CreatedAt:  issuedAt,
+			UserID:     userID,
+			JTI:        jti,
+			IP:         ip,
+			UserAgent:  userAgent,
+			LastSeenIP: ip,
+			LastSeenAt: time.Now(),
+			ExpiresAt:  expiresAt,
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	if session.RevokedAt != nil || session.UserID != userID {
+		return common.NewError(common.CodeUnauthorized, "认证令牌已失效")
+	}
+
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval || session.LastSeenIP != ip {
+		global.APP_DB.Model(&session).Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"last_seen_ip": ip,
+		})
+	}
+	return nil
+}
+
+// ListSessions 获取用户未过期且未撤销的会话，按最近访问时间倒序
+func (s *SessionService) ListSessions(userID uint, currentJTI string) ([]auth.SessionInfo, error) {
+	var sessions []auth.UserSession
+	if err := global.APP_DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	list := make([]auth.SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		list = append(list, auth.SessionInfo{
+			UserSession: session,
+			Current:     currentJTI != "" && session.JTI == currentJTI,
+		})
+	}
+	return list, nil
+}
+
+// RevokeSession 撤销用户的指定会话
+func (s *SessionService) RevokeSession(userID, sessionID uint, reason string, revokedBy uint) error {
+	var session auth.UserSession
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return common.NewError(common.CodeNotFound, "会话不存在")
+		}
+		return err
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	if err := revokeSessions(global.APP_DB, []auth.UserSession{session}, reason, revokedBy); err != nil {
+		return err
+	}
+	global.APP_LOG.Info("会话已撤销",
+		zap.Uint("userID", userID),
+		zap.Uint("sessionID", sessionID),
+		zap.String("reason", reason),
+		zap.Uint("revokedBy", revokedBy))
+	return nil
+}
+
+// RevokeOtherSessions 撤销除当前会话外的所有会话，返回撤销数量
+func (s *SessionService) RevokeOtherSessions(userID uint, currentJTI string) (int, error) {
+	var sessions []auth.UserSession
+	if err := global.APP_DB.Where("user_id = ? AND jti <> ? AND revoked_at IS NULL AND expires_at > ?", userID, currentJTI, time.Now()).
+		Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+	if len(sessions) == 0 {
+		return 0, nil
+	}
+
+	if err := revokeSessions(global.APP_DB, sessions, "revoke_others", userID); err != nil {
+		return 0, err
+	}
+	global.APP_LOG.Info("用户撤销其他会话", zap.Uint("userID", userID), zap.Int("count", len(sessions)))
+	return len(sessions), nil
+}
+
+// RevokeAllSessions 撤销用户的所有会话，包括没有会话记录的旧Token
+func (s *SessionService) RevokeAllSessions(userID uint, reason string, revokedBy uint) error {
+	var count int64
+	if err := global.APP_DB.Model(&userModel.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return common.NewError(common.CodeUserNotFound)
+	}
+
+	blacklistService := JWTBlacklistService{}
+	if err := blacklistService.RevokeUserTokens(userID, reason, revokedBy); err != nil {
+		return err
+	}
+	global.APP_LOG.Info("用户所有会话已撤销",
+		zap.Uint("userID", userID),
+		zap.String("reason", reason),
+		zap.Uint("revokedBy", revokedBy))
+	return nil
+}
+
+// CleanupExpiredSessions 清理已过期的会话记录
+func CleanupExpiredSessions() {
+	if global.APP_DB == nil {
+		return
+	}
+	result := global.APP_DB.Where("expires_at < ?", time.Now()).Delete(&auth.UserSession{})
+	if result.Error != nil {
+		global.APP_LOG.Error("清理过期会话失败", zap.Error(result.Error))
+	} else if result.RowsAffected > 10 {
+		global.APP_LOG.Info("清理过期会话", zap.Int64("count", result.RowsAffected))
+	}
+}
+
+// revokeSessions 将会话标记为已撤销，并把对应JTI加入黑名单
+func revokeSessions(db *gorm.DB, sessions []auth.UserSession, reason string, revokedBy uint) error {
+	now := time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		ids := make([]uint, 0, len(sessions))
+		for _, session := range sessions {
+			ids = append(ids, session.ID)
+			var existing int64
+			if err := tx.Model(&auth.JWTBlacklist{}).Where("jti = ?", session.JTI).Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing > 0 {
+				continue
+			}
+			if err := tx.Create(&auth.JWTBlacklist{
+				JTI:       session.JTI,
+				UserID:    session.UserID,
+				ExpiresAt: session.ExpiresAt,
+				Reason:    reason,
+				RevokedBy: revokedBy,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&auth.UserSession{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"revoked_at": now,
+			"revoked_by": revokedBy,
+			"reason":     reason,
+		}).Error
+	})
+}
+
+// parseSessionClaims 校验JWT并提取JTI、签发时间和过期时间
+func parseSessionClaims(token string) (string, time.Time, time.Time, error) {
+	claims, err := utils.ValidateToken(token)
+	if err != nil {
+		return "", time.Time{}, time.Time{}, err
+	}
+	jti, _ := (*claims)["jti"].(string)
+	if jti == "" {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("Token缺少JTI字段")
+	}
+	issuedAt, expiresAt := sessionClaimTimes(*claims)
+	return jti, issuedAt, expiresAt, nil
+}
+
+// sessionClaimTimes 从JWT声明中提取签发时间和过期时间
+func sessionClaimTimes(claims jwt.MapClaims) (time.Time, time.Time) {
+	issuedAt, expiresAt := time.Now(), time.Now().Add(24*time.Hour)
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		issuedAt = iat.Time
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		expiresAt = exp.Time
+	}
+	return issuedAt, expiresAt
+}
@@ -0,0 +1,568 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"image/png"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/auth"
+	"oneclickvirt/model/common"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/utils"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	twoFactorIssuer       = "OneClickVirt"
+	twoFactorPeriod       = 30              // TOTP时间步长（秒）
+	twoFactorChallengeTTL = 5 * time.Minute // 两步验证挑战有效期
+	twoFactorMaxAttempts  = 5               // 每个挑战允许的最大失败次数
+	recoveryCodeCount     = 10              // 每次生成的恢复码数量
+	recoveryCodeAlphabet  = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+var totpOpts = totp.ValidateOpts{
+	Period:    twoFactorPeriod,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// TwoFactorChallengeError 登录凭据校验通过但需要完成两步验证时返回
+// 调用方应将挑战令牌返回给前端，而不是视为登录失败
+type TwoFactorChallengeError struct {
+	ChallengeToken   string
+	EnrollRequired   bool
+	PasskeyAvailable bool
+}
+
+func (e *TwoFactorChallengeError) Error() string {
+	return "需要完成两步验证"
+}
+
+// twoFactorChallenge 两步验证挑战信息
+type twoFactorChallenge struct {
+	UserID         uint
+	EnrollRequired bool
+	Attempts       int
+	Expiry         time.Time
+}
+
+var (
+	twoFactorChallenges   = make(map[string]*twoFactorChallenge)
+	twoFactorChallengesMu sync.Mutex
+)
+
+// TwoFactorService 两步验证服务
+type TwoFactorService struct{}
+
+// IsTwoFactorRequired 判断管理员策略是否要求该用户启用两步验证
+func IsTwoFactorRequired(user *userModel.User) bool {
+	switch global.APP_CONFIG.Auth.TwoFactorRequired {
+	case "all":
+		return true
+	case "admin":
+		return user.UserType == "admin"
+	default:
+		return false
+	}
+}
+
+// IssueLoginToken 登录凭据校验通过后签发JWT令牌
+// 用户已启用两步验证或策略要求启用时，返回TwoFactorChallengeError，完成验证后才签发令牌
+// 已绑定通行密钥的用户可以使用通行密钥作为第二因素，策略强制时无需再绑定TOTP
+func IssueLoginToken(user *userModel.User) (string, error) {
+	if user.TwoFactorEnabled || IsTwoFactorRequired(user) {
+		passkeyAvailable := HasPasskey(user.ID)
+		enrollRequired := !user.TwoFactorEnabled && !passkeyAvailable
+		challengeToken, err := newTwoFactorChallenge(user.ID, enrollRequired)
+		if err != nil {
+			global.APP_LOG.Error("生成两步验证挑战失败", zap.Uint("userID", user.ID), zap.Error(err))
+			return "", errors.New("登录失败，请稍后重试")
+		}
+		return "", &TwoFactorChallengeError{
+			ChallengeToken:   challengeToken,
+			EnrollRequired:   enrollRequired,
+			PasskeyAvailable: passkeyAvailable,
+		}
+	}
+	return generateLoginToken(user)
+}
+
+// generateLoginToken 生成JWT令牌并更新最后登录时间
+func generateLoginToken(user *userModel.User) (string, error) {
+	token, err := utils.GenerateToken(user.ID, user.Username, user.UserType)
+	if err != nil {
+		global.APP_LOG.Error("生成JWT令牌失败", zap.Error(err))
+		return "", errors.New("登录失败，请稍后重试")
+	}
+	global.APP_DB.Model(user).Update("last_login_at", time.Now())
+	return token, nil
+}
+
+// newTwoFactorChallenge 创建两步验证挑战
+func newTwoFactorChallenge(userID uint, enrollRequired bool) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	twoFactorChallengesMu.Lock()
+	defer twoFactorChallengesMu.Unlock()
+
+	now := time.Now()
+	for key, challenge := range twoFactorChallenges {
+		if now.After(challenge.Expiry) {
+			delete(twoFactorChallenges, key)
+		}
+	}
+	twoFactorChallenges[token] = &twoFactorChallenge{
+		UserID:         userID,
+		EnrollRequired: enrollRequired,
+		Expiry:         now.Add(twoFactorChallengeTTL),
+	}
+	return token, nil
+}
+
+// getTwoFactorChallenge 获取未过期的两步验证挑战
+func getTwoFactorChallenge(token string) (*twoFactorChallenge, error) {
+	twoFactorChallengesMu.Lock()
+	defer twoFactorChallengesMu.Unlock()
+
+	challenge, ok := twoFactorChallenges[token]
+	if !ok || time.Now().After(challenge.Expiry) {
+		delete(twoFactorChallenges, token)
+		return nil, common.NewError(common.CodeTwoFactorExpired)
+	}
+	copied := *challenge
+	return &copied, nil
+}
+
+// failTwoFactorChallenge 记录一次验证失败，超过次数后挑战作废
+func failTwoFactorChallenge(token string) {
+	twoFactorChallengesMu.Lock()
+	defer twoFactorChallengesMu.Unlock()
+
+	if challenge, ok := twoFactorChallenges[token]; ok {
+		challenge.Attempts++
+		if challenge.Attempts >= twoFactorMaxAttempts {
+			delete(twoFactorChallenges, token)
+		}
+	}
+}
+
+// removeTwoFactorChallenge 删除两步验证挑战
+func removeTwoFactorChallenge(token string) {
+	twoFactorChallengesMu.Lock()
+	defer twoFactorChallengesMu.Unlock()
+	delete(twoFactorChallenges, token)
+}
+
+// CompleteLogin 使用TOTP验证码或恢复码完成两步验证登录
+// 策略强制绑定的挑战在验证通过后启用两步验证，并返回新生成的恢复码
+func (s *TwoFactorService) CompleteLogin(req auth.TwoFactorLoginRequest) (*userModel.User, string, []string, error) {
+	challenge, err := getTwoFactorChallenge(req.ChallengeToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	user, err := s.getActiveUser(challenge.UserID)
+	if err != nil {
+		removeTwoFactorChallenge(req.ChallengeToken)
+		return nil, "", nil, err
+	}
+
+	var recoveryCodes []string
+	if challenge.EnrollRequired && !user.TwoFactorEnabled {
+		if user.TwoFactorSecret == "" {
+			return nil, "", nil, common.NewError(common.CodeInvalidParam, "请先获取两步验证密钥并完成绑定")
+		}
+		ok, err := verifyTOTPCode(user, req.Code)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if !ok {
+			failTwoFactorChallenge(req.ChallengeToken)
+			return nil, "", nil, common.NewError(common.CodeTwoFactorInvalid)
+		}
+		if recoveryCodes, err = s.enable(user); err != nil {
+			return nil, "", nil, err
+		}
+	} else {
+		if !user.TwoFactorEnabled {
+			return nil, "", nil, common.NewError(common.CodeInvalidParam, "请使用通行密钥完成验证")
+		}
+		ok, err := verifySecondFactor(user, req.Code, req.RecoveryCode)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if !ok {
+			failTwoFactorChallenge(req.ChallengeToken)
+			return nil, "", nil, common.NewError(common.CodeTwoFactorInvalid)
+		}
+	}
+
+	removeTwoFactorChallenge(req.ChallengeToken)
+
+	token, err := generateLoginToken(user)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	global.APP_LOG.Info("用户完成两步验证登录", zap.Uint("userID", user.ID), zap.String("username", user.Username))
+	return user, token, recoveryCodes, nil
+}
+
+// SetupWithChallenge 策略强制绑定时，凭登录挑战令牌获取两步验证绑定信息
+func (s *TwoFactorService) SetupWithChallenge(challengeToken string) (*auth.TwoFactorSetupResponse, error) {
+	challenge, err := getTwoFactorChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	if !challenge.EnrollRequired {
+		return nil, common.NewError(common.CodeInvalidParam, "两步验证已启用，无需重新绑定")
+	}
+	return s.Setup(challenge.UserID)
+}
+
+// GetStatus 获取用户两步验证状态
+func (s *TwoFactorService) GetStatus(userID uint) (*auth.TwoFactorStatusResponse, error) {
+	user, err := s.getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var remaining int64
+	if err := global.APP_DB.Model(&userModel.UserRecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).Count(&remaining).Error; err != nil {
+		return nil, err
+	}
+
+	return &auth.TwoFactorStatusResponse{
+		Enabled:                user.TwoFactorEnabled,
+		Required:               IsTwoFactorRequired(user),
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// Setup 生成新的TOTP密钥，启用前需使用验证码确认
+func (s *TwoFactorService) Setup(userID uint) (*auth.TwoFactorSetupResponse, error) {
+	user, err := s.getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, common.NewError(common.CodeConflict, "两步验证已启用")
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      twoFactorIssuer,
+		AccountName: user.Username,
+		Period:      twoFactorPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := key.Image(200, 200)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	if err := global.APP_DB.Model(user).Updates(map[string]interface{}{
+		"two_factor_secret":    key.Secret(),
+		"two_factor_last_step": 0,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return &auth.TwoFactorSetupResponse{
+		Secret:     key.Secret(),
+		OTPAuthURL: key.URL(),
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// Enable 验证TOTP验证码后启用两步验证，返回恢复码
+func (s *TwoFactorService) Enable(userID uint, code string) ([]string, error) {
+	user, err := s.getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, common.NewError(common.CodeConflict, "两步验证已启用")
+	}
+	if user.TwoFactorSecret == "" {
+		return nil, common.NewError(common.CodeInvalidParam, "请先获取两步验证密钥")
+	}
+
+	ok, err := verifyTOTPCode(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, common.NewError(common.CodeTwoFactorInvalid)
+	}
+	return s.enable(user)
+}
+
+// Disable 使用TOTP验证码或恢复码关闭两步验证
+func (s *TwoFactorService) Disable(userID uint, code, recoveryCode string) error {
+	user, err := s.getActiveUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled {
+		return common.NewError(common.CodeInvalidParam, "两步验证未启用")
+	}
+	if IsTwoFactorRequired(user) {
+		return common.NewError(common.CodeForbidden, "管理员要求启用两步验证，无法关闭")
+	}
+
+	ok, err := verifySecondFactor(user, code, recoveryCode)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return common.NewError(common.CodeTwoFactorInvalid)
+	}
+
+	if err := clearTwoFactor(global.APP_DB, userID); err != nil {
+		return err
+	}
+	global.APP_LOG.Info("用户关闭两步验证", zap.Uint("userID", userID))
+	return nil
+}
+
+// RegenerateRecoveryCodes 验证TOTP验证码后重新生成恢复码，旧恢复码全部作废
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	user, err := s.getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, common.NewError(common.CodeInvalidParam, "两步验证未启用")
+	}
+
+	ok, err := verifyTOTPCode(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, common.NewError(common.CodeTwoFactorInvalid)
+	}
+
+	var codes []string
+	err = global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Reset 管理员重置用户的两步验证，同时删除通行密钥并撤销该用户已签发的所有令牌
+func (s *TwoFactorService) Reset(userID, adminUserID uint) error {
+	var user userModel.User
+	if err := global.APP_DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return common.NewError(common.CodeUserNotFound)
+		}
+		return err
+	}
+
+	if err := clearTwoFactor(global.APP_DB, userID); err != nil {
+		return err
+	}
+	if err := global.APP_DB.Where("user_id = ?", userID).Delete(&userModel.UserWebAuthnCredential{}).Error; err != nil {
+		return err
+	}
+
+	blacklistService := JWTBlacklistService{}
+	if err := blacklistService.RevokeUserTokens(userID, "2fa_reset", adminUserID); err != nil {
+		return err
+	}
+
+	global.APP_LOG.Info("管理员重置用户两步验证",
+		zap.Uint("userID", userID),
+		zap.Uint("adminUserID", adminUserID))
+	return nil
+}
+
+// enable 启用两步验证并生成恢复码
+func (s *TwoFactorService) enable(user *userModel.User) ([]string, error) {
+	var codes []string
+	err := global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("two_factor_enabled", true).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	global.APP_LOG.Info("用户启用两步验证", zap.Uint("userID", user.ID))
+	return codes, nil
+}
+
+// getActiveUser 获取状态正常的用户
+func (s *TwoFactorService) getActiveUser(userID uint) (*userModel.User, error) {
+	var user userModel.User
+	if err := global.APP_DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, common.NewError(common.CodeUserNotFound)
+		}
+		return nil, err
+	}
+	if user.Status != 1 {
+		return nil, common.NewError(common.CodeUserDisabled)
+	}
+	return &user, nil
+}
+
+// clearTwoFactor 清除用户的两步验证密钥和恢复码
+func clearTwoFactor(db *gorm.DB, userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&userModel.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"two_factor_enabled":   false,
+			"two_factor_secret":    "",
+			"two_factor_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&userModel.UserRecoveryCode{}).Error
+	})
+}
+
+// verifySecondFactor 校验TOTP验证码或恢复码，优先使用恢复码
+func verifySecondFactor(user *userModel.User, code, recoveryCode string) (bool, error) {
+	if strings.TrimSpace(recoveryCode) != "" {
+		return useRecoveryCode(user.ID, recoveryCode)
+	}
+	return verifyTOTPCode(user, code)
+}
+
+// verifyTOTPCode 校验TOTP验证码，允许前后各一个时间步的偏差
+// 验证通过的时间步会被记录，同一时间步及更早的验证码不能再次使用
+func verifyTOTPCode(user *userModel.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if user.TwoFactorSecret == "" || len(code) != otp.DigitsSix.Length() {
+		return false, nil
+	}
+
+	current := time.Now().Unix() / twoFactorPeriod
+	for _, step := range []int64{current - 1, current, current + 1} {
+		if step <= user.TwoFactorLastStep {
+			continue
+		}
+		expected, err := totp.GenerateCodeCustom(user.TwoFactorSecret, time.Unix(step*twoFactorPeriod, 0), totpOpts)
+		if err != nil {
+			return false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) != 1 {
+			continue
+		}
+
+		// 条件更新，并发请求中只有一个能使用该验证码
+		result := global.APP_DB.Model(&userModel.User{}).
+			Where("id = ? AND two_factor_last_step < ?", user.ID, step).
+			Update("two_factor_last_step", step)
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return false, nil
+		}
+		user.TwoFactorLastStep = step
+		return true, nil
+	}
+	return false, nil
+}
+
+// replaceRecoveryCodes 生成新的恢复码并替换旧恢复码，返回明文恢复码
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&userModel.UserRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]userModel.UserRecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, userModel.UserRecoveryCode{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(code),
+		})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// useRecoveryCode 使用恢复码，每个恢复码只能使用一次
+func useRecoveryCode(userID uint, code string) (bool, error) {
+	now := time.Now()
+	result := global.APP_DB.Model(&userModel.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(code)).
+		Update("used_at", &now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	global.APP_LOG.Info("用户使用两步验证恢复码", zap.Uint("userID", userID))
+	return true, nil
+}
+
+// generateRecoveryCode 生成 xxxxx-xxxxx 格式的恢复码
+func generateRecoveryCode() (string, error) {
+	var sb strings.Builder
+	limit := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			sb.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(recoveryCodeAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// hashRecoveryCode 计算恢复码哈希，忽略大小写、空格和连字符
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
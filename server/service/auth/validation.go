@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"strings"
 
 	"oneclickvirt/global"
@@ -61,6 +62,15 @@ func (s *AuthValidationService) ClassifyLoginError(err error) *common.AppError {
 		return nil
 	}
 
+	// 限流和账户锁定错误保留原错误码，前端据此提示等待时间；LDAP服务不可用不属于凭据错误
+	var appErr *common.AppError
+	if errors.As(err, &appErr) {
+		switch appErr.Code {
+		case common.CodeTooManyRequests, common.CodeAccountLocked, common.CodeLDAPFailed:
+			return appErr
+		}
+	}
+
 	errMsg := err.Error()
 	if errMsg == "用户已被禁用，有问题请联系管理员" {
 		return common.NewError(common.CodeUserDisabled, errMsg)
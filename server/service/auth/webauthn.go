@@ -0,0 +1,488 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/auth"
+	"oneclickvirt/model/common"
+	userModel "oneclickvirt/model/user"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	passkeyDisplayName     = "OneClickVirt"
+	passkeySessionTTL      = 5 * time.Minute // 通行密钥注册/登录会话有效期
+	maxPasskeysPerUser     = 10              // 每个用户最多绑定的通行密钥数量
+	passkeyDefaultName     = "通行密钥"
+	passkeyPurposeRegister = "register"
+	passkeyPurposeLogin    = "login"
+	passkeyPurpose2FA      = "2fa"
+)
+
+// passkeySession 通行密钥注册/登录会话
+type passkeySession struct {
+	Purpose        string
+	UserID         uint
+	Name           string
+	ChallengeToken string
+	Data           webauthn.SessionData
+	Expiry         time.Time
+}
+
+var (
+	passkeySessions   = make(map[string]*passkeySession)
+	passkeySessionsMu sync.Mutex
+)
+
+// PasskeyService 通行密钥服务
+type PasskeyService struct{}
+
+// webAuthnUser 适配webauthn.User接口
+type webAuthnUser struct {
+	user        *userModel.User
+	credentials []webauthn.Credential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return []byte(u.user.UUID)
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	if u.user.Nickname != "" {
+		return u.user.Nickname
+	}
+	return u.user.Username
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+// newWebAuthn 根据配置创建WebAuthn实例
+// 未配置依赖方ID和来源时，使用前端URL推导
+func newWebAuthn() (*webauthn.WebAuthn, error) {
+	authConfig := global.APP_CONFIG.Auth
+	rpID := strings.TrimSpace(authConfig.WebAuthnRPID)
+	origins := splitCommaList(authConfig.WebAuthnOrigins)
+
+	if frontendURL := strings.TrimSpace(global.APP_CONFIG.System.FrontendURL); frontendURL != "" {
+		if u, err := url.Parse(frontendURL); err == nil && u.Host != "" {
+			if rpID == "" {
+				rpID = u.Hostname()
+			}
+			if len(origins) == 0 {
+				origins = []string{u.Scheme + "://" + u.Host}
+			}
+		}
+	}
+
+	if rpID == "" || len(origins) == 0 {
+		return nil, common.NewError(common.CodeConfigError, "未配置通行密钥依赖方，请设置前端URL或auth.webauthn-rp-id")
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: passkeyDisplayName,
+		RPOrigins:     origins,
+	})
+}
+
+// newPasskeySession 保存通行密钥会话并返回会话ID
+func newPasskeySession(session *passkeySession) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	sessionID := base64.RawURLEncoding.EncodeToString(b)
+
+	passkeySessionsMu.Lock()
+	defer passkeySessionsMu.Unlock()
+
+	now := time.Now()
+	for key, s := range passkeySessions {
+		if now.After(s.Expiry) {
+			delete(passkeySessions, key)
+		}
+	}
+	session.Expiry = now.Add(passkeySessionTTL)
+	passkeySessions[sessionID] = session
+	return sessionID, nil
+}
+
+// takePasskeySession 取出并删除通行密钥会话，每个会话只能使用一次
+func takePasskeySession(sessionID, purpose string) (*passkeySession, error) {
+	passkeySessionsMu.Lock()
+	defer passkeySessionsMu.Unlock()
+
+	session, ok := passkeySessions[sessionID]
+	delete(passkeySessions, sessionID)
+	if !ok || session.Purpose != purpose || time.Now().After(session.Expiry) {
+		return nil, common.NewError(common.CodeInvalidParam, "通行密钥会话已过期，请重试")
+	}
+	return session, nil
+}
+
+// HasPasskey 判断用户是否绑定了通行密钥
+func HasPasskey(userID uint) bool {
+	var count int64
+	global.APP_DB.Model(&userModel.UserWebAuthnCredential{}).Where("user_id = ?", userID).Count(&count)
+	return count > 0
+}
+
+// ListPasskeys 获取用户的通行密钥列表
+func (s *PasskeyService) ListPasskeys(userID uint) ([]userModel.UserWebAuthnCredential, error) {
+	var records []userModel.UserWebAuthnCredential
+	if err := global.APP_DB.Where("user_id = ?", userID).Order("id ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// BeginRegistration 开始注册通行密钥
+func (s *PasskeyService) BeginRegistration(userID uint, name string) (*auth.PasskeyBeginResponse, error) {
+	wa, err := newWebAuthn()
+	if err != nil {
+		return nil, err
+	}
+	waUser, err := loadWebAuthnUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(waUser.credentials) >= maxPasskeysPerUser {
+		return nil, common.NewError(common.CodeInvalidParam, "通行密钥数量已达上限")
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(waUser.credentials))
+	for _, cred := range waUser.credentials {
+		exclusions = append(exclusions, cred.Descriptor())
+	}
+
+	creation, session, err := wa.BeginRegistration(waUser,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementRequired,
+			UserVerification: protocol.VerificationRequired,
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = passkeyDefaultName
+	}
+	sessionID, err := newPasskeySession(&passkeySession{
+		Purpose: passkeyPurposeRegister,
+		UserID:  userID,
+		Name:    name,
+		Data:    *session,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &auth.PasskeyBeginResponse{SessionID: sessionID, Options: creation.Response}, nil
+}
+
+// FinishRegistration 完成通行密钥注册
+func (s *PasskeyService) FinishRegistration(userID uint, req auth.PasskeyFinishRequest) (*userModel.UserWebAuthnCredential, error) {
+	session, err := takePasskeySession(req.SessionID, passkeyPurposeRegister)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != userID {
+		return nil, common.NewError(common.CodeInvalidParam, "通行密钥会话已过期，请重试")
+	}
+
+	wa, err := newWebAuthn()
+	if err != nil {
+		return nil, err
+	}
+	waUser, err := loadWebAuthnUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(waUser.credentials) >= maxPasskeysPerUser {
+		return nil, common.NewError(common.CodeInvalidParam, "通行密钥数量已达上限")
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return nil, common.NewError(common.CodePasskeyInvalid, describeWebAuthnError(err))
+	}
+	credential, err := wa.CreateCredential(waUser, session.Data, parsed)
+	if err != nil {
+		global.APP_LOG.Warn("通行密钥注册校验失败", zap.Uint("userID", userID), zap.Error(err))
+		return nil, common.NewError(common.CodePasskeyInvalid, describeWebAuthnError(err))
+	}
+
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return nil, err
+	}
+	record := userModel.UserWebAuthnCredential{
+		UserID:       userID,
+		Name:         session.Name,
+		CredentialID: base64.RawURLEncoding.EncodeToString(credential.ID),
+		Credential:   string(data),
+	}
+	if err := global.APP_DB.Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	global.APP_LOG.Info("用户注册通行密钥", zap.Uint("userID", userID), zap.Uint("passkeyID", record.ID))
+	return &record, nil
+}
+
+// DeletePasskey 删除通行密钥
+// 管理员策略要求两步验证且用户未启用TOTP时，不允许删除最后一个通行密钥
+func (s *PasskeyService) DeletePasskey(userID, passkeyID uint) error {
+	var record userModel.UserWebAuthnCredential
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", passkeyID, userID).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return common.NewError(common.CodeNotFound, "通行密钥不存在")
+		}
+		return err
+	}
+
+	var user userModel.User
+	if err := global.APP_DB.First(&user, userID).Error; err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled && IsTwoFactorRequired(&user) {
+		var count int64
+		if err := global.APP_DB.Model(&userModel.UserWebAuthnCredential{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count <= 1 {
+			return common.NewError(common.CodeForbidden, "管理员要求启用两步验证，请先绑定验证器再删除最后一个通行密钥")
+		}
+	}
+
+	if err := global.APP_DB.Delete(&record).Error; err != nil {
+		return err
+	}
+	global.APP_LOG.Info("用户删除通行密钥", zap.Uint("userID", userID), zap.Uint("passkeyID", passkeyID))
+	return nil
+}
+
+// BeginLogin 开始通行密钥无密码登录，由浏览器选择可发现凭据
+func (s *PasskeyService) BeginLogin() (*auth.PasskeyBeginResponse, error) {
+	wa, err := newWebAuthn()
+	if err != nil {
+		return nil, err
+	}
+	assertion, session, err := wa.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := newPasskeySession(&passkeySession{
+		Purpose: passkeyPurposeLogin,
+		Data:    *session,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &auth.PasskeyBeginResponse{SessionID: sessionID, Options: assertion.Response}, nil
+}
+
+// FinishLogin 完成通行密钥无密码登录
+// 通行密钥要求用户验证（生物识别或PIN），本身即满足多因素，因此不再要求TOTP
+func (s *PasskeyService) FinishLogin(req auth.PasskeyFinishRequest) (*userModel.User, string, error) {
+	session, err := takePasskeySession(req.SessionID, passkeyPurposeLogin)
+	if err != nil {
+		return nil, "", err
+	}
+	wa, err := newWebAuthn()
+	if err != nil {
+		return nil, "", err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, "", common.NewError(common.CodePasskeyInvalid, describeWebAuthnError(err))
+	}
+
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		var user userModel.User
+		if err := global.APP_DB.Where("uuid = ?", string(userHandle)).First(&user).Error; err != nil {
+			return nil, err
+		}
+		return loadWebAuthnUser(user.ID)
+	}
+	found, credential, err := wa.ValidatePasskeyLogin(handler, session.Data, parsed)
+	if err != nil {
+		global.APP_LOG.Warn("通行密钥登录校验失败", zap.Error(err))
+		var appErr *common.AppError
+		if errors.As(err, &appErr) {
+			return nil, "", appErr
+		}
+		return nil, "", common.NewError(common.CodePasskeyInvalid, describeWebAuthnError(err))
+	}
+
+	waUser := found.(*webAuthnUser)
+	if err := updatePasskeyCredential(waUser.user.ID, credential); err != nil {
+		return nil, "", err
+	}
+
+	token, err := generateLoginToken(waUser.user)
+	if err != nil {
+		return nil, "", err
+	}
+	global.APP_LOG.Info("用户使用通行密钥登录", zap.Uint("userID", waUser.user.ID), zap.String("username", waUser.user.Username))
+	return waUser.user, token, nil
+}
+
+// BeginTwoFactor 开始使用通行密钥完成两步验证
+func (s *PasskeyService) BeginTwoFactor(challengeToken string) (*auth.PasskeyBeginResponse, error) {
+	challenge, err := getTwoFactorChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	wa, err := newWebAuthn()
+	if err != nil {
+		return nil, err
+	}
+	waUser, err := loadWebAuthnUser(challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(waUser.credentials) == 0 {
+		return nil, common.NewError(common.CodeInvalidParam, "未绑定通行密钥")
+	}
+
+	assertion, session, err := wa.BeginLogin(waUser, webauthn.WithUserVerification(protocol.VerificationPreferred))
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := newPasskeySession(&passkeySession{
+		Purpose:        passkeyPurpose2FA,
+		UserID:         challenge.UserID,
+		ChallengeToken: challengeToken,
+		Data:           *session,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &auth.PasskeyBeginResponse{SessionID: sessionID, Options: assertion.Response}, nil
+}
+
+// FinishTwoFactor 使用通行密钥完成两步验证登录
+func (s *PasskeyService) FinishTwoFactor(req auth.PasskeyTwoFactorFinishRequest) (*userModel.User, string, error) {
+	session, err := takePasskeySession(req.SessionID, passkeyPurpose2FA)
+	if err != nil {
+		return nil, "", err
+	}
+	if session.ChallengeToken != req.ChallengeToken {
+		return nil, "", common.NewError(common.CodeInvalidParam, "通行密钥会话已过期，请重试")
+	}
+	challenge, err := getTwoFactorChallenge(req.ChallengeToken)
+	if err != nil {
+		return nil, "", err
+	}
+	if challenge.UserID != session.UserID {
+		return nil, "", common.NewError(common.CodeTwoFactorExpired)
+	}
+
+	wa, err := newWebAuthn()
+	if err != nil {
+		return nil, "", err
+	}
+	waUser, err := loadWebAuthnUser(challenge.UserID)
+	if err != nil {
+		removeTwoFactorChallenge(req.ChallengeToken)
+		return nil, "", err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		failTwoFactorChallenge(req.ChallengeToken)
+		return nil, "", common.NewError(common.CodePasskeyInvalid, describeWebAuthnError(err))
+	}
+	credential, err := wa.ValidateLogin(waUser, session.Data, parsed)
+	if err != nil {
+		global.APP_LOG.Warn("通行密钥两步验证失败", zap.Uint("userID", challenge.UserID), zap.Error(err))
+		failTwoFactorChallenge(req.ChallengeToken)
+		return nil, "", common.NewError(common.CodePasskeyInvalid, describeWebAuthnError(err))
+	}
+	if err := updatePasskeyCredential(challenge.UserID, credential); err != nil {
+		return nil, "", err
+	}
+
+	removeTwoFactorChallenge(req.ChallengeToken)
+
+	token, err := generateLoginToken(waUser.user)
+	if err != nil {
+		return nil, "", err
+	}
+	global.APP_LOG.Info("用户使用通行密钥完成两步验证登录", zap.Uint("userID", waUser.user.ID), zap.String("username", waUser.user.Username))
+	return waUser.user, token, nil
+}
+
+// loadWebAuthnUser 加载状态正常的用户及其通行密钥
+func loadWebAuthnUser(userID uint) (*webAuthnUser, error) {
+	twoFactorService := TwoFactorService{}
+	user, err := twoFactorService.getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []userModel.UserWebAuthnCredential
+	if err := global.APP_DB.Where("user_id = ?", userID).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	credentials := make([]webauthn.Credential, 0, len(records))
+	for _, record := range records {
+		var credential webauthn.Credential
+		if err := json.Unmarshal([]byte(record.Credential), &credential); err != nil {
+			global.APP_LOG.Warn("解析通行密钥数据失败", zap.Uint("passkeyID", record.ID), zap.Error(err))
+			continue
+		}
+		credentials = append(credentials, credential)
+	}
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+// updatePasskeyCredential 登录成功后更新签名计数和最近使用时间
+// 签名计数回退说明凭据可能被克隆，拒绝本次登录
+func updatePasskeyCredential(userID uint, credential *webauthn.Credential) error {
+	if credential.Authenticator.CloneWarning {
+		global.APP_LOG.Warn("通行密钥签名计数异常，可能已被克隆", zap.Uint("userID", userID))
+		return common.NewError(common.CodePasskeyInvalid, "通行密钥签名计数异常，请联系管理员")
+	}
+
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+	return global.APP_DB.Model(&userModel.UserWebAuthnCredential{}).
+		Where("user_id = ? AND credential_id = ?", userID, base64.RawURLEncoding.EncodeToString(credential.ID)).
+		Updates(map[string]interface{}{
+			"credential":   string(data),
+			"last_used_at": time.Now(),
+		}).Error
+}
+
+// describeWebAuthnError 提取WebAuthn错误的详细信息
+func describeWebAuthnError(err error) string {
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) && protocolErr.DevInfo != "" {
+		return protocolErr.DevInfo
+	}
+	return err.Error()
+}
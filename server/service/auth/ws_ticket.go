@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// wsTicketTTL WebSocket连接票据的有效期
+const wsTicketTTL = 30 * time.Second
+
+// wsTicket 票据对应的认证令牌
+type wsTicket struct {
+	token     string
+	expiresAt time.Time
+}
+
+// wsTickets 按一次性票据索引的待使用令牌
+var wsTickets = struct {
+	sync.Mutex
+	items map[string]wsTicket
+}{items: make(map[string]wsTicket)}
+
+// IssueWebSocketTicket 为当前请求的认证令牌签发一次性WebSocket连接票据，返回票据和有效期（秒）
+// 浏览器建立WebSocket连接时无法设置请求头，以短期票据代替令牌放在URL中
+func IssueWebSocketTicket(token string) (string, int) {
+	ticket := uuid.New().String()
+	now := time.Now()
+
+	wsTickets.Lock()
+	defer wsTickets.Unlock()
+
+	// 顺带清理过期未使用的票据
+	for key, item := range wsTickets.items {
+		if now.After(item.expiresAt) {
+			delete(wsTickets.items, key)
+		}
+	}
+	wsTickets.items[ticket] = wsTicket{token: token, expiresAt: now.Add(wsTicketTTL)}
+	return ticket, int(wsTicketTTL.Seconds())
+}
+
+// RedeemWebSocketTicket 取出票据对应的令牌，票据无论是否过期只能使用一次
+func RedeemWebSocketTicket(ticket string) (string, bool) {
+	wsTickets.Lock()
+	defer wsTickets.Unlock()
+
+	item, ok := wsTickets.items[ticket]
+	delete(wsTickets.items, ticket)
+	if !ok || time.Now().After(item.expiresAt) {
+		return "", false
+	}
+	return item.token, true
+}
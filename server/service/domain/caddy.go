@@ -0,0 +1,186 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"oneclickvirt/global"
+	providerModel "oneclickvirt/model/provider"
+	"oneclickvirt/provider/portmapping"
+
+	"go.uber.org/zap"
+)
+
+// caddySiteFile 节点上由系统管理的Caddy站点配置，通过import引入主配置文件
+const caddySiteFile = "/etc/caddy/oneclickvirt.caddy"
+
+// providerLocks 每个Provider一把锁，避免并发生成配置时互相覆盖
+var providerLocks sync.Map
+
+func providerLock(providerID uint) *sync.Mutex {
+	lock, _ := providerLocks.LoadOrStore(providerID, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// SyncProvider 按数据库中已生效的域名绑定重新生成节点上的Caddy站点配置并重载
+// 每次都生成完整配置，校验通过后再替换，不会留下半生效的状态
+func SyncProvider(providerID uint) error {
+	lock := providerLock(providerID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	var providerInfo providerModel.Provider
+	if err := global.APP_DB.First(&providerInfo, providerID).Error; err != nil {
+		return fmt.Errorf("Provider不存在")
+	}
+
+	var records []providerModel.InstanceDomain
+	if err := global.APP_DB.Where("provider_id = ? AND status = ?", providerID, "active").
+		Order("domain ASC").Find(&records).Error; err != nil {
+		return fmt.Errorf("获取域名绑定失败: %v", err)
+	}
+
+	content, err := renderCaddySites(records)
+	if err != nil {
+		return err
+	}
+
+	sshClient, err := portmapping.NewProviderSSHClient(&providerInfo)
+	if err != nil {
+		return fmt.Errorf("连接节点失败: %v", err)
+	}
+	defer sshClient.Close()
+
+	if _, err := sshClient.Execute("command -v caddy"); err != nil {
+		return fmt.Errorf("节点未安装Caddy，无法提供域名绑定")
+	}
+
+	tmpFile := caddySiteFile + ".new"
+	if err := sshClient.UploadContent(content, tmpFile, 0644); err != nil {
+		return fmt.Errorf("上传Caddy配置失败: %v", err)
+	}
+	if len(records) > 0 {
+		if output, err := sshClient.Execute(fmt.Sprintf("caddy validate --adapter caddyfile --config %s 2>&1", tmpFile)); err != nil {
+			sshClient.Execute("rm -f " + tmpFile)
+			return fmt.Errorf("Caddy配置校验失败: %v %s", err, strings.TrimSpace(output))
+		}
+	}
+
+	caddyFile := global.APP_CONFIG.DomainBinding.CaddyFile
+	if caddyFile == "" {
+		caddyFile = "/etc/caddy/Caddyfile"
+	}
+	importLine := "import " + caddySiteFile
+	cmd := fmt.Sprintf("mv -f %s %s && (grep -qxF '%s' %s || echo '%s' >> %s) && (systemctl reload caddy 2>/dev/null || caddy reload --adapter caddyfile --config %s)",
+		tmpFile, caddySiteFile, importLine, caddyFile, importLine, caddyFile, caddyFile)
+	if output, err := sshClient.Execute(cmd); err != nil {
+		return fmt.Errorf("重载Caddy失败: %v %s", err, strings.TrimSpace(output))
+	}
+
+	global.APP_LOG.Info("节点域名绑定配置已同步",
+		zap.Uint("providerID", providerID),
+		zap.Int("domains", len(records)))
+	return nil
+}
+
+// renderCaddySites 生成站点配置，Caddy会为每个站点自动申请证书并按SNI路由
+func renderCaddySites(records []providerModel.InstanceDomain) (string, error) {
+	instanceIDs := make([]uint, 0, len(records))
+	for _, record := range records {
+		instanceIDs = append(instanceIDs, record.InstanceID)
+	}
+	instanceIPs := make(map[uint]string)
+	if len(instanceIDs) > 0 {
+		var instances []providerModel.Instance
+		if err := global.APP_DB.Select("id", "private_ip").Where("id IN ?", instanceIDs).Find(&instances).Error; err != nil {
+			return "", fmt.Errorf("获取实例信息失败: %v", err)
+		}
+		for _, instance := range instances {
+			instanceIPs[instance.ID] = portmapping.CleanIP(instance.PrivateIP)
+		}
+	}
+
+	email := global.APP_CONFIG.DomainBinding.ACMEEmail
+
+	var b strings.Builder
+	b.WriteString("# 由 OneClickVirt 根据数据库生成，请勿手动修改\n")
+	for _, record := range records {
+		ip := instanceIPs[record.InstanceID]
+		if ip == "" {
+			global.APP_LOG.Warn("实例内网IP为空，跳过域名绑定",
+				zap.Uint("instanceID", record.InstanceID),
+				zap.String("domain", record.Domain))
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s {\n", record.Domain)
+		if email != "" {
+			fmt.Fprintf(&b, "\ttls %s\n", email)
+		}
+		fmt.Fprintf(&b, "\treverse_proxy %s:%d\n}\n", ip, record.GuestPort)
+	}
+	return b.String(), nil
+}
+
+// CleanupInstanceDomains 删除实例的全部域名绑定，并在后台同步相关节点的配置
+func CleanupInstanceDomains(instanceID uint) {
+	var providerIDs []uint
+	global.APP_DB.Model(&providerModel.InstanceDomain{}).
+		Where("instance_id = ? AND status = ?", instanceID, "active").
+		Distinct().Pluck("provider_id", &providerIDs)
+
+	if err := global.APP_DB.Where("instance_id = ?", instanceID).Delete(&providerModel.InstanceDomain{}).Error; err != nil {
+		global.APP_LOG.Warn("删除实例域名绑定失败", zap.Uint("instanceID", instanceID), zap.Error(err))
+		return
+	}
+	syncProvidersAsync(providerIDs)
+}
+
+// SyncInstanceDomains 实例迁移或重置后调用：将域名绑定指向实例当前所在节点，并同步新旧节点的配置
+func SyncInstanceDomains(instanceID uint) {
+	var records []providerModel.InstanceDomain
+	if err := global.APP_DB.Where("instance_id = ?", instanceID).Find(&records).Error; err != nil || len(records) == 0 {
+		return
+	}
+
+	var instance providerModel.Instance
+	if err := global.APP_DB.Select("id", "provider_id").First(&instance, instanceID).Error; err != nil {
+		return
+	}
+
+	providerIDs := []uint{instance.ProviderID}
+	for _, record := range records {
+		if record.ProviderID != instance.ProviderID {
+			providerIDs = append(providerIDs, record.ProviderID)
+		}
+	}
+	if len(providerIDs) > 1 {
+		if err := global.APP_DB.Model(&providerModel.InstanceDomain{}).Where("instance_id = ?", instanceID).
+			Update("provider_id", instance.ProviderID).Error; err != nil {
+			global.APP_LOG.Warn("更新域名绑定节点失败", zap.Uint("instanceID", instanceID), zap.Error(err))
+			return
+		}
+	}
+	syncProvidersAsync(providerIDs)
+}
+
+// syncProvidersAsync 在后台同步多个节点的配置，失败只记录日志
+func syncProvidersAsync(providerIDs []uint) {
+	if len(providerIDs) == 0 {
+		return
+	}
+	go func() {
+		seen := make(map[uint]bool)
+		for _, providerID := range providerIDs {
+			if seen[providerID] {
+				continue
+			}
+			seen[providerID] = true
+			if err := SyncProvider(providerID); err != nil {
+				global.APP_LOG.Warn("同步节点域名绑定配置失败",
+					zap.Uint("providerID", providerID),
+					zap.Error(err))
+			}
+		}
+	}()
+}
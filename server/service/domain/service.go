@@ -0,0 +1,352 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	providerModel "oneclickvirt/model/provider"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultMaxDomains 等级未配置域名数量时的默认上限
+const defaultMaxDomains = 1
+
+// Service 实例域名绑定服务
+type Service struct{}
+
+// NewService 创建域名绑定服务
+func NewService() *Service {
+	return &Service{}
+}
+
+// GetUserMaxDomains 获取用户等级对应的可绑定域名数量
+func GetUserMaxDomains(level int) int {
+	if levelLimits, exists := global.APP_CONFIG.Quota.LevelLimits[level]; exists && levelLimits.MaxDomains > 0 {
+		return levelLimits.MaxDomains
+	}
+	return defaultMaxDomains
+}
+
+// ListUserDomains 获取用户的域名绑定列表
+func (s *Service) ListUserDomains(userID uint) (*userModel.DomainListResponse, error) {
+	var user userModel.User
+	if err := global.APP_DB.Select("level").First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %v", err)
+	}
+
+	var records []providerModel.InstanceDomain
+	if err := global.APP_DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("获取域名绑定失败: %v", err)
+	}
+
+	domains, err := buildBindingInfos(records)
+	if err != nil {
+		return nil, err
+	}
+	return &userModel.DomainListResponse{
+		Domains:    domains,
+		MaxDomains: GetUserMaxDomains(user.Level),
+	}, nil
+}
+
+// CreateDomain 为用户实例添加域名绑定，创建后处于待验证状态，需添加TXT记录后调用验证
+func (s *Service) CreateDomain(userID uint, req userModel.CreateDomainRequest) (*userModel.DomainBindingInfo, error) {
+	if !global.APP_CONFIG.DomainBinding.Enabled {
+		return nil, errors.New("域名绑定功能未启用")
+	}
+
+	domain, err := normalizeDomain(req.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var instance providerModel.Instance
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", req.InstanceID, userID).First(&instance).Error; err != nil {
+		return nil, errors.New("实例不存在或无权限")
+	}
+	var providerInfo providerModel.Provider
+	if err := global.APP_DB.First(&providerInfo, instance.ProviderID).Error; err != nil {
+		return nil, errors.New("实例所在节点不存在")
+	}
+	// 独立IP实例可直接在实例内部署Web服务，只有共享公网IP的NAT实例需要反向代理
+	if providerInfo.NetworkType != "nat_ipv4" && providerInfo.NetworkType != "nat_ipv4_ipv6" {
+		return nil, errors.New("仅NAT网络的实例支持域名绑定")
+	}
+
+	var user userModel.User
+	if err := global.APP_DB.Select("level").First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %v", err)
+	}
+	maxDomains := GetUserMaxDomains(user.Level)
+
+	token, err := generateVerifyToken()
+	if err != nil {
+		return nil, fmt.Errorf("生成验证值失败: %v", err)
+	}
+
+	record := providerModel.InstanceDomain{
+		UserID:      userID,
+		InstanceID:  instance.ID,
+		ProviderID:  instance.ProviderID,
+		Domain:      domain,
+		GuestPort:   req.GuestPort,
+		Status:      "pending",
+		VerifyToken: token,
+	}
+	err = global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		// 在事务中检查数量，避免并发创建超出限制
+		var count int64
+		if err := tx.Model(&providerModel.InstanceDomain{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) >= maxDomains {
+			return fmt.Errorf("域名绑定数量已达上限（%d个）", maxDomains)
+		}
+
+		// 其他用户可以同时申请同一域名，以先通过验证者为准
+		var existing int64
+		if err := tx.Model(&providerModel.InstanceDomain{}).
+			Where("domain = ? AND (status = ? OR user_id = ?)", domain, "active", userID).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return errors.New("该域名已被绑定")
+		}
+		return tx.Create(&record).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	global.APP_LOG.Info("创建域名绑定",
+		zap.Uint("userID", userID),
+		zap.Uint("instanceID", instance.ID),
+		zap.String("domain", domain))
+
+	infos, err := buildBindingInfos([]providerModel.InstanceDomain{record})
+	if err != nil {
+		return nil, err
+	}
+	return &infos[0], nil
+}
+
+// VerifyDomain 验证域名所有权，通过后在节点上生效
+func (s *Service) VerifyDomain(ctx context.Context, userID, id uint) (*userModel.DomainBindingInfo, error) {
+	if !global.APP_CONFIG.DomainBinding.Enabled {
+		return nil, errors.New("域名绑定功能未启用")
+	}
+
+	record, err := getUserDomain(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status == "active" {
+		return nil, errors.New("域名已生效，无需重复验证")
+	}
+
+	var providerInfo providerModel.Provider
+	if err := global.APP_DB.First(&providerInfo, record.ProviderID).Error; err != nil {
+		return nil, errors.New("实例所在节点不存在")
+	}
+
+	if err := verifyDNS(ctx, record, &providerInfo); err != nil {
+		global.APP_DB.Model(record).Update("last_error", utils.TruncateString(err.Error(), 500))
+		return nil, err
+	}
+
+	// 验证通过时再确认域名未被他人抢先绑定
+	// 先锁定该域名的全部绑定再更新，MySQL不允许UPDATE的子查询读取被更新的表
+	now := time.Now()
+	err = global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		var bindings []providerModel.InstanceDomain
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").
+			Where("domain = ?", record.Domain).Find(&bindings).Error; err != nil {
+			return fmt.Errorf("更新域名状态失败: %v", err)
+		}
+		found := false
+		for _, binding := range bindings {
+			if binding.ID == record.ID {
+				found = true
+			} else if binding.Status == "active" {
+				return errors.New("该域名已被绑定")
+			}
+		}
+		if !found {
+			return errors.New("域名绑定不存在")
+		}
+		if err := tx.Model(&providerModel.InstanceDomain{}).Where("id = ?", record.ID).
+			Updates(map[string]interface{}{"status": "active", "verified_at": now, "last_error": ""}).Error; err != nil {
+			return fmt.Errorf("更新域名状态失败: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := SyncProvider(record.ProviderID); err != nil {
+		global.APP_LOG.Error("域名绑定生效失败",
+			zap.Uint("domainID", record.ID),
+			zap.String("domain", record.Domain),
+			zap.Error(err))
+		global.APP_DB.Model(record).Updates(map[string]interface{}{
+			"status":     "failed",
+			"last_error": utils.TruncateString(err.Error(), 500),
+		})
+		// 失败的绑定不应继续出现在节点配置中
+		syncProvidersAsync([]uint{record.ProviderID})
+		return nil, fmt.Errorf("域名绑定在节点上生效失败: %v", err)
+	}
+
+	global.APP_LOG.Info("域名绑定已生效",
+		zap.Uint("userID", userID),
+		zap.Uint("domainID", record.ID),
+		zap.String("domain", record.Domain))
+
+	if err := global.APP_DB.First(record, record.ID).Error; err != nil {
+		return nil, err
+	}
+	infos, err := buildBindingInfos([]providerModel.InstanceDomain{*record})
+	if err != nil {
+		return nil, err
+	}
+	return &infos[0], nil
+}
+
+// DeleteDomain 删除用户的域名绑定
+func (s *Service) DeleteDomain(userID, id uint) error {
+	record, err := getUserDomain(userID, id)
+	if err != nil {
+		return err
+	}
+	return deleteDomain(record)
+}
+
+// ListDomains 管理员获取域名绑定列表
+func (s *Service) ListDomains(req adminModel.DomainListRequest) ([]userModel.DomainBindingInfo, int64, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	query := global.APP_DB.Model(&providerModel.InstanceDomain{})
+	if req.Keyword != "" {
+		query = query.Where("domain LIKE ?", "%"+req.Keyword+"%")
+	}
+	if req.UserID > 0 {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	if req.ProviderID > 0 {
+		query = query.Where("provider_id = ?", req.ProviderID)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取域名绑定总数失败: %v", err)
+	}
+
+	var records []providerModel.InstanceDomain
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Order("created_at DESC").Offset(offset).Limit(req.PageSize).Find(&records).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取域名绑定失败: %v", err)
+	}
+
+	infos, err := buildBindingInfos(records)
+	if err != nil {
+		return nil, 0, err
+	}
+	return infos, total, nil
+}
+
+// AdminDeleteDomain 管理员删除任意域名绑定
+func (s *Service) AdminDeleteDomain(id uint) error {
+	var record providerModel.InstanceDomain
+	if err := global.APP_DB.First(&record, id).Error; err != nil {
+		return errors.New("域名绑定不存在")
+	}
+	return deleteDomain(&record)
+}
+
+// getUserDomain 获取用户的域名绑定
+func getUserDomain(userID, id uint) (*providerModel.InstanceDomain, error) {
+	var record providerModel.InstanceDomain
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", id, userID).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("域名绑定不存在")
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// deleteDomain 删除绑定记录，已生效的绑定同时在后台更新节点配置
+func deleteDomain(record *providerModel.InstanceDomain) error {
+	if err := global.APP_DB.Delete(record).Error; err != nil {
+		return fmt.Errorf("删除域名绑定失败: %v", err)
+	}
+	if record.Status == "active" {
+		syncProvidersAsync([]uint{record.ProviderID})
+	}
+
+	global.APP_LOG.Info("删除域名绑定",
+		zap.Uint("domainID", record.ID),
+		zap.Uint("userID", record.UserID),
+		zap.String("domain", record.Domain))
+	return nil
+}
+
+// buildBindingInfos 补充实例名称和DNS配置说明
+func buildBindingInfos(records []providerModel.InstanceDomain) ([]userModel.DomainBindingInfo, error) {
+	instanceIDs := make([]uint, 0, len(records))
+	providerIDs := make([]uint, 0, len(records))
+	for _, record := range records {
+		instanceIDs = append(instanceIDs, record.InstanceID)
+		providerIDs = append(providerIDs, record.ProviderID)
+	}
+
+	instanceNames := make(map[uint]string)
+	providerIPs := make(map[uint]string)
+	if len(records) > 0 {
+		var instances []providerModel.Instance
+		if err := global.APP_DB.Select("id", "name").Where("id IN ?", instanceIDs).Find(&instances).Error; err != nil {
+			return nil, fmt.Errorf("获取实例信息失败: %v", err)
+		}
+		for _, instance := range instances {
+			instanceNames[instance.ID] = instance.Name
+		}
+
+		var providers []providerModel.Provider
+		if err := global.APP_DB.Where("id IN ?", providerIDs).Find(&providers).Error; err != nil {
+			return nil, fmt.Errorf("获取节点信息失败: %v", err)
+		}
+		for i := range providers {
+			providerIPs[providers[i].ID] = providerPublicIP(&providers[i])
+		}
+	}
+
+	infos := make([]userModel.DomainBindingInfo, 0, len(records))
+	for _, record := range records {
+		infos = append(infos, userModel.DomainBindingInfo{
+			InstanceDomain: record,
+			InstanceName:   instanceNames[record.InstanceID],
+			TXTRecord:      verifyRecordName(record.Domain),
+			TargetIP:       providerIPs[record.ProviderID],
+		})
+	}
+	return infos, nil
+}
@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	providerModel "oneclickvirt/model/provider"
+)
+
+// dnsLookupTimeout 单次DNS查询超时
+const dnsLookupTimeout = 10 * time.Second
+
+// domainPattern 允许绑定的域名格式：至少两级，仅字母数字和连字符，不支持通配符
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// normalizeDomain 统一为小写并去除末尾的点，校验格式
+func normalizeDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if len(domain) > 253 || !domainPattern.MatchString(domain) {
+		return "", fmt.Errorf("域名格式不合法")
+	}
+	return domain, nil
+}
+
+// generateVerifyToken 生成TXT记录验证值
+func generateVerifyToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "oneclickvirt-" + hex.EncodeToString(b), nil
+}
+
+// verifyRecordName 所有权验证TXT记录名称
+func verifyRecordName(domain string) string {
+	prefix := global.APP_CONFIG.DomainBinding.VerifyPrefix
+	if prefix == "" {
+		prefix = "_oneclickvirt-verify"
+	}
+	return prefix + "." + domain
+}
+
+// providerPublicIP 节点公网IP，优先使用端口映射专用IP，其次为SSH地址中的主机部分
+func providerPublicIP(providerInfo *providerModel.Provider) string {
+	if providerInfo.PortIP != "" {
+		return providerInfo.PortIP
+	}
+	host := providerInfo.Endpoint
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return host
+}
+
+// verifyDNS 检查TXT记录包含验证值，且域名已解析到节点公网IP（否则ACME无法签发证书）
+func verifyDNS(ctx context.Context, record *providerModel.InstanceDomain, providerInfo *providerModel.Provider) error {
+	ctx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+	defer cancel()
+	resolver := net.DefaultResolver
+
+	txtName := verifyRecordName(record.Domain)
+	txts, err := resolver.LookupTXT(ctx, txtName)
+	if err != nil {
+		return fmt.Errorf("未查询到TXT记录 %s", txtName)
+	}
+	found := false
+	for _, txt := range txts {
+		if strings.TrimSpace(txt) == record.VerifyToken {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("TXT记录 %s 的值与验证值不一致", txtName)
+	}
+
+	// 节点地址可能是域名，解析后比较
+	expected := make(map[string]bool)
+	publicHost := providerPublicIP(providerInfo)
+	if ip := net.ParseIP(publicHost); ip != nil {
+		expected[ip.String()] = true
+	} else {
+		ips, err := resolver.LookupIPAddr(ctx, publicHost)
+		if err != nil {
+			return fmt.Errorf("无法解析节点地址")
+		}
+		for _, ip := range ips {
+			expected[ip.IP.String()] = true
+		}
+	}
+
+	ips, err := resolver.LookupIPAddr(ctx, record.Domain)
+	if err != nil || len(ips) == 0 {
+		return fmt.Errorf("域名 %s 未解析到任何地址，请添加指向 %s 的A记录", record.Domain, publicHost)
+	}
+	for _, ip := range ips {
+		if expected[ip.IP.String()] {
+			return nil
+		}
+	}
+	return fmt.Errorf("域名 %s 未解析到节点地址 %s", record.Domain, publicHost)
+}
@@ -0,0 +1,454 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"oneclickvirt/config"
+	"oneclickvirt/global"
+	"oneclickvirt/model/auth"
+	"oneclickvirt/model/common"
+	"oneclickvirt/model/user"
+	auth2 "oneclickvirt/service/auth"
+	"oneclickvirt/service/oauth2"
+	"oneclickvirt/utils"
+
+	ldapv3 "github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Service LDAP/AD认证服务
+// 先用服务账户搜索用户条目和所属组，再以用户DN和密码绑定校验凭据
+type Service struct{}
+
+// Entry LDAP用户条目中与登录相关的信息
+type Entry struct {
+	DN       string   `json:"dn"`
+	Username string   `json:"username"`
+	Email    string   `json:"email"`
+	Nickname string   `json:"nickname"`
+	Groups   []string `json:"groups"`
+	Level    int      `json:"level"` // 按组映射得到的用户等级
+}
+
+// TestResult 连接测试结果
+type TestResult struct {
+	URL           string `json:"url"`
+	Connected     bool   `json:"connected"`     // 连接及服务账户绑定是否成功
+	UserFound     bool   `json:"userFound"`     // 是否找到测试用户
+	Authenticated bool   `json:"authenticated"` // 测试用户密码是否正确，未提供密码时为false
+	User          *Entry `json:"user,omitempty"`
+}
+
+var (
+	errUserNotFound    = errors.New("LDAP用户不存在")
+	errMultipleEntries = errors.New("LDAP用户过滤器匹配到多个条目")
+)
+
+// ldapConfig 获取LDAP配置，未配置的项使用默认值
+func ldapConfig() config.LDAP {
+	cfg := global.APP_CONFIG.Auth.LDAP
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(uid=%s)"
+	}
+	if cfg.UsernameAttribute == "" {
+		cfg.UsernameAttribute = "uid"
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if cfg.NicknameAttribute == "" {
+		cfg.NicknameAttribute = "cn"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	if cfg.GroupFilter == "" {
+		cfg.GroupFilter = "(member=%s)"
+	}
+	if cfg.TimeoutSeconds <= 0 {
+		cfg.TimeoutSeconds = 10
+	}
+	return cfg
+}
+
+// connect 连接LDAP服务器并以服务账户绑定
+func (s *Service) connect(cfg config.LDAP) (*ldapv3.Conn, error) {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, common.NewError(common.CodeConfigError, "LDAP服务器地址或基础DN未配置")
+	}
+
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if u, err := url.Parse(cfg.URL); err == nil {
+		// StartTLS时证书校验需要显式指定主机名
+		tlsConfig.ServerName = u.Hostname()
+	}
+
+	conn, err := ldapv3.DialURL(cfg.URL,
+		ldapv3.DialWithDialer(&net.Dialer{Timeout: timeout}),
+		ldapv3.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, common.NewError(common.CodeLDAPFailed, fmt.Sprintf("连接LDAP服务器失败: %v", err))
+	}
+	conn.SetTimeout(timeout)
+
+	if cfg.StartTLS && !strings.HasPrefix(strings.ToLower(cfg.URL), "ldaps://") {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, common.NewError(common.CodeLDAPFailed, fmt.Sprintf("LDAP StartTLS失败: %v", err))
+		}
+	}
+
+	if cfg.BindDN != "" {
+		err = conn.Bind(cfg.BindDN, cfg.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		conn.Close()
+		return nil, common.NewError(common.CodeLDAPFailed, fmt.Sprintf("LDAP服务账户绑定失败: %v", err))
+	}
+	return conn, nil
+}
+
+// searchUser 按用户过滤器搜索用户条目，必须恰好匹配一个条目
+func (s *Service) searchUser(conn *ldapv3.Conn, cfg config.LDAP, username string) (*ldapv3.Entry, error) {
+	filter := strings.ReplaceAll(cfg.UserFilter, "%s", ldapv3.EscapeFilter(username))
+	attributes := []string{cfg.UsernameAttribute, cfg.EmailAttribute, cfg.NicknameAttribute, cfg.GroupAttribute}
+	req := ldapv3.NewSearchRequest(cfg.BaseDN, ldapv3.ScopeWholeSubtree, ldapv3.NeverDerefAliases,
+		2, cfg.TimeoutSeconds, false, filter, attributes, nil)
+
+	result, err := conn.Search(req)
+	if err != nil {
+		if ldapv3.IsErrorWithCode(err, ldapv3.LDAPResultSizeLimitExceeded) {
+			return nil, errMultipleEntries
+		}
+		return nil, common.NewError(common.CodeLDAPFailed, fmt.Sprintf("搜索LDAP用户失败: %v", err))
+	}
+	switch len(result.Entries) {
+	case 0:
+		return nil, errUserNotFound
+	case 1:
+		return result.Entries[0], nil
+	default:
+		return nil, errMultipleEntries
+	}
+}
+
+// searchGroups 获取用户所属的组DN
+// 优先读取用户条目上的memberOf类属性，配置了组搜索基础DN时再按成员关系搜索组条目
+func (s *Service) searchGroups(conn *ldapv3.Conn, cfg config.LDAP, entry *ldapv3.Entry) ([]string, error) {
+	groups := entry.GetEqualFoldAttributeValues(cfg.GroupAttribute)
+	if cfg.GroupBaseDN == "" {
+		return groups, nil
+	}
+
+	filter := strings.ReplaceAll(cfg.GroupFilter, "%s", ldapv3.EscapeFilter(entry.DN))
+	req := ldapv3.NewSearchRequest(cfg.GroupBaseDN, ldapv3.ScopeWholeSubtree, ldapv3.NeverDerefAliases,
+		0, cfg.TimeoutSeconds, false, filter, []string{"dn"}, nil)
+	result, err := conn.Search(req)
+	if err != nil {
+		return nil, common.NewError(common.CodeLDAPFailed, fmt.Sprintf("搜索LDAP组失败: %v", err))
+	}
+	for _, group := range result.Entries {
+		groups = append(groups, group.DN)
+	}
+	return groups, nil
+}
+
+// lookup 搜索用户条目和所属组，连接由调用方关闭
+func (s *Service) lookup(conn *ldapv3.Conn, cfg config.LDAP, username string) (*Entry, error) {
+	entry, err := s.searchUser(conn, cfg, username)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.searchGroups(conn, cfg, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Entry{
+		DN:       entry.DN,
+		Username: entry.GetEqualFoldAttributeValue(cfg.UsernameAttribute),
+		Email:    entry.GetEqualFoldAttributeValue(cfg.EmailAttribute),
+		Nickname: entry.GetEqualFoldAttributeValue(cfg.NicknameAttribute),
+		Groups:   groups,
+	}
+	if result.Username == "" {
+		result.Username = username
+	}
+	result.Level = s.GetUserLevel(cfg, groups)
+	return result, nil
+}
+
+// Authenticate 校验LDAP用户名和密码，返回用户条目信息
+func (s *Service) Authenticate(username, password string) (*Entry, error) {
+	// 空密码的简单绑定会被服务器视为匿名绑定而返回成功，必须拒绝
+	if strings.TrimSpace(username) == "" || password == "" {
+		return nil, common.NewError(common.CodeInvalidParam, "用户名和密码不能为空")
+	}
+
+	cfg := ldapConfig()
+	conn, err := s.connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := s.lookup(conn, cfg, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldapv3.IsErrorWithCode(err, ldapv3.LDAPResultInvalidCredentials) {
+			return nil, common.NewError(common.CodeInvalidCredentials)
+		}
+		return nil, common.NewError(common.CodeLDAPFailed, fmt.Sprintf("LDAP用户绑定失败: %v", err))
+	}
+	return entry, nil
+}
+
+// GetUserLevel 根据所属组获取系统用户等级，映射键可以是组DN或CN，匹配多个时取最高等级
+func (s *Service) GetUserLevel(cfg config.LDAP, groups []string) int {
+	level := 0
+	for key, mapped := range cfg.GroupLevelMapping {
+		for _, group := range groups {
+			if groupMatches(key, group) && mapped > level {
+				level = mapped
+			}
+		}
+	}
+	if level > 0 {
+		return level
+	}
+	if cfg.DefaultLevel > 0 {
+		return cfg.DefaultLevel
+	}
+	if global.APP_CONFIG.Quota.DefaultLevel > 0 {
+		return global.APP_CONFIG.Quota.DefaultLevel
+	}
+	return 1
+}
+
+// groupMatches 判断映射键是否匹配组DN或组CN
+func groupMatches(key, groupDN string) bool {
+	key = strings.TrimSpace(key)
+	if strings.EqualFold(key, groupDN) {
+		return true
+	}
+	dn, err := ldapv3.ParseDN(groupDN)
+	if err != nil || len(dn.RDNs) == 0 {
+		return false
+	}
+	// 键为DN时比较完整DN，键为单个RDN（如cn=admins）时只比较组的第一个RDN
+	if keyDN, err := ldapv3.ParseDN(key); err == nil && len(keyDN.RDNs) > 0 {
+		if len(keyDN.RDNs) == 1 {
+			return keyDN.RDNs[0].EqualFold(dn.RDNs[0])
+		}
+		return keyDN.EqualFold(dn)
+	}
+	for _, attr := range dn.RDNs[0].Attributes {
+		if strings.EqualFold(attr.Value, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Login LDAP账户登录，首次登录时自动创建本地用户
+func (s *Service) Login(req auth.LoginRequest) (*user.User, string, error) {
+	if !global.APP_CONFIG.Auth.EnableLDAP {
+		return nil, "", common.NewError(common.CodeForbidden, "LDAP登录未启用")
+	}
+
+	authService := auth2.AuthService{}
+	if err := authService.CheckLoginCaptcha(req.CaptchaId, req.Captcha); err != nil {
+		return nil, "", err
+	}
+	if req.Username == "" || req.Password == "" {
+		return nil, "", common.NewError(common.CodeInvalidParam, "用户名和密码不能为空")
+	}
+
+	protectionService := auth2.LoginProtectionService{}
+	if err := protectionService.CheckUsername(req.Username); err != nil {
+		return nil, "", err
+	}
+
+	// 已关联的本地用户被禁用或锁定时，不再向LDAP服务器发起绑定
+	var linked *user.User
+	var existing user.User
+	if err := global.APP_DB.Where("ldap_uid = ?", req.Username).First(&existing).Error; err == nil {
+		linked = &existing
+		if err := checkUserUsable(linked); err != nil {
+			return nil, "", err
+		}
+	}
+
+	entry, err := s.Authenticate(req.Username, req.Password)
+	if err != nil {
+		var appErr *common.AppError
+		if errors.Is(err, errUserNotFound) || (errors.As(err, &appErr) && appErr.Code == common.CodeInvalidCredentials) {
+			global.APP_LOG.Debug("LDAP用户登录失败", zap.String("username", utils.SanitizeUserInput(req.Username)), zap.Error(err))
+			protectionService.RecordPasswordFailure(req.Username, linked)
+			return nil, "", common.NewError(common.CodeInvalidCredentials)
+		}
+		if errors.Is(err, errMultipleEntries) {
+			global.APP_LOG.Warn("LDAP用户过滤器匹配到多个条目", zap.String("username", utils.SanitizeUserInput(req.Username)))
+			return nil, "", common.NewError(common.CodeInvalidCredentials)
+		}
+		global.APP_LOG.Error("LDAP认证失败", zap.String("username", utils.SanitizeUserInput(req.Username)), zap.Error(err))
+		return nil, "", err
+	}
+
+	usr, err := s.FindOrCreateUser(entry)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkUserUsable(usr); err != nil {
+		return nil, "", err
+	}
+	protectionService.RecordLoginSuccess(usr)
+
+	global.APP_LOG.Info("LDAP用户登录成功",
+		zap.String("username", usr.Username),
+		zap.String("ldapUid", entry.Username),
+		zap.Uint("userID", usr.ID))
+
+	// 生成JWT令牌，启用两步验证的用户返回两步验证挑战
+	token, err := auth2.IssueLoginToken(usr)
+	if err != nil {
+		return nil, "", err
+	}
+	return usr, token, nil
+}
+
+// FindOrCreateUser 按LDAP用户名查找本地用户，不存在且允许自动创建时创建新用户
+// 不会自动关联同名的本地用户，避免LDAP账户接管本地账户
+func (s *Service) FindOrCreateUser(entry *Entry) (*user.User, error) {
+	var usr user.User
+	err := global.APP_DB.Where("ldap_uid = ?", entry.Username).First(&usr).Error
+	if err == nil {
+		updates := map[string]interface{}{"ldap_dn": entry.DN}
+		if entry.Nickname != "" && usr.Nickname == "" {
+			updates["nickname"] = entry.Nickname
+		}
+		if entry.Email != "" && usr.Email == "" {
+			updates["email"] = entry.Email
+		}
+		global.APP_DB.Model(&usr).Updates(updates)
+		return &usr, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	cfg := ldapConfig()
+	if !cfg.AutoProvision {
+		global.APP_LOG.Warn("LDAP用户未开通本地账户", zap.String("ldapUid", entry.Username))
+		return nil, common.NewError(common.CodeForbidden, "账户尚未开通，请联系管理员")
+	}
+	return s.CreateUser(entry)
+}
+
+// CreateUser 为LDAP用户创建本地用户，等级按组映射确定并据此设置配额
+func (s *Service) CreateUser(entry *Entry) (*user.User, error) {
+	oauth2Service := oauth2.Service{}
+	username := oauth2Service.GenerateUniqueUsername(entry.Username)
+
+	// 生成随机密码（LDAP用户不使用本地密码登录）
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(auth2.GenerateRandomString(32)), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	usr := &user.User{
+		Username: username,
+		Password: string(hashedPassword),
+		Nickname: entry.Nickname,
+		Email:    entry.Email,
+		Status:   1,
+		Level:    entry.Level,
+		UserType: "user",
+		LDAPUID:  entry.Username,
+		LDAPDN:   entry.DN,
+	}
+	if usr.Nickname == "" {
+		usr.Nickname = username
+	}
+
+	// 根据用户等级设置配额
+	oauth2Service.SetUserQuotaByLevel(usr)
+
+	err = utils.RetryableDBOperation(context.Background(), func() error {
+		return global.APP_DB.Create(usr).Error
+	}, 3)
+	if err != nil {
+		global.APP_LOG.Error("创建LDAP用户失败", zap.Error(err))
+		return nil, common.NewError(common.CodeInternalError, "创建用户失败")
+	}
+
+	global.APP_LOG.Info("LDAP用户自动开通成功",
+		zap.String("ldapUid", entry.Username),
+		zap.String("username", username),
+		zap.Strings("groups", entry.Groups),
+		zap.Int("level", usr.Level))
+	return usr, nil
+}
+
+// TestConnection 测试当前LDAP配置
+// 提供用户名时搜索该用户并返回所属组和映射等级，同时提供密码时校验密码
+func (s *Service) TestConnection(username, password string) (*TestResult, error) {
+	cfg := ldapConfig()
+	result := &TestResult{URL: cfg.URL}
+
+	conn, err := s.connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	result.Connected = true
+
+	if username == "" {
+		return result, nil
+	}
+
+	entry, err := s.lookup(conn, cfg, username)
+	if err != nil {
+		if errors.Is(err, errUserNotFound) || errors.Is(err, errMultipleEntries) {
+			return nil, common.NewError(common.CodeNotFound, err.Error())
+		}
+		return nil, err
+	}
+	result.UserFound = true
+	result.User = entry
+
+	if password != "" {
+		if err := conn.Bind(entry.DN, password); err != nil {
+			if !ldapv3.IsErrorWithCode(err, ldapv3.LDAPResultInvalidCredentials) {
+				return nil, common.NewError(common.CodeLDAPFailed, fmt.Sprintf("LDAP用户绑定失败: %v", err))
+			}
+		} else {
+			result.Authenticated = true
+		}
+	}
+	return result, nil
+}
+
+// checkUserUsable 检查本地用户是否被禁用或锁定
+func checkUserUsable(usr *user.User) error {
+	if usr.Status != 1 {
+		global.APP_LOG.Warn("禁用用户尝试LDAP登录", zap.String("username", usr.Username), zap.Int("status", usr.Status))
+		return common.NewError(common.CodeUserDisabled)
+	}
+	protectionService := auth2.LoginProtectionService{}
+	return protectionService.CheckAccountLock(usr)
+}
@@ -0,0 +1,288 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	userModel "oneclickvirt/model/user"
+)
+
+// 外部通知渠道名称
+const (
+	ChannelEmail    = "email"
+	ChannelTelegram = "telegram"
+	ChannelQQ       = "qq"
+	ChannelSMS      = "sms"
+)
+
+const (
+	sendTimeout     = 10 * time.Second // 单次发送超时时间
+	maxErrorBodyLen = 512              // 错误信息中保留的响应内容长度
+)
+
+// Channel 外部通知渠道
+type Channel interface {
+	// Name 渠道名称
+	Name() string
+	// Enabled 渠道是否已启用并完成配置
+	Enabled() bool
+	// Target 获取用户在该渠道绑定的接收地址，未绑定时返回空字符串
+	Target(user *userModel.User) string
+	// Send 发送消息
+	Send(ctx context.Context, target string, msg *Message) error
+}
+
+// channels 已注册的渠道，顺序即向用户发送敏感信息（如新密码）时的优先级
+var channels = []Channel{
+	&emailChannel{},
+	&telegramChannel{},
+	&qqChannel{},
+	&smsChannel{},
+}
+
+// GetChannel 根据名称获取渠道
+func GetChannel(name string) (Channel, bool) {
+	for _, ch := range channels {
+		if ch.Name() == name {
+			return ch, true
+		}
+	}
+	return nil, false
+}
+
+// httpClient 渠道发送使用的HTTP客户端
+var httpClient = &http.Client{Timeout: sendTimeout}
+
+// postJSON 发送JSON请求并返回响应内容，非2xx响应视为失败
+func postJSON(ctx context.Context, url, token string, payload interface{}) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		text := string(respBody)
+		if len(text) > maxErrorBodyLen {
+			text = text[:maxErrorBodyLen]
+		}
+		return respBody, fmt.Errorf("HTTP %d: %s", resp.StatusCode, text)
+	}
+	return respBody, nil
+}
+
+// ============ 邮件 ============
+
+// emailChannel 通过SMTP发送邮件，465端口使用隐式TLS，其余端口在服务器支持时使用STARTTLS
+type emailChannel struct{}
+
+func (c *emailChannel) Name() string { return ChannelEmail }
+
+func (c *emailChannel) Enabled() bool {
+	config := global.APP_CONFIG.Auth
+	return config.EnableEmail && config.EmailSMTPHost != ""
+}
+
+func (c *emailChannel) Target(user *userModel.User) string { return user.Email }
+
+func (c *emailChannel) Send(ctx context.Context, target string, msg *Message) error {
+	config := global.APP_CONFIG.Auth
+	port := config.EmailSMTPPort
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(config.EmailSMTPHost, strconv.Itoa(port))
+
+	var header strings.Builder
+	header.WriteString("From: " + config.EmailUsername + "\r\n")
+	header.WriteString("To: " + target + "\r\n")
+	header.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", msg.Title) + "\r\n")
+	header.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	header.WriteString("MIME-Version: 1.0\r\n")
+	header.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	header.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	data := []byte(header.String() + strings.ReplaceAll(msg.Content, "\n", "\r\n"))
+
+	dialer := &net.Dialer{Timeout: sendTimeout}
+	var conn net.Conn
+	var err error
+	if port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: config.EmailSMTPHost})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("连接SMTP服务器失败: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(2 * sendTimeout))
+
+	client, err := smtp.NewClient(conn, config.EmailSMTPHost)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("连接SMTP服务器失败: %v", err)
+	}
+	defer client.Close()
+
+	if port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: config.EmailSMTPHost}); err != nil {
+				return fmt.Errorf("SMTP STARTTLS失败: %v", err)
+			}
+		}
+	}
+	if config.EmailUsername != "" && config.EmailPassword != "" {
+		auth := smtp.PlainAuth("", config.EmailUsername, config.EmailPassword, config.EmailSMTPHost)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP认证失败: %v", err)
+		}
+	}
+	if err := client.Mail(config.EmailUsername); err != nil {
+		return fmt.Errorf("设置发件人失败: %v", err)
+	}
+	if err := client.Rcpt(target); err != nil {
+		return fmt.Errorf("设置收件人失败: %v", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("发送邮件内容失败: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("发送邮件内容失败: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("发送邮件内容失败: %v", err)
+	}
+	return client.Quit()
+}
+
+// ============ Telegram ============
+
+// telegramChannel 通过Telegram Bot API发送消息，用户绑定的Telegram为Chat ID
+type telegramChannel struct{}
+
+func (c *telegramChannel) Name() string { return ChannelTelegram }
+
+func (c *telegramChannel) Enabled() bool {
+	config := global.APP_CONFIG.Auth
+	return config.EnableTelegram && config.TelegramBotToken != ""
+}
+
+func (c *telegramChannel) Target(user *userModel.User) string { return user.Telegram }
+
+func (c *telegramChannel) Send(ctx context.Context, target string, msg *Message) error {
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", global.APP_CONFIG.Auth.TelegramBotToken)
+	respBody, err := postJSON(ctx, url, "", map[string]interface{}{
+		"chat_id": target,
+		"text":    msg.Title + "\n\n" + msg.Content,
+	})
+	if err != nil {
+		return fmt.Errorf("Telegram消息发送失败: %v", err)
+	}
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("解析Telegram响应失败: %v", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("Telegram消息发送失败: %s", result.Description)
+	}
+	return nil
+}
+
+// ============ QQ ============
+
+// qqChannel 通过OneBot v11协议的HTTP API发送QQ私聊消息
+type qqChannel struct{}
+
+func (c *qqChannel) Name() string { return ChannelQQ }
+
+func (c *qqChannel) Enabled() bool {
+	config := global.APP_CONFIG.Auth
+	return config.EnableQQ && config.QQBotAPI != ""
+}
+
+func (c *qqChannel) Target(user *userModel.User) string { return user.QQ }
+
+func (c *qqChannel) Send(ctx context.Context, target string, msg *Message) error {
+	qq, err := strconv.ParseInt(target, 10, 64)
+	if err != nil {
+		return errors.New("无效的QQ号")
+	}
+
+	config := global.APP_CONFIG.Auth
+	url := strings.TrimRight(config.QQBotAPI, "/") + "/send_private_msg"
+	respBody, err := postJSON(ctx, url, config.QQBotToken, map[string]interface{}{
+		"user_id": qq,
+		"message": msg.Title + "\n\n" + msg.Content,
+	})
+	if err != nil {
+		return fmt.Errorf("QQ消息发送失败: %v", err)
+	}
+
+	var result struct {
+		Status  string `json:"status"`
+		RetCode int    `json:"retcode"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("解析QQ机器人响应失败: %v", err)
+	}
+	if result.RetCode != 0 {
+		return fmt.Errorf("QQ消息发送失败: retcode=%d %s", result.RetCode, result.Message)
+	}
+	return nil
+}
+
+// ============ 短信 ============
+
+// smsChannel 通过HTTP短信网关发送短信，网关接收 {"phone","content"} JSON请求，2xx响应视为成功
+type smsChannel struct{}
+
+func (c *smsChannel) Name() string { return ChannelSMS }
+
+func (c *smsChannel) Enabled() bool {
+	return global.APP_CONFIG.Auth.SMSGatewayURL != ""
+}
+
+func (c *smsChannel) Target(user *userModel.User) string { return user.Phone }
+
+func (c *smsChannel) Send(ctx context.Context, target string, msg *Message) error {
+	config := global.APP_CONFIG.Auth
+	if _, err := postJSON(ctx, config.SMSGatewayURL, config.SMSGatewayToken, map[string]interface{}{
+		"phone":   target,
+		"content": msg.Content,
+	}); err != nil {
+		return fmt.Errorf("短信发送失败: %v", err)
+	}
+	return nil
+}
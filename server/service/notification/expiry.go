@@ -0,0 +1,47 @@
+package notification
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"oneclickvirt/global"
+	providerModel "oneclickvirt/model/provider"
+
+	"go.uber.org/zap"
+)
+
+// expiryWarnDays 实例到期提醒的提前天数，从大到小，每个档位各提醒一次
+var expiryWarnDays = []int{7, 3, 1}
+
+// CheckExpiringInstances 检查即将到期的实例并提醒用户
+// 去重键包含到期时间，实例续期后会重新提醒
+func CheckExpiringInstances() {
+	now := time.Now()
+	deadline := now.Add(time.Duration(expiryWarnDays[0]) * 24 * time.Hour)
+
+	var instances []providerModel.Instance
+	if err := global.APP_DB.Select("id, name, user_id, expired_at").
+		Where("expired_at > ? AND expired_at <= ? AND status NOT IN ?", now, deadline, []string{"deleted", "deleting"}).
+		Find(&instances).Error; err != nil {
+		global.APP_LOG.Error("查询即将到期的实例失败", zap.Error(err))
+		return
+	}
+
+	for _, instance := range instances {
+		remaining := instance.ExpiredAt.Sub(now)
+		// 取剩余时间所在的最小档位，例如剩余2天时使用3天档位
+		bucket := expiryWarnDays[0]
+		for _, days := range expiryWarnDays {
+			if remaining <= time.Duration(days)*24*time.Hour {
+				bucket = days
+			}
+		}
+
+		Notify(instance.UserID, CategoryInstanceExpiry, TemplateInstanceExpiry, map[string]interface{}{
+			"InstanceName": instance.Name,
+			"ExpiredAt":    instance.ExpiredAt.Format("2006-01-02 15:04"),
+			"Days":         int(math.Ceil(remaining.Hours() / 24)),
+		}, fmt.Sprintf("instance_expiry:%d:%d:%d", instance.ID, instance.ExpiredAt.Unix(), bucket))
+	}
+}
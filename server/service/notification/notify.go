@@ -0,0 +1,209 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"oneclickvirt/global"
+	userModel "oneclickvirt/model/user"
+
+	"go.uber.org/zap"
+)
+
+// 站内通知分类
+const (
+	CategoryInstanceExpiry = "instance_expiry" // 实例到期提醒
+	CategoryTraffic        = "traffic"         // 流量使用提醒
+	CategoryTask           = "task"            // 任务执行结果
+)
+
+// 站内通知保留时间
+const notificationRetention = 90 * 24 * time.Hour
+
+// CategoryInfo 通知分类说明
+type CategoryInfo struct {
+	Category        string
+	Description     string
+	DefaultChannels []string // 用户未设置偏好时额外发送的外部渠道
+}
+
+// Categories 支持的通知分类
+var Categories = []CategoryInfo{
+	{Category: CategoryInstanceExpiry, Description: "实例到期提醒", DefaultChannels: []string{ChannelEmail, ChannelTelegram}},
+	{Category: CategoryTraffic, Description: "流量使用提醒", DefaultChannels: []string{ChannelEmail, ChannelTelegram}},
+	{Category: CategoryTask, Description: "任务执行结果", DefaultChannels: nil},
+}
+
+// getCategory 获取通知分类
+func getCategory(category string) (CategoryInfo, bool) {
+	for _, info := range Categories {
+		if info.Category == category {
+			return info, true
+		}
+	}
+	return CategoryInfo{}, false
+}
+
+// deliver 通过渠道发送消息，开发环境下渠道未配置时只记录日志
+func deliver(ch Channel, target string, msg *Message) error {
+	if !ch.Enabled() {
+		if global.APP_CONFIG.System.Env == "development" {
+			global.APP_LOG.Info("开发环境模拟发送通知",
+				zap.String("channel", ch.Name()),
+				zap.String("target", target),
+				zap.String("template", msg.Template),
+				zap.String("content", msg.Content))
+			return nil
+		}
+		return fmt.Errorf("%s通知渠道未启用或未配置", ch.Name())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*sendTimeout)
+	defer cancel()
+	if err := ch.Send(ctx, target, msg); err != nil {
+		return err
+	}
+
+	global.APP_LOG.Info("通知发送成功",
+		zap.String("channel", ch.Name()),
+		zap.String("target", target),
+		zap.String("template", msg.Template))
+	return nil
+}
+
+// SendToTarget 通过指定渠道向指定地址发送消息，用于验证码等尚未关联用户的场景
+func SendToTarget(channel, target, templateName string, data interface{}) error {
+	ch, ok := GetChannel(channel)
+	if !ok {
+		return fmt.Errorf("不支持的通知渠道: %s", channel)
+	}
+	msg, err := Render(templateName, data)
+	if err != nil {
+		return err
+	}
+	return deliver(ch, target, msg)
+}
+
+// SendToUser 按渠道优先级（邮箱 > Telegram > QQ > 手机号）向用户发送消息
+// 只使用一个渠道，用于发送新密码等敏感信息，不写入站内信
+func SendToUser(user *userModel.User, templateName string, data interface{}) error {
+	msg, err := Render(templateName, data)
+	if err != nil {
+		return err
+	}
+
+	var fallback Channel
+	var fallbackTarget string
+	for _, ch := range channels {
+		target := ch.Target(user)
+		if target == "" {
+			continue
+		}
+		if ch.Enabled() {
+			return deliver(ch, target, msg)
+		}
+		if fallback == nil {
+			fallback, fallbackTarget = ch, target
+		}
+	}
+
+	if fallback == nil {
+		return errors.New("用户未绑定任何通信渠道")
+	}
+	// 用户绑定的渠道均未启用，开发环境下模拟发送，否则返回错误
+	return deliver(fallback, fallbackTarget, msg)
+}
+
+// Notify 向用户发送站内通知，并按用户偏好通过外部渠道异步发送
+// dedupKey不为空时，同一用户相同dedupKey的通知只发送一次
+func Notify(userID uint, category, templateName string, data interface{}, dedupKey string) {
+	if userID == 0 {
+		return
+	}
+	msg, err := Render(templateName, data)
+	if err != nil {
+		global.APP_LOG.Error("渲染通知失败", zap.String("template", templateName), zap.Error(err))
+		return
+	}
+
+	if dedupKey != "" {
+		var count int64
+		if err := global.APP_DB.Model(&userModel.UserNotification{}).
+			Where("user_id = ? AND dedup_key = ?", userID, dedupKey).Count(&count).Error; err != nil {
+			global.APP_LOG.Error("检查通知去重失败", zap.Uint("userId", userID), zap.Error(err))
+			return
+		}
+		if count > 0 {
+			return
+		}
+	}
+
+	notification := userModel.UserNotification{
+		UserID:   userID,
+		Category: category,
+		Title:    msg.Title,
+		Content:  msg.Content,
+		DedupKey: dedupKey,
+	}
+	if err := global.APP_DB.Create(&notification).Error; err != nil {
+		global.APP_LOG.Error("保存站内通知失败",
+			zap.Uint("userId", userID),
+			zap.String("category", category),
+			zap.Error(err))
+		return
+	}
+
+	channelNames, err := getUserChannels(userID, category)
+	if err != nil {
+		global.APP_LOG.Warn("获取用户通知偏好失败", zap.Uint("userId", userID), zap.Error(err))
+		return
+	}
+	if len(channelNames) == 0 {
+		return
+	}
+
+	var user userModel.User
+	if err := global.APP_DB.First(&user, userID).Error; err != nil {
+		return
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				global.APP_LOG.Error("发送外部通知时发生panic", zap.Any("panic", r))
+			}
+		}()
+		for _, name := range channelNames {
+			ch, ok := GetChannel(name)
+			if !ok || !ch.Enabled() {
+				continue
+			}
+			target := ch.Target(&user)
+			if target == "" {
+				continue
+			}
+			if err := deliver(ch, target, msg); err != nil {
+				global.APP_LOG.Warn("发送外部通知失败",
+					zap.Uint("userId", userID),
+					zap.String("channel", name),
+					zap.String("category", category),
+					zap.Error(err))
+			}
+		}
+	}()
+}
+
+// CleanupOldNotifications 清理超过保留时间的站内通知
+func CleanupOldNotifications() {
+	result := global.APP_DB.Where("created_at < ?", time.Now().Add(-notificationRetention)).
+		Delete(&userModel.UserNotification{})
+	if result.Error != nil {
+		global.APP_LOG.Error("清理站内通知失败", zap.Error(result.Error))
+		return
+	}
+	if result.RowsAffected > 0 {
+		global.APP_LOG.Info("清理过期站内通知", zap.Int64("count", result.RowsAffected))
+	}
+}
@@ -0,0 +1,261 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	userModel "oneclickvirt/model/user"
+
+	"gorm.io/gorm"
+)
+
+// Service 站内通知和通知偏好管理服务
+type Service struct{}
+
+// NewService 创建通知管理服务
+func NewService() *Service {
+	return &Service{}
+}
+
+// ListNotifications 分页获取用户的站内通知
+func (s *Service) ListNotifications(userID uint, req userModel.NotificationListRequest) (*userModel.NotificationListResponse, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	query := global.APP_DB.Model(&userModel.UserNotification{}).Where("user_id = ?", userID)
+	if req.Category != "" {
+		query = query.Where("category = ?", req.Category)
+	}
+	if req.UnreadOnly {
+		query = query.Where("is_read = ?", false)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("获取通知列表失败: %v", err)
+	}
+
+	var list []userModel.UserNotification
+	if err := query.Order("id DESC").Offset((req.Page - 1) * req.PageSize).Limit(req.PageSize).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("获取通知列表失败: %v", err)
+	}
+
+	unread, err := s.UnreadCount(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userModel.NotificationListResponse{
+		List:   list,
+		Total:  total,
+		Unread: unread,
+	}, nil
+}
+
+// UnreadCount 获取用户未读通知数量
+func (s *Service) UnreadCount(userID uint) (int64, error) {
+	var count int64
+	if err := global.APP_DB.Model(&userModel.UserNotification{}).
+		Where("user_id = ? AND is_read = ?", userID, false).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("获取未读通知数量失败: %v", err)
+	}
+	return count, nil
+}
+
+// MarkRead 标记单条通知为已读
+func (s *Service) MarkRead(userID, notificationID uint) error {
+	notification, err := s.getNotification(userID, notificationID)
+	if err != nil {
+		return err
+	}
+	if notification.IsRead {
+		return nil
+	}
+
+	now := time.Now()
+	if err := global.APP_DB.Model(notification).Updates(map[string]interface{}{
+		"is_read": true,
+		"read_at": &now,
+	}).Error; err != nil {
+		return fmt.Errorf("标记通知已读失败: %v", err)
+	}
+	return nil
+}
+
+// MarkAllRead 标记用户所有未读通知为已读，返回更新数量
+func (s *Service) MarkAllRead(userID uint) (int64, error) {
+	now := time.Now()
+	result := global.APP_DB.Model(&userModel.UserNotification{}).
+		Where("user_id = ? AND is_read = ?", userID, false).
+		Updates(map[string]interface{}{
+			"is_read": true,
+			"read_at": &now,
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("标记通知已读失败: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// DeleteNotification 删除单条通知
+func (s *Service) DeleteNotification(userID, notificationID uint) error {
+	notification, err := s.getNotification(userID, notificationID)
+	if err != nil {
+		return err
+	}
+	if err := global.APP_DB.Delete(notification).Error; err != nil {
+		return fmt.Errorf("删除通知失败: %v", err)
+	}
+	return nil
+}
+
+// GetPreferences 获取用户的通知偏好和渠道状态
+func (s *Service) GetPreferences(userID uint) (*userModel.NotificationPreferencesResponse, error) {
+	var user userModel.User
+	if err := global.APP_DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	saved, err := loadPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &userModel.NotificationPreferencesResponse{}
+	for _, info := range Categories {
+		channelNames := info.DefaultChannels
+		if pref, ok := saved[info.Category]; ok {
+			channelNames = splitChannels(pref.Channels)
+		}
+		if channelNames == nil {
+			channelNames = []string{}
+		}
+		resp.Preferences = append(resp.Preferences, userModel.NotificationPreferenceInfo{
+			Category:    info.Category,
+			Description: info.Description,
+			Channels:    channelNames,
+		})
+	}
+	for _, ch := range channels {
+		resp.Channels = append(resp.Channels, userModel.NotificationChannelStatus{
+			Name:    ch.Name(),
+			Enabled: ch.Enabled(),
+			Bound:   ch.Target(&user) != "",
+		})
+	}
+	return resp, nil
+}
+
+// UpdatePreferences 更新用户的通知偏好，未提交的分类保持不变
+func (s *Service) UpdatePreferences(userID uint, req userModel.UpdateNotificationPreferencesRequest) (*userModel.NotificationPreferencesResponse, error) {
+	prefs := make([]userModel.UserNotificationPreference, 0, len(req.Preferences))
+	for _, item := range req.Preferences {
+		if _, ok := getCategory(item.Category); !ok {
+			return nil, fmt.Errorf("不支持的通知分类: %s", item.Category)
+		}
+		channelNames, err := normalizeChannels(item.Channels)
+		if err != nil {
+			return nil, err
+		}
+		prefs = append(prefs, userModel.UserNotificationPreference{
+			UserID:   userID,
+			Category: item.Category,
+			Channels: channelNames,
+		})
+	}
+
+	if len(prefs) > 0 {
+		if err := global.APP_DB.Transaction(func(tx *gorm.DB) error {
+			for i := range prefs {
+				var pref userModel.UserNotificationPreference
+				if err := tx.Where(userModel.UserNotificationPreference{UserID: userID, Category: prefs[i].Category}).
+					Assign(map[string]interface{}{"channels": prefs[i].Channels}).
+					FirstOrCreate(&pref).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("保存通知偏好失败: %v", err)
+		}
+	}
+
+	return s.GetPreferences(userID)
+}
+
+// getNotification 获取属于用户的通知
+func (s *Service) getNotification(userID, notificationID uint) (*userModel.UserNotification, error) {
+	var notification userModel.UserNotification
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", notificationID, userID).First(&notification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("通知不存在")
+		}
+		return nil, fmt.Errorf("获取通知失败: %v", err)
+	}
+	return &notification, nil
+}
+
+// loadPreferences 读取用户已保存的通知偏好，按分类索引
+func loadPreferences(userID uint) (map[string]userModel.UserNotificationPreference, error) {
+	var prefs []userModel.UserNotificationPreference
+	if err := global.APP_DB.Where("user_id = ?", userID).Find(&prefs).Error; err != nil {
+		return nil, fmt.Errorf("获取通知偏好失败: %v", err)
+	}
+	result := make(map[string]userModel.UserNotificationPreference, len(prefs))
+	for _, pref := range prefs {
+		result[pref.Category] = pref
+	}
+	return result, nil
+}
+
+// getUserChannels 获取用户在某个通知分类下需要额外发送的外部渠道
+func getUserChannels(userID uint, category string) ([]string, error) {
+	info, ok := getCategory(category)
+	if !ok {
+		return nil, nil
+	}
+	var pref userModel.UserNotificationPreference
+	err := global.APP_DB.Where("user_id = ? AND category = ?", userID, category).First(&pref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return info.DefaultChannels, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return splitChannels(pref.Channels), nil
+}
+
+// normalizeChannels 校验并去重渠道名称
+func normalizeChannels(names []string) (string, error) {
+	var result []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := GetChannel(name); !ok {
+			return "", fmt.Errorf("不支持的通知渠道: %s", name)
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	return strings.Join(result, ","), nil
+}
+
+func splitChannels(value string) []string {
+	result := []string{}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
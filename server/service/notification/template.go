@@ -0,0 +1,119 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// 消息模板名称
+const (
+	TemplateVerifyCode        = "verify_code"         // 登录验证码
+	TemplatePasswordReset     = "password_reset"      // 重置后的新密码
+	TemplatePasswordResetLink = "password_reset_link" // 密码重置链接
+	TemplateInstanceExpiry    = "instance_expiry"     // 实例到期提醒
+	TemplateTrafficThreshold  = "traffic_threshold"   // 流量使用提醒
+	TemplateTaskResult        = "task_result"         // 任务执行结果
+)
+
+// Message 渲染后的消息
+type Message struct {
+	Template string
+	Title    string
+	Content  string
+}
+
+// messageTemplate 消息模板，标题和内容均为text/template格式
+type messageTemplate struct {
+	title   string
+	content string
+}
+
+var messageTemplates = map[string]messageTemplate{
+	TemplateVerifyCode: {
+		title:   "登录验证码",
+		content: "您的登录验证码是：{{.Code}}\n验证码{{.Minutes}}分钟内有效，请勿泄露给他人。",
+	},
+	TemplatePasswordReset: {
+		title:   "密码重置通知",
+		content: "尊敬的用户 {{.Username}}：\n\n您的密码已重置，新密码为：{{.Password}}\n\n请使用新密码登录系统，并尽快修改密码。\n\n系统自动发送，请勿回复。",
+	},
+	TemplatePasswordResetLink: {
+		title:   "密码重置",
+		content: "请打开以下链接重置密码：\n{{.URL}}\n\n链接有效期为{{.Hours}}小时，如非本人操作请忽略本邮件。",
+	},
+	TemplateInstanceExpiry: {
+		title:   "实例即将到期：{{.InstanceName}}",
+		content: "您的实例 {{.InstanceName}} 将于 {{.ExpiredAt}} 到期（剩余约{{.Days}}天），到期后实例将被自动删除，请及时备份数据或联系管理员续期。",
+	},
+	TemplateTrafficThreshold: {
+		title: "{{if ge .Percent 100}}流量已用尽{{else}}流量使用提醒{{end}}：{{.Name}}",
+		content: "{{.Scope}} {{.Name}} 本月已使用流量 {{.Used}}MB，达到限额 {{.Total}}MB 的 {{.Percent}}%。" +
+			"{{if ge .Percent 100}}\n相关实例已被停止，流量重置或限额调整后将自动恢复。{{end}}",
+	},
+	TemplateTaskResult: {
+		title: "任务{{if .Success}}已完成{{else}}执行失败{{end}}：{{taskName .TaskType}}{{if .InstanceName}} - {{.InstanceName}}{{end}}",
+		content: "任务 #{{.TaskID}}（{{taskName .TaskType}}）{{if .Success}}已成功完成。{{else}}执行失败。{{end}}" +
+			"{{if .InstanceName}}\n实例：{{.InstanceName}}{{end}}" +
+			"{{if .ErrorMessage}}\n错误信息：{{.ErrorMessage}}{{end}}",
+	},
+}
+
+// taskTypeNames 任务类型显示名称
+var taskTypeNames = map[string]string{
+	"create":              "创建实例",
+	"start":               "启动实例",
+	"stop":                "停止实例",
+	"restart":             "重启实例",
+	"reset":               "重置系统",
+	"delete":              "删除实例",
+	"reset-password":      "重置实例密码",
+	"create-port-mapping": "创建端口映射",
+	"delete-port-mapping": "删除端口映射",
+	"create-snapshot":     "创建快照",
+	"restore-snapshot":    "恢复快照",
+	"delete-snapshot":     "删除快照",
+	"create-backup":       "创建备份",
+	"restore-backup":      "恢复备份",
+	"resize":              "调整配置",
+	"migrate":             "迁移实例",
+}
+
+var templateFuncs = template.FuncMap{
+	"taskName": func(taskType string) string {
+		if name, ok := taskTypeNames[taskType]; ok {
+			return name
+		}
+		return taskType
+	},
+}
+
+// Render 使用模板渲染消息
+func Render(name string, data interface{}) (*Message, error) {
+	tpl, ok := messageTemplates[name]
+	if !ok {
+		return nil, fmt.Errorf("消息模板不存在: %s", name)
+	}
+	title, err := execute(name+".title", tpl.title, data)
+	if err != nil {
+		return nil, err
+	}
+	content, err := execute(name+".content", tpl.content, data)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Template: name, Title: title, Content: content}, nil
+}
+
+func execute(name, text string, data interface{}) (string, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("解析消息模板失败: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染消息模板失败: %v", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
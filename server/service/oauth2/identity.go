@@ -0,0 +1,254 @@
+package oauth2
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	oauth2Model "oneclickvirt/model/oauth2"
+	"oneclickvirt/model/user"
+	auth2 "oneclickvirt/service/auth"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// IdentityService 第三方登录身份管理服务
+type IdentityService struct{}
+
+// IdentityInfo 已绑定的第三方登录身份
+type IdentityInfo struct {
+	user.UserIdentity
+	ProviderName        string `json:"providerName"`        // 提供商名称
+	ProviderDisplayName string `json:"providerDisplayName"` // 提供商显示名称
+}
+
+// LinkableProvider 可绑定的OAuth2提供商
+type LinkableProvider struct {
+	ID          uint   `json:"id"`          // 提供商ID
+	Name        string `json:"name"`        // 提供商名称
+	DisplayName string `json:"displayName"` // 显示名称
+	Linked      bool   `json:"linked"`      // 当前用户是否已绑定
+}
+
+// IdentityListResponse 第三方登录身份列表
+type IdentityListResponse struct {
+	Identities []IdentityInfo     `json:"identities"` // 已绑定的身份
+	Providers  []LinkableProvider `json:"providers"`  // 已启用的提供商
+}
+
+// newIdentity 根据提供商返回的用户信息构造身份记录
+func newIdentity(userID, providerID uint, userInfo *UserInfo, now time.Time) *user.UserIdentity {
+	return &user.UserIdentity{
+		UserID:      userID,
+		ProviderID:  providerID,
+		UID:         userInfo.ID,
+		Username:    userInfo.Username,
+		Email:       userInfo.Email,
+		Avatar:      userInfo.Avatar,
+		Extra:       userInfo.RawData,
+		LastLoginAt: &now,
+	}
+}
+
+// identityUpdates 登录或重新绑定时同步到身份记录的字段
+func identityUpdates(userInfo *UserInfo, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"username":      userInfo.Username,
+		"email":         userInfo.Email,
+		"avatar":        userInfo.Avatar,
+		"extra":         userInfo.RawData,
+		"last_login_at": now,
+	}
+}
+
+// BuildLinkURL 为已登录用户生成绑定提供商账号的授权跳转地址
+func (s *Service) BuildLinkURL(userID, providerID uint) (string, error) {
+	if !global.APP_CONFIG.Auth.EnableOAuth2 {
+		return "", common.NewError(common.CodeForbidden, "OAuth2登录未启用")
+	}
+	provider, err := s.GetProviderByID(providerID)
+	if err != nil {
+		return "", err
+	}
+
+	var count int64
+	global.APP_DB.Model(&user.UserIdentity{}).Where("user_id = ? AND provider_id = ?", userID, providerID).Count(&count)
+	if count > 0 {
+		return "", common.NewError(common.CodeConflict, fmt.Sprintf("已绑定%s账号，请先解绑", provider.DisplayName))
+	}
+
+	return s.buildAuthCodeURL(provider, &StateInfo{ProviderID: provider.ID, LinkUserID: userID})
+}
+
+// LinkIdentity 处理绑定模式的回调，将提供商账号绑定到发起绑定的用户
+func (s *Service) LinkIdentity(state *StateInfo, code string) (*oauth2Model.OAuth2Provider, error) {
+	provider, err := s.GetProviderByID(state.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+
+	var usr user.User
+	if err := global.APP_DB.First(&usr, state.LinkUserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, common.NewError(common.CodeUserNotFound)
+		}
+		return nil, err
+	}
+	if usr.Status != 1 {
+		return nil, common.NewError(common.CodeUserDisabled)
+	}
+
+	userInfoData, err := s.ExchangeUserInfo(provider, state, code)
+	if err != nil {
+		return nil, err
+	}
+	userInfo, err := s.ExtractUserInfo(provider, userInfoData)
+	if err != nil {
+		global.APP_LOG.Error("提取OAuth2用户信息失败", zap.Error(err))
+		return nil, common.NewError(common.CodeOAuth2Failed, "用户信息格式错误")
+	}
+
+	now := time.Now()
+	var existing user.UserIdentity
+	err = global.APP_DB.Where("provider_id = ? AND uid = ?", provider.ID, userInfo.ID).First(&existing).Error
+	if err == nil {
+		if existing.UserID != usr.ID {
+			return nil, common.NewError(common.CodeConflict, fmt.Sprintf("该%s账号已绑定其他用户", provider.DisplayName))
+		}
+		// 重复绑定同一账号，仅同步信息
+		global.APP_DB.Model(&existing).Updates(identityUpdates(userInfo, now))
+		return provider, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var count int64
+	global.APP_DB.Model(&user.UserIdentity{}).Where("user_id = ? AND provider_id = ?", usr.ID, provider.ID).Count(&count)
+	if count > 0 {
+		return nil, common.NewError(common.CodeConflict, fmt.Sprintf("已绑定其他%s账号，请先解绑", provider.DisplayName))
+	}
+
+	if err := global.APP_DB.Create(newIdentity(usr.ID, provider.ID, userInfo, now)).Error; err != nil {
+		global.APP_LOG.Warn("绑定OAuth2身份失败",
+			zap.Uint("userID", usr.ID),
+			zap.String("provider", provider.Name),
+			zap.Error(err))
+		return nil, common.NewError(common.CodeConflict, fmt.Sprintf("该%s账号已绑定其他用户", provider.DisplayName))
+	}
+
+	global.APP_LOG.Info("绑定OAuth2身份",
+		zap.Uint("userID", usr.ID),
+		zap.String("provider", provider.Name),
+		zap.String("oauth2_uid", userInfo.ID))
+	return provider, nil
+}
+
+// ListIdentities 获取用户已绑定的身份和可绑定的提供商
+func (s *IdentityService) ListIdentities(userID uint) (*IdentityListResponse, error) {
+	var identities []user.UserIdentity
+	if err := global.APP_DB.Where("user_id = ?", userID).Order("id ASC").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+
+	var providers []oauth2Model.OAuth2Provider
+	if err := global.APP_DB.Unscoped().Find(&providers).Error; err != nil {
+		return nil, err
+	}
+	providerMap := make(map[uint]oauth2Model.OAuth2Provider, len(providers))
+	for _, provider := range providers {
+		providerMap[provider.ID] = provider
+	}
+
+	resp := &IdentityListResponse{
+		Identities: make([]IdentityInfo, 0, len(identities)),
+		Providers:  make([]LinkableProvider, 0),
+	}
+	linked := make(map[uint]bool, len(identities))
+	for _, identity := range identities {
+		provider := providerMap[identity.ProviderID]
+		resp.Identities = append(resp.Identities, IdentityInfo{
+			UserIdentity:        identity,
+			ProviderName:        provider.Name,
+			ProviderDisplayName: provider.DisplayName,
+		})
+		linked[identity.ProviderID] = true
+	}
+
+	if global.APP_CONFIG.Auth.EnableOAuth2 {
+		enabled, err := (&Service{}).GetAllEnabledProviders()
+		if err != nil {
+			return nil, err
+		}
+		for _, provider := range enabled {
+			resp.Providers = append(resp.Providers, LinkableProvider{
+				ID:          provider.ID,
+				Name:        provider.Name,
+				DisplayName: provider.DisplayName,
+				Linked:      linked[provider.ID],
+			})
+		}
+	}
+	return resp, nil
+}
+
+// UnlinkIdentity 解绑第三方登录身份，至少保留一种可用的登录方式
+func (s *IdentityService) UnlinkIdentity(userID, identityID uint) error {
+	var identity user.UserIdentity
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", identityID, userID).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return common.NewError(common.CodeNotFound, "绑定的账号不存在")
+		}
+		return err
+	}
+
+	var usr user.User
+	if err := global.APP_DB.First(&usr, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return common.NewError(common.CodeUserNotFound)
+		}
+		return err
+	}
+	if !s.hasOtherLoginMethod(&usr, identity.ID) {
+		return common.NewError(common.CodeForbidden, "这是账户唯一的登录方式，请先设置密码或绑定其他登录方式")
+	}
+
+	if err := global.APP_DB.Delete(&identity).Error; err != nil {
+		return err
+	}
+
+	global.APP_LOG.Info("解绑OAuth2身份",
+		zap.Uint("userID", userID),
+		zap.Uint("providerID", identity.ProviderID),
+		zap.String("oauth2_uid", identity.UID))
+	return nil
+}
+
+// hasOtherLoginMethod 检查解绑指定身份后用户是否还有可用的登录方式
+func (s *IdentityService) hasOtherLoginMethod(usr *user.User, excludeIdentityID uint) bool {
+	// 用户自己设置过的密码
+	if !usr.PasswordGenerated {
+		return true
+	}
+	// 已关联的LDAP账户
+	if usr.LDAPUID != "" && global.APP_CONFIG.Auth.EnableLDAP {
+		return true
+	}
+	// 通行密钥
+	if auth2.HasPasskey(usr.ID) {
+		return true
+	}
+	// 其他已启用提供商的身份
+	if !global.APP_CONFIG.Auth.EnableOAuth2 {
+		return false
+	}
+	enabledProviders := global.APP_DB.Model(&oauth2Model.OAuth2Provider{}).Select("id").Where("enabled = ?", true)
+	var count int64
+	global.APP_DB.Model(&user.UserIdentity{}).
+		Where("user_id = ? AND id <> ? AND provider_id IN (?)", usr.ID, excludeIdentityID, enabledProviders).
+		Count(&count)
+	return count > 0
+}
@@ -17,8 +17,10 @@ import (
 	"oneclickvirt/model/common"
 	oauth2Model "oneclickvirt/model/oauth2"
 	"oneclickvirt/model/user"
+	auth2 "oneclickvirt/service/auth"
 	"oneclickvirt/utils"
 
+	"github.com/coreos/go-oidc/v3/oidc"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/oauth2"
@@ -33,8 +35,11 @@ type Service struct {
 
 // StateInfo 状态信息
 type StateInfo struct {
-	ProviderID uint      // OAuth2提供商ID
-	Expiry     time.Time // 过期时间
+	ProviderID   uint      // OAuth2提供商ID
+	Expiry       time.Time // 过期时间
+	CodeVerifier string    // PKCE校验码，未启用PKCE时为空
+	Nonce        string    // OIDC nonce，用于校验ID Token，非OIDC提供商为空
+	LinkUserID   uint      // 绑定模式下发起绑定的用户ID，登录模式为0
 }
 
 // NewService 创建OAuth2服务实例
@@ -59,11 +64,16 @@ func (s *Service) cleanExpiredStates() {
 
 // GenerateStateToken 生成OAuth2 state令牌
 func (s *Service) GenerateStateToken(providerID uint) (string, error) {
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
+	return s.storeState(&StateInfo{ProviderID: providerID})
+}
+
+// storeState 生成state令牌并保存授权请求的状态信息
+func (s *Service) storeState(info *StateInfo) (string, error) {
+	state, err := randomToken()
+	if err != nil {
 		return "", err
 	}
-	state := base64.URLEncoding.EncodeToString(b)
+	providerID := info.ProviderID
 
 	// 从配置读取state令牌有效期，默认15分钟
 	stateTokenMinutes := global.APP_CONFIG.System.OAuth2StateTokenMinutes
@@ -74,11 +84,9 @@ func (s *Service) GenerateStateToken(providerID uint) (string, error) {
 	expiryDuration := time.Duration(stateTokenMinutes) * time.Minute
 	expiry := time.Now().Add(expiryDuration)
 
+	info.Expiry = expiry
 	s.mu.Lock()
-	s.states[state] = &StateInfo{
-		ProviderID: providerID,
-		Expiry:     expiry,
-	}
+	s.states[state] = info
 	s.mu.Unlock()
 
 	global.APP_LOG.Debug("生成OAuth2 state令牌",
@@ -93,11 +101,45 @@ func (s *Service) GenerateStateToken(providerID uint) (string, error) {
 	return state, nil
 }
 
+// BuildAuthCodeURL 生成授权跳转地址，按提供商配置附带PKCE挑战和OIDC nonce
+func (s *Service) BuildAuthCodeURL(provider *oauth2Model.OAuth2Provider) (string, error) {
+	return s.buildAuthCodeURL(provider, &StateInfo{ProviderID: provider.ID})
+}
+
+// buildAuthCodeURL 按state信息生成授权跳转地址
+func (s *Service) buildAuthCodeURL(provider *oauth2Model.OAuth2Provider, info *StateInfo) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oidcRequestTimeout)
+	defer cancel()
+
+	oauth2Cfg, _, err := s.resolveOAuth2Config(ctx, provider)
+	if err != nil {
+		return "", err
+	}
+
+	var opts []oauth2.AuthCodeOption
+	if provider.UsePKCE {
+		info.CodeVerifier = oauth2.GenerateVerifier()
+		opts = append(opts, oauth2.S256ChallengeOption(info.CodeVerifier))
+	}
+	if provider.IsOIDC() {
+		if info.Nonce, err = randomToken(); err != nil {
+			return "", err
+		}
+		opts = append(opts, oidc.Nonce(info.Nonce))
+	}
+
+	state, err := s.storeState(info)
+	if err != nil {
+		return "", err
+	}
+	return oauth2Cfg.AuthCodeURL(state, opts...), nil
+}
+
 // ValidateStateToken 验证OAuth2 state令牌
-func (s *Service) ValidateStateToken(state string) (uint, bool) {
+func (s *Service) ValidateStateToken(state string) (*StateInfo, bool) {
 	if state == "" {
 		global.APP_LOG.Warn("收到空的state令牌")
-		return 0, false
+		return nil, false
 	}
 
 	s.mu.RLock()
@@ -107,7 +149,7 @@ func (s *Service) ValidateStateToken(state string) (uint, bool) {
 	if !exists {
 		global.APP_LOG.Warn("state令牌不存在（可能已过期或已使用）",
 			zap.String("state", state[:16]+"..."))
-		return 0, false
+		return nil, false
 	}
 
 	// 检查是否过期
@@ -120,7 +162,7 @@ func (s *Service) ValidateStateToken(state string) (uint, bool) {
 			zap.String("state", state[:16]+"..."),
 			zap.Time("expiry", info.Expiry),
 			zap.Duration("过期时长", time.Since(info.Expiry)))
-		return 0, false
+		return nil, false
 	}
 
 	// 验证成功后删除state（一次性使用）
@@ -132,7 +174,7 @@ func (s *Service) ValidateStateToken(state string) (uint, bool) {
 		zap.Uint("provider_id", info.ProviderID),
 		zap.String("state", state[:16]+"..."))
 
-	return info.ProviderID, true
+	return info, true
 }
 
 // GetProviderByID 根据ID获取OAuth2提供商配置
@@ -180,7 +222,7 @@ func (s *Service) GetOAuth2Config(provider *oauth2Model.OAuth2Provider) *oauth2.
 		ClientID:     provider.ClientID,
 		ClientSecret: provider.ClientSecret,
 		RedirectURL:  provider.RedirectURL,
-		Scopes:       []string{}, // 不使用权限范围，只用于基本用户注册
+		Scopes:       providerScopes(provider), // 通用OAuth2提供商未配置时不请求权限范围
 		Endpoint: oauth2.Endpoint{
 			AuthURL:  provider.AuthURL,
 			TokenURL: provider.TokenURL,
@@ -252,7 +294,7 @@ func (s *Service) ExtractUserInfo(provider *oauth2Model.OAuth2Provider, userInfo
 		userInfo.Nickname = fmt.Sprintf("%v", nickname)
 	}
 
-	// 提取信任等级（可选）
+	// 提取信任等级（可选），字段为字符串数组时（如OIDC的groups声明）作为组列表按名称映射等级
 	if provider.TrustLevelField != "" {
 		if trustLevel := s.GetNestedField(userInfoData, provider.TrustLevelField); trustLevel != nil {
 			// 尝试转换为整数
@@ -262,7 +304,13 @@ func (s *Service) ExtractUserInfo(provider *oauth2Model.OAuth2Provider, userInfo
 			case int:
 				userInfo.TrustLevel = v
 			case string:
-				fmt.Sscanf(v, "%d", &userInfo.TrustLevel)
+				if _, err := fmt.Sscanf(v, "%d", &userInfo.TrustLevel); err != nil {
+					userInfo.Groups = []string{v}
+				}
+			case []interface{}:
+				for _, item := range v {
+					userInfo.Groups = append(userInfo.Groups, fmt.Sprintf("%v", item))
+				}
 			}
 		}
 	}
@@ -276,13 +324,14 @@ func (s *Service) ExtractUserInfo(provider *oauth2Model.OAuth2Provider, userInfo
 
 // UserInfo OAuth2用户信息
 type UserInfo struct {
-	ID         string // OAuth2提供商返回的用户ID
-	Username   string // 用户名
-	Email      string // 邮箱
-	Avatar     string // 头像URL
-	Nickname   string // 昵称
-	TrustLevel int    // 信任等级
-	RawData    string // 原始JSON数据
+	ID         string   // OAuth2提供商返回的用户ID
+	Username   string   // 用户名
+	Email      string   // 邮箱
+	Avatar     string   // 头像URL
+	Nickname   string   // 昵称
+	TrustLevel int      // 信任等级
+	Groups     []string // 信任等级字段为组列表时的组名
+	RawData    string   // 原始JSON数据
 }
 
 // FetchUserInfo 获取OAuth2用户信息
@@ -307,15 +356,24 @@ func (s *Service) FetchUserInfo(provider *oauth2Model.OAuth2Provider, token *oau
 	return userInfoData, nil
 }
 
-// GetUserLevel 根据信任等级获取系统用户等级
-func (s *Service) GetUserLevel(provider *oauth2Model.OAuth2Provider, trustLevel int) int {
+// GetUserLevel 根据信任等级或所属组获取系统用户等级，匹配多个组时取最高等级
+func (s *Service) GetUserLevel(provider *oauth2Model.OAuth2Provider, userInfo *UserInfo) int {
 	// 解析等级映射
 	if provider.LevelMapping != "" {
 		var mapping map[string]int
 		if err := json.Unmarshal([]byte(provider.LevelMapping), &mapping); err == nil {
-			// 尝试查找对应的等级
-			key := fmt.Sprintf("%d", trustLevel)
-			if level, ok := mapping[key]; ok {
+			if len(userInfo.Groups) > 0 {
+				level := 0
+				for _, group := range userInfo.Groups {
+					if mapped, ok := mapping[group]; ok && mapped > level {
+						level = mapped
+					}
+				}
+				if level > 0 {
+					return level
+				}
+			} else if level, ok := mapping[fmt.Sprintf("%d", userInfo.TrustLevel)]; ok {
+				// 尝试查找对应的等级
 				return level
 			}
 		}
@@ -326,26 +384,18 @@ func (s *Service) GetUserLevel(provider *oauth2Model.OAuth2Provider, trustLevel
 }
 
 // HandleCallback 处理OAuth2回调
-func (s *Service) HandleCallback(providerID uint, code string) (*user.User, string, error) {
+func (s *Service) HandleCallback(state *StateInfo, code string) (*user.User, string, error) {
+	providerID := state.ProviderID
 	// 获取提供商配置
 	provider, err := s.GetProviderByID(providerID)
 	if err != nil {
 		return nil, "", err
 	}
 
-	// 交换授权码获取令牌
-	oauth2Cfg := s.GetOAuth2Config(provider)
-	token, err := oauth2Cfg.Exchange(context.Background(), code)
-	if err != nil {
-		global.APP_LOG.Error("交换OAuth2令牌失败", zap.Error(err))
-		return nil, "", common.NewError(common.CodeOAuth2Failed, "授权失败")
-	}
-
-	// 获取用户信息
-	userInfoData, err := s.FetchUserInfo(provider, token)
+	// 获取用户信息，OIDC提供商以校验通过的ID Token声明为准
+	userInfoData, err := s.ExchangeUserInfo(provider, state, code)
 	if err != nil {
-		global.APP_LOG.Error("获取OAuth2用户信息失败", zap.Error(err))
-		return nil, "", common.NewError(common.CodeOAuth2Failed, "获取用户信息失败")
+		return nil, "", err
 	}
 
 	// 提取用户信息
@@ -355,33 +405,23 @@ func (s *Service) HandleCallback(providerID uint, code string) (*user.User, stri
 		return nil, "", common.NewError(common.CodeOAuth2Failed, "用户信息格式错误")
 	}
 
-	// 检查用户是否已存在
-	var existingUser user.User
-	err = global.APP_DB.Where(&user.User{OAuth2ProviderID: providerID, OAuth2UID: userInfo.ID}).First(&existingUser).Error
-	isUserExists := err == nil
-
-	// 如果用户不存在且已达到注册限制，拒绝注册
-	if !isUserExists && provider.MaxRegistrations > 0 && provider.CurrentRegistrations >= provider.MaxRegistrations {
-		return nil, "", common.NewError(common.CodeOAuth2RegistrationLimit, fmt.Sprintf("%s 注册已达限制", provider.DisplayName))
-	}
-
-	// 查找或创建用户
-	usr, isNewUser, err := s.FindOrCreateUser(provider, userInfo)
+	// 通过已绑定的身份查找用户
+	usr, err := s.findIdentityUser(provider, userInfo)
 	if err != nil {
 		return nil, "", err
 	}
 
-	// 生成JWT令牌
-	jwtToken, err := utils.GenerateToken(usr.ID, usr.Username, usr.UserType)
-	if err != nil {
-		global.APP_LOG.Error("生成JWT令牌失败", zap.Error(err))
-		return nil, "", common.NewError(common.CodeInternalError, "生成令牌失败")
+	isNewUser := false
+	if usr == nil {
+		// 用户不存在且已达到注册限制，拒绝注册
+		if provider.MaxRegistrations > 0 && provider.CurrentRegistrations >= provider.MaxRegistrations {
+			return nil, "", common.NewError(common.CodeOAuth2RegistrationLimit, fmt.Sprintf("%s 注册已达限制", provider.DisplayName))
+		}
+		if usr, isNewUser, err = s.CreateUser(provider, userInfo); err != nil {
+			return nil, "", err
+		}
 	}
 
-	// 更新最后登录时间
-	now := time.Now()
-	global.APP_DB.Model(usr).Update("last_login_at", now)
-
 	// 如果是新用户，更新提供商的注册计数
 	if isNewUser {
 		global.APP_DB.Model(&oauth2Model.OAuth2Provider{}).
@@ -392,45 +432,116 @@ func (s *Service) HandleCallback(providerID uint, code string) (*user.User, stri
 			})
 	}
 
+	// 生成JWT令牌，启用两步验证的用户返回两步验证挑战
+	jwtToken, err := auth2.IssueLoginToken(usr)
+	if err != nil {
+		var challengeErr *auth2.TwoFactorChallengeError
+		if errors.As(err, &challengeErr) {
+			return nil, "", err
+		}
+		return nil, "", common.NewError(common.CodeInternalError, "生成令牌失败")
+	}
+
 	return usr, jwtToken, nil
 }
 
-// FindOrCreateUser 查找或创建用户
-func (s *Service) FindOrCreateUser(provider *oauth2Model.OAuth2Provider, userInfo *UserInfo) (*user.User, bool, error) {
-	// 首先通过OAuth2 UID查找用户
-	var usr user.User
-	err := global.APP_DB.Where(&user.User{OAuth2ProviderID: provider.ID, OAuth2UID: userInfo.ID}).First(&usr).Error
-
-	if err == nil {
-		// 用户已存在，更新OAuth2相关信息
-		updates := map[string]interface{}{
-			"OAuth2Username": userInfo.Username,
-			"OAuth2Email":    userInfo.Email,
-			"OAuth2Avatar":   userInfo.Avatar,
-			"OAuth2Extra":    userInfo.RawData,
-		}
+// ExchangeUserInfo 用授权码交换令牌并获取用户信息
+// OIDC提供商校验ID Token的签名、签发者、受众、有效期和nonce，并合并用户信息接口返回的声明
+func (s *Service) ExchangeUserInfo(provider *oauth2Model.OAuth2Provider, state *StateInfo, code string) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oidcRequestTimeout)
+	defer cancel()
 
-		// 更新昵称和头像（如果用户未自定义）
-		if userInfo.Nickname != "" && usr.Nickname == "" {
-			updates["nickname"] = userInfo.Nickname
-		}
-		if userInfo.Avatar != "" && usr.Avatar == "" {
-			updates["avatar"] = userInfo.Avatar
+	oauth2Cfg, oidcProvider, err := s.resolveOAuth2Config(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	// 交换授权码获取令牌
+	var opts []oauth2.AuthCodeOption
+	if state.CodeVerifier != "" {
+		opts = append(opts, oauth2.VerifierOption(state.CodeVerifier))
+	}
+	token, err := oauth2Cfg.Exchange(ctx, code, opts...)
+	if err != nil {
+		global.APP_LOG.Error("交换OAuth2令牌失败", zap.Error(err))
+		return nil, common.NewError(common.CodeOAuth2Failed, "授权失败")
+	}
+
+	if oidcProvider == nil {
+		userInfoData, err := s.FetchUserInfo(provider, token)
+		if err != nil {
+			global.APP_LOG.Error("获取OAuth2用户信息失败", zap.Error(err))
+			return nil, common.NewError(common.CodeOAuth2Failed, "获取用户信息失败")
 		}
+		return userInfoData, nil
+	}
 
-		global.APP_DB.Model(&usr).Updates(updates)
-		return &usr, false, nil
+	claims, err := verifyIDToken(ctx, oidcProvider, provider, token, state.Nonce)
+	if err != nil {
+		global.APP_LOG.Warn("OIDC ID Token校验失败", zap.String("provider", provider.Name), zap.Error(err))
+		return nil, common.NewError(common.CodeOAuth2Failed, "身份令牌校验失败")
+	}
+	if err := s.fetchOIDCUserInfo(ctx, oidcProvider, provider, token, claims); err != nil {
+		global.APP_LOG.Error("获取OIDC用户信息失败", zap.String("provider", provider.Name), zap.Error(err))
+		return nil, common.NewError(common.CodeOAuth2Failed, "获取用户信息失败")
 	}
+	return claims, nil
+}
 
-	if !errors.Is(err, gorm.ErrRecordNotFound) {
+// FindOrCreateUser 查找或创建用户
+func (s *Service) FindOrCreateUser(provider *oauth2Model.OAuth2Provider, userInfo *UserInfo) (*user.User, bool, error) {
+	usr, err := s.findIdentityUser(provider, userInfo)
+	if err != nil {
 		return nil, false, err
 	}
+	if usr != nil {
+		return usr, false, nil
+	}
 
 	// 用户不存在，创建新用户
 	return s.CreateUser(provider, userInfo)
 }
 
-// CreateUser 创建新用户
+// findIdentityUser 通过已绑定的身份查找用户并同步提供商返回的信息，未绑定时返回nil
+func (s *Service) findIdentityUser(provider *oauth2Model.OAuth2Provider, userInfo *UserInfo) (*user.User, error) {
+	var identity user.UserIdentity
+	err := global.APP_DB.Where("provider_id = ? AND uid = ?", provider.ID, userInfo.ID).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var usr user.User
+	if err := global.APP_DB.First(&usr, identity.UserID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		// 用户已被删除，清理残留的身份后按新用户处理
+		global.APP_DB.Delete(&identity)
+		return nil, nil
+	}
+
+	// 更新身份信息
+	now := time.Now()
+	global.APP_DB.Model(&identity).Updates(identityUpdates(userInfo, now))
+
+	// 更新昵称和头像（如果用户未自定义）
+	updates := map[string]interface{}{}
+	if userInfo.Nickname != "" && usr.Nickname == "" {
+		updates["nickname"] = userInfo.Nickname
+	}
+	if userInfo.Avatar != "" && usr.Avatar == "" {
+		updates["avatar"] = userInfo.Avatar
+	}
+	if len(updates) > 0 {
+		global.APP_DB.Model(&usr).Updates(updates)
+	}
+	return &usr, nil
+}
+
+// CreateUser 创建新用户并绑定OAuth2身份
 func (s *Service) CreateUser(provider *oauth2Model.OAuth2Provider, userInfo *UserInfo) (*user.User, bool, error) {
 	// 生成用户名（确保唯一）
 	username := s.GenerateUniqueUsername(userInfo.Username)
@@ -443,24 +554,19 @@ func (s *Service) CreateUser(provider *oauth2Model.OAuth2Provider, userInfo *Use
 	}
 
 	// 确定用户等级
-	userLevel := s.GetUserLevel(provider, userInfo.TrustLevel)
+	userLevel := s.GetUserLevel(provider, userInfo)
 
 	// 创建用户
 	usr := &user.User{
-		Username:         username,
-		Password:         string(hashedPassword),
-		Nickname:         userInfo.Nickname,
-		Email:            userInfo.Email,
-		Avatar:           userInfo.Avatar,
-		Status:           1,
-		Level:            userLevel,
-		UserType:         "user",
-		OAuth2ProviderID: provider.ID,
-		OAuth2UID:        userInfo.ID,
-		OAuth2Username:   userInfo.Username,
-		OAuth2Email:      userInfo.Email,
-		OAuth2Avatar:     userInfo.Avatar,
-		OAuth2Extra:      userInfo.RawData,
+		Username:          username,
+		Password:          string(hashedPassword),
+		PasswordGenerated: true,
+		Nickname:          userInfo.Nickname,
+		Email:             userInfo.Email,
+		Avatar:            userInfo.Avatar,
+		Status:            1,
+		Level:             userLevel,
+		UserType:          "user",
 	}
 
 	// 设置昵称默认值
@@ -471,12 +577,24 @@ func (s *Service) CreateUser(provider *oauth2Model.OAuth2Provider, userInfo *Use
 	// 根据用户等级设置配额
 	s.SetUserQuotaByLevel(usr)
 
-	// 使用带重试的数据库操作创建用户
+	// 用户和身份在同一事务中创建，身份的唯一索引防止并发回调重复注册
+	now := time.Now()
 	err = utils.RetryableDBOperation(context.Background(), func() error {
-		return global.APP_DB.Create(usr).Error
+		return global.APP_DB.Transaction(func(tx *gorm.DB) error {
+			usr.ID = 0
+			if err := tx.Create(usr).Error; err != nil {
+				return err
+			}
+			identity := newIdentity(usr.ID, provider.ID, userInfo, now)
+			return tx.Create(identity).Error
+		})
 	}, 3)
 
 	if err != nil {
+		// 并发回调已创建了该身份，返回已存在的用户
+		if existing, findErr := s.findIdentityUser(provider, userInfo); findErr == nil && existing != nil {
+			return existing, false, nil
+		}
 		global.APP_LOG.Error("创建OAuth2用户失败", zap.Error(err))
 		return nil, false, common.NewError(common.CodeInternalError, "创建用户失败")
 	}
@@ -490,6 +608,15 @@ func (s *Service) CreateUser(provider *oauth2Model.OAuth2Provider, userInfo *Use
 	return usr, true, nil
 }
 
+// randomToken 生成URL安全的随机令牌
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
 // generateRandomPassword 生成随机密码
 func generateRandomPassword(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
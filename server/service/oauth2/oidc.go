@@ -0,0 +1,246 @@
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/common"
+	oauth2Model "oneclickvirt/model/oauth2"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcDiscoveryTTL    = time.Hour        // 发现文档缓存时长，JWKS由go-oidc按kid自动刷新
+	oidcRequestTimeout  = 15 * time.Second // 发现、令牌交换和用户信息请求的超时
+	defaultOIDCScopes   = "openid profile email"
+	oidcSubjectClaim    = "sub"
+	oidcUsernameClaim   = "preferred_username"
+	oidcEmailClaim      = "email"
+	oidcAvatarClaim     = "picture"
+	oidcNicknameClaim   = "name"
+	oidcIDTokenExtraKey = "id_token"
+)
+
+// oidcProviderEntry 缓存的OIDC提供商，复用其中的JWKS密钥集
+type oidcProviderEntry struct {
+	provider  *oidc.Provider
+	expiresAt time.Time
+}
+
+var (
+	oidcProviders   = make(map[string]*oidcProviderEntry)
+	oidcProvidersMu sync.Mutex
+)
+
+// DiscoveryResult OIDC发现结果
+type DiscoveryResult struct {
+	Issuer                        string   `json:"issuer"`
+	AuthURL                       string   `json:"authUrl"`
+	TokenURL                      string   `json:"tokenUrl"`
+	UserInfoURL                   string   `json:"userInfoUrl"`
+	JWKSURL                       string   `json:"jwksUrl"`
+	ScopesSupported               []string `json:"scopesSupported"`
+	ClaimsSupported               []string `json:"claimsSupported"`
+	SigningAlgsSupported          []string `json:"signingAlgsSupported"`
+	CodeChallengeMethodsSupported []string `json:"codeChallengeMethodsSupported"`
+}
+
+// getOIDCProvider 获取签发者的OIDC提供商，发现文档按签发者缓存
+func getOIDCProvider(ctx context.Context, issuer string) (*oidc.Provider, error) {
+	issuer = strings.TrimSpace(issuer)
+	if issuer == "" {
+		return nil, common.NewError(common.CodeOAuth2Failed, "OIDC签发者地址未配置")
+	}
+
+	oidcProvidersMu.Lock()
+	entry, ok := oidcProviders[issuer]
+	oidcProvidersMu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.provider, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		global.APP_LOG.Error("OIDC发现失败", zap.String("issuer", issuer), zap.Error(err))
+		return nil, common.NewError(common.CodeOAuth2Failed, fmt.Sprintf("OIDC发现失败: %v", err))
+	}
+
+	oidcProvidersMu.Lock()
+	oidcProviders[issuer] = &oidcProviderEntry{provider: provider, expiresAt: time.Now().Add(oidcDiscoveryTTL)}
+	oidcProvidersMu.Unlock()
+	return provider, nil
+}
+
+// invalidateOIDCProvider 清除签发者的发现缓存，提供商配置修改后调用
+func invalidateOIDCProvider(issuer string) {
+	oidcProvidersMu.Lock()
+	delete(oidcProviders, strings.TrimSpace(issuer))
+	oidcProvidersMu.Unlock()
+}
+
+// Discover 获取签发者的OIDC发现文档
+func (s *ProviderService) Discover(issuer string) (*DiscoveryResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oidcRequestTimeout)
+	defer cancel()
+
+	// 手动发现时总是重新获取
+	invalidateOIDCProvider(issuer)
+	provider, err := getOIDCProvider(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		Issuer                        string   `json:"issuer"`
+		JWKSURL                       string   `json:"jwks_uri"`
+		ScopesSupported               []string `json:"scopes_supported"`
+		ClaimsSupported               []string `json:"claims_supported"`
+		SigningAlgsSupported          []string `json:"id_token_signing_alg_values_supported"`
+		CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+	}
+	if err := provider.Claims(&doc); err != nil {
+		return nil, common.NewError(common.CodeOAuth2Failed, "解析OIDC发现文档失败")
+	}
+
+	endpoint := provider.Endpoint()
+	return &DiscoveryResult{
+		Issuer:                        doc.Issuer,
+		AuthURL:                       endpoint.AuthURL,
+		TokenURL:                      endpoint.TokenURL,
+		UserInfoURL:                   provider.UserInfoEndpoint(),
+		JWKSURL:                       doc.JWKSURL,
+		ScopesSupported:               doc.ScopesSupported,
+		ClaimsSupported:               doc.ClaimsSupported,
+		SigningAlgsSupported:          doc.SigningAlgsSupported,
+		CodeChallengeMethodsSupported: doc.CodeChallengeMethodsSupported,
+	}, nil
+}
+
+// providerScopes 解析提供商的授权范围，OIDC提供商总是包含openid
+func providerScopes(provider *oauth2Model.OAuth2Provider) []string {
+	raw := provider.Scopes
+	if raw == "" && provider.IsOIDC() {
+		raw = defaultOIDCScopes
+	}
+	scopes := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	if provider.IsOIDC() {
+		for _, scope := range scopes {
+			if scope == oidc.ScopeOpenID {
+				return scopes
+			}
+		}
+		scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+	}
+	return scopes
+}
+
+// resolveOAuth2Config 获取提供商的OAuth2配置，OIDC提供商未手动填写的端点使用发现的地址
+func (s *Service) resolveOAuth2Config(ctx context.Context, provider *oauth2Model.OAuth2Provider) (*oauth2.Config, *oidc.Provider, error) {
+	cfg := s.GetOAuth2Config(provider)
+	if !provider.IsOIDC() {
+		return cfg, nil, nil
+	}
+
+	oidcProvider, err := getOIDCProvider(ctx, provider.IssuerURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	endpoint := oidcProvider.Endpoint()
+	if cfg.Endpoint.AuthURL == "" {
+		cfg.Endpoint.AuthURL = endpoint.AuthURL
+	}
+	if cfg.Endpoint.TokenURL == "" {
+		cfg.Endpoint.TokenURL = endpoint.TokenURL
+	}
+	return cfg, oidcProvider, nil
+}
+
+// verifyIDToken 校验ID Token的签名、签发者、受众、有效期和nonce，返回其中的声明
+func verifyIDToken(ctx context.Context, oidcProvider *oidc.Provider, provider *oauth2Model.OAuth2Provider, token *oauth2.Token, nonce string) (map[string]interface{}, error) {
+	rawIDToken, ok := token.Extra(oidcIDTokenExtraKey).(string)
+	if !ok || rawIDToken == "" {
+		return nil, fmt.Errorf("令牌响应中缺少id_token")
+	}
+
+	verifier := oidcProvider.Verifier(&oidc.Config{ClientID: provider.ClientID})
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("ID Token校验失败: %w", err)
+	}
+	if nonce == "" || idToken.Nonce != nonce {
+		return nil, fmt.Errorf("ID Token的nonce不匹配")
+	}
+	if idToken.AccessTokenHash != "" {
+		if err := idToken.VerifyAccessToken(token.AccessToken); err != nil {
+			return nil, fmt.Errorf("访问令牌与ID Token不匹配: %w", err)
+		}
+	}
+
+	claims := make(map[string]interface{})
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("解析ID Token声明失败: %w", err)
+	}
+	return claims, nil
+}
+
+// fetchOIDCUserInfo 获取用户信息声明并合并到ID Token声明中，用户信息的sub必须与ID Token一致
+func (s *Service) fetchOIDCUserInfo(ctx context.Context, oidcProvider *oidc.Provider, provider *oauth2Model.OAuth2Provider, token *oauth2.Token, claims map[string]interface{}) error {
+	var userInfoClaims map[string]interface{}
+	switch {
+	case provider.UserInfoURL != "":
+		data, err := s.FetchUserInfo(provider, token)
+		if err != nil {
+			return err
+		}
+		userInfoClaims = data
+	case oidcProvider.UserInfoEndpoint() != "":
+		info, err := oidcProvider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+		if err != nil {
+			return fmt.Errorf("获取用户信息失败: %w", err)
+		}
+		if err := info.Claims(&userInfoClaims); err != nil {
+			return fmt.Errorf("解析用户信息失败: %w", err)
+		}
+	default:
+		return nil
+	}
+
+	if sub := fmt.Sprintf("%v", userInfoClaims[oidcSubjectClaim]); sub != fmt.Sprintf("%v", claims[oidcSubjectClaim]) {
+		return fmt.Errorf("用户信息的sub与ID Token不一致")
+	}
+	for key, value := range userInfoClaims {
+		claims[key] = value
+	}
+	return nil
+}
+
+// applyOIDCDefaults 为OIDC提供商填充标准声明的字段映射
+func applyOIDCDefaults(req *CreateProviderRequest) {
+	if req.UserIDField == "" {
+		req.UserIDField = oidcSubjectClaim
+	}
+	if req.UsernameField == "" {
+		req.UsernameField = oidcUsernameClaim
+	}
+	if req.EmailField == "" {
+		req.EmailField = oidcEmailClaim
+	}
+	if req.AvatarField == "" {
+		req.AvatarField = oidcAvatarClaim
+	}
+	if req.NicknameField == "" {
+		req.NicknameField = oidcNicknameClaim
+	}
+	if req.Scopes == "" {
+		req.Scopes = defaultOIDCScopes
+	}
+}
@@ -45,6 +45,19 @@ func (s *ProviderService) CreateProvider(req *CreateProviderRequest) (*oauth2Mod
 		return nil, common.NewError(common.CodeValidationError, "提供商名称已存在")
 	}
 
+	if req.ProviderType == "" {
+		req.ProviderType = oauth2Model.ProviderTypeOAuth2
+	}
+	if req.ProviderType == oauth2Model.ProviderTypeOIDC {
+		// 创建时完成一次发现，确保签发者地址可用
+		if _, err := s.Discover(req.IssuerURL); err != nil {
+			return nil, err
+		}
+		applyOIDCDefaults(req)
+	} else if req.AuthURL == "" || req.TokenURL == "" || req.UserInfoURL == "" {
+		return nil, common.NewError(common.CodeValidationError, "授权地址、令牌地址和用户信息地址不能为空")
+	}
+
 	// 序列化LevelMapping
 	levelMappingJSON, _ := json.Marshal(req.LevelMapping)
 
@@ -55,9 +68,13 @@ func (s *ProviderService) CreateProvider(req *CreateProviderRequest) (*oauth2Mod
 		ClientID:         req.ClientID,
 		ClientSecret:     req.ClientSecret,
 		RedirectURL:      req.RedirectURL,
+		ProviderType:     req.ProviderType,
+		IssuerURL:        req.IssuerURL,
 		AuthURL:          req.AuthURL,
 		TokenURL:         req.TokenURL,
 		UserInfoURL:      req.UserInfoURL,
+		Scopes:           req.Scopes,
+		UsePKCE:          req.UsePKCE,
 		UserIDField:      req.UserIDField,
 		UsernameField:    req.UsernameField,
 		EmailField:       req.EmailField,
@@ -118,6 +135,18 @@ func (s *ProviderService) UpdateProvider(id uint, req *UpdateProviderRequest) er
 	if req.RedirectURL != nil {
 		updates["redirect_url"] = *req.RedirectURL
 	}
+	if req.ProviderType != nil {
+		updates["provider_type"] = *req.ProviderType
+	}
+	if req.IssuerURL != nil {
+		updates["issuer_url"] = *req.IssuerURL
+	}
+	if req.Scopes != nil {
+		updates["scopes"] = *req.Scopes
+	}
+	if req.UsePKCE != nil {
+		updates["use_pkce"] = *req.UsePKCE
+	}
 	if req.AuthURL != nil {
 		updates["auth_url"] = *req.AuthURL
 	}
@@ -159,12 +188,27 @@ func (s *ProviderService) UpdateProvider(id uint, req *UpdateProviderRequest) er
 		updates["sort"] = *req.Sort
 	}
 
+	// 切换为OIDC或修改签发者地址时重新发现
+	providerType, issuerURL := provider.ProviderType, provider.IssuerURL
+	if req.ProviderType != nil {
+		providerType = *req.ProviderType
+	}
+	if req.IssuerURL != nil {
+		issuerURL = *req.IssuerURL
+	}
+	if providerType == oauth2Model.ProviderTypeOIDC && (!provider.IsOIDC() || issuerURL != provider.IssuerURL) {
+		if _, err := s.Discover(issuerURL); err != nil {
+			return err
+		}
+	}
+
 	if len(updates) > 0 {
 		if err := global.APP_DB.Model(&provider).Updates(updates).Error; err != nil {
 			global.APP_LOG.Error("更新OAuth2提供商失败", zap.Error(err))
 			return common.NewError(common.CodeInternalError, "更新失败")
 		}
 	}
+	invalidateOIDCProvider(provider.IssuerURL)
 
 	global.APP_LOG.Info("更新OAuth2提供商成功", zap.Uint("id", id))
 	return nil
@@ -182,7 +226,7 @@ func (s *ProviderService) DeleteProvider(id uint) error {
 
 	// 检查是否有用户使用此提供商
 	var userCount int64
-	global.APP_DB.Model(&userModel.User{}).Where(&userModel.User{OAuth2ProviderID: id}).Count(&userCount)
+	global.APP_DB.Model(&userModel.UserIdentity{}).Where("provider_id = ?", id).Count(&userCount)
 	if userCount > 0 {
 		return common.NewError(common.CodeValidationError, "无法删除：有用户正在使用此提供商")
 	}
@@ -217,9 +261,13 @@ type CreateProviderRequest struct {
 	ClientID         string         `json:"clientId" binding:"required"`
 	ClientSecret     string         `json:"clientSecret" binding:"required"`
 	RedirectURL      string         `json:"redirectUrl" binding:"required"`
-	AuthURL          string         `json:"authUrl" binding:"required"`
-	TokenURL         string         `json:"tokenUrl" binding:"required"`
-	UserInfoURL      string         `json:"userInfoUrl" binding:"required"`
+	ProviderType     string         `json:"providerType" binding:"omitempty,oneof=oauth2 oidc"` // 提供商类型，默认oauth2
+	IssuerURL        string         `json:"issuerUrl"`                                          // OIDC签发者地址，oidc类型必填
+	AuthURL          string         `json:"authUrl"`                                            // oauth2类型必填
+	TokenURL         string         `json:"tokenUrl"`                                           // oauth2类型必填
+	UserInfoURL      string         `json:"userInfoUrl"`                                        // oauth2类型必填
+	Scopes           string         `json:"scopes"`
+	UsePKCE          bool           `json:"usePkce"`
 	UserIDField      string         `json:"userIdField"`
 	UsernameField    string         `json:"usernameField"`
 	EmailField       string         `json:"emailField"`
@@ -232,6 +280,11 @@ type CreateProviderRequest struct {
 	Sort             int            `json:"sort"`
 }
 
+// DiscoverRequest OIDC发现请求
+type DiscoverRequest struct {
+	IssuerURL string `json:"issuerUrl" binding:"required"` // 签发者地址
+}
+
 // UpdateProviderRequest 更新提供商请求
 type UpdateProviderRequest struct {
 	Name             *string        `json:"name"`
@@ -240,9 +293,13 @@ type UpdateProviderRequest struct {
 	ClientID         *string        `json:"clientId"`
 	ClientSecret     *string        `json:"clientSecret"`
 	RedirectURL      *string        `json:"redirectUrl"`
+	ProviderType     *string        `json:"providerType" binding:"omitempty,oneof=oauth2 oidc"`
+	IssuerURL        *string        `json:"issuerUrl"`
 	AuthURL          *string        `json:"authUrl"`
 	TokenURL         *string        `json:"tokenUrl"`
 	UserInfoURL      *string        `json:"userInfoUrl"`
+	Scopes           *string        `json:"scopes"`
+	UsePKCE          *bool          `json:"usePkce"`
 	UserIDField      *string        `json:"userIdField"`
 	UsernameField    *string        `json:"usernameField"`
 	EmailField       *string        `json:"emailField"`
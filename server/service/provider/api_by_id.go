@@ -33,6 +33,22 @@ func (s *ProviderApiService) GetProviderByID(providerID uint) (provider.Provider
 		return nil, nil, fmt.Errorf("Provider已过期")
 	}
 
+	return s.loadProviderInstance(providerID, dbProvider)
+}
+
+// GetProviderByIDIgnoreFrozen 根据Provider ID获取Provider实例，不检查冻结和过期状态
+// 用于将实例从冻结或即将退役的节点迁移出去
+func (s *ProviderApiService) GetProviderByIDIgnoreFrozen(providerID uint) (provider.Provider, *providerModel.Provider, error) {
+	var dbProvider providerModel.Provider
+	if err := global.APP_DB.First(&dbProvider, providerID).Error; err != nil {
+		return nil, nil, fmt.Errorf("Provider不存在")
+	}
+
+	return s.loadProviderInstance(providerID, dbProvider)
+}
+
+// loadProviderInstance 获取已连接的Provider实例，未连接时尝试加载并连接
+func (s *ProviderApiService) loadProviderInstance(providerID uint, dbProvider providerModel.Provider) (provider.Provider, *providerModel.Provider, error) {
 	// 从Provider服务获取已连接的实例
 	providerService := GetProviderService()
 	if prov, exists := providerService.GetProvider(dbProvider.Name); exists {
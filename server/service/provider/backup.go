@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+
+	"go.uber.org/zap"
+)
+
+// ExportInstanceByProviderID 根据Provider ID导出实例备份到本地目录，返回备份文件名
+func (s *ProviderApiService) ExportInstanceByProviderID(ctx context.Context, providerID uint, instanceID, localDir string) (string, error) {
+	prov, _, err := s.GetProviderByID(providerID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := CheckProviderConnection(prov); err != nil {
+		return "", err
+	}
+
+	fileName, err := prov.ExportInstance(ctx, instanceID, localDir)
+	if err != nil {
+		global.APP_LOG.Error("导出实例备份失败",
+			zap.Uint("providerId", providerID),
+			zap.String("instanceId", instanceID),
+			zap.Error(err))
+		return "", fmt.Errorf("导出实例备份失败: %v", err)
+	}
+
+	global.APP_LOG.Info("实例备份导出成功",
+		zap.Uint("providerId", providerID),
+		zap.String("instanceId", instanceID),
+		zap.String("file", fileName))
+	return fileName, nil
+}
+
+// ImportInstanceByProviderID 根据Provider ID将本地备份文件恢复为新实例
+func (s *ProviderApiService) ImportInstanceByProviderID(ctx context.Context, providerID uint, localPath string, config provider.InstanceConfig) error {
+	prov, _, err := s.GetProviderByID(providerID)
+	if err != nil {
+		return err
+	}
+
+	if err := CheckProviderConnection(prov); err != nil {
+		return err
+	}
+
+	if err := prov.ImportInstance(ctx, localPath, config); err != nil {
+		global.APP_LOG.Error("导入实例备份失败",
+			zap.Uint("providerId", providerID),
+			zap.String("instanceName", config.Name),
+			zap.Error(err))
+		return fmt.Errorf("导入实例备份失败: %v", err)
+	}
+
+	global.APP_LOG.Info("实例备份导入成功",
+		zap.Uint("providerId", providerID),
+		zap.String("instanceName", config.Name))
+	return nil
+}
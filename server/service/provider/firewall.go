@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+
+	"go.uber.org/zap"
+)
+
+// SetInstanceFirewallByProviderID 根据Provider ID覆盖实例的入站防火墙规则，rules为空时移除过滤
+func (s *ProviderApiService) SetInstanceFirewallByProviderID(ctx context.Context, providerID uint, instanceID string, rules []provider.FirewallRule) error {
+	prov, _, err := s.GetProviderByID(providerID)
+	if err != nil {
+		return err
+	}
+
+	if err := CheckProviderConnection(prov); err != nil {
+		return err
+	}
+
+	if err := prov.SetInstanceFirewall(ctx, instanceID, rules); err != nil {
+		global.APP_LOG.Error("配置实例防火墙失败",
+			zap.Uint("providerId", providerID),
+			zap.String("instanceId", instanceID),
+			zap.Error(err))
+		return fmt.Errorf("配置实例防火墙失败: %v", err)
+	}
+
+	global.APP_LOG.Info("实例防火墙配置成功",
+		zap.Uint("providerId", providerID),
+		zap.String("instanceId", instanceID),
+		zap.Int("ruleCount", len(rules)))
+	return nil
+}
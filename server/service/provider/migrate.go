@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+
+	"go.uber.org/zap"
+)
+
+// 实例迁移通过源节点导出、目标节点导入完成，适用于不在同一集群内的独立节点。
+// 源节点通常已冻结或即将过期，因此源节点上的操作不检查冻结和过期状态。
+
+// getMigrationSource 获取迁移源节点的Provider实例
+func (s *ProviderApiService) getMigrationSource(providerID uint) (provider.Provider, error) {
+	prov, _, err := s.GetProviderByIDIgnoreFrozen(providerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckProviderConnection(prov); err != nil {
+		return nil, err
+	}
+	return prov, nil
+}
+
+// MigrateInstanceByProviderID 将实例从源Provider复制到目标Provider
+// 源实例会先停止以保证导出数据一致，迁移文件暂存在localDir中；源实例不会被删除，由调用方在目标实例就绪后清理
+func (s *ProviderApiService) MigrateInstanceByProviderID(ctx context.Context, sourceProviderID, targetProviderID uint, localDir string, config provider.InstanceConfig, progressCallback provider.ProgressCallback) error {
+	report := func(percentage int, message string) {
+		if progressCallback != nil {
+			progressCallback(percentage, message)
+		}
+	}
+
+	source, err := s.getMigrationSource(sourceProviderID)
+	if err != nil {
+		return fmt.Errorf("源节点不可用: %v", err)
+	}
+
+	report(5, "正在停止源实例...")
+	if err := source.StopInstance(ctx, config.Name); err != nil {
+		// 实例可能本来就处于停止状态，导出失败时会再报告错误
+		global.APP_LOG.Warn("停止源实例失败，继续导出",
+			zap.Uint("providerId", sourceProviderID),
+			zap.String("instanceName", config.Name),
+			zap.Error(err))
+	}
+
+	report(10, "正在导出源实例...")
+	fileName, err := source.ExportInstance(ctx, config.Name, localDir)
+	if err != nil {
+		global.APP_LOG.Error("迁移导出实例失败",
+			zap.Uint("providerId", sourceProviderID),
+			zap.String("instanceName", config.Name),
+			zap.Error(err))
+		return fmt.Errorf("导出源实例失败: %v", err)
+	}
+
+	report(50, "正在导入实例到目标节点...")
+	if err := s.ImportInstanceByProviderID(ctx, targetProviderID, filepath.Join(localDir, fileName), config); err != nil {
+		return err
+	}
+
+	report(100, "实例已导入目标节点")
+
+	global.APP_LOG.Info("实例迁移复制完成",
+		zap.Uint("sourceProviderId", sourceProviderID),
+		zap.Uint("targetProviderId", targetProviderID),
+		zap.String("instanceName", config.Name))
+	return nil
+}
+
+// StartMigrationSourceInstance 迁移失败时重新启动源节点上的实例
+func (s *ProviderApiService) StartMigrationSourceInstance(ctx context.Context, providerID uint, instanceName string) error {
+	source, err := s.getMigrationSource(providerID)
+	if err != nil {
+		return err
+	}
+	return source.StartInstance(ctx, instanceName)
+}
+
+// DeleteMigrationSourceInstance 迁移完成后删除源节点上的实例
+func (s *ProviderApiService) DeleteMigrationSourceInstance(ctx context.Context, providerID uint, instanceName string) error {
+	source, err := s.getMigrationSource(providerID)
+	if err != nil {
+		return err
+	}
+	if err := source.DeleteInstance(ctx, instanceName); err != nil {
+		return fmt.Errorf("删除源实例失败: %v", err)
+	}
+
+	global.APP_LOG.Info("迁移源实例已删除",
+		zap.Uint("providerId", providerID),
+		zap.String("instanceName", instanceName))
+	return nil
+}
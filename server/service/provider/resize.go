@@ -0,0 +1,36 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+
+	"go.uber.org/zap"
+)
+
+// ResizeInstanceByProviderID 根据Provider ID在线调整实例规格
+func (s *ProviderApiService) ResizeInstanceByProviderID(ctx context.Context, providerID uint, instanceID string, spec provider.ResizeSpec) error {
+	prov, _, err := s.GetProviderByID(providerID)
+	if err != nil {
+		return err
+	}
+
+	if err := CheckProviderConnection(prov); err != nil {
+		return err
+	}
+
+	if err := prov.ResizeInstance(ctx, instanceID, spec); err != nil {
+		global.APP_LOG.Error("调整实例规格失败",
+			zap.Uint("providerId", providerID),
+			zap.String("instanceId", instanceID),
+			zap.Error(err))
+		return fmt.Errorf("调整实例规格失败: %v", err)
+	}
+
+	global.APP_LOG.Info("实例规格调整成功",
+		zap.Uint("providerId", providerID),
+		zap.String("instanceId", instanceID))
+	return nil
+}
@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+
+	"go.uber.org/zap"
+)
+
+// CreateSnapshotByProviderID 根据Provider ID创建实例快照
+func (s *ProviderApiService) CreateSnapshotByProviderID(ctx context.Context, providerID uint, instanceID, snapshotName string) error {
+	prov, _, err := s.GetProviderByID(providerID)
+	if err != nil {
+		return err
+	}
+
+	if err := CheckProviderConnection(prov); err != nil {
+		return err
+	}
+
+	if err := prov.CreateSnapshot(ctx, instanceID, snapshotName); err != nil {
+		global.APP_LOG.Error("创建快照失败",
+			zap.Uint("providerId", providerID),
+			zap.String("instanceId", instanceID),
+			zap.String("snapshot", snapshotName),
+			zap.Error(err))
+		return fmt.Errorf("创建快照失败: %v", err)
+	}
+
+	global.APP_LOG.Info("快照创建成功",
+		zap.Uint("providerId", providerID),
+		zap.String("instanceId", instanceID),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
+
+// ListSnapshotsByProviderID 根据Provider ID获取实例快照列表
+func (s *ProviderApiService) ListSnapshotsByProviderID(ctx context.Context, providerID uint, instanceID string) ([]provider.Snapshot, error) {
+	prov, _, err := s.GetProviderByID(providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckProviderConnection(prov); err != nil {
+		return nil, err
+	}
+
+	snapshots, err := prov.ListSnapshots(ctx, instanceID)
+	if err != nil {
+		global.APP_LOG.Error("获取快照列表失败",
+			zap.Uint("providerId", providerID),
+			zap.String("instanceId", instanceID),
+			zap.Error(err))
+		return nil, fmt.Errorf("获取快照列表失败: %v", err)
+	}
+
+	return snapshots, nil
+}
+
+// RestoreSnapshotByProviderID 根据Provider ID将实例恢复到指定快照
+func (s *ProviderApiService) RestoreSnapshotByProviderID(ctx context.Context, providerID uint, instanceID, snapshotName string) error {
+	prov, _, err := s.GetProviderByID(providerID)
+	if err != nil {
+		return err
+	}
+
+	if err := CheckProviderConnection(prov); err != nil {
+		return err
+	}
+
+	if err := prov.RestoreSnapshot(ctx, instanceID, snapshotName); err != nil {
+		global.APP_LOG.Error("恢复快照失败",
+			zap.Uint("providerId", providerID),
+			zap.String("instanceId", instanceID),
+			zap.String("snapshot", snapshotName),
+			zap.Error(err))
+		return fmt.Errorf("恢复快照失败: %v", err)
+	}
+
+	global.APP_LOG.Info("快照恢复成功",
+		zap.Uint("providerId", providerID),
+		zap.String("instanceId", instanceID),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
+
+// DeleteSnapshotByProviderID 根据Provider ID删除实例快照
+func (s *ProviderApiService) DeleteSnapshotByProviderID(ctx context.Context, providerID uint, instanceID, snapshotName string) error {
+	prov, _, err := s.GetProviderByID(providerID)
+	if err != nil {
+		return err
+	}
+
+	if err := CheckProviderConnection(prov); err != nil {
+		return err
+	}
+
+	if err := prov.DeleteSnapshot(ctx, instanceID, snapshotName); err != nil {
+		global.APP_LOG.Error("删除快照失败",
+			zap.Uint("providerId", providerID),
+			zap.String("instanceId", instanceID),
+			zap.String("snapshot", snapshotName),
+			zap.Error(err))
+		return fmt.Errorf("删除快照失败: %v", err)
+	}
+
+	global.APP_LOG.Info("快照删除成功",
+		zap.Uint("providerId", providerID),
+		zap.String("instanceId", instanceID),
+		zap.String("snapshot", snapshotName))
+	return nil
+}
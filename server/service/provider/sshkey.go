@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/global"
+
+	"go.uber.org/zap"
+)
+
+// SetInstanceSSHKeysByProviderID 根据Provider ID向实例写入SSH公钥，可选禁用密码登录
+func (s *ProviderApiService) SetInstanceSSHKeysByProviderID(ctx context.Context, providerID uint, instanceID string, publicKeys []string, disablePassword bool) error {
+	prov, _, err := s.GetProviderByID(providerID)
+	if err != nil {
+		return err
+	}
+
+	if err := CheckProviderConnection(prov); err != nil {
+		return err
+	}
+
+	if err := prov.SetInstanceSSHKeys(ctx, instanceID, publicKeys, disablePassword); err != nil {
+		global.APP_LOG.Error("注入SSH公钥失败",
+			zap.Uint("providerId", providerID),
+			zap.String("instanceId", instanceID),
+			zap.Error(err))
+		return fmt.Errorf("注入SSH公钥失败: %v", err)
+	}
+
+	global.APP_LOG.Info("实例SSH公钥注入成功",
+		zap.Uint("providerId", providerID),
+		zap.String("instanceId", instanceID),
+		zap.Int("keyCount", len(publicKeys)),
+		zap.Bool("disablePassword", disablePassword))
+	return nil
+}
@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"oneclickvirt/global"
+	"oneclickvirt/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// OpenTerminalByProviderID 根据Provider ID打开实例的交互式终端
+func (s *ProviderApiService) OpenTerminalByProviderID(ctx context.Context, providerID uint, instanceID string, cols, rows int) (*utils.SSHTerminal, error) {
+	prov, _, err := s.GetProviderByID(providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckProviderConnection(prov); err != nil {
+		return nil, err
+	}
+
+	terminal, err := prov.OpenTerminal(ctx, instanceID, cols, rows)
+	if err != nil {
+		global.APP_LOG.Error("打开实例终端失败",
+			zap.Uint("providerId", providerID),
+			zap.String("instanceId", instanceID),
+			zap.Error(err))
+		return nil, fmt.Errorf("打开实例终端失败: %v", err)
+	}
+	return terminal, nil
+}
+
+// OpenConsoleByProviderID 根据Provider ID打开实例的图形控制台
+func (s *ProviderApiService) OpenConsoleByProviderID(ctx context.Context, providerID uint, instanceID string) (*provider.ConsoleStream, error) {
+	prov, _, err := s.GetProviderByID(providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckProviderConnection(prov); err != nil {
+		return nil, err
+	}
+
+	stream, err := prov.OpenConsole(ctx, instanceID)
+	if err != nil {
+		global.APP_LOG.Error("打开实例控制台失败",
+			zap.Uint("providerId", providerID),
+			zap.String("instanceId", instanceID),
+			zap.Error(err))
+		return nil, fmt.Errorf("打开实例控制台失败: %v", err)
+	}
+	return stream, nil
+}
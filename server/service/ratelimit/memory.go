@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+const memoryCleanupInterval = 1000 // 每处理多少次请求清理一次过期的键
+
+// memoryEntry 单个键的事件时间记录
+type memoryEntry struct {
+	events []time.Time
+	expiry time.Time
+}
+
+// memoryBucket 单个键的令牌桶状态
+type memoryBucket struct {
+	tokens float64
+	last   time.Time
+	expiry time.Time
+}
+
+// memoryStore 基于内存的滑动窗口计数和令牌桶，仅适用于单实例部署
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	buckets map[string]*memoryBucket
+	ops     int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		entries: make(map[string]*memoryEntry),
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+func (s *memoryStore) Hit(key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.cleanup(now)
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	entry.events = append(prune(entry.events, now.Add(-window)), now)
+	entry.expiry = now.Add(window)
+	return len(entry.events), nil
+}
+
+func (s *memoryStore) Count(key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return 0, nil
+	}
+	entry.events = prune(entry.events, time.Now().Add(-window))
+	return len(entry.events), nil
+}
+
+func (s *memoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	delete(s.buckets, key)
+	return nil
+}
+
+func (s *memoryStore) Take(key string, rate float64, burst int) (TakeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.cleanup(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(burst), last: now}
+		s.buckets[key] = bucket
+	}
+	bucket.tokens += now.Sub(bucket.last).Seconds() * rate
+	if bucket.tokens > float64(burst) {
+		bucket.tokens = float64(burst)
+	}
+	bucket.last = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	// 桶补满后状态与新建时相同，可以清理
+	bucket.expiry = now.Add(time.Duration((float64(burst) - bucket.tokens) / rate * float64(time.Second)))
+	return newTakeResult(allowed, bucket.tokens, rate, burst), nil
+}
+
+// cleanup 定期清理过期的键，调用方需持有锁
+func (s *memoryStore) cleanup(now time.Time) {
+	s.ops++
+	if s.ops < memoryCleanupInterval {
+		return
+	}
+	s.ops = 0
+	for k, entry := range s.entries {
+		if now.After(entry.expiry) {
+			delete(s.entries, k)
+		}
+	}
+	for k, bucket := range s.buckets {
+		if now.After(bucket.expiry) {
+			delete(s.buckets, k)
+		}
+	}
+}
+
+// prune 移除早于since的事件，事件按时间顺序追加
+func prune(events []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(events) && !events[i].After(since) {
+		i++
+	}
+	return events[i:]
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeyPrefix       = "oneclickvirt:ratelimit:"
+	redisBucketKeyPrefix = "oneclickvirt:ratelimit:bucket:"
+	redisTimeout         = 2 * time.Second
+)
+
+// takeScript 原子地补充令牌并尝试取出一个，返回是否取到和剩余令牌数
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// redisStore 基于Redis有序集合的滑动窗口计数，多实例部署时共享
+type redisStore struct {
+	client *redis.Client
+}
+
+func (s *redisStore) Hit(key string, window time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	now := time.Now()
+	redisKey := redisKeyPrefix + key
+	var card *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, redisKey, "-inf", strconv.FormatInt(now.Add(-window).UnixNano(), 10))
+		pipe.ZAdd(ctx, redisKey, redis.Z{Score: float64(now.UnixNano()), Member: eventMember(now)})
+		card = pipe.ZCard(ctx, redisKey)
+		pipe.PExpire(ctx, redisKey, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(card.Val()), nil
+}
+
+func (s *redisStore) Count(key string, window time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	since := strconv.FormatInt(time.Now().Add(-window).UnixNano(), 10)
+	n, err := s.client.ZCount(ctx, redisKeyPrefix+key, "("+since, "+inf").Result()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+func (s *redisStore) Reset(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return s.client.Del(ctx, redisKeyPrefix+key, redisBucketKeyPrefix+key).Err()
+}
+
+func (s *redisStore) Take(key string, rate float64, burst int) (TakeResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	res, err := takeScript.Run(ctx, s.client, []string{redisBucketKeyPrefix + key},
+		strconv.FormatFloat(rate, 'f', -1, 64), burst, time.Now().UnixMilli()).Slice()
+	if err != nil {
+		return TakeResult{}, err
+	}
+	if len(res) != 2 {
+		return TakeResult{}, fmt.Errorf("令牌桶脚本返回值异常: %v", res)
+	}
+	allowed, _ := res[0].(int64)
+	tokensStr, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return TakeResult{}, fmt.Errorf("令牌桶脚本返回值异常: %v", res)
+	}
+	return newTakeResult(allowed == 1, tokens, rate, burst), nil
+}
+
+// eventMember 生成有序集合成员，同一纳秒内的多个事件也不会互相覆盖
+func eventMember(now time.Time) string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return strconv.FormatInt(now.UnixNano(), 10) + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"oneclickvirt/global"
+)
+
+// Store 限流计数存储
+// 默认使用内存存储，启用system.use-redis且Redis可用时使用Redis存储，多实例部署时共享计数
+type Store interface {
+	// Hit 记录一次事件并返回滑动窗口内的事件数（包含本次）
+	Hit(key string, window time.Duration) (int, error)
+	// Count 返回滑动窗口内的事件数，不记录新事件
+	Count(key string, window time.Duration) (int, error)
+	// Reset 清除计数
+	Reset(key string) error
+	// Take 从令牌桶中取出一个令牌，rate为每秒补充的令牌数，burst为桶容量
+	Take(key string, rate float64, burst int) (TakeResult, error)
+}
+
+// TakeResult 令牌桶取令牌结果
+type TakeResult struct {
+	Allowed    bool          // 是否取到令牌
+	Remaining  int           // 剩余令牌数
+	RetryAfter time.Duration // 未取到令牌时距离下一个令牌补充的时间
+	ResetAfter time.Duration // 令牌桶补满所需时间
+}
+
+// newTakeResult 根据取令牌后的桶内令牌数计算结果
+func newTakeResult(allowed bool, tokens, rate float64, burst int) TakeResult {
+	result := TakeResult{
+		Allowed:    allowed,
+		Remaining:  int(tokens),
+		ResetAfter: time.Duration((float64(burst) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return result
+}
+
+var (
+	memory     *memoryStore
+	memoryOnce sync.Once
+)
+
+// GetStore 获取当前使用的限流存储
+func GetStore() Store {
+	if global.APP_REDIS != nil {
+		return &redisStore{client: global.APP_REDIS}
+	}
+	memoryOnce.Do(func() {
+		memory = newMemoryStore()
+	})
+	return memory
+}
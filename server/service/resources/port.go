@@ -1,11 +1,14 @@
 package resources
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"oneclickvirt/constant"
 	"oneclickvirt/global"
 	"oneclickvirt/model/admin"
 	"oneclickvirt/model/provider"
+	"oneclickvirt/provider/portmapping"
 	"oneclickvirt/utils"
 	"strings"
 
@@ -83,23 +86,8 @@ func (s *PortMappingService) CreatePortMappingWithTask(req admin.CreatePortMappi
 		return 0, nil, fmt.Errorf("Provider不存在")
 	}
 
-	// 只支持 LXD/Incus/Proxmox 手动添加端口
-	if providerInfo.Type != "lxd" && providerInfo.Type != "incus" && providerInfo.Type != "proxmox" {
-		return 0, nil, fmt.Errorf("不支持的 Provider 类型，手动添加端口仅支持 LXD/Incus/Proxmox")
-	}
-
-	// 检查是否为独立IPv4模式或纯IPv6模式
-	if providerInfo.NetworkType == "dedicated_ipv4" || providerInfo.NetworkType == "dedicated_ipv4_ipv6" || providerInfo.NetworkType == "ipv6_only" {
-		var reason string
-		switch providerInfo.NetworkType {
-		case "dedicated_ipv4":
-			reason = "独立IPv4模式下不需要端口映射，实例已具有独立的IPv4地址"
-		case "dedicated_ipv4_ipv6":
-			reason = "独立IPv4+IPv6模式下不需要端口映射，实例已具有独立的IP地址"
-		case "ipv6_only":
-			reason = "纯IPv6模式下不允许IPv4端口映射，请使用IPv6直接访问"
-		}
-		return 0, nil, fmt.Errorf("%s", reason)
+	if err := checkManualPortSupported(&providerInfo); err != nil {
+		return 0, nil, err
 	}
 
 	// 分配主机端口
@@ -165,6 +153,134 @@ func (s *PortMappingService) CreatePortMappingWithTask(req admin.CreatePortMappi
 	return port.ID, taskData, nil
 }
 
+// checkManualPortSupported 检查Provider是否支持手动添加端口映射
+func checkManualPortSupported(providerInfo *provider.Provider) error {
+	// 只支持 LXD/Incus/Proxmox 手动添加端口
+	if providerInfo.Type != "lxd" && providerInfo.Type != "incus" && providerInfo.Type != "proxmox" {
+		return fmt.Errorf("不支持的 Provider 类型，手动添加端口仅支持 LXD/Incus/Proxmox")
+	}
+
+	// 检查是否为独立IPv4模式或纯IPv6模式
+	switch providerInfo.NetworkType {
+	case "dedicated_ipv4":
+		return fmt.Errorf("独立IPv4模式下不需要端口映射，实例已具有独立的IPv4地址")
+	case "dedicated_ipv4_ipv6":
+		return fmt.Errorf("独立IPv4+IPv6模式下不需要端口映射，实例已具有独立的IP地址")
+	case "ipv6_only":
+		return fmt.Errorf("纯IPv6模式下不允许IPv4端口映射，请使用IPv6直接访问")
+	}
+	return nil
+}
+
+// CreateUserPortMappingWithTask 用户为自己的实例添加端口映射，主机端口从Provider端口范围内自动分配
+// maxPorts为该实例允许的手动端口数量，数量检查、端口分配和记录创建在同一事务中完成，避免并发超限
+// 返回任务数据（由调用者创建任务）
+func (s *PortMappingService) CreateUserPortMappingWithTask(userID, instanceID uint, guestPort int, protocol, description string, maxPorts int) (*admin.CreatePortMappingTaskRequest, error) {
+	var instance provider.Instance
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", instanceID, userID).First(&instance).Error; err != nil {
+		return nil, errors.New("实例不存在或无权限")
+	}
+	if instance.Status != "running" && instance.Status != "stopped" {
+		return nil, errors.New("实例当前状态不允许添加端口映射")
+	}
+
+	var providerInfo provider.Provider
+	if err := global.APP_DB.Where("id = ?", instance.ProviderID).First(&providerInfo).Error; err != nil {
+		return nil, fmt.Errorf("Provider不存在")
+	}
+	if err := checkManualPortSupported(&providerInfo); err != nil {
+		return nil, err
+	}
+
+	var port provider.Port
+	err := global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&provider.Port{}).
+			Where("instance_id = ? AND port_type = ?", instanceID, "manual").
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) >= maxPorts {
+			return fmt.Errorf("端口映射数量已达上限（%d个），请先删除不需要的端口", maxPorts)
+		}
+
+		// 同一实例端口的同一协议只允许映射一次
+		protocols := []string{protocol, "both"}
+		if protocol == "both" {
+			protocols = []string{"tcp", "udp", "both"}
+		}
+		var existing int64
+		if err := tx.Model(&provider.Port{}).
+			Where("instance_id = ? AND guest_port = ? AND protocol IN ? AND status <> ?", instanceID, guestPort, protocols, "deleting").
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("实例端口 %d 已存在映射", guestPort)
+		}
+
+		hostPort, err := s.allocateHostPortInTx(tx, providerInfo.ID, providerInfo.PortRangeStart, providerInfo.PortRangeEnd)
+		if err != nil {
+			return fmt.Errorf("端口分配失败: %v", err)
+		}
+
+		port = provider.Port{
+			InstanceID:    instanceID,
+			ProviderID:    providerInfo.ID,
+			HostPort:      hostPort,
+			GuestPort:     guestPort,
+			Protocol:      protocol,
+			Description:   description,
+			Status:        "pending",
+			IsSSH:         guestPort == 22,
+			IsAutomatic:   false,
+			PortType:      "manual",
+			IPv6Enabled:   false,
+			MappingMethod: providerInfo.IPv4PortMappingMethod,
+		}
+		return tx.Create(&port).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	global.APP_LOG.Info("用户端口映射记录已创建，准备创建任务",
+		zap.Uint("user_id", userID),
+		zap.Uint("port_id", port.ID),
+		zap.Uint("instance_id", instanceID),
+		zap.Int("host_port", port.HostPort),
+		zap.Int("guest_port", guestPort))
+
+	return &admin.CreatePortMappingTaskRequest{
+		PortID:      port.ID,
+		InstanceID:  instanceID,
+		ProviderID:  providerInfo.ID,
+		HostPort:    port.HostPort,
+		GuestPort:   guestPort,
+		Protocol:    protocol,
+		Description: description,
+	}, nil
+}
+
+// DeleteUserPortMappingWithTask 用户删除自己实例上手动添加的端口映射
+// 返回任务数据（由调用者创建任务）
+func (s *PortMappingService) DeleteUserPortMappingWithTask(userID, instanceID, portID uint) (*admin.DeletePortMappingTaskRequest, error) {
+	var instance provider.Instance
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", instanceID, userID).First(&instance).Error; err != nil {
+		return nil, errors.New("实例不存在或无权限")
+	}
+
+	var port provider.Port
+	if err := global.APP_DB.Where("id = ? AND instance_id = ?", portID, instanceID).First(&port).Error; err != nil {
+		return nil, errors.New("端口映射不存在")
+	}
+	if port.Status == "pending" || port.Status == "deleting" {
+		return nil, errors.New("端口映射正在处理中，请稍后再试")
+	}
+
+	return s.DeletePortMappingWithTask(port.ID)
+}
+
 // DeletePortMappingWithTask 删除端口映射（通过任务系统异步执行，仅支持删除手动添加的端口）
 // 返回任务数据（由调用者创建和启动任务）
 func (s *PortMappingService) DeletePortMappingWithTask(id uint) (*admin.DeletePortMappingTaskRequest, error) {
@@ -319,6 +435,12 @@ func (s *PortMappingService) CreateDefaultPortMappings(instanceID uint, provider
 		defaultPortCount = availablePortCount
 	}
 
+	// nftables后端只管理映射方法为nftables的端口记录，其他方式沿用默认值
+	mappingMethod := ""
+	if usesNftables(&providerInfo) {
+		mappingMethod = string(constant.PortMappingMethodNftables)
+	}
+
 	// 使用事务确保端口分配的原子性，防止并发创建时的端口冲突
 	return global.APP_DB.Transaction(func(tx *gorm.DB) error {
 		var createdPorts []provider.Port
@@ -342,17 +464,18 @@ func (s *PortMappingService) CreateDefaultPortMappings(instanceID uint, provider
 		}
 
 		sshPort := provider.Port{
-			InstanceID:  instanceID,
-			ProviderID:  providerID,
-			HostPort:    sshHostPort,
-			GuestPort:   22,     // SSH端口固定为22
-			Protocol:    "both", // SSH 使用 TCP/UDP 通用协议
-			Description: "SSH",
-			Status:      "active",
-			IsSSH:       true,
-			IsAutomatic: true,
-			PortType:    "range_mapped", // 标记为区间映射
-			IPv6Enabled: providerInfo.NetworkType == "nat_ipv4_ipv6" || providerInfo.NetworkType == "dedicated_ipv4_ipv6" || providerInfo.NetworkType == "ipv6_only",
+			InstanceID:    instanceID,
+			ProviderID:    providerID,
+			HostPort:      sshHostPort,
+			GuestPort:     22,     // SSH端口固定为22
+			Protocol:      "both", // SSH 使用 TCP/UDP 通用协议
+			Description:   "SSH",
+			Status:        "active",
+			IsSSH:         true,
+			IsAutomatic:   true,
+			PortType:      "range_mapped", // 标记为区间映射
+			IPv6Enabled:   providerInfo.NetworkType == "nat_ipv4_ipv6" || providerInfo.NetworkType == "dedicated_ipv4_ipv6" || providerInfo.NetworkType == "ipv6_only",
+			MappingMethod: mappingMethod,
 		}
 
 		if err := tx.Create(&sshPort).Error; err != nil {
@@ -388,17 +511,18 @@ func (s *PortMappingService) CreateDefaultPortMappings(instanceID uint, provider
 
 			// 创建1:1端口映射记录（内外端口完全相同）
 			portRecord := provider.Port{
-				InstanceID:  instanceID,
-				ProviderID:  providerID,
-				HostPort:    port,
-				GuestPort:   port,   // 内外端口完全相同
-				Protocol:    "both", // 区间映射使用 TCP/UDP 通用协议
-				Description: fmt.Sprintf("端口%d", port),
-				Status:      "active",
-				IsSSH:       false,
-				IsAutomatic: true,
-				PortType:    "range_mapped", // 标记为区间映射
-				IPv6Enabled: providerInfo.NetworkType == "nat_ipv4_ipv6" || providerInfo.NetworkType == "dedicated_ipv4_ipv6" || providerInfo.NetworkType == "ipv6_only",
+				InstanceID:    instanceID,
+				ProviderID:    providerID,
+				HostPort:      port,
+				GuestPort:     port,   // 内外端口完全相同
+				Protocol:      "both", // 区间映射使用 TCP/UDP 通用协议
+				Description:   fmt.Sprintf("端口%d", port),
+				Status:        "active",
+				IsSSH:         false,
+				IsAutomatic:   true,
+				PortType:      "range_mapped", // 标记为区间映射
+				IPv6Enabled:   providerInfo.NetworkType == "nat_ipv4_ipv6" || providerInfo.NetworkType == "dedicated_ipv4_ipv6" || providerInfo.NetworkType == "ipv6_only",
+				MappingMethod: mappingMethod,
 			}
 
 			if err := tx.Create(&portRecord).Error; err != nil {
@@ -419,6 +543,50 @@ func (s *PortMappingService) CreateDefaultPortMappings(instanceID uint, provider
 	})
 }
 
+// usesNftables Provider是否通过nftables后端管理IPv4端口映射，Docker端口由容器自身发布
+func usesNftables(providerInfo *provider.Provider) bool {
+	return providerInfo.IPv4PortMappingMethod == string(constant.PortMappingMethodNftables) && providerInfo.Type != "docker"
+}
+
+// ApplyNftablesMappings Provider使用nftables端口映射时，按数据库在主机上重新下发整张表
+// 实例创建时Provider层不配置nftables映射，需要在实例内网IP写入数据库后调用
+func (s *PortMappingService) ApplyNftablesMappings(ctx context.Context, providerID uint) error {
+	var providerInfo provider.Provider
+	if err := global.APP_DB.First(&providerInfo, providerID).Error; err != nil {
+		return fmt.Errorf("Provider不存在")
+	}
+	if !usesNftables(&providerInfo) {
+		return nil
+	}
+
+	backend, err := portmapping.GetProvider(string(constant.PortMappingMethodNftables))
+	if err != nil {
+		return err
+	}
+	reconciler, ok := backend.(portmapping.Reconcilable)
+	if !ok {
+		return fmt.Errorf("nftables端口映射后端不支持恢复映射")
+	}
+
+	var ports []provider.Port
+	if err := global.APP_DB.Where("provider_id = ? AND mapping_method = ? AND status = ?",
+		providerID, string(constant.PortMappingMethodNftables), "active").Find(&ports).Error; err != nil {
+		return fmt.Errorf("获取端口映射失败: %v", err)
+	}
+	return reconciler.RestoreMappings(ctx, providerID, ports)
+}
+
+// RefreshNftablesMappings 端口记录变更后按数据库重新下发相关Provider的nftables表，失败只记录日志，可通过端口对账修复
+func (s *PortMappingService) RefreshNftablesMappings(ctx context.Context, providerIDs ...uint) {
+	for _, providerID := range providerIDs {
+		if err := s.ApplyNftablesMappings(ctx, providerID); err != nil {
+			global.APP_LOG.Warn("下发nftables端口映射失败",
+				zap.Uint("providerId", providerID),
+				zap.Error(err))
+		}
+	}
+}
+
 // allocateHostPortInTx 在事务中分配主机端口 - 防止并发冲突
 func (s *PortMappingService) allocateHostPortInTx(tx *gorm.DB, providerID uint, rangeStart, rangeEnd int) (int, error) {
 	// 获取Provider信息（带锁）
@@ -923,6 +1091,36 @@ func (s *PortMappingService) DeleteInstancePortMappingsInTx(tx *gorm.DB, instanc
 	return nil
 }
 
+// DeleteProviderPortMappingsInTx 在事务中删除实例在指定Provider上的端口映射并释放端口，用于实例迁移
+func (s *PortMappingService) DeleteProviderPortMappingsInTx(tx *gorm.DB, instanceID, providerID uint) error {
+	var ports []provider.Port
+	if err := tx.Where("instance_id = ? AND provider_id = ?", instanceID, providerID).Find(&ports).Error; err != nil {
+		return fmt.Errorf("获取端口映射失败: %v", err)
+	}
+	if len(ports) == 0 {
+		return nil
+	}
+
+	if err := tx.Where("instance_id = ? AND provider_id = ?", instanceID, providerID).Delete(&provider.Port{}).Error; err != nil {
+		return fmt.Errorf("删除端口映射失败: %v", err)
+	}
+
+	releasedPorts := make([]int, 0, len(ports))
+	for _, port := range ports {
+		releasedPorts = append(releasedPorts, port.HostPort)
+	}
+	if err := s.optimizeNextAvailablePortInTx(tx, providerID, releasedPorts); err != nil {
+		global.APP_LOG.Warn("优化Provider端口重用失败", zap.Uint("providerId", providerID), zap.Error(err))
+	}
+
+	global.APP_LOG.Info("删除实例在Provider上的端口映射",
+		zap.Uint("instance_id", instanceID),
+		zap.Uint("provider_id", providerID),
+		zap.Int("releasedPortCount", len(ports)))
+
+	return nil
+}
+
 // optimizeNextAvailablePortInTx 在事务中优化Provider的NextAvailablePort以促进端口重用
 func (s *PortMappingService) optimizeNextAvailablePortInTx(tx *gorm.DB, providerID uint, releasedPorts []int) error {
 	// 获取Provider当前配置
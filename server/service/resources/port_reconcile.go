@@ -0,0 +1,399 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"oneclickvirt/constant"
+	"oneclickvirt/global"
+	"oneclickvirt/model/admin"
+	"oneclickvirt/model/provider"
+	"oneclickvirt/provider/portmapping"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PortReconcileService 端口映射漂移检测与修复
+// 读取各Provider主机上实际生效的端口映射，与数据库中的端口记录比对，
+// 找出缺失（missing）、多余（orphaned）和转发目标不一致（conflicting）的映射
+type PortReconcileService struct{}
+
+var (
+	// reconcileMu 同一时间只允许一次检测，避免定时任务与手动修复同时改动主机规则
+	reconcileMu sync.Mutex
+
+	// lastReports 每个Provider最近一次的检测报告
+	lastReports   = make(map[uint]admin.PortReconcileReport)
+	lastReportsMu sync.RWMutex
+)
+
+// expectedMapping 数据库中按协议展开后的单条映射
+type expectedMapping struct {
+	port     provider.Port
+	protocol string
+	name     string
+	ip       string
+}
+
+// ReconcileProvider 检测单个Provider的端口映射漂移，autoFix为false时只生成报告不改动主机
+func (s *PortReconcileService) ReconcileProvider(ctx context.Context, providerID uint, autoFix bool) (*admin.PortReconcileReport, error) {
+	var providerInfo provider.Provider
+	if err := global.APP_DB.First(&providerInfo, providerID).Error; err != nil {
+		return nil, fmt.Errorf("Provider不存在")
+	}
+
+	reconcileMu.Lock()
+	report := s.reconcile(ctx, &providerInfo, autoFix)
+	reconcileMu.Unlock()
+
+	s.saveReport(report)
+	return &report, nil
+}
+
+// ReconcileAll 检测所有可用Provider的端口映射漂移
+func (s *PortReconcileService) ReconcileAll(ctx context.Context, autoFix bool) []admin.PortReconcileReport {
+	var providers []provider.Provider
+	if err := global.APP_DB.Where("status IN ?", []string{"active", "partial"}).Find(&providers).Error; err != nil {
+		global.APP_LOG.Error("获取Provider列表失败", zap.Error(err))
+		return nil
+	}
+
+	reconcileMu.Lock()
+	defer reconcileMu.Unlock()
+
+	reports := make([]admin.PortReconcileReport, 0, len(providers))
+	for i := range providers {
+		select {
+		case <-ctx.Done():
+			return reports
+		default:
+		}
+		report := s.reconcile(ctx, &providers[i], autoFix)
+		s.saveReport(report)
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// GetLastReports 获取最近一次的检测报告，providerID为0时返回全部
+func (s *PortReconcileService) GetLastReports(providerID uint) []admin.PortReconcileReport {
+	lastReportsMu.RLock()
+	defer lastReportsMu.RUnlock()
+
+	reports := make([]admin.PortReconcileReport, 0, len(lastReports))
+	for id, report := range lastReports {
+		if providerID == 0 || id == providerID {
+			reports = append(reports, report)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ProviderID < reports[j].ProviderID })
+	return reports
+}
+
+func (s *PortReconcileService) saveReport(report admin.PortReconcileReport) {
+	lastReportsMu.Lock()
+	lastReports[report.ProviderID] = report
+	lastReportsMu.Unlock()
+}
+
+// reconcileBackend 确定读取实际映射使用的后端，返回空字符串表示不支持
+func reconcileBackend(providerInfo *provider.Provider) string {
+	method := providerInfo.IPv4PortMappingMethod
+	switch {
+	case providerInfo.Type == "docker":
+		// Docker端口由容器自身发布，不经过可检测的规则
+		return ""
+	case method == string(constant.PortMappingMethodNftables):
+		return "nftables"
+	case providerInfo.Type == "proxmox" || method == string(constant.PortMappingMethodIptables):
+		return "iptables"
+	case providerInfo.Type == "lxd" || providerInfo.Type == "incus":
+		return providerInfo.Type
+	}
+	return ""
+}
+
+// reconcile 执行一次检测，调用方需持有reconcileMu
+func (s *PortReconcileService) reconcile(ctx context.Context, providerInfo *provider.Provider, autoFix bool) admin.PortReconcileReport {
+	report := admin.PortReconcileReport{
+		ProviderID:   providerInfo.ID,
+		ProviderName: providerInfo.Name,
+		Backend:      reconcileBackend(providerInfo),
+		AutoFix:      autoFix,
+		CheckedAt:    time.Now(),
+		Items:        []admin.PortDriftItem{},
+	}
+	if report.Backend == "" {
+		return report
+	}
+
+	backend, err := portmapping.GetProvider(report.Backend)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	reconciler, ok := backend.(portmapping.Reconcilable)
+	if !ok {
+		return report
+	}
+	report.Supported = true
+
+	expected, inflight, err := s.loadExpected(providerInfo.ID)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	live, err := reconciler.ListLiveMappings(ctx, providerInfo.ID)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.ExpectedCount = len(expected)
+	report.LiveCount = len(live)
+
+	liveByKey := make(map[string][]*portmapping.LiveMapping)
+	for _, m := range live {
+		key := fmt.Sprintf("%s/%d", m.Protocol, m.HostPort)
+		liveByKey[key] = append(liveByKey[key], m)
+	}
+
+	var toRestore []provider.Port
+	var toRemove []*portmapping.LiveMapping
+	var removeItems []int
+	validRefs := make(map[string]bool)
+
+	keys := make([]string, 0, len(expected))
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		exp := expected[key]
+		entries := liveByKey[key]
+		delete(liveByKey, key)
+
+		item := admin.PortDriftItem{
+			PortID:       exp.port.ID,
+			InstanceID:   exp.port.InstanceID,
+			InstanceName: exp.name,
+			Protocol:     exp.protocol,
+			HostPort:     exp.port.HostPort,
+			GuestPort:    exp.port.GuestPort,
+		}
+
+		if len(entries) == 0 {
+			item.Type = admin.PortDriftMissing
+			item.Detail = "主机上缺少该端口映射"
+		} else {
+			var matched *portmapping.LiveMapping
+			var mismatched []*portmapping.LiveMapping
+			for _, m := range entries {
+				if matched == nil && liveMatches(m, exp) {
+					matched = m
+					continue
+				}
+				mismatched = append(mismatched, m)
+			}
+			if matched != nil && matched.Ref != "" {
+				validRefs[liveRefKey(matched)] = true
+			}
+			if len(mismatched) == 0 {
+				continue
+			}
+
+			item.Type = admin.PortDriftConflicting
+			item.LiveTarget = liveTarget(mismatched[0])
+			if matched != nil {
+				item.Detail = "主机上存在多条相同端口的映射"
+			} else {
+				item.Detail = "主机上的转发目标与数据库不一致"
+			}
+			for _, m := range mismatched {
+				toRemove = append(toRemove, m)
+				removeItems = append(removeItems, len(report.Items))
+			}
+			if matched != nil {
+				// 已有正确映射，删除多余规则即可
+				report.Items = append(report.Items, item)
+				report.ConflictingCount++
+				continue
+			}
+		}
+
+		if item.Type == admin.PortDriftMissing {
+			report.MissingCount++
+		} else {
+			report.ConflictingCount++
+		}
+		port := exp.port
+		port.Protocol = exp.protocol
+		toRestore = append(toRestore, port)
+		report.Items = append(report.Items, item)
+	}
+
+	// 剩余的实际映射在数据库中没有对应记录，只统计Provider端口范围内的，范围外的可能是管理员手动配置
+	for key, entries := range liveByKey {
+		if inflight[key] {
+			continue
+		}
+		for _, m := range entries {
+			if m.HostPort < providerInfo.PortRangeStart || m.HostPort > providerInfo.PortRangeEnd {
+				continue
+			}
+			toRemove = append(toRemove, m)
+			removeItems = append(removeItems, len(report.Items))
+			report.Items = append(report.Items, admin.PortDriftItem{
+				Type:         admin.PortDriftOrphaned,
+				InstanceID:   m.InstanceID,
+				InstanceName: m.InstanceName,
+				Protocol:     m.Protocol,
+				HostPort:     m.HostPort,
+				LiveTarget:   liveTarget(m),
+				Detail:       "数据库中没有对应的端口记录",
+			})
+			report.OrphanedCount++
+		}
+	}
+
+	if autoFix && len(report.Items) > 0 {
+		s.fix(ctx, reconciler, providerInfo.ID, &report, toRemove, removeItems, toRestore, validRefs)
+	}
+
+	if len(report.Items) > 0 {
+		global.APP_LOG.Warn("检测到端口映射漂移",
+			zap.Uint("providerID", providerInfo.ID),
+			zap.String("backend", report.Backend),
+			zap.Int("missing", report.MissingCount),
+			zap.Int("orphaned", report.OrphanedCount),
+			zap.Int("conflicting", report.ConflictingCount),
+			zap.Bool("autoFix", autoFix))
+	}
+	return report
+}
+
+// fix 先删除多余和冲突的映射，再补建缺失的映射
+func (s *PortReconcileService) fix(ctx context.Context, reconciler portmapping.Reconcilable, providerID uint, report *admin.PortReconcileReport,
+	toRemove []*portmapping.LiveMapping, removeItems []int, toRestore []provider.Port, validRefs map[string]bool) {
+
+	var removable []*portmapping.LiveMapping
+	var removableItems []int
+	for i, m := range toRemove {
+		if m.Ref != "" && validRefs[liveRefKey(m)] {
+			// 区间映射的多个端口共用同一规则，删除会影响正确的映射
+			report.Items[removeItems[i]].FixError = "与有效映射共用同一规则，需手动处理"
+			continue
+		}
+		removable = append(removable, m)
+		removableItems = append(removableItems, removeItems[i])
+	}
+
+	var removeErr, restoreErr error
+	if len(removable) > 0 {
+		removeErr = reconciler.RemoveLiveMappings(ctx, providerID, removable)
+	}
+	for _, idx := range removableItems {
+		if removeErr != nil {
+			report.Items[idx].FixError = removeErr.Error()
+		} else if report.Items[idx].Type == admin.PortDriftOrphaned {
+			report.Items[idx].Fixed = true
+		}
+	}
+
+	if len(toRestore) > 0 {
+		restoreErr = reconciler.RestoreMappings(ctx, providerID, toRestore)
+	}
+	for i := range report.Items {
+		item := &report.Items[i]
+		if item.Type == admin.PortDriftOrphaned || item.FixError != "" {
+			continue
+		}
+		if restoreErr != nil {
+			item.FixError = restoreErr.Error()
+		} else {
+			item.Fixed = true
+		}
+	}
+
+	if removeErr != nil || restoreErr != nil {
+		global.APP_LOG.Error("端口映射漂移修复失败",
+			zap.Uint("providerID", providerID),
+			zap.NamedError("removeError", removeErr),
+			zap.NamedError("restoreError", restoreErr))
+	}
+}
+
+// loadExpected 加载数据库中生效的端口记录并按协议展开，同时返回处理中端口的键，避免误判为多余
+func (s *PortReconcileService) loadExpected(providerID uint) (map[string]*expectedMapping, map[string]bool, error) {
+	var ports []provider.Port
+	if err := global.APP_DB.Where("provider_id = ?", providerID).Find(&ports).Error; err != nil {
+		return nil, nil, fmt.Errorf("获取端口记录失败: %v", err)
+	}
+
+	instanceIDs := make([]uint, 0, len(ports))
+	for _, port := range ports {
+		instanceIDs = append(instanceIDs, port.InstanceID)
+	}
+	var instances []provider.Instance
+	if len(instanceIDs) > 0 {
+		if err := global.APP_DB.Where("id IN ?", instanceIDs).Find(&instances).Error; err != nil {
+			return nil, nil, fmt.Errorf("获取实例信息失败: %v", err)
+		}
+	}
+	instanceMap := make(map[uint]provider.Instance, len(instances))
+	for _, instance := range instances {
+		instanceMap[instance.ID] = instance
+	}
+
+	expected := make(map[string]*expectedMapping)
+	inflight := make(map[string]bool)
+	for _, port := range ports {
+		for _, proto := range portmapping.ExpandProtocol(port.Protocol) {
+			key := fmt.Sprintf("%s/%d", proto, port.HostPort)
+			if port.Status != "active" {
+				inflight[key] = true
+				continue
+			}
+			instance := instanceMap[port.InstanceID]
+			expected[key] = &expectedMapping{
+				port:     port,
+				protocol: proto,
+				name:     instance.Name,
+				ip:       portmapping.CleanIP(instance.PrivateIP),
+			}
+		}
+	}
+	return expected, inflight, nil
+}
+
+// liveMatches 判断实际映射是否与数据库记录一致，后端无法确定的字段不参与比较
+func liveMatches(m *portmapping.LiveMapping, exp *expectedMapping) bool {
+	if m.GuestPort != exp.port.GuestPort {
+		return false
+	}
+	if m.InstanceID != 0 && m.InstanceID != exp.port.InstanceID {
+		return false
+	}
+	if m.InstanceName != "" && m.InstanceName != exp.name {
+		return false
+	}
+	// proxy设备连接0.0.0.0时转发到设备所属实例
+	if m.TargetIP != "0.0.0.0" && m.TargetIP != exp.ip {
+		return false
+	}
+	return true
+}
+
+// liveRefKey 实际映射对应的规则标识，同一规则展开的多个端口标识相同
+func liveRefKey(m *portmapping.LiveMapping) string {
+	return m.InstanceName + "/" + m.Ref
+}
+
+func liveTarget(m *portmapping.LiveMapping) string {
+	if m.InstanceName != "" {
+		return fmt.Sprintf("%s(%s):%d", m.InstanceName, m.TargetIP, m.GuestPort)
+	}
+	return fmt.Sprintf("%s:%d", m.TargetIP, m.GuestPort)
+}
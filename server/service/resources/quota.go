@@ -35,6 +35,9 @@ type ResourceRequest struct {
 	Bandwidth    int // 带宽字段
 	InstanceType string
 	ProviderID   uint //  Provider ID 用于节点级限制检查
+	// ExcludeInstanceID 调整实例规格时传入，该实例的资源和数量不计入当前使用量，
+	// 此时CPU、Memory等字段为调整后的规格
+	ExcludeInstanceID uint
 }
 
 // QuotaCheckResult 配额检查结果
@@ -154,6 +157,26 @@ func (s *QuotaService) validateInTransaction(tx *gorm.DB, req ResourceRequest) (
 		}
 	}
 
+	// 调整规格时扣除实例自身的占用，相当于以新规格替换原实例
+	if req.ExcludeInstanceID > 0 {
+		var excluded provider.Instance
+		err := tx.Where("id = ? AND user_id = ? AND status NOT IN (?)", req.ExcludeInstanceID, req.UserID, []string{"deleting", "deleted"}).
+			First(&excluded).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("获取实例信息失败: %v", err)
+		}
+		if err == nil {
+			currentInstances--
+			currentResources.CPU -= excluded.CPU
+			currentResources.Memory -= excluded.Memory
+			currentResources.Disk -= excluded.Disk
+			currentResources.Bandwidth -= excluded.Bandwidth
+			if excluded.ProviderID == req.ProviderID {
+				currentProviderInstances--
+			}
+		}
+	}
+
 	// 计算请求的资源
 	requestedResources := ResourceUsage{
 		CPU:       req.CPU,
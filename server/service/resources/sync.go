@@ -119,6 +119,42 @@ func (s *QuotaSyncService) extractLevelLimits(configMap map[string]interface{})
 				}
 			}
 
+			// 解析 MaxSnapshots
+			if maxSnapshots, exists := limitMap["maxSnapshots"]; exists {
+				if snapshots, ok := maxSnapshots.(float64); ok {
+					levelLimit.MaxSnapshots = int(snapshots)
+				} else if snapshots, ok := maxSnapshots.(int); ok {
+					levelLimit.MaxSnapshots = snapshots
+				}
+			}
+
+			// 解析 MaxBackups
+			if maxBackups, exists := limitMap["maxBackups"]; exists {
+				if backups, ok := maxBackups.(float64); ok {
+					levelLimit.MaxBackups = int(backups)
+				} else if backups, ok := maxBackups.(int); ok {
+					levelLimit.MaxBackups = backups
+				}
+			}
+
+			// 解析 MaxPorts
+			if maxPorts, exists := limitMap["maxPorts"]; exists {
+				if ports, ok := maxPorts.(float64); ok {
+					levelLimit.MaxPorts = int(ports)
+				} else if ports, ok := maxPorts.(int); ok {
+					levelLimit.MaxPorts = ports
+				}
+			}
+
+			// 解析 MaxDomains
+			if maxDomains, exists := limitMap["maxDomains"]; exists {
+				if domains, ok := maxDomains.(float64); ok {
+					levelLimit.MaxDomains = int(domains)
+				} else if domains, ok := maxDomains.(int); ok {
+					levelLimit.MaxDomains = domains
+				}
+			}
+
 			levelLimits[level] = levelLimit
 		}
 	}
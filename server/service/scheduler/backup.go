@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/model/provider"
+	userInstance "oneclickvirt/service/user/instance"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+)
+
+// runScheduledBackups 触发到期的定时备份计划
+func (s *SchedulerService) runScheduledBackups() {
+	// 检查数据库是否已初始化
+	if global.APP_DB == nil {
+		global.APP_LOG.Debug("数据库未初始化，跳过定时备份检查")
+		return
+	}
+
+	now := time.Now()
+	var schedules []provider.BackupSchedule
+	if err := global.APP_DB.Where("enabled = ? AND next_run_at <= ?", true, now).Find(&schedules).Error; err != nil {
+		global.APP_LOG.Error("获取到期的定时备份计划失败", zap.Error(err))
+		return
+	}
+
+	instanceService := userInstance.NewService()
+	for i := range schedules {
+		schedule := &schedules[i]
+
+		updates := map[string]interface{}{
+			"last_run_at": now,
+			"last_error":  "",
+		}
+
+		cron, err := utils.ParseCron(schedule.CronExpr)
+		if err != nil {
+			// 表达式在保存时已校验，这里仅防御数据被手动修改的情况
+			updates["enabled"] = false
+			updates["last_error"] = "Cron表达式不合法，计划已停用"
+			global.APP_DB.Model(schedule).Updates(updates)
+			continue
+		}
+		updates["next_run_at"] = cron.Next(now)
+
+		result, err := instanceService.CreateScheduledBackup(schedule)
+		if err != nil {
+			updates["last_error"] = utils.TruncateString(err.Error(), 250)
+			global.APP_LOG.Warn("定时备份触发失败",
+				zap.Uint("scheduleId", schedule.ID),
+				zap.Uint("instanceId", schedule.InstanceID),
+				zap.Error(err))
+		} else {
+			global.APP_LOG.Info("定时备份已触发",
+				zap.Uint("scheduleId", schedule.ID),
+				zap.Uint("instanceId", schedule.InstanceID),
+				zap.Uint("backupId", result.BackupID),
+				zap.Uint("taskId", result.TaskID))
+		}
+
+		if err := global.APP_DB.Model(schedule).Updates(updates).Error; err != nil {
+			global.APP_LOG.Error("更新定时备份计划失败",
+				zap.Uint("scheduleId", schedule.ID),
+				zap.Error(err))
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"oneclickvirt/global"
 	providerModel "oneclickvirt/model/provider"
 	adminProviderService "oneclickvirt/service/admin/provider"
+	"oneclickvirt/service/webhook"
 
 	"go.uber.org/zap"
 )
@@ -149,6 +150,18 @@ func (s *ProviderHealthSchedulerService) checkSingleProviderHealth(provider prov
 			zap.String("old_api", oldAPIStatus),
 			zap.String("new_api", updatedProvider.APIStatus))
 
+		webhook.Publish(webhook.EventProviderStatusChanged, map[string]interface{}{
+			"providerId":   provider.ID,
+			"providerName": provider.Name,
+			"providerType": provider.Type,
+			"oldStatus":    oldStatus,
+			"newStatus":    updatedProvider.Status,
+			"oldSshStatus": oldSSHStatus,
+			"newSshStatus": updatedProvider.SSHStatus,
+			"oldApiStatus": oldAPIStatus,
+			"newApiStatus": updatedProvider.APIStatus,
+		})
+
 		// 根据Provider健康状态更新allow_claim字段，控制是否允许申领新实例
 		// 重要原则：
 		// 1. 健康检查仅影响新实例的申领（allow_claim字段）
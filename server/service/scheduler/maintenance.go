@@ -8,7 +8,10 @@ import (
 	adminModel "oneclickvirt/model/admin"
 	"oneclickvirt/model/auth"
 	"oneclickvirt/model/provider"
+	auth2 "oneclickvirt/service/auth"
+	"oneclickvirt/service/notification"
 	"oneclickvirt/service/system"
+	"oneclickvirt/service/webhook"
 	"oneclickvirt/utils"
 
 	"go.uber.org/zap"
@@ -37,6 +40,9 @@ func (s *SchedulerService) performMaintenance() {
 	// 清理过期的JWT黑名单
 	s.cleanupExpiredJWTBlacklist()
 
+	// 清理过期的登录会话
+	auth2.CleanupExpiredSessions()
+
 	// 清理过期的Provider配置
 	s.cleanupExpiredProviders()
 
@@ -45,6 +51,15 @@ func (s *SchedulerService) performMaintenance() {
 
 	// 清理旧的任务记录（可选）
 	s.cleanupOldTasks()
+
+	// 清理过期的Webhook投递记录
+	webhook.CleanupOldDeliveries()
+
+	// 提醒用户即将到期的实例
+	notification.CheckExpiringInstances()
+
+	// 清理过期的站内通知
+	notification.CleanupOldNotifications()
 }
 
 // cleanupExpiredInstances 清理过期实例
@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"sync/atomic"
+	"time"
+
+	"oneclickvirt/global"
+	"oneclickvirt/service/resources"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// portReconcileRunning 上一轮检测未结束时跳过本轮，SSH读取规则可能较慢
+	portReconcileRunning atomic.Bool
+	portReconcileLastRun time.Time
+)
+
+// runPortReconcile 按配置的间隔检测所有Provider的端口映射漂移
+func (s *SchedulerService) runPortReconcile() {
+	// 检查数据库是否已初始化
+	if global.APP_DB == nil {
+		return
+	}
+
+	cfg := global.APP_CONFIG.PortReconcile
+	if !cfg.Enabled {
+		return
+	}
+	interval := time.Duration(cfg.Interval) * time.Minute
+	if interval <= 0 {
+		interval = 30 * time.Minute
+	}
+	if time.Since(portReconcileLastRun) < interval {
+		return
+	}
+	if !portReconcileRunning.CompareAndSwap(false, true) {
+		return
+	}
+	portReconcileLastRun = time.Now()
+
+	go func() {
+		defer portReconcileRunning.Store(false)
+
+		reconcileService := resources.PortReconcileService{}
+		reports := reconcileService.ReconcileAll(s.ctx, cfg.AutoFix)
+
+		var drifted, failed int
+		for _, report := range reports {
+			if report.Error != "" {
+				failed++
+			} else if len(report.Items) > 0 {
+				drifted++
+			}
+		}
+		global.APP_LOG.Info("端口映射漂移检测完成",
+			zap.Int("providers", len(reports)),
+			zap.Int("drifted", drifted),
+			zap.Int("failed", failed),
+			zap.Bool("autoFix", cfg.AutoFix))
+	}()
+}
@@ -10,6 +10,7 @@ import (
 	adminModel "oneclickvirt/model/admin"
 	dashboardModel "oneclickvirt/model/dashboard"
 	"oneclickvirt/model/provider"
+	"oneclickvirt/service/webhook"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -111,11 +112,14 @@ func (s *SchedulerService) runTaskScheduler() {
 	defer s.wg.Done()
 
 	// 创建定时器，错开执行时间避免并发峰值
-	taskTicker := time.NewTicker(5 * time.Second)         // 任务处理
-	cleanupTicker := time.NewTicker(1 * time.Minute)      // 超时清理
-	maintenanceTicker := time.NewTicker(10 * time.Minute) // 系统维护
-	trafficTicker := time.NewTicker(2 * time.Hour)        // 流量同步
-	trafficResetTicker := time.NewTicker(3 * time.Hour)   // 流量重置检查
+	taskTicker := time.NewTicker(5 * time.Second)          // 任务处理
+	cleanupTicker := time.NewTicker(1 * time.Minute)       // 超时清理
+	maintenanceTicker := time.NewTicker(10 * time.Minute)  // 系统维护
+	trafficTicker := time.NewTicker(2 * time.Hour)         // 流量同步
+	trafficResetTicker := time.NewTicker(3 * time.Hour)    // 流量重置检查
+	backupTicker := time.NewTicker(1 * time.Minute)        // 定时备份
+	webhookTicker := time.NewTicker(30 * time.Second)      // Webhook重试
+	portReconcileTicker := time.NewTicker(1 * time.Minute) // 端口映射漂移检测，实际间隔由配置决定
 
 	defer func() {
 		taskTicker.Stop()
@@ -123,6 +127,9 @@ func (s *SchedulerService) runTaskScheduler() {
 		maintenanceTicker.Stop()
 		trafficTicker.Stop()
 		trafficResetTicker.Stop()
+		backupTicker.Stop()
+		webhookTicker.Stop()
+		portReconcileTicker.Stop()
 	}()
 
 	global.APP_LOG.Info("Task scheduler main loop started")
@@ -152,6 +159,16 @@ func (s *SchedulerService) runTaskScheduler() {
 
 		case <-trafficResetTicker.C:
 			s.checkMonthlyTrafficReset()
+
+		case <-backupTicker.C:
+			s.runScheduledBackups()
+
+		case <-webhookTicker.C:
+			// 投递请求可能较慢，放到独立goroutine中避免阻塞调度循环
+			go webhook.ProcessPendingDeliveries()
+
+		case <-portReconcileTicker.C:
+			s.runPortReconcile()
 		}
 	}
 }
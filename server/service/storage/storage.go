@@ -27,6 +27,7 @@ func GetDefaultStorageConfig() *system.StorageConfig {
 			system.CacheDir,
 			system.TempDir,
 			system.AvatarsDir,
+			system.BackupsDir,
 		},
 	}
 }
@@ -128,6 +129,32 @@ func (s *StorageService) GetAvatarsPath() string {
 	return s.GetStoragePath(system.AvatarsDir)
 }
 
+// GetBackupsPath 获取实例备份文件存储路径
+func (s *StorageService) GetBackupsPath() string {
+	return s.GetStoragePath(system.BackupsDir)
+}
+
+// PrepareBackupDir 为单个备份创建独立的存储目录
+func (s *StorageService) PrepareBackupDir(backupUUID string) (string, error) {
+	dir := filepath.Join(s.GetBackupsPath(), backupUUID)
+	if err := s.ensureDir(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// RemoveBackupDir 删除单个备份的存储目录及其中的所有文件
+func (s *StorageService) RemoveBackupDir(backupUUID string) error {
+	if backupUUID == "" {
+		return nil
+	}
+	dir := filepath.Join(s.GetBackupsPath(), backupUUID)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("删除备份目录 %s 失败: %w", dir, err)
+	}
+	return nil
+}
+
 // CleanupTempFiles 清理临时文件
 func (s *StorageService) CleanupTempFiles() error {
 	tempPath := s.GetTempPath()
@@ -6,6 +6,7 @@ import (
 	"oneclickvirt/global"
 	providerModel "oneclickvirt/model/provider"
 	"oneclickvirt/service/resources"
+	"oneclickvirt/service/webhook"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -164,7 +165,7 @@ func (s *InstanceCleanupService) CleanupExpiredInstances() error {
 
 // cleanupSingleExpiredInstance 清理单个过期实例
 func (s *InstanceCleanupService) cleanupSingleExpiredInstance(instance *providerModel.Instance) error {
-	return global.APP_DB.Transaction(func(tx *gorm.DB) error {
+	err := global.APP_DB.Transaction(func(tx *gorm.DB) error {
 		// 1. 标记实例为删除中
 		if err := tx.Model(instance).Updates(map[string]interface{}{
 			"status":     "deleting",
@@ -200,6 +201,12 @@ func (s *InstanceCleanupService) cleanupSingleExpiredInstance(instance *provider
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	webhook.PublishInstance(webhook.EventInstanceExpired, instance, nil)
+	return nil
 }
 
 // GetInstanceCleanupService 获取实例清理服务实例
@@ -0,0 +1,454 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	providerModel "oneclickvirt/model/provider"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/provider"
+	provider2 "oneclickvirt/service/provider"
+	"oneclickvirt/service/resources"
+	"oneclickvirt/service/storage"
+	"oneclickvirt/service/vnstat"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// backupHeartbeatInterval 备份任务心跳间隔
+// 导出和传输可能超过调度器的30分钟超时判定，需要定期刷新任务更新时间
+const backupHeartbeatInterval = 5 * time.Minute
+
+// startBackupHeartbeat 启动备份任务心跳，返回停止函数
+func (s *TaskService) startBackupHeartbeat(taskID uint, progress int, message string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(backupHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.updateTaskProgress(taskID, progress, message)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// loadBackupTaskContext 解析备份任务数据并加载备份、实例记录
+func (s *TaskService) loadBackupTaskContext(task *adminModel.Task) (*providerModel.InstanceBackup, *providerModel.Instance, error) {
+	var taskReq adminModel.BackupTaskRequest
+	if err := json.Unmarshal([]byte(task.TaskData), &taskReq); err != nil {
+		return nil, nil, fmt.Errorf("解析任务数据失败: %v", err)
+	}
+
+	var backup providerModel.InstanceBackup
+	if err := global.APP_DB.First(&backup, taskReq.BackupID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("备份不存在")
+		}
+		return nil, nil, fmt.Errorf("获取备份信息失败: %v", err)
+	}
+
+	var instance providerModel.Instance
+	if err := global.APP_DB.First(&instance, taskReq.InstanceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("实例不存在")
+		}
+		return nil, nil, fmt.Errorf("获取实例信息失败: %v", err)
+	}
+
+	// 验证所有权
+	if instance.UserID != task.UserID || backup.UserID != task.UserID {
+		return nil, nil, fmt.Errorf("无权限操作此实例")
+	}
+
+	return &backup, &instance, nil
+}
+
+// executeCreateBackupTask 执行创建备份任务
+func (s *TaskService) executeCreateBackupTask(ctx context.Context, task *adminModel.Task) error {
+	// 初始化进度
+	s.updateTaskProgress(task.ID, 10, "正在解析任务数据...")
+
+	backup, instance, err := s.loadBackupTaskContext(task)
+	if err != nil {
+		return err
+	}
+
+	storageService := storage.GetStorageService()
+	backupDir, err := storageService.PrepareBackupDir(backup.UUID)
+	if err != nil {
+		s.markBackupFailed(backup, err)
+		return fmt.Errorf("准备备份目录失败: %v", err)
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 20, "正在导出实例并传输备份文件...")
+	stopHeartbeat := s.startBackupHeartbeat(task.ID, 20, "正在导出实例并传输备份文件...")
+
+	providerApiService := &provider2.ProviderApiService{}
+	fileName, err := providerApiService.ExportInstanceByProviderID(ctx, instance.ProviderID, instance.Name, backupDir)
+	stopHeartbeat()
+	if err != nil {
+		if removeErr := storageService.RemoveBackupDir(backup.UUID); removeErr != nil {
+			global.APP_LOG.Warn("清理备份目录失败", zap.Uint("backupId", backup.ID), zap.Error(removeErr))
+		}
+		s.markBackupFailed(backup, err)
+		return fmt.Errorf("创建备份失败: %v", err)
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 90, "正在更新备份状态...")
+
+	var fileSize int64
+	if info, err := os.Stat(filepath.Join(backupDir, fileName)); err == nil {
+		fileSize = info.Size()
+	}
+
+	now := time.Now()
+	if err := global.APP_DB.Model(backup).Updates(map[string]interface{}{
+		"status":        "available",
+		"file_name":     fileName,
+		"file_size":     fileSize,
+		"error_message": "",
+		"completed_at":  &now,
+	}).Error; err != nil {
+		global.APP_LOG.Error("更新备份状态失败", zap.Uint("backupId", backup.ID), zap.Error(err))
+		return fmt.Errorf("更新备份状态失败: %v", err)
+	}
+
+	// 定时备份完成后按保留数量清理旧备份
+	if backup.ScheduleID > 0 {
+		s.pruneScheduledBackups(backup.ScheduleID)
+	}
+
+	// 标记任务完成
+	stateManager := GetTaskStateManager()
+	if err := stateManager.CompleteMainTask(task.ID, true, "备份创建成功", map[string]interface{}{
+		"backupId": backup.ID,
+		"fileName": fileName,
+		"fileSize": fileSize,
+	}); err != nil {
+		global.APP_LOG.Error("完成任务失败", zap.Uint("taskId", task.ID), zap.Error(err))
+	}
+
+	global.APP_LOG.Info("实例备份创建成功",
+		zap.Uint("taskId", task.ID),
+		zap.Uint("instanceId", instance.ID),
+		zap.String("instanceName", instance.Name),
+		zap.Uint("backupId", backup.ID),
+		zap.Int64("fileSize", fileSize))
+
+	return nil
+}
+
+// markBackupFailed 标记备份失败并记录原因
+func (s *TaskService) markBackupFailed(backup *providerModel.InstanceBackup, cause error) {
+	global.APP_DB.Model(backup).Updates(map[string]interface{}{
+		"status":        "failed",
+		"error_message": utils.TruncateString(cause.Error(), 500),
+	})
+}
+
+// pruneScheduledBackups 清理超出保留数量的定时备份，从最旧的开始删除
+func (s *TaskService) pruneScheduledBackups(scheduleID uint) {
+	var schedule providerModel.BackupSchedule
+	if err := global.APP_DB.First(&schedule, scheduleID).Error; err != nil {
+		// 计划已被删除时不再清理，已生成的备份由用户自行管理
+		return
+	}
+
+	var backups []providerModel.InstanceBackup
+	if err := global.APP_DB.Where("schedule_id = ? AND status = ?", scheduleID, "available").
+		Order("created_at DESC").Find(&backups).Error; err != nil {
+		global.APP_LOG.Warn("获取定时备份列表失败", zap.Uint("scheduleId", scheduleID), zap.Error(err))
+		return
+	}
+	if len(backups) <= schedule.Retention {
+		return
+	}
+
+	storageService := storage.GetStorageService()
+	for _, old := range backups[schedule.Retention:] {
+		if err := storageService.RemoveBackupDir(old.UUID); err != nil {
+			global.APP_LOG.Warn("删除过期备份文件失败",
+				zap.Uint("backupId", old.ID),
+				zap.Error(err))
+		}
+		if err := global.APP_DB.Delete(&old).Error; err != nil {
+			global.APP_LOG.Warn("删除过期备份记录失败",
+				zap.Uint("backupId", old.ID),
+				zap.Error(err))
+			continue
+		}
+		global.APP_LOG.Info("已按保留策略清理定时备份",
+			zap.Uint("scheduleId", scheduleID),
+			zap.Uint("backupId", old.ID))
+	}
+}
+
+// executeRestoreBackupTask 执行备份恢复为新实例任务
+func (s *TaskService) executeRestoreBackupTask(ctx context.Context, task *adminModel.Task) error {
+	// 初始化进度
+	s.updateTaskProgress(task.ID, 10, "正在解析任务数据...")
+
+	backup, instance, err := s.loadBackupTaskContext(task)
+	if err != nil {
+		return err
+	}
+
+	if err := s.restoreBackupToInstance(ctx, task, backup, instance); err != nil {
+		s.rollbackRestoredInstance(ctx, instance)
+		global.APP_DB.Model(backup).Update("status", "available")
+		return fmt.Errorf("恢复备份失败: %v", err)
+	}
+
+	global.APP_DB.Model(backup).Update("status", "available")
+
+	// 标记任务完成
+	stateManager := GetTaskStateManager()
+	if err := stateManager.CompleteMainTask(task.ID, true, "备份恢复成功", map[string]interface{}{
+		"backupId":     backup.ID,
+		"instanceId":   instance.ID,
+		"instanceName": instance.Name,
+	}); err != nil {
+		global.APP_LOG.Error("完成任务失败", zap.Uint("taskId", task.ID), zap.Error(err))
+	}
+
+	global.APP_LOG.Info("备份恢复为新实例成功",
+		zap.Uint("taskId", task.ID),
+		zap.Uint("backupId", backup.ID),
+		zap.Uint("instanceId", instance.ID),
+		zap.String("instanceName", instance.Name))
+
+	return nil
+}
+
+// restoreBackupToInstance 将备份导入到Provider并完善新实例信息
+func (s *TaskService) restoreBackupToInstance(ctx context.Context, task *adminModel.Task, backup *providerModel.InstanceBackup, instance *providerModel.Instance) error {
+	localPath := filepath.Join(storage.GetStorageService().GetBackupsPath(), backup.UUID, backup.FileName)
+	if _, err := os.Stat(localPath); err != nil {
+		return fmt.Errorf("备份文件不存在: %v", err)
+	}
+
+	var dbProvider providerModel.Provider
+	if err := global.APP_DB.First(&dbProvider, instance.ProviderID).Error; err != nil {
+		return fmt.Errorf("获取Provider信息失败: %v", err)
+	}
+
+	var user userModel.User
+	if err := global.APP_DB.Select("level").First(&user, instance.UserID).Error; err != nil {
+		return fmt.Errorf("获取用户信息失败: %v", err)
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 20, "正在分配端口映射...")
+
+	// 端口映射记录需要在导入前创建，LXD/Incus/Proxmox导入时从数据库读取
+	portMappingService := &resources.PortMappingService{}
+	if err := portMappingService.CreateDefaultPortMappings(instance.ID, dbProvider.ID); err != nil {
+		global.APP_LOG.Warn("预分配端口映射失败",
+			zap.Uint("instanceId", instance.ID),
+			zap.Error(err))
+	}
+
+	instanceConfig := buildImportInstanceConfig(instance, &dbProvider, user.Level)
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 30, "正在传输并导入备份...")
+	stopHeartbeat := s.startBackupHeartbeat(task.ID, 30, "正在传输并导入备份...")
+
+	providerApiService := &provider2.ProviderApiService{}
+	err := providerApiService.ImportInstanceByProviderID(ctx, dbProvider.ID, localPath, instanceConfig)
+	stopHeartbeat()
+	if err != nil {
+		return err
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 70, "正在设置实例密码...")
+
+	// 新实例使用新密码，设置失败时沿用备份中的密码
+	password := backup.Password
+	newPassword := utils.GenerateStrongPassword(12)
+	providerService := provider2.GetProviderService()
+	for attempt := 1; attempt <= 3; attempt++ {
+		if attempt > 1 {
+			time.Sleep(time.Duration(attempt*3) * time.Second)
+		}
+		if err := providerService.SetInstancePassword(ctx, dbProvider.ID, instance.Name, newPassword); err != nil {
+			global.APP_LOG.Warn("设置恢复实例密码失败",
+				zap.Uint("instanceId", instance.ID),
+				zap.Int("attempt", attempt),
+				zap.Error(err))
+			continue
+		}
+		password = newPassword
+		break
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 85, "正在更新实例信息...")
+
+	updates := map[string]interface{}{
+		"status":    "running",
+		"password":  password,
+		"public_ip": publicIPFromEndpoint(dbProvider.Endpoint),
+		"ssh_port":  22,
+	}
+	if backup.Username != "" {
+		updates["username"] = backup.Username
+	} else {
+		updates["username"] = "root"
+	}
+	collectInstanceAddresses(ctx, dbProvider.ID, instance.Name, updates)
+
+	var sshPortMapping providerModel.Port
+	if err := global.APP_DB.Where("instance_id = ? AND is_ssh = true AND status = 'active'", instance.ID).First(&sshPortMapping).Error; err == nil {
+		updates["ssh_port"] = sshPortMapping.HostPort
+	}
+
+	if err := global.APP_DB.Model(instance).Updates(updates).Error; err != nil {
+		return fmt.Errorf("更新实例信息失败: %v", err)
+	}
+
+	// nftables端口映射依赖实例内网IP，在IP写入数据库后统一下发
+	portMappingService.RefreshNftablesMappings(ctx, dbProvider.ID)
+
+	if err := vnstat.NewService().InitializeVnStatForInstance(instance.ID); err != nil {
+		global.APP_LOG.Warn("初始化vnstat监控失败",
+			zap.Uint("instanceId", instance.ID),
+			zap.Error(err))
+	}
+
+	return nil
+}
+
+// collectInstanceAddresses 从Provider读取实例的实际IP地址并写入updates
+func collectInstanceAddresses(ctx context.Context, providerID uint, instanceName string, updates map[string]interface{}) {
+	providerApiService := &provider2.ProviderApiService{}
+	prov, _, err := providerApiService.GetProviderByID(providerID)
+	if err != nil {
+		return
+	}
+	providerInstance, err := prov.GetInstance(ctx, instanceName)
+	if err != nil || providerInstance == nil {
+		return
+	}
+	if providerInstance.IP != "" {
+		updates["private_ip"] = providerInstance.IP
+	}
+	if providerInstance.PrivateIP != "" {
+		updates["private_ip"] = providerInstance.PrivateIP
+	}
+	if providerInstance.PublicIP != "" {
+		updates["public_ip"] = providerInstance.PublicIP
+	}
+	if providerInstance.IPv6Address != "" {
+		updates["ipv6_address"] = providerInstance.IPv6Address
+	}
+}
+
+// buildImportInstanceConfig 构建导入实例到Provider时使用的实例配置
+// 端口映射需要提前在数据库中分配好，Docker容器的端口映射在创建容器时指定
+func buildImportInstanceConfig(instance *providerModel.Instance, dbProvider *providerModel.Provider, userLevel int) provider.InstanceConfig {
+	instanceConfig := provider.InstanceConfig{
+		Name:         instance.Name,
+		Image:        instance.Image,
+		CPU:          fmt.Sprintf("%d", instance.CPU),
+		Memory:       fmt.Sprintf("%dm", instance.Memory),
+		Disk:         fmt.Sprintf("%dm", instance.Disk),
+		InstanceType: instance.InstanceType,
+		Metadata: map[string]string{
+			"user_level":               fmt.Sprintf("%d", userLevel),
+			"bandwidth_spec":           fmt.Sprintf("%d", instance.Bandwidth),
+			"ipv4_port_mapping_method": dbProvider.IPv4PortMappingMethod,
+			"ipv6_port_mapping_method": dbProvider.IPv6PortMappingMethod,
+			"network_type":             dbProvider.NetworkType,
+			"instance_id":              fmt.Sprintf("%d", instance.ID),
+			"provider_id":              fmt.Sprintf("%d", dbProvider.ID),
+		},
+	}
+
+	if dbProvider.Type == "docker" {
+		var portMappings []providerModel.Port
+		if err := global.APP_DB.Where("instance_id = ? AND provider_id = ? AND status = 'active'", instance.ID, dbProvider.ID).
+			Find(&portMappings).Error; err == nil {
+			var ports []string
+			for _, port := range portMappings {
+				if port.Protocol == "both" {
+					ports = append(ports,
+						fmt.Sprintf("0.0.0.0:%d:%d/tcp", port.HostPort, port.GuestPort),
+						fmt.Sprintf("0.0.0.0:%d:%d/udp", port.HostPort, port.GuestPort))
+				} else {
+					ports = append(ports, fmt.Sprintf("0.0.0.0:%d:%d/%s", port.HostPort, port.GuestPort, port.Protocol))
+				}
+			}
+			instanceConfig.Ports = ports
+		}
+	}
+
+	return instanceConfig
+}
+
+// rollbackRestoredInstance 恢复失败时清理新实例及其占用的资源
+func (s *TaskService) rollbackRestoredInstance(ctx context.Context, instance *providerModel.Instance) {
+	// 导入可能已部分完成，尽量删除Provider上的残留实例
+	providerApiService := &provider2.ProviderApiService{}
+	if err := providerApiService.DeleteInstanceByProviderID(ctx, instance.ProviderID, instance.Name); err != nil {
+		global.APP_LOG.Debug("清理恢复失败的Provider实例",
+			zap.String("instanceName", instance.Name),
+			zap.Error(err))
+	}
+
+	err := global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		portMappingService := &resources.PortMappingService{}
+		if err := portMappingService.DeleteInstancePortMappingsInTx(tx, instance.ID); err != nil {
+			global.APP_LOG.Warn("清理恢复失败实例端口映射失败",
+				zap.Uint("instanceId", instance.ID),
+				zap.Error(err))
+		}
+
+		resourceService := &resources.ResourceService{}
+		if err := resourceService.ReleaseResourcesInTx(tx, instance.ProviderID, instance.InstanceType,
+			instance.CPU, instance.Memory, instance.Disk); err != nil {
+			global.APP_LOG.Warn("释放Provider资源失败",
+				zap.Uint("instanceId", instance.ID),
+				zap.Error(err))
+		}
+
+		return tx.Delete(instance).Error
+	})
+	if err != nil {
+		global.APP_LOG.Error("清理恢复失败的实例记录失败",
+			zap.Uint("instanceId", instance.ID),
+			zap.Error(err))
+	}
+
+	portMappingService := &resources.PortMappingService{}
+	portMappingService.RefreshNftablesMappings(ctx, instance.ProviderID)
+}
+
+// publicIPFromEndpoint 从Provider的Endpoint中提取公网IP
+func publicIPFromEndpoint(endpoint string) string {
+	if colonIndex := strings.LastIndex(endpoint, ":"); colonIndex > 0 {
+		if strings.Count(endpoint, ":") > 1 && !strings.HasPrefix(endpoint, "[") {
+			return endpoint // IPv6格式
+		}
+		return endpoint[:colonIndex] // IPv4格式，移除端口
+	}
+	return endpoint
+}
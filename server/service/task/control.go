@@ -8,7 +8,9 @@ import (
 	"oneclickvirt/global"
 	adminModel "oneclickvirt/model/admin"
 	providerModel "oneclickvirt/model/provider"
+	"oneclickvirt/service/notification"
 	"oneclickvirt/service/resources"
+	"oneclickvirt/service/webhook"
 	"time"
 
 	"go.uber.org/zap"
@@ -77,6 +79,58 @@ func (s *TaskService) CompleteTask(taskID uint, success bool, errorMessage strin
 		zap.Bool("success", success),
 		zap.String("errorMessage", errorMessage))
 
+	// 通知订阅了任务事件的Webhook
+	eventType := webhook.EventTaskCompleted
+	if !success {
+		eventType = webhook.EventTaskFailed
+	}
+	webhook.Publish(eventType, map[string]interface{}{
+		"taskId":       task.ID,
+		"uuid":         task.UUID,
+		"taskType":     task.TaskType,
+		"status":       status,
+		"userId":       task.UserID,
+		"providerId":   task.ProviderID,
+		"instanceId":   task.InstanceID,
+		"errorMessage": errorMessage,
+		"completedAt":  now,
+	})
+
+	// 删除任务完成时实例记录已软删除，需要包含已删除的记录
+	instanceName := ""
+	if task.InstanceID != nil {
+		var instance providerModel.Instance
+		if err := global.APP_DB.Unscoped().First(&instance, *task.InstanceID).Error; err == nil {
+			instanceName = instance.Name
+
+			// 通知订阅了实例生命周期事件的Webhook，从备份恢复的任务总是创建新实例
+			if success {
+				switch task.TaskType {
+				case "create", "restore-backup":
+					webhook.PublishInstance(webhook.EventInstanceCreated, &instance, map[string]interface{}{
+						"taskId":   task.ID,
+						"taskType": task.TaskType,
+						"status":   instance.Status,
+					})
+				case "delete":
+					webhook.PublishInstance(webhook.EventInstanceDeleted, &instance, map[string]interface{}{
+						"taskId":   task.ID,
+						"taskType": task.TaskType,
+					})
+				}
+			}
+		}
+	}
+
+	// 通知任务所属用户
+	notification.Notify(task.UserID, notification.CategoryTask, notification.TemplateTaskResult, map[string]interface{}{
+		"TaskID":       task.ID,
+		"TaskType":     task.TaskType,
+		"Success":      success,
+		"InstanceName": instanceName,
+		"ErrorMessage": errorMessage,
+	}, "")
+
 	// 任务完成后，立即触发调度器检查pending任务
 	if global.APP_SCHEDULER != nil {
 		global.APP_SCHEDULER.TriggerTaskProcessing()
@@ -9,9 +9,11 @@ import (
 	adminModel "oneclickvirt/model/admin"
 	providerModel "oneclickvirt/model/provider"
 	"oneclickvirt/service/database"
+	"oneclickvirt/service/domain"
 	provider2 "oneclickvirt/service/provider"
 	"oneclickvirt/service/resources"
 	"oneclickvirt/service/traffic"
+	"oneclickvirt/service/user/securitygroup"
 	"oneclickvirt/service/vnstat"
 	"time"
 
@@ -169,6 +171,20 @@ func (s *TaskService) executeDeleteInstanceTask(ctx context.Context, task *admin
 				zap.Error(err))
 		}
 
+		// 删除实例快照记录（Provider侧快照随实例一同删除）
+		if err := tx.Where("instance_id = ?", instance.ID).Delete(&providerModel.InstanceSnapshot{}).Error; err != nil {
+			global.APP_LOG.Warn("删除实例快照记录失败",
+				zap.Uint("instanceId", instance.ID),
+				zap.Error(err))
+		}
+
+		// 删除定时备份计划，已生成的备份保留，可用于恢复为新实例
+		if err := tx.Where("instance_id = ?", instance.ID).Delete(&providerModel.BackupSchedule{}).Error; err != nil {
+			global.APP_LOG.Warn("删除定时备份计划失败",
+				zap.Uint("instanceId", instance.ID),
+				zap.Error(err))
+		}
+
 		// 删除实例记录
 		if err := tx.Delete(&instance).Error; err != nil {
 			return fmt.Errorf("删除实例记录失败: %v", err)
@@ -193,6 +209,16 @@ func (s *TaskService) executeDeleteInstanceTask(ctx context.Context, task *admin
 		return err
 	}
 
+	// 端口记录已删除，重新生成nftables表以移除该实例的规则，避免复用IP的新实例收到旧流量
+	portMappingService := &resources.PortMappingService{}
+	portMappingService.RefreshNftablesMappings(ctx, instance.ProviderID)
+
+	// 删除实例的域名绑定，并从节点配置中移除
+	domain.CleanupInstanceDomains(instance.ID)
+
+	// 删除实例的安全组绑定，并清理节点上残留的ACL或规则链
+	securitygroup.CleanupInstanceGroups(&instance)
+
 	// 标记任务完成
 	operationType := "用户"
 	if taskReq.AdminOperation {
@@ -22,6 +22,13 @@ func (s *TaskService) getDefaultTimeout(taskType string) int {
 		"create-port-mapping": 600,  // 10分钟
 		"delete-port-mapping": 300,  // 5分钟
 		"reset-password":      600,  // 10分钟
+		"create-snapshot":     1200, // 20分钟
+		"restore-snapshot":    1200, // 20分钟
+		"delete-snapshot":     600,  // 10分钟
+		"create-backup":       7200, // 2小时
+		"restore-backup":      7200, // 2小时
+		"resize":              1200, // 20分钟
+		"migrate":             7200, // 2小时
 	}
 
 	if timeout, exists := timeouts[taskType]; exists {
@@ -114,6 +121,20 @@ func (s *TaskService) executeTaskLogic(ctx context.Context, task *adminModel.Tas
 		return s.executeCreatePortMappingTask(ctx, task)
 	case "delete-port-mapping":
 		return s.executeDeletePortMappingTask(ctx, task)
+	case "create-snapshot":
+		return s.executeCreateSnapshotTask(ctx, task)
+	case "restore-snapshot":
+		return s.executeRestoreSnapshotTask(ctx, task)
+	case "delete-snapshot":
+		return s.executeDeleteSnapshotTask(ctx, task)
+	case "create-backup":
+		return s.executeCreateBackupTask(ctx, task)
+	case "restore-backup":
+		return s.executeRestoreBackupTask(ctx, task)
+	case "resize":
+		return s.executeResizeTask(ctx, task)
+	case "migrate":
+		return s.executeMigrateTask(ctx, task)
 	default:
 		return fmt.Errorf("未知的任务类型: %s", task.TaskType)
 	}
@@ -10,6 +10,7 @@ import (
 	providerModel "oneclickvirt/model/provider"
 	provider2 "oneclickvirt/service/provider"
 	"oneclickvirt/service/traffic"
+	"oneclickvirt/service/user/securitygroup"
 	"oneclickvirt/service/vnstat"
 	"oneclickvirt/utils"
 	"time"
@@ -110,6 +111,9 @@ func (s *TaskService) executeStartInstanceTask(ctx context.Context, task *adminM
 			return
 		}
 
+		// 容器启动后IP可能变化，重新下发安全组规则
+		securitygroup.ResyncInstanceFirewall(instanceID)
+
 		// 更新进度
 		s.updateTaskProgress(taskID, 90, "正在初始化vnStat监控...")
 
@@ -354,6 +358,9 @@ func (s *TaskService) executeRestartInstanceTask(ctx context.Context, task *admi
 			return
 		}
 
+		// 容器重启后IP可能变化，重新下发安全组规则
+		securitygroup.ResyncInstanceFirewall(instanceID)
+
 		// 更新进度
 		s.updateTaskProgress(taskID, 90, "正在重新初始化vnStat监控...")
 
@@ -0,0 +1,375 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	providerModel "oneclickvirt/model/provider"
+	resourceModel "oneclickvirt/model/resource"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/service/domain"
+	provider2 "oneclickvirt/service/provider"
+	"oneclickvirt/service/resources"
+	"oneclickvirt/service/storage"
+	"oneclickvirt/service/traffic"
+	"oneclickvirt/service/user/securitygroup"
+	"oneclickvirt/service/vnstat"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// migrateState 迁移过程中需要在失败时恢复的状态
+type migrateState struct {
+	previousStatus  string
+	previousSSHPort int
+	sourcePortIDs   []uint // 迁移期间停用的源节点端口映射
+	workDir         string // 导出文件暂存目录名（位于备份存储目录下）
+}
+
+// executeMigrateTask 执行实例跨节点迁移任务
+// 流程：占用目标节点资源并分配端口 -> 源节点导出、目标节点导入 -> 切换实例记录 -> 删除源实例
+// 导入完成前任一步骤失败都会回滚目标节点上的资源和端口，并恢复源实例
+func (s *TaskService) executeMigrateTask(ctx context.Context, task *adminModel.Task) error {
+	// 初始化进度
+	s.updateTaskProgress(task.ID, 5, "正在解析任务数据...")
+
+	var taskReq adminModel.MigrateTaskRequest
+	if err := json.Unmarshal([]byte(task.TaskData), &taskReq); err != nil {
+		return fmt.Errorf("解析任务数据失败: %v", err)
+	}
+
+	var instance providerModel.Instance
+	if err := global.APP_DB.First(&instance, taskReq.InstanceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("实例不存在")
+		}
+		return fmt.Errorf("获取实例信息失败: %v", err)
+	}
+	if instance.UserID != task.UserID {
+		return fmt.Errorf("无权限操作此实例")
+	}
+	if instance.ProviderID != taskReq.SourceProviderID {
+		return fmt.Errorf("实例已不在源节点上")
+	}
+
+	var target providerModel.Provider
+	if err := global.APP_DB.First(&target, taskReq.TargetProviderID).Error; err != nil {
+		return fmt.Errorf("获取目标节点信息失败: %v", err)
+	}
+
+	var user userModel.User
+	if err := global.APP_DB.Select("level").First(&user, instance.UserID).Error; err != nil {
+		return fmt.Errorf("获取用户信息失败: %v", err)
+	}
+
+	// 迁移前同步一次流量，尽量减少源节点上未统计的流量
+	if err := traffic.NewService().SyncInstanceTraffic(instance.ID); err != nil {
+		global.APP_LOG.Warn("迁移前同步实例流量失败",
+			zap.Uint("instanceId", instance.ID),
+			zap.Error(err))
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 10, "正在分配目标节点资源...")
+
+	state, err := s.prepareMigration(&instance, &target)
+	if err != nil {
+		return err
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 15, "正在迁移实例...")
+
+	if err := s.copyInstanceToTarget(ctx, task, &instance, &target, user.Level, state); err != nil {
+		s.rollbackMigration(&instance, &target, state)
+		return fmt.Errorf("迁移实例失败: %v", err)
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 85, "正在更新实例信息...")
+
+	if err := s.finishMigration(ctx, &instance, &target, state); err != nil {
+		// 目标实例已就绪，实例记录切换失败时保留两边实例，由管理员处理
+		global.APP_LOG.Error("切换实例记录失败，源实例与目标实例均已保留",
+			zap.Uint("taskId", task.ID),
+			zap.Uint("instanceId", instance.ID),
+			zap.Error(err))
+		return fmt.Errorf("更新实例信息失败: %v", err)
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 95, "正在清理源节点实例...")
+
+	providerApiService := &provider2.ProviderApiService{}
+	if err := providerApiService.DeleteMigrationSourceInstance(ctx, taskReq.SourceProviderID, instance.Name); err != nil {
+		global.APP_LOG.Warn("删除源节点实例失败，需要手动清理",
+			zap.Uint("instanceId", instance.ID),
+			zap.Uint("sourceProviderId", taskReq.SourceProviderID),
+			zap.String("instanceName", instance.Name),
+			zap.Error(err))
+	}
+
+	if err := vnstat.NewService().InitializeVnStatForInstance(instance.ID); err != nil {
+		global.APP_LOG.Warn("初始化vnstat监控失败",
+			zap.Uint("instanceId", instance.ID),
+			zap.Error(err))
+	}
+
+	// 域名绑定跟随实例迁移到目标节点
+	domain.SyncInstanceDomains(instance.ID)
+
+	// 安全组规则下发到目标节点，并清理源节点上残留的ACL或规则链
+	if securitygroup.HasInstanceGroups(instance.ID) {
+		securitygroup.ResyncInstanceFirewall(instance.ID)
+		go securitygroup.ClearInstanceFirewall(taskReq.SourceProviderID, instance.Name)
+	}
+
+	// 标记任务完成
+	stateManager := GetTaskStateManager()
+	if err := stateManager.CompleteMainTask(task.ID, true, "实例迁移成功", map[string]interface{}{
+		"instanceId":       instance.ID,
+		"sourceProviderId": taskReq.SourceProviderID,
+		"targetProviderId": target.ID,
+		"targetProvider":   target.Name,
+	}); err != nil {
+		global.APP_LOG.Error("完成任务失败", zap.Uint("taskId", task.ID), zap.Error(err))
+	}
+
+	global.APP_LOG.Info("实例迁移成功",
+		zap.Uint("taskId", task.ID),
+		zap.Uint("instanceId", instance.ID),
+		zap.String("instanceName", instance.Name),
+		zap.Uint("sourceProviderId", taskReq.SourceProviderID),
+		zap.String("targetProvider", target.Name))
+
+	return nil
+}
+
+// prepareMigration 在目标节点上占用资源、分配默认端口映射，并停用源节点的端口映射
+func (s *TaskService) prepareMigration(instance *providerModel.Instance, target *providerModel.Provider) (*migrateState, error) {
+	state := &migrateState{
+		previousStatus:  instance.Status,
+		previousSSHPort: instance.SSHPort,
+		workDir:         "migrate-" + instance.UUID,
+	}
+
+	err := global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		var locked providerModel.Instance
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&locked, instance.ID).Error; err != nil {
+			return fmt.Errorf("获取实例信息失败: %v", err)
+		}
+		if locked.Status != "running" && locked.Status != "stopped" {
+			return fmt.Errorf("实例当前状态不允许迁移")
+		}
+
+		resourceService := &resources.ResourceService{}
+		checkResult, err := resourceService.CheckProviderResourcesWithTx(tx, resourceModel.ResourceCheckRequest{
+			ProviderID:   target.ID,
+			InstanceType: instance.InstanceType,
+			CPU:          instance.CPU,
+			Memory:       instance.Memory,
+			Disk:         instance.Disk,
+		})
+		if err != nil {
+			return fmt.Errorf("检查目标节点资源失败: %v", err)
+		}
+		if !checkResult.Allowed {
+			return fmt.Errorf("目标节点资源不足: %s", checkResult.Reason)
+		}
+		if err := resourceService.AllocateResourcesInTx(tx, target.ID, instance.InstanceType,
+			instance.CPU, instance.Memory, instance.Disk); err != nil {
+			return fmt.Errorf("分配目标节点资源失败: %v", err)
+		}
+
+		// 导入时Provider按实例ID读取有效的端口映射，先停用源节点上的映射
+		var sourcePorts []providerModel.Port
+		if err := tx.Where("instance_id = ? AND provider_id = ? AND status = 'active'", instance.ID, instance.ProviderID).
+			Find(&sourcePorts).Error; err != nil {
+			return fmt.Errorf("获取端口映射失败: %v", err)
+		}
+		for _, port := range sourcePorts {
+			state.sourcePortIDs = append(state.sourcePortIDs, port.ID)
+		}
+		if len(state.sourcePortIDs) > 0 {
+			if err := tx.Model(&providerModel.Port{}).Where("id IN (?)", state.sourcePortIDs).
+				Update("status", "inactive").Error; err != nil {
+				return fmt.Errorf("停用源节点端口映射失败: %v", err)
+			}
+		}
+
+		// 迁移期间标记实例状态，避免用户同时进行其他操作
+		return tx.Model(instance).Update("status", "migrating").Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 目标节点的规则在实例切换、内网IP更新后再下发，这里只移除源节点上已停用的映射
+	portMappingService := &resources.PortMappingService{}
+	portMappingService.RefreshNftablesMappings(context.Background(), instance.ProviderID)
+
+	if err := portMappingService.CreateDefaultPortMappings(instance.ID, target.ID); err != nil {
+		s.rollbackMigration(instance, target, state)
+		return nil, fmt.Errorf("分配目标节点端口映射失败: %v", err)
+	}
+
+	return state, nil
+}
+
+// copyInstanceToTarget 导出源实例并导入到目标节点，迁移进度映射到任务进度的15%-80%
+func (s *TaskService) copyInstanceToTarget(ctx context.Context, task *adminModel.Task, instance *providerModel.Instance, target *providerModel.Provider, userLevel int, state *migrateState) error {
+	storageService := storage.GetStorageService()
+	workDir, err := storageService.PrepareBackupDir(state.workDir)
+	if err != nil {
+		return fmt.Errorf("准备迁移目录失败: %v", err)
+	}
+	defer func() {
+		if err := storageService.RemoveBackupDir(state.workDir); err != nil {
+			global.APP_LOG.Warn("清理迁移目录失败", zap.Uint("instanceId", instance.ID), zap.Error(err))
+		}
+	}()
+
+	// 导出和导入可能超过调度器的超时判定，每个阶段都保持心跳
+	stopHeartbeat := func() {}
+	progressCallback := func(percentage int, message string) {
+		stopHeartbeat()
+		progress := 15 + percentage*65/100
+		s.updateTaskProgress(task.ID, progress, message)
+		stopHeartbeat = s.startBackupHeartbeat(task.ID, progress, message)
+	}
+	defer func() { stopHeartbeat() }()
+
+	instanceConfig := buildImportInstanceConfig(instance, target, userLevel)
+	providerApiService := &provider2.ProviderApiService{}
+	return providerApiService.MigrateInstanceByProviderID(ctx, instance.ProviderID, target.ID, workDir, instanceConfig, progressCallback)
+}
+
+// rollbackMigration 迁移失败时清理目标节点上的实例、资源和端口，并恢复源实例
+func (s *TaskService) rollbackMigration(instance *providerModel.Instance, target *providerModel.Provider, state *migrateState) {
+	ctx := context.Background()
+	providerApiService := &provider2.ProviderApiService{}
+
+	// 导入可能已部分完成，尽量删除目标节点上的残留实例
+	if err := providerApiService.DeleteInstanceByProviderID(ctx, target.ID, instance.Name); err != nil {
+		global.APP_LOG.Debug("清理目标节点残留实例",
+			zap.String("instanceName", instance.Name),
+			zap.Error(err))
+	}
+
+	err := global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		portMappingService := &resources.PortMappingService{}
+		if err := portMappingService.DeleteProviderPortMappingsInTx(tx, instance.ID, target.ID); err != nil {
+			return err
+		}
+		if len(state.sourcePortIDs) > 0 {
+			if err := tx.Model(&providerModel.Port{}).Where("id IN (?)", state.sourcePortIDs).
+				Update("status", "active").Error; err != nil {
+				return fmt.Errorf("恢复源节点端口映射失败: %v", err)
+			}
+		}
+
+		resourceService := &resources.ResourceService{}
+		if err := resourceService.ReleaseResourcesInTx(tx, target.ID, instance.InstanceType,
+			instance.CPU, instance.Memory, instance.Disk); err != nil {
+			return fmt.Errorf("释放目标节点资源失败: %v", err)
+		}
+
+		return tx.Model(instance).Updates(map[string]interface{}{
+			"status":   state.previousStatus,
+			"ssh_port": state.previousSSHPort,
+		}).Error
+	})
+	if err != nil {
+		global.APP_LOG.Error("回滚迁移失败",
+			zap.Uint("instanceId", instance.ID),
+			zap.Error(err))
+	}
+
+	// 恢复源节点上的映射，并清理目标节点上可能已下发的规则
+	portMappingService := &resources.PortMappingService{}
+	portMappingService.RefreshNftablesMappings(ctx, instance.ProviderID, target.ID)
+
+	// 源实例在导出前已停止，按原状态恢复运行
+	if state.previousStatus == "running" {
+		if err := providerApiService.StartMigrationSourceInstance(ctx, instance.ProviderID, instance.Name); err != nil {
+			global.APP_LOG.Warn("重新启动源实例失败",
+				zap.Uint("instanceId", instance.ID),
+				zap.Error(err))
+		}
+	}
+}
+
+// finishMigration 目标实例就绪后切换实例记录：释放源节点资源和端口，转移流量记录并更新实例所属节点
+func (s *TaskService) finishMigration(ctx context.Context, instance *providerModel.Instance, target *providerModel.Provider, state *migrateState) error {
+	providerApiService := &provider2.ProviderApiService{}
+	newStatus := "running"
+	if state.previousStatus == "stopped" {
+		if err := providerApiService.StopInstanceByProviderID(ctx, target.ID, instance.Name); err != nil {
+			global.APP_LOG.Warn("停止目标节点实例失败",
+				zap.Uint("instanceId", instance.ID),
+				zap.Error(err))
+		} else {
+			newStatus = "stopped"
+		}
+	}
+
+	// vnStat接口需要在切换节点前从源节点移除
+	if err := vnstat.NewService().TransferVnStatData(instance.ID, target.ID, target.Type); err != nil {
+		global.APP_LOG.Warn("转移vnStat数据失败",
+			zap.Uint("instanceId", instance.ID),
+			zap.Error(err))
+	}
+
+	updates := map[string]interface{}{
+		"provider_id": target.ID,
+		"provider":    target.Name,
+		"status":      newStatus,
+		"public_ip":   publicIPFromEndpoint(target.Endpoint),
+		"private_ip":  "",
+	}
+	collectInstanceAddresses(ctx, target.ID, instance.Name, updates)
+
+	var sshPortMapping providerModel.Port
+	if err := global.APP_DB.Where("instance_id = ? AND provider_id = ? AND is_ssh = true AND status = 'active'", instance.ID, target.ID).
+		First(&sshPortMapping).Error; err == nil {
+		updates["ssh_port"] = sshPortMapping.HostPort
+	}
+
+	sourceProviderID := instance.ProviderID
+	err := global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		resourceService := &resources.ResourceService{}
+		if err := resourceService.ReleaseResourcesInTx(tx, sourceProviderID, instance.InstanceType,
+			instance.CPU, instance.Memory, instance.Disk); err != nil {
+			return fmt.Errorf("释放源节点资源失败: %v", err)
+		}
+
+		portMappingService := &resources.PortMappingService{}
+		if err := portMappingService.DeleteProviderPortMappingsInTx(tx, instance.ID, sourceProviderID); err != nil {
+			return err
+		}
+
+		if err := traffic.NewService().TransferInstanceTraffic(tx, instance.ID, target.ID); err != nil {
+			return err
+		}
+
+		// 源节点上的快照随源实例一同删除
+		if err := tx.Where("instance_id = ?", instance.ID).Delete(&providerModel.InstanceSnapshot{}).Error; err != nil {
+			return fmt.Errorf("删除实例快照记录失败: %v", err)
+		}
+
+		return tx.Model(instance).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// 源节点的端口记录已删除，目标节点的实例内网IP已更新，两边的nftables表都需要重新生成
+	portMappingService := &resources.PortMappingService{}
+	portMappingService.RefreshNftablesMappings(ctx, sourceProviderID, target.ID)
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"oneclickvirt/constant"
 	"oneclickvirt/global"
 	adminModel "oneclickvirt/model/admin"
 	providerModel "oneclickvirt/model/provider"
@@ -156,10 +157,7 @@ func (s *TaskService) executeCreatePortMappingTask(ctx context.Context, task *ad
 	})
 
 	// 确定使用的 portmapping provider 类型
-	portMappingType := providerInfo.Type
-	if portMappingType == "proxmox" {
-		portMappingType = "iptables"
-	}
+	portMappingType := portMappingBackend(&providerInfo)
 
 	portReq := &portmapping.PortMappingRequest{
 		InstanceID:    fmt.Sprintf("%d", instance.ID),
@@ -201,8 +199,8 @@ func (s *TaskService) executeCreatePortMappingTask(ctx context.Context, task *ad
 			zap.Uint("originalPortId", port.ID))
 	}
 
-	// 对于 LXD/Incus/Proxmox，还需要在远程服务器上实际创建端口映射
-	if providerInfo.Type == "lxd" || providerInfo.Type == "incus" || providerInfo.Type == "proxmox" {
+	// 对于 LXD/Incus/Proxmox，还需要在远程服务器上实际创建端口映射（nftables后端已直接应用到主机）
+	if portMappingType != "nftables" && (providerInfo.Type == "lxd" || providerInfo.Type == "incus" || providerInfo.Type == "proxmox") {
 		s.updateTaskProgress(task.ID, 85, "正在应用端口映射到远程服务器...")
 
 		// 调用 provider 层的方法在远程服务器上创建实际映射（使用最新获取的内网IP）
@@ -337,10 +335,7 @@ func (s *TaskService) executeDeletePortMappingTask(ctx context.Context, task *ad
 			DefaultMappingMethod: providerInfo.IPv4PortMappingMethod,
 		})
 
-		portMappingType := providerInfo.Type
-		if portMappingType == "proxmox" {
-			portMappingType = "iptables"
-		}
+		portMappingType := portMappingBackend(&providerInfo)
 
 		deleteReq := &portmapping.DeletePortMappingRequest{
 			ID:         port.ID,
@@ -357,7 +352,7 @@ func (s *TaskService) executeDeletePortMappingTask(ctx context.Context, task *ad
 		}
 
 		// 对于 LXD/Incus，还需要在远程服务器上实际删除 proxy device
-		if (providerInfo.Type == "lxd" || providerInfo.Type == "incus") && instance.Name != "" {
+		if portMappingType != "nftables" && (providerInfo.Type == "lxd" || providerInfo.Type == "incus") && instance.Name != "" {
 			s.updateTaskProgress(task.ID, 70, "正在从远程服务器删除端口映射...")
 
 			// 获取 Provider 实例
@@ -428,3 +423,14 @@ func (s *TaskService) executeDeletePortMappingTask(ctx context.Context, task *ad
 
 	return nil
 }
+
+// portMappingBackend 根据Provider类型和IPv4端口映射方式确定使用的portmapping后端
+func portMappingBackend(providerInfo *providerModel.Provider) string {
+	if providerInfo.IPv4PortMappingMethod == string(constant.PortMappingMethodNftables) && providerInfo.Type != "docker" {
+		return "nftables"
+	}
+	if providerInfo.Type == "proxmox" {
+		return "iptables"
+	}
+	return providerInfo.Type
+}
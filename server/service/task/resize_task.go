@@ -0,0 +1,222 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	providerModel "oneclickvirt/model/provider"
+	resourceModel "oneclickvirt/model/resource"
+	"oneclickvirt/provider"
+	provider2 "oneclickvirt/service/provider"
+	"oneclickvirt/service/resources"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// resizeSpec 实例规格，用于在调整前后两组规格之间切换资源占用
+type resizeSpec struct {
+	CPU       int
+	Memory    int64
+	Disk      int64
+	Bandwidth int
+}
+
+// swapInstanceSpec 在事务中将实例的资源占用从from替换为to：
+// 校验用户配额和节点资源后，释放原规格、占用新规格，并同步实例记录和用户已用配额
+func swapInstanceSpec(tx *gorm.DB, instance *providerModel.Instance, from, to resizeSpec, validate bool) error {
+	if validate {
+		quotaResult, err := resources.NewQuotaService().ValidateInTransaction(tx, resources.ResourceRequest{
+			UserID:            instance.UserID,
+			CPU:               to.CPU,
+			Memory:            to.Memory,
+			Disk:              to.Disk,
+			Bandwidth:         to.Bandwidth,
+			InstanceType:      instance.InstanceType,
+			ProviderID:        instance.ProviderID,
+			ExcludeInstanceID: instance.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("配额验证失败: %v", err)
+		}
+		if !quotaResult.Allowed {
+			return errors.New(quotaResult.Reason)
+		}
+	}
+
+	// 先释放原规格再检查节点资源，实例数量在释放和占用之间保持不变
+	resourceService := &resources.ResourceService{}
+	if err := resourceService.ReleaseResourcesInTx(tx, instance.ProviderID, instance.InstanceType,
+		from.CPU, from.Memory, from.Disk); err != nil {
+		return fmt.Errorf("释放原规格资源失败: %v", err)
+	}
+
+	if validate {
+		checkResult, err := resourceService.CheckProviderResourcesWithTx(tx, resourceModel.ResourceCheckRequest{
+			ProviderID:   instance.ProviderID,
+			InstanceType: instance.InstanceType,
+			CPU:          to.CPU,
+			Memory:       to.Memory,
+			Disk:         to.Disk,
+		})
+		if err != nil {
+			return fmt.Errorf("检查节点资源失败: %v", err)
+		}
+		if !checkResult.Allowed {
+			return fmt.Errorf("节点资源不足: %s", checkResult.Reason)
+		}
+	}
+
+	if err := resourceService.AllocateResourcesInTx(tx, instance.ProviderID, instance.InstanceType,
+		to.CPU, to.Memory, to.Disk); err != nil {
+		return fmt.Errorf("分配新规格资源失败: %v", err)
+	}
+
+	quotaService := resources.NewQuotaService()
+	if err := quotaService.UpdateUserQuotaAfterDeletionWithTx(tx, instance.UserID, resources.ResourceUsage{
+		CPU: from.CPU, Memory: from.Memory, Disk: from.Disk, Bandwidth: from.Bandwidth,
+	}); err != nil {
+		return err
+	}
+	if err := quotaService.UpdateUserQuotaAfterCreationWithTx(tx, instance.UserID, resources.ResourceUsage{
+		CPU: to.CPU, Memory: to.Memory, Disk: to.Disk, Bandwidth: to.Bandwidth,
+	}); err != nil {
+		return err
+	}
+
+	return tx.Model(instance).Updates(map[string]interface{}{
+		"cpu":       to.CPU,
+		"memory":    to.Memory,
+		"disk":      to.Disk,
+		"bandwidth": to.Bandwidth,
+	}).Error
+}
+
+// executeResizeTask 执行调整实例规格任务
+// 资源占用在调用Provider前于事务中完成调整，Provider执行失败时通过补偿事务恢复原规格
+func (s *TaskService) executeResizeTask(ctx context.Context, task *adminModel.Task) error {
+	// 初始化进度
+	s.updateTaskProgress(task.ID, 10, "正在解析任务数据...")
+
+	var taskReq adminModel.ResizeTaskRequest
+	if err := json.Unmarshal([]byte(task.TaskData), &taskReq); err != nil {
+		return fmt.Errorf("解析任务数据失败: %v", err)
+	}
+
+	var instance providerModel.Instance
+	if err := global.APP_DB.First(&instance, taskReq.InstanceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("实例不存在")
+		}
+		return fmt.Errorf("获取实例信息失败: %v", err)
+	}
+
+	// 验证实例所有权
+	if instance.UserID != task.UserID {
+		return fmt.Errorf("无权限操作此实例")
+	}
+
+	// 任务排队期间实例规格可能已被修改，此时原规格与任务记录不一致，拒绝执行
+	oldSpec := resizeSpec{CPU: instance.CPU, Memory: instance.Memory, Disk: instance.Disk, Bandwidth: instance.Bandwidth}
+	if oldSpec != (resizeSpec{CPU: taskReq.OldCPU, Memory: taskReq.OldMemory, Disk: taskReq.OldDisk, Bandwidth: taskReq.OldBandwidth}) {
+		return fmt.Errorf("实例规格已发生变化，请重新提交调整请求")
+	}
+	newSpec := resizeSpec{CPU: taskReq.CPU, Memory: taskReq.Memory, Disk: taskReq.Disk, Bandwidth: taskReq.Bandwidth}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 20, "正在校验配额并调整资源占用...")
+
+	previousStatus := instance.Status
+	err := global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		var locked providerModel.Instance
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&locked, instance.ID).Error; err != nil {
+			return fmt.Errorf("获取实例信息失败: %v", err)
+		}
+		if locked.Status != "running" && locked.Status != "stopped" {
+			return fmt.Errorf("实例当前状态不允许调整规格")
+		}
+		if err := swapInstanceSpec(tx, &instance, oldSpec, newSpec, true); err != nil {
+			return err
+		}
+		// 调整期间标记实例状态，避免用户同时进行其他操作
+		return tx.Model(&instance).Update("status", "resizing").Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 40, "正在调整实例规格...")
+
+	providerApiService := &provider2.ProviderApiService{}
+	spec := provider.ResizeSpec{
+		CPU:       newSpec.CPU,
+		Memory:    newSpec.Memory,
+		Bandwidth: newSpec.Bandwidth,
+	}
+	if newSpec.Disk > oldSpec.Disk {
+		spec.Disk = newSpec.Disk
+	}
+	if resizeErr := providerApiService.ResizeInstanceByProviderID(ctx, instance.ProviderID, instance.Name, spec); resizeErr != nil {
+		s.updateTaskProgress(task.ID, 70, "调整失败，正在回滚...")
+
+		// Provider可能已应用了部分配置，尽量恢复CPU、内存和带宽，磁盘无法缩容
+		if err := providerApiService.ResizeInstanceByProviderID(context.Background(), instance.ProviderID, instance.Name, provider.ResizeSpec{
+			CPU:       oldSpec.CPU,
+			Memory:    oldSpec.Memory,
+			Bandwidth: oldSpec.Bandwidth,
+		}); err != nil {
+			global.APP_LOG.Warn("恢复实例原规格失败",
+				zap.Uint("taskId", task.ID),
+				zap.Uint("instanceId", instance.ID),
+				zap.Error(err))
+		}
+
+		rollbackErr := global.APP_DB.Transaction(func(tx *gorm.DB) error {
+			if err := swapInstanceSpec(tx, &instance, newSpec, oldSpec, false); err != nil {
+				return err
+			}
+			return tx.Model(&instance).Update("status", previousStatus).Error
+		})
+		if rollbackErr != nil {
+			global.APP_LOG.Error("回滚实例资源占用失败",
+				zap.Uint("taskId", task.ID),
+				zap.Uint("instanceId", instance.ID),
+				zap.Error(rollbackErr))
+		}
+		return fmt.Errorf("调整实例规格失败: %v", resizeErr)
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 90, "正在更新实例状态...")
+
+	if err := global.APP_DB.Model(&instance).Update("status", previousStatus).Error; err != nil {
+		global.APP_LOG.Error("更新实例状态失败", zap.Uint("instanceId", instance.ID), zap.Error(err))
+	}
+
+	// 标记任务完成
+	stateManager := GetTaskStateManager()
+	if err := stateManager.CompleteMainTask(task.ID, true, "实例规格调整成功", map[string]interface{}{
+		"instanceId": instance.ID,
+		"cpu":        newSpec.CPU,
+		"memory":     newSpec.Memory,
+		"disk":       newSpec.Disk,
+		"bandwidth":  newSpec.Bandwidth,
+	}); err != nil {
+		global.APP_LOG.Error("完成任务失败", zap.Uint("taskId", task.ID), zap.Error(err))
+	}
+
+	global.APP_LOG.Info("实例规格调整成功",
+		zap.Uint("taskId", task.ID),
+		zap.Uint("instanceId", instance.ID),
+		zap.String("instanceName", instance.Name),
+		zap.Int("cpu", newSpec.CPU),
+		zap.Int64("memory", newSpec.Memory),
+		zap.Int64("disk", newSpec.Disk),
+		zap.Int("bandwidth", newSpec.Bandwidth))
+
+	return nil
+}
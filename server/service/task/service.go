@@ -10,10 +10,12 @@ import (
 	"oneclickvirt/provider/portmapping"
 	"oneclickvirt/provider/proxmox"
 	"oneclickvirt/service/database"
+	"oneclickvirt/service/domain"
 	"oneclickvirt/service/interfaces"
 	provider2 "oneclickvirt/service/provider"
 	"oneclickvirt/service/resources"
 	userprovider "oneclickvirt/service/user/provider"
+	"oneclickvirt/service/user/securitygroup"
 	"oneclickvirt/service/vnstat"
 	"oneclickvirt/utils"
 	"sort"
@@ -319,6 +321,13 @@ func (s *TaskService) executeResetInstanceTask(ctx context.Context, task *adminM
 			zap.String("instanceName", instance.Name))
 	}
 
+	// 旧实例删除后其快照也随之失效，清理快照记录
+	if err := global.APP_DB.Where("instance_id = ?", instance.ID).Delete(&providerModel.InstanceSnapshot{}).Error; err != nil {
+		global.APP_LOG.Warn("清理实例快照记录失败",
+			zap.Uint("instanceId", instance.ID),
+			zap.Error(err))
+	}
+
 	// 更新进度
 	s.updateTaskProgress(task.ID, 40, "等待旧实例完全删除...")
 
@@ -364,6 +373,7 @@ CreateNewInstance:
 			Disk:         fmt.Sprintf("%dMB", instance.Disk),
 			Env:          make(map[string]string),
 			Metadata:     make(map[string]string),
+			UserData:     instance.UserData, // 重新下发创建时提供的用户数据
 		},
 		SystemImageID: systemImage.ID,
 	}
@@ -640,10 +650,7 @@ CreateNewInstance:
 			})
 
 			// 确定portmapping类型
-			portMappingType := provider.Type
-			if portMappingType == "proxmox" {
-				portMappingType = "iptables"
-			}
+			portMappingType := portMappingBackend(&provider)
 
 			// 按协议分组端口映射
 			tcpPorts := make([]providerModel.Port, 0)
@@ -703,6 +710,10 @@ CreateNewInstance:
 		}
 	}
 
+	// 重置后实例内网IP可能变化，按数据库重新生成nftables表
+	portMappingService := &resources.PortMappingService{}
+	portMappingService.RefreshNftablesMappings(ctx, provider.ID)
+
 	// 第4.5步：从数据库查询SSH端口映射并更新实例的ssh_port字段
 	var sshPortMapping providerModel.Port
 	if err := global.APP_DB.Where("instance_id = ? AND is_ssh = true AND status = 'active'", instance.ID).First(&sshPortMapping).Error; err == nil {
@@ -752,6 +763,12 @@ CreateNewInstance:
 			zap.Uint("instanceId", instance.ID))
 	}
 
+	// 重置后实例内网IP可能变化，重新生成节点上的域名绑定配置
+	domain.SyncInstanceDomains(instance.ID)
+
+	// 重置会重建实例，重新下发安全组规则
+	securitygroup.ResyncInstanceFirewall(instance.ID)
+
 	// 更新进度
 	s.updateTaskProgress(task.ID, 95, "重置完成，正在收尾...")
 
@@ -813,8 +830,8 @@ func (s *TaskService) restorePortMappingsOptimized(
 	// 处理每个分组
 	for _, group := range consecutiveGroups {
 		// 判断是否应该使用范围映射
-		// LXD/Incus 支持范围映射，Proxmox 使用 iptables 需要逐个创建
-		useRangeMapping := (provider.Type == "lxd" || provider.Type == "incus") && len(group) >= 3
+		// LXD/Incus 支持范围映射，Proxmox 使用 iptables、nftables 后端需要逐个创建
+		useRangeMapping := portMappingType != "nftables" && (provider.Type == "lxd" || provider.Type == "incus") && len(group) >= 3
 
 		if useRangeMapping {
 			// 3个或以上连续端口，使用范围映射（避免创建重复设备）
@@ -0,0 +1,204 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	providerModel "oneclickvirt/model/provider"
+	provider2 "oneclickvirt/service/provider"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// loadSnapshotTaskContext 解析快照任务数据并加载快照、实例记录
+func (s *TaskService) loadSnapshotTaskContext(task *adminModel.Task) (*providerModel.InstanceSnapshot, *providerModel.Instance, error) {
+	var taskReq adminModel.SnapshotTaskRequest
+	if err := json.Unmarshal([]byte(task.TaskData), &taskReq); err != nil {
+		return nil, nil, fmt.Errorf("解析任务数据失败: %v", err)
+	}
+
+	var snapshot providerModel.InstanceSnapshot
+	if err := global.APP_DB.First(&snapshot, taskReq.SnapshotID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("快照不存在")
+		}
+		return nil, nil, fmt.Errorf("获取快照信息失败: %v", err)
+	}
+
+	var instance providerModel.Instance
+	if err := global.APP_DB.First(&instance, snapshot.InstanceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("实例不存在")
+		}
+		return nil, nil, fmt.Errorf("获取实例信息失败: %v", err)
+	}
+
+	// 验证实例所有权
+	if instance.UserID != task.UserID || snapshot.UserID != task.UserID {
+		return nil, nil, fmt.Errorf("无权限操作此实例")
+	}
+
+	return &snapshot, &instance, nil
+}
+
+// executeCreateSnapshotTask 执行创建快照任务
+func (s *TaskService) executeCreateSnapshotTask(ctx context.Context, task *adminModel.Task) error {
+	// 初始化进度
+	s.updateTaskProgress(task.ID, 10, "正在解析任务数据...")
+
+	snapshot, instance, err := s.loadSnapshotTaskContext(task)
+	if err != nil {
+		return err
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 30, "正在创建快照...")
+
+	providerApiService := &provider2.ProviderApiService{}
+	if err := providerApiService.CreateSnapshotByProviderID(ctx, instance.ProviderID, instance.Name, snapshot.Name); err != nil {
+		global.APP_DB.Model(snapshot).Update("status", "failed")
+		return fmt.Errorf("创建快照失败: %v", err)
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 90, "正在更新快照状态...")
+
+	if err := global.APP_DB.Model(snapshot).Update("status", "available").Error; err != nil {
+		global.APP_LOG.Error("更新快照状态失败", zap.Uint("snapshotId", snapshot.ID), zap.Error(err))
+		return fmt.Errorf("更新快照状态失败: %v", err)
+	}
+
+	// 标记任务完成
+	stateManager := GetTaskStateManager()
+	if err := stateManager.CompleteMainTask(task.ID, true, "快照创建成功", map[string]interface{}{
+		"snapshotId":   snapshot.ID,
+		"snapshotName": snapshot.Name,
+	}); err != nil {
+		global.APP_LOG.Error("完成任务失败", zap.Uint("taskId", task.ID), zap.Error(err))
+	}
+
+	global.APP_LOG.Info("实例快照创建成功",
+		zap.Uint("taskId", task.ID),
+		zap.Uint("instanceId", instance.ID),
+		zap.String("instanceName", instance.Name),
+		zap.String("snapshot", snapshot.Name))
+
+	return nil
+}
+
+// executeRestoreSnapshotTask 执行恢复快照任务
+func (s *TaskService) executeRestoreSnapshotTask(ctx context.Context, task *adminModel.Task) error {
+	// 初始化进度
+	s.updateTaskProgress(task.ID, 10, "正在解析任务数据...")
+
+	snapshot, instance, err := s.loadSnapshotTaskContext(task)
+	if err != nil {
+		return err
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 30, "正在恢复快照...")
+
+	// 恢复期间标记实例状态，避免用户同时进行其他操作
+	previousStatus := instance.Status
+	global.APP_DB.Model(instance).Update("status", "restoring")
+
+	providerApiService := &provider2.ProviderApiService{}
+	if err := providerApiService.RestoreSnapshotByProviderID(ctx, instance.ProviderID, instance.Name, snapshot.Name); err != nil {
+		global.APP_DB.Model(instance).Update("status", previousStatus)
+		global.APP_DB.Model(snapshot).Update("status", "available")
+		return fmt.Errorf("恢复快照失败: %v", err)
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 80, "正在确认实例状态...")
+
+	// 恢复后以Provider上的实际状态为准
+	newStatus := previousStatus
+	if prov, _, err := providerApiService.GetProviderByID(instance.ProviderID); err == nil {
+		if providerInstance, err := prov.GetInstance(ctx, instance.Name); err == nil && providerInstance != nil {
+			switch providerInstance.Status {
+			case "running", "Running", "RUNNING":
+				newStatus = "running"
+			case "stopped", "Stopped", "STOPPED", "exited":
+				newStatus = "stopped"
+			}
+		}
+	}
+	if err := global.APP_DB.Model(instance).Update("status", newStatus).Error; err != nil {
+		global.APP_LOG.Error("更新实例状态失败", zap.Uint("instanceId", instance.ID), zap.Error(err))
+	}
+
+	now := time.Now()
+	global.APP_DB.Model(snapshot).Updates(map[string]interface{}{
+		"status":      "available",
+		"restored_at": &now,
+	})
+
+	// 标记任务完成
+	stateManager := GetTaskStateManager()
+	if err := stateManager.CompleteMainTask(task.ID, true, "快照恢复成功", map[string]interface{}{
+		"snapshotId":   snapshot.ID,
+		"snapshotName": snapshot.Name,
+	}); err != nil {
+		global.APP_LOG.Error("完成任务失败", zap.Uint("taskId", task.ID), zap.Error(err))
+	}
+
+	global.APP_LOG.Info("实例快照恢复成功",
+		zap.Uint("taskId", task.ID),
+		zap.Uint("instanceId", instance.ID),
+		zap.String("instanceName", instance.Name),
+		zap.String("snapshot", snapshot.Name))
+
+	return nil
+}
+
+// executeDeleteSnapshotTask 执行删除快照任务
+func (s *TaskService) executeDeleteSnapshotTask(ctx context.Context, task *adminModel.Task) error {
+	// 初始化进度
+	s.updateTaskProgress(task.ID, 10, "正在解析任务数据...")
+
+	snapshot, instance, err := s.loadSnapshotTaskContext(task)
+	if err != nil {
+		return err
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 40, "正在删除快照...")
+
+	// 创建失败的快照在Provider上可能不存在，直接删除记录即可
+	if snapshot.Status != "failed" {
+		providerApiService := &provider2.ProviderApiService{}
+		if err := providerApiService.DeleteSnapshotByProviderID(ctx, instance.ProviderID, instance.Name, snapshot.Name); err != nil {
+			global.APP_DB.Model(snapshot).Update("status", "available")
+			return fmt.Errorf("删除快照失败: %v", err)
+		}
+	}
+
+	// 更新进度
+	s.updateTaskProgress(task.ID, 90, "正在清理快照记录...")
+
+	if err := global.APP_DB.Delete(snapshot).Error; err != nil {
+		global.APP_LOG.Error("删除快照记录失败", zap.Uint("snapshotId", snapshot.ID), zap.Error(err))
+		return fmt.Errorf("删除快照记录失败: %v", err)
+	}
+
+	// 标记任务完成
+	stateManager := GetTaskStateManager()
+	if err := stateManager.CompleteMainTask(task.ID, true, "快照删除成功", nil); err != nil {
+		global.APP_LOG.Error("完成任务失败", zap.Uint("taskId", task.ID), zap.Error(err))
+	}
+
+	global.APP_LOG.Info("实例快照删除成功",
+		zap.Uint("taskId", task.ID),
+		zap.Uint("instanceId", instance.ID),
+		zap.String("instanceName", instance.Name),
+		zap.String("snapshot", snapshot.Name))
+
+	return nil
+}
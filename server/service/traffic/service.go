@@ -866,6 +866,31 @@ func (s *Service) MarkInstanceTrafficDeleted(instanceID uint) error {
 	return nil
 }
 
+// TransferInstanceTraffic 实例迁移到其他Provider时转移当月流量记录
+// 历史月份的记录保留在原Provider名下，当月记录归属目标Provider以保持实例和用户的月度累计；
+// 目标Provider上的vnStat从零开始计数，因此清空vnStat基准值，下次同步时以新值作为增量
+func (s *Service) TransferInstanceTraffic(tx *gorm.DB, instanceID, targetProviderID uint) error {
+	now := time.Now()
+	result := tx.Model(&userModel.TrafficRecord{}).
+		Where("instance_id = ? AND year = ? AND month = ?", instanceID, now.Year(), int(now.Month())).
+		Updates(map[string]interface{}{
+			"provider_id":       targetProviderID,
+			"interface_name":    "",
+			"last_vnstat_rx_mb": 0,
+			"last_vnstat_tx_mb": 0,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("转移实例流量记录失败: %w", result.Error)
+	}
+
+	global.APP_LOG.Info("实例当月流量记录已转移",
+		zap.Uint("instanceID", instanceID),
+		zap.Uint("targetProviderID", targetProviderID),
+		zap.Int64("affectedRows", result.RowsAffected))
+
+	return nil
+}
+
 // ClearInstanceTrafficInterface 清理实例流量接口映射（用于实例重置时）
 func (s *Service) ClearInstanceTrafficInterface(instanceID uint) error {
 	// 清理实例的vnstat接口映射，重置后会重新检测
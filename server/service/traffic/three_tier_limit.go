@@ -9,6 +9,8 @@ import (
 	adminModel "oneclickvirt/model/admin"
 	"oneclickvirt/model/provider"
 	"oneclickvirt/model/user"
+	"oneclickvirt/service/notification"
+	"oneclickvirt/service/webhook"
 
 	"go.uber.org/zap"
 )
@@ -59,6 +61,31 @@ func (s *ThreeTierLimitService) CheckAllTrafficLimits(ctx context.Context) error
 	return nil
 }
 
+// trafficNotifyThresholds 流量使用提醒阈值（百分比），从高到低
+var trafficNotifyThresholds = []int64{100, 80}
+
+// notifyTrafficThreshold 流量使用达到提醒阈值时通知用户，每个自然月每个阈值只提醒一次
+func notifyTrafficThreshold(userID uint, scope, name, key string, used, total int64) {
+	if total <= 0 {
+		return
+	}
+	percent := used * 100 / total
+	for _, threshold := range trafficNotifyThresholds {
+		if percent < threshold {
+			continue
+		}
+		now := time.Now()
+		notification.Notify(userID, notification.CategoryTraffic, notification.TemplateTrafficThreshold, map[string]interface{}{
+			"Scope":   scope,
+			"Name":    name,
+			"Used":    used,
+			"Total":   total,
+			"Percent": percent,
+		}, fmt.Sprintf("traffic:%s:%d-%02d:%d", key, now.Year(), int(now.Month()), threshold))
+		return
+	}
+}
+
 // ============ 实例层级流量限制 ============
 
 // CheckAllInstancesTrafficLimit 检查所有实例的流量限制
@@ -138,6 +165,8 @@ func (s *ThreeTierLimitService) CheckInstanceTrafficLimit(instanceID uint) (bool
 		return false, fmt.Errorf("更新实例流量失败: %w", err)
 	}
 
+	notifyTrafficThreshold(instance.UserID, "实例", instance.Name, fmt.Sprintf("instance:%d", instance.ID), usedTraffic, instance.MaxTraffic)
+
 	// 检查是否超限
 	if usedTraffic >= instance.MaxTraffic {
 		// 实例超限，仅停止该实例
@@ -183,6 +212,18 @@ func (s *ThreeTierLimitService) limitInstance(instanceID uint, reason string, me
 			zap.Error(err))
 	}
 
+	// 已处于受限状态时每轮检查都会重新标记，只在首次受限时通知
+	if !instance.TrafficLimited {
+		webhook.Publish(webhook.EventInstanceTrafficLimited, map[string]interface{}{
+			"instanceId":   instance.ID,
+			"instanceName": instance.Name,
+			"userId":       instance.UserID,
+			"providerId":   instance.ProviderID,
+			"level":        reason,
+			"message":      message,
+		})
+	}
+
 	return true, nil
 }
 
@@ -201,6 +242,17 @@ func (s *ThreeTierLimitService) unlimitInstance(instanceID uint, reason string)
 		zap.Uint("instanceID", instanceID),
 		zap.String("reason", reason))
 
+	var instance provider.Instance
+	if err := global.APP_DB.First(&instance, instanceID).Error; err == nil {
+		webhook.Publish(webhook.EventInstanceTrafficResumed, map[string]interface{}{
+			"instanceId":   instance.ID,
+			"instanceName": instance.Name,
+			"userId":       instance.UserID,
+			"providerId":   instance.ProviderID,
+			"message":      reason,
+		})
+	}
+
 	return false, nil
 }
 
@@ -321,6 +373,8 @@ func (s *ThreeTierLimitService) CheckUserTrafficLimit(userID uint) (bool, error)
 		return false, fmt.Errorf("更新用户流量失败: %w", err)
 	}
 
+	notifyTrafficThreshold(userID, "用户", u.Username, fmt.Sprintf("user:%d", userID), totalUsed, u.TotalTraffic)
+
 	// 检查是否超限
 	if totalUsed >= u.TotalTraffic {
 		// 用户超限，停止用户所有实例
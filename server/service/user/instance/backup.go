@@ -0,0 +1,481 @@
+package instance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	providerModel "oneclickvirt/model/provider"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/service/resources"
+	"oneclickvirt/service/storage"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// defaultMaxBackups 等级未配置备份数量时的默认上限
+const defaultMaxBackups = 1
+
+// GetUserMaxBackups 获取用户等级对应的最大备份数量
+func GetUserMaxBackups(level int) int {
+	if levelLimits, exists := global.APP_CONFIG.Quota.LevelLimits[level]; exists && levelLimits.MaxBackups > 0 {
+		return levelLimits.MaxBackups
+	}
+	return defaultMaxBackups
+}
+
+// getUserLevel 获取用户等级
+func getUserLevel(userID uint) (int, error) {
+	var user userModel.User
+	if err := global.APP_DB.Select("level").First(&user, userID).Error; err != nil {
+		return 0, fmt.Errorf("获取用户信息失败: %v", err)
+	}
+	return user.Level, nil
+}
+
+// getUserBackup 获取用户的备份记录
+func getUserBackup(userID, backupID uint) (*providerModel.InstanceBackup, error) {
+	var backup providerModel.InstanceBackup
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", backupID, userID).First(&backup).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("备份不存在")
+		}
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// checkBackupOperable 检查实例当前是否允许创建备份
+func checkBackupOperable(instance *providerModel.Instance) error {
+	if instance.Status != "running" && instance.Status != "stopped" {
+		return errors.New("实例当前状态不允许进行备份操作")
+	}
+
+	// 同一实例同时只允许一个备份任务
+	var count int64
+	global.APP_DB.Model(&adminModel.Task{}).
+		Where("instance_id = ? AND task_type = ? AND status IN (?)", instance.ID, "create-backup", []string{"pending", "running"}).
+		Count(&count)
+	if count > 0 {
+		return errors.New("实例已有备份任务正在进行")
+	}
+	return nil
+}
+
+// ListBackups 获取用户备份列表，instanceID为0时返回全部备份
+func (s *Service) ListBackups(userID, instanceID uint) (*userModel.BackupListResponse, error) {
+	query := global.APP_DB.Where("user_id = ?", userID)
+	if instanceID > 0 {
+		query = query.Where("instance_id = ?", instanceID)
+	}
+
+	var backups []providerModel.InstanceBackup
+	if err := query.Order("created_at DESC").Find(&backups).Error; err != nil {
+		return nil, fmt.Errorf("获取备份列表失败: %v", err)
+	}
+
+	level, err := getUserLevel(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userModel.BackupListResponse{
+		Backups:    backups,
+		MaxBackups: GetUserMaxBackups(level),
+	}, nil
+}
+
+// CreateInstanceBackup 创建实例备份
+func (s *Service) CreateInstanceBackup(userID, instanceID uint, req userModel.CreateBackupRequest) (*userModel.BackupTaskResponse, error) {
+	instance, err := s.getSnapshotInstance(userID, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBackupOperable(instance); err != nil {
+		return nil, err
+	}
+
+	level, err := getUserLevel(userID)
+	if err != nil {
+		return nil, err
+	}
+	maxBackups := GetUserMaxBackups(level)
+
+	var backup *providerModel.InstanceBackup
+	err = global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		// 在事务中检查数量，避免并发创建超出限制
+		var count int64
+		if err := tx.Model(&providerModel.InstanceBackup{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) >= maxBackups {
+			return fmt.Errorf("备份数量已达上限（%d个），请先删除旧备份", maxBackups)
+		}
+
+		backup = newInstanceBackup(instance, "manual", req.Description, 0)
+		return tx.Create(backup).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := createBackupTask(userID, instance.ProviderID, instance.ID, backup.ID, "create-backup")
+	if err != nil {
+		global.APP_DB.Unscoped().Delete(backup)
+		return nil, fmt.Errorf("创建备份任务失败: %v", err)
+	}
+	global.APP_DB.Model(backup).Update("task_id", task.ID)
+
+	global.APP_LOG.Info("用户创建实例备份任务",
+		zap.Uint("userID", userID),
+		zap.Uint("instanceID", instance.ID),
+		zap.Uint("backupID", backup.ID),
+		zap.Uint("taskID", task.ID))
+
+	return &userModel.BackupTaskResponse{BackupID: backup.ID, TaskID: task.ID}, nil
+}
+
+// CreateScheduledBackup 按定时备份计划创建备份任务
+// 定时备份数量由计划的保留数量控制，超出部分在备份完成后清理，因此不受用户备份上限限制
+func (s *Service) CreateScheduledBackup(schedule *providerModel.BackupSchedule) (*userModel.BackupTaskResponse, error) {
+	instance, err := s.getSnapshotInstance(schedule.UserID, schedule.InstanceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBackupOperable(instance); err != nil {
+		return nil, err
+	}
+
+	backup := newInstanceBackup(instance, "scheduled", "定时备份", schedule.ID)
+	if err := global.APP_DB.Create(backup).Error; err != nil {
+		return nil, fmt.Errorf("创建备份记录失败: %v", err)
+	}
+
+	task, err := createBackupTask(schedule.UserID, instance.ProviderID, instance.ID, backup.ID, "create-backup")
+	if err != nil {
+		global.APP_DB.Unscoped().Delete(backup)
+		return nil, fmt.Errorf("创建备份任务失败: %v", err)
+	}
+	global.APP_DB.Model(backup).Update("task_id", task.ID)
+
+	return &userModel.BackupTaskResponse{BackupID: backup.ID, TaskID: task.ID}, nil
+}
+
+// newInstanceBackup 根据实例当前信息构建备份记录，恢复时按这些信息创建新实例
+func newInstanceBackup(instance *providerModel.Instance, trigger, description string, scheduleID uint) *providerModel.InstanceBackup {
+	return &providerModel.InstanceBackup{
+		InstanceID:   instance.ID,
+		ProviderID:   instance.ProviderID,
+		UserID:       instance.UserID,
+		ScheduleID:   scheduleID,
+		InstanceName: instance.Name,
+		InstanceType: instance.InstanceType,
+		ProviderType: getProviderType(instance.ProviderID),
+		Image:        instance.Image,
+		OSType:       instance.OSType,
+		CPU:          instance.CPU,
+		Memory:       instance.Memory,
+		Disk:         instance.Disk,
+		Bandwidth:    instance.Bandwidth,
+		Username:     instance.Username,
+		Password:     instance.Password,
+		Status:       "creating",
+		TriggerType:  trigger,
+		Description:  description,
+	}
+}
+
+// getProviderType 获取Provider类型，仅用于备份记录展示
+func getProviderType(providerID uint) string {
+	var provider providerModel.Provider
+	if err := global.APP_DB.Select("type").First(&provider, providerID).Error; err != nil {
+		return ""
+	}
+	return provider.Type
+}
+
+// createBackupTask 创建备份相关任务
+func createBackupTask(userID, providerID, instanceID, backupID uint, taskType string) (*adminModel.Task, error) {
+	taskData, err := json.Marshal(adminModel.BackupTaskRequest{
+		BackupID:   backupID,
+		InstanceID: instanceID,
+		ProviderID: providerID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("序列化任务数据失败: %v", err)
+	}
+
+	taskService := getTaskService()
+	return taskService.CreateTask(userID, &providerID, &instanceID, taskType, string(taskData), 0)
+}
+
+// RestoreBackupAsNewInstance 将备份恢复为新实例
+// 新实例与来源实例使用相同的Provider和配置，占用用户的实例配额
+func (s *Service) RestoreBackupAsNewInstance(userID, backupID uint) (*userModel.BackupTaskResponse, error) {
+	backup, err := getUserBackup(userID, backupID)
+	if err != nil {
+		return nil, err
+	}
+	if backup.Status != "available" {
+		return nil, errors.New("备份当前不可用于恢复")
+	}
+
+	var instance providerModel.Instance
+	var task *adminModel.Task
+	err = global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		// 锁定用户记录，防止并发恢复超出实例配额
+		var currentUser userModel.User
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&currentUser, userID).Error; err != nil {
+			return fmt.Errorf("获取用户信息失败: %v", err)
+		}
+		if currentUser.Status != 1 {
+			return errors.New("用户账户已被禁用")
+		}
+
+		levelLimits, exists := global.APP_CONFIG.Quota.LevelLimits[currentUser.Level]
+		if !exists {
+			return fmt.Errorf("用户等级 %d 没有配置资源限制", currentUser.Level)
+		}
+		quotaService := resources.NewQuotaService()
+		currentInstances, _, err := quotaService.GetCurrentResourceUsageInTx(tx, userID)
+		if err != nil {
+			return fmt.Errorf("获取当前实例数量失败: %v", err)
+		}
+		if currentInstances >= levelLimits.MaxInstances {
+			return fmt.Errorf("实例数量已达上限：当前 %d/%d", currentInstances, levelLimits.MaxInstances)
+		}
+
+		var provider providerModel.Provider
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").
+			Where("id = ? AND status IN (?)", backup.ProviderID, []string{"active", "partial"}).
+			First(&provider).Error; err != nil {
+			return errors.New("备份所在节点不存在或不可用")
+		}
+		if provider.IsFrozen {
+			return errors.New("备份所在节点已被冻结")
+		}
+		if provider.ExpiresAt != nil && provider.ExpiresAt.Before(time.Now()) {
+			return errors.New("备份所在节点已过期")
+		}
+		if backup.InstanceType == "vm" && provider.MaxVMInstances > 0 && provider.VMCount >= provider.MaxVMInstances {
+			return fmt.Errorf("节点虚拟机数量已达上限：%d/%d", provider.VMCount, provider.MaxVMInstances)
+		}
+		if backup.InstanceType != "vm" && provider.MaxContainerInstances > 0 && provider.ContainerCount >= provider.MaxContainerInstances {
+			return fmt.Errorf("节点容器数量已达上限：%d/%d", provider.ContainerCount, provider.MaxContainerInstances)
+		}
+
+		expiredAt := time.Now().AddDate(1, 0, 0)
+		if provider.ExpiresAt != nil {
+			expiredAt = *provider.ExpiresAt
+		}
+
+		instance = providerModel.Instance{
+			Name:         generateRestoreInstanceName(provider.Name),
+			Provider:     provider.Name,
+			ProviderID:   provider.ID,
+			Image:        backup.Image,
+			CPU:          backup.CPU,
+			Memory:       backup.Memory,
+			Disk:         backup.Disk,
+			Bandwidth:    backup.Bandwidth,
+			InstanceType: backup.InstanceType,
+			UserID:       userID,
+			Status:       "creating",
+			OSType:       backup.OSType,
+			Username:     backup.Username,
+			ExpiredAt:    expiredAt,
+		}
+		if err := tx.Create(&instance).Error; err != nil {
+			return fmt.Errorf("创建实例失败: %v", err)
+		}
+
+		resourceService := &resources.ResourceService{}
+		if err := resourceService.AllocateResourcesInTx(tx, provider.ID, instance.InstanceType,
+			instance.CPU, instance.Memory, instance.Disk); err != nil {
+			return fmt.Errorf("分配Provider资源失败: %v", err)
+		}
+
+		taskData, err := json.Marshal(adminModel.BackupTaskRequest{
+			BackupID:   backup.ID,
+			InstanceID: instance.ID,
+			ProviderID: provider.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("序列化任务数据失败: %v", err)
+		}
+
+		task = &adminModel.Task{
+			UserID:           userID,
+			ProviderID:       &instance.ProviderID,
+			InstanceID:       &instance.ID,
+			TaskType:         "restore-backup",
+			TaskData:         string(taskData),
+			Status:           "pending",
+			TimeoutDuration:  7200,
+			IsForceStoppable: true,
+		}
+		if err := tx.Create(task).Error; err != nil {
+			return fmt.Errorf("创建任务失败: %v", err)
+		}
+
+		// 按状态条件更新，防止同一备份被并发恢复
+		result := tx.Model(&providerModel.InstanceBackup{}).
+			Where("id = ? AND status = ?", backup.ID, "available").
+			Updates(map[string]interface{}{"status": "restoring", "task_id": task.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("备份当前不可用于恢复")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	global.APP_LOG.Info("用户创建备份恢复任务",
+		zap.Uint("userID", userID),
+		zap.Uint("backupID", backup.ID),
+		zap.Uint("instanceID", instance.ID),
+		zap.Uint("taskID", task.ID))
+
+	return &userModel.BackupTaskResponse{BackupID: backup.ID, InstanceID: instance.ID, TaskID: task.ID}, nil
+}
+
+// generateRestoreInstanceName 生成恢复实例名称，格式与新建实例一致
+func generateRestoreInstanceName(providerName string) string {
+	cleanName := strings.ReplaceAll(strings.ToLower(providerName), " ", "-")
+	cleanName = strings.ReplaceAll(cleanName, "_", "-")
+	return fmt.Sprintf("%s-%04x", cleanName, rand.Intn(65536))
+}
+
+// DeleteBackup 删除备份及其备份文件
+func (s *Service) DeleteBackup(userID, backupID uint) error {
+	backup, err := getUserBackup(userID, backupID)
+	if err != nil {
+		return err
+	}
+	if backup.Status == "creating" || backup.Status == "restoring" {
+		return errors.New("备份任务正在进行，暂不允许删除")
+	}
+
+	return DeleteBackupRecord(backup)
+}
+
+// DeleteBackupRecord 删除备份记录和本地备份文件，同时用于保留策略清理
+func DeleteBackupRecord(backup *providerModel.InstanceBackup) error {
+	if err := storage.GetStorageService().RemoveBackupDir(backup.UUID); err != nil {
+		global.APP_LOG.Warn("删除备份文件失败",
+			zap.Uint("backupID", backup.ID),
+			zap.String("uuid", backup.UUID),
+			zap.Error(err))
+	}
+
+	if err := global.APP_DB.Delete(backup).Error; err != nil {
+		return fmt.Errorf("删除备份记录失败: %v", err)
+	}
+
+	global.APP_LOG.Info("备份已删除",
+		zap.Uint("backupID", backup.ID),
+		zap.Uint("instanceID", backup.InstanceID))
+	return nil
+}
+
+// GetBackupSchedule 获取实例定时备份计划，未设置时返回nil
+func (s *Service) GetBackupSchedule(userID, instanceID uint) (*providerModel.BackupSchedule, error) {
+	if _, err := s.getSnapshotInstance(userID, instanceID); err != nil {
+		return nil, err
+	}
+
+	var schedule providerModel.BackupSchedule
+	if err := global.APP_DB.Where("instance_id = ?", instanceID).First(&schedule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// SaveBackupSchedule 创建或更新实例定时备份计划
+func (s *Service) SaveBackupSchedule(userID, instanceID uint, req userModel.BackupScheduleRequest) (*providerModel.BackupSchedule, error) {
+	if _, err := s.getSnapshotInstance(userID, instanceID); err != nil {
+		return nil, err
+	}
+
+	cron, err := utils.ParseCron(req.CronExpr)
+	if err != nil {
+		return nil, fmt.Errorf("Cron表达式不合法: %v", err)
+	}
+	nextRun := cron.Next(time.Now())
+	if nextRun.IsZero() {
+		return nil, errors.New("Cron表达式不合法: 没有可触发的时间")
+	}
+
+	level, err := getUserLevel(userID)
+	if err != nil {
+		return nil, err
+	}
+	if maxBackups := GetUserMaxBackups(level); req.Retention > maxBackups {
+		return nil, fmt.Errorf("保留数量不能超过备份上限（%d个）", maxBackups)
+	}
+
+	var schedule providerModel.BackupSchedule
+	err = global.APP_DB.Where("instance_id = ?", instanceID).First(&schedule).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	schedule.InstanceID = instanceID
+	schedule.UserID = userID
+	schedule.CronExpr = req.CronExpr
+	schedule.Retention = req.Retention
+	schedule.Enabled = req.Enabled
+	schedule.NextRunAt = &nextRun
+	schedule.LastError = ""
+	if schedule.ID == 0 {
+		err = global.APP_DB.Create(&schedule).Error
+		// Enabled字段有默认值，创建时为false会被忽略，需要单独更新
+		if err == nil && !req.Enabled {
+			err = global.APP_DB.Model(&schedule).Update("enabled", false).Error
+		}
+	} else {
+		// 使用Select保存全部字段，确保Enabled为false时也能写入
+		err = global.APP_DB.Select("*").Save(&schedule).Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("保存定时备份计划失败: %v", err)
+	}
+
+	global.APP_LOG.Info("定时备份计划已保存",
+		zap.Uint("userID", userID),
+		zap.Uint("instanceID", instanceID),
+		zap.String("cron", req.CronExpr),
+		zap.Int("retention", req.Retention),
+		zap.Bool("enabled", req.Enabled))
+	return &schedule, nil
+}
+
+// DeleteBackupSchedule 删除实例定时备份计划，已生成的备份保留
+func (s *Service) DeleteBackupSchedule(userID, instanceID uint) error {
+	if _, err := s.getSnapshotInstance(userID, instanceID); err != nil {
+		return err
+	}
+
+	result := global.APP_DB.Where("instance_id = ?", instanceID).Delete(&providerModel.BackupSchedule{})
+	if result.Error != nil {
+		return fmt.Errorf("删除定时备份计划失败: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("定时备份计划不存在")
+	}
+	return nil
+}
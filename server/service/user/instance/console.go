@@ -0,0 +1,116 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"oneclickvirt/global"
+	providerModel "oneclickvirt/model/provider"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/provider"
+	providerService "oneclickvirt/service/provider"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// consoleTicketTTL 控制台会话创建后等待客户端连接的有效期
+const consoleTicketTTL = 60 * time.Second
+
+// consoleTicket 已建立上游连接、等待客户端接入的控制台会话
+type consoleTicket struct {
+	userID     uint
+	instanceID uint
+	stream     *provider.ConsoleStream
+}
+
+// consoleTickets 按一次性票据索引的待接入控制台会话
+var consoleTickets = struct {
+	sync.Mutex
+	items map[string]*consoleTicket
+}{items: make(map[string]*consoleTicket)}
+
+// takeConsoleTicket 取出并移除票据对应的控制台会话
+func takeConsoleTicket(ticket string) *consoleTicket {
+	consoleTickets.Lock()
+	defer consoleTickets.Unlock()
+
+	item := consoleTickets.items[ticket]
+	delete(consoleTickets.items, ticket)
+	return item
+}
+
+// CreateInstanceConsole 为用户实例建立图形控制台会话，返回客户端连接所需的票据和密码
+func (s *Service) CreateInstanceConsole(userID, instanceID uint) (*userModel.ConsoleResponse, error) {
+	var instance providerModel.Instance
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", instanceID, userID).First(&instance).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("实例不存在或无权限")
+		}
+		return nil, err
+	}
+	if instance.Status != "running" {
+		return nil, errors.New("实例未运行，无法打开控制台")
+	}
+
+	// LXD/Incus容器没有图形输出，Proxmox容器可通过vncproxy访问控制台
+	switch getProviderType(instance.ProviderID) {
+	case "lxd", "incus":
+		if instance.InstanceType != "vm" {
+			return nil, errors.New("容器实例不支持图形控制台，请使用Web终端")
+		}
+	case "docker":
+		return nil, errors.New("容器实例不支持图形控制台，请使用Web终端")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	providerApiService := &providerService.ProviderApiService{}
+	stream, err := providerApiService.OpenConsoleByProviderID(ctx, instance.ProviderID, instance.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	ticket := uuid.New().String()
+	consoleTickets.Lock()
+	consoleTickets.items[ticket] = &consoleTicket{userID: userID, instanceID: instance.ID, stream: stream}
+	consoleTickets.Unlock()
+
+	// 超时未接入时释放上游连接
+	time.AfterFunc(consoleTicketTTL, func() {
+		if item := takeConsoleTicket(ticket); item != nil {
+			item.stream.Conn.Close()
+			global.APP_LOG.Debug("控制台会话超时未接入，已释放",
+				zap.Uint("instanceID", item.instanceID))
+		}
+	})
+
+	global.APP_LOG.Info("实例控制台会话已创建",
+		zap.Uint("userID", userID),
+		zap.Uint("instanceID", instance.ID),
+		zap.String("protocol", stream.Protocol))
+
+	return &userModel.ConsoleResponse{
+		Protocol:  stream.Protocol,
+		Password:  stream.Password,
+		Ticket:    ticket,
+		ExpiresIn: int(consoleTicketTTL.Seconds()),
+	}, nil
+}
+
+// AttachInstanceConsole 使用一次性票据接入控制台会话，票据只能由创建者对同一实例使用一次
+func (s *Service) AttachInstanceConsole(userID, instanceID uint, ticket string) (*provider.ConsoleStream, error) {
+	item := takeConsoleTicket(ticket)
+	if item == nil {
+		return nil, errors.New("控制台会话不存在或已过期")
+	}
+	if item.userID != userID || item.instanceID != instanceID {
+		item.stream.Conn.Close()
+		return nil, errors.New("控制台会话不存在或已过期")
+	}
+	return item.stream, nil
+}
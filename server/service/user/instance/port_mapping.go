@@ -0,0 +1,104 @@
+package instance
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"oneclickvirt/global"
+	providerModel "oneclickvirt/model/provider"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/service/resources"
+
+	"go.uber.org/zap"
+)
+
+// defaultMaxPorts 等级未配置端口映射数量时的默认上限
+const defaultMaxPorts = 3
+
+// GetUserMaxPorts 获取用户等级对应的每个实例可自行添加的端口映射数量
+func GetUserMaxPorts(level int) int {
+	if levelLimits, exists := global.APP_CONFIG.Quota.LevelLimits[level]; exists && levelLimits.MaxPorts > 0 {
+		return levelLimits.MaxPorts
+	}
+	return defaultMaxPorts
+}
+
+// CreatePortMapping 为用户实例添加端口映射，通过create-port-mapping任务在主机上生效
+func (s *Service) CreatePortMapping(userID, instanceID uint, req userModel.CreatePortMappingRequest) (*userModel.PortMappingTaskResponse, error) {
+	level, err := getUserLevel(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	portMappingService := resources.PortMappingService{}
+	taskReq, err := portMappingService.CreateUserPortMappingWithTask(userID, instanceID, req.GuestPort, req.Protocol, req.Description, GetUserMaxPorts(level))
+	if err != nil {
+		return nil, err
+	}
+
+	taskData, err := json.Marshal(taskReq)
+	if err != nil {
+		global.APP_DB.Delete(&providerModel.Port{}, taskReq.PortID)
+		return nil, fmt.Errorf("序列化任务数据失败: %v", err)
+	}
+
+	taskService := getTaskService()
+	task, err := taskService.CreateTask(userID, &taskReq.ProviderID, &taskReq.InstanceID, "create-port-mapping", string(taskData), 0)
+	if err != nil {
+		// 任务未创建，回收已分配的端口
+		global.APP_DB.Delete(&providerModel.Port{}, taskReq.PortID)
+		return nil, fmt.Errorf("创建端口映射任务失败: %v", err)
+	}
+
+	global.APP_LOG.Info("创建用户端口映射任务",
+		zap.Uint("userId", userID),
+		zap.Uint("instanceId", instanceID),
+		zap.Uint("portId", taskReq.PortID),
+		zap.Int("hostPort", taskReq.HostPort),
+		zap.Int("guestPort", taskReq.GuestPort),
+		zap.Uint("taskId", task.ID))
+
+	return &userModel.PortMappingTaskResponse{
+		PortID:   taskReq.PortID,
+		HostPort: taskReq.HostPort,
+		TaskID:   task.ID,
+	}, nil
+}
+
+// DeletePortMapping 删除用户实例上手动添加的端口映射，通过delete-port-mapping任务执行
+func (s *Service) DeletePortMapping(userID, instanceID, portID uint) (*userModel.PortMappingTaskResponse, error) {
+	// 记录原状态，任务创建失败时恢复
+	var previous providerModel.Port
+	global.APP_DB.Select("status").Where("id = ?", portID).First(&previous)
+
+	portMappingService := resources.PortMappingService{}
+	taskReq, err := portMappingService.DeleteUserPortMappingWithTask(userID, instanceID, portID)
+	if err != nil {
+		return nil, err
+	}
+
+	taskData, err := json.Marshal(taskReq)
+	if err != nil {
+		global.APP_DB.Model(&providerModel.Port{}).Where("id = ?", portID).Update("status", previous.Status)
+		return nil, fmt.Errorf("序列化任务数据失败: %v", err)
+	}
+
+	taskService := getTaskService()
+	task, err := taskService.CreateTask(userID, &taskReq.ProviderID, &taskReq.InstanceID, "delete-port-mapping", string(taskData), 0)
+	if err != nil {
+		// 任务未创建，恢复端口状态
+		global.APP_DB.Model(&providerModel.Port{}).Where("id = ?", portID).Update("status", previous.Status)
+		return nil, fmt.Errorf("创建端口删除任务失败: %v", err)
+	}
+
+	global.APP_LOG.Info("创建用户端口删除任务",
+		zap.Uint("userId", userID),
+		zap.Uint("instanceId", instanceID),
+		zap.Uint("portId", portID),
+		zap.Uint("taskId", task.ID))
+
+	return &userModel.PortMappingTaskResponse{
+		PortID: portID,
+		TaskID: task.ID,
+	}, nil
+}
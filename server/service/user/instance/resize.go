@@ -0,0 +1,164 @@
+package instance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"oneclickvirt/constant"
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	providerModel "oneclickvirt/model/provider"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/provider"
+	"oneclickvirt/service/resources"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ResizeInstance 按规格ID调整实例规格，留空的规格保持不变
+func (s *Service) ResizeInstance(userID, instanceID uint, req userModel.ResizeInstanceRequest) (*userModel.ResizeTaskResponse, error) {
+	var spec provider.ResizeSpec
+
+	if req.CPUId != "" {
+		cpuSpec, err := constant.GetCPUSpecByID(req.CPUId)
+		if err != nil {
+			return nil, fmt.Errorf("无效的CPU规格ID: %v", err)
+		}
+		spec.CPU = cpuSpec.Cores
+	}
+	if req.MemoryId != "" {
+		memorySpec, err := constant.GetMemorySpecByID(req.MemoryId)
+		if err != nil {
+			return nil, fmt.Errorf("无效的内存规格ID: %v", err)
+		}
+		spec.Memory = int64(memorySpec.SizeMB)
+	}
+	if req.DiskId != "" {
+		diskSpec, err := constant.GetDiskSpecByID(req.DiskId)
+		if err != nil {
+			return nil, fmt.Errorf("无效的磁盘规格ID: %v", err)
+		}
+		spec.Disk = int64(diskSpec.SizeMB)
+	}
+	if req.BandwidthId != "" {
+		bandwidthSpec, err := constant.GetBandwidthSpecByID(req.BandwidthId)
+		if err != nil {
+			return nil, fmt.Errorf("无效的带宽规格ID: %v", err)
+		}
+		spec.Bandwidth = bandwidthSpec.SpeedMbps
+	}
+
+	return s.CreateResizeTask(userID, instanceID, spec)
+}
+
+// CreateResizeTask 校验并创建调整实例规格任务，spec中为0的项保持不变
+// 这里的配额检查只用于提前拒绝请求，任务执行时会在事务中重新校验并调整资源占用
+func (s *Service) CreateResizeTask(userID, instanceID uint, spec provider.ResizeSpec) (*userModel.ResizeTaskResponse, error) {
+	var instance providerModel.Instance
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", instanceID, userID).First(&instance).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("实例不存在或无权限")
+		}
+		return nil, err
+	}
+
+	if instance.Status != "running" && instance.Status != "stopped" {
+		return nil, errors.New("实例当前状态不允许调整规格")
+	}
+
+	// 调整规格期间不允许同一实例的其他任务并行执行
+	var count int64
+	global.APP_DB.Model(&adminModel.Task{}).
+		Where("instance_id = ? AND status IN (?)", instance.ID, []string{"pending", "running"}).
+		Count(&count)
+	if count > 0 {
+		return nil, errors.New("实例有任务正在进行，请稍后再试")
+	}
+
+	taskReq := adminModel.ResizeTaskRequest{
+		InstanceID:   instance.ID,
+		ProviderID:   instance.ProviderID,
+		CPU:          instance.CPU,
+		Memory:       instance.Memory,
+		Disk:         instance.Disk,
+		Bandwidth:    instance.Bandwidth,
+		OldCPU:       instance.CPU,
+		OldMemory:    instance.Memory,
+		OldDisk:      instance.Disk,
+		OldBandwidth: instance.Bandwidth,
+	}
+	if spec.CPU > 0 {
+		taskReq.CPU = spec.CPU
+	}
+	if spec.Memory > 0 {
+		taskReq.Memory = spec.Memory
+	}
+	if spec.Disk > 0 {
+		taskReq.Disk = spec.Disk
+	}
+	if spec.Bandwidth > 0 {
+		taskReq.Bandwidth = spec.Bandwidth
+	}
+
+	if taskReq.CPU == taskReq.OldCPU && taskReq.Memory == taskReq.OldMemory &&
+		taskReq.Disk == taskReq.OldDisk && taskReq.Bandwidth == taskReq.OldBandwidth {
+		return nil, errors.New("实例规格未发生变化")
+	}
+	if taskReq.Disk < taskReq.OldDisk {
+		return nil, errors.New("磁盘只支持扩容，不能小于当前大小")
+	}
+	if taskReq.Disk != taskReq.OldDisk && getProviderType(instance.ProviderID) == "docker" {
+		return nil, errors.New("Docker容器不支持调整磁盘大小")
+	}
+
+	quotaService := resources.NewQuotaService()
+	err := global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		result, err := quotaService.ValidateInTransaction(tx, resources.ResourceRequest{
+			UserID:            userID,
+			CPU:               taskReq.CPU,
+			Memory:            taskReq.Memory,
+			Disk:              taskReq.Disk,
+			Bandwidth:         taskReq.Bandwidth,
+			InstanceType:      instance.InstanceType,
+			ProviderID:        instance.ProviderID,
+			ExcludeInstanceID: instance.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("配额验证失败: %v", err)
+		}
+		if !result.Allowed {
+			return errors.New(result.Reason)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	taskData, err := json.Marshal(taskReq)
+	if err != nil {
+		return nil, fmt.Errorf("序列化任务数据失败: %v", err)
+	}
+
+	taskService := getTaskService()
+	task, err := taskService.CreateTask(userID, &instance.ProviderID, &instance.ID, "resize", string(taskData), 0)
+	if err != nil {
+		return nil, fmt.Errorf("创建调整规格任务失败: %v", err)
+	}
+
+	global.APP_LOG.Info("创建调整实例规格任务",
+		zap.Uint("userId", userID),
+		zap.Uint("instanceId", instance.ID),
+		zap.Uint("taskId", task.ID),
+		zap.Int("cpu", taskReq.CPU),
+		zap.Int64("memory", taskReq.Memory),
+		zap.Int64("disk", taskReq.Disk),
+		zap.Int("bandwidth", taskReq.Bandwidth))
+
+	return &userModel.ResizeTaskResponse{
+		InstanceID: instance.ID,
+		TaskID:     task.ID,
+	}, nil
+}
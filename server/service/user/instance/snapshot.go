@@ -0,0 +1,224 @@
+package instance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	providerModel "oneclickvirt/model/provider"
+	userModel "oneclickvirt/model/user"
+	"oneclickvirt/provider"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// defaultMaxSnapshots 等级未配置快照数量时的默认上限
+const defaultMaxSnapshots = 1
+
+// GetUserMaxSnapshots 获取用户等级对应的单实例最大快照数量
+func GetUserMaxSnapshots(level int) int {
+	if levelLimits, exists := global.APP_CONFIG.Quota.LevelLimits[level]; exists && levelLimits.MaxSnapshots > 0 {
+		return levelLimits.MaxSnapshots
+	}
+	return defaultMaxSnapshots
+}
+
+// getSnapshotInstance 获取用户可操作快照的实例
+func (s *Service) getSnapshotInstance(userID, instanceID uint) (*providerModel.Instance, error) {
+	var instance providerModel.Instance
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", instanceID, userID).First(&instance).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("实例不存在或无权限")
+		}
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// checkSnapshotOperable 检查实例当前是否允许进行快照操作
+func checkSnapshotOperable(instance *providerModel.Instance) error {
+	if instance.Status != "running" && instance.Status != "stopped" {
+		return errors.New("实例当前状态不允许进行快照操作")
+	}
+
+	// 同一实例同时只允许一个快照任务
+	var count int64
+	global.APP_DB.Model(&adminModel.Task{}).
+		Where("instance_id = ? AND task_type IN (?) AND status IN (?)", instance.ID,
+			[]string{"create-snapshot", "restore-snapshot", "delete-snapshot"},
+			[]string{"pending", "running"}).
+		Count(&count)
+	if count > 0 {
+		return errors.New("实例已有快照任务正在进行")
+	}
+	return nil
+}
+
+// createSnapshotTask 创建快照相关任务
+func createSnapshotTask(userID uint, instance *providerModel.Instance, snapshotID uint, taskType string) (*adminModel.Task, error) {
+	taskData, err := json.Marshal(adminModel.SnapshotTaskRequest{
+		SnapshotID: snapshotID,
+		InstanceID: instance.ID,
+		ProviderID: instance.ProviderID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("序列化任务数据失败: %v", err)
+	}
+
+	taskService := getTaskService()
+	return taskService.CreateTask(userID, &instance.ProviderID, &instance.ID, taskType, string(taskData), 1200)
+}
+
+// ListInstanceSnapshots 获取实例快照列表
+func (s *Service) ListInstanceSnapshots(userID, instanceID uint) (*userModel.SnapshotListResponse, error) {
+	if _, err := s.getSnapshotInstance(userID, instanceID); err != nil {
+		return nil, err
+	}
+
+	var snapshots []providerModel.InstanceSnapshot
+	if err := global.APP_DB.Where("instance_id = ? AND user_id = ?", instanceID, userID).
+		Order("created_at DESC").Find(&snapshots).Error; err != nil {
+		return nil, fmt.Errorf("获取快照列表失败: %v", err)
+	}
+
+	var user userModel.User
+	if err := global.APP_DB.Select("level").First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %v", err)
+	}
+
+	return &userModel.SnapshotListResponse{
+		Snapshots:    snapshots,
+		MaxSnapshots: GetUserMaxSnapshots(user.Level),
+	}, nil
+}
+
+// CreateInstanceSnapshot 创建实例快照
+func (s *Service) CreateInstanceSnapshot(userID, instanceID uint, req userModel.CreateSnapshotRequest) (*userModel.SnapshotTaskResponse, error) {
+	instance, err := s.getSnapshotInstance(userID, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSnapshotOperable(instance); err != nil {
+		return nil, err
+	}
+
+	name := req.Name
+	if name == "" {
+		name = fmt.Sprintf("snap-%s", time.Now().Format("20060102150405"))
+	}
+	if !provider.IsValidSnapshotName(name) {
+		return nil, errors.New("快照名称只能包含字母、数字、下划线和短横线，且必须以字母开头")
+	}
+
+	var user userModel.User
+	if err := global.APP_DB.Select("level").First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %v", err)
+	}
+	maxSnapshots := GetUserMaxSnapshots(user.Level)
+
+	var snapshot providerModel.InstanceSnapshot
+	err = global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		// 在事务中检查数量和重名，避免并发创建超出限制
+		var count int64
+		if err := tx.Model(&providerModel.InstanceSnapshot{}).Where("instance_id = ?", instance.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) >= maxSnapshots {
+			return fmt.Errorf("快照数量已达上限（%d个），请先删除旧快照", maxSnapshots)
+		}
+
+		var sameName int64
+		tx.Model(&providerModel.InstanceSnapshot{}).Where("instance_id = ? AND name = ?", instance.ID, name).Count(&sameName)
+		if sameName > 0 {
+			return errors.New("快照名称已存在")
+		}
+
+		snapshot = providerModel.InstanceSnapshot{
+			InstanceID:  instance.ID,
+			ProviderID:  instance.ProviderID,
+			UserID:      userID,
+			Name:        name,
+			Description: req.Description,
+			Status:      "creating",
+		}
+		return tx.Create(&snapshot).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := createSnapshotTask(userID, instance, snapshot.ID, "create-snapshot")
+	if err != nil {
+		global.APP_DB.Delete(&snapshot)
+		return nil, fmt.Errorf("创建快照任务失败: %v", err)
+	}
+	global.APP_DB.Model(&snapshot).Update("task_id", task.ID)
+
+	global.APP_LOG.Info("用户创建实例快照任务",
+		zap.Uint("userID", userID),
+		zap.Uint("instanceID", instance.ID),
+		zap.String("snapshot", name),
+		zap.Uint("taskID", task.ID))
+
+	return &userModel.SnapshotTaskResponse{SnapshotID: snapshot.ID, TaskID: task.ID}, nil
+}
+
+// RestoreInstanceSnapshot 恢复实例快照
+func (s *Service) RestoreInstanceSnapshot(userID, instanceID, snapshotID uint) (*userModel.SnapshotTaskResponse, error) {
+	return s.snapshotOperation(userID, instanceID, snapshotID, "restore-snapshot", "restoring")
+}
+
+// DeleteInstanceSnapshot 删除实例快照
+func (s *Service) DeleteInstanceSnapshot(userID, instanceID, snapshotID uint) (*userModel.SnapshotTaskResponse, error) {
+	return s.snapshotOperation(userID, instanceID, snapshotID, "delete-snapshot", "deleting")
+}
+
+// snapshotOperation 对已有快照创建恢复或删除任务
+func (s *Service) snapshotOperation(userID, instanceID, snapshotID uint, taskType, snapshotStatus string) (*userModel.SnapshotTaskResponse, error) {
+	instance, err := s.getSnapshotInstance(userID, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot providerModel.InstanceSnapshot
+	if err := global.APP_DB.Where("id = ? AND instance_id = ? AND user_id = ?", snapshotID, instanceID, userID).First(&snapshot).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("快照不存在")
+		}
+		return nil, err
+	}
+
+	// 删除失败的快照时不要求实例状态，其余操作需要实例处于稳定状态
+	if taskType == "restore-snapshot" || snapshot.Status != "failed" {
+		if err := checkSnapshotOperable(instance); err != nil {
+			return nil, err
+		}
+	}
+	if taskType == "restore-snapshot" && snapshot.Status != "available" {
+		return nil, errors.New("快照当前不可用于恢复")
+	}
+
+	task, err := createSnapshotTask(userID, instance, snapshot.ID, taskType)
+	if err != nil {
+		return nil, fmt.Errorf("创建快照任务失败: %v", err)
+	}
+	updates := map[string]interface{}{"task_id": task.ID}
+	// 创建失败的快照保留failed状态，删除任务据此跳过Provider侧删除
+	if snapshot.Status != "failed" {
+		updates["status"] = snapshotStatus
+	}
+	global.APP_DB.Model(&snapshot).Updates(updates)
+
+	global.APP_LOG.Info("用户创建实例快照任务",
+		zap.Uint("userID", userID),
+		zap.Uint("instanceID", instance.ID),
+		zap.Uint("snapshotID", snapshot.ID),
+		zap.String("taskType", taskType),
+		zap.Uint("taskID", task.ID))
+
+	return &userModel.SnapshotTaskResponse{SnapshotID: snapshot.ID, TaskID: task.ID}, nil
+}
@@ -0,0 +1,287 @@
+package instance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	providerModel "oneclickvirt/model/provider"
+	"oneclickvirt/provider"
+	providerService "oneclickvirt/service/provider"
+	"oneclickvirt/utils"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 终端会话结束原因
+const (
+	TerminalCloseClient   = "client_closed"
+	TerminalCloseExited   = "shell_exited"
+	TerminalCloseIdle     = "idle_timeout"
+	TerminalCloseDuration = "max_duration"
+	TerminalCloseError    = "error"
+)
+
+// terminalWatchInterval 检查空闲和最长持续时间的间隔
+const terminalWatchInterval = 30 * time.Second
+
+// terminalSessionCounter 记录每个用户当前打开的终端会话数量
+var terminalSessionCounter = struct {
+	sync.Mutex
+	counts map[uint]int
+}{counts: make(map[uint]int)}
+
+// GetTerminalMaxSessions 获取每个用户同时打开的终端会话上限
+func GetTerminalMaxSessions() int {
+	if n := global.APP_CONFIG.Terminal.MaxSessionsPerUser; n > 0 {
+		return n
+	}
+	return 3
+}
+
+// GetTerminalIdleTimeout 获取终端无输入空闲超时时间
+func GetTerminalIdleTimeout() time.Duration {
+	if n := global.APP_CONFIG.Terminal.IdleTimeout; n > 0 {
+		return time.Duration(n) * time.Minute
+	}
+	return 15 * time.Minute
+}
+
+// GetTerminalMaxDuration 获取单个终端会话的最长持续时间
+func GetTerminalMaxDuration() time.Duration {
+	if n := global.APP_CONFIG.Terminal.MaxDuration; n > 0 {
+		return time.Duration(n) * time.Minute
+	}
+	return 240 * time.Minute
+}
+
+// acquireTerminalSlot 占用一个用户终端会话名额
+func acquireTerminalSlot(userID uint) error {
+	terminalSessionCounter.Lock()
+	defer terminalSessionCounter.Unlock()
+
+	maxSessions := GetTerminalMaxSessions()
+	if terminalSessionCounter.counts[userID] >= maxSessions {
+		return fmt.Errorf("终端会话数量已达上限(%d)，请先关闭其他终端", maxSessions)
+	}
+	terminalSessionCounter.counts[userID]++
+	return nil
+}
+
+// releaseTerminalSlot 释放用户终端会话名额
+func releaseTerminalSlot(userID uint) {
+	terminalSessionCounter.Lock()
+	defer terminalSessionCounter.Unlock()
+
+	if terminalSessionCounter.counts[userID] <= 1 {
+		delete(terminalSessionCounter.counts, userID)
+		return
+	}
+	terminalSessionCounter.counts[userID]--
+}
+
+// TerminalClientInfo 发起终端会话的客户端信息，用于审计记录
+type TerminalClientInfo struct {
+	Username  string
+	Path      string
+	ClientIP  string
+	UserAgent string
+}
+
+// TerminalSession 实例Web终端会话，负责空闲超时、会话名额和审计记录
+type TerminalSession struct {
+	UserID       uint
+	InstanceID   uint
+	ProviderID   uint
+	InstanceName string
+	StartedAt    time.Time
+
+	terminal    *utils.SSHTerminal
+	client      TerminalClientInfo
+	lastActive  atomic.Int64
+	bytesIn     atomic.Int64
+	bytesOut    atomic.Int64
+	closeReason string
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+// Read 读取终端输出
+func (t *TerminalSession) Read(p []byte) (int, error) {
+	n, err := t.terminal.Read(p)
+	t.bytesOut.Add(int64(n))
+	return n, err
+}
+
+// Write 写入用户输入，同时刷新空闲计时
+func (t *TerminalSession) Write(p []byte) (int, error) {
+	t.lastActive.Store(time.Now().UnixNano())
+	n, err := t.terminal.Write(p)
+	t.bytesIn.Add(int64(n))
+	return n, err
+}
+
+// Resize 调整终端窗口大小
+func (t *TerminalSession) Resize(cols, rows int) error {
+	if cols <= 0 || rows <= 0 {
+		return nil
+	}
+	return t.terminal.Resize(cols, rows)
+}
+
+// Wait 等待实例内shell退出
+func (t *TerminalSession) Wait() error {
+	return t.terminal.Wait()
+}
+
+// Done 会话关闭时关闭的通道
+func (t *TerminalSession) Done() <-chan struct{} {
+	return t.done
+}
+
+// CloseReason 获取会话结束原因，会话未结束时为空
+func (t *TerminalSession) CloseReason() string {
+	select {
+	case <-t.done:
+		return t.closeReason
+	default:
+		return ""
+	}
+}
+
+// Close 关闭会话并写入审计记录，只有第一次调用生效
+func (t *TerminalSession) Close(reason string) {
+	t.closeOnce.Do(func() {
+		t.closeReason = reason
+		close(t.done)
+		t.terminal.Close()
+		releaseTerminalSlot(t.UserID)
+		t.recordAudit()
+
+		global.APP_LOG.Info("实例终端会话结束",
+			zap.Uint("userID", t.UserID),
+			zap.Uint("instanceID", t.InstanceID),
+			zap.String("reason", reason),
+			zap.Duration("duration", time.Since(t.StartedAt)))
+	})
+}
+
+// watch 按空闲超时和最长持续时间关闭会话
+func (t *TerminalSession) watch() {
+	idleTimeout := GetTerminalIdleTimeout()
+	deadline := time.NewTimer(GetTerminalMaxDuration())
+	defer deadline.Stop()
+	ticker := time.NewTicker(terminalWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-deadline.C:
+			t.Close(TerminalCloseDuration)
+			return
+		case <-ticker.C:
+			if time.Since(time.Unix(0, t.lastActive.Load())) >= idleTimeout {
+				t.Close(TerminalCloseIdle)
+				return
+			}
+		}
+	}
+}
+
+// recordAudit 将终端会话写入审计日志
+func (t *TerminalSession) recordAudit() {
+	duration := time.Since(t.StartedAt)
+	request, _ := json.Marshal(map[string]interface{}{
+		"instanceId":   t.InstanceID,
+		"instanceName": t.InstanceName,
+		"providerId":   t.ProviderID,
+		"startedAt":    t.StartedAt,
+	})
+	response, _ := json.Marshal(map[string]interface{}{
+		"closeReason": t.closeReason,
+		"durationSec": int64(duration.Seconds()),
+		"bytesIn":     t.bytesIn.Load(),
+		"bytesOut":    t.bytesOut.Load(),
+	})
+
+	userID := t.UserID
+	auditLog := adminModel.AuditLog{
+		UserID:     &userID,
+		Username:   t.client.Username,
+		Method:     "WS",
+		Path:       utils.TruncateString(t.client.Path, 255),
+		StatusCode: http.StatusSwitchingProtocols,
+		Latency:    duration.Milliseconds(),
+		ClientIP:   t.client.ClientIP,
+		UserAgent:  utils.TruncateString(t.client.UserAgent, 255),
+		Request:    string(request),
+		Response:   string(response),
+	}
+	if err := global.APP_DB.Create(&auditLog).Error; err != nil {
+		global.APP_LOG.Error("写入终端审计日志失败",
+			zap.Uint("userID", t.UserID),
+			zap.Uint("instanceID", t.InstanceID),
+			zap.Error(err))
+	}
+}
+
+// OpenInstanceTerminal 打开用户实例的Web终端会话
+func (s *Service) OpenInstanceTerminal(userID, instanceID uint, cols, rows int, client TerminalClientInfo) (*TerminalSession, error) {
+	var instance providerModel.Instance
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", instanceID, userID).First(&instance).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("实例不存在或无权限")
+		}
+		return nil, err
+	}
+	if instance.Status != "running" {
+		return nil, errors.New("实例未运行，无法打开终端")
+	}
+
+	if cols <= 0 || rows <= 0 {
+		cols, rows = provider.TerminalDefaultCols, provider.TerminalDefaultRows
+	}
+
+	if err := acquireTerminalSlot(userID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	providerApiService := &providerService.ProviderApiService{}
+	terminal, err := providerApiService.OpenTerminalByProviderID(ctx, instance.ProviderID, instance.Name, cols, rows)
+	if err != nil {
+		releaseTerminalSlot(userID)
+		return nil, err
+	}
+
+	session := &TerminalSession{
+		UserID:       userID,
+		InstanceID:   instance.ID,
+		ProviderID:   instance.ProviderID,
+		InstanceName: instance.Name,
+		StartedAt:    time.Now(),
+		terminal:     terminal,
+		client:       client,
+		done:         make(chan struct{}),
+	}
+	session.lastActive.Store(session.StartedAt.UnixNano())
+	go session.watch()
+
+	global.APP_LOG.Info("实例终端会话已建立",
+		zap.Uint("userID", userID),
+		zap.Uint("instanceID", instance.ID),
+		zap.String("clientIP", client.ClientIP))
+	return session, nil
+}
@@ -76,7 +76,7 @@ func (s *Service) ChangePassword(userID uint, oldPassword, newPassword string) e
 		return err
 	}
 
-	return global.APP_DB.Model(&user).Update("password", string(hashedPassword)).Error
+	return global.APP_DB.Model(&user).Updates(map[string]interface{}{"password": string(hashedPassword), "password_generated": false}).Error
 }
 
 // BatchDeleteUsers 批量删除用户
@@ -22,6 +22,7 @@ import (
 	providerService "oneclickvirt/service/provider"
 	"oneclickvirt/service/resources"
 	"oneclickvirt/service/traffic"
+	"oneclickvirt/service/user/sshkey"
 	"oneclickvirt/service/vnstat"
 
 	"go.uber.org/zap"
@@ -198,6 +199,28 @@ func (s *Service) CreateUserInstance(userID uint, req userModel.CreateInstanceRe
 		return nil, err
 	}
 
+	// 验证选择的SSH公钥属于当前用户
+	if err := sshkey.NewService().ValidateSelection(userID, req.SSHKeyIds, req.DisablePassword); err != nil {
+		global.APP_LOG.Error("SSH公钥验证失败",
+			zap.Uint("userID", userID),
+			zap.Uints("sshKeyIds", req.SSHKeyIds),
+			zap.Error(err))
+		return nil, err
+	}
+
+	// 验证用户数据（cloud-config或shell脚本）
+	userData, err := s.validateInstanceUserData(req.UserData, &systemImage, &provider)
+	if err != nil {
+		global.APP_LOG.Error("用户数据验证失败",
+			zap.Uint("userID", userID),
+			zap.String("providerType", provider.Type),
+			zap.String("instanceType", systemImage.InstanceType),
+			zap.Int("size", len(req.UserData)),
+			zap.Error(err))
+		return nil, err
+	}
+	req.UserData = userData
+
 	global.APP_LOG.Info("所有验证通过，开始创建实例",
 		zap.Uint("userID", userID),
 		zap.Uint("providerId", req.ProviderId),
@@ -300,15 +323,29 @@ func (s *Service) createInstanceWithMinimalTransaction(userID uint, req *userMod
 		}
 
 		// 2. 创建任务
-		taskData := fmt.Sprintf(`{"providerId":%d,"imageId":%d,"cpuId":"%s","memoryId":"%s","diskId":"%s","bandwidthId":"%s","description":"%s","sessionId":"%s"}`,
-			req.ProviderId, req.ImageId, req.CPUId, req.MemoryId, req.DiskId, req.BandwidthId, req.Description, sessionID)
+		taskData, err := json.Marshal(adminModel.CreateInstanceTaskRequest{
+			ProviderId:      req.ProviderId,
+			ImageId:         req.ImageId,
+			CPUId:           req.CPUId,
+			MemoryId:        req.MemoryId,
+			DiskId:          req.DiskId,
+			BandwidthId:     req.BandwidthId,
+			Description:     req.Description,
+			SessionId:       sessionID,
+			SSHKeyIds:       req.SSHKeyIds,
+			DisablePassword: req.DisablePassword,
+			UserData:        req.UserData,
+		})
+		if err != nil {
+			return fmt.Errorf("序列化任务数据失败: %v", err)
+		}
 
 		// 在事务中创建任务
 		newTask := &adminModel.Task{
 			UserID:          userID,
 			ProviderID:      &req.ProviderId,
 			TaskType:        "create",
-			TaskData:        taskData,
+			TaskData:        string(taskData),
 			Status:          "pending",
 			TimeoutDuration: 1800,
 		}
@@ -474,6 +511,7 @@ func (s *Service) prepareInstanceCreation(ctx context.Context, task *adminModel.
 			UsedTraffic:        0,     // 初始已用流量为0
 			TrafficLimited:     false, // 显式设置为false，确保不会因流量误判为超限
 			TrafficLimitReason: "",    // 初始无限制原因
+			UserData:           taskReq.UserData,
 		}
 
 		// 创建实例
@@ -653,6 +691,7 @@ func (s *Service) executeProviderCreation(ctx context.Context, task *adminModel.
 		Disk:         fmt.Sprintf("%dm", diskSpec.SizeMB),   // 使用实际磁盘大小（MB格式）
 		InstanceType: instance.InstanceType,
 		ImageURL:     systemImage.URL, // 镜像URL用于下载
+		UserData:     instance.UserData,
 		Metadata: map[string]string{
 			"user_level":               fmt.Sprintf("%d", user.Level),              // 用户等级，用于带宽限制配置
 			"bandwidth_spec":           fmt.Sprintf("%d", bandwidthSpec.SpeedMbps), // 用户选择的带宽规格
@@ -1132,6 +1171,14 @@ func (s *Service) finalizeInstanceCreation(ctx context.Context, task *adminModel
 					zap.Int("existingPortCount", len(existingPorts)))
 			}
 
+			// nftables端口映射依赖实例内网IP，在IP写入数据库后统一下发
+			if err := portMappingService.ApplyNftablesMappings(context.Background(), providerID); err != nil {
+				global.APP_LOG.Warn("下发nftables端口映射失败",
+					zap.Uint("instanceId", instanceID),
+					zap.Uint("providerId", providerID),
+					zap.Error(err))
+			}
+
 			// 更新进度到80%
 			s.updateTaskProgress(taskID, 80, "正在初始化监控...")
 
@@ -1183,10 +1230,23 @@ func (s *Service) finalizeInstanceCreation(ctx context.Context, task *adminModel
 				}
 			}
 
+			// 4. 注入用户选择的SSH公钥
+			var keysRequested bool
+			var keyErr error
+			if currentInstance.ID != 0 {
+				keysRequested, keyErr = s.injectInstanceSSHKeys(task, &currentInstance)
+				if keyErr != nil {
+					global.APP_LOG.Error("实例SSH公钥注入失败",
+						zap.Uint("instanceId", instanceID),
+						zap.String("instanceName", currentInstance.Name),
+						zap.Error(keyErr))
+				}
+			}
+
 			// 更新进度到90%
 			s.updateTaskProgress(taskID, 90, "正在配置网络监控...")
 
-			// 4. 自动检测并设置vnstat接口（仅在vnStat初始化成功时执行）
+			// 5. 自动检测并设置vnstat接口（仅在vnStat初始化成功时执行）
 			if vnstatInitSuccess {
 				trafficService := &traffic.Service{}
 				if err := trafficService.AutoDetectVnstatInterface(instanceID); err != nil {
@@ -1205,7 +1265,7 @@ func (s *Service) finalizeInstanceCreation(ctx context.Context, task *adminModel
 			// 更新进度到95%
 			s.updateTaskProgress(taskID, 95, "正在启动流量同步...")
 
-			// 5. 触发流量同步（仅在vnStat初始化成功时执行）
+			// 6. 触发流量同步（仅在vnStat初始化成功时执行）
 			if vnstatInitSuccess {
 				syncTrigger := traffic.NewSyncTriggerService()
 				syncTrigger.TriggerInstanceTrafficSync(instanceID, "实例创建后初始同步")
@@ -1224,6 +1284,8 @@ func (s *Service) finalizeInstanceCreation(ctx context.Context, task *adminModel
 				global.APP_LOG.Warn("实例创建完成但SSH密码设置失败",
 					zap.Uint("instanceId", instanceID),
 					zap.String("instanceName", currentInstance.Name))
+			} else if keysRequested && keyErr != nil {
+				completionMessage = "实例创建成功，但SSH公钥注入失败，请手动配置公钥"
 			}
 
 			// 标记任务最终完成
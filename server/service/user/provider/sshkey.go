@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	providerModel "oneclickvirt/model/provider"
+	providerService "oneclickvirt/service/provider"
+	"oneclickvirt/service/user/sshkey"
+
+	"go.uber.org/zap"
+)
+
+// injectInstanceSSHKeys 将创建实例时选择的SSH公钥注入实例，未选择公钥时返回false
+// 公钥在执行时按ID重新读取，创建任务后被删除的公钥会导致注入失败
+func (s *Service) injectInstanceSSHKeys(task *adminModel.Task, instance *providerModel.Instance) (bool, error) {
+	var taskReq adminModel.CreateInstanceTaskRequest
+	if err := json.Unmarshal([]byte(task.TaskData), &taskReq); err != nil {
+		return false, fmt.Errorf("解析任务数据失败: %v", err)
+	}
+	if len(taskReq.SSHKeyIds) == 0 {
+		return false, nil
+	}
+
+	publicKeys, err := sshkey.NewService().ResolveKeys(task.UserID, taskReq.SSHKeyIds)
+	if err != nil {
+		return true, err
+	}
+
+	providerApiService := &providerService.ProviderApiService{}
+	maxRetries := 3
+	for i := 0; i < maxRetries; i++ {
+		err = providerApiService.SetInstanceSSHKeysByProviderID(context.Background(), instance.ProviderID, instance.Name, publicKeys, taskReq.DisablePassword)
+		if err == nil {
+			break
+		}
+		global.APP_LOG.Warn("注入SSH公钥失败，正在重试",
+			zap.Uint("instanceId", instance.ID),
+			zap.String("instanceName", instance.Name),
+			zap.Int("attempt", i+1),
+			zap.Int("maxRetries", maxRetries),
+			zap.Error(err))
+		if i < maxRetries-1 {
+			time.Sleep(15 * time.Second)
+		}
+	}
+	if err != nil {
+		return true, err
+	}
+
+	if taskReq.DisablePassword {
+		if err := global.APP_DB.Model(instance).Update("password_login_disabled", true).Error; err != nil {
+			global.APP_LOG.Warn("更新实例密码登录状态失败",
+				zap.Uint("instanceId", instance.ID),
+				zap.Error(err))
+		}
+	}
+	return true, nil
+}
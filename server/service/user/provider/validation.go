@@ -12,6 +12,7 @@ import (
 	providerModel "oneclickvirt/model/provider"
 	resourceModel "oneclickvirt/model/resource"
 	systemModel "oneclickvirt/model/system"
+	"oneclickvirt/provider"
 	"oneclickvirt/service/auth"
 	"oneclickvirt/service/resources"
 
@@ -241,6 +242,15 @@ func (s *Service) validateInstanceMinimumRequirements(image *systemModel.SystemI
 	return nil
 }
 
+// validateInstanceUserData 验证创建实例时提供的用户数据，返回规范化后的内容
+// Docker和Proxmox容器没有cloud-init，只接受shell脚本
+func (s *Service) validateInstanceUserData(userData string, image *systemModel.SystemImage, dbProvider *providerModel.Provider) (string, error) {
+	if err := provider.ValidateUserData(userData, dbProvider.Type, image.InstanceType); err != nil {
+		return "", err
+	}
+	return provider.NormalizeUserData(userData), nil
+}
+
 // validateCreateTaskPermissionsInTx 在事务中验证任务创建权限（三重验证）
 // 保持事务和行锁直到验证完成，防止并发创建导致超出配额
 func (s *Service) validateCreateTaskPermissionsInTx(tx *gorm.DB, userID uint, providerID uint, instanceType string,
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"oneclickvirt/service/database"
 	"oneclickvirt/service/resources"
+	"oneclickvirt/service/user/sshkey"
 	"time"
 
 	"oneclickvirt/global"
@@ -124,6 +125,11 @@ func (s *Service) ClaimResource(userID uint, req userModel.ClaimResourceRequest)
 		return nil, errors.New(quotaResult.Reason)
 	}
 
+	// 验证选择的SSH公钥属于当前用户
+	if err := sshkey.NewService().ValidateSelection(userID, req.SSHKeyIds, req.DisablePassword); err != nil {
+		return nil, err
+	}
+
 	// 验证提供商
 	var provider providerModel.Provider
 	if err := global.APP_DB.First(&provider, req.ProviderID).Error; err != nil {
@@ -0,0 +1,415 @@
+package securitygroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	providerModel "oneclickvirt/model/provider"
+	userModel "oneclickvirt/model/user"
+	providerApi "oneclickvirt/provider"
+	providerService "oneclickvirt/service/provider"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	maxGroupsPerUser     = 20 // 每个用户最多创建的安全组数量
+	maxRulesPerGroup     = 50 // 每个安全组最多的规则数量
+	maxGroupsPerInstance = 5  // 每个实例最多绑定的安全组数量
+)
+
+// syncTimeout 单个实例下发防火墙规则的超时时间
+const syncTimeout = 2 * time.Minute
+
+// Service 安全组管理服务
+type Service struct{}
+
+// NewService 创建安全组服务
+func NewService() *Service {
+	return &Service{}
+}
+
+// ListGroups 获取用户的安全组及规则
+func (s *Service) ListGroups(userID uint) ([]providerModel.SecurityGroup, error) {
+	var groups []providerModel.SecurityGroup
+	if err := global.APP_DB.Preload("Rules").Where("user_id = ?", userID).Order("id ASC").Find(&groups).Error; err != nil {
+		return nil, fmt.Errorf("获取安全组列表失败: %v", err)
+	}
+	return groups, nil
+}
+
+// CreateGroup 创建安全组
+func (s *Service) CreateGroup(userID uint, req userModel.SecurityGroupRequest) (*providerModel.SecurityGroup, error) {
+	rules, err := normalizeRules(req.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := global.APP_DB.Model(&providerModel.SecurityGroup{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("获取安全组数量失败: %v", err)
+	}
+	if count >= maxGroupsPerUser {
+		return nil, fmt.Errorf("安全组数量已达上限 %d 个", maxGroupsPerUser)
+	}
+
+	group := providerModel.SecurityGroup{
+		UserID:      userID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Rules:       rules,
+	}
+	if err := global.APP_DB.Create(&group).Error; err != nil {
+		return nil, fmt.Errorf("保存安全组失败: %v", err)
+	}
+
+	global.APP_LOG.Info("用户创建安全组",
+		zap.Uint("userId", userID),
+		zap.Uint("groupId", group.ID),
+		zap.Int("ruleCount", len(rules)))
+	return &group, nil
+}
+
+// UpdateGroup 更新安全组并整体替换规则，已绑定的实例在后台重新下发
+func (s *Service) UpdateGroup(userID, groupID uint, req userModel.SecurityGroupRequest) (*providerModel.SecurityGroup, error) {
+	group, err := s.getGroup(userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := normalizeRules(req.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	err = global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(group).Updates(map[string]interface{}{
+			"name":        strings.TrimSpace(req.Name),
+			"description": strings.TrimSpace(req.Description),
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("security_group_id = ?", group.ID).Delete(&providerModel.SecurityGroupRule{}).Error; err != nil {
+			return err
+		}
+		for i := range rules {
+			rules[i].SecurityGroupID = group.ID
+		}
+		if len(rules) > 0 {
+			return tx.Create(&rules).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("更新安全组失败: %v", err)
+	}
+
+	var instanceIDs []uint
+	global.APP_DB.Model(&providerModel.InstanceSecurityGroup{}).Where("security_group_id = ?", group.ID).Pluck("instance_id", &instanceIDs)
+	syncInstancesAsync(instanceIDs)
+
+	global.APP_LOG.Info("用户更新安全组",
+		zap.Uint("userId", userID),
+		zap.Uint("groupId", group.ID),
+		zap.Int("ruleCount", len(rules)),
+		zap.Int("instanceCount", len(instanceIDs)))
+	return s.getGroup(userID, groupID)
+}
+
+// DeleteGroup 删除安全组，仍绑定在实例上时不允许删除
+func (s *Service) DeleteGroup(userID, groupID uint) error {
+	group, err := s.getGroup(userID, groupID)
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	if err := global.APP_DB.Model(&providerModel.InstanceSecurityGroup{}).Where("security_group_id = ?", group.ID).Count(&count).Error; err != nil {
+		return fmt.Errorf("检查安全组绑定失败: %v", err)
+	}
+	if count > 0 {
+		return errors.New("安全组正在被实例使用，请先解绑")
+	}
+
+	err = global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("security_group_id = ?", group.ID).Delete(&providerModel.SecurityGroupRule{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(group).Error
+	})
+	if err != nil {
+		return fmt.Errorf("删除安全组失败: %v", err)
+	}
+
+	global.APP_LOG.Info("用户删除安全组",
+		zap.Uint("userId", userID),
+		zap.Uint("groupId", groupID))
+	return nil
+}
+
+// GetInstanceGroups 获取实例绑定的安全组
+func (s *Service) GetInstanceGroups(userID, instanceID uint) ([]providerModel.SecurityGroup, error) {
+	if _, err := getUserInstance(userID, instanceID); err != nil {
+		return nil, err
+	}
+	return instanceGroups(instanceID)
+}
+
+// SetInstanceGroups 整体替换实例绑定的安全组并立即下发到节点
+// 数据库中的绑定关系是期望状态，下发失败时保留绑定，可调用同步接口或在实例重启时重试
+func (s *Service) SetInstanceGroups(userID, instanceID uint, groupIDs []uint) ([]providerModel.SecurityGroup, error) {
+	instance, err := getUserInstance(userID, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if instance.Status != "running" && instance.Status != "stopped" {
+		return nil, errors.New("实例当前状态不允许修改安全组")
+	}
+
+	ids := make([]uint, 0, len(groupIDs))
+	seen := make(map[uint]bool, len(groupIDs))
+	for _, id := range groupIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > maxGroupsPerInstance {
+		return nil, fmt.Errorf("每个实例最多绑定 %d 个安全组", maxGroupsPerInstance)
+	}
+	if len(ids) > 0 {
+		var count int64
+		if err := global.APP_DB.Model(&providerModel.SecurityGroup{}).Where("user_id = ? AND id IN (?)", userID, ids).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("获取安全组失败: %v", err)
+		}
+		if int(count) != len(ids) {
+			return nil, errors.New("安全组不存在或无权限")
+		}
+	}
+
+	err = global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("instance_id = ?", instance.ID).Delete(&providerModel.InstanceSecurityGroup{}).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := tx.Create(&providerModel.InstanceSecurityGroup{InstanceID: instance.ID, SecurityGroupID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存实例安全组失败: %v", err)
+	}
+
+	global.APP_LOG.Info("用户设置实例安全组",
+		zap.Uint("userId", userID),
+		zap.Uint("instanceId", instance.ID),
+		zap.Uints("groupIds", ids))
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+	if err := SyncInstanceFirewall(ctx, instance.ID); err != nil {
+		return nil, fmt.Errorf("安全组已保存，但下发到节点失败: %v", err)
+	}
+	return instanceGroups(instance.ID)
+}
+
+// SyncInstance 按当前绑定重新下发实例的防火墙规则
+func (s *Service) SyncInstance(userID, instanceID uint) error {
+	if _, err := getUserInstance(userID, instanceID); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+	return SyncInstanceFirewall(ctx, instanceID)
+}
+
+// SyncInstanceFirewall 将实例绑定的全部安全组规则下发到实例所在节点，没有绑定时移除过滤
+func SyncInstanceFirewall(ctx context.Context, instanceID uint) error {
+	var instance providerModel.Instance
+	if err := global.APP_DB.Select("id", "name", "provider_id").First(&instance, instanceID).Error; err != nil {
+		return errors.New("实例不存在")
+	}
+
+	rules, err := instanceRules(instanceID)
+	if err != nil {
+		return err
+	}
+
+	providerApiService := &providerService.ProviderApiService{}
+	return providerApiService.SetInstanceFirewallByProviderID(ctx, instance.ProviderID, instance.Name, rules)
+}
+
+// ResyncInstanceFirewall 实例启动、重启、重置或迁移后调用：绑定了安全组时重新下发规则，失败只记录日志
+func ResyncInstanceFirewall(instanceID uint) {
+	var count int64
+	if err := global.APP_DB.Model(&providerModel.InstanceSecurityGroup{}).Where("instance_id = ?", instanceID).Count(&count).Error; err != nil || count == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+	if err := SyncInstanceFirewall(ctx, instanceID); err != nil {
+		global.APP_LOG.Warn("重新下发实例安全组失败",
+			zap.Uint("instanceId", instanceID),
+			zap.Error(err))
+	}
+}
+
+// ClearInstanceFirewall 移除指定节点上实例的防火墙配置，用于迁移后清理源节点，失败只记录日志
+func ClearInstanceFirewall(providerID uint, instanceName string) {
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+	providerApiService := &providerService.ProviderApiService{}
+	if err := providerApiService.SetInstanceFirewallByProviderID(ctx, providerID, instanceName, nil); err != nil {
+		global.APP_LOG.Warn("清理节点上的实例防火墙失败",
+			zap.Uint("providerId", providerID),
+			zap.String("instanceName", instanceName),
+			zap.Error(err))
+	}
+}
+
+// HasInstanceGroups 实例是否绑定了安全组
+func HasInstanceGroups(instanceID uint) bool {
+	var count int64
+	global.APP_DB.Model(&providerModel.InstanceSecurityGroup{}).Where("instance_id = ?", instanceID).Count(&count)
+	return count > 0
+}
+
+// CleanupInstanceGroups 实例删除后调用：删除绑定关系，并在后台移除节点上残留的ACL或规则链
+func CleanupInstanceGroups(instance *providerModel.Instance) {
+	if !HasInstanceGroups(instance.ID) {
+		return
+	}
+	if err := global.APP_DB.Where("instance_id = ?", instance.ID).Delete(&providerModel.InstanceSecurityGroup{}).Error; err != nil {
+		global.APP_LOG.Warn("删除实例安全组绑定失败",
+			zap.Uint("instanceId", instance.ID),
+			zap.Error(err))
+	}
+	go ClearInstanceFirewall(instance.ProviderID, instance.Name)
+}
+
+// syncInstancesAsync 在后台依次重新下发多个实例的规则，失败只记录日志
+func syncInstancesAsync(instanceIDs []uint) {
+	if len(instanceIDs) == 0 {
+		return
+	}
+	go func() {
+		for _, instanceID := range instanceIDs {
+			ResyncInstanceFirewall(instanceID)
+		}
+	}()
+}
+
+// normalizeRules 校验并规范化规则：tcp/udp端口0表示全部端口，icmp和all不区分端口，单个IP转换为地址段
+func normalizeRules(reqs []userModel.SecurityGroupRuleRequest) ([]providerModel.SecurityGroupRule, error) {
+	if len(reqs) > maxRulesPerGroup {
+		return nil, fmt.Errorf("每个安全组最多 %d 条规则", maxRulesPerGroup)
+	}
+
+	rules := make([]providerModel.SecurityGroupRule, 0, len(reqs))
+	for i, req := range reqs {
+		rule := providerModel.SecurityGroupRule{
+			Action:      req.Action,
+			Protocol:    req.Protocol,
+			Description: strings.TrimSpace(req.Description),
+		}
+
+		if req.Protocol == "tcp" || req.Protocol == "udp" {
+			switch {
+			case req.PortStart == 0 && req.PortEnd != 0:
+				return nil, fmt.Errorf("第 %d 条规则的端口范围不合法", i+1)
+			case req.PortEnd == 0:
+				rule.PortStart, rule.PortEnd = req.PortStart, req.PortStart
+			case req.PortEnd < req.PortStart:
+				return nil, fmt.Errorf("第 %d 条规则的端口范围不合法", i+1)
+			default:
+				rule.PortStart, rule.PortEnd = req.PortStart, req.PortEnd
+			}
+		}
+
+		if cidr := strings.TrimSpace(req.CIDR); cidr != "" {
+			if !strings.Contains(cidr, "/") {
+				ip := net.ParseIP(cidr)
+				if ip == nil {
+					return nil, fmt.Errorf("第 %d 条规则的来源地址不合法", i+1)
+				}
+				if ip.To4() != nil {
+					cidr += "/32"
+				} else {
+					cidr += "/128"
+				}
+			}
+			_, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 条规则的来源地址不合法", i+1)
+			}
+			rule.CIDR = ipNet.String()
+		}
+
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// instanceRules 汇总实例绑定的全部安全组规则
+func instanceRules(instanceID uint) ([]providerApi.FirewallRule, error) {
+	var rules []providerModel.SecurityGroupRule
+	if err := global.APP_DB.Model(&providerModel.SecurityGroupRule{}).
+		Joins("JOIN instance_security_groups ON instance_security_groups.security_group_id = security_group_rules.security_group_id").
+		Where("instance_security_groups.instance_id = ?", instanceID).
+		Order("security_group_rules.id ASC").
+		Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("获取安全组规则失败: %v", err)
+	}
+
+	result := make([]providerApi.FirewallRule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, providerApi.FirewallRule{
+			Action:    rule.Action,
+			Protocol:  rule.Protocol,
+			PortStart: rule.PortStart,
+			PortEnd:   rule.PortEnd,
+			CIDR:      rule.CIDR,
+		})
+	}
+	return result, nil
+}
+
+// instanceGroups 获取实例绑定的安全组及规则
+func instanceGroups(instanceID uint) ([]providerModel.SecurityGroup, error) {
+	var groups []providerModel.SecurityGroup
+	if err := global.APP_DB.Preload("Rules").
+		Where("id IN (?)", global.APP_DB.Model(&providerModel.InstanceSecurityGroup{}).Select("security_group_id").Where("instance_id = ?", instanceID)).
+		Order("id ASC").Find(&groups).Error; err != nil {
+		return nil, fmt.Errorf("获取实例安全组失败: %v", err)
+	}
+	return groups, nil
+}
+
+// getUserInstance 获取属于用户的实例
+func getUserInstance(userID, instanceID uint) (*providerModel.Instance, error) {
+	var instance providerModel.Instance
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", instanceID, userID).First(&instance).Error; err != nil {
+		return nil, errors.New("实例不存在或无权限")
+	}
+	return &instance, nil
+}
+
+// getGroup 获取属于用户的安全组
+func (s *Service) getGroup(userID, groupID uint) (*providerModel.SecurityGroup, error) {
+	var group providerModel.SecurityGroup
+	if err := global.APP_DB.Preload("Rules").Where("id = ? AND user_id = ?", groupID, userID).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("安全组不存在")
+		}
+		return nil, fmt.Errorf("获取安全组失败: %v", err)
+	}
+	return &group, nil
+}
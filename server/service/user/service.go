@@ -8,11 +8,14 @@ import (
 	"oneclickvirt/service/user/profile"
 	"oneclickvirt/service/user/provider"
 	"oneclickvirt/service/user/resource"
+	"oneclickvirt/service/user/securitygroup"
+	"oneclickvirt/service/user/sshkey"
 
 	adminModel "oneclickvirt/model/admin"
 	"oneclickvirt/model/auth"
 	providerModel "oneclickvirt/model/provider"
 	userModel "oneclickvirt/model/user"
+	providerApi "oneclickvirt/provider"
 )
 
 // Service 用户服务聚合层，维持向后兼容性
@@ -23,6 +26,8 @@ type Service struct {
 	notification *notification.Service
 	resource     *resource.Service
 	provider     *provider.Service
+	sshKey       *sshkey.Service
+	secGroup     *securitygroup.Service
 }
 
 // NewService 创建用户服务实例
@@ -34,6 +39,8 @@ func NewService() *Service {
 		notification: notification.NewService(),
 		resource:     resource.NewService(),
 		provider:     provider.NewService(),
+		sshKey:       sshkey.NewService(),
+		secGroup:     securitygroup.NewService(),
 	}
 }
 
@@ -76,6 +83,91 @@ func (s *Service) PerformInstanceAction(userID uint, req userModel.InstanceActio
 	return s.instance.PerformInstanceAction(userID, req)
 }
 
+// ListInstanceSnapshots 获取实例快照列表
+func (s *Service) ListInstanceSnapshots(userID, instanceID uint) (*userModel.SnapshotListResponse, error) {
+	return s.instance.ListInstanceSnapshots(userID, instanceID)
+}
+
+// CreateInstanceSnapshot 创建实例快照
+func (s *Service) CreateInstanceSnapshot(userID, instanceID uint, req userModel.CreateSnapshotRequest) (*userModel.SnapshotTaskResponse, error) {
+	return s.instance.CreateInstanceSnapshot(userID, instanceID, req)
+}
+
+// RestoreInstanceSnapshot 恢复实例快照
+func (s *Service) RestoreInstanceSnapshot(userID, instanceID, snapshotID uint) (*userModel.SnapshotTaskResponse, error) {
+	return s.instance.RestoreInstanceSnapshot(userID, instanceID, snapshotID)
+}
+
+// DeleteInstanceSnapshot 删除实例快照
+func (s *Service) DeleteInstanceSnapshot(userID, instanceID, snapshotID uint) (*userModel.SnapshotTaskResponse, error) {
+	return s.instance.DeleteInstanceSnapshot(userID, instanceID, snapshotID)
+}
+
+// CreatePortMapping 为实例添加端口映射
+func (s *Service) CreatePortMapping(userID, instanceID uint, req userModel.CreatePortMappingRequest) (*userModel.PortMappingTaskResponse, error) {
+	return s.instance.CreatePortMapping(userID, instanceID, req)
+}
+
+// DeletePortMapping 删除实例上手动添加的端口映射
+func (s *Service) DeletePortMapping(userID, instanceID, portID uint) (*userModel.PortMappingTaskResponse, error) {
+	return s.instance.DeletePortMapping(userID, instanceID, portID)
+}
+
+// ListBackups 获取用户备份列表
+func (s *Service) ListBackups(userID, instanceID uint) (*userModel.BackupListResponse, error) {
+	return s.instance.ListBackups(userID, instanceID)
+}
+
+// CreateInstanceBackup 创建实例备份
+func (s *Service) CreateInstanceBackup(userID, instanceID uint, req userModel.CreateBackupRequest) (*userModel.BackupTaskResponse, error) {
+	return s.instance.CreateInstanceBackup(userID, instanceID, req)
+}
+
+// RestoreBackupAsNewInstance 将备份恢复为新实例
+func (s *Service) RestoreBackupAsNewInstance(userID, backupID uint) (*userModel.BackupTaskResponse, error) {
+	return s.instance.RestoreBackupAsNewInstance(userID, backupID)
+}
+
+// DeleteBackup 删除备份
+func (s *Service) DeleteBackup(userID, backupID uint) error {
+	return s.instance.DeleteBackup(userID, backupID)
+}
+
+// GetBackupSchedule 获取实例定时备份计划
+func (s *Service) GetBackupSchedule(userID, instanceID uint) (*providerModel.BackupSchedule, error) {
+	return s.instance.GetBackupSchedule(userID, instanceID)
+}
+
+// SaveBackupSchedule 设置实例定时备份计划
+func (s *Service) SaveBackupSchedule(userID, instanceID uint, req userModel.BackupScheduleRequest) (*providerModel.BackupSchedule, error) {
+	return s.instance.SaveBackupSchedule(userID, instanceID, req)
+}
+
+// DeleteBackupSchedule 删除实例定时备份计划
+func (s *Service) DeleteBackupSchedule(userID, instanceID uint) error {
+	return s.instance.DeleteBackupSchedule(userID, instanceID)
+}
+
+// ResizeInstance 调整实例规格
+func (s *Service) ResizeInstance(userID, instanceID uint, req userModel.ResizeInstanceRequest) (*userModel.ResizeTaskResponse, error) {
+	return s.instance.ResizeInstance(userID, instanceID, req)
+}
+
+// OpenInstanceTerminal 打开实例Web终端会话
+func (s *Service) OpenInstanceTerminal(userID, instanceID uint, cols, rows int, client instance.TerminalClientInfo) (*instance.TerminalSession, error) {
+	return s.instance.OpenInstanceTerminal(userID, instanceID, cols, rows, client)
+}
+
+// CreateInstanceConsole 创建实例图形控制台会话
+func (s *Service) CreateInstanceConsole(userID, instanceID uint) (*userModel.ConsoleResponse, error) {
+	return s.instance.CreateInstanceConsole(userID, instanceID)
+}
+
+// AttachInstanceConsole 接入实例图形控制台会话
+func (s *Service) AttachInstanceConsole(userID, instanceID uint, ticket string) (*providerApi.ConsoleStream, error) {
+	return s.instance.AttachInstanceConsole(userID, instanceID, ticket)
+}
+
 // ===== 用户资料管理相关方法 =====
 
 // UpdateProfile 更新用户资料
@@ -125,6 +217,65 @@ func (s *Service) ResetPasswordAndNotify(userID uint) (string, error) {
 	return s.notification.ResetPasswordAndNotify(userID)
 }
 
+// ===== SSH公钥管理相关方法 =====
+
+// ListSSHKeys 获取用户SSH公钥列表
+func (s *Service) ListSSHKeys(userID uint) ([]userModel.UserSSHKey, error) {
+	return s.sshKey.ListKeys(userID)
+}
+
+// CreateSSHKey 添加SSH公钥
+func (s *Service) CreateSSHKey(userID uint, req userModel.SSHKeyRequest) (*userModel.UserSSHKey, error) {
+	return s.sshKey.CreateKey(userID, req)
+}
+
+// UpdateSSHKey 更新SSH公钥
+func (s *Service) UpdateSSHKey(userID, keyID uint, req userModel.SSHKeyRequest) (*userModel.UserSSHKey, error) {
+	return s.sshKey.UpdateKey(userID, keyID, req)
+}
+
+// DeleteSSHKey 删除SSH公钥
+func (s *Service) DeleteSSHKey(userID, keyID uint) error {
+	return s.sshKey.DeleteKey(userID, keyID)
+}
+
+// ===== 安全组相关方法 =====
+
+// ListSecurityGroups 获取用户安全组列表
+func (s *Service) ListSecurityGroups(userID uint) ([]providerModel.SecurityGroup, error) {
+	return s.secGroup.ListGroups(userID)
+}
+
+// CreateSecurityGroup 创建安全组
+func (s *Service) CreateSecurityGroup(userID uint, req userModel.SecurityGroupRequest) (*providerModel.SecurityGroup, error) {
+	return s.secGroup.CreateGroup(userID, req)
+}
+
+// UpdateSecurityGroup 更新安全组
+func (s *Service) UpdateSecurityGroup(userID, groupID uint, req userModel.SecurityGroupRequest) (*providerModel.SecurityGroup, error) {
+	return s.secGroup.UpdateGroup(userID, groupID, req)
+}
+
+// DeleteSecurityGroup 删除安全组
+func (s *Service) DeleteSecurityGroup(userID, groupID uint) error {
+	return s.secGroup.DeleteGroup(userID, groupID)
+}
+
+// GetInstanceSecurityGroups 获取实例绑定的安全组
+func (s *Service) GetInstanceSecurityGroups(userID, instanceID uint) ([]providerModel.SecurityGroup, error) {
+	return s.secGroup.GetInstanceGroups(userID, instanceID)
+}
+
+// SetInstanceSecurityGroups 设置实例绑定的安全组
+func (s *Service) SetInstanceSecurityGroups(userID, instanceID uint, groupIDs []uint) ([]providerModel.SecurityGroup, error) {
+	return s.secGroup.SetInstanceGroups(userID, instanceID, groupIDs)
+}
+
+// SyncInstanceSecurityGroups 重新下发实例的安全组规则
+func (s *Service) SyncInstanceSecurityGroups(userID, instanceID uint) error {
+	return s.secGroup.SyncInstance(userID, instanceID)
+}
+
 // ===== 资源管理相关方法 =====
 
 // GetAvailableResources 获取可用资源列表
@@ -0,0 +1,215 @@
+package sshkey
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"oneclickvirt/global"
+	userModel "oneclickvirt/model/user"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+// maxKeysPerUser 每个用户最多保存的公钥数量
+const maxKeysPerUser = 20
+
+// Service 用户SSH公钥管理服务
+type Service struct{}
+
+// NewService 创建SSH公钥服务
+func NewService() *Service {
+	return &Service{}
+}
+
+// parsedKey 解析后的公钥信息
+type parsedKey struct {
+	publicKey   string
+	keyType     string
+	fingerprint string
+}
+
+// parsePublicKey 校验并规范化authorized_keys格式的公钥，只接受单个公钥且不允许携带options
+func parsePublicKey(raw string) (*parsedKey, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, errors.New("公钥不能为空")
+	}
+	if strings.ContainsAny(raw, "\r\n") {
+		return nil, errors.New("每次只能添加一个公钥")
+	}
+
+	pub, comment, options, _, err := ssh.ParseAuthorizedKey([]byte(raw))
+	if err != nil {
+		return nil, errors.New("公钥格式不合法")
+	}
+	if len(options) > 0 {
+		return nil, errors.New("公钥不能包含options")
+	}
+
+	normalized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+	if comment = strings.TrimSpace(comment); comment != "" {
+		normalized += " " + comment
+	}
+
+	return &parsedKey{
+		publicKey:   normalized,
+		keyType:     pub.Type(),
+		fingerprint: ssh.FingerprintSHA256(pub),
+	}, nil
+}
+
+// ListKeys 获取用户的SSH公钥列表
+func (s *Service) ListKeys(userID uint) ([]userModel.UserSSHKey, error) {
+	var keys []userModel.UserSSHKey
+	if err := global.APP_DB.Where("user_id = ?", userID).Order("id ASC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("获取SSH公钥列表失败: %v", err)
+	}
+	return keys, nil
+}
+
+// CreateKey 添加SSH公钥
+func (s *Service) CreateKey(userID uint, req userModel.SSHKeyRequest) (*userModel.UserSSHKey, error) {
+	parsed, err := parsePublicKey(req.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := global.APP_DB.Model(&userModel.UserSSHKey{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("获取SSH公钥数量失败: %v", err)
+	}
+	if count >= maxKeysPerUser {
+		return nil, fmt.Errorf("SSH公钥数量已达上限 %d 个", maxKeysPerUser)
+	}
+	if err := s.checkDuplicate(userID, 0, parsed.fingerprint); err != nil {
+		return nil, err
+	}
+
+	key := userModel.UserSSHKey{
+		UserID:      userID,
+		Name:        strings.TrimSpace(req.Name),
+		PublicKey:   parsed.publicKey,
+		KeyType:     parsed.keyType,
+		Fingerprint: parsed.fingerprint,
+	}
+	if err := global.APP_DB.Create(&key).Error; err != nil {
+		return nil, fmt.Errorf("保存SSH公钥失败: %v", err)
+	}
+
+	global.APP_LOG.Info("用户添加SSH公钥",
+		zap.Uint("userId", userID),
+		zap.Uint("keyId", key.ID),
+		zap.String("fingerprint", key.Fingerprint))
+	return &key, nil
+}
+
+// UpdateKey 更新SSH公钥，已创建的实例中的公钥不会同步修改
+func (s *Service) UpdateKey(userID, keyID uint, req userModel.SSHKeyRequest) (*userModel.UserSSHKey, error) {
+	key, err := s.getKey(userID, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := parsePublicKey(req.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkDuplicate(userID, key.ID, parsed.fingerprint); err != nil {
+		return nil, err
+	}
+
+	if err := global.APP_DB.Model(key).Updates(map[string]interface{}{
+		"name":        strings.TrimSpace(req.Name),
+		"public_key":  parsed.publicKey,
+		"key_type":    parsed.keyType,
+		"fingerprint": parsed.fingerprint,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("更新SSH公钥失败: %v", err)
+	}
+
+	return s.getKey(userID, keyID)
+}
+
+// DeleteKey 删除SSH公钥，已注入实例中的公钥不会被移除
+func (s *Service) DeleteKey(userID, keyID uint) error {
+	key, err := s.getKey(userID, keyID)
+	if err != nil {
+		return err
+	}
+	if err := global.APP_DB.Delete(key).Error; err != nil {
+		return fmt.Errorf("删除SSH公钥失败: %v", err)
+	}
+
+	global.APP_LOG.Info("用户删除SSH公钥",
+		zap.Uint("userId", userID),
+		zap.Uint("keyId", keyID))
+	return nil
+}
+
+// ResolveKeys 按ID获取用户的公钥内容，任一公钥不存在或不属于该用户时返回错误
+func (s *Service) ResolveKeys(userID uint, keyIDs []uint) ([]string, error) {
+	if len(keyIDs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(keyIDs))
+	seen := make(map[uint]bool, len(keyIDs))
+	for _, id := range keyIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var keys []userModel.UserSSHKey
+	if err := global.APP_DB.Where("user_id = ? AND id IN (?)", userID, ids).Order("id ASC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("获取SSH公钥失败: %v", err)
+	}
+	if len(keys) != len(ids) {
+		return nil, errors.New("SSH公钥不存在或无权限")
+	}
+
+	publicKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		publicKeys = append(publicKeys, key.PublicKey)
+	}
+	return publicKeys, nil
+}
+
+// ValidateSelection 校验创建实例时选择的公钥，禁用密码登录时至少需要选择一个公钥
+func (s *Service) ValidateSelection(userID uint, keyIDs []uint, disablePassword bool) error {
+	if disablePassword && len(keyIDs) == 0 {
+		return errors.New("禁用密码登录时至少需要选择一个SSH公钥")
+	}
+	_, err := s.ResolveKeys(userID, keyIDs)
+	return err
+}
+
+// getKey 获取属于用户的公钥
+func (s *Service) getKey(userID, keyID uint) (*userModel.UserSSHKey, error) {
+	var key userModel.UserSSHKey
+	if err := global.APP_DB.Where("id = ? AND user_id = ?", keyID, userID).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("SSH公钥不存在")
+		}
+		return nil, fmt.Errorf("获取SSH公钥失败: %v", err)
+	}
+	return &key, nil
+}
+
+// checkDuplicate 检查用户是否已添加过相同指纹的公钥
+func (s *Service) checkDuplicate(userID, excludeID uint, fingerprint string) error {
+	var count int64
+	if err := global.APP_DB.Model(&userModel.UserSSHKey{}).
+		Where("user_id = ? AND fingerprint = ? AND id <> ?", userID, fingerprint, excludeID).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("检查SSH公钥失败: %v", err)
+	}
+	if count > 0 {
+		return errors.New("该SSH公钥已存在")
+	}
+	return nil
+}
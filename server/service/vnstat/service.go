@@ -385,6 +385,70 @@ func (s *Service) getAggregatedHistory(instanceID uint, limit int) []*monitoring
 
 // CleanupVnStatData 清理实例的vnStat数据
 func (s *Service) CleanupVnStatData(instanceID uint) error {
+	s.removeInstanceInterfaces(instanceID)
+
+	// 删除接口记录
+	if err := global.APP_DB.Where("instance_id = ?", instanceID).Delete(&monitoringModel.VnStatInterface{}).Error; err != nil {
+		return fmt.Errorf("failed to delete vnstat interfaces: %w", err)
+	}
+
+	// 删除流量记录
+	result := global.APP_DB.Where("instance_id = ?", instanceID).Delete(&monitoringModel.VnStatTrafficRecord{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete vnstat traffic records: %w", result.Error)
+	}
+
+	// 清空实例表中的vnstat_interface字段
+	if err := global.APP_DB.Model(&providerModel.Instance{}).Where("id = ?", instanceID).Update("vnstat_interface", "").Error; err != nil {
+		global.APP_LOG.Warn("清空实例vnstat接口字段失败",
+			zap.Uint("instance_id", instanceID),
+			zap.Error(err))
+		// 不返回错误，继续执行
+	}
+
+	global.APP_LOG.Info("vnStat数据清理完成",
+		zap.Uint("instance_id", instanceID),
+		zap.Int64("deleted_records", result.RowsAffected))
+
+	return nil
+}
+
+// TransferVnStatData 实例迁移到其他Provider时转移vnStat数据
+// 在原Provider上停止监控实例接口，历史流量记录归属到目标Provider，需要在实例记录切换Provider之前调用
+func (s *Service) TransferVnStatData(instanceID, targetProviderID uint, targetProviderType string) error {
+	s.removeInstanceInterfaces(instanceID)
+
+	// 接口名称在目标Provider上会重新检测，旧接口记录直接删除
+	if err := global.APP_DB.Where("instance_id = ?", instanceID).Delete(&monitoringModel.VnStatInterface{}).Error; err != nil {
+		return fmt.Errorf("failed to delete vnstat interfaces: %w", err)
+	}
+
+	result := global.APP_DB.Model(&monitoringModel.VnStatTrafficRecord{}).
+		Where("instance_id = ?", instanceID).
+		Updates(map[string]interface{}{
+			"provider_id":   targetProviderID,
+			"provider_type": targetProviderType,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to transfer vnstat traffic records: %w", result.Error)
+	}
+
+	if err := global.APP_DB.Model(&providerModel.Instance{}).Where("id = ?", instanceID).Update("vnstat_interface", "").Error; err != nil {
+		global.APP_LOG.Warn("清空实例vnstat接口字段失败",
+			zap.Uint("instance_id", instanceID),
+			zap.Error(err))
+	}
+
+	global.APP_LOG.Info("vnStat数据已转移",
+		zap.Uint("instance_id", instanceID),
+		zap.Uint("target_provider_id", targetProviderID),
+		zap.Int64("transferred_records", result.RowsAffected))
+
+	return nil
+}
+
+// removeInstanceInterfaces 在实例所在Provider上删除实例的vnStat监控接口，失败只记录日志
+func (s *Service) removeInstanceInterfaces(instanceID uint) {
 	// 获取实例信息
 	var instance providerModel.Instance
 	if err := global.APP_DB.First(&instance, instanceID).Error; err != nil {
@@ -452,31 +516,6 @@ func (s *Service) CleanupVnStatData(instanceID uint) error {
 			}
 		}
 	}
-
-	// 删除接口记录
-	if err := global.APP_DB.Where("instance_id = ?", instanceID).Delete(&monitoringModel.VnStatInterface{}).Error; err != nil {
-		return fmt.Errorf("failed to delete vnstat interfaces: %w", err)
-	}
-
-	// 删除流量记录
-	result := global.APP_DB.Where("instance_id = ?", instanceID).Delete(&monitoringModel.VnStatTrafficRecord{})
-	if result.Error != nil {
-		return fmt.Errorf("failed to delete vnstat traffic records: %w", result.Error)
-	}
-
-	// 清空实例表中的vnstat_interface字段
-	if err := global.APP_DB.Model(&providerModel.Instance{}).Where("id = ?", instanceID).Update("vnstat_interface", "").Error; err != nil {
-		global.APP_LOG.Warn("清空实例vnstat接口字段失败",
-			zap.Uint("instance_id", instanceID),
-			zap.Error(err))
-		// 不返回错误，继续执行
-	}
-
-	global.APP_LOG.Info("vnStat数据清理完成",
-		zap.Uint("instance_id", instanceID),
-		zap.Int64("deleted_records", result.RowsAffected))
-
-	return nil
 }
 
 // CleanupOldVnStatData 清理过期的vnStat数据
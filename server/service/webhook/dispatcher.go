@@ -0,0 +1,376 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+	"oneclickvirt/utils"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 投递状态
+const (
+	deliveryStatusPending    = "pending"
+	deliveryStatusDelivering = "delivering"
+	deliveryStatusSuccess    = "success"
+	deliveryStatusFailed     = "failed"
+)
+
+const (
+	maxDeliveryAttempts   = 6                // 最大投递次数（含首次）
+	retryBaseInterval     = 30 * time.Second // 首次重试间隔，之后每次翻倍
+	retryMaxInterval      = time.Hour        // 重试间隔上限
+	deliveryTimeout       = 10 * time.Second // 单次请求超时
+	staleDeliveryTimeout  = 5 * time.Minute  // delivering状态超过该时间视为进程中断，重新投递
+	retryBatchSize        = 50               // 每轮最多处理的重试数量
+	retryConcurrency      = 5                // 重试并发数
+	maxStoredResponseSize = 2048             // 保存的响应内容最大长度
+	deliveryRetentionDays = 30               // 投递记录保留天数
+)
+
+// 请求头
+const (
+	HeaderEvent     = "X-OneClickVirt-Event"
+	HeaderDelivery  = "X-OneClickVirt-Delivery"
+	HeaderTimestamp = "X-OneClickVirt-Timestamp"
+	HeaderSignature = "X-OneClickVirt-Signature"
+)
+
+// httpClient 不跟随重定向，避免签名请求被转发到其他地址
+var httpClient = &http.Client{
+	Timeout: deliveryTimeout,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// retryRunning 防止重试轮次重叠执行
+var retryRunning atomic.Bool
+
+// Payload Webhook请求体
+type Payload struct {
+	ID        string      `json:"id"`        // 事件ID，同一事件投递给多个订阅者时相同
+	Event     string      `json:"event"`     // 事件类型
+	CreatedAt time.Time   `json:"createdAt"` // 事件发生时间
+	Data      interface{} `json:"data"`      // 事件数据
+}
+
+// Sign 计算签名：HMAC-SHA256(secret, timestamp + "." + body)，结果为 sha256=<hex>
+// 接收方应使用相同方式计算并比较，同时校验时间戳防止重放
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// subscribes 判断Webhook是否订阅了该事件
+func subscribes(webhook *adminModel.Webhook, eventType string) bool {
+	for _, event := range strings.Split(webhook.Events, ",") {
+		event = strings.TrimSpace(event)
+		if event == EventAll || event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// retryDelay 第attempts次失败后的重试间隔
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseInterval
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= retryMaxInterval {
+			return retryMaxInterval
+		}
+	}
+	return delay
+}
+
+// buildPayload 生成事件请求体
+func buildPayload(eventType string, data interface{}) (string, error) {
+	body, err := json.Marshal(Payload{
+		ID:        uuid.New().String(),
+		Event:     eventType,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		return "", fmt.Errorf("序列化事件数据失败: %v", err)
+	}
+	return string(body), nil
+}
+
+// createDelivery 为单个Webhook创建投递记录
+func createDelivery(webhook *adminModel.Webhook, eventType string, data interface{}) (*adminModel.WebhookDelivery, error) {
+	payload, err := buildPayload(eventType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery := &adminModel.WebhookDelivery{
+		WebhookID:   webhook.ID,
+		EventType:   eventType,
+		Payload:     payload,
+		Status:      deliveryStatusPending,
+		NextRetryAt: &now,
+	}
+	if err := global.APP_DB.Create(delivery).Error; err != nil {
+		return nil, fmt.Errorf("创建投递记录失败: %v", err)
+	}
+	return delivery, nil
+}
+
+// Publish 发布事件，为所有订阅该事件的已启用Webhook生成投递记录并异步投递
+// 投递失败不会影响调用方，失败的投递由调度器按退避间隔重试
+func Publish(eventType string, data interface{}) {
+	if global.APP_DB == nil {
+		return
+	}
+
+	var webhooks []adminModel.Webhook
+	if err := global.APP_DB.Where("enabled = ?", true).Find(&webhooks).Error; err != nil {
+		global.APP_LOG.Error("获取Webhook列表失败", zap.String("event", eventType), zap.Error(err))
+		return
+	}
+
+	var targets []adminModel.Webhook
+	for i := range webhooks {
+		if subscribes(&webhooks[i], eventType) {
+			targets = append(targets, webhooks[i])
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	payload, err := buildPayload(eventType, data)
+	if err != nil {
+		global.APP_LOG.Error("生成Webhook事件失败", zap.String("event", eventType), zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	deliveryIDs := make([]uint, 0, len(targets))
+	for i := range targets {
+		delivery := adminModel.WebhookDelivery{
+			WebhookID:   targets[i].ID,
+			EventType:   eventType,
+			Payload:     payload,
+			Status:      deliveryStatusPending,
+			NextRetryAt: &now,
+		}
+		if err := global.APP_DB.Create(&delivery).Error; err != nil {
+			global.APP_LOG.Error("创建Webhook投递记录失败",
+				zap.Uint("webhookId", targets[i].ID),
+				zap.String("event", eventType),
+				zap.Error(err))
+			continue
+		}
+		deliveryIDs = append(deliveryIDs, delivery.ID)
+	}
+
+	for _, id := range deliveryIDs {
+		go attemptDelivery(id)
+	}
+}
+
+// attemptDelivery 执行一次投递，通过状态更新抢占记录，避免即时投递和调度器重试重复发送
+func attemptDelivery(deliveryID uint) {
+	result := global.APP_DB.Model(&adminModel.WebhookDelivery{}).
+		Where("id = ? AND status = ?", deliveryID, deliveryStatusPending).
+		Updates(map[string]interface{}{
+			"status":   deliveryStatusDelivering,
+			"attempts": gorm.Expr("attempts + ?", 1),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+
+	var delivery adminModel.WebhookDelivery
+	if err := global.APP_DB.First(&delivery, deliveryID).Error; err != nil {
+		global.APP_LOG.Error("获取Webhook投递记录失败", zap.Uint("deliveryId", deliveryID), zap.Error(err))
+		return
+	}
+
+	var webhook adminModel.Webhook
+	if err := global.APP_DB.First(&webhook, delivery.WebhookID).Error; err != nil {
+		msg := "获取Webhook失败"
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			msg = "Webhook已删除"
+		}
+		finishDelivery(&delivery, nil, 0, "", 0, errors.New(msg), true)
+		return
+	}
+	if !webhook.Enabled && delivery.EventType != EventWebhookTest {
+		finishDelivery(&delivery, &webhook, 0, "", 0, errors.New("Webhook已停用"), true)
+		return
+	}
+
+	statusCode, body, duration, err := send(&webhook, &delivery)
+	finishDelivery(&delivery, &webhook, statusCode, body, duration, err, false)
+}
+
+// send 发送签名请求，非2xx响应视为失败
+func send(webhook *adminModel.Webhook, delivery *adminModel.WebhookDelivery) (int, string, time.Duration, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", 0, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "OneClickVirt-Webhook/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.UUID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, body))
+
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	duration := time.Since(start)
+	if err != nil {
+		return 0, "", duration, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxStoredResponseSize))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(respBody), duration, fmt.Errorf("响应状态码 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(respBody), duration, nil
+}
+
+// finishDelivery 记录投递结果，失败且未超过最大次数时安排下次重试
+func finishDelivery(delivery *adminModel.WebhookDelivery, webhook *adminModel.Webhook, statusCode int, body string, duration time.Duration, sendErr error, final bool) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"response_code": statusCode,
+		"response_body": strings.ToValidUTF8(body, ""),
+		"duration_ms":   duration.Milliseconds(),
+		"error":         "",
+		"next_retry_at": nil,
+	}
+
+	switch {
+	case sendErr == nil:
+		updates["status"] = deliveryStatusSuccess
+		updates["delivered_at"] = now
+	case final || delivery.Attempts >= maxDeliveryAttempts || delivery.EventType == EventWebhookTest:
+		updates["status"] = deliveryStatusFailed
+		updates["error"] = strings.ToValidUTF8(utils.TruncateString(sendErr.Error(), 500), "")
+	default:
+		updates["status"] = deliveryStatusPending
+		updates["error"] = strings.ToValidUTF8(utils.TruncateString(sendErr.Error(), 500), "")
+		updates["next_retry_at"] = now.Add(retryDelay(delivery.Attempts))
+	}
+
+	if err := global.APP_DB.Model(delivery).Updates(updates).Error; err != nil {
+		global.APP_LOG.Error("更新Webhook投递记录失败", zap.Uint("deliveryId", delivery.ID), zap.Error(err))
+	}
+
+	if webhook == nil {
+		return
+	}
+	lastStatus := deliveryStatusSuccess
+	if sendErr != nil {
+		lastStatus = deliveryStatusFailed
+	}
+	global.APP_DB.Model(webhook).Updates(map[string]interface{}{
+		"last_status":  lastStatus,
+		"last_error":   updates["error"],
+		"last_sent_at": now,
+	})
+
+	if sendErr != nil {
+		global.APP_LOG.Warn("Webhook投递失败",
+			zap.Uint("webhookId", webhook.ID),
+			zap.Uint("deliveryId", delivery.ID),
+			zap.String("event", delivery.EventType),
+			zap.Int("attempts", delivery.Attempts),
+			zap.Any("status", updates["status"]),
+			zap.Error(sendErr))
+	}
+}
+
+// ProcessPendingDeliveries 重试到期的投递，由调度器定期调用
+// 上一轮尚未结束时直接返回，避免慢速的接收方导致重试堆积
+func ProcessPendingDeliveries() {
+	if global.APP_DB == nil {
+		return
+	}
+	if !retryRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer retryRunning.Store(false)
+
+	now := time.Now()
+
+	// 进程在投递过程中退出时记录会停留在delivering状态，超时后重新投递
+	global.APP_DB.Model(&adminModel.WebhookDelivery{}).
+		Where("status = ? AND updated_at < ?", deliveryStatusDelivering, now.Add(-staleDeliveryTimeout)).
+		Updates(map[string]interface{}{"status": deliveryStatusPending, "next_retry_at": now})
+
+	var ids []uint
+	if err := global.APP_DB.Model(&adminModel.WebhookDelivery{}).
+		Where("status = ? AND next_retry_at <= ?", deliveryStatusPending, now).
+		Order("next_retry_at ASC").
+		Limit(retryBatchSize).
+		Pluck("id", &ids).Error; err != nil {
+		global.APP_LOG.Error("获取待重试的Webhook投递失败", zap.Error(err))
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	sem := make(chan struct{}, retryConcurrency)
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id uint) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			attemptDelivery(id)
+		}(id)
+	}
+	wg.Wait()
+}
+
+// CleanupOldDeliveries 清理过期的投递记录
+func CleanupOldDeliveries() {
+	if global.APP_DB == nil {
+		return
+	}
+
+	threshold := time.Now().AddDate(0, 0, -deliveryRetentionDays)
+	result := global.APP_DB.Where("status IN ? AND created_at < ?",
+		[]string{deliveryStatusSuccess, deliveryStatusFailed}, threshold).
+		Delete(&adminModel.WebhookDelivery{})
+	if result.Error != nil {
+		global.APP_LOG.Error("清理Webhook投递记录失败", zap.Error(result.Error))
+	} else if result.RowsAffected > 0 {
+		global.APP_LOG.Info("清理Webhook投递记录", zap.Int64("count", result.RowsAffected))
+	}
+}
@@ -0,0 +1,65 @@
+package webhook
+
+import providerModel "oneclickvirt/model/provider"
+
+// 事件类型
+const (
+	EventAll                    = "*"                          // 订阅全部事件
+	EventTaskCompleted          = "task.completed"             // 任务执行成功
+	EventTaskFailed             = "task.failed"                // 任务执行失败
+	EventInstanceCreated        = "instance.created"           // 实例创建完成（含从备份恢复为新实例）
+	EventInstanceDeleted        = "instance.deleted"           // 实例删除完成
+	EventInstanceExpired        = "instance.expired"           // 实例到期被清理
+	EventInstanceTrafficLimited = "instance.traffic_limited"   // 实例因流量超限被限制
+	EventInstanceTrafficResumed = "instance.traffic_unlimited" // 实例流量限制解除
+	EventProviderStatusChanged  = "provider.status_changed"    // 节点健康状态变化
+	EventWebhookTest            = "webhook.test"               // 管理员手动发送的测试事件，不需要订阅
+)
+
+// EventInfo 事件类型说明
+type EventInfo struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// SupportedEvents 可订阅的事件类型
+var SupportedEvents = []EventInfo{
+	{Type: EventTaskCompleted, Description: "任务执行成功"},
+	{Type: EventTaskFailed, Description: "任务执行失败"},
+	{Type: EventInstanceCreated, Description: "实例创建完成"},
+	{Type: EventInstanceDeleted, Description: "实例删除完成"},
+	{Type: EventInstanceExpired, Description: "实例到期被清理"},
+	{Type: EventInstanceTrafficLimited, Description: "实例因流量超限被限制"},
+	{Type: EventInstanceTrafficResumed, Description: "实例流量限制解除"},
+	{Type: EventProviderStatusChanged, Description: "节点健康状态变化"},
+}
+
+// PublishInstance 发布实例生命周期事件，extra中的字段合并到实例基本信息中
+func PublishInstance(eventType string, instance *providerModel.Instance, extra map[string]interface{}) {
+	data := map[string]interface{}{
+		"instanceId":   instance.ID,
+		"instanceName": instance.Name,
+		"instanceType": instance.InstanceType,
+		"userId":       instance.UserID,
+		"providerId":   instance.ProviderID,
+		"provider":     instance.Provider,
+		"expiredAt":    instance.ExpiredAt,
+	}
+	for key, value := range extra {
+		data[key] = value
+	}
+	Publish(eventType, data)
+}
+
+// isSupportedEvent 判断事件类型是否可订阅
+func isSupportedEvent(eventType string) bool {
+	if eventType == EventAll {
+		return true
+	}
+	for _, event := range SupportedEvents {
+		if event.Type == eventType {
+			return true
+		}
+	}
+	return false
+}
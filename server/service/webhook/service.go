@@ -0,0 +1,271 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"oneclickvirt/global"
+	adminModel "oneclickvirt/model/admin"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Service Webhook管理服务
+type Service struct{}
+
+// NewService 创建Webhook管理服务
+func NewService() *Service {
+	return &Service{}
+}
+
+// generateSecret 生成随机签名密钥
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成签名密钥失败: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// normalizeEvents 校验并去重订阅的事件类型，包含"*"时只保留"*"
+func normalizeEvents(events []string) (string, error) {
+	var result []string
+	seen := make(map[string]bool)
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if event == "" || seen[event] {
+			continue
+		}
+		if !isSupportedEvent(event) {
+			return "", fmt.Errorf("不支持的事件类型: %s", event)
+		}
+		if event == EventAll {
+			return EventAll, nil
+		}
+		seen[event] = true
+		result = append(result, event)
+	}
+	if len(result) == 0 {
+		return "", errors.New("至少需要订阅一个事件类型")
+	}
+	return strings.Join(result, ","), nil
+}
+
+// validateURL 只允许http和https地址
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("Webhook地址不合法，只支持http和https")
+	}
+	return nil
+}
+
+// ListWebhooks 获取Webhook列表
+func (s *Service) ListWebhooks() ([]adminModel.Webhook, error) {
+	var webhooks []adminModel.Webhook
+	if err := global.APP_DB.Order("id ASC").Find(&webhooks).Error; err != nil {
+		return nil, fmt.Errorf("获取Webhook列表失败: %v", err)
+	}
+	return webhooks, nil
+}
+
+// CreateWebhook 创建Webhook，未提供密钥时自动生成
+func (s *Service) CreateWebhook(req adminModel.WebhookRequest, createdBy uint) (*adminModel.Webhook, error) {
+	if err := validateURL(req.URL); err != nil {
+		return nil, err
+	}
+	events, err := normalizeEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = generateSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	webhook := adminModel.Webhook{
+		Name:        strings.TrimSpace(req.Name),
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      events,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		Description: req.Description,
+		CreatedBy:   createdBy,
+	}
+	if err := global.APP_DB.Create(&webhook).Error; err != nil {
+		return nil, fmt.Errorf("创建Webhook失败: %v", err)
+	}
+	// enabled字段默认值为true，创建时false会被当作零值忽略，需要单独更新
+	if !webhook.Enabled {
+		if err := global.APP_DB.Model(&webhook).Update("enabled", false).Error; err != nil {
+			return nil, fmt.Errorf("创建Webhook失败: %v", err)
+		}
+	}
+
+	global.APP_LOG.Info("创建Webhook",
+		zap.Uint("webhookId", webhook.ID),
+		zap.String("events", webhook.Events),
+		zap.Uint("createdBy", createdBy))
+	return &webhook, nil
+}
+
+// UpdateWebhook 更新Webhook，密钥留空时保持不变
+func (s *Service) UpdateWebhook(id uint, req adminModel.WebhookRequest) (*adminModel.Webhook, error) {
+	webhook, err := s.getWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateURL(req.URL); err != nil {
+		return nil, err
+	}
+	events, err := normalizeEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"name":        strings.TrimSpace(req.Name),
+		"url":         req.URL,
+		"events":      events,
+		"description": req.Description,
+	}
+	if req.Secret != "" {
+		updates["secret"] = req.Secret
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+	if err := global.APP_DB.Model(webhook).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("更新Webhook失败: %v", err)
+	}
+
+	return s.getWebhook(id)
+}
+
+// DeleteWebhook 删除Webhook，未完成的投递一并取消
+func (s *Service) DeleteWebhook(id uint) error {
+	webhook, err := s.getWebhook(id)
+	if err != nil {
+		return err
+	}
+
+	return global.APP_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(webhook).Error; err != nil {
+			return fmt.Errorf("删除Webhook失败: %v", err)
+		}
+		if err := tx.Model(&adminModel.WebhookDelivery{}).
+			Where("webhook_id = ? AND status = ?", id, deliveryStatusPending).
+			Updates(map[string]interface{}{"status": deliveryStatusFailed, "error": "Webhook已删除", "next_retry_at": nil}).Error; err != nil {
+			return fmt.Errorf("取消待投递记录失败: %v", err)
+		}
+		return nil
+	})
+}
+
+// ListDeliveries 获取Webhook的投递记录
+func (s *Service) ListDeliveries(webhookID uint, req adminModel.WebhookDeliveryListRequest) ([]adminModel.WebhookDelivery, int64, error) {
+	if _, err := s.getWebhook(webhookID); err != nil {
+		return nil, 0, err
+	}
+
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	query := global.APP_DB.Model(&adminModel.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if req.EventType != "" {
+		query = query.Where("event_type = ?", req.EventType)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取投递记录失败: %v", err)
+	}
+
+	var deliveries []adminModel.WebhookDelivery
+	if err := query.Order("id DESC").Offset((req.Page - 1) * req.PageSize).Limit(req.PageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取投递记录失败: %v", err)
+	}
+	return deliveries, total, nil
+}
+
+// SendTestEvent 向Webhook发送一次测试事件，同步返回投递结果
+func (s *Service) SendTestEvent(id uint) (*adminModel.WebhookDelivery, error) {
+	webhook, err := s.getWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := createDelivery(webhook, EventWebhookTest, map[string]interface{}{
+		"webhookId": webhook.ID,
+		"name":      webhook.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attemptDelivery(delivery.ID)
+	if err := global.APP_DB.First(delivery, delivery.ID).Error; err != nil {
+		return nil, fmt.Errorf("获取投递结果失败: %v", err)
+	}
+	return delivery, nil
+}
+
+// RedeliverDelivery 重新投递一条已完成或失败的记录，生成新的投递记录
+func (s *Service) RedeliverDelivery(deliveryID uint) (*adminModel.WebhookDelivery, error) {
+	var original adminModel.WebhookDelivery
+	if err := global.APP_DB.First(&original, deliveryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("投递记录不存在")
+		}
+		return nil, fmt.Errorf("获取投递记录失败: %v", err)
+	}
+	if _, err := s.getWebhook(original.WebhookID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery := adminModel.WebhookDelivery{
+		WebhookID:   original.WebhookID,
+		EventType:   original.EventType,
+		Payload:     original.Payload,
+		Status:      deliveryStatusPending,
+		NextRetryAt: &now,
+	}
+	if err := global.APP_DB.Create(&delivery).Error; err != nil {
+		return nil, fmt.Errorf("创建投递记录失败: %v", err)
+	}
+
+	attemptDelivery(delivery.ID)
+	if err := global.APP_DB.First(&delivery, delivery.ID).Error; err != nil {
+		return nil, fmt.Errorf("获取投递结果失败: %v", err)
+	}
+	return &delivery, nil
+}
+
+// getWebhook 获取Webhook
+func (s *Service) getWebhook(id uint) (*adminModel.Webhook, error) {
+	var webhook adminModel.Webhook
+	if err := global.APP_DB.First(&webhook, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("Webhook不存在")
+		}
+		return nil, fmt.Errorf("获取Webhook失败: %v", err)
+	}
+	return &webhook, nil
+}
@@ -30,8 +30,18 @@ func InitSeedData() {
 
 func initDefaultRoles() {
 	roles := []auth.Role{
-		{Name: "admin", Code: "admin", Description: "系统管理员角色", Status: 1},
-		{Name: "user", Code: "user", Description: "普通用户角色", Status: 1},
+		{Name: "admin", Code: "admin", Description: "系统管理员角色", Status: 1, Permissions: auth.PermissionAll, IsSystem: true},
+		{Name: "user", Code: "user", Description: "普通用户角色", Status: 1, IsSystem: true},
+		{Name: "support", Code: "support", Description: "客服角色，可查看管理后台数据但不能修改", Status: 1, Permissions: strings.Join([]string{
+			auth.PermissionDashboardView,
+			auth.PermissionUserView,
+			auth.PermissionInstanceViewAny,
+			auth.PermissionProviderView,
+			auth.PermissionTrafficView,
+			auth.PermissionTaskView,
+			auth.PermissionContentView,
+			auth.PermissionAuditView,
+		}, ",")},
 	}
 
 	for _, role := range roles {
@@ -544,7 +554,11 @@ func initLevelConfigurations() {
 			"disk":      1024, // 1GB
 			"bandwidth": 100,  // 100Mbps
 		},
-		MaxTraffic: 102400, // 100GB
+		MaxTraffic:   102400, // 100GB
+		MaxSnapshots: 1,
+		MaxBackups:   1,
+		MaxPorts:     3,
+		MaxDomains:   1,
 	}
 
 	// 等级2: 中级档次
@@ -556,7 +570,11 @@ func initLevelConfigurations() {
 			"disk":      20480, // 20GB
 			"bandwidth": 200,   // 200Mbps
 		},
-		MaxTraffic: 204800, // 200GB
+		MaxTraffic:   204800, // 200GB
+		MaxSnapshots: 2,
+		MaxBackups:   2,
+		MaxPorts:     5,
+		MaxDomains:   2,
 	}
 
 	// 等级3: 高级档次
@@ -568,7 +586,11 @@ func initLevelConfigurations() {
 			"disk":      40960, // 40GB
 			"bandwidth": 500,   // 500Mbps
 		},
-		MaxTraffic: 307200, // 300GB
+		MaxTraffic:   307200, // 300GB
+		MaxSnapshots: 3,
+		MaxBackups:   3,
+		MaxPorts:     10,
+		MaxDomains:   5,
 	}
 
 	// 等级4: 超级档次
@@ -580,7 +602,11 @@ func initLevelConfigurations() {
 			"disk":      81920, // 80GB
 			"bandwidth": 1000,  // 1000Mbps
 		},
-		MaxTraffic: 409600, // 400GB
+		MaxTraffic:   409600, // 400GB
+		MaxSnapshots: 5,
+		MaxBackups:   5,
+		MaxPorts:     20,
+		MaxDomains:   10,
 	}
 
 	// 等级5: 管理员档次
@@ -592,7 +618,11 @@ func initLevelConfigurations() {
 			"disk":      163840, // 160GB
 			"bandwidth": 2000,   // 2000Mbps
 		},
-		MaxTraffic: 512000, // 500GB
+		MaxTraffic:   512000, // 500GB
+		MaxSnapshots: 10,
+		MaxBackups:   10,
+		MaxPorts:     50,
+		MaxDomains:   20,
 	}
 
 	global.APP_LOG.Info("等级与带宽配置初始化完成")
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"字段数量不足", "0 0 * *"},
+		{"字段数量过多", "0 0 * * * *"},
+		{"步长为0", "*/0 * * * *"},
+		{"步长不是数字", "*/x * * * *"},
+		{"范围颠倒", "0 5-1 * * *"},
+		{"分钟超出范围", "60 * * * *"},
+		{"日为0", "0 0 0 * *"},
+		{"月超出范围", "0 0 1 13 *"},
+		{"周超出范围", "0 0 * * 8"},
+		{"范围上界超出", "0 20-24 * * *"},
+		{"非数字", "a * * * *"},
+		{"列表中含无效项", "0,61 * * * *"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCron(tt.expr); err == nil {
+				t.Errorf("ParseCron(%q) 应返回错误", tt.expr)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	// 2026-01-15 是周四
+	from := time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		// 步长
+		{"每15分钟", "*/15 * * * *", from, at(1, 15, 10, 45)},
+		{"每6小时", "0 */6 * * *", from, at(1, 15, 12, 0)},
+		{"单个值带步长从该值开始", "10/20 * * * *", from, at(1, 15, 10, 50)},
+		{"每2天从1日开始", "0 0 */2 * *", from, at(1, 17, 0, 0)},
+
+		// 范围
+		{"小时范围", "0 9-17 * * *", from, at(1, 15, 11, 0)},
+		{"范围带步长", "0 9-17/4 * * *", from, at(1, 15, 13, 0)},
+		{"范围结束后进入次日", "0 1-3 * * *", from, at(1, 16, 1, 0)},
+		{"工作日跳过周末", "0 0 * * 1-5", at(1, 16, 10, 30), at(1, 19, 0, 0)},
+
+		// 列表
+		{"分钟列表", "5,35 * * * *", from, at(1, 15, 10, 35)},
+		{"列表回绕到下一小时", "5,35 * * * *", at(1, 15, 10, 35), at(1, 15, 11, 5)},
+		{"列表与范围混合", "0 2,20-22 * * *", from, at(1, 15, 20, 0)},
+		{"月份列表", "0 0 1 3,6 *", from, at(3, 1, 0, 0)},
+
+		// 时间严格晚于起点
+		{"起点恰好匹配时取下一次", "30 10 * * *", from, at(1, 16, 10, 30)},
+		{"秒被截断", "31 10 * * *", from.Add(45 * time.Second), at(1, 15, 10, 31)},
+
+		// 日和周
+		{"只限定日", "0 0 20 * *", from, at(1, 20, 0, 0)},
+		{"只限定周", "0 0 * * 0", from, at(1, 18, 0, 0)},
+		{"周日可写作7", "0 0 * * 7", from, at(1, 18, 0, 0)},
+		{"日和周同时限定按任一匹配-周先到", "0 0 20 * 1", from, at(1, 19, 0, 0)},
+		{"日和周同时限定按任一匹配-日先到", "0 0 20 * 1", at(1, 19, 0, 0), at(1, 20, 0, 0)},
+		{"日和周同时限定-13日或周五", "0 0 13 * 5", at(2, 7, 0, 0), at(2, 13, 0, 0)},
+		{"周字段为*/n时视为不限定", "0 0 20 * */2", from, at(1, 20, 0, 0)},
+		{"跳过没有31日的月份", "0 0 31 * *", at(1, 31, 0, 0), at(3, 31, 0, 0)},
+		{"闰日", "0 0 29 2 *", from, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"跨年", "0 0 1 1 *", from, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) 失败: %v", tt.expr, err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from.Format(time.RFC3339), got.Format(time.RFC3339), tt.want.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestCronScheduleNextNeverMatches(t *testing.T) {
+	schedule, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("ParseCron 失败: %v", err)
+	}
+	if got := schedule.Next(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("2月30日不存在，Next() = %s, want 零值", got)
+	}
+}
+
+func TestCronScheduleNextKeepsLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	schedule, err := ParseCron("0 3 * * *")
+	if err != nil {
+		t.Fatalf("ParseCron 失败: %v", err)
+	}
+	want := time.Date(2026, 1, 16, 3, 0, 0, 0, loc)
+	if got := schedule.Next(time.Date(2026, 1, 15, 22, 0, 0, 0, loc)); !got.Equal(want) || got.Location() != loc {
+		t.Errorf("Next() = %s, want %s", got, want)
+	}
+}
@@ -221,8 +221,25 @@ func (c *SSHClient) Execute(command string) (string, error) {
 	return output, err
 }
 
+// ExecuteWithTimeout 使用指定超时时间执行命令，用于导出、导入等耗时较长的操作
+func (c *SSHClient) ExecuteWithTimeout(command string, timeout time.Duration) (string, error) {
+	if !c.IsHealthy() {
+		global.APP_LOG.Warn("SSH连接不健康，尝试重连",
+			zap.String("host", c.config.Host))
+		if err := c.Reconnect(); err != nil {
+			return "", fmt.Errorf("failed to reconnect SSH before execution: %w", err)
+		}
+	}
+	return c.executeCommandWithTimeout(command, timeout)
+}
+
 // executeCommand 执行SSH命令的内部方法
 func (c *SSHClient) executeCommand(command string) (string, error) {
+	return c.executeCommandWithTimeout(command, c.config.ExecuteTimeout)
+}
+
+// executeCommandWithTimeout 使用指定超时时间执行SSH命令
+func (c *SSHClient) executeCommandWithTimeout(command string, timeout time.Duration) (string, error) {
 	session, err := c.client.NewSession()
 	if err != nil {
 		return "", fmt.Errorf("failed to create SSH session: %w", err)
@@ -268,9 +285,9 @@ func (c *SSHClient) executeCommand(command string) (string, error) {
 			return string(output), fmt.Errorf("command execution failed: %w", execErr)
 		}
 		return string(output), nil
-	case <-time.After(c.config.ExecuteTimeout):
+	case <-time.After(timeout):
 		session.Signal(ssh.SIGKILL) // 强制终止会话
-		return "", fmt.Errorf("command execution timeout after %v", c.config.ExecuteTimeout)
+		return "", fmt.Errorf("command execution timeout after %v", timeout)
 	}
 }
 
@@ -547,3 +564,64 @@ func (c *SSHClient) UploadContent(content, remotePath string, perm os.FileMode)
 
 	return nil
 }
+
+// DownloadFile 通过SFTP将远程文件下载到本地路径
+func (c *SSHClient) DownloadFile(remotePath, localPath string) (int64, error) {
+	sftpClient, err := sftp.NewClient(c.client)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create SFTP client: %w", err)
+	}
+	defer sftpClient.Close()
+
+	remoteFile, err := sftpClient.Open(remotePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open remote file %s: %w", remotePath, err)
+	}
+	defer remoteFile.Close()
+
+	localFile, err := os.Create(localPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create local file %s: %w", localPath, err)
+	}
+	defer localFile.Close()
+
+	written, err := io.Copy(localFile, remoteFile)
+	if err != nil {
+		return written, fmt.Errorf("failed to download remote file %s: %w", remotePath, err)
+	}
+
+	return written, nil
+}
+
+// UploadFile 通过SFTP将本地文件上传到远程服务器指定路径
+func (c *SSHClient) UploadFile(localPath, remotePath string) error {
+	sftpClient, err := sftp.NewClient(c.client)
+	if err != nil {
+		return fmt.Errorf("failed to create SFTP client: %w", err)
+	}
+	defer sftpClient.Close()
+
+	if lastSlash := strings.LastIndex(remotePath, "/"); lastSlash > 0 {
+		if err := sftpClient.MkdirAll(remotePath[:lastSlash]); err != nil {
+			return fmt.Errorf("failed to create remote directory %s: %w", remotePath[:lastSlash], err)
+		}
+	}
+
+	localFile, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file %s: %w", localPath, err)
+	}
+	defer localFile.Close()
+
+	remoteFile, err := sftpClient.Create(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to create remote file %s: %w", remotePath, err)
+	}
+	defer remoteFile.Close()
+
+	if _, err := io.Copy(remoteFile, localFile); err != nil {
+		return fmt.Errorf("failed to upload file to %s: %w", remotePath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"oneclickvirt/global"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHTerminal 基于SSH会话的交互式终端，输入输出直接对接远程PTY
+type SSHTerminal struct {
+	session   *ssh.Session
+	stdin     io.WriteCloser
+	stdout    io.Reader
+	closeOnce sync.Once
+}
+
+// OpenTerminal 在远程主机上分配PTY并启动交互式命令
+func (c *SSHClient) OpenTerminal(command string, cols, rows int) (*SSHTerminal, error) {
+	if !c.IsHealthy() {
+		global.APP_LOG.Warn("SSH连接不健康，尝试重连",
+			zap.String("host", c.config.Host))
+		if err := c.Reconnect(); err != nil {
+			return nil, fmt.Errorf("failed to reconnect SSH before opening terminal: %w", err)
+		}
+	}
+
+	session, err := c.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH session: %w", err)
+	}
+
+	// 交互式终端需要开启回显
+	err = session.RequestPty("xterm-256color", rows, cols, ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	})
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to request PTY: %w", err)
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to get stdin pipe: %w", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+
+	// 与普通命令一致加载节点环境变量，exec替换当前shell使会话随命令退出而结束
+	envCommand := fmt.Sprintf("source /etc/profile 2>/dev/null || true; export PATH=$PATH:/usr/local/bin:/snap/bin:/usr/sbin:/sbin; exec %s", command)
+	if err := session.Start(envCommand); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to start terminal command: %w", err)
+	}
+
+	return &SSHTerminal{session: session, stdin: stdin, stdout: stdout}, nil
+}
+
+// Read 读取终端输出（PTY下标准错误已合并到标准输出）
+func (t *SSHTerminal) Read(p []byte) (int, error) {
+	return t.stdout.Read(p)
+}
+
+// Write 向终端写入输入
+func (t *SSHTerminal) Write(p []byte) (int, error) {
+	return t.stdin.Write(p)
+}
+
+// Resize 调整终端窗口大小
+func (t *SSHTerminal) Resize(cols, rows int) error {
+	return t.session.WindowChange(rows, cols)
+}
+
+// Wait 等待远程命令退出
+func (t *SSHTerminal) Wait() error {
+	return t.session.Wait()
+}
+
+// Close 关闭终端会话，可重复调用
+func (t *SSHTerminal) Close() error {
+	var err error
+	t.closeOnce.Do(func() {
+		t.stdin.Close()
+		err = t.session.Close()
+	})
+	return err
+}